@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+)
+
+// boot runs startup as ordered, named phases. Each phase's duration is logged and
+// recorded. A failure names the phase, and both a failure and a shutdown signal
+// during boot skip the remaining phases and unwind whatever had already started.
+type boot struct {
+	ctx    context.Context
+	logger *zap.SugaredLogger
+
+	mu      sync.Mutex
+	phase   string
+	started time.Time
+	bootAt  time.Time
+	cleanup []namedCleanup
+}
+
+type namedCleanup struct {
+	name string
+	fn   func()
+}
+
+// phase is one named startup step; run gets the boot context, cancelled by a
+// shutdown signal
+type phase struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// errInterrupted is returned by run when a shutdown signal arrived before boot finished
+var errInterrupted = errors.New("shutdown requested during startup")
+
+func newBoot(ctx context.Context, logger *zap.SugaredLogger) *boot {
+	return &boot{ctx: ctx, logger: logger, bootAt: time.Now()}
+}
+
+// begin ends the current phase and starts the next one. It returns false if
+// shutdown was requested, in which case the caller should call shutdown and return.
+func (b *boot) begin(phase string) bool {
+	b.end()
+	if b.ctx.Err() != nil {
+		b.logger.Warnw("Shutdown requested during startup; skipping remaining phases", "nextPhase", phase)
+		return false
+	}
+	b.mu.Lock()
+	b.phase, b.started = phase, time.Now()
+	b.mu.Unlock()
+	b.logger.Infow("Startup phase started", "phase", phase)
+	return true
+}
+
+// end finishes the current phase, if any
+func (b *boot) end() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.phase == "" {
+		return
+	}
+	elapsed := time.Since(b.started)
+	metrics.StartupPhaseDuration.WithLabelValues(b.phase).Set(elapsed.Seconds())
+	b.logger.Infow("Startup phase completed", "phase", b.phase, "duration", elapsed)
+	b.phase = ""
+}
+
+// finish ends the last phase and logs the total boot time
+func (b *boot) finish() {
+	b.end()
+	b.logger.Infow("Startup completed", "duration", time.Since(b.bootAt))
+}
+
+// current is the phase in progress, or "" between phases and after boot
+func (b *boot) current() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.phase
+}
+
+// run runs phases in order and finishes the boot. When a phase fails, or shutdown
+// is requested, the remaining phases are skipped and the cleanups registered so
+// far run before it returns. A failure's error names its phase; a phase that
+// failed because of the signal, such as a cancelled migration lock wait, counts
+// as interrupted.
+func (b *boot) run(phases []phase) error {
+	for _, p := range phases {
+		if !b.begin(p.name) {
+			b.shutdown()
+			return errInterrupted
+		}
+		if err := p.run(b.ctx); err != nil {
+			if b.ctx.Err() != nil {
+				b.logger.Warnw("Shutdown requested during startup phase", "phase", p.name, "error", err)
+				b.shutdown()
+				return errInterrupted
+			}
+			b.logger.Errorw("Startup phase failed", "phase", p.name, "error", err)
+			b.shutdown()
+			return fmt.Errorf("startup phase %q failed: %w", p.name, err)
+		}
+	}
+	b.finish()
+	return nil
+}
+
+// onShutdown registers fn to run at shutdown; cleanups run in reverse order of registration
+func (b *boot) onShutdown(name string, fn func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cleanup = append(b.cleanup, namedCleanup{name: name, fn: fn})
+}
+
+// shutdown runs the registered cleanups, newest first
+func (b *boot) shutdown() {
+	b.mu.Lock()
+	cleanup := b.cleanup
+	b.cleanup = nil
+	b.mu.Unlock()
+	for i := len(cleanup) - 1; i >= 0; i-- {
+		start := time.Now()
+		cleanup[i].fn()
+		b.logger.Infow("Stopped", "component", cleanup[i].name, "duration", time.Since(start))
+	}
+}
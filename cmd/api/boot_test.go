@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// recorder notes each phase run and each cleanup, in the order they happen
+type recorder struct {
+	b     *boot
+	calls []string
+}
+
+func newRecorder(ctx context.Context) *recorder {
+	return &recorder{b: newBoot(ctx, zap.NewNop().Sugar())}
+}
+
+// phase runs fn after recording its name, then registers a cleanup for it
+func (r *recorder) phase(name string, fn func() error) phase {
+	return phase{name: name, run: func(context.Context) error {
+		r.calls = append(r.calls, name)
+		if r.b.current() != name {
+			r.calls = append(r.calls, "current="+r.b.current())
+		}
+		if fn != nil {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+		r.b.onShutdown(name, func() { r.calls = append(r.calls, "stop "+name) })
+		return nil
+	}}
+}
+
+func TestPhaseOrder(t *testing.T) {
+	var names []string
+	for _, p := range (&app{}).phases() {
+		names = append(names, p.name)
+	}
+	want := []string{"config", "database", "migrations", "services", "broker", "http", "warmup", "consumer", "jobs", "ready"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("phases %v, want %v", names, want)
+	}
+}
+
+func TestBootRunsPhasesInOrder(t *testing.T) {
+	r := newRecorder(context.Background())
+	err := r.b.run([]phase{r.phase("config", nil), r.phase("database", nil), r.phase("ready", nil)})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if want := []string{"config", "database", "ready"}; !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("calls %v, want %v", r.calls, want)
+	}
+	if r.b.current() != "" {
+		t.Errorf("current phase %q after boot, want none", r.b.current())
+	}
+	// Nothing is stopped until shutdown, which goes newest first
+	r.calls = nil
+	r.b.shutdown()
+	if want := []string{"stop ready", "stop database", "stop config"}; !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("shutdown %v, want %v", r.calls, want)
+	}
+}
+
+// TestBootFailureShutsDown fails the third phase: the fourth never runs, what the
+// first two started is stopped newest first, and the error names the phase
+func TestBootFailureShutsDown(t *testing.T) {
+	r := newRecorder(context.Background())
+	refused := errors.New("connection refused")
+	err := r.b.run([]phase{
+		r.phase("config", nil),
+		r.phase("database", nil),
+		r.phase("broker", func() error { return refused }),
+		r.phase("http", nil),
+	})
+	if !errors.Is(err, refused) || errors.Is(err, errInterrupted) || !strings.Contains(err.Error(), `"broker"`) {
+		t.Fatalf("run: %v, want the broker phase's error", err)
+	}
+	if want := []string{"config", "database", "broker", "stop database", "stop config"}; !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("calls %v, want %v", r.calls, want)
+	}
+	// The cleanups ran once; a later shutdown has nothing left to stop
+	r.calls = nil
+	r.b.shutdown()
+	if len(r.calls) != 0 {
+		t.Errorf("second shutdown ran %v", r.calls)
+	}
+}
+
+// TestBootInterrupted signals shutdown during a phase: the next phase is skipped
+// and what had started is stopped
+func TestBootInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newRecorder(ctx)
+	err := r.b.run([]phase{
+		r.phase("config", nil),
+		r.phase("database", func() error { cancel(); return nil }),
+		r.phase("migrations", nil),
+	})
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("run: %v, want errInterrupted", err)
+	}
+	if want := []string{"config", "database", "stop database", "stop config"}; !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("calls %v, want %v", r.calls, want)
+	}
+}
+
+// TestBootFailureAfterSignal is a phase failing because shutdown was requested,
+// like a migration lock wait cut short: the boot counts as interrupted, not failed
+func TestBootFailureAfterSignal(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newRecorder(ctx)
+	err := r.b.run([]phase{
+		r.phase("config", nil),
+		{name: "migrations", run: func(ctx context.Context) error {
+			cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	})
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("run: %v, want errInterrupted", err)
+	}
+	if want := []string{"config", "stop config"}; !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("calls %v, want %v", r.calls, want)
+	}
+}
@@ -2,21 +2,40 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 
 	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/cache"
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/cursor"
 	"github.com/streamhive/video-catalog-api/internal/db"
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/flags"
+	"github.com/streamhive/video-catalog-api/internal/health"
+	"github.com/streamhive/video-catalog-api/internal/jobs"
+	"github.com/streamhive/video-catalog-api/internal/logging"
+	"github.com/streamhive/video-catalog-api/internal/models"
 	"github.com/streamhive/video-catalog-api/internal/queue"
+	"github.com/streamhive/video-catalog-api/internal/search"
 	"github.com/streamhive/video-catalog-api/internal/services"
+	"github.com/streamhive/video-catalog-api/internal/streaming"
+	"github.com/streamhive/video-catalog-api/internal/tracing"
+	"github.com/streamhive/video-catalog-api/internal/warmup"
 )
 
 func main() {
@@ -27,67 +46,710 @@ func main() {
 	}
 	defer logger.Sync()
 	sugar := logger.Sugar()
+	config.SetLogger(sugar)
 
-	// Initialize database
-	database, err := db.NewConnection()
+	// A signal during boot cancels bootCtx: the remaining phases are skipped and
+	// whatever already started is unwound. After boot it triggers the normal shutdown.
+	bootCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+	b := newBoot(bootCtx, sugar)
+
+	// Background jobs share a context cancelled on shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	a := &app{boot: b, logger: sugar, jobsCtx: jobsCtx, stopJobs: stopJobs}
+	if err := b.run(a.phases()); err != nil {
+		if errors.Is(err, errInterrupted) {
+			return
+		}
+		logger.Sync()
+		os.Exit(1)
+	}
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-bootCtx.Done()
+	stopSignals() // a second signal kills the process
+	sugar.Info("Shutting down server...")
+	a.ready.Store(false)
+	b.shutdown()
+
+	sugar.Info("Server exited")
+}
+
+// app holds what each startup phase builds for the ones after it
+type app struct {
+	boot     *boot
+	logger   *zap.SugaredLogger
+	jobsCtx  context.Context
+	stopJobs context.CancelFunc
+
+	cfg      *config.Config
+	database *gorm.DB
+	replica  *gorm.DB
+
+	storage          *services.StorageLoader
+	videos           *services.VideoService
+	progress         *services.WatchProgressService
+	dataExports      *services.DataExportService
+	contentDeletions *services.ContentDeletionService
+	quarantine       *services.EventQuarantineService
+	inboundArchive   *services.InboundEventArchive
+	outbox           *services.Outbox
+	jobRunner        *jobs.Runner
+	deps             api.Dependencies
+
+	consumer *queue.Consumer
+	sources  []queue.EventSource
+	warmup   *warmup.Runner
+	ready    atomic.Bool
+}
+
+// phases is the startup order: config -> database -> migrations -> services ->
+// broker -> http (not ready) -> warmup -> consumer -> jobs -> ready. Events are
+// only consumed once the schema is current and warmup has run.
+func (a *app) phases() []phase {
+	return []phase{
+		{name: "config", run: a.configure},
+		{name: "database", run: a.connectDatabase},
+		{name: "migrations", run: a.migrate},
+		{name: "services", run: a.buildServices},
+		{name: "broker", run: a.connectBroker},
+		{name: "http", run: a.serveHTTP},
+		{name: "warmup", run: a.warmUp},
+		{name: "consumer", run: a.startConsumers},
+		{name: "jobs", run: a.startJobs},
+		{name: "ready", run: a.markReady},
+	}
+}
+
+// configure loads and validates the settings and sets up tracing
+func (a *app) configure(ctx context.Context) error {
+	sugar := a.logger
+	// Settings are read once here and handed to each constructor; every missing or
+	// invalid variable is reported together
+	cfg, err := config.Load()
 	if err != nil {
-		sugar.Fatalf("Failed to connect to database: %v", err)
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := errors.Join(logging.SetContentPolicy(cfg.LogContentPolicy), models.SetTagStorageMode(cfg.TagsStorageMode)); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	// Run migrations
-	if err := db.RunMigrations(database); err != nil {
-		sugar.Fatalf("Failed to run migrations: %v", err)
+	// Caps for endpoints that stream large result sets
+	streaming.DefaultLimits = streaming.Limits{
+		MaxRows:    cfg.Streaming.MaxRows,
+		MaxBytes:   cfg.Streaming.MaxBytes,
+		FlushEvery: cfg.Streaming.FlushEvery,
 	}
 
-	// Initialize services
-	videoService := services.NewVideoService(database, sugar)
+	// OTLP trace export follows the standard OTEL_* variables and is off without an endpoint
+	shutdownTracing, exporting, err := tracing.Setup(ctx)
+	if err != nil {
+		return fmt.Errorf("configure tracing: %w", err)
+	}
+	a.boot.onShutdown("tracing", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			sugar.Warnw("Failed to flush traces", "error", err)
+		}
+	})
+	sugar.Infow("Tracing configured", "exporting", exporting, "service", tracing.ServiceName())
+	a.cfg = cfg
+	return nil
+}
+
+// connectDatabase opens the primary database and the optional read replica
+func (a *app) connectDatabase(ctx context.Context) error {
+	database, err := db.NewConnection(a.cfg.Database)
+	if err != nil {
+		return fmt.Errorf("connect to database: %w", err)
+	}
 
-	// Initialize RabbitMQ consumer
-	consumer, err := queue.NewConsumer(sugar)
+	// Optional read replica for comment listings; nil when DB_REPLICA_HOST is unset
+	replica, err := db.NewReplicaConnection(a.cfg.Database)
 	if err != nil {
-		sugar.Fatalf("Failed to initialize RabbitMQ consumer: %v", err)
+		return fmt.Errorf("connect to read replica: %w", err)
 	}
-	defer consumer.Close()
+	a.database, a.replica = database, replica
+	return nil
+}
 
-	// Start RabbitMQ consumer
-	go func() {
-		if err := consumer.StartConsuming(videoService); err != nil {
-			sugar.Errorf("RabbitMQ consumer error: %v", err)
+// migrate brings the schema up to date
+func (a *app) migrate(ctx context.Context) error {
+	// Replicas booting together take turns; each waits until the schema is current
+	migrateCtx, cancelMigrate := context.WithTimeout(ctx, a.cfg.Database.MigrationLockTimeout)
+	defer cancelMigrate()
+	// A signal while waiting for the lock is reported as an interrupted boot
+	if err := db.RunMigrationsLocked(migrateCtx, a.database); err != nil {
+		return fmt.Errorf("run migrations: %w", err)
+	}
+	return nil
+}
+
+// buildServices constructs the services and starts their background work
+func (a *app) buildServices(ctx context.Context) error {
+	cfg, sugar, database := a.cfg, a.logger, a.database
+	// Blob storage is optional unless STORAGE_BACKEND names it. A client that can't be
+	// built yet, say because the secrets volume isn't mounted, is retried with fresh
+	// secrets on later use; until then deletes only touch the database.
+	storage := services.NewStorageLoader(func() (services.StorageClient, error) {
+		return services.NewStorageClient(cfg.Storage.ReloadSecrets())
+	}, cfg.Storage.InitRetry, sugar)
+	if _, err := storage.Client(); err != nil && cfg.Storage.Explicit {
+		return fmt.Errorf("initialize storage client: %w", err)
+	}
+	videoService := services.NewVideoService(database, storage, cfg.Videos, sugar)
+	// The shared video cache is off without REDIS_URL
+	var videoCache *services.VideoCache
+	if cfg.Cache.RedisURL != "" {
+		redisCache, err := cache.NewRedis(cfg.Cache.RedisURL)
+		if err != nil {
+			return fmt.Errorf("configure redis cache: %w", err)
 		}
-	}()
+		a.boot.onShutdown("redis", func() {
+			if err := redisCache.Close(); err != nil {
+				sugar.Warnw("Failed to close redis client", "error", err)
+			}
+		})
+		videoCache = services.NewVideoCache(redisCache, cfg.Cache.TTL, sugar)
+		videoService.SetCache(videoCache)
+		sugar.Infow("Video cache enabled", "ttl", cfg.Cache.TTL)
+	}
+	// Videos are filed under managed categories rather than free text
+	categoryService := services.NewCategoryService(database, sugar)
+	videoService.SetCategories(categoryService)
+	commentService := services.NewCommentService(database, cfg.Comments, sugar)
+	commentService.SetReplica(a.replica)
+
+	// Content moderation runs asynchronously after writes and never blocks them
+	moderationProvider, err := services.NewModerationProvider(cfg.Moderation)
+	if err != nil {
+		return fmt.Errorf("configure moderation provider: %w", err)
+	}
+	moderationService := services.NewModerationService(database, sugar, moderationProvider,
+		cfg.Moderation.FlagThreshold, cfg.Moderation.HighSeverityThreshold, cfg.Moderation.MaxInflight)
+	videoService.SetModeration(moderationService)
+	commentService.SetModeration(moderationService)
+
+	// Comment notifications collapse into a rolling batch on high-velocity videos
+	notificationService := services.NewNotificationService(database, sugar,
+		cfg.Engagement.NotifyCollapseThreshold, cfg.Engagement.NotifyCollapseWindow)
+	commentService.SetNotifications(notificationService)
+
+	// Personal data exports: small ones stream, large ones run as jobs writing to the shared directory
+	dataExportService := services.NewDataExportService(database, sugar,
+		cfg.DataExport.Dir, cfg.DataExport.TTL, int64(cfg.DataExport.SyncMaxRows))
+	if err := dataExportService.Start(a.jobsCtx); err != nil {
+		return fmt.Errorf("start data export service: %w", err)
+	}
+	// Account deletion: videos go through the deletion jobs, comments are anonymized
+	contentDeletionService := services.NewContentDeletionService(database, sugar, videoService,
+		cfg.Jobs.ContentDeletionBatch)
+	if err := contentDeletionService.Start(a.jobsCtx); err != nil {
+		return fmt.Errorf("start content deletion service: %w", err)
+	}
+	counterService := services.NewCounterService(database, sugar)
+	tagMigrationService := services.NewTagMigrationService(database, sugar)
+
+	// Access log for non-public videos: buffered writes plus retention pruning
+	accessLogService := services.NewAccessLogService(database, sugar,
+		cfg.AccessLog.Size, cfg.AccessLog.Batch, cfg.AccessLog.Flush)
+	go accessLogService.Run(a.jobsCtx)
+	a.boot.onShutdown("access_log", func() {
+		a.stopJobs()
+		accessLogService.Wait()
+	})
+
+	// Search runs against OpenSearch when configured, falling back to the database
+	var searchIndex *services.SearchIndex
+	if cfg.Search.Backend == config.SearchBackendOpenSearch {
+		searchClient := search.NewClient(search.Config{
+			URL:      cfg.Search.URL,
+			Index:    cfg.Search.Index,
+			Username: cfg.Search.Username,
+			Password: cfg.Search.Password,
+			Timeout:  cfg.Search.Timeout,
+		}, nil)
+		ensureCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := searchClient.EnsureIndex(ensureCtx); err != nil {
+			sugar.Warnw("Failed to ensure search index; searches fall back to the database until it's reachable", "error", err)
+		}
+		cancel()
+		searchIndex = services.NewSearchIndex(database, searchClient, sugar,
+			cfg.Search.Indexing.Size, cfg.Search.Indexing.Batch, cfg.Search.Indexing.Flush)
+		videoService.SetSearchIndex(searchIndex)
+		go searchIndex.Run(a.jobsCtx)
+		a.boot.onShutdown("search_index", func() {
+			a.stopJobs()
+			searchIndex.Wait()
+		})
+	}
+
+	bundleService := services.NewSupportBundleService(database, sugar,
+		services.VideoRecordSection{},
+		services.StorageSection{
+			Storage:      storage,
+			CheckTimeout: cfg.Storage.CheckTimeout,
+		},
+		services.QuarantineSection{DB: database},
+		services.StatusHistorySection{DB: database},
+		services.CommentsSection{DB: database},
+		services.AccessLogSection{DB: database},
+		services.InboundEventsSection{DB: database},
+		services.ModerationSection{DB: database},
+		services.DeletionsSection{DB: database},
+		services.AuditSection{DB: database},
+	)
+
+	// Failed events are parked with their original envelope for ordered replay
+	quarantineService := services.NewEventQuarantineService(database, sugar, videoService)
+	// Every consumed message is archived with its outcome for inspection and
+	// reprocessing. The archive has its own context: it must keep recording while
+	// the consumer drains, which happens after background jobs stop.
+	inboundArchive := services.NewInboundEventArchive(database, sugar, videoService,
+		cfg.Events.Inbound.Size, cfg.Events.Inbound.Batch, cfg.Events.Inbound.Flush, cfg.Events.InboundMaxBody)
+	archiveCtx, stopArchive := context.WithCancel(context.Background())
+	go inboundArchive.Run(archiveCtx)
+	a.boot.onShutdown("inbound_events", func() {
+		stopArchive()
+		inboundArchive.Wait()
+	})
+
+	// Pagination cursors are HMAC-signed; replicas must share the secret
+	cursorSecret := cfg.Sessions.CursorSecret
+	if len(cursorSecret) == 0 {
+		cursorSecret = make([]byte, 32)
+		if _, err := rand.Read(cursorSecret); err != nil {
+			return fmt.Errorf("generate cursor secret: %w", err)
+		}
+		sugar.Warn("CURSOR_SECRET not set; cursors will not survive restarts or work across replicas")
+	}
+	cursorCodec := cursor.NewCodec(cursorSecret, cfg.Sessions.CursorTTL)
+
+	// After commenting, a user's comment reads for that video skip the replica for a while
+	recentWrites := cache.NewRecentWrites(cfg.Engagement.ReadYourWrites, cfg.Engagement.ReadYourWritesMax)
+
+	// Callers authenticate with a Bearer JWT; AUTH_MODE=header trusts X-User-ID as
+	// sent and is only for local development
+	authenticator, err := api.NewAuthenticator(api.AuthConfig{
+		Mode:         cfg.Auth.Mode,
+		Algorithm:    cfg.Auth.Algorithm,
+		Secret:       cfg.Auth.Secret,
+		PublicKeyPEM: cfg.Auth.PublicKeyPEM,
+		JWKSURL:      cfg.Auth.JWKSURL,
+		Issuer:       cfg.Auth.Issuer,
+		Audience:     cfg.Auth.Audience,
+		RolesClaim:   cfg.Auth.RolesClaim,
+		Leeway:       cfg.Auth.Leeway,
+	})
+	if err != nil {
+		return fmt.Errorf("configure authentication: %w", err)
+	}
+	if authenticator.Mode() == api.AuthModeHeader {
+		sugar.Warnw("AUTH_MODE=header: X-User-ID is trusted as sent; use only for local development")
+	}
+
+	// Admins may view the API as a user via X-Impersonate-User (read-only, audited)
+	auditService := services.NewAuditService(database, sugar)
+	impersonation := api.ImpersonationConfig{
+		Enabled: cfg.Auth.Impersonation,
+		Audit:   auditService,
+	}
+
+	// Per-caller request limits for the whole API, kept in memory on each replica
+	var rateLimits api.RateLimits
+	if cfg.HTTP.RateLimit.Enabled {
+		rateLimits = api.RateLimits{
+			Reads:  api.NewTokenBucketLimiter(cfg.HTTP.RateLimit.ReadsPerMin),
+			Writes: api.NewTokenBucketLimiter(cfg.HTTP.RateLimit.WritesPerMin),
+		}
+	}
+
+	// Logged-out engagement runs under signed anonymous sessions (anon:<id> identities)
+	anonSecret := cfg.Sessions.AnonSecret
+	if len(anonSecret) == 0 {
+		anonSecret = make([]byte, 32)
+		if _, err := rand.Read(anonSecret); err != nil {
+			return fmt.Errorf("generate anonymous session secret: %w", err)
+		}
+		sugar.Warn("ANON_SESSION_SECRET not set; anonymous sessions will not survive restarts or work across replicas")
+	}
+	anonymousService := services.NewAnonymousSessionService(database, sugar, anonSecret,
+		cfg.Sessions.AnonTTL, cfg.Sessions.AnonRetention, int64(cfg.Sessions.AnonMaxRows))
+
+	// Views by the same viewer within the dedup interval count once
+	viewService := services.NewViewService(database, sugar, cfg.Engagement.ViewDedupInterval)
+	viewService.SetAnonymousSessions(anonymousService)
+	viewService.SetCache(videoCache)
 
+	// Resume positions; reports that barely moved the position aren't written
+	progressService := services.NewWatchProgressService(database, sugar, cfg.Engagement.WatchProgressMinStep)
+	progressService.SetAnonymousSessions(anonymousService)
+
+	reactionService := services.NewReactionService(database, sugar)
+	reportService := services.NewReportService(database, videoService, sugar)
+
+	// Popular tags are recomputed at most once per interval; exact counts don't matter
+	tagService := services.NewTagService(database, sugar, cfg.Engagement.TagsCacheInterval, cfg.Engagement.TagsCacheMax)
+	publicEventLog := services.NewPublicEventLog(database, sugar)
+
+	// Thumbnails are resized on demand to a fixed set of widths and cached in memory
+	var thumbnailBlobs services.BlobDownloader
+	if blobs, ok := videoService.Storage().(services.BlobDownloader); ok && blobs != nil {
+		thumbnailBlobs = blobs
+	}
+	thumbnailService := services.NewThumbnailService(sugar, thumbnailBlobs,
+		cfg.Thumbnails.Widths, cfg.Thumbnails.Workers, cfg.Thumbnails.CacheSize, cfg.Thumbnails.FetchTimeout)
+
+	// Feature flags: code defaults, FLAG_<NAME> env overrides, and the feature_flags
+	// table, which every replica polls so runtime changes need no deploy
+	flagSet, err := flags.New(database, sugar, cfg.Jobs.FeatureFlagsPoll)
+	if err != nil {
+		return fmt.Errorf("configure feature flags: %w", err)
+	}
+	if err := flagSet.Load(a.jobsCtx); err != nil {
+		sugar.Warnw("Failed to load feature flags; using defaults and environment", "error", err)
+	}
+	flags.SetDefault(flagSet)
+	go flagSet.Run(a.jobsCtx)
+
+	// Periodic jobs run once per interval across all replicas (lease rows in job_runs)
+	jobRunner := jobs.NewRunner(database, sugar)
+	jobRunner.Register(jobs.Job{
+		Name:     "counter_repair",
+		Interval: cfg.Jobs.CounterRepairInterval,
+		Timeout:  15 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := counterService.RepairSample(ctx, cfg.Jobs.CounterRepairSample)
+			return err
+		},
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "access_log_prune",
+		Interval: 6 * time.Hour,
+		Timeout:  30 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := accessLogService.Prune(ctx, cfg.Jobs.AccessLogRetention)
+			return err
+		},
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "data_export_prune",
+		Interval: time.Hour,
+		Timeout:  10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := dataExportService.PruneExpired(ctx)
+			return err
+		},
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "anonymous_prune",
+		Interval: 6 * time.Hour,
+		Timeout:  30 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := anonymousService.PurgeExpired(ctx)
+			return err
+		},
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "view_dedup_prune",
+		Interval: 6 * time.Hour,
+		Timeout:  30 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := viewService.PruneDedupRows(ctx, cfg.Jobs.ViewDedupRetention)
+			return err
+		},
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "inbound_events_prune",
+		Interval: time.Hour,
+		Timeout:  10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := inboundArchive.Prune(ctx, cfg.Jobs.InboundEventRetention)
+			return err
+		},
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "processed_events_prune",
+		Interval: time.Hour,
+		Timeout:  10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := videoService.PruneProcessedEvents(ctx, cfg.Jobs.ProcessedEventRetention)
+			return err
+		},
+	})
+	idempotencyStore := services.NewIdempotencyStore(database, sugar, cfg.Jobs.IdempotencyKeyTTL)
+	jobRunner.Register(jobs.Job{
+		Name:     "idempotency_keys_prune",
+		Interval: time.Hour,
+		Timeout:  10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := idempotencyStore.Prune(ctx)
+			return err
+		},
+	})
+	// Deletion jobs purge a deleted video's files and row once its restore window
+	// (VIDEO_DELETE_GRACE) closes; storage cleanup never runs inside a request
+	deletionWorker := services.NewDeletionWorker(videoService, sugar,
+		cfg.Jobs.DeletionWorkers, cfg.Jobs.DeletionPollInterval, cfg.Jobs.DeletionTimeout)
+	videoService.Changes().Subscribe("deletion_worker", deletionWorker.Observe)
+	deletionCtx, stopDeletions := context.WithCancel(a.jobsCtx)
+	deletionWorker.Run(deletionCtx)
+	a.boot.onShutdown("deletion_worker", func() {
+		stopDeletions()
+		deletionWorker.Wait()
+	})
+	// Videos soft-deleted before deletion jobs existed get one once expired
+	jobRunner.Register(jobs.Job{
+		Name:     "video_purge",
+		Interval: cfg.Jobs.PurgeInterval,
+		Timeout:  5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := videoService.QueueExpiredDeletions(ctx, cfg.Jobs.PurgeBatch)
+			return err
+		},
+	})
+	// catalog_videos{status} is refreshed on every replica, not once per deployment
+	go videoService.RunStatusMetrics(a.jobsCtx, cfg.Jobs.StatusMetrics)
+	// Transactional outbox: messages committed with their writes, published by a
+	// dispatcher on every replica
+	outbox := services.NewOutbox(database, sugar, services.OutboxConfig{
+		PollInterval:   cfg.Outbox.PollInterval,
+		BatchSize:      cfg.Outbox.BatchSize,
+		MaxAttempts:    cfg.Outbox.MaxAttempts,
+		BackoffMin:     cfg.Outbox.BackoffMin,
+		BackoffMax:     cfg.Outbox.BackoffMax,
+		PublishTimeout: cfg.AMQP.PublishConfirmTimeout,
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "outbox_prune",
+		Interval: time.Hour,
+		Timeout:  10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := outbox.PruneSent(ctx, cfg.Outbox.Retention)
+			return err
+		},
+	})
+
+	// Handed to the router in the http phase
+	a.deps = api.Dependencies{
+		Videos:            videoService,
+		Comments:          commentService,
+		Counters:          counterService,
+		Bundles:           bundleService,
+		Moderation:        moderationService,
+		AccessLog:         accessLogService,
+		TagMigrations:     tagMigrationService,
+		Notifications:     notificationService,
+		DataExports:       dataExportService,
+		Quarantine:        quarantineService,
+		InboundEvents:     inboundArchive,
+		Jobs:              jobRunner,
+		Cursors:           cursorCodec,
+		RecentWrites:      recentWrites,
+		Audit:             auditService,
+		Anonymous:         anonymousService,
+		Views:             viewService,
+		Thumbnails:        thumbnailService,
+		Reactions:         reactionService,
+		EventLog:          publicEventLog,
+		Tags:              tagService,
+		Categories:        categoryService,
+		UserContent:       contentDeletionService,
+		Reports:           reportService,
+		Progress:          progressService,
+		Search:            searchIndex,
+		Auth:              authenticator,
+		Impersonation:     impersonation,
+		RateLimits:        rateLimits,
+		Idempotency:       idempotencyStore,
+		MaxBodyBytes:      cfg.HTTP.MaxBodyBytes,
+		MaxThumbnailBytes: cfg.HTTP.MaxThumbnailBytes,
+	}
+	a.storage, a.videos, a.progress = storage, videoService, progressService
+	a.dataExports, a.contentDeletions = dataExportService, contentDeletionService
+	a.quarantine, a.inboundArchive, a.outbox, a.jobRunner = quarantineService, inboundArchive, outbox, jobRunner
+	return nil
+}
+
+// connectBroker connects to RabbitMQ and, with EVENT_BUS=kafka or both, Kafka; consuming starts later
+func (a *app) connectBroker(ctx context.Context) error {
+	cfg, sugar := a.cfg, a.logger
+	// Connect to RabbitMQ and declare queues; consuming starts after warmup
+	consumer, err := queue.NewConsumer(cfg.AMQP, sugar)
+	if err != nil {
+		return fmt.Errorf("initialize RabbitMQ consumer: %w", err)
+	}
+	// Cleanups run newest first, so this drains after the HTTP server, the outbox and
+	// the publisher have stopped; events handled meanwhile leave their outbox rows for
+	// the next dispatcher
+	a.boot.onShutdown("consumer", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.AMQP.ShutdownTimeout)
+		defer cancel()
+		if err := consumer.Stop(ctx); err != nil {
+			sugar.Warnw("Consumer stopped with events in flight", "error", err)
+		}
+	})
+	// Reconnect backoff after a broker restart or network drop
+	consumer.SetReconnectBackoff(cfg.AMQP.ReconnectMin, cfg.AMQP.ReconnectMax)
+	consumer.SetConcurrency(cfg.AMQP.Prefetch, cfg.AMQP.Workers)
+	consumer.SetDataExports(a.dataExports)
+	consumer.SetContentDeletions(a.contentDeletions)
+	consumer.SetQuarantine(a.quarantine)
+	if cfg.Events.Archive {
+		consumer.SetArchive(a.inboundArchive)
+	}
+	consumer.SetStrictFields(cfg.Events.StrictFields)
+	// With EVENT_BUS=kafka or both, video events are also read from a Kafka consumer
+	// group; user events and everything published stay on RabbitMQ
+	sources := []queue.EventSource{consumer}
+	if cfg.EventBus != config.EventBusRabbitMQ {
+		kafkaSource := queue.NewKafkaSource(cfg.Kafka, sugar)
+		kafkaSource.SetQuarantine(a.quarantine)
+		if cfg.Events.Archive {
+			kafkaSource.SetArchive(a.inboundArchive)
+		}
+		a.boot.onShutdown("kafka", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Kafka.ShutdownTimeout)
+			defer cancel()
+			if err := kafkaSource.Stop(ctx); err != nil {
+				sugar.Warnw("Kafka source stopped with events in flight", "error", err)
+			}
+		})
+		sources = append(sources, kafkaSource)
+	}
+	// Outbound messages go over a confirm-mode channel on the consumer's connection
+	publisher := consumer.NewPublisher()
+	publisher.SetConfirmTimeout(cfg.AMQP.PublishConfirmTimeout)
+	a.boot.onShutdown("publisher", publisher.Close)
+	// Downstream edge caches get one video.cache.invalidate per video per window
+	if cfg.Events.CacheInvalidation {
+		invalidator := services.NewCacheInvalidator(publisher, sugar,
+			cfg.AMQP.CacheInvalidateRoutingKey, cfg.Events.CacheInvalidateWindow)
+		a.videos.Changes().Subscribe("cache_invalidation", invalidator.Observe)
+		a.boot.onShutdown("cache_invalidation", invalidator.Stop)
+	}
+	// video.watch.milestone for recommendations, published as saved progress crosses 25/50/75/95%
+	a.progress.SetMilestones(services.NewWatchMilestones(publisher, sugar,
+		cfg.AMQP.WatchMilestoneRoutingKey, cfg.Events.WatchMilestones))
+	// catalog.video.updated / catalog.video.deleted for recommendations and search,
+	// enqueued in each video write's transaction
+	if cfg.Events.Catalog {
+		a.videos.SetOutbox(a.outbox)
+	}
+	// Runs before the publisher closes on shutdown; unsent messages wait in the table
+	outboxCtx, stopOutbox := context.WithCancel(a.jobsCtx)
+	go a.outbox.Run(outboxCtx, publisher)
+	a.videos.Changes().Subscribe("outbox", a.outbox.Observe)
+	a.boot.onShutdown("outbox", func() {
+		stopOutbox()
+		a.outbox.Wait()
+	})
+	a.consumer, a.sources = consumer, sources
+	return nil
+}
+
+// serveHTTP builds the router and starts serving; /readyz stays 503 until ready
+func (a *app) serveHTTP(ctx context.Context) error {
+	cfg, sugar := a.cfg, a.logger
 	// Initialize Gin router
 	router := gin.New()
-	router.Use(gin.Logger())
+	router.Use(api.RequestID())
+	// Probes and scrapes would drown out real traffic in the trace backend
+	router.Use(otelgin.Middleware(tracing.ServiceName(), otelgin.WithFilter(func(r *http.Request) bool {
+		switch r.URL.Path {
+		case "/health", "/readyz", "/metrics":
+			return false
+		}
+		return true
+	})))
+	router.Use(api.AccessLog())
+	router.Use(api.RequestMetrics())
 	router.Use(gin.Recovery())
 
-	// CORS middleware
-	router.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-User-ID")
+	router.Use(api.CORS(router, api.CORSConfig{
+		AllowedOrigins:   cfg.HTTP.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.HTTP.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.HTTP.CORS.AllowedHeaders,
+		AllowCredentials: cfg.HTTP.CORS.AllowCredentials,
+		MaxAge:           cfg.HTTP.CORS.MaxAge,
+	}))
+	router.Use(api.Compress(int(cfg.HTTP.CompressMinSize)))
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+	// Liveness endpoint: always 200 while the process serves HTTP, so a dependency
+	// outage never gets the pod restarted; it reports degraded while the consumer
+	// is reconnecting. Dependency failures take the pod out of rotation via /readyz.
+	router.GET("/health", func(c *gin.Context) {
+		if state := a.consumer.State(); state != queue.StateConnected {
+			c.JSON(http.StatusOK, gin.H{"status": "degraded", "amqp": state})
 			return
 		}
+		c.JSON(http.StatusOK, gin.H{"status": "healthy", "amqp": queue.StateConnected})
+	})
+
+	// Warmup verifies dependencies and touches hot indexes before events are
+	// consumed and before the gateway starts routing traffic here
+	warmupSteps := []warmup.Step{
+		{Name: "database", Run: func(ctx context.Context) error { return db.Ping(ctx, a.database) }},
+		{Name: "amqp", Run: func(ctx context.Context) error {
+			if !a.consumer.IsConnected() {
+				return fmt.Errorf("amqp connection closed")
+			}
+			return nil
+		}},
+	}
+	for _, check := range db.IndexSelfChecks {
+		check := check
+		warmupSteps = append(warmupSteps, warmup.Step{
+			Name: "index:" + check.Name,
+			Run:  func(ctx context.Context) error { return db.RunSelfCheck(ctx, a.database, check) },
+		})
+	}
+	a.warmup = warmup.NewRunner(sugar, warmupSteps...)
+
+	// Once started, every readiness probe re-checks what requests depend on; storage
+	// is reported but optional, and only probed when READYZ_CHECK_STORAGE is set
+	readiness := health.NewChecker(cfg.HTTP.ReadyzTimeout,
+		health.Database(a.database),
+		health.AMQP(a.consumer),
+		health.Storage(a.storage, cfg.HTTP.ReadyzCheckStorage))
 
-		c.Next()
+	// Readiness endpoint: 503 until every startup phase has finished, then 503
+	// whenever a required dependency check fails
+	router.GET("/readyz", func(c *gin.Context) {
+		if !a.ready.Load() {
+			phase := a.boot.current()
+			status := "starting"
+			if phase == "warmup" {
+				status = "warming_up"
+			}
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": status, "phase": phase})
+			return
+		}
+		readiness.ServeHTTP(c.Writer, c.Request)
 	})
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+	// Detailed internal status
+	router.GET("/internal/status", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"ready":  a.ready.Load(),
+			"phase":  a.boot.current(),
+			"warmup": a.warmup.Report(),
+			// Producer-to-catalog latency over recent events, per kind and stage
+			"event_latency": events.RecentLatencies(),
+			"feature_flags": flags.Snapshot(),
+		})
 	})
 
 	// Metrics endpoint
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// API routes
-	api.SetupRoutes(router, videoService, sugar)
+	api.SetupRoutes(router, a.deps, sugar)
 
-	// Get port from environment or use default
-	port := getEnv("PORT", "8080")
+	port := cfg.HTTP.Port
 
 	// Create HTTP server
 	srv := &http.Server{
@@ -95,34 +757,61 @@ func main() {
 		Handler: router,
 	}
 
-	// Start server in a goroutine
+	// Serve from here on so probes work during warmup; /readyz stays 503 until boot completes
 	go func() {
 		sugar.Infow("Starting server", "port", port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			sugar.Fatalf("Failed to start server: %v", err)
 		}
 	}()
+	a.boot.onShutdown("http", func() {
+		// Give outstanding requests a deadline for completion
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			sugar.Errorw("Server forced to shutdown", "error", err)
+		}
+	})
+	return nil
+}
 
-	// Wait for interrupt signal to gracefully shutdown the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	sugar.Info("Shutting down server...")
-
-	// Give outstanding requests a deadline for completion
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// warmUp checks dependencies and touches hot indexes
+func (a *app) warmUp(ctx context.Context) error {
+	a.warmup.Run(ctx, a.cfg.WarmupDeadline)
+	return nil
+}
 
-	if err := srv.Shutdown(ctx); err != nil {
-		sugar.Fatalf("Server forced to shutdown: %v", err)
+// startConsumers starts consuming events from every source
+func (a *app) startConsumers(ctx context.Context) error {
+	for _, source := range a.sources {
+		handlers := queue.VideoEventHandlers(a.videos)
+		// RabbitMQ then only carries user events
+		if source.Name() == queue.SourceRabbitMQ && a.cfg.EventBus == config.EventBusKafka {
+			handlers = queue.EventHandlers{}
+		}
+		go func(source queue.EventSource) {
+			if err := source.Start(context.Background(), handlers); err != nil {
+				a.logger.Errorw("Event source stopped", "source", source.Name(), "error", err)
+			}
+		}(source)
 	}
-
-	sugar.Info("Server exited")
+	return nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// startJobs starts the periodic jobs
+func (a *app) startJobs(ctx context.Context) error {
+	if err := a.jobRunner.Start(a.jobsCtx); err != nil {
+		return fmt.Errorf("start background jobs: %w", err)
 	}
-	return defaultValue
+	a.boot.onShutdown("jobs", func() {
+		a.stopJobs()
+		a.jobRunner.Wait()
+	})
+	return nil
+}
+
+// markReady opens /readyz to traffic
+func (a *app) markReady(ctx context.Context) error {
+	a.ready.Store(true)
+	return nil
 }
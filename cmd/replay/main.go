@@ -0,0 +1,113 @@
+// Command replay re-applies quarantined catalog events. Replays that would move a
+// video's state backwards are refused unless --force is given.
+//
+//	replay --id 42                        replay one quarantined event
+//	replay --upload-id abc [--force]      replay all events for an upload in original order
+//	replay --import-dlq video-catalog.dlq move dead-lettered messages into quarantine
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/db"
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/logging"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/queue"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func main() {
+	id := flag.Uint("id", 0, "quarantined event ID to replay")
+	uploadID := flag.String("upload-id", "", "replay every quarantined event for this upload, oldest first")
+	force := flag.Bool("force", false, "apply even if the replay would move state backwards")
+	importDLQ := flag.String("import-dlq", "", "dead-letter queue to drain into quarantine")
+	flag.Parse()
+
+	if *id == 0 && *uploadID == "" && *importDLQ == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer logger.Sync()
+	sugar := logger.Sugar()
+	config.SetLogger(sugar)
+
+	cfg, err := config.Load()
+	if err != nil {
+		sugar.Fatalf("Invalid configuration:\n%v", err)
+	}
+	// Match the API process so replays write the same way live handling does
+	if err := errors.Join(logging.SetContentPolicy(cfg.LogContentPolicy), models.SetTagStorageMode(cfg.TagsStorageMode)); err != nil {
+		sugar.Fatalf("Invalid configuration:\n%v", err)
+	}
+	database, err := db.NewConnection(cfg.Database)
+	if err != nil {
+		sugar.Fatalf("Failed to connect to database: %v", err)
+	}
+	storage := services.NewStorageLoader(func() (services.StorageClient, error) {
+		return services.NewStorageClient(cfg.Storage)
+	}, time.Minute, sugar)
+	quarantine := services.NewEventQuarantineService(database, sugar, services.NewVideoService(database, storage, cfg.Videos, sugar))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch {
+	case *importDLQ != "":
+		consumer, err := queue.NewConsumer(cfg.AMQP, sugar)
+		if err != nil {
+			sugar.Fatalf("Failed to connect to RabbitMQ: %v", err)
+		}
+		defer consumer.Close()
+		consumer.SetQuarantine(quarantine)
+		n, err := consumer.ImportDeadLetters(ctx, *importDLQ)
+		fmt.Printf("imported %d message(s) from %s\n", n, *importDLQ)
+		if err != nil {
+			sugar.Fatalf("Import stopped: %v", err)
+		}
+	case *id != 0:
+		event, err := quarantine.Replay(ctx, uint(*id), *force)
+		printJSON(event)
+		exitOnReplayError(err)
+	default:
+		replayed, err := quarantine.ReplayUpload(ctx, *uploadID, *force)
+		printJSON(replayed)
+		exitOnReplayError(err)
+	}
+}
+
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func exitOnReplayError(err error) {
+	if err == nil {
+		return
+	}
+	var rejected *events.ReplayRejectedError
+	if errors.As(err, &rejected) {
+		fmt.Fprintf(os.Stderr, "%v\nre-run with --force to apply anyway\n", err)
+		os.Exit(3)
+	}
+	fmt.Fprintf(os.Stderr, "replay failed: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// GetAccessLog handles GET /api/v1/videos/:id/access-log (owner only)
+func (h *VideoHandler) GetAccessLog(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrVideoNotFound) {
+			respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to get video", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get video", nil)
+		return
+	}
+	if video.UserID != requester {
+		respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 200 {
+		perPage = 50
+	}
+
+	entries, total, err := h.accessLog.List(c.Request.Context(), uint(id), c.Query("viewer"), page, perPage)
+	if err != nil {
+		h.log(c).Errorw("Failed to list access log", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list access log", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":     entries,
+		"total":       total,
+		"page":        page,
+		"per_page":    perPage,
+		"total_pages": (int(total) + perPage - 1) / perPage,
+	})
+}
+
+// recordAccess logs a non-owner access to a non-public video. An impersonated view
+// is attributed to the admin, not to the user being impersonated, and a signed-out
+// share link viewer to a digest of the link's token.
+func (h *VideoHandler) recordAccess(c *gin.Context, video *models.Video, viewerID, endpoint string) {
+	viewerType := models.ViewerUser
+	if id := identityFrom(c); id.Impersonating {
+		viewerID, viewerType = id.ActorID, models.ViewerSupport
+	} else if token := c.Query("token"); viewerID != video.UserID && video.ShareTokenMatches(token) {
+		viewerType = models.ViewerShareToken
+		if viewerID == "" {
+			viewerID = models.ShareTokenViewer(token)
+		}
+	} else if viewerID == "" {
+		viewerID, viewerType = "anonymous", models.ViewerAnonymous
+	}
+	h.accessLog.Record(video, viewerID, viewerType, endpoint, clientPlatform(c))
+}
+
+// clientPlatform prefers the explicit X-Client-Platform header and otherwise
+// derives a coarse platform from the User-Agent
+func clientPlatform(c *gin.Context) string {
+	if p := c.GetHeader("X-Client-Platform"); p != "" {
+		if len(p) > 40 {
+			p = p[:40]
+		}
+		return strings.ToLower(p)
+	}
+	ua := strings.ToLower(c.GetHeader("User-Agent"))
+	switch {
+	case strings.Contains(ua, "android"):
+		return "android"
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ios"):
+		return "ios"
+	case strings.Contains(ua, "mozilla"):
+		return "web"
+	case ua == "":
+		return "unknown"
+	default:
+		return "other"
+	}
+}
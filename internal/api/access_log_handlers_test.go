@@ -0,0 +1,82 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestAccessLogViewerIdentity(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	accessLog := services.NewAccessLogService(db, log, 100, 100, time.Hour)
+	ctx, stop := context.WithCancel(context.Background())
+	go accessLog.Run(ctx)
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+		AccessLog: accessLog,
+	})
+
+	const token = "s3cret-share-token"
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t", Visibility: models.VisibilityUnlisted, ShareToken: token}
+	db.Create(&video)
+	path := "/api/v1/videos/" + itoa(video.ID)
+
+	requests := []struct {
+		name  string
+		query string
+		user  string
+	}{
+		{"signed-out link viewer", "?token=" + token, ""},
+		{"signed-in link viewer", "?token=" + token, "bob"},
+		{"owner", "?token=" + token, "owner"},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(http.MethodGet, path+r.query, nil)
+		if r.user != "" {
+			req.Header.Set("X-User-ID", r.user)
+		}
+		if w := serve(router, req); w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", r.name, w.Code, w.Body)
+		}
+	}
+	if w := serve(router, httptest.NewRequest(http.MethodGet, path, nil)); w.Code != http.StatusNotFound {
+		t.Fatalf("without the token: status %d, want 404", w.Code)
+	}
+	stop()
+	accessLog.Wait()
+
+	var entries []models.VideoAccessLog
+	db.Order("id").Find(&entries)
+	if len(entries) != 2 {
+		t.Fatalf("entries = %+v, want the two non-owner views", entries)
+	}
+	anon, user := entries[0], entries[1]
+	if anon.ViewerType != models.ViewerShareToken || anon.ViewerID != models.ShareTokenViewer(token) {
+		t.Errorf("signed-out entry = %s/%s, want share_token/%s", anon.ViewerType, anon.ViewerID, models.ShareTokenViewer(token))
+	}
+	if strings.Contains(anon.ViewerID, token) || anon.ViewerID == "anonymous" {
+		t.Errorf("signed-out viewer stored as %q", anon.ViewerID)
+	}
+	if user.ViewerType != models.ViewerShareToken || user.ViewerID != "bob" {
+		t.Errorf("signed-in entry = %s/%s, want share_token/bob", user.ViewerType, user.ViewerID)
+	}
+}
+
+func TestShareTokenViewerStable(t *testing.T) {
+	a, b := models.ShareTokenViewer("one"), models.ShareTokenViewer("two")
+	if a != models.ShareTokenViewer("one") || a == b || !strings.HasPrefix(a, "share:") {
+		t.Errorf("ShareTokenViewer gave %q and %q", a, b)
+	}
+}
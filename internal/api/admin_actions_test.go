@@ -0,0 +1,246 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func adminRouter(t *testing.T) (*gorm.DB, http.Handler) {
+	t.Helper()
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	return db, newRouter(api.Dependencies{
+		Videos:   services.NewVideoService(db, nil, videoSettings, log),
+		Comments: services.NewCommentService(db, commentSettings, log),
+		Audit:    services.NewAuditService(db, log),
+	})
+}
+
+// TestAdminRoutesRequireAdmin sends every admin route without a user and from
+// users without the admin role: nothing changes and nothing is audited
+func TestAdminRoutesRequireAdmin(t *testing.T) {
+	db, router := adminRouter(t)
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Status: models.StatusProcessing}
+	db.Create(&video)
+	comment := models.Comment{VideoID: video.ID, UserID: "author", Content: "x", Status: models.CommentVisible}
+	db.Create(&comment)
+
+	routes := []struct{ method, path, body string }{
+		{http.MethodGet, "/api/v1/admin/videos", ""},
+		{http.MethodDelete, "/api/v1/admin/videos/" + itoa(video.ID), ""},
+		{http.MethodPatch, "/api/v1/admin/videos/" + itoa(video.ID) + "/status", `{"status":"failed"}`},
+		{http.MethodDelete, "/api/v1/admin/comments/" + itoa(comment.ID), ""},
+		{http.MethodGet, "/api/v1/admin/audit", ""},
+	}
+	callers := []struct {
+		name, user, roles string
+		status            int
+	}{
+		{"anonymous", "", "", http.StatusUnauthorized},
+		{"no roles", "owner", "", http.StatusForbidden},
+		{"other roles", "mod-1", "user,moderator", http.StatusForbidden},
+		{"role prefix", "mod-1", "administrator", http.StatusForbidden},
+	}
+	for _, route := range routes {
+		for _, caller := range callers {
+			w := serve(router, adminRequest(route.method, route.path, route.body, caller.user, caller.roles))
+			if w.Code != caller.status {
+				t.Errorf("%s %s as %s: status %d, want %d", route.method, route.path, caller.name, w.Code, caller.status)
+			}
+		}
+	}
+
+	var got models.Video
+	if err := db.First(&got, video.ID).Error; err != nil || got.Status != models.StatusProcessing {
+		t.Errorf("video after refused requests: %+v, %v", got, err)
+	}
+	if err := db.First(&models.Comment{}, comment.ID).Error; err != nil {
+		t.Errorf("comment after refused requests: %v", err)
+	}
+	var audits int64
+	db.Model(&models.AuditLog{}).Count(&audits)
+	if audits != 0 {
+		t.Errorf("%d audit entries for refused requests", audits)
+	}
+}
+
+// TestAdminActionsAudited runs each admin action and checks the entry it leaves
+func TestAdminActionsAudited(t *testing.T) {
+	db, router := adminRouter(t)
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Status: models.StatusProcessing}
+	doomed := models.Video{UploadID: "up-2", UserID: "owner-2", Title: "t"}
+	db.Create(&video)
+	db.Create(&doomed)
+	comment := models.Comment{VideoID: video.ID, UserID: "author", Content: "x", Status: models.CommentVisible}
+	db.Create(&comment)
+
+	steps := []struct {
+		name         string
+		method, path string
+		body         string
+		status       int
+		want         models.AuditLog // zero Action: no entry
+	}{
+		{"set status", http.MethodPatch, "/api/v1/admin/videos/" + itoa(video.ID) + "/status", `{"status":"failed","reason":"stuck"}`, http.StatusOK,
+			models.AuditLog{Action: models.AuditActionSetStatus, SubjectID: "owner", Target: "video:" + itoa(video.ID),
+				Detail: "status processing -> failed: stuck", Route: "/api/v1/admin/videos/:id/status", Outcome: models.AuditOutcomeSucceeded}},
+		{"disallowed transition", http.MethodPatch, "/api/v1/admin/videos/" + itoa(video.ID) + "/status", `{"status":"ready"}`, http.StatusConflict,
+			models.AuditLog{Action: models.AuditActionSetStatus, SubjectID: "owner", Target: "video:" + itoa(video.ID),
+				Detail: "status failed -> ready", Route: "/api/v1/admin/videos/:id/status", Outcome: models.AuditOutcomeFailed}},
+		{"delete comment", http.MethodDelete, "/api/v1/admin/comments/" + itoa(comment.ID), "", http.StatusOK,
+			models.AuditLog{Action: models.AuditActionDeleteComment, SubjectID: "author", Target: "comment:" + itoa(comment.ID),
+				Detail: "on video " + itoa(video.ID), Route: "/api/v1/admin/comments/:commentID", Outcome: models.AuditOutcomeSucceeded}},
+		{"delete video", http.MethodDelete, "/api/v1/admin/videos/" + itoa(doomed.ID), "", http.StatusAccepted,
+			models.AuditLog{Action: models.AuditActionDeleteVideo, SubjectID: "owner-2", Target: "video:" + itoa(doomed.ID),
+				Route: "/api/v1/admin/videos/:id", Outcome: models.AuditOutcomeSucceeded}},
+		{"missing video", http.MethodDelete, "/api/v1/admin/videos/999", "", http.StatusNotFound, models.AuditLog{}},
+		{"missing comment", http.MethodDelete, "/api/v1/admin/comments/999", "", http.StatusNotFound, models.AuditLog{}},
+		{"invalid status", http.MethodPatch, "/api/v1/admin/videos/" + itoa(video.ID) + "/status", `{"status":"gone"}`, http.StatusBadRequest, models.AuditLog{}},
+	}
+	for _, step := range steps {
+		var before models.AuditLog
+		db.Order("id DESC").Limit(1).Find(&before)
+
+		req := adminRequest(step.method, step.path, step.body, "admin-1", "admin")
+		w := serve(router, req)
+		if w.Code != step.status {
+			t.Errorf("%s: status %d, want %d: %s", step.name, w.Code, step.status, w.Body)
+			continue
+		}
+		var entries []models.AuditLog
+		db.Where("id > ?", before.ID).Find(&entries)
+		if step.want.Action == "" {
+			if len(entries) != 0 {
+				t.Errorf("%s: audited %+v, want no entry", step.name, entries)
+			}
+			continue
+		}
+		if len(entries) != 1 {
+			t.Errorf("%s: %d audit entries, want 1", step.name, len(entries))
+			continue
+		}
+		got, want := entries[0], step.want
+		if got.Action != want.Action || got.ActorID != "admin-1" || got.SubjectID != want.SubjectID || got.Target != want.Target ||
+			got.Detail != want.Detail || got.Method != step.method || got.Route != want.Route || got.Path != step.path || got.Outcome != want.Outcome {
+			t.Errorf("%s: audit entry %+v, want %+v", step.name, got, want)
+		}
+	}
+
+	var got models.Video
+	db.First(&got, video.ID)
+	if got.Status != models.StatusFailed {
+		t.Errorf("video status %s, want failed", got.Status)
+	}
+	if err := db.First(&models.Comment{}, comment.ID).Error; err == nil {
+		t.Error("comment still visible after the admin deleted it")
+	}
+	if err := db.First(&models.Video{}, doomed.ID).Error; err == nil {
+		t.Error("video still listed after the admin deleted it")
+	}
+
+	// The entries can be read back by filter
+	w := serve(router, adminRequest(http.MethodGet, "/api/v1/admin/audit?subject=owner&action="+models.AuditActionSetStatus, "", "admin-1", "admin"))
+	var page struct {
+		Entries []models.AuditLog `json:"entries"`
+		Total   int64             `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("audit list: status %d: %s", w.Code, w.Body)
+	}
+	if page.Total != 2 || len(page.Entries) != 2 {
+		t.Errorf("audit list = %+v, want the two status changes", page)
+	}
+}
+
+// TestAdminActionWithoutAudit refuses to act when the audit entry can't be written
+func TestAdminActionWithoutAudit(t *testing.T) {
+	db, router := adminRouter(t)
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Status: models.StatusProcessing}
+	db.Create(&video)
+	comment := models.Comment{VideoID: video.ID, UserID: "author", Content: "x", Status: models.CommentVisible}
+	db.Create(&comment)
+	if err := db.Migrator().DropTable(&models.AuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, route := range []struct{ method, path, body string }{
+		{http.MethodDelete, "/api/v1/admin/videos/" + itoa(video.ID), ""},
+		{http.MethodPatch, "/api/v1/admin/videos/" + itoa(video.ID) + "/status", `{"status":"failed"}`},
+		{http.MethodDelete, "/api/v1/admin/comments/" + itoa(comment.ID), ""},
+	} {
+		w := serve(router, adminRequest(route.method, route.path, route.body, "admin-1", "admin"))
+		if w.Code != http.StatusServiceUnavailable || errorCode(w) != api.CodeAuditUnavailable {
+			t.Errorf("%s %s: status %d: %s, want 503 %s", route.method, route.path, w.Code, w.Body, api.CodeAuditUnavailable)
+		}
+	}
+	var got models.Video
+	if err := db.First(&got, video.ID).Error; err != nil || got.Status != models.StatusProcessing {
+		t.Errorf("video after unaudited requests: %+v, %v", got, err)
+	}
+	if err := db.First(&models.Comment{}, comment.ID).Error; err != nil {
+		t.Errorf("comment after unaudited requests: %v", err)
+	}
+}
+
+func TestAdminListVideos(t *testing.T) {
+	db, router := adminRouter(t)
+	for _, v := range []models.Video{
+		{UploadID: "up-1", UserID: "owner", Title: "public"},
+		{UploadID: "up-2", UserID: "owner", Title: "private", Visibility: models.VisibilityPrivate},
+		{UploadID: "up-3", UserID: "other", Title: "other"},
+		{UploadID: "up-4", UserID: "owner", Title: "deleted"},
+	} {
+		db.Create(&v)
+		if v.Title == "deleted" {
+			db.Delete(&v)
+		}
+	}
+
+	tests := []struct {
+		query  string
+		status int
+		titles []string
+	}{
+		{"?user_id=owner&sort=title&order=asc", http.StatusOK, []string{"private", "public"}},
+		{"?user_id=owner&deleted=include&sort=title&order=asc", http.StatusOK, []string{"deleted", "private", "public"}},
+		{"?deleted=only", http.StatusOK, []string{"deleted"}},
+		{"?deleted=maybe", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		w := serve(router, adminRequest(http.MethodGet, "/api/v1/admin/videos"+tt.query, "", "admin-1", "admin"))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.query, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var page models.VideoListResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, v := range page.Videos {
+			titles = append(titles, v.Title)
+		}
+		if len(titles) != len(tt.titles) || page.Total != int64(len(tt.titles)) {
+			t.Errorf("%s: %v, want %v", tt.query, titles, tt.titles)
+			continue
+		}
+		for i := range titles {
+			if titles[i] != tt.titles[i] {
+				t.Errorf("%s: %v, want %v", tt.query, titles, tt.titles)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestAdminRetryDeletion(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos: services.NewVideoService(db, nil, videoSettings, log),
+		Audit:  services.NewAuditService(db, log),
+	})
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t"}
+	db.Create(&video)
+	live := models.Video{UploadID: "up-2", UserID: "owner", Title: "t"}
+	db.Create(&live)
+	job := models.VideoDeletion{VideoID: video.ID, UserID: "owner", ActorID: "owner", Status: models.DeletionFailed,
+		RunAfter: time.Now().UTC().Add(-time.Hour), Error: "1 of 5 storage paths not deleted"}
+	db.Create(&job)
+	db.Create(&models.DeletionItem{DeletionID: job.ID, VideoID: video.ID, Kind: models.DeletionItemFile,
+		Path: "raw/owner/up-1.mp4", Status: models.DeletionItemFailed, Attempts: 1, Error: "storage timeout"})
+	path := "/api/v1/admin/videos/" + itoa(video.ID) + "/deletion/retry"
+
+	if w := serve(router, adminRequest(http.MethodPost, path, "", "owner", "user")); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: status %d, want 403", w.Code)
+	}
+	w := serve(router, adminRequest(http.MethodPost, "/api/v1/admin/videos/"+itoa(live.ID)+"/deletion/retry", "", "admin-1", "admin"))
+	if w.Code != http.StatusNotFound || errorCode(w) != api.CodeDeletionNotFound {
+		t.Errorf("video without a job: status %d: %s, want 404 %s", w.Code, w.Body, api.CodeDeletionNotFound)
+	}
+
+	w = serve(router, adminRequest(http.MethodPost, path, "", "admin-1", "admin"))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("retry: status %d: %s", w.Code, w.Body)
+	}
+	var retried models.VideoDeletion
+	if err := json.Unmarshal(w.Body.Bytes(), &retried); err != nil {
+		t.Fatal(err)
+	}
+	if retried.ID != job.ID || retried.Status != models.DeletionPending || len(retried.Items) != 1 ||
+		retried.Items[0].Status != models.DeletionItemFailed {
+		t.Errorf("retried job = %+v, want pending with its failed item", retried)
+	}
+
+	// Pending again, so a second retry has nothing to do
+	w = serve(router, adminRequest(http.MethodPost, path, "", "admin-1", "admin"))
+	if w.Code != http.StatusConflict || errorCode(w) != api.CodeNotRetryable {
+		t.Errorf("retry of a pending job: status %d: %s, want 409 %s", w.Code, w.Body, api.CodeNotRetryable)
+	}
+
+	var audits []models.AuditLog
+	db.Where("action = ?", models.AuditActionRetryDeletion).Order("id").Find(&audits)
+	if len(audits) != 2 || audits[0].Outcome != models.AuditOutcomeSucceeded || audits[0].ActorID != "admin-1" ||
+		audits[0].SubjectID != "owner" || audits[0].Target != "video:"+itoa(video.ID) || audits[1].Outcome != models.AuditOutcomeFailed {
+		t.Errorf("audit entries = %+v", audits)
+	}
+}
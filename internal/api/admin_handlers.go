@@ -0,0 +1,720 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/jobs"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// RecountVideo handles POST /api/v1/admin/videos/:id/recount
+func (h *VideoHandler) RecountVideo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+
+	result, err := h.counterSvc.RecountVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrVideoNotFound) {
+			respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to recount video", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to recount video", nil)
+		return
+	}
+
+	h.log(c).Infow("Video counters recounted", "videoID", id, "drift", result.Drift, "admin", identityFrom(c).ActorID)
+	c.JSON(http.StatusOK, result)
+}
+
+// GetSupportBundle handles GET /api/v1/admin/videos/:id/support-bundle
+func (h *VideoHandler) GetSupportBundle(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+
+	bundle, err := h.bundleSvc.Build(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrVideoNotFound) {
+			respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to build support bundle", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to build support bundle", nil)
+		return
+	}
+
+	h.log(c).Infow("Support bundle generated", "videoID", id, "admin", identityFrom(c).ActorID)
+	c.JSON(http.StatusOK, bundle)
+}
+
+// ListModerationFlags handles GET /api/v1/admin/moderation/flags; ?all=true streams every match
+func (h *VideoHandler) ListModerationFlags(c *gin.Context) {
+	if c.Query("all") == "true" {
+		query := h.moderationSvc.FlagsQuery(c.Request.Context(), c.Query("status"), c.Query("target_type")).Order("id DESC")
+		streamArray[models.ModerationFlag](h, c, "flags", query)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	flags, total, err := h.moderationSvc.ListFlags(c.Request.Context(), c.Query("status"), c.Query("target_type"), page, perPage)
+	if err != nil {
+		h.log(c).Errorw("Failed to list moderation flags", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list moderation flags", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flags":       flags,
+		"total":       total,
+		"page":        page,
+		"per_page":    perPage,
+		"total_pages": (int(total) + perPage - 1) / perPage,
+	})
+}
+
+// AdminResolveModerationFlag handles POST /api/v1/admin/moderation/flags/:flagID/resolve.
+// Approving shows a comment the provider hid again; either decision closes the
+// content's other open flags too.
+func (h *VideoHandler) AdminResolveModerationFlag(c *gin.Context) {
+	fid, err := strconv.ParseUint(c.Param("flagID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid flag ID", nil)
+		return
+	}
+	var req models.ModerationFlagResolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if req.Decision != models.ModerationDecisionApprove && req.Decision != models.ModerationDecisionUphold {
+		respondError(c, http.StatusBadRequest, CodeInvalidDecision, "Invalid decision",
+			gin.H{"allowed_decisions": []string{models.ModerationDecisionApprove, models.ModerationDecisionUphold}})
+		return
+	}
+	flag, err := h.moderationSvc.GetFlag(c.Request.Context(), uint(fid))
+	if err != nil {
+		h.moderationFlagFailed(c, err, uint(fid))
+		return
+	}
+	owner, err := h.moderationSvc.TargetOwner(c.Request.Context(), flag)
+	if err != nil {
+		h.moderationFlagFailed(c, err, flag.ID)
+		return
+	}
+
+	admin := identityFrom(c).ActorID
+	detail := fmt.Sprintf("flag %d on %s %d -> %s", flag.ID, flag.TargetType, flag.TargetID, req.Decision)
+	if req.Note != "" {
+		detail += ": " + req.Note
+	}
+	target := flag.TargetType + ":" + strconv.FormatUint(uint64(flag.TargetID), 10)
+	entry, ok := h.beginAudit(c, models.AuditActionResolveModerationFlag, owner, target, detail)
+	if !ok {
+		return
+	}
+	resolution, err := h.moderationSvc.ResolveFlag(c.Request.Context(), flag.ID, req.Decision, admin)
+	h.finishAudit(c, entry, err)
+	if err != nil {
+		h.moderationFlagFailed(c, err, flag.ID)
+		return
+	}
+
+	h.log(c).Infow("Moderation flag resolved by admin", "flagID", flag.ID, "targetType", flag.TargetType, "targetID", flag.TargetID,
+		"decision", req.Decision, "alsoResolved", resolution.AlsoResolved, "restored", resolution.Restored, "admin", admin)
+	c.JSON(http.StatusOK, resolution)
+}
+
+// moderationFlagFailed answers a failed flag lookup or resolution
+func (h *VideoHandler) moderationFlagFailed(c *gin.Context, err error, flagID uint) {
+	switch {
+	case errors.Is(err, services.ErrModerationFlagNotFound):
+		respondError(c, http.StatusNotFound, CodeFlagNotFound, "Moderation flag not found", nil)
+	case errors.Is(err, services.ErrModerationFlagResolved):
+		respondError(c, http.StatusConflict, CodeFlagResolved, "Moderation flag is already resolved", nil)
+	default:
+		h.log(c).Errorw("Failed to resolve moderation flag", "error", err, "flagID", flagID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to resolve moderation flag", nil)
+	}
+}
+
+// StartTagsBackfill handles POST /api/v1/admin/migrations/tags/backfill
+func (h *VideoHandler) StartTagsBackfill(c *gin.Context) {
+	batchSize, _ := strconv.Atoi(c.DefaultQuery("batch_size", "500"))
+	if batchSize < 1 || batchSize > 5000 {
+		batchSize = 500
+	}
+
+	// Detached from the request: the backfill outlives it and resumes from its checkpoint
+	if !h.tagMigrationSvc.StartBackfill(context.Background(), batchSize) {
+		respondError(c, http.StatusConflict, CodeAlreadyRunning, "Backfill already running", nil)
+		return
+	}
+	h.log(c).Infow("Tags backfill started", "batchSize", batchSize, "admin", identityFrom(c).ActorID)
+	c.JSON(http.StatusAccepted, gin.H{"started": true, "batch_size": batchSize})
+}
+
+// StartSearchReindex handles POST /api/v1/admin/search/reindex
+func (h *VideoHandler) StartSearchReindex(c *gin.Context) {
+	if h.searchIndex == nil {
+		respondError(c, http.StatusServiceUnavailable, CodeSearchUnavailable, "Search index is not configured", nil)
+		return
+	}
+	batchSize, _ := strconv.Atoi(c.DefaultQuery("batch_size", "500"))
+	if batchSize < 1 || batchSize > 5000 {
+		batchSize = 500
+	}
+
+	// Detached from the request: a full reindex outlives it
+	if !h.searchIndex.StartReindex(context.Background(), batchSize) {
+		respondError(c, http.StatusConflict, CodeAlreadyRunning, "Reindex already running", nil)
+		return
+	}
+	h.log(c).Infow("Search reindex started", "batchSize", batchSize, "admin", identityFrom(c).ActorID)
+	c.JSON(http.StatusAccepted, gin.H{"started": true, "batch_size": batchSize})
+}
+
+// VerifyTagsMigration handles GET /api/v1/admin/migrations/tags/verify
+func (h *VideoHandler) VerifyTagsMigration(c *gin.Context) {
+	sample, _ := strconv.Atoi(c.DefaultQuery("sample", "200"))
+	if sample < 1 || sample > 5000 {
+		sample = 200
+	}
+
+	report, err := h.tagMigrationSvc.Verify(c.Request.Context(), sample)
+	if err != nil {
+		h.log(c).Errorw("Failed to verify tags migration", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to verify tags migration", nil)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ListQuarantinedEvents handles GET /api/v1/admin/events/quarantine; ?all=true streams every match
+func (h *VideoHandler) ListQuarantinedEvents(c *gin.Context) {
+	if c.Query("all") == "true" {
+		query := h.quarantineSvc.Query(c.Request.Context(), c.Query("upload_id"), c.Query("status")).Order("id DESC")
+		streamArray[models.QuarantinedEvent](h, c, "events", query)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	list, total, err := h.quarantineSvc.List(c.Request.Context(), c.Query("upload_id"), c.Query("status"), page, perPage)
+	if err != nil {
+		h.log(c).Errorw("Failed to list quarantined events", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list quarantined events", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      list,
+		"total":       total,
+		"page":        page,
+		"per_page":    perPage,
+		"total_pages": (int(total) + perPage - 1) / perPage,
+	})
+}
+
+// ReplayQuarantinedEvent handles POST /api/v1/admin/events/quarantine/:eventID/replay?force=true
+func (h *VideoHandler) ReplayQuarantinedEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("eventID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid event ID", nil)
+		return
+	}
+	force := c.Query("force") == "true"
+
+	event, err := h.quarantineSvc.Replay(c.Request.Context(), uint(id), force)
+	if err != nil && event == nil {
+		if errors.Is(err, services.ErrQuarantinedEventNotFound) {
+			respondError(c, http.StatusNotFound, CodeEventNotFound, "Quarantined event not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to load quarantined event", "error", err, "eventID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to replay event", nil)
+		return
+	}
+	h.log(c).Infow("Quarantined event replay requested", "eventID", id, "force", force, "admin", identityFrom(c).ActorID)
+	h.replayResponse(c, err, gin.H{"event": event})
+}
+
+// ReplayUploadEvents handles POST /api/v1/admin/events/quarantine/replay?upload_id=&force=true
+func (h *VideoHandler) ReplayUploadEvents(c *gin.Context) {
+	uploadID := c.Query("upload_id")
+	if uploadID == "" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "upload_id is required", nil)
+		return
+	}
+	force := c.Query("force") == "true"
+
+	replayed, err := h.quarantineSvc.ReplayUpload(c.Request.Context(), uploadID, force)
+	h.log(c).Infow("Upload event replay requested", "uploadID", uploadID, "force", force, "admin", identityFrom(c).ActorID)
+	h.replayResponse(c, err, gin.H{"events": replayed})
+}
+
+// replayResponse maps a replay outcome to a status: 409 when the ordering guard or
+// the status state machine refused it, 422 when the event handler itself failed
+func (h *VideoHandler) replayResponse(c *gin.Context, err error, body gin.H) {
+	if err == nil {
+		c.JSON(http.StatusOK, body)
+		return
+	}
+	var rejected *events.ReplayRejectedError
+	if errors.As(err, &rejected) || errors.Is(err, services.ErrInvalidTransition) {
+		body["hint"] = "retry with force=true to apply anyway"
+		respondError(c, http.StatusConflict, CodeReplayRejected, err.Error(), body)
+		return
+	}
+	respondError(c, http.StatusUnprocessableEntity, CodeReplayFailed, err.Error(), body)
+}
+
+// ListInboundEvents handles GET /api/v1/admin/events?status=failed&upload_id=&kind=:
+// archived broker messages newest first, without their bodies
+func (h *VideoHandler) ListInboundEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	list, total, err := h.inboundEvents.List(c.Request.Context(), c.Query("upload_id"), c.Query("status"), c.Query("kind"), page, perPage)
+	if err != nil {
+		h.log(c).Errorw("Failed to list inbound events", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list inbound events", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      list,
+		"total":       total,
+		"page":        page,
+		"per_page":    perPage,
+		"total_pages": (int(total) + perPage - 1) / perPage,
+	})
+}
+
+// GetInboundEvent handles GET /api/v1/admin/events/:eventID: one archived message
+// with its body
+func (h *VideoHandler) GetInboundEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("eventID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid event ID", nil)
+		return
+	}
+	event, err := h.inboundEvents.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrInboundEventNotFound) {
+			respondError(c, http.StatusNotFound, CodeEventNotFound, "Inbound event not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to get inbound event", "error", err, "eventID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get inbound event", nil)
+		return
+	}
+	c.JSON(http.StatusOK, event)
+}
+
+// ReprocessInboundEvent handles POST /api/v1/admin/events/:eventID/reprocess?force=true,
+// running an archived video event through its handler again
+func (h *VideoHandler) ReprocessInboundEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("eventID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid event ID", nil)
+		return
+	}
+	force := c.Query("force") == "true"
+
+	event, err := h.inboundEvents.Reprocess(c.Request.Context(), uint(id), force)
+	if err != nil && event == nil {
+		switch {
+		case errors.Is(err, services.ErrInboundEventNotFound):
+			respondError(c, http.StatusNotFound, CodeEventNotFound, "Inbound event not found", nil)
+		case errors.Is(err, services.ErrEventNotReprocessable):
+			respondError(c, http.StatusConflict, CodeNotRetryable, err.Error(), nil)
+		default:
+			h.log(c).Errorw("Failed to load inbound event", "error", err, "eventID", id)
+			respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to reprocess event", nil)
+		}
+		return
+	}
+	h.log(c).Infow("Inbound event reprocess requested", "eventID", id, "force", force, "admin", identityFrom(c).ActorID)
+	h.replayResponse(c, err, gin.H{"event": event})
+}
+
+// ListJobs handles GET /api/v1/admin/jobs
+func (h *VideoHandler) ListJobs(c *gin.Context) {
+	list, err := h.jobs.List(c.Request.Context())
+	if err != nil {
+		h.log(c).Errorw("Failed to list jobs", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list jobs", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": list})
+}
+
+// RunJob handles POST /api/v1/admin/jobs/:name/run
+func (h *VideoHandler) RunJob(c *gin.Context) {
+	name := c.Param("name")
+	switch err := h.jobs.Trigger(c.Request.Context(), name); {
+	case err == nil:
+	case errors.Is(err, jobs.ErrUnknownJob):
+		respondError(c, http.StatusNotFound, CodeJobNotFound, "Unknown job", nil)
+		return
+	case errors.Is(err, jobs.ErrJobRunning):
+		respondError(c, http.StatusConflict, CodeAlreadyRunning, "Job is already running", nil)
+		return
+	default:
+		h.log(c).Errorw("Failed to trigger job", "error", err, "job", name)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to trigger job", nil)
+		return
+	}
+	h.log(c).Infow("Job triggered manually", "job", name, "admin", identityFrom(c).ActorID)
+	c.JSON(http.StatusAccepted, gin.H{"started": true, "job": name})
+}
+
+// ListAuditLog handles GET /api/v1/admin/audit?action=&actor=&subject=
+func (h *VideoHandler) ListAuditLog(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	entries, total, err := h.audit.List(c.Request.Context(), c.Query("action"), c.Query("actor"), c.Query("subject"), c.Query("target"), page, perPage)
+	if err != nil {
+		h.log(c).Errorw("Failed to list audit log", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list audit log", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":     entries,
+		"total":       total,
+		"page":        page,
+		"per_page":    perPage,
+		"total_pages": (int(total) + perPage - 1) / perPage,
+	})
+}
+
+// ListPublicEvents handles GET /api/v1/admin/event-log?after_seq=&type=&limit=.
+// Entries come in sequence order; pass the last seq back as after_seq to continue.
+func (h *VideoHandler) ListPublicEvents(c *gin.Context) {
+	afterSeq, err := strconv.ParseInt(c.DefaultQuery("after_seq", "0"), 10, 64)
+	if err != nil || afterSeq < 0 {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "after_seq must be a non-negative integer", nil)
+		return
+	}
+	eventType := c.Query("type")
+	if eventType != "" && !knownPublicEventType(eventType) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Unknown event type", gin.H{"types": models.PublicEventTypes})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	entries, more, err := h.eventLog.List(c.Request.Context(), afterSeq, eventType, limit)
+	if err != nil {
+		h.log(c).Errorw("Failed to list public event log", "error", err, "afterSeq", afterSeq)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list event log", nil)
+		return
+	}
+	nextAfterSeq := afterSeq
+	if len(entries) > 0 {
+		nextAfterSeq = entries[len(entries)-1].Seq
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"events":         entries,
+		"next_after_seq": nextAfterSeq,
+		"has_more":       more,
+	})
+}
+
+func knownPublicEventType(eventType string) bool {
+	for _, t := range models.PublicEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminListVideos handles GET /api/v1/admin/videos?user_id=&deleted=&status=&category=&tag=&sort=&order=.
+// Unlike the public lists it includes private videos, and soft-deleted ones when
+// deleted is include or only.
+func (h *VideoHandler) AdminListVideos(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	perPage := perPageFor(c, 0)
+	filters := services.VideoFilters{
+		Category: c.Query("category"),
+		Status:   models.VideoStatus(c.Query("status")),
+	}
+	if tag := c.Query("tag"); tag != "" {
+		filters.Tags = []string{tag}
+	}
+
+	response, err := h.videoService.ListAllVideos(c.Request.Context(), c.Query("user_id"), c.Query("deleted"), page, perPage, requestedSort(c), filters)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDeletedFilter) {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error(), nil)
+			return
+		}
+		if h.invalidVideoFilter(c, err) {
+			return
+		}
+		h.log(c).Errorw("Failed to list videos for admin", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list videos", nil)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AdminDeleteVideo handles DELETE /api/v1/admin/videos/:id, deleting any video
+// regardless of owner
+func (h *VideoHandler) AdminDeleteVideo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+
+	admin := identityFrom(c).ActorID
+	entry, ok := h.beginAudit(c, models.AuditActionDeleteVideo, video.UserID, "video:"+c.Param("id"), "")
+	if !ok {
+		return
+	}
+	job, err := h.videoService.DeleteVideoAsAdmin(c.Request.Context(), video.ID, admin)
+	h.finishAudit(c, entry, err)
+	if err != nil {
+		if errors.Is(err, services.ErrVideoNotFound) {
+			respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to delete video as admin", "error", err, "videoID", id, "admin", admin)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to delete video", nil)
+		return
+	}
+
+	h.log(c).Infow("Video deleted by admin", "videoID", id, "owner", video.UserID, "admin", admin, "deletionID", job.ID)
+	c.JSON(http.StatusAccepted, gin.H{"video_id": id, "deleted": true, "job_id": job.ID, "status": job.Status})
+}
+
+// AdminRetryDeletion handles POST /api/v1/admin/videos/:id/deletion/retry,
+// requeueing the video's failed deletion job. Only the storage paths that weren't
+// deleted are tried again.
+func (h *VideoHandler) AdminRetryDeletion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	job, err := h.videoService.LatestDeletion(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrDeletionNotFound) {
+			respondError(c, http.StatusNotFound, CodeDeletionNotFound, "Video has no deletion job", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to get deletion job", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to retry deletion", nil)
+		return
+	}
+
+	admin := identityFrom(c).ActorID
+	detail := fmt.Sprintf("deletion %d", job.ID)
+	entry, ok := h.beginAudit(c, models.AuditActionRetryDeletion, job.UserID, "video:"+c.Param("id"), detail)
+	if !ok {
+		return
+	}
+	job, err = h.videoService.RetryDeletion(c.Request.Context(), job.ID)
+	h.finishAudit(c, entry, err)
+	if err != nil {
+		if errors.Is(err, services.ErrDeletionNotFailed) {
+			respondError(c, http.StatusConflict, CodeNotRetryable, "Only a failed deletion job can be retried", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to retry deletion as admin", "error", err, "videoID", id, "admin", admin)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to retry deletion", nil)
+		return
+	}
+
+	h.log(c).Infow("Video deletion retried by admin", "videoID", id, "deletionID", job.ID, "admin", admin)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// AdminSetVideoStatus handles PATCH /api/v1/admin/videos/:id/status with
+// {"status": "ready", "reason": "..."}; reason is kept in the audit entry. A
+// transition the status state machine doesn't allow is a 409.
+func (h *VideoHandler) AdminSetVideoStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	var req struct {
+		Status models.VideoStatus `json:"status" binding:"required"`
+		Reason string             `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if !req.Status.Valid() {
+		respondError(c, http.StatusBadRequest, CodeInvalidStatus, "Invalid status", gin.H{"allowed_statuses": models.VideoStatuses})
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+
+	admin := identityFrom(c).ActorID
+	detail := fmt.Sprintf("status %s -> %s", video.Status, req.Status)
+	if req.Reason != "" {
+		detail += ": " + req.Reason
+	}
+	entry, ok := h.beginAudit(c, models.AuditActionSetStatus, video.UserID, "video:"+c.Param("id"), detail)
+	if !ok {
+		return
+	}
+	updated, err := h.videoService.SetVideoStatus(c.Request.Context(), video.ID, req.Status, admin, req.Reason)
+	h.finishAudit(c, entry, err)
+	if err != nil {
+		if errors.Is(err, services.ErrVideoNotFound) {
+			respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+			return
+		}
+		var invalid *services.InvalidTransitionError
+		if errors.As(err, &invalid) {
+			respondError(c, http.StatusConflict, CodeInvalidTransition, invalid.Error(), gin.H{"allowed_statuses": invalid.From.NextStatuses()})
+			return
+		}
+		h.log(c).Errorw("Failed to set video status", "error", err, "videoID", id, "admin", admin)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to set video status", nil)
+		return
+	}
+
+	h.log(c).Infow("Video status forced by admin", "videoID", id, "from", video.Status, "to", req.Status, "admin", admin)
+	c.JSON(http.StatusOK, updated)
+}
+
+// AdminDeleteComment handles DELETE /api/v1/admin/comments/:commentID, deleting any
+// comment regardless of author or video owner
+func (h *VideoHandler) AdminDeleteComment(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("commentID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid comment ID", nil)
+		return
+	}
+	comment, err := h.commentSvc.GetComment(c.Request.Context(), uint(cid))
+	if err != nil {
+		if errors.Is(err, services.ErrCommentNotFound) {
+			respondError(c, http.StatusNotFound, CodeCommentNotFound, "Comment not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to get comment", "error", err, "commentID", cid)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to delete comment", nil)
+		return
+	}
+
+	admin := identityFrom(c).ActorID
+	detail := fmt.Sprintf("on video %d", comment.VideoID)
+	entry, ok := h.beginAudit(c, models.AuditActionDeleteComment, comment.UserID, "comment:"+c.Param("commentID"), detail)
+	if !ok {
+		return
+	}
+	err = h.commentSvc.DeleteComment(c.Request.Context(), comment.ID, admin, true)
+	h.finishAudit(c, entry, err)
+	if err != nil {
+		if errors.Is(err, services.ErrCommentNotFound) {
+			respondError(c, http.StatusNotFound, CodeCommentNotFound, "Comment not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to delete comment as admin", "error", err, "commentID", cid, "admin", admin)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to delete comment", nil)
+		return
+	}
+
+	h.log(c).Infow("Comment deleted by admin", "commentID", cid, "author", comment.UserID, "admin", admin)
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// beginAudit records an admin action as started before it runs. If the entry can't
+// be written the request is refused with 503, so nothing happens unaudited.
+func (h *VideoHandler) beginAudit(c *gin.Context, action, subjectID, target, detail string) (*models.AuditLog, bool) {
+	entry := &models.AuditLog{
+		Action:    action,
+		ActorID:   identityFrom(c).ActorID,
+		SubjectID: subjectID,
+		Target:    target,
+		Detail:    detail,
+		Method:    c.Request.Method,
+		Route:     c.FullPath(),
+		Path:      c.Request.URL.Path,
+		Outcome:   models.AuditOutcomeStarted,
+	}
+	if err := h.audit.Record(c.Request.Context(), entry); err != nil {
+		h.log(c).Errorw("Failed to audit admin action", "error", err, "action", action, "target", target, "admin", entry.ActorID)
+		respondError(c, http.StatusServiceUnavailable, CodeAuditUnavailable, "Audit unavailable", nil)
+		return nil, false
+	}
+	return entry, true
+}
+
+// finishAudit records whether the audited action succeeded
+func (h *VideoHandler) finishAudit(c *gin.Context, entry *models.AuditLog, err error) {
+	outcome := models.AuditOutcomeSucceeded
+	if err != nil {
+		outcome = models.AuditOutcomeFailed
+	}
+	// The action has happened; record it even if the client has gone away
+	h.audit.SetOutcome(context.WithoutCancel(c.Request.Context()), entry, outcome)
+}
@@ -0,0 +1,152 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+	"github.com/streamhive/video-catalog-api/internal/streaming"
+)
+
+// adminRequest builds a request from user with the given roles, as the gateway forwards it
+func adminRequest(method, target, body, user, roles string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", user)
+	req.Header.Set("X-User-Roles", roles)
+	return req
+}
+
+func moderationRouter(t *testing.T) (*gorm.DB, http.Handler) {
+	t.Helper()
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	return db, newRouter(api.Dependencies{
+		Videos:     services.NewVideoService(db, nil, videoSettings, log),
+		Moderation: services.NewModerationService(db, log, services.NoopModerationProvider{}, 0.5, 0.9, 1),
+		Audit:      services.NewAuditService(db, log),
+	})
+}
+
+func TestAdminResolveModerationFlag(t *testing.T) {
+	db, router := moderationRouter(t)
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t"}
+	db.Create(&video)
+	comment := models.Comment{VideoID: video.ID, UserID: "author", Content: "x", Status: models.CommentPending}
+	db.Create(&comment)
+	flag := models.ModerationFlag{TargetType: models.ModerationTargetComment, TargetID: comment.ID, Field: "comment",
+		Severity: "high", Status: models.ModerationAutoHidden}
+	db.Create(&flag)
+	path := "/api/v1/admin/moderation/flags/" + itoa(flag.ID) + "/resolve"
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		roles  string
+		status int
+	}{
+		{"not an admin", path, `{"decision":"approve"}`, "user", http.StatusForbidden},
+		{"unknown decision", path, `{"decision":"delete"}`, "admin", http.StatusBadRequest},
+		{"unknown flag", "/api/v1/admin/moderation/flags/999/resolve", `{"decision":"approve"}`, "admin", http.StatusNotFound},
+		{"approve", path, `{"decision":"approve","note":"satire"}`, "admin", http.StatusOK},
+		{"already resolved", path, `{"decision":"uphold"}`, "admin", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		w := serve(router, adminRequest(http.MethodPost, tt.path, tt.body, "admin-1", tt.roles))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+		}
+	}
+
+	var got models.Comment
+	db.First(&got, comment.ID)
+	if got.Status != models.CommentVisible {
+		t.Errorf("comment status = %s after approval, want visible", got.Status)
+	}
+	var audits []models.AuditLog
+	db.Where("action = ?", models.AuditActionResolveModerationFlag).Order("id").Find(&audits)
+	if len(audits) != 2 || audits[0].Outcome != models.AuditOutcomeSucceeded || audits[0].SubjectID != "author" ||
+		audits[0].Target != "comment:"+itoa(comment.ID) || audits[1].Outcome != models.AuditOutcomeFailed {
+		t.Errorf("audit entries = %+v", audits)
+	}
+}
+
+func TestAdminStreamModerationFlags(t *testing.T) {
+	db, router := moderationRouter(t)
+	for i := 1; i <= 5; i++ {
+		db.Create(&models.ModerationFlag{TargetType: models.ModerationTargetVideo, TargetID: uint(i), Field: "title",
+			Severity: "low", Status: models.ModerationFlagged})
+	}
+	defaults := streaming.DefaultLimits
+	t.Cleanup(func() { streaming.DefaultLimits = defaults })
+
+	list := func() (body struct {
+		Flags     []models.ModerationFlag `json:"flags"`
+		Count     int                     `json:"count"`
+		Truncated bool                    `json:"truncated"`
+	}) {
+		w := serve(router, adminRequest(http.MethodGet, "/api/v1/admin/moderation/flags?all=true", "", "root", "admin"))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode %s: %v", w.Body, err)
+		}
+		return body
+	}
+	if body := list(); body.Count != 5 || len(body.Flags) != 5 || body.Truncated || body.Flags[0].TargetID != 5 {
+		t.Errorf("full stream = %+v", body)
+	}
+	streaming.DefaultLimits = streaming.Limits{MaxRows: 2}
+	if body := list(); body.Count != 2 || !body.Truncated {
+		t.Errorf("capped stream = %+v", body)
+	}
+}
+
+func TestAdminSetVideoStatus(t *testing.T) {
+	db, router := moderationRouter(t)
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t", Status: models.StatusProcessing}
+	db.Create(&video)
+	path := "/api/v1/admin/videos/" + itoa(video.ID) + "/status"
+
+	w := serve(router, adminRequest(http.MethodPatch, path, `{"status":"uploaded"}`, "root", "admin"))
+	var conflict struct {
+		Code    string `json:"code"`
+		Details struct {
+			AllowedStatuses []models.VideoStatus `json:"allowed_statuses"`
+		} `json:"details"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &conflict)
+	if w.Code != http.StatusConflict || conflict.Code != api.CodeInvalidTransition {
+		t.Fatalf("processing -> uploaded: status %d: %s; want 409 %s", w.Code, w.Body, api.CodeInvalidTransition)
+	}
+	if allowed := conflict.Details.AllowedStatuses; len(allowed) != 2 || allowed[0] != models.StatusReady || allowed[1] != models.StatusFailed {
+		t.Errorf("allowed statuses %v, want ready and failed", allowed)
+	}
+
+	if w := serve(router, adminRequest(http.MethodPatch, path, `{"status":"failed","reason":"stuck"}`, "root", "admin")); w.Code != http.StatusOK {
+		t.Fatalf("processing -> failed: status %d: %s", w.Code, w.Body)
+	}
+	// Failed only goes back to processing
+	if w := serve(router, adminRequest(http.MethodPatch, path, `{"status":"ready"}`, "root", "admin")); w.Code != http.StatusConflict {
+		t.Errorf("failed -> ready: status %d, want 409", w.Code)
+	}
+	if w := serve(router, adminRequest(http.MethodPatch, path, `{"status":"processing"}`, "root", "admin")); w.Code != http.StatusOK {
+		t.Errorf("failed -> processing: status %d: %s", w.Code, w.Body)
+	}
+	var current models.Video
+	db.First(&current, video.ID)
+	if current.Status != models.StatusProcessing {
+		t.Errorf("status %s, want processing", current.Status)
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// CreateAnonymousSession handles POST /api/v1/sessions/anonymous. The token goes
+// in X-Anonymous-Session on later requests from the logged-out client.
+func (h *VideoHandler) CreateAnonymousSession(c *gin.Context) {
+	if currentUser(c) != "" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Signed-in users don't need an anonymous session", nil)
+		return
+	}
+	token, session, expires, err := h.anonymous.Issue(c.Request.Context())
+	if err != nil {
+		h.log(c).Errorw("Failed to issue anonymous session", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to create session", nil)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"token":      token,
+		"session_id": session.ID,
+		"expires_at": expires,
+	})
+}
+
+// MergeAnonymousSession handles POST /api/v1/users/:userID/merge-anonymous (self only).
+// The body carries the anonymous token; its rows are rewritten to the user.
+func (h *VideoHandler) MergeAnonymousSession(c *gin.Context) {
+	userID := c.Param("userID")
+	if !h.requireSelf(c, userID) {
+		return
+	}
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	result, err := h.anonymous.Merge(c.Request.Context(), req.Token, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAnonymousSession):
+			respondError(c, http.StatusBadRequest, CodeInvalidAnonymousSession, "Invalid anonymous session", gin.H{"reason": err.Error()})
+		case errors.Is(err, services.ErrAnonymousSessionMerged):
+			respondError(c, http.StatusConflict, CodeSessionMerged, "Session already merged into another account", nil)
+		default:
+			h.log(c).Errorw("Failed to merge anonymous session", "error", err, "userID", userID)
+			respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to merge session", nil)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+)
+
+// Authentication modes
+const (
+	// AuthModeJWT verifies a Bearer token on every request
+	AuthModeJWT = "jwt"
+	// AuthModeHeader trusts X-User-ID / X-User-Roles as sent; only for local
+	// development or behind a gateway that strips client-supplied values
+	AuthModeHeader = "header"
+)
+
+// AuthConfig selects how callers are authenticated
+type AuthConfig struct {
+	Mode string
+	// Algorithm is HS256 (Secret) or RS256 (PublicKeyPEM or JWKSURL)
+	Algorithm    string
+	Secret       []byte
+	PublicKeyPEM []byte
+	JWKSURL      string
+	// Issuer and Audience, when set, must match the token's iss and aud
+	Issuer   string
+	Audience string
+	// RolesClaim names the claim holding the caller's roles, as a list or a
+	// comma/space separated string
+	RolesClaim string
+	// Leeway tolerates clock skew when checking exp, nbf and iat
+	Leeway time.Duration
+}
+
+// Authenticator establishes who is calling
+type Authenticator struct {
+	mode       string
+	parser     *jwt.Parser
+	keyfunc    jwt.Keyfunc
+	rolesClaim string
+}
+
+// NewAuthenticator validates cfg and prepares token verification
+func NewAuthenticator(cfg AuthConfig) (*Authenticator, error) {
+	switch cfg.Mode {
+	case AuthModeHeader:
+		return &Authenticator{mode: AuthModeHeader}, nil
+	case AuthModeJWT:
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q: use %s or %s", cfg.Mode, AuthModeJWT, AuthModeHeader)
+	}
+
+	a := &Authenticator{mode: AuthModeJWT, rolesClaim: cfg.RolesClaim}
+	if a.rolesClaim == "" {
+		a.rolesClaim = "roles"
+	}
+	switch cfg.Algorithm {
+	case "HS256":
+		if len(cfg.Secret) < 32 {
+			return nil, errors.New("HS256 needs a secret of at least 32 bytes")
+		}
+		a.keyfunc = func(*jwt.Token) (interface{}, error) { return cfg.Secret, nil }
+	case "RS256":
+		switch {
+		case cfg.JWKSURL != "":
+			a.keyfunc = newJWKS(cfg.JWKSURL).keyfunc
+		case len(cfg.PublicKeyPEM) > 0:
+			key, err := jwt.ParseRSAPublicKeyFromPEM(cfg.PublicKeyPEM)
+			if err != nil {
+				return nil, fmt.Errorf("parse RS256 public key: %w", err)
+			}
+			a.keyfunc = func(*jwt.Token) (interface{}, error) { return key, nil }
+		default:
+			return nil, errors.New("RS256 needs a public key or a JWKS URL")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q: use HS256 or RS256", cfg.Algorithm)
+	}
+
+	// Pinning the algorithm stops a token choosing how it is verified (alg=none,
+	// or HS256 signed with the RSA public key)
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{cfg.Algorithm}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	a.parser = jwt.NewParser(opts...)
+	return a, nil
+}
+
+// Mode returns the authentication mode in use
+func (a *Authenticator) Mode() string {
+	if a == nil {
+		return AuthModeHeader
+	}
+	return a.mode
+}
+
+// errMissingSubject rejects a verified token that doesn't say who it is for
+var errMissingSubject = errors.New("token has no subject")
+
+// identify returns the caller's identity before impersonation. A request without
+// credentials is anonymous (empty UserID); credentials that don't verify are an error.
+func (a *Authenticator) identify(c *gin.Context) (Identity, error) {
+	if a.Mode() == AuthModeHeader {
+		return headerIdentity(c), nil
+	}
+	header := c.GetHeader("Authorization")
+	if header == "" {
+		return Identity{}, nil
+	}
+	scheme, raw, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(raw) == "" {
+		return Identity{}, fmt.Errorf("%w: expected a Bearer token", jwt.ErrTokenMalformed)
+	}
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(strings.TrimSpace(raw), claims, a.keyfunc); err != nil {
+		return Identity{}, err
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return Identity{}, errMissingSubject
+	}
+	username, _ := claims["preferred_username"].(string)
+	return Identity{
+		UserID:   subject,
+		Roles:    claimRoles(claims[a.rolesClaim]),
+		ActorID:  subject,
+		Username: username,
+	}, nil
+}
+
+// headerIdentity is the identity the gateway headers claim
+func headerIdentity(c *gin.Context) Identity {
+	return Identity{
+		UserID:   c.GetHeader("X-User-ID"),
+		Roles:    c.GetHeader("X-User-Roles"),
+		ActorID:  c.GetHeader("X-User-ID"),
+		Username: c.GetHeader("X-Username"),
+	}
+}
+
+// claimRoles flattens a roles claim into the comma separated form hasRole reads
+func claimRoles(v interface{}) string {
+	switch roles := v.(type) {
+	case string:
+		return strings.Join(strings.FieldsFunc(roles, func(r rune) bool { return r == ',' || r == ' ' }), ",")
+	case []interface{}:
+		out := make([]string, 0, len(roles))
+		for _, r := range roles {
+			if s, ok := r.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return strings.Join(out, ",")
+	}
+	return ""
+}
+
+// authFailureReason classifies a rejected token for metrics
+func authFailureReason(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return "signature"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "malformed"
+	default:
+		return "claims"
+	}
+}
+
+// rejectCredentials answers 401 for credentials that didn't verify
+func rejectCredentials(c *gin.Context, err error) {
+	metrics.AuthFailuresTotal.WithLabelValues(authFailureReason(err)).Inc()
+	c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	abortWithError(c, http.StatusUnauthorized, CodeInvalidToken, "Invalid token", gin.H{"reason": err.Error()})
+}
+
+// anonymousWrites are mutating routes open to callers without a user: counting a
+// view (keyed by anonymous session or IP), saving watch progress under an anonymous
+// session and starting an anonymous session
+var anonymousWrites = map[string]bool{
+	"/api/v1/videos/:id/view":     true,
+	"/api/v1/videos/:id/progress": true,
+	"/api/v1/sessions/anonymous":  true,
+}
+
+// requireUserForWrites answers 401 to unauthenticated mutating requests. Reads stay
+// open; handlers decide what an anonymous caller may see.
+func requireUserForWrites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if identityFrom(c).UserID == "" && !anonymousWrites[c.FullPath()] {
+			c.Header("WWW-Authenticate", "Bearer")
+			abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "Authentication required", nil)
+			return
+		}
+		c.Next()
+	}
+}
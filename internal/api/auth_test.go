@@ -0,0 +1,360 @@
+package api_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+var hsSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// authRouter serves the API behind auth, with impersonation enabled
+func authRouter(t *testing.T, auth *api.Authenticator) (*gorm.DB, http.Handler) {
+	t.Helper()
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	audit := services.NewAuditService(db, log)
+	return db, newRouter(api.Dependencies{
+		Auth:          auth,
+		Impersonation: api.ImpersonationConfig{Enabled: true, Audit: audit},
+		Videos:        services.NewVideoService(db, nil, videoSettings, log),
+		Reactions:     services.NewReactionService(db, log),
+		Audit:         audit,
+		EventLog:      services.NewPublicEventLog(db, log),
+	})
+}
+
+func newAuthenticator(t *testing.T, cfg api.AuthConfig) *api.Authenticator {
+	t.Helper()
+	auth, err := api.NewAuthenticator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth
+}
+
+// claims returns valid claims for user, expiring in an hour, with over applied
+func claims(user string, over jwt.MapClaims) jwt.MapClaims {
+	now := time.Now()
+	c := jwt.MapClaims{"sub": user, "iat": now.Unix(), "exp": now.Add(time.Hour).Unix(), "iss": "idp", "aud": "catalog"}
+	for k, v := range over {
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, c jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, c).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func bearer(method, target, body, token string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func errorCode(w *httptest.ResponseRecorder) string {
+	var body struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return body.Code
+}
+
+func TestJWTAuthHS256(t *testing.T) {
+	db, router := authRouter(t, newAuthenticator(t, api.AuthConfig{
+		Mode: api.AuthModeJWT, Algorithm: "HS256", Secret: hsSecret, Issuer: "idp", Audience: "catalog",
+	}))
+	valid := sign(t, jwt.SigningMethodHS256, hsSecret, claims("alice", nil))
+
+	// The token's subject owns what it creates
+	w := serve(router, bearer(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-1","title":"t"}`, valid))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create with a valid token: %d %s", w.Code, w.Body)
+	}
+	var video models.Video
+	db.First(&video)
+	if video.UserID != "alice" {
+		t.Errorf("video owned by %q, want the token's subject", video.UserID)
+	}
+
+	// Reads stay open; writes need a user
+	if w := serve(router, bearer(http.MethodGet, "/api/v1/videos", "", "")); w.Code != http.StatusOK {
+		t.Errorf("anonymous read: status %d", w.Code)
+	}
+	w = serve(router, bearer(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-2","title":"t"}`, ""))
+	if w.Code != http.StatusUnauthorized || errorCode(w) != api.CodeUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("anonymous write: %d %s", w.Code, w.Body)
+	}
+	// The gateway headers mean nothing in jwt mode
+	req := adminRequest(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-3","title":"t"}`, "alice", "admin")
+	if w := serve(router, req); w.Code != http.StatusUnauthorized {
+		t.Errorf("X-User-ID without a token: status %d, want 401", w.Code)
+	}
+
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims("alice", nil)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected := []struct {
+		name, header, reason string
+	}{
+		{"expired", "Bearer " + sign(t, jwt.SigningMethodHS256, hsSecret, claims("alice", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})), "expired"},
+		{"wrong signature", "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("another-secret-another-secret-xx"), claims("alice", nil)), "signature"},
+		{"alg none", "Bearer " + none, "signature"},
+		{"HS512", "Bearer " + sign(t, jwt.SigningMethodHS512, hsSecret, claims("alice", nil)), "signature"},
+		{"no subject", "Bearer " + sign(t, jwt.SigningMethodHS256, hsSecret, claims("", jwt.MapClaims{"sub": nil})), "claims"},
+		{"no expiry", "Bearer " + sign(t, jwt.SigningMethodHS256, hsSecret, claims("alice", jwt.MapClaims{"exp": nil})), "claims"},
+		{"not yet valid", "Bearer " + sign(t, jwt.SigningMethodHS256, hsSecret, claims("alice", jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()})), "claims"},
+		{"wrong audience", "Bearer " + sign(t, jwt.SigningMethodHS256, hsSecret, claims("alice", jwt.MapClaims{"aud": "billing"})), "claims"},
+		{"wrong issuer", "Bearer " + sign(t, jwt.SigningMethodHS256, hsSecret, claims("alice", jwt.MapClaims{"iss": "evil"})), "claims"},
+		{"not bearer", "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:pw")), "malformed"},
+		{"garbage", "Bearer not.a.token", "malformed"},
+	}
+	for _, tt := range rejected {
+		before := testutil.ToFloat64(metrics.AuthFailuresTotal.WithLabelValues(tt.reason))
+		// Bad credentials are refused even on reads, rather than served anonymously
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/videos", nil)
+		req.Header.Set("Authorization", tt.header)
+		w := serve(router, req)
+		if w.Code != http.StatusUnauthorized || errorCode(w) != api.CodeInvalidToken {
+			t.Errorf("%s: %d %s, want 401 invalid_token", tt.name, w.Code, w.Body)
+		}
+		if got := testutil.ToFloat64(metrics.AuthFailuresTotal.WithLabelValues(tt.reason)) - before; got != 1 {
+			t.Errorf("%s: %s failures counted %v times, want 1", tt.name, tt.reason, got)
+		}
+	}
+}
+
+func TestJWTAuthRoles(t *testing.T) {
+	auth := newAuthenticator(t, api.AuthConfig{Mode: api.AuthModeJWT, Algorithm: "HS256", Secret: hsSecret, RolesClaim: "groups"})
+	_, router := authRouter(t, auth)
+
+	for _, tt := range []struct {
+		name  string
+		extra jwt.MapClaims
+		want  int
+	}{
+		{"list", jwt.MapClaims{"groups": []string{"viewer", "admin"}}, http.StatusOK},
+		{"comma separated", jwt.MapClaims{"groups": "viewer,admin"}, http.StatusOK},
+		{"space separated", jwt.MapClaims{"groups": "viewer admin"}, http.StatusOK},
+		{"not admin", jwt.MapClaims{"groups": []string{"viewer"}}, http.StatusForbidden},
+		{"other claim", jwt.MapClaims{"roles": []string{"admin"}}, http.StatusForbidden},
+		{"similar name", jwt.MapClaims{"groups": "administrator"}, http.StatusForbidden},
+	} {
+		token := sign(t, jwt.SigningMethodHS256, hsSecret, claims("alice", tt.extra))
+		if w := serve(router, bearer(http.MethodGet, "/api/v1/admin/event-log", "", token)); w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func rsaKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestJWTAuthRS256(t *testing.T) {
+	key, pub := rsaKey(t)
+	_, router := authRouter(t, newAuthenticator(t, api.AuthConfig{Mode: api.AuthModeJWT, Algorithm: "RS256", PublicKeyPEM: pub}))
+	other, _ := rsaKey(t)
+
+	for _, tt := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"valid", sign(t, jwt.SigningMethodRS256, key, claims("alice", nil)), http.StatusCreated},
+		{"other key", sign(t, jwt.SigningMethodRS256, other, claims("alice", nil)), http.StatusUnauthorized},
+		// The public key is no secret; an HS256 token keyed with it must not pass
+		{"HS256 with the public key", sign(t, jwt.SigningMethodHS256, pub, claims("alice", nil)), http.StatusUnauthorized},
+	} {
+		w := serve(router, bearer(http.MethodPost, "/api/v1/videos", `{"upload_id":"`+tt.name+`","title":"t"}`, tt.token))
+		if w.Code != tt.want {
+			t.Errorf("%s: %d %s, want %d", tt.name, w.Code, w.Body, tt.want)
+		}
+	}
+}
+
+func TestJWTAuthJWKS(t *testing.T) {
+	key, _ := rsaKey(t)
+	var fetches atomic.Int32
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer idp.Close()
+	_, router := authRouter(t, newAuthenticator(t, api.AuthConfig{Mode: api.AuthModeJWT, Algorithm: "RS256", JWKSURL: idp.URL}))
+
+	withKid := func(kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims("alice", nil))
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	for i := 0; i < 3; i++ {
+		if w := serve(router, bearer(http.MethodGet, "/api/v1/videos", "", withKid("k1"))); w.Code != http.StatusOK {
+			t.Fatalf("token signed with k1: status %d %s", w.Code, w.Body)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("key set fetched %d times, want once and then cached", n)
+	}
+	// Made-up key IDs can't make every request refetch the key set
+	for i := 0; i < 3; i++ {
+		if w := serve(router, bearer(http.MethodGet, "/api/v1/videos", "", withKid("forged"))); w.Code != http.StatusUnauthorized {
+			t.Errorf("unknown kid: status %d, want 401", w.Code)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("key set fetched %d times after unknown kids, want no refetch within the minimum interval", n)
+	}
+}
+
+func TestNewAuthenticatorRejectsConfig(t *testing.T) {
+	for name, cfg := range map[string]api.AuthConfig{
+		"unknown mode":      {Mode: "cookie"},
+		"short secret":      {Mode: api.AuthModeJWT, Algorithm: "HS256", Secret: []byte("short")},
+		"unknown algorithm": {Mode: api.AuthModeJWT, Algorithm: "none", Secret: hsSecret},
+		"RS256 without key": {Mode: api.AuthModeJWT, Algorithm: "RS256"},
+		"bad public key":    {Mode: api.AuthModeJWT, Algorithm: "RS256", PublicKeyPEM: []byte("not a key")},
+	} {
+		if _, err := api.NewAuthenticator(cfg); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestImpersonationIsReadOnly(t *testing.T) {
+	db, router := authRouter(t, nil)
+	db.Create(&models.Video{UploadID: "up-1", UserID: "target", Title: "t"})
+	impersonate := func(method, path, roles string) *httptest.ResponseRecorder {
+		req := adminRequest(method, path, `{"title":"changed"}`, "root", roles)
+		req.Header.Set("X-Impersonate-User", "target")
+		return serve(router, req)
+	}
+
+	if w := impersonate(http.MethodGet, "/api/v1/users/target/videos", "admin"); w.Code != http.StatusOK {
+		t.Fatalf("impersonated read: %d %s", w.Code, w.Body)
+	}
+	for _, tt := range []struct {
+		method, path, roles string
+		want                int
+	}{
+		{http.MethodPost, "/api/v1/videos", "admin", http.StatusForbidden},
+		{http.MethodPut, "/api/v1/videos/1", "admin", http.StatusForbidden},
+		{http.MethodPatch, "/api/v1/videos/1", "admin", http.StatusForbidden},
+		{http.MethodDelete, "/api/v1/videos/1", "admin", http.StatusForbidden},
+		{http.MethodGet, "/api/v1/admin/event-log", "admin", http.StatusForbidden},
+		{http.MethodGet, "/api/v1/users/target/data-export", "admin", http.StatusForbidden},
+		// Bulk exports of the user's data go through the admin's own audited export
+		{http.MethodGet, "/api/v1/users/target/export", "admin", http.StatusForbidden},
+		{http.MethodGet, "/api/v1/users/target/data-exports/1/download", "admin", http.StatusForbidden},
+		{http.MethodGet, "/api/v1/users/target/videos", "user", http.StatusForbidden},
+	} {
+		if w := impersonate(tt.method, tt.path, tt.roles); w.Code != tt.want {
+			t.Errorf("%s %s as %s: %d %s, want %d", tt.method, tt.path, tt.roles, w.Code, w.Body, tt.want)
+		}
+	}
+	var video models.Video
+	db.First(&video)
+	if video.Title != "t" {
+		t.Errorf("impersonated write changed the title to %q", video.Title)
+	}
+
+	// Every impersonated request by the admin is audited, refused ones included
+	var entries []models.AuditLog
+	db.Where("action = ?", models.AuditActionImpersonate).Order("id").Find(&entries)
+	if len(entries) != 9 {
+		t.Fatalf("%d audit entries, want 9: %+v", len(entries), entries)
+	}
+	if e := entries[0]; e.ActorID != "root" || e.SubjectID != "target" || e.Outcome != "served" {
+		t.Errorf("read audited as %+v", e)
+	}
+	for _, e := range entries[1:] {
+		if e.Outcome != "rejected" {
+			t.Errorf("%s %s audited as %q, want rejected", e.Method, e.Path, e.Outcome)
+		}
+	}
+}
+
+// TestImpersonationAuditedFirst breaks the audit table: the impersonated read is
+// refused rather than served unaudited
+func TestImpersonationAuditedFirst(t *testing.T) {
+	db, router := authRouter(t, nil)
+	db.Create(&models.Video{UploadID: "up-1", UserID: "target", Title: "secret", Visibility: models.VisibilityPrivate})
+	impersonate := func() *httptest.ResponseRecorder {
+		req := adminRequest(http.MethodGet, "/api/v1/users/target/videos", "", "root", "admin")
+		req.Header.Set("X-Impersonate-User", "target")
+		return serve(router, req)
+	}
+
+	w := impersonate()
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "secret") {
+		t.Fatalf("impersonated read of a private video: %d %s", w.Code, w.Body)
+	}
+	var entry models.AuditLog
+	if err := db.Where("action = ?", models.AuditActionImpersonate).First(&entry).Error; err != nil || entry.Route != "/api/v1/users/:userID/videos" {
+		t.Errorf("audit entry %+v, %v", entry, err)
+	}
+
+	failed := testutil.ToFloat64(metrics.ImpersonatedRequestsTotal.WithLabelValues("root", "audit_failed"))
+	if err := db.Migrator().DropTable(&models.AuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+	w = impersonate()
+	if w.Code != http.StatusServiceUnavailable || strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("read with the audit down: %d %s, want 503 without the video", w.Code, w.Body)
+	}
+	if got := testutil.ToFloat64(metrics.ImpersonatedRequestsTotal.WithLabelValues("root", "audit_failed")) - failed; got != 1 {
+		t.Errorf("audit_failed +%v, want +1", got)
+	}
+}
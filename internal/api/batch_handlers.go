@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// maxBatchVideos caps how many videos one GET /videos/batch may ask for
+const maxBatchVideos = 100
+
+// BatchGetVideos handles GET /api/v1/videos/batch?ids=1,2,3 or ?upload_ids=a,b,c -
+// up to 100 videos in one response, in the order asked for. Videos that are
+// missing, or that the caller couldn't see through GET /videos/:id, are left out of
+// videos and listed under missing instead; the two can't be told apart, as there.
+func (h *VideoHandler) BatchGetVideos(c *gin.Context) {
+	rawIDs, rawUploadIDs := c.Query("ids"), c.Query("upload_ids")
+	if (rawIDs == "") == (rawUploadIDs == "") {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Exactly one of ids or upload_ids is required", nil)
+		return
+	}
+
+	if rawIDs != "" {
+		ids := make([]uint, 0)
+		for _, s := range batchKeys(rawIDs) {
+			id, err := strconv.ParseUint(s, 10, 32)
+			if err != nil || id == 0 {
+				respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", gin.H{"id": s})
+				return
+			}
+			ids = append(ids, uint(id))
+		}
+		if !checkBatchSize(c, len(ids)) {
+			return
+		}
+		ids = uniqueKeys(ids)
+		videos, err := h.videoService.GetVideosByIDs(c.Request.Context(), ids)
+		if err != nil {
+			h.log(c).Errorw("Failed to batch get videos", "error", err, "count", len(ids))
+			respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get videos", nil)
+			return
+		}
+		respondBatch(h, c, videos, ids, func(v *models.Video) uint { return v.ID })
+		return
+	}
+
+	uploadIDs := batchKeys(rawUploadIDs)
+	if !checkBatchSize(c, len(uploadIDs)) {
+		return
+	}
+	uploadIDs = uniqueKeys(uploadIDs)
+	videos, err := h.videoService.GetVideosByUploadIDs(c.Request.Context(), uploadIDs)
+	if err != nil {
+		h.log(c).Errorw("Failed to batch get videos by upload ID", "error", err, "count", len(uploadIDs))
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get videos", nil)
+		return
+	}
+	respondBatch(h, c, videos, uploadIDs, func(v *models.Video) string { return v.UploadID })
+}
+
+func checkBatchSize(c *gin.Context, n int) bool {
+	switch {
+	case n == 0:
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "No IDs given", nil)
+		return false
+	case n > maxBatchVideos:
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Too many IDs", gin.H{"max": maxBatchVideos})
+		return false
+	}
+	return true
+}
+
+// batchKeys splits a comma-separated list, skipping empty entries
+func batchKeys(raw string) []string {
+	var keys []string
+	for _, s := range strings.Split(raw, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			keys = append(keys, s)
+		}
+	}
+	return keys
+}
+
+// uniqueKeys drops repeats, keeping each key's first position
+func uniqueKeys[K comparable](keys []K) []K {
+	seen := make(map[K]bool, len(keys))
+	out := make([]K, 0, len(keys))
+	for _, k := range keys {
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// respondBatch filters videos through the same privacy rules as GetVideo and
+// answers with the visible ones in the order of keys, reporting which were found
+func respondBatch[K comparable](h *VideoHandler, c *gin.Context, videos []models.Video, keys []K, key func(*models.Video) K) {
+	requester := currentUser(c)
+	visible := make(map[K]*models.Video, len(videos))
+	for i := range videos {
+		video := &videos[i]
+		if !canView(c, video) {
+			continue
+		}
+		if requester != video.UserID || identityFrom(c).Impersonating {
+			h.recordAccess(c, video, requester, "batch")
+		}
+		hideShareToken(c, video)
+		visible[key(video)] = video
+	}
+
+	shown := make([]*models.Video, 0, len(visible))
+	found, missing := make([]K, 0, len(keys)), make([]K, 0)
+	for _, k := range keys {
+		if video, ok := visible[k]; ok {
+			shown = append(shown, video)
+			found = append(found, k)
+		} else {
+			missing = append(missing, k)
+		}
+	}
+	h.attachReactions(c, shown...)
+	c.JSON(http.StatusOK, gin.H{"videos": shown, "found": found, "missing": missing})
+}
@@ -0,0 +1,108 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+type batchBody struct {
+	Videos  []models.Video    `json:"videos"`
+	Found   []json.RawMessage `json:"found"`
+	Missing []json.RawMessage `json:"missing"`
+}
+
+// titles lists the batch's videos by title, and its found and missing keys
+func (b batchBody) String() string {
+	var titles []string
+	for _, v := range b.Videos {
+		titles = append(titles, v.Title)
+	}
+	keys := func(raw []json.RawMessage) string {
+		var out []string
+		for _, k := range raw {
+			out = append(out, strings.Trim(string(k), `"`))
+		}
+		return strings.Join(out, ",")
+	}
+	return strings.Join(titles, ",") + " found " + keys(b.Found) + " missing " + keys(b.Missing)
+}
+
+func TestBatchGetVideos(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+	})
+	a := models.Video{UploadID: "up-a", UserID: "owner", Title: "a", Status: models.StatusReady}
+	b := models.Video{UploadID: "up-b", UserID: "owner", Title: "b", Status: models.StatusReady}
+	private := models.Video{UploadID: "up-p", UserID: "owner", Title: "private", Status: models.StatusReady, Visibility: models.VisibilityPrivate, ShareToken: "tok"}
+	gone := models.Video{UploadID: "up-g", UserID: "owner", Title: "gone", Status: models.StatusReady}
+	for _, v := range []*models.Video{&a, &b, &private, &gone} {
+		db.Create(v)
+	}
+	db.Delete(&gone)
+
+	batch := func(query, user string) batchBody {
+		t.Helper()
+		w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/batch?"+query, "", user, ""))
+		var body batchBody
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", query, w.Code, w.Body)
+		}
+		return body
+	}
+
+	// Request order, not id order; repeats once; missing, deleted and hidden ones listed apart
+	ids := "ids=" + strings.Join([]string{itoa(b.ID), itoa(a.ID), "999", itoa(private.ID), itoa(a.ID), itoa(gone.ID)}, ",")
+	tests := []struct {
+		name, query, user, want string
+	}{
+		{"stranger by id", ids, "stranger", "b,a found " + itoa(b.ID) + "," + itoa(a.ID) + " missing 999," + itoa(private.ID) + "," + itoa(gone.ID)},
+		{"owner by id", ids, "owner", "b,a,private found " + itoa(b.ID) + "," + itoa(a.ID) + "," + itoa(private.ID) + " missing 999," + itoa(gone.ID)},
+		{"signed out by upload id", "upload_ids=up-p,%20up-b,,nope,up-a", "", "b,a found up-b,up-a missing up-p,nope"},
+		{"owner by upload id", "upload_ids=up-p,up-b,up-a", "owner", "private,b,a found up-p,up-b,up-a missing "},
+	}
+	for _, tt := range tests {
+		if got := batch(tt.query, tt.user).String(); got != tt.want {
+			t.Errorf("%s: %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+
+	// The limit is 100 keys
+	many := make([]string, 100)
+	for i := range many {
+		many[i] = itoa(uint(i + 1))
+	}
+	if got := batch("ids="+strings.Join(many, ","), "owner"); len(got.Found)+len(got.Missing) != 100 {
+		t.Errorf("100 ids: %d found, %d missing", len(got.Found), len(got.Missing))
+	}
+	errs := []struct{ name, query string }{
+		{"101 ids", "ids=" + strings.Join(many, ",") + ",101"},
+		{"101 upload ids", "upload_ids=" + strings.Repeat("u,", 101)},
+		{"neither", ""},
+		{"both", "ids=1&upload_ids=up-a"},
+		{"only commas", "ids=,,"},
+		{"not a number", "ids=1,abc"},
+		{"zero", "ids=0"},
+	}
+	for _, tt := range errs {
+		w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/batch?"+tt.query, "", "owner", ""))
+		if w.Code != http.StatusBadRequest || errorCode(w) != api.CodeInvalidRequest {
+			t.Errorf("%s: %d %s, want 400 %s", tt.name, w.Code, w.Body, api.CodeInvalidRequest)
+		}
+	}
+	w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/batch?"+errs[0].query, "", "owner", ""))
+	if body := decodeError(t, w.Body.Bytes()); string(body.Details) != `{"max":100}` {
+		t.Errorf("over the limit: details %s, want the maximum", body.Details)
+	}
+}
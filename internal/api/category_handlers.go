@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// ListCategories handles GET /api/v1/categories - the active categories videos can
+// be filed under. Admins can add ?include_inactive=true to see retired ones too.
+func (h *VideoHandler) ListCategories(c *gin.Context) {
+	includeInactive := c.Query("include_inactive") == "true" && hasRole(identityFrom(c).Roles, "admin")
+	categories, err := h.categories.List(c.Request.Context(), includeInactive)
+	if err != nil {
+		h.log(c).Errorw("Failed to list categories", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list categories", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+// AdminCreateCategory handles POST /api/v1/admin/categories with
+// {"slug": "cooking", "display_name": "Cooking", "active": true}
+func (h *VideoHandler) AdminCreateCategory(c *gin.Context) {
+	var req models.CategoryCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	entry, ok := h.beginAudit(c, models.AuditActionCreateCategory, "", "category:"+req.Slug, req.DisplayName)
+	if !ok {
+		return
+	}
+	category, err := h.categories.Create(c.Request.Context(), &req)
+	h.finishAudit(c, entry, err)
+	if err != nil {
+		h.categoryWriteFailed(c, err, req.Slug)
+		return
+	}
+
+	h.log(c).Infow("Category created by admin", "slug", category.Slug, "admin", identityFrom(c).ActorID)
+	c.JSON(http.StatusCreated, category)
+}
+
+// AdminUpdateCategory handles PUT /api/v1/admin/categories/:slug with
+// {"display_name": "...", "active": false}. The slug itself can't change.
+func (h *VideoHandler) AdminUpdateCategory(c *gin.Context) {
+	slug := c.Param("slug")
+	var req models.CategoryUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	detail := ""
+	if req.DisplayName != nil {
+		detail = fmt.Sprintf("display_name=%q ", *req.DisplayName)
+	}
+	if req.Active != nil {
+		detail += fmt.Sprintf("active=%t", *req.Active)
+	}
+	entry, ok := h.beginAudit(c, models.AuditActionUpdateCategory, "", "category:"+slug, detail)
+	if !ok {
+		return
+	}
+	category, err := h.categories.Update(c.Request.Context(), slug, &req)
+	h.finishAudit(c, entry, err)
+	if err != nil {
+		h.categoryWriteFailed(c, err, slug)
+		return
+	}
+
+	h.log(c).Infow("Category updated by admin", "slug", slug, "admin", identityFrom(c).ActorID)
+	c.JSON(http.StatusOK, category)
+}
+
+// AdminDeleteCategory handles DELETE /api/v1/admin/categories/:slug. A category
+// still on videos is a 409; deactivate it instead.
+func (h *VideoHandler) AdminDeleteCategory(c *gin.Context) {
+	slug := c.Param("slug")
+	entry, ok := h.beginAudit(c, models.AuditActionDeleteCategory, "", "category:"+slug, "")
+	if !ok {
+		return
+	}
+	err := h.categories.Delete(c.Request.Context(), slug)
+	h.finishAudit(c, entry, err)
+	if err != nil {
+		h.categoryWriteFailed(c, err, slug)
+		return
+	}
+
+	h.log(c).Infow("Category deleted by admin", "slug", slug, "admin", identityFrom(c).ActorID)
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// categoryWriteFailed maps a category service error onto a response
+func (h *VideoHandler) categoryWriteFailed(c *gin.Context, err error, slug string) {
+	var invalid *services.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		respondFieldErrors(c, invalid.Error(), invalid.Fields)
+	case errors.Is(err, services.ErrCategoryNotFound):
+		respondError(c, http.StatusNotFound, CodeCategoryNotFound, "Category not found", nil)
+	case errors.Is(err, services.ErrCategoryExists):
+		respondError(c, http.StatusConflict, CodeCategoryExists, "Category already exists", nil)
+	case errors.Is(err, services.ErrCategoryInUse):
+		respondError(c, http.StatusConflict, CodeCategoryInUse, "Category is in use; deactivate it instead", nil)
+	default:
+		h.log(c).Errorw("Failed to write category", "error", err, "slug", slug)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to write category", nil)
+	}
+}
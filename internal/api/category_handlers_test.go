@@ -0,0 +1,151 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// categoryRouter serves the API with managed categories: music and gaming active,
+// retired not
+func categoryRouter(t *testing.T) (*gorm.DB, http.Handler) {
+	t.Helper()
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	db.Create(&[]models.Category{
+		{Slug: "music", DisplayName: "Music", Active: true},
+		{Slug: "gaming", DisplayName: "Gaming", Active: true},
+		{Slug: "retired", DisplayName: "Retired"},
+	})
+	categories := services.NewCategoryService(db, log)
+	videos := services.NewVideoService(db, nil, videoSettings, log)
+	videos.SetCategories(categories)
+	return db, newRouter(api.Dependencies{
+		Videos:     videos,
+		Categories: categories,
+		Audit:      services.NewAuditService(db, log),
+	})
+}
+
+func TestListCategories(t *testing.T) {
+	_, router := categoryRouter(t)
+	list := func(query, user, roles string) []string {
+		t.Helper()
+		w := serve(router, adminRequest(http.MethodGet, "/api/v1/categories"+query, "", user, roles))
+		var body struct {
+			Categories []models.Category `json:"categories"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("list: status %d: %s", w.Code, w.Body)
+		}
+		var slugs []string
+		for _, c := range body.Categories {
+			slugs = append(slugs, c.Slug)
+		}
+		return slugs
+	}
+	active := []string{"gaming", "music"}
+	if got := list("", "", ""); !reflect.DeepEqual(got, active) {
+		t.Errorf("signed out: %v, want %v", got, active)
+	}
+	if got := list("?include_inactive=true", "alice", ""); !reflect.DeepEqual(got, active) {
+		t.Errorf("non-admin asking for inactive: %v, want only %v", got, active)
+	}
+	if got := list("?include_inactive=true", "admin-1", "admin"); !reflect.DeepEqual(got, []string{"gaming", "music", "retired"}) {
+		t.Errorf("admin asking for inactive: %v", got)
+	}
+}
+
+func TestVideoCategoryValidation(t *testing.T) {
+	_, router := categoryRouter(t)
+	w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-1","title":"t","category":"Music"}`, "owner", ""))
+	var video models.Video
+	if err := json.Unmarshal(w.Body.Bytes(), &video); err != nil || w.Code != http.StatusCreated || video.Category != "music" {
+		t.Fatalf("create in Music: status %d: %s", w.Code, w.Body)
+	}
+
+	requests := []struct {
+		name, method, path, body string
+	}{
+		{"create", http.MethodPost, "/api/v1/videos", `{"upload_id":"up-2","title":"t","category":"Müsik"}`},
+		{"create in a retired category", http.MethodPost, "/api/v1/videos", `{"upload_id":"up-3","title":"t","category":"retired"}`},
+		{"update", http.MethodPut, "/api/v1/videos/" + itoa(video.ID), `{"category":"polka"}`},
+	}
+	for _, tt := range requests {
+		w := serve(router, adminRequest(tt.method, tt.path, tt.body, "owner", ""))
+		var body struct {
+			api.ErrorResponse
+			Details struct {
+				AllowedCategories []string `json:"allowed_categories"`
+			} `json:"details"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusBadRequest || body.Code != api.CodeInvalidCategory || !reflect.DeepEqual(body.Details.AllowedCategories, []string{"gaming", "music"}) {
+			t.Errorf("%s: status %d: %s; want 400 %s listing the active slugs", tt.name, w.Code, w.Body, api.CodeInvalidCategory)
+		}
+	}
+}
+
+func TestAdminCategories(t *testing.T) {
+	db, router := categoryRouter(t)
+	db.Create(&models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Category: "music"})
+
+	tests := []struct {
+		name, method, path, body, roles string
+		status                          int
+		code                            string
+	}{
+		{"create as a non-admin", http.MethodPost, "/api/v1/admin/categories", `{"slug":"cooking","display_name":"Cooking"}`, "", http.StatusForbidden, api.CodeForbidden},
+		{"create", http.MethodPost, "/api/v1/admin/categories", `{"slug":"cooking","display_name":"Cooking"}`, "admin", http.StatusCreated, ""},
+		{"create again", http.MethodPost, "/api/v1/admin/categories", `{"slug":"cooking","display_name":"Cooking"}`, "admin", http.StatusConflict, api.CodeCategoryExists},
+		{"create with a bad slug", http.MethodPost, "/api/v1/admin/categories", `{"slug":"Home Garden","display_name":"Home"}`, "admin", http.StatusBadRequest, api.CodeValidationFailed},
+		{"create without a name", http.MethodPost, "/api/v1/admin/categories", `{"slug":"home"}`, "admin", http.StatusBadRequest, api.CodeValidationFailed},
+		{"retire", http.MethodPut, "/api/v1/admin/categories/cooking", `{"active":false}`, "admin", http.StatusOK, ""},
+		{"rename to blank", http.MethodPut, "/api/v1/admin/categories/cooking", `{"display_name":" "}`, "admin", http.StatusBadRequest, api.CodeValidationFailed},
+		{"update a missing one", http.MethodPut, "/api/v1/admin/categories/baking", `{"active":true}`, "admin", http.StatusNotFound, api.CodeCategoryNotFound},
+		{"delete one in use", http.MethodDelete, "/api/v1/admin/categories/music", "", "admin", http.StatusConflict, api.CodeCategoryInUse},
+		{"delete as a non-admin", http.MethodDelete, "/api/v1/admin/categories/cooking", "", "", http.StatusForbidden, api.CodeForbidden},
+		{"delete", http.MethodDelete, "/api/v1/admin/categories/cooking", "", "admin", http.StatusOK, ""},
+		{"delete a missing one", http.MethodDelete, "/api/v1/admin/categories/cooking", "", "admin", http.StatusNotFound, api.CodeCategoryNotFound},
+	}
+	for _, tt := range tests {
+		w := serve(router, adminRequest(tt.method, tt.path, tt.body, "admin-1", tt.roles))
+		if w.Code != tt.status || errorCode(w) != tt.code {
+			t.Errorf("%s: %d %s, want %d %q", tt.name, w.Code, w.Body, tt.status, tt.code)
+		}
+	}
+
+	var music models.Category
+	db.First(&music, "slug = ?", "music")
+	if !music.Active {
+		t.Error("refused delete changed the category")
+	}
+	// Each admin call that got past the role check is audited with its outcome
+	var entries []models.AuditLog
+	db.Where("target = ?", "category:cooking").Order("id").Find(&entries)
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Action+" "+e.Outcome)
+	}
+	ok, failed := " "+models.AuditOutcomeSucceeded, " "+models.AuditOutcomeFailed
+	want := []string{
+		models.AuditActionCreateCategory + ok,
+		models.AuditActionCreateCategory + failed,
+		models.AuditActionUpdateCategory + ok,
+		models.AuditActionUpdateCategory + failed,
+		models.AuditActionDeleteCategory + ok,
+		models.AuditActionDeleteCategory + failed,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("audit for cooking:\n%v\nwant\n%v", got, want)
+	}
+}
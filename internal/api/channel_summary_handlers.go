@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChannelSummary handles GET /api/v1/users/:userID/summary - video count, total
+// views and duration, latest upload and top categories for a profile page. The
+// owner's numbers cover all their videos; everyone else's cover public ones only.
+func (h *VideoHandler) GetChannelSummary(c *gin.Context) {
+	userID := c.Param("userID")
+	owner := currentUser(c) == userID
+	summary, err := h.videoService.ChannelSummary(c.Request.Context(), userID, owner)
+	if err != nil {
+		h.log(c).Errorw("Failed to get channel summary", "error", err, "userID", userID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get channel summary", nil)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
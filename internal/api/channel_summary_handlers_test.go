@@ -0,0 +1,39 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestGetChannelSummary(t *testing.T) {
+	db := dbtest.Open(t)
+	router := newRouter(api.Dependencies{Videos: services.NewVideoService(db, nil, videoSettings, zap.NewNop().Sugar())})
+	for i, visibility := range []models.Visibility{models.VisibilityPublic, models.VisibilityPrivate, models.VisibilityUnlisted} {
+		db.Create(&models.Video{UploadID: "up-" + itoa(uint(i)), UserID: "owner", Title: "t", Visibility: visibility, ViewCount: 10})
+	}
+
+	tests := []struct {
+		name, user   string
+		count, views int64
+	}{
+		{"owner", "owner", 3, 30},
+		{"someone else", "stranger", 1, 10},
+		{"anonymous", "", 1, 10},
+	}
+	for _, tt := range tests {
+		w := serve(router, adminRequest(http.MethodGet, "/api/v1/users/owner/summary", "", tt.user, ""))
+		var summary services.ChannelSummary
+		json.Unmarshal(w.Body.Bytes(), &summary)
+		if w.Code != http.StatusOK || summary.UserID != "owner" || summary.VideoCount != tt.count || summary.TotalViews != tt.views {
+			t.Errorf("%s: status %d: %s; want %d videos and %d views", tt.name, w.Code, w.Body, tt.count, tt.views)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// TestVideoChapters sets chapters through PUT and checks clients can tell chapters
+// never set (null) from removed ones ([])
+func TestVideoChapters(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+	})
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Status: models.StatusReady, Duration: 300}
+	db.Create(&video)
+	path := "/api/v1/videos/" + itoa(video.ID)
+
+	chapters := func() string {
+		t.Helper()
+		w := serve(router, adminRequest(http.MethodGet, path, "", "owner", ""))
+		var body struct {
+			Chapters json.RawMessage `json:"chapters"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("get: %s", w.Body)
+		}
+		return string(body.Chapters)
+	}
+	put := func(body string) *models.Video {
+		t.Helper()
+		w := serve(router, adminRequest(http.MethodPut, path, body, "owner", ""))
+		if w.Code != http.StatusOK {
+			t.Fatalf("put %s: status %d: %s", body, w.Code, w.Body)
+		}
+		var got models.Video
+		json.Unmarshal(w.Body.Bytes(), &got)
+		return &got
+	}
+
+	if got := chapters(); got != "null" {
+		t.Errorf("chapters never set = %s, want null", got)
+	}
+	saved := put(`{"chapters":[{"title":"Intro","start_seconds":0},{"title":"Demo","start_seconds":42.5}]}`)
+	if len(saved.Chapters) != 2 || saved.Chapters[1].Title != "Demo" || saved.Chapters[1].StartSeconds != 42.5 {
+		t.Errorf("saved chapters %+v", saved.Chapters)
+	}
+	if got := chapters(); got != `[{"title":"Intro","start_seconds":0},{"title":"Demo","start_seconds":42.5}]` {
+		t.Errorf("chapters = %s", got)
+	}
+
+	// Each offending field is reported
+	w := serve(router, adminRequest(http.MethodPut, path, `{"chapters":[{"title":"","start_seconds":10},{"title":"B","start_seconds":5},{"title":"C","start_seconds":300}]}`, "owner", ""))
+	body := decodeError(t, w.Body.Bytes())
+	var fields []api.FieldError
+	json.Unmarshal(body.Details, &fields)
+	want := []string{"chapters[0].title", "chapters[1].start_seconds", "chapters[2].start_seconds"}
+	if w.Code != http.StatusBadRequest || body.Code != api.CodeValidationFailed || len(fields) != len(want) {
+		t.Fatalf("invalid chapters: %d %s", w.Code, w.Body)
+	}
+	for i, field := range want {
+		if fields[i].Field != field || fields[i].Message == "" {
+			t.Errorf("field error %d = %+v, want one for %s", i, fields[i], field)
+		}
+	}
+
+	// Updates without chapters keep them; an empty list removes them
+	put(`{"title":"renamed"}`)
+	if got := chapters(); got == "null" || got == "[]" {
+		t.Errorf("title update changed chapters to %s", got)
+	}
+	put(`{"chapters":[]}`)
+	if got := chapters(); got != "[]" {
+		t.Errorf("removed chapters = %s, want []", got)
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// UpdateComment handles PUT /api/v1/comments/:commentID - the author fixing their
+// comment's content within the edit window. Video owners can delete others'
+// comments but not edit them.
+func (h *VideoHandler) UpdateComment(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("commentID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid comment ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	var req models.CommentUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	comment, err := h.commentSvc.UpdateComment(c.Request.Context(), uint(cid), requester, req.Content)
+	if err != nil {
+		var invalid *services.ValidationError
+		switch {
+		case errors.As(err, &invalid):
+			respondFieldErrors(c, invalid.Error(), invalid.Fields)
+		case errors.Is(err, services.ErrCommentNotFound):
+			respondError(c, http.StatusNotFound, CodeCommentNotFound, "Comment not found", nil)
+		case errors.Is(err, services.ErrForbidden):
+			respondError(c, http.StatusForbidden, CodeForbidden, "Only the author can edit a comment", nil)
+		case errors.Is(err, services.ErrEditWindowClosed):
+			respondError(c, http.StatusForbidden, CodeEditWindowClosed, "Edit window has closed", gin.H{"edit_window_seconds": int64(h.commentSvc.EditWindow().Seconds())})
+		default:
+			h.log(c).Errorw("Failed to update comment", "error", err, "commentID", cid)
+			respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to update comment", nil)
+		}
+		return
+	}
+	h.recentWrites.Mark(commentWriteKey(requester, comment.VideoID))
+	c.JSON(http.StatusOK, comment)
+}
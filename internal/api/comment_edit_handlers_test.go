@@ -0,0 +1,83 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/cache"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestUpdateComment(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:       services.NewVideoService(db, nil, videoSettings, log),
+		Comments:     services.NewCommentService(db, commentSettings, log),
+		RecentWrites: cache.NewRecentWrites(time.Minute, 100),
+	})
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", CommentsEnabled: true}
+	db.Create(&video)
+	fresh := models.Comment{VideoID: video.ID, UserID: "alice", Content: "teh", Status: models.CommentVisible}
+	db.Create(&fresh)
+	old := models.Comment{VideoID: video.ID, UserID: "alice", Content: "old", Status: models.CommentVisible,
+		CreatedAt: time.Now().Add(-16 * time.Minute)}
+	db.Create(&old)
+
+	w := serve(router, adminRequest(http.MethodPut, "/api/v1/comments/"+itoa(fresh.ID), `{"content":"the"}`, "alice", ""))
+	var edited struct {
+		Content  string     `json:"content"`
+		EditedAt *time.Time `json:"edited_at"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &edited)
+	if w.Code != http.StatusOK || edited.Content != "the" || edited.EditedAt == nil {
+		t.Errorf("author's edit: status %d: %s, want 200 with edited_at", w.Code, w.Body)
+	}
+
+	for _, tt := range []struct {
+		name, user, body string
+		id               uint
+		status           int
+		code             string
+	}{
+		{"video owner", "owner", `{"content":"mine now"}`, fresh.ID, http.StatusForbidden, api.CodeForbidden},
+		{"anonymous", "", `{"content":"x"}`, fresh.ID, http.StatusUnauthorized, api.CodeUnauthorized},
+		{"empty", "alice", `{"content":""}`, fresh.ID, http.StatusBadRequest, api.CodeValidationFailed},
+		{"too long", "alice", `{"content":"` + strings.Repeat("x", 2001) + `"}`, fresh.ID, http.StatusBadRequest, api.CodeValidationFailed},
+		{"unknown comment", "alice", `{"content":"x"}`, 999, http.StatusNotFound, api.CodeCommentNotFound},
+	} {
+		w := serve(router, adminRequest(http.MethodPut, "/api/v1/comments/"+itoa(tt.id), tt.body, tt.user, ""))
+		if w.Code != tt.status || errorCode(w) != tt.code {
+			t.Errorf("%s: status %d: %s, want %d %s", tt.name, w.Code, w.Body, tt.status, tt.code)
+		}
+	}
+
+	// Past the default 15 minute window the author is refused too
+	w = serve(router, adminRequest(http.MethodPut, "/api/v1/comments/"+itoa(old.ID), `{"content":"fixed"}`, "alice", ""))
+	var closed struct {
+		Code    string `json:"code"`
+		Details struct {
+			EditWindowSeconds int64 `json:"edit_window_seconds"`
+		} `json:"details"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &closed)
+	if w.Code != http.StatusForbidden || closed.Code != api.CodeEditWindowClosed || closed.Details.EditWindowSeconds != 900 {
+		t.Errorf("edit after the window: status %d: %s, want 403 %s with a 900s window", w.Code, w.Body, api.CodeEditWindowClosed)
+	}
+	var stored models.Comment
+	db.First(&stored, old.ID)
+	if stored.Content != "old" || stored.EditedAt != nil {
+		t.Errorf("refused edit changed the comment: %+v", stored)
+	}
+	if w := serve(router, adminRequest(http.MethodPut, "/api/v1/comments/"+itoa(fresh.ID), `{"content":"the end"}`, "alice", "")); w.Code != http.StatusOK {
+		t.Errorf("second edit within the window: status %d", w.Code)
+	}
+}
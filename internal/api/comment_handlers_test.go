@@ -0,0 +1,240 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/cache"
+	"github.com/streamhive/video-catalog-api/internal/cursor"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+type commentPage struct {
+	Comments   []models.Comment `json:"comments"`
+	Total      int64            `json:"total"`
+	TotalPages int              `json:"total_pages"`
+	Page       int              `json:"page"`
+	PerPage    int              `json:"per_page"`
+}
+
+func decodeComments(t *testing.T, body []byte) commentPage {
+	t.Helper()
+	var p commentPage
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	return p
+}
+
+// TestCommentReadYourWrites posts a comment against a primary whose replica never
+// catches up: the author's reads go to the primary for the window, everyone
+// else's keep using the lagging replica
+func TestCommentReadYourWrites(t *testing.T) {
+	primary, replica := dbtest.Open(t), dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	comments := services.NewCommentService(primary, commentSettings, log)
+	comments.SetReplica(replica)
+	recent := cache.NewRecentWrites(time.Minute, 100)
+	router := newRouter(api.Dependencies{
+		Videos:       services.NewVideoService(primary, nil, videoSettings, log),
+		Comments:     comments,
+		RecentWrites: recent,
+	})
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t", Visibility: models.VisibilityPublic, Status: models.StatusReady, CommentsEnabled: true}
+	primary.Create(&video)
+	replica.Create(&video)
+	path := "/api/v1/videos/" + itoa(video.ID) + "/comments"
+
+	w := serve(router, adminRequest(http.MethodPost, path, `{"content":"first!"}`, "alice", ""))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("post: status %d: %s", w.Code, w.Body)
+	}
+	var posted struct {
+		ID               uint  `json:"id"`
+		Position         int   `json:"position"`
+		Total            int64 `json:"total"`
+		ReadYourWritesMs int64 `json:"read_your_writes_ms"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &posted)
+	if posted.Position != 1 || posted.Total != 1 || posted.ReadYourWritesMs != time.Minute.Milliseconds() {
+		t.Errorf("position context = %+v", posted)
+	}
+
+	list := func(user string) (commentPage, string) {
+		w := serve(router, adminRequest(http.MethodGet, path, "", user, ""))
+		if w.Code != http.StatusOK {
+			t.Fatalf("list as %q: status %d", user, w.Code)
+		}
+		return decodeComments(t, w.Body.Bytes()), w.Header().Get("Cache-Control")
+	}
+	if page, cacheControl := list("alice"); len(page.Comments) != 1 || page.Comments[0].ID != posted.ID || cacheControl != "no-store" {
+		t.Errorf("author right after posting: %d comments, Cache-Control %q", len(page.Comments), cacheControl)
+	}
+	if page, cacheControl := list("bob"); len(page.Comments) != 0 || cacheControl == "no-store" {
+		t.Errorf("other users should read the lagging replica: %d comments, Cache-Control %q", len(page.Comments), cacheControl)
+	}
+}
+
+func TestListCommentsPaging(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:   services.NewVideoService(db, nil, videoSettings, log),
+		Comments: services.NewCommentService(db, commentSettings, log),
+		Cursors:  cursor.NewCodec([]byte("secret"), time.Hour),
+	})
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t", Visibility: models.VisibilityPublic, Status: models.StatusReady, CommentsEnabled: true}
+	db.Create(&video)
+	for i := 0; i < 25; i++ {
+		db.Create(&models.Comment{VideoID: video.ID, UserID: "u", Content: "c", Status: models.CommentVisible})
+	}
+	path := "/api/v1/videos/" + itoa(video.ID) + "/comments"
+
+	tests := []struct {
+		query                string
+		page, perPage, count int
+		totalPages           int
+	}{
+		{"", 1, 20, 20, 2},
+		{"?page=0", 1, 20, 20, 2},
+		{"?page=-3", 1, 20, 20, 2},
+		{"?page=2", 2, 20, 5, 2},
+		{"?page=9", 9, 20, 0, 2},
+		{"?per_page=10&page=3", 3, 10, 5, 3},
+		{"?per_page=101", 1, 20, 20, 2},
+		{"?per_page=0", 1, 20, 20, 2},
+		{"?per_page=100", 1, 100, 25, 1},
+		{"?per_page=abc", 1, 20, 20, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := serve(router, adminRequest(http.MethodGet, path+tt.query, "", "", ""))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			p := decodeComments(t, w.Body.Bytes())
+			if p.Page != tt.page || p.PerPage != tt.perPage || len(p.Comments) != tt.count || p.Total != 25 || p.TotalPages != tt.totalPages {
+				t.Errorf("page %d per_page %d: %d comments, total %d, total_pages %d", p.Page, p.PerPage, len(p.Comments), p.Total, p.TotalPages)
+			}
+		})
+	}
+}
+
+func TestAddCommentAuthorName(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:   services.NewVideoService(db, nil, videoSettings, log),
+		Comments: services.NewCommentService(db, commentSettings, log),
+	})
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t", Visibility: models.VisibilityPublic, Status: models.StatusReady, CommentsEnabled: true}
+	db.Create(&video)
+	path := "/api/v1/videos/" + itoa(video.ID) + "/comments"
+
+	tests := []struct {
+		name, header, body, want string
+	}{
+		{"from X-Username", "Alice A.", `{"content":"hi"}`, "Alice A."},
+		{"explicit author_name wins", "Alice A.", `{"content":"hi","author_name":"Al"}`, "Al"},
+		{"no name", "", `{"content":"hi"}`, ""},
+		{"long header is cut", strings.Repeat("é", 130), `{"content":"hi"}`, strings.Repeat("é", 120)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := adminRequest(http.MethodPost, path, tt.body, "alice", "")
+			if tt.header != "" {
+				req.Header.Set("X-Username", tt.header)
+			}
+			w := serve(router, req)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			var posted models.Comment
+			json.Unmarshal(w.Body.Bytes(), &posted)
+			if posted.Username != tt.want {
+				t.Errorf("username = %q, want %q", posted.Username, tt.want)
+			}
+		})
+	}
+}
+
+func TestListCommentsFirstPage(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:   services.NewVideoService(db, nil, videoSettings, log),
+		Comments: services.NewCommentService(db, commentSettings, log),
+		Cursors:  cursor.NewCodec([]byte("secret"), time.Hour),
+	})
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t", Visibility: models.VisibilityPublic, Status: models.StatusReady, CommentsEnabled: true}
+	db.Create(&video)
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 40; i++ {
+		db.Create(&models.Comment{VideoID: video.ID, UserID: "u", Content: "c", Status: models.CommentVisible, CreatedAt: base.Add(time.Duration(i) * time.Second)})
+	}
+	path := "/api/v1/videos/" + itoa(video.ID) + "/comments"
+
+	type page struct {
+		Comments   []models.Comment `json:"comments"`
+		PerPage    int              `json:"per_page"`
+		PageSize   int              `json:"page_size"`
+		TotalPages *int             `json:"total_pages"`
+		NextCursor string           `json:"next_cursor"`
+	}
+	get := func(query string) (int, page) {
+		w := serve(router, adminRequest(http.MethodGet, path+"?"+query, "", "", ""))
+		var p page
+		json.Unmarshal(w.Body.Bytes(), &p)
+		return w.Code, p
+	}
+
+	tests := []struct {
+		name string
+		// first is the initial query; then is added to the query that follows its cursor
+		first, then string
+		// sizes are the page sizes of the first page and of the page after it
+		firstSize, nextSize int
+	}{
+		{"first alone hands off to the default size", "first=5", "", 5, 20},
+		{"first with per_page hands off to per_page", "first=5&per_page=8", "", 5, 8},
+		{"per_page on a cursor request overrides the carried size", "first=5&per_page=8", "per_page=3", 5, 3},
+		{"per_page alone", "per_page=7", "", 7, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, p := get(tt.first)
+			if status != http.StatusOK || len(p.Comments) != tt.firstSize || p.PageSize != tt.firstSize || p.NextCursor == "" {
+				t.Fatalf("first page: status %d, %d comments, page_size %d", status, len(p.Comments), p.PageSize)
+			}
+			if strings.Contains(tt.first, "first=") && p.TotalPages != nil {
+				t.Error("total_pages given for a short first page; page numbers don't line up")
+			}
+			query := "cursor=" + url.QueryEscape(p.NextCursor)
+			if tt.then != "" {
+				query += "&" + tt.then
+			}
+			status, next := get(query)
+			if status != http.StatusOK || len(next.Comments) != tt.nextSize || next.PageSize != tt.nextSize || next.PerPage != tt.nextSize {
+				t.Fatalf("next page: status %d, %d comments, page_size %d, per_page %d; want %d", status, len(next.Comments), next.PageSize, next.PerPage, tt.nextSize)
+			}
+			if next.Comments[0].ID != p.Comments[len(p.Comments)-1].ID-1 {
+				t.Errorf("next page starts at %d after %d", next.Comments[0].ID, p.Comments[len(p.Comments)-1].ID)
+			}
+		})
+	}
+
+	for _, query := range []string{"first=0", "first=101", "first=x", "first=5&page=2"} {
+		if status, _ := get(query); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, status)
+		}
+	}
+}
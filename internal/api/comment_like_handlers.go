@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// LikeComment handles POST /api/v1/comments/:commentID/like
+func (h *VideoHandler) LikeComment(c *gin.Context) {
+	h.likeComment(c, true)
+}
+
+// UnlikeComment handles DELETE /api/v1/comments/:commentID/like. Unliking a
+// comment the caller never liked succeeds and changes nothing.
+func (h *VideoHandler) UnlikeComment(c *gin.Context) {
+	h.likeComment(c, false)
+}
+
+// likeComment sets or clears the caller's like and responds with the comment's
+// updated count. Only visible comments on videos the caller can see can be liked.
+func (h *VideoHandler) likeComment(c *gin.Context, like bool) {
+	cid, err := strconv.ParseUint(c.Param("commentID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid comment ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	comment, err := h.commentSvc.GetComment(c.Request.Context(), uint(cid))
+	if err == nil && comment.Status != models.CommentVisible {
+		err = services.ErrCommentNotFound
+	}
+	if err != nil {
+		h.commentLikeFailed(c, err, uint(cid))
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), comment.VideoID)
+	if err != nil {
+		h.videoLookupFailed(c, err, comment.VideoID)
+		return
+	}
+	if !canView(c, video) {
+		respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+		return
+	}
+
+	var likes *services.CommentLikes
+	if like {
+		likes, err = h.commentSvc.LikeComment(c.Request.Context(), comment.ID, requester)
+	} else {
+		likes, err = h.commentSvc.UnlikeComment(c.Request.Context(), comment.ID, requester)
+	}
+	if err != nil {
+		h.commentLikeFailed(c, err, comment.ID)
+		return
+	}
+	h.recentWrites.Mark(commentWriteKey(requester, comment.VideoID))
+	c.JSON(http.StatusOK, likes)
+}
+
+func (h *VideoHandler) commentLikeFailed(c *gin.Context, err error, commentID uint) {
+	if errors.Is(err, services.ErrCommentNotFound) {
+		respondError(c, http.StatusNotFound, CodeCommentNotFound, "Comment not found", nil)
+		return
+	}
+	h.log(c).Errorw("Failed to update comment like", "error", err, "commentID", commentID)
+	respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to update comment like", nil)
+}
+
+// attachCommentLikes fills in whether the caller likes each comment, with one
+// query for the whole page. Anonymous callers get nothing, and a failed lookup
+// only leaves the field out.
+func (h *VideoHandler) attachCommentLikes(c *gin.Context, comments []models.Comment) {
+	requester := currentUser(c)
+	if requester == "" || len(comments) == 0 {
+		return
+	}
+	ids := make([]uint, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+	}
+	liked, err := h.commentSvc.UserCommentLikes(c.Request.Context(), requester, ids)
+	if err != nil {
+		h.log(c).Warnw("Failed to load comment likes", "error", err, "userID", requester)
+		return
+	}
+	for i := range comments {
+		mine := liked[comments[i].ID]
+		comments[i].Liked = &mine
+	}
+}
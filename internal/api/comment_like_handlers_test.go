@@ -0,0 +1,103 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestCommentLikeHandlers(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:   services.NewVideoService(db, nil, videoSettings, log),
+		Comments: services.NewCommentService(db, commentSettings, log),
+	})
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t", Status: models.StatusReady, CommentsEnabled: true}
+	db.Create(&video)
+	base := time.Now().UTC().Add(-time.Hour)
+	older := models.Comment{VideoID: video.ID, UserID: "u", Content: "older", Status: models.CommentVisible, CreatedAt: base}
+	newer := models.Comment{VideoID: video.ID, UserID: "u", Content: "newer", Status: models.CommentVisible, CreatedAt: base.Add(time.Minute)}
+	hidden := models.Comment{VideoID: video.ID, UserID: "u", Content: "hidden", Status: models.CommentPending, CreatedAt: base}
+	db.Create(&older)
+	db.Create(&newer)
+	db.Create(&hidden)
+
+	like := func(method string, id uint, user string) (int, services.CommentLikes) {
+		t.Helper()
+		w := serve(router, adminRequest(method, "/api/v1/comments/"+itoa(id)+"/like", "", user, ""))
+		var likes services.CommentLikes
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &likes); err != nil {
+				t.Fatalf("decode %s: %v", w.Body, err)
+			}
+		}
+		return w.Code, likes
+	}
+	tests := []struct {
+		name   string
+		method string
+		id     uint
+		user   string
+		status int
+		count  int64
+		liked  bool
+	}{
+		{"unlike, never liked", http.MethodDelete, older.ID, "alice", http.StatusOK, 0, false},
+		{"like", http.MethodPost, older.ID, "alice", http.StatusOK, 1, true},
+		{"like twice", http.MethodPost, older.ID, "alice", http.StatusOK, 1, true},
+		{"another user", http.MethodPost, older.ID, "bob", http.StatusOK, 2, true},
+		{"unlike", http.MethodDelete, older.ID, "bob", http.StatusOK, 1, false},
+		{"unlike twice", http.MethodDelete, older.ID, "bob", http.StatusOK, 1, false},
+		{"anonymous", http.MethodPost, older.ID, "", http.StatusUnauthorized, 0, false},
+		{"hidden comment", http.MethodPost, hidden.ID, "alice", http.StatusNotFound, 0, false},
+		{"missing comment", http.MethodPost, 999, "alice", http.StatusNotFound, 0, false},
+	}
+	for _, tt := range tests {
+		status, likes := like(tt.method, tt.id, tt.user)
+		if status != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, status, tt.status)
+			continue
+		}
+		if status == http.StatusOK && (likes.LikeCount != tt.count || likes.Liked != tt.liked) {
+			t.Errorf("%s: %+v, want %d likes, liked %v", tt.name, likes, tt.count, tt.liked)
+		}
+	}
+	var stored models.Comment
+	db.First(&stored, hidden.ID)
+	if stored.LikeCount != 0 {
+		t.Errorf("hidden comment like_count = %d", stored.LikeCount)
+	}
+
+	// older leads on likes; each caller sees their own flag, anonymous callers none
+	path := "/api/v1/videos/" + itoa(video.ID) + "/comments?sort=top"
+	for user, want := range map[string][]bool{"alice": {true, false}, "bob": {false, false}} {
+		w := serve(router, adminRequest(http.MethodGet, path, "", user, ""))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: list status %d: %s", user, w.Code, w.Body)
+		}
+		page := decodeComments(t, w.Body.Bytes())
+		if len(page.Comments) != 2 || page.Comments[0].ID != older.ID || page.Comments[1].ID != newer.ID {
+			t.Fatalf("%s: top = %+v, want older then newer", user, page.Comments)
+		}
+		for i, c := range page.Comments {
+			if c.Liked == nil || *c.Liked != want[i] {
+				t.Errorf("%s: comment %q liked = %v, want %v", user, c.Content, c.Liked, want[i])
+			}
+		}
+	}
+	w := serve(router, adminRequest(http.MethodGet, path, "", "", ""))
+	for _, c := range decodeComments(t, w.Body.Bytes()).Comments {
+		if c.Liked != nil {
+			t.Errorf("anonymous: comment %q liked = %v, want it left out", c.Content, *c.Liked)
+		}
+	}
+}
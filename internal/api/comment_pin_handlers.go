@@ -0,0 +1,50 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// PinComment handles PATCH /api/v1/comments/:commentID/pin - the video owner pinning
+// a top-level comment above the others, or unpinning it
+func (h *VideoHandler) PinComment(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("commentID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid comment ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	var req models.CommentPinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	comment, err := h.commentSvc.SetPinned(c.Request.Context(), uint(cid), requester, *req.Pinned)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrCommentNotFound):
+			respondError(c, http.StatusNotFound, CodeCommentNotFound, "Comment not found", nil)
+		case errors.Is(err, services.ErrForbidden):
+			respondError(c, http.StatusForbidden, CodeForbidden, "Only the video owner can pin comments", nil)
+		case errors.Is(err, services.ErrNotPinnable):
+			respondError(c, http.StatusBadRequest, CodeNotPinnable, "Only visible top-level comments can be pinned", nil)
+		default:
+			h.log(c).Errorw("Failed to pin comment", "error", err, "commentID", cid)
+			respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to pin comment", nil)
+		}
+		return
+	}
+	h.recentWrites.Mark(commentWriteKey(requester, comment.VideoID))
+	c.JSON(http.StatusOK, comment)
+}
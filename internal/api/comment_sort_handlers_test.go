@@ -0,0 +1,102 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestListCommentsSort(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:   services.NewVideoService(db, nil, videoSettings, log),
+		Comments: services.NewCommentService(db, commentSettings, log),
+	})
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t", Status: models.StatusReady, CommentsEnabled: true}
+	db.Create(&video)
+	path := "/api/v1/videos/" + itoa(video.ID) + "/comments"
+	for _, content := range []string{"one", "two", "three"} {
+		if w := serve(router, adminRequest(http.MethodPost, path, `{"content":"`+content+`"}`, "alice", "")); w.Code != http.StatusCreated {
+			t.Fatalf("post: status %d: %s", w.Code, w.Body)
+		}
+	}
+	var two models.Comment
+	db.Where("content = ?", "two").First(&two)
+
+	list := func(query string) (int, string) {
+		w := serve(router, adminRequest(http.MethodGet, path+query, "", "alice", ""))
+		if w.Code != http.StatusOK {
+			return w.Code, w.Body.String()
+		}
+		got := ""
+		for _, c := range decodeComments(t, w.Body.Bytes()).Comments {
+			got += c.Content + " "
+		}
+		return w.Code, got
+	}
+	tests := []struct {
+		query  string
+		status int
+		want   string
+	}{
+		{"", http.StatusOK, "three two one "},
+		{"?sort=newest", http.StatusOK, "three two one "},
+		{"?sort=oldest", http.StatusOK, "one two three "},
+		{"?sort=likes", http.StatusBadRequest, ""},
+		{"?sort=oldest&cursor=abc", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		status, got := list(tt.query)
+		if status != tt.status || (status == http.StatusOK && got != tt.want) {
+			t.Errorf("%q: status %d, %s; want %d %s", tt.query, status, got, tt.status, tt.want)
+		}
+	}
+	w := serve(router, adminRequest(http.MethodGet, path+"?sort=likes", "", "alice", ""))
+	var body struct {
+		api.ErrorResponse
+		Details struct {
+			AllowedSorts []string `json:"allowed_sorts"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Code != api.CodeInvalidSort || len(body.Details.AllowedSorts) != 3 {
+		t.Errorf("unknown sort: %s, want %s listing the allowed sorts", w.Body, api.CodeInvalidSort)
+	}
+
+	// Only the video owner pins, and the pinned comment leads
+	pin := func(user, body string) int {
+		return serve(router, adminRequest(http.MethodPatch, "/api/v1/comments/"+itoa(two.ID)+"/pin", body, user, "")).Code
+	}
+	pins := []struct {
+		name, user, body string
+		status           int
+	}{
+		{"signed out", "", `{"pinned":true}`, http.StatusUnauthorized},
+		{"not the owner", "alice", `{"pinned":true}`, http.StatusForbidden},
+		{"no pinned field", "owner", `{}`, http.StatusBadRequest},
+		{"owner", "owner", `{"pinned":true}`, http.StatusOK},
+	}
+	for _, tt := range pins {
+		if status := pin(tt.user, tt.body); status != tt.status {
+			t.Errorf("pin, %s: status %d, want %d", tt.name, status, tt.status)
+		}
+	}
+	for query, want := range map[string]string{"?sort=newest": "two three one ", "?sort=oldest": "two one three "} {
+		if _, got := list(query); got != want {
+			t.Errorf("%s with two pinned: %s, want %s", query, got, want)
+		}
+	}
+	if status := pin("owner", `{"pinned":false}`); status != http.StatusOK {
+		t.Fatalf("unpin: status %d", status)
+	}
+	if _, got := list("?sort=oldest"); got != "one two three " {
+		t.Errorf("after unpinning: %s", got)
+	}
+}
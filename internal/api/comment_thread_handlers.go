@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/db"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// ListReplies handles GET /api/v1/comments/:commentID/replies - a page of a comment's
+// direct replies, oldest first. The comment's video decides who may read them, as
+// for ListComments.
+func (h *VideoHandler) ListReplies(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("commentID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid comment ID", nil)
+		return
+	}
+	comment, err := h.commentSvc.GetComment(c.Request.Context(), uint(cid))
+	if err == nil && comment.Status == models.CommentPending {
+		// Held for moderation: its thread is hidden along with it
+		err = services.ErrCommentNotFound
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrCommentNotFound) {
+			respondError(c, http.StatusNotFound, CodeCommentNotFound, "Comment not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to get comment", "error", err, "commentID", cid)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list replies", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), comment.VideoID)
+	if err != nil {
+		h.videoLookupFailed(c, err, comment.VideoID)
+		return
+	}
+	if !canView(c, video) {
+		respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+		return
+	}
+	if h.recentWrites.Recent(commentWriteKey(currentUser(c), video.ID)) {
+		c.Request = c.Request.WithContext(db.WithPrimary(c.Request.Context()))
+		c.Header("Cache-Control", "no-store")
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	perPage := perPageFor(c, 0)
+	replies, total, err := h.commentSvc.ListReplies(c.Request.Context(), comment.ID, page, perPage)
+	if err != nil {
+		h.log(c).Errorw("Failed to list replies", "error", err, "commentID", cid)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list replies", nil)
+		return
+	}
+	h.attachCommentLikes(c, replies)
+	c.JSON(http.StatusOK, gin.H{
+		"parent_id":   comment.ID,
+		"replies":     replies,
+		"total":       total,
+		"page":        page,
+		"per_page":    perPage,
+		"total_pages": (int(total) + perPage - 1) / perPage,
+	})
+}
@@ -0,0 +1,117 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/cache"
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestCommentThreads(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:       services.NewVideoService(db, nil, videoSettings, log),
+		Comments:     services.NewCommentService(db, config.Comments{MaxDepth: 1}, log),
+		RecentWrites: cache.NewRecentWrites(time.Minute, 100),
+	})
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Visibility: models.VisibilityPublic, Status: models.StatusReady, CommentsEnabled: true}
+	db.Create(&video)
+	private := models.Video{UploadID: "up-2", UserID: "owner", Title: "t", Visibility: models.VisibilityPrivate, Status: models.StatusReady, CommentsEnabled: true}
+	db.Create(&private)
+	path := "/api/v1/videos/" + itoa(video.ID) + "/comments"
+
+	post := func(user, body string) (int, uint, string) {
+		t.Helper()
+		w := serve(router, adminRequest(http.MethodPost, path, body, user, ""))
+		var posted struct {
+			ID uint `json:"id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &posted)
+		if w.Code >= 300 {
+			return w.Code, 0, errorCode(w)
+		}
+		return w.Code, posted.ID, ""
+	}
+	_, root, _ := post("alice", `{"content":"first"}`)
+	_, first, _ := post("bob", `{"content":"reply","parent_id":`+itoa(root)+`}`)
+	post("carol", `{"content":"reply 2","parent_id":`+itoa(root)+`}`)
+
+	for _, tt := range []struct {
+		name, body, code string
+	}{
+		{"unknown parent", `{"content":"x","parent_id":999}`, api.CodeInvalidParent},
+		{"reply to a reply", `{"content":"x","parent_id":` + itoa(first) + `}`, api.CodeReplyTooDeep},
+	} {
+		if status, _, code := post("dave", tt.body); status != http.StatusBadRequest || code != tt.code {
+			t.Errorf("%s: status %d, code %q; want 400 %s", tt.name, status, code, tt.code)
+		}
+	}
+	w := serve(router, adminRequest(http.MethodPost, path, `{"content":"x","parent_id":`+itoa(first)+`}`, "dave", ""))
+	var tooDeep struct {
+		Details struct {
+			MaxDepth int `json:"max_depth"`
+		} `json:"details"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &tooDeep)
+	if tooDeep.Details.MaxDepth != 1 {
+		t.Errorf("too deep details = %s, want max_depth 1", w.Body)
+	}
+
+	w = serve(router, adminRequest(http.MethodGet, path, "", "", ""))
+	page := decodeComments(t, w.Body.Bytes())
+	if page.Total != 1 || len(page.Comments) != 1 || page.Comments[0].ID != root || page.Comments[0].ReplyCount != 2 {
+		t.Errorf("comment list = %+v, want the top-level comment with 2 replies", page)
+	}
+
+	type replyPage struct {
+		ParentID   uint             `json:"parent_id"`
+		Replies    []models.Comment `json:"replies"`
+		Total      int64            `json:"total"`
+		TotalPages int              `json:"total_pages"`
+	}
+	listReplies := func(id uint, query, user string) (int, replyPage) {
+		w := serve(router, adminRequest(http.MethodGet, "/api/v1/comments/"+itoa(id)+"/replies"+query, "", user, ""))
+		var p replyPage
+		json.Unmarshal(w.Body.Bytes(), &p)
+		return w.Code, p
+	}
+	status, replies := listReplies(root, "?per_page=1&page=2", "")
+	if status != http.StatusOK || replies.ParentID != root || replies.Total != 2 || replies.TotalPages != 2 ||
+		len(replies.Replies) != 1 || replies.Replies[0].UserID != "carol" {
+		t.Errorf("second page of replies: status %d, %+v", status, replies)
+	}
+	if status, _ := listReplies(999, "", ""); status != http.StatusNotFound {
+		t.Errorf("replies of an unknown comment: status %d, want 404", status)
+	}
+	hidden := models.Comment{VideoID: private.ID, UserID: "owner", Content: "x", Status: models.CommentVisible}
+	db.Create(&hidden)
+	if status, _ := listReplies(hidden.ID, "", "stranger"); status != http.StatusForbidden {
+		t.Errorf("replies on a private video: status %d, want 403", status)
+	}
+	if status, _ := listReplies(hidden.ID, "", "owner"); status != http.StatusOK {
+		t.Errorf("replies on the owner's private video: status %d", status)
+	}
+
+	// Deleting the root leaves a tombstone, with its replies still listed under it
+	if w := serve(router, adminRequest(http.MethodDelete, "/api/v1/comments/"+itoa(root), "", "alice", "")); w.Code >= 300 {
+		t.Fatalf("delete: status %d: %s", w.Code, w.Body)
+	}
+	w = serve(router, adminRequest(http.MethodGet, path, "", "", ""))
+	page = decodeComments(t, w.Body.Bytes())
+	if len(page.Comments) != 1 || page.Comments[0].Content != models.CommentTombstone || page.Comments[0].ReplyCount != 2 {
+		t.Errorf("list after deleting the root = %+v", page.Comments)
+	}
+	if status, replies := listReplies(root, "", ""); status != http.StatusOK || replies.Total != 2 {
+		t.Errorf("replies under the tombstone: status %d, %+v", status, replies)
+	}
+}
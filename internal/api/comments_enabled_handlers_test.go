@@ -0,0 +1,81 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestCommentsEnabledToggle(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Comments:  services.NewCommentService(db, commentSettings, log),
+		Reactions: services.NewReactionService(db, log),
+	})
+	w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-1","title":"t","visibility":"public"}`, "owner", ""))
+	var video struct {
+		ID              uint  `json:"id"`
+		CommentsEnabled *bool `json:"comments_enabled"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &video); err != nil || video.CommentsEnabled == nil || !*video.CommentsEnabled {
+		t.Fatalf("created video %s: want comments_enabled true", w.Body)
+	}
+	path := "/api/v1/videos/" + itoa(video.ID)
+	comments := path + "/comments"
+	if w := serve(router, adminRequest(http.MethodPost, comments, `{"content":"first"}`, "alice", "")); w.Code != http.StatusCreated {
+		t.Fatalf("comment: %d %s", w.Code, w.Body)
+	}
+	enabled := func() bool {
+		t.Helper()
+		w := serve(router, adminRequest(http.MethodGet, path, "", "viewer", ""))
+		var got struct {
+			CommentsEnabled bool `json:"comments_enabled"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &got)
+		return got.CommentsEnabled
+	}
+
+	// Only the owner may turn them off
+	if w := serve(router, adminRequest(http.MethodPut, path, `{"comments_enabled":false}`, "mallory", "")); w.Code != http.StatusForbidden {
+		t.Errorf("stranger turning comments off: %d, want 403", w.Code)
+	}
+	if w := serve(router, adminRequest(http.MethodPut, path, `{"comments_enabled":false}`, "owner", "")); w.Code != http.StatusOK {
+		t.Fatalf("PUT comments_enabled false: %d %s", w.Code, w.Body)
+	}
+	if enabled() {
+		t.Error("video JSON still says comments_enabled after turning them off")
+	}
+	w = serve(router, adminRequest(http.MethodPost, comments, `{"content":"second"}`, "bob", ""))
+	if w.Code != http.StatusForbidden || errorCode(w) != api.CodeCommentsDisabled {
+		t.Errorf("comment with comments off: %d %s, want 403 comments_disabled", w.Code, w.Body)
+	}
+	// Even the owner can't post, but everyone can still read the thread
+	if w := serve(router, adminRequest(http.MethodPost, comments, `{"content":"mine"}`, "owner", "")); errorCode(w) != api.CodeCommentsDisabled {
+		t.Errorf("owner comment with comments off: %d %s", w.Code, w.Body)
+	}
+	w = serve(router, adminRequest(http.MethodGet, comments, "", "bob", ""))
+	if page := decodeComments(t, w.Body.Bytes()); w.Code != http.StatusOK || page.Total != 1 || page.Comments[0].Content != "first" {
+		t.Errorf("thread with comments off: %d %s", w.Code, w.Body)
+	}
+
+	// PATCH null restores the default
+	req := adminRequest(http.MethodPatch, path, `{"comments_enabled":null}`, "owner", "")
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	if w := serve(router, req); w.Code != http.StatusOK {
+		t.Fatalf("PATCH comments_enabled null: %d %s", w.Code, w.Body)
+	}
+	if !enabled() {
+		t.Error("comments still off after PATCH null")
+	}
+	if w := serve(router, adminRequest(http.MethodPost, comments, `{"content":"second"}`, "bob", "")); w.Code != http.StatusCreated {
+		t.Errorf("comment after turning comments back on: %d %s", w.Code, w.Body)
+	}
+}
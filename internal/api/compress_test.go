@@ -0,0 +1,126 @@
+package api_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+)
+
+// gunzip decodes a gzipped body, failing the test if it isn't one
+func gunzip(t *testing.T, body []byte) string {
+	t.Helper()
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("gunzip: %v", err)
+	}
+	return string(out)
+}
+
+// decode undoes the Content-Encoding a response was sent with
+func decode(t *testing.T, coding string, body []byte) string {
+	t.Helper()
+	if coding == "gzip" {
+		return gunzip(t, body)
+	}
+	out, err := io.ReadAll(brotli.NewReader(bytes.NewReader(body)))
+	if err != nil {
+		t.Fatalf("brotli: %v", err)
+	}
+	return string(out)
+}
+
+func TestCompress(t *testing.T) {
+	big := strings.Repeat(`{"title":"holiday"},`, 100)
+	router := gin.New()
+	router.Use(api.Compress(1024))
+	router.GET("/big", func(c *gin.Context) { c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(big)) })
+	router.GET("/small", func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(`{"ok":true}`)) })
+	router.GET("/image", func(c *gin.Context) { c.Data(http.StatusOK, "image/jpeg", []byte(big)) })
+	router.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "br")
+		c.Data(http.StatusOK, "application/json", []byte(big))
+	})
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Writer.WriteString("{\"n\":1}\n")
+		c.Writer.Flush()
+		c.Writer.WriteString("{\"n\":2}\n")
+	})
+
+	tests := []struct {
+		name, path, accept string
+		ranged             bool
+		encoding           string
+	}{
+		{"large JSON", "/big", "gzip, deflate", false, "gzip"},
+		{"brotli offered", "/big", "gzip, deflate, br", false, "br"},
+		{"brotli only", "/big", "br", false, "br"},
+		{"gzip preferred", "/big", "br;q=0.5, gzip", false, "gzip"},
+		{"brotli preferred", "/big", "gzip;q=0.8, br;q=0.9", false, "br"},
+		{"brotli refused", "/big", "br;q=0, gzip;q=0.1", false, "gzip"},
+		{"any coding", "/big", "*", false, "br"},
+		{"any but brotli", "/big", "br;q=0, *", false, "gzip"},
+		{"named beats any", "/big", "*;q=0.9, gzip", false, "gzip"},
+		{"gzip refused", "/big", "gzip;q=0, identity", false, ""},
+		{"all refused", "/big", "*;q=0", false, ""},
+		{"bad q-value", "/big", "br;q=2, gzip;q=x", false, ""},
+		{"no Accept-Encoding", "/big", "", false, ""},
+		{"under the minimum", "/small", "gzip", false, ""},
+		{"not compressible", "/image", "br, gzip", false, ""},
+		{"already encoded", "/encoded", "gzip", false, "br"},
+		{"range request", "/big", "gzip", true, ""},
+		{"streamed", "/stream", "gzip", false, "gzip"},
+		{"streamed brotli", "/stream", "br", false, "br"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept-Encoding", tt.accept)
+		}
+		if tt.ranged {
+			req.Header.Set("Range", "bytes=0-10")
+		}
+		w := serve(router, req)
+		if w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s: Vary %q, want Accept-Encoding", tt.name, w.Header().Get("Vary"))
+		}
+		encoding := w.Header().Get("Content-Encoding")
+		if encoding != tt.encoding {
+			t.Errorf("%s: Content-Encoding %q, want %q", tt.name, encoding, tt.encoding)
+			continue
+		}
+		if encoding == "" || tt.path == "/encoded" {
+			continue
+		}
+		if w.Header().Get("Content-Length") != "" {
+			t.Errorf("%s: Content-Length %q on an encoded body", tt.name, w.Header().Get("Content-Length"))
+		}
+		want := big
+		if tt.path == "/stream" {
+			want = "{\"n\":1}\n{\"n\":2}\n"
+		}
+		if got := decode(t, encoding, w.Body.Bytes()); got != want {
+			t.Errorf("%s: body %q after decoding %s", tt.name, got, encoding)
+		}
+	}
+
+	// The uncompressed small body goes out intact
+	req := httptest.NewRequest(http.MethodGet, "/small", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	if w := serve(router, req); w.Body.String() != `{"ok":true}` {
+		t.Errorf("small body = %q", w.Body)
+	}
+}
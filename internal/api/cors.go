@@ -0,0 +1,191 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Defaults for CORSConfig fields left empty
+var (
+	DefaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+	DefaultCORSHeaders = []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token",
+		"Authorization", "X-User-ID", "X-User-Roles", "X-Username", "X-Impersonate-User", "X-Anonymous-Session",
+		HeaderRequestID, idempotencyKeyHeader, "If-Match"}
+	DefaultCORSExposed = []string{HeaderRequestID, idempotentReplayHeader, "ETag", "Retry-After"}
+)
+
+// CORSConfig says which browser origins may call the API and how
+type CORSConfig struct {
+	// AllowedOrigins are exact origins ("https://app.streamhive.io"), subdomain
+	// wildcards ("*.streamhive.io" or "https://*.streamhive.io"), or "*" for any
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and Authorization; a "*" origin
+	// is then answered with the caller's own origin, as browsers require
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight; 0 leaves it to them
+	MaxAge time.Duration
+}
+
+// CORS answers cross-origin requests from the allowed origins. Matching origins are
+// echoed back with Vary: Origin; others get no CORS headers, so browsers block the
+// response, and their preflights are refused with 403. A preflight is answered with
+// the methods router actually serves for its path, 404 if none.
+func CORS(router *gin.Engine, cfg CORSConfig) gin.HandlerFunc {
+	if len(cfg.AllowedMethods) == 0 {
+		cfg.AllowedMethods = DefaultCORSMethods
+	}
+	if len(cfg.AllowedHeaders) == 0 {
+		cfg.AllowedHeaders = DefaultCORSHeaders
+	}
+	if len(cfg.ExposedHeaders) == 0 {
+		cfg.ExposedHeaders = DefaultCORSExposed
+	}
+	allowAny := false
+	for _, pattern := range cfg.AllowedOrigins {
+		allowAny = allowAny || pattern == "*"
+	}
+	allowHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	// Routes are all registered before the server starts taking requests
+	var loadRoutes sync.Once
+	var routes gin.RoutesInfo
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if origin == "" {
+			c.Next()
+			return
+		}
+		if !allowAny || cfg.AllowCredentials {
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		if !allowAny && !originAllowed(cfg.AllowedOrigins, origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAny && !cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			c.Header("Access-Control-Expose-Headers", exposeHeaders)
+			c.Next()
+			return
+		}
+
+		loadRoutes.Do(func() { routes = router.Routes() })
+		methods := routeMethods(routes, c.Request.URL.Path, cfg.AllowedMethods)
+		if len(methods) == 0 {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+		c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+		c.Header("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		c.Header("Access-Control-Allow-Headers", allowHeaders)
+		if cfg.MaxAge > 0 {
+			c.Header("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// originAllowed reports whether origin matches any of the patterns
+func originAllowed(patterns []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range patterns {
+		if matchOrigin(strings.ToLower(strings.TrimSpace(pattern)), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin matches an origin against one pattern. A pattern without a scheme
+// matches any scheme, and "*." matches one or more subdomain labels but not the
+// bare domain.
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" || pattern == origin {
+		return true
+	}
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || host == "" {
+		return false
+	}
+	if s, h, ok := strings.Cut(pattern, "://"); ok {
+		if s != scheme {
+			return false
+		}
+		pattern = h
+	}
+	if pattern == host {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(pattern, "*")
+	if !ok || !strings.HasPrefix(suffix, ".") {
+		return false
+	}
+	return len(host) > len(suffix) && strings.HasSuffix(host, suffix)
+}
+
+// routeMethods lists the allowed methods routes serve at path, in allowed's order
+func routeMethods(routes gin.RoutesInfo, path string, allowed []string) []string {
+	served := map[string]bool{}
+	for _, route := range routes {
+		if routeMatches(route.Path, path) {
+			served[route.Method] = true
+		}
+	}
+	var out []string
+	for _, method := range allowed {
+		if served[strings.ToUpper(method)] {
+			out = append(out, strings.ToUpper(method))
+		}
+	}
+	return out
+}
+
+// routeMatches matches a request path against a gin route pattern with :param
+// and *catch-all segments
+func routeMatches(pattern, path string) bool {
+	want := strings.Split(strings.Trim(pattern, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range want {
+		if strings.HasPrefix(segment, "*") {
+			return true
+		}
+		if i >= len(got) {
+			return false
+		}
+		if strings.HasPrefix(segment, ":") {
+			if got[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != got[i] {
+			return false
+		}
+	}
+	return len(want) == len(got)
+}
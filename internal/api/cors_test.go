@@ -0,0 +1,160 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+)
+
+// corsRouter serves a few routes behind CORS, as main wires it
+func corsRouter(cfg api.CORSConfig) *gin.Engine {
+	router := gin.New()
+	router.Use(api.CORS(router, cfg))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/videos", ok)
+	router.POST("/api/v1/videos", ok)
+	router.GET("/api/v1/videos/:id", ok)
+	router.PUT("/api/v1/videos/:id", ok)
+	router.DELETE("/api/v1/videos/:id", ok)
+	router.GET("/static/*path", ok)
+	return router
+}
+
+func corsRequest(method, path, origin string, preflight string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if preflight != "" {
+		req.Header.Set("Access-Control-Request-Method", preflight)
+	}
+	return req
+}
+
+func TestCORSOriginMatching(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		origin   string
+		allowed  bool
+	}{
+		{[]string{"https://app.streamhive.io"}, "https://app.streamhive.io", true},
+		{[]string{"https://app.streamhive.io"}, "https://APP.streamhive.io", true},
+		{[]string{"https://app.streamhive.io"}, "http://app.streamhive.io", false},
+		{[]string{"https://app.streamhive.io"}, "https://app.streamhive.io:8443", false},
+		{[]string{"https://app.streamhive.io"}, "https://evil.io", false},
+		{[]string{" https://app.streamhive.io "}, "https://app.streamhive.io", true},
+		// Without a scheme any scheme matches
+		{[]string{"app.streamhive.io"}, "http://app.streamhive.io", true},
+		{[]string{"*.streamhive.io"}, "https://app.streamhive.io", true},
+		{[]string{"*.streamhive.io"}, "http://a.b.streamhive.io", true},
+		// The wildcard needs at least one label and a real boundary
+		{[]string{"*.streamhive.io"}, "https://streamhive.io", false},
+		{[]string{"*.streamhive.io"}, "https://evilstreamhive.io", false},
+		{[]string{"*.streamhive.io"}, "https://streamhive.io.evil.com", false},
+		{[]string{"https://*.streamhive.io"}, "https://app.streamhive.io", true},
+		{[]string{"https://*.streamhive.io"}, "http://app.streamhive.io", false},
+		{[]string{"*streamhive.io"}, "https://evilstreamhive.io", false},
+		{[]string{"http://localhost:3000", "*.streamhive.io"}, "http://localhost:3000", true},
+		{[]string{"http://localhost:3000", "*.streamhive.io"}, "http://localhost:3001", false},
+		{[]string{"*.streamhive.io"}, "null", false},
+		{[]string{"*"}, "https://anything.example", true},
+		{nil, "https://app.streamhive.io", false},
+	}
+	for _, tt := range tests {
+		router := corsRouter(api.CORSConfig{AllowedOrigins: tt.patterns})
+		w := serve(router, corsRequest(http.MethodGet, "/api/v1/videos", tt.origin, ""))
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.allowed && got != tt.origin && got != "*" {
+			t.Errorf("%v, %s: Allow-Origin %q, want it allowed", tt.patterns, tt.origin, got)
+		}
+		if !tt.allowed && got != "" {
+			t.Errorf("%v, %s: Allow-Origin %q, want none", tt.patterns, tt.origin, got)
+		}
+		// A refused origin still gets its response; the browser withholds it
+		if w.Code != http.StatusOK {
+			t.Errorf("%v, %s: status %d, want the route's 200", tt.patterns, tt.origin, w.Code)
+		}
+	}
+}
+
+func TestCORSResponseHeaders(t *testing.T) {
+	listed := corsRouter(api.CORSConfig{AllowedOrigins: []string{"https://app.streamhive.io"}})
+	w := serve(listed, corsRequest(http.MethodGet, "/api/v1/videos", "https://app.streamhive.io", ""))
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.streamhive.io" {
+		t.Errorf("Allow-Origin %q, want the echoed origin", got)
+	}
+	if w.Header().Get("Vary") != "Origin" || !strings.Contains(w.Header().Get("Access-Control-Expose-Headers"), api.HeaderRequestID) {
+		t.Errorf("headers %v, want Vary: Origin and the exposed request ID", w.Header())
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Error("credentials allowed without AllowCredentials")
+	}
+	// Unlisted origins vary too, so a cache can't serve them the listed answer
+	if w := serve(listed, corsRequest(http.MethodGet, "/api/v1/videos", "https://evil.io", "")); w.Header().Get("Vary") != "Origin" {
+		t.Errorf("unlisted origin: Vary %q, want Origin", w.Header().Get("Vary"))
+	}
+	if w := serve(listed, corsRequest(http.MethodGet, "/api/v1/videos", "", "")); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("a same-origin request got CORS headers")
+	}
+
+	// The default "*" answers "*" and needs no Vary; with credentials it must echo
+	open := corsRouter(api.CORSConfig{AllowedOrigins: []string{"*"}})
+	w = serve(open, corsRequest(http.MethodGet, "/api/v1/videos", "http://localhost:3000", ""))
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Vary") != "" {
+		t.Errorf("*: Allow-Origin %q, Vary %q", w.Header().Get("Access-Control-Allow-Origin"), w.Header().Get("Vary"))
+	}
+	credentialed := corsRouter(api.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	w = serve(credentialed, corsRequest(http.MethodGet, "/api/v1/videos", "http://localhost:3000", ""))
+	if w.Header().Get("Access-Control-Allow-Origin") != "http://localhost:3000" || w.Header().Get("Access-Control-Allow-Credentials") != "true" ||
+		w.Header().Get("Vary") != "Origin" {
+		t.Errorf("* with credentials: %v, want the origin echoed with credentials", w.Header())
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	router := corsRouter(api.CORSConfig{
+		AllowedOrigins: []string{"*.streamhive.io"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		MaxAge:         10 * time.Minute,
+	})
+	const origin = "https://app.streamhive.io"
+	tests := []struct {
+		path, origin string
+		status       int
+		methods      string
+	}{
+		{"/api/v1/videos", origin, http.StatusNoContent, "GET, POST"},
+		{"/api/v1/videos/42", origin, http.StatusNoContent, "GET, PUT, DELETE"},
+		{"/api/v1/videos/42/", origin, http.StatusNoContent, "GET, PUT, DELETE"},
+		{"/static/js/app.js", origin, http.StatusNoContent, "GET"},
+		{"/api/v1/videos/42/comments", origin, http.StatusNotFound, ""},
+		{"/api/v1/videos", "https://evil.io", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		w := serve(router, corsRequest(http.MethodOptions, tt.path, tt.origin, http.MethodPut))
+		if w.Code != tt.status || w.Header().Get("Access-Control-Allow-Methods") != tt.methods {
+			t.Errorf("preflight %s from %s: %d, methods %q; want %d, %q", tt.path, tt.origin, w.Code,
+				w.Header().Get("Access-Control-Allow-Methods"), tt.status, tt.methods)
+		}
+		if tt.status != http.StatusNoContent {
+			continue
+		}
+		if w.Header().Get("Access-Control-Allow-Headers") != "Content-Type, Authorization" || w.Header().Get("Access-Control-Max-Age") != "600" {
+			t.Errorf("preflight %s: headers %v", tt.path, w.Header())
+		}
+		if vary := strings.Join(w.Header().Values("Vary"), ","); vary != "Origin,Access-Control-Request-Method,Access-Control-Request-Headers" {
+			t.Errorf("preflight %s: Vary %q", tt.path, vary)
+		}
+	}
+
+	// A bare OPTIONS isn't a preflight and reaches the router
+	if w := serve(router, corsRequest(http.MethodOptions, "/api/v1/videos", origin, "")); w.Code == http.StatusNoContent {
+		t.Error("an OPTIONS without Access-Control-Request-Method was answered as a preflight")
+	}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// ExportUserData handles GET /api/v1/users/:userID/data-export (owner only).
+// Small exports stream back as a ZIP; large ones, or ?async=true, start a job
+// and return 202 with its status URL.
+func (h *VideoHandler) ExportUserData(c *gin.Context) {
+	userID := c.Param("userID")
+	if !h.requireSelf(c, userID) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	asJob := c.Query("async") == "true"
+	if !asJob {
+		large, err := h.dataExportSvc.ShouldRunAsJob(ctx, userID)
+		if err != nil {
+			h.log(c).Errorw("Failed to size data export", "error", err, "userID", userID)
+			respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to export data", nil)
+			return
+		}
+		asJob = large
+	}
+
+	if asJob {
+		export, created, err := h.dataExportSvc.StartExport(ctx, userID, services.DataExportTriggerAPI)
+		if err != nil {
+			h.log(c).Errorw("Failed to start data export", "error", err, "userID", userID)
+			respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to export data", nil)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"export":     export,
+			"created":    created,
+			"status_url": fmt.Sprintf("/api/v1/users/%s/data-exports/%d", userID, export.ID),
+		})
+		return
+	}
+
+	start := time.Now()
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="streamhive-data.zip"`)
+	c.Status(http.StatusOK)
+	manifest, err := h.dataExportSvc.Assemble(ctx, userID, c.Writer)
+	metrics.DataExportDuration.WithLabelValues("stream").Observe(time.Since(start).Seconds())
+	if err != nil {
+		// Headers are already sent; the truncated archive fails to open client-side
+		metrics.DataExportsTotal.WithLabelValues("stream", "failed").Inc()
+		h.log(c).Errorw("Data export stream failed", "error", err, "userID", userID)
+		return
+	}
+	metrics.DataExportsTotal.WithLabelValues("stream", "ready").Inc()
+	h.log(c).Infow("Data export streamed", "userID", userID, "rows", manifest.Rows)
+}
+
+// DownloadUserData handles GET /api/v1/users/:userID/export?format=json|ndjson
+// (owner or admin) - the same data as the ZIP export, streamed as one JSON
+// document or as NDJSON, always synchronously
+func (h *VideoHandler) DownloadUserData(c *gin.Context) {
+	userID := c.Param("userID")
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	admin := hasRole(identityFrom(c).Roles, "admin")
+	if requester != userID && !admin {
+		respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+		return
+	}
+
+	format := c.DefaultQuery("format", services.ExportFormatJSON)
+	var contentType string
+	switch format {
+	case services.ExportFormatJSON:
+		contentType = "application/json"
+	case services.ExportFormatNDJSON:
+		contentType = "application/x-ndjson"
+	default:
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid format", gin.H{"allowed": []string{services.ExportFormatJSON, services.ExportFormatNDJSON}})
+		return
+	}
+
+	var entry *models.AuditLog
+	if requester != userID {
+		var ok bool
+		if entry, ok = h.beginAudit(c, models.AuditActionExportUserData, userID, "user:"+userID, format); !ok {
+			return
+		}
+	}
+
+	start := time.Now()
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="streamhive-data.%s"`, format))
+	c.Status(http.StatusOK)
+	manifest, err := h.dataExportSvc.WriteDocument(c.Request.Context(), userID, format, c.Writer)
+	if entry != nil {
+		h.finishAudit(c, entry, err)
+	}
+	metrics.DataExportDuration.WithLabelValues("stream").Observe(time.Since(start).Seconds())
+	if err != nil {
+		// Headers are already sent; the document is left unterminated so it fails to parse
+		metrics.DataExportsTotal.WithLabelValues("stream", "failed").Inc()
+		h.log(c).Errorw("Data export stream failed", "error", err, "userID", userID, "format", format)
+		return
+	}
+	metrics.DataExportsTotal.WithLabelValues("stream", "ready").Inc()
+	h.log(c).Infow("Data export streamed", "userID", userID, "format", format, "rows", manifest.Rows, "byAdmin", requester != userID)
+}
+
+// GetDataExport handles GET /api/v1/users/:userID/data-exports/:exportID
+func (h *VideoHandler) GetDataExport(c *gin.Context) {
+	userID := c.Param("userID")
+	if !h.requireSelf(c, userID) {
+		return
+	}
+	export, ok := h.loadDataExport(c, userID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, export)
+}
+
+// DownloadDataExport handles GET /api/v1/users/:userID/data-exports/:exportID/download
+func (h *VideoHandler) DownloadDataExport(c *gin.Context) {
+	userID := c.Param("userID")
+	if !h.requireSelf(c, userID) {
+		return
+	}
+	export, ok := h.loadDataExport(c, userID)
+	if !ok {
+		return
+	}
+	switch export.Status {
+	case models.DataExportReady:
+	case models.DataExportExpired:
+		respondError(c, http.StatusGone, CodeExportExpired, "Export has expired", nil)
+		return
+	default:
+		respondError(c, http.StatusConflict, CodeNotReady, "Export is not ready", gin.H{"status": export.Status})
+		return
+	}
+	f, err := h.dataExportSvc.OpenArchive(export)
+	if err != nil {
+		if errors.Is(err, services.ErrExportExpired) {
+			respondError(c, http.StatusGone, CodeExportExpired, "Export is no longer available", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to open data export", "error", err, "exportID", export.ID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to open export", nil)
+		return
+	}
+	defer f.Close()
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="streamhive-data-%d.zip"`, export.ID))
+	http.ServeContent(c.Writer, c.Request, "", export.UpdatedAt, f)
+}
+
+func (h *VideoHandler) loadDataExport(c *gin.Context, userID string) (*models.DataExport, bool) {
+	id, err := strconv.ParseUint(c.Param("exportID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid export ID", nil)
+		return nil, false
+	}
+	export, err := h.dataExportSvc.GetExport(c.Request.Context(), userID, uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrExportNotFound) {
+			respondError(c, http.StatusNotFound, CodeExportNotFound, "Export not found", nil)
+			return nil, false
+		}
+		h.log(c).Errorw("Failed to get data export", "error", err, "exportID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get export", nil)
+		return nil, false
+	}
+	return export, true
+}
+
+// requireSelf allows the request only when the caller is the user in the path
+func (h *VideoHandler) requireSelf(c *gin.Context, userID string) bool {
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return false
+	}
+	if requester != userID {
+		respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestDownloadDataExport(t *testing.T) {
+	db := dbtest.Open(t)
+	dir := t.TempDir()
+	router := newRouter(api.Dependencies{
+		DataExports: services.NewDataExportService(db, zap.NewNop().Sugar(), dir, time.Hour, 0),
+	})
+	path := filepath.Join(dir, "export-1.zip")
+	if err := os.WriteFile(path, []byte("PK\x05\x06"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	export := models.DataExport{UserID: "alice", Status: models.DataExportReady, FilePath: path}
+	db.Create(&export)
+	target := "/api/v1/users/alice/data-exports/" + itoa(export.ID) + "/download"
+	download := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("X-User-ID", user)
+		return serve(router, req)
+	}
+
+	w := download("alice")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" || w.Body.String() != "PK\x05\x06" {
+		t.Fatalf("download: status %d, type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w := download("bob"); w.Code != http.StatusForbidden {
+		t.Errorf("another user's download: status %d, want 403", w.Code)
+	}
+
+	// A replica that never saw the file, or a file removed out of band, is a 410
+	os.Remove(path)
+	if w := download("alice"); w.Code != http.StatusGone {
+		t.Errorf("missing archive: status %d, want 410: %s", w.Code, w.Body)
+	}
+}
+
+func TestStreamUserDataExport(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		DataExports: services.NewDataExportService(db, log, t.TempDir(), time.Hour, 0),
+		Audit:       services.NewAuditService(db, log),
+	})
+	video := models.Video{UploadID: "up-1", UserID: "alice", Title: "mine"}
+	db.Create(&video)
+	db.Create(&models.Comment{VideoID: video.ID, UserID: "alice", Content: "hi", Status: models.CommentVisible})
+
+	tests := []struct {
+		name, query, user, roles string
+		status                   int
+		contentType              string
+	}{
+		{"owner", "", "alice", "", http.StatusOK, "application/json"},
+		{"owner, ndjson", "?format=ndjson", "alice", "", http.StatusOK, "application/x-ndjson"},
+		{"admin", "?format=json", "root", "admin", http.StatusOK, "application/json"},
+		{"someone else", "", "bob", "", http.StatusForbidden, ""},
+		{"anonymous", "", "", "", http.StatusUnauthorized, ""},
+		{"unknown format", "?format=xml", "alice", "", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		w := serve(router, adminRequest(http.MethodGet, "/api/v1/users/alice/export"+tt.query, "", tt.user, tt.roles))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		if w.Header().Get("Content-Type") != tt.contentType || !strings.HasPrefix(w.Header().Get("Content-Disposition"), `attachment; filename="streamhive-data.`) {
+			t.Errorf("%s: headers %v", tt.name, w.Header())
+		}
+		if tt.contentType == "application/json" {
+			var doc struct {
+				Videos   []models.Video   `json:"videos"`
+				Comments []models.Comment `json:"comments"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || len(doc.Videos) != 1 || len(doc.Comments) != 1 {
+				t.Errorf("%s: %v: %s", tt.name, err, w.Body)
+			}
+		}
+	}
+
+	// Only the admin's download of someone else's data is audited
+	var audits []models.AuditLog
+	db.Find(&audits)
+	if len(audits) != 1 || audits[0].Action != models.AuditActionExportUserData || audits[0].ActorID != "root" || audits[0].SubjectID != "alice" {
+		t.Errorf("audit log = %+v", audits)
+	}
+}
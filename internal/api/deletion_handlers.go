@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// GetDeletion handles GET /api/v1/videos/deletions/:jobID - a deletion job's status
+// and progress, for the video's owner or an admin
+func (h *VideoHandler) GetDeletion(c *gin.Context) {
+	job, ok := h.deletionForCaller(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// RetryDeletion handles POST /api/v1/videos/deletions/:jobID/retry - requeues a
+// failed deletion job
+func (h *VideoHandler) RetryDeletion(c *gin.Context) {
+	job, ok := h.deletionForCaller(c)
+	if !ok {
+		return
+	}
+	job, err := h.videoService.RetryDeletion(c.Request.Context(), job.ID)
+	if err != nil {
+		if errors.Is(err, services.ErrDeletionNotFailed) {
+			respondError(c, http.StatusConflict, CodeNotRetryable, "Only a failed deletion job can be retried", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to retry deletion job", "error", err, "deletionID", c.Param("jobID"))
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to retry deletion job", nil)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// deletionForCaller loads the :jobID deletion job, writing the error response and
+// returning false unless the caller owns the video or is an admin. Other callers
+// get 404, as if the job didn't exist.
+func (h *VideoHandler) deletionForCaller(c *gin.Context) (*models.VideoDeletion, bool) {
+	id, err := strconv.ParseUint(c.Param("jobID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid job ID", nil)
+		return nil, false
+	}
+	identity := identityFrom(c)
+	if identity.UserID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return nil, false
+	}
+	job, err := h.videoService.GetDeletion(c.Request.Context(), uint(id))
+	if err == nil && job.UserID != identity.UserID && !hasRole(identity.Roles, "admin") {
+		err = services.ErrDeletionNotFound
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrDeletionNotFound) {
+			respondError(c, http.StatusNotFound, CodeDeletionNotFound, "Deletion job not found", nil)
+			return nil, false
+		}
+		h.log(c).Errorw("Failed to get deletion job", "error", err, "deletionID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get deletion job", nil)
+		return nil, false
+	}
+	return job, true
+}
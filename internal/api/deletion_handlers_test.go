@@ -0,0 +1,67 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// TestDeleteVideoReturnsJob deletes a video and follows the job it returns, which
+// only the owner and admins can see
+func TestDeleteVideoReturnsJob(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{Videos: services.NewVideoService(db, nil, videoSettings, log)})
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t"}
+	db.Create(&video)
+
+	if w := serve(router, adminRequest(http.MethodDelete, "/api/v1/videos/"+itoa(video.ID), "", "mallory", "")); w.Code != http.StatusForbidden {
+		t.Fatalf("delete by another user: status %d, want 403", w.Code)
+	}
+	w := serve(router, adminRequest(http.MethodDelete, "/api/v1/videos/"+itoa(video.ID), "", "owner", ""))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("delete: status %d: %s", w.Code, w.Body)
+	}
+	var accepted struct {
+		JobID  uint   `json:"job_id"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &accepted); err != nil || accepted.JobID == 0 || accepted.Status != models.DeletionPending {
+		t.Fatalf("delete response %s, want the pending job", w.Body)
+	}
+
+	path := "/api/v1/videos/deletions/" + itoa(accepted.JobID)
+	tests := []struct {
+		name, user, roles string
+		path              string
+		status            int
+	}{
+		{"owner", "owner", "", path, http.StatusOK},
+		{"admin", "admin-1", "admin", path, http.StatusOK},
+		{"another user", "mallory", "", path, http.StatusNotFound},
+		{"anonymous", "", "", path, http.StatusUnauthorized},
+		{"unknown job", "owner", "", "/api/v1/videos/deletions/999", http.StatusNotFound},
+		{"bad id", "owner", "", "/api/v1/videos/deletions/abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := serve(router, adminRequest(http.MethodGet, tt.path, "", tt.user, tt.roles))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var job models.VideoDeletion
+		if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil || job.ID != accepted.JobID || job.VideoID != video.ID {
+			t.Errorf("%s: job %s", tt.name, w.Body)
+		}
+	}
+}
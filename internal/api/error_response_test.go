@@ -0,0 +1,154 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// denyLimiter refuses every request, as a limiter whose caller is over its limit
+type denyLimiter struct{}
+
+func (denyLimiter) Allow(context.Context, string) (bool, time.Duration, error) {
+	return false, 30 * time.Second, nil
+}
+
+type errorBody struct {
+	api.ErrorResponse
+	Details json.RawMessage `json:"details"`
+}
+
+func decodeError(t *testing.T, raw []byte) errorBody {
+	t.Helper()
+	var body errorBody
+	if err := json.Unmarshal(raw, &body); err != nil {
+		t.Fatalf("decode %s: %v", raw, err)
+	}
+	return body
+}
+
+func TestErrorResponseShape(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	deps := api.Dependencies{
+		Videos:       services.NewVideoService(db, nil, videoSettings, log),
+		Comments:     services.NewCommentService(db, commentSettings, log),
+		MaxBodyBytes: 1 << 10,
+	}
+	router := gin.New()
+	router.Use(api.RequestID())
+	api.SetupRoutes(router, deps, log)
+
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Visibility: models.VisibilityPublic}
+	db.Create(&video)
+	path := "/api/v1/videos/" + itoa(video.ID)
+
+	tests := []struct {
+		name         string
+		method, path string
+		body, user   string
+		status       int
+		code         string
+		details      string
+	}{
+		{"video not found", http.MethodGet, "/api/v1/videos/999", "", "owner", http.StatusNotFound, api.CodeVideoNotFound, ``},
+		{"forbidden", http.MethodDelete, path, "", "mallory", http.StatusForbidden, api.CodeForbidden, ``},
+		{"duplicate upload ID", http.MethodPost, "/api/v1/videos", `{"upload_id":"up-1","title":"t"}`, "owner",
+			http.StatusConflict, api.CodeDuplicateUploadID, `{"video_id":` + itoa(video.ID) + `}`},
+		{"missing field", http.MethodPost, "/api/v1/videos", `{"upload_id":"up-2"}`, "owner", http.StatusBadRequest, api.CodeValidationFailed,
+			`[{"field":"title","rule":"required","message":"is required"}]`},
+		{"too long", http.MethodPost, "/api/v1/videos", `{"upload_id":"up-2","title":"` + strings.Repeat("x", 201) + `"}`, "owner",
+			http.StatusBadRequest, api.CodeValidationFailed, `[{"field":"title","rule":"max","message":"must be at most 200 characters"}]`},
+		{"wrong type", http.MethodPost, "/api/v1/videos", `{"upload_id":"up-2","title":7}`, "owner", http.StatusBadRequest, api.CodeValidationFailed,
+			`[{"field":"title","rule":"type","message":"must be a string"}]`},
+		{"malformed JSON", http.MethodPost, "/api/v1/videos", `{"upload_id":`, "owner", http.StatusBadRequest, api.CodeValidationFailed,
+			`[{"field":"body","rule":"json","message":"must be valid JSON"}]`},
+		{"body too large", http.MethodPost, "/api/v1/videos", `{"upload_id":"up-2","title":"` + strings.Repeat("x", 2000) + `"}`, "owner",
+			http.StatusRequestEntityTooLarge, api.CodeRequestTooLarge, `{"max_bytes":1024}`},
+		{"unauthenticated", http.MethodPost, "/api/v1/videos", `{"upload_id":"up-2","title":"t"}`, "", http.StatusUnauthorized, api.CodeUnauthorized, ``},
+	}
+	for _, tt := range tests {
+		req := adminRequest(tt.method, tt.path, tt.body, tt.user, "")
+		req.Header.Set(api.HeaderRequestID, "req-"+strings.ReplaceAll(tt.name, " ", "-"))
+		w := serve(router, req)
+		body := decodeError(t, w.Body.Bytes())
+		if w.Code != tt.status || body.Code != tt.code {
+			t.Errorf("%s: %d %q, want %d %q: %s", tt.name, w.Code, body.Code, tt.status, tt.code, w.Body)
+			continue
+		}
+		if body.Message == "" || body.Error != body.Message {
+			t.Errorf("%s: message %q, error %q; want a message repeated in error", tt.name, body.Message, body.Error)
+		}
+		if want := req.Header.Get(api.HeaderRequestID); body.RequestID != want {
+			t.Errorf("%s: request_id %q, want %q", tt.name, body.RequestID, want)
+		}
+		if !sameJSON(t, body.Details, tt.details) {
+			t.Errorf("%s: details %s, want %s", tt.name, body.Details, tt.details)
+		}
+		// The binder's own messages name Go structs; none may reach the client
+		if strings.Contains(w.Body.String(), "VideoCreateRequest") {
+			t.Errorf("%s: body leaks the binder's message: %s", tt.name, w.Body)
+		}
+	}
+
+	// A request without an ID gets a generated one
+	w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/999", "", "owner", ""))
+	if body := decodeError(t, w.Body.Bytes()); body.RequestID == "" || body.RequestID != w.Header().Get(api.HeaderRequestID) {
+		t.Errorf("request_id %q, want the generated %q", body.RequestID, w.Header().Get(api.HeaderRequestID))
+	}
+}
+
+func TestErrorResponseRateLimitedAndInternal(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:     services.NewVideoService(db, nil, videoSettings, log),
+		RateLimits: api.RateLimits{Writes: denyLimiter{}},
+	})
+
+	w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-1","title":"t"}`, "owner", ""))
+	body := decodeError(t, w.Body.Bytes())
+	if w.Code != http.StatusTooManyRequests || body.Code != api.CodeRateLimited || body.Message == "" || w.Header().Get("Retry-After") != "31" {
+		t.Errorf("over the limit: %d %+v, Retry-After %q", w.Code, body, w.Header().Get("Retry-After"))
+	}
+
+	// Reads aren't limited here, so a broken database surfaces as a 500
+	db.Migrator().DropTable(&models.Video{})
+	w = serve(router, adminRequest(http.MethodGet, "/api/v1/videos/1", "", "owner", ""))
+	body = decodeError(t, w.Body.Bytes())
+	if w.Code != http.StatusInternalServerError || body.Code != api.CodeInternal || body.Message == "" || len(body.Details) != 0 {
+		t.Errorf("broken database: %d %s", w.Code, w.Body)
+	}
+	if strings.Contains(strings.ToLower(w.Body.String()), "no such table") {
+		t.Errorf("the 500 leaks the database error: %s", w.Body)
+	}
+}
+
+// sameJSON reports whether got and want hold the same JSON value; an empty want
+// means details must be absent
+func sameJSON(t *testing.T, got json.RawMessage, want string) bool {
+	t.Helper()
+	if want == "" {
+		return len(got) == 0
+	}
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("bad want %s: %v", want, err)
+	}
+	return reflect.DeepEqual(g, w)
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Error codes are the machine-readable half of an ErrorResponse. Clients branch on
+// the code; the message is for people and may change. A code is never reused for
+// a different condition.
+const (
+	// 400
+	CodeInvalidRequest          = "invalid_request"
+	CodeValidationFailed        = "validation_failed"
+	CodeInvalidCursor           = "invalid_cursor"
+	CodeInvalidSort             = "invalid_sort"
+	CodeInvalidStatus           = "invalid_status"
+	CodeInvalidVisibility       = "invalid_visibility"
+	CodeInvalidCategory         = "invalid_category"
+	CodeInvalidParent           = "invalid_parent"
+	CodeReplyTooDeep            = "reply_too_deep"
+	CodeNotPinnable             = "not_pinnable"
+	CodeInvalidAnonymousSession = "invalid_anonymous_session"
+	CodeInvalidReason           = "invalid_reason"
+	CodeInvalidModerationStatus = "invalid_moderation_status"
+	CodeInvalidDecision         = "invalid_decision"
+	// 401
+	CodeUnauthorized = "unauthorized"
+	CodeInvalidToken = "invalid_token"
+	// 403
+	CodeForbidden               = "forbidden"
+	CodeImpersonationNotAllowed = "impersonation_not_allowed"
+	CodeEditWindowClosed        = "edit_window_closed"
+	CodeCommentsDisabled        = "comments_disabled"
+	// 404
+	CodeNotFound             = "not_found"
+	CodeVideoNotFound        = "video_not_found"
+	CodeCommentNotFound      = "comment_not_found"
+	CodeNotificationNotFound = "notification_not_found"
+	CodeCategoryNotFound     = "category_not_found"
+	CodeExportNotFound       = "export_not_found"
+	CodeDeletionNotFound     = "deletion_not_found"
+	CodeEventNotFound        = "event_not_found"
+	CodeJobNotFound          = "job_not_found"
+	CodeThumbnailNotFound    = "thumbnail_not_found"
+	CodeReportNotFound       = "report_not_found"
+	CodeProgressNotFound     = "progress_not_found"
+	CodeFlagNotFound         = "moderation_flag_not_found"
+	// 409
+	CodeConflict               = "conflict"
+	CodeDuplicateUploadID      = "duplicate_upload_id"
+	CodeInvalidTransition      = "invalid_transition"
+	CodeCategoryExists         = "category_exists"
+	CodeCategoryInUse          = "category_in_use"
+	CodeNotReady               = "not_ready"
+	CodeNotRetryable           = "not_retryable"
+	CodeAlreadyRunning         = "already_running"
+	CodeVideoPublic            = "video_public"
+	CodeVideoNotDeleted        = "video_not_deleted"
+	CodeSessionMerged          = "session_already_merged"
+	CodeIdempotencyKeyInFlight = "idempotency_key_in_flight"
+	CodeReplayRejected         = "replay_rejected"
+	CodeAlreadyReported        = "already_reported"
+	CodeReportResolved         = "report_resolved"
+	CodeFlagResolved           = "moderation_flag_resolved"
+	// 410
+	CodeVideoPurged   = "video_purged"
+	CodeExportExpired = "export_expired"
+	// 412
+	CodeVersionConflict = "version_conflict"
+	// 413
+	CodeRequestTooLarge = "request_too_large"
+	// 415
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	// 422
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+	CodeReplayFailed         = "replay_failed"
+	// 429
+	CodeRateLimited            = "rate_limited"
+	CodeAnonymousQuotaExceeded = "anonymous_quota_exceeded"
+	// 451
+	CodeVideoTakenDown = "video_taken_down"
+	// 500
+	CodeInternal = "internal_error"
+	// 503
+	CodeUnavailable         = "unavailable"
+	CodeAuditUnavailable    = "audit_unavailable"
+	CodePlaybackUnavailable = "playback_unavailable"
+	CodeSearchUnavailable   = "search_unavailable"
+	CodeStorageUnavailable  = "storage_unavailable"
+)
+
+// ErrorResponse is the body of every error answered by the API
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Details is extra context for the code: []FieldError for validation_failed,
+	// otherwise an object such as {"allowed_visibilities": [...]}
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	// Error repeats Message for clients written before codes; don't branch on it
+	Error string `json:"error"`
+}
+
+// FieldError is one problem with one field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func newErrorResponse(c *gin.Context, code, msg string, details interface{}) ErrorResponse {
+	requestID, _ := c.Get(requestIDKey)
+	id, _ := requestID.(string)
+	return ErrorResponse{Code: code, Message: msg, Details: details, RequestID: id, Error: msg}
+}
+
+// respondError answers the request with an ErrorResponse; details may be nil
+func respondError(c *gin.Context, status int, code, msg string, details interface{}) {
+	c.JSON(status, newErrorResponse(c, code, msg, details))
+}
+
+// abortWithError is respondError for middleware: it also stops the handler chain
+func abortWithError(c *gin.Context, status int, code, msg string, details interface{}) {
+	c.AbortWithStatusJSON(status, newErrorResponse(c, code, msg, details))
+}
+
+// respondBindError answers a request whose body failed to bind with 400
+// validation_failed, one FieldError per problem instead of the binder's message,
+// or 413 if the body was over the size limit
+func respondBindError(c *gin.Context, err error) {
+	if respondTooLarge(c, err) {
+		return
+	}
+	respondError(c, http.StatusBadRequest, CodeValidationFailed, "Invalid request body", bindingFieldErrors(err))
+}
+
+// respondTooLarge aborts with 413 if err came from reading past limitRequestBody's
+// cap, reporting whether it did
+func respondTooLarge(c *gin.Context, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	abortWithError(c, http.StatusRequestEntityTooLarge, CodeRequestTooLarge, "Request body too large", gin.H{"max_bytes": tooLarge.Limit})
+	return true
+}
+
+// respondFieldErrors answers 400 validation_failed for problems keyed by field,
+// as services.ValidationError and the merge patch decoder report them
+func respondFieldErrors(c *gin.Context, msg string, fields map[string]string) {
+	out := make([]FieldError, 0, len(fields))
+	for field, problem := range fields {
+		out = append(out, FieldError{Field: field, Rule: "invalid", Message: problem})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	respondError(c, http.StatusBadRequest, CodeValidationFailed, msg, out)
+}
+
+func bindingFieldErrors(err error) []FieldError {
+	var invalid validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &invalid):
+		out := make([]FieldError, 0, len(invalid))
+		for _, fe := range invalid {
+			out = append(out, FieldError{Field: fieldPath(fe), Rule: fe.Tag(), Message: ruleMessage(fe)})
+		}
+		return out
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return []FieldError{{Field: field, Rule: "type", Message: "must be " + jsonKind(typeErr.Type)}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return []FieldError{{Field: "body", Rule: "json", Message: "must be valid JSON"}}
+	default:
+		return []FieldError{{Field: "body", Rule: "invalid", Message: "could not be read"}}
+	}
+}
+
+// fieldPath is a failed field's JSON path, without the request struct's name
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		switch fe.Kind() {
+		case reflect.String:
+			return "must be at least " + fe.Param() + " characters"
+		case reflect.Slice, reflect.Map:
+			return "must have at least " + fe.Param() + " items"
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		switch fe.Kind() {
+		case reflect.String:
+			return "must be at most " + fe.Param() + " characters"
+		case reflect.Slice, reflect.Map:
+			return "must have at most " + fe.Param() + " items"
+		}
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	default:
+		return "failed the " + fe.Tag() + " rule"
+	}
+}
+
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonKind(t.Elem())
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	default:
+		return "a " + t.String()
+	}
+}
+
+var registerFieldNames sync.Once
+
+// useJSONFieldNames makes the binding validator report fields by their JSON
+// names, which are what clients send
+func useJSONFieldNames() {
+	registerFieldNames.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			switch name {
+			case "-":
+				return ""
+			case "":
+				return f.Name
+			}
+			return name
+		})
+	})
+}
@@ -0,0 +1,79 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// TestLookupErrorsMapToStatus checks that not-found and forbidden sentinels map to
+// 404 and 403 while any other database error, wrapped or not, stays a 500
+func TestLookupErrorsMapToStatus(t *testing.T) {
+	requests := []struct {
+		name, method, path, user string
+	}{
+		{"get video", http.MethodGet, "/api/v1/videos/999", "owner"},
+		{"get video by upload ID", http.MethodGet, "/api/v1/videos/upload/up-none", "owner"},
+		{"update video", http.MethodPut, "/api/v1/videos/999", "owner"},
+		{"delete video", http.MethodDelete, "/api/v1/videos/999", "owner"},
+		{"list comments", http.MethodGet, "/api/v1/videos/999/comments", "owner"},
+		{"delete comment", http.MethodDelete, "/api/v1/comments/999", "owner"},
+	}
+	setup := func(t *testing.T, broken bool) http.Handler {
+		db := dbtest.Open(t)
+		log := zap.NewNop().Sugar()
+		router := newRouter(api.Dependencies{
+			Videos:   services.NewVideoService(db, nil, videoSettings, log),
+			Comments: services.NewCommentService(db, commentSettings, log),
+		})
+		if broken {
+			db.Migrator().DropTable(&models.Comment{}, &models.Video{})
+		}
+		return router
+	}
+	for _, broken := range []bool{false, true} {
+		want, wantCode := http.StatusNotFound, ""
+		if broken {
+			want, wantCode = http.StatusInternalServerError, api.CodeInternal
+		}
+		router := setup(t, broken)
+		for _, r := range requests {
+			w := serve(router, adminRequest(r.method, r.path, `{"title":"x"}`, r.user, ""))
+			var body struct {
+				Code string `json:"code"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if w.Code != want || (wantCode != "" && body.Code != wantCode) {
+				t.Errorf("%s (database broken: %v): status %d %q, want %d", r.name, broken, w.Code, body.Code, want)
+			}
+		}
+	}
+}
+
+func TestDeleteCommentForbidden(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:   services.NewVideoService(db, nil, videoSettings, log),
+		Comments: services.NewCommentService(db, commentSettings, log),
+	})
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t", Visibility: models.VisibilityPublic}
+	db.Create(&video)
+	comment := models.Comment{VideoID: video.ID, UserID: "author", Content: "c", Status: models.CommentVisible}
+	db.Create(&comment)
+	path := "/api/v1/comments/" + itoa(comment.ID)
+
+	if w := serve(router, adminRequest(http.MethodDelete, path, "", "mallory", "")); w.Code != http.StatusForbidden {
+		t.Errorf("bystander: status %d, want 403", w.Code)
+	}
+	if w := serve(router, adminRequest(http.MethodDelete, path, "", "owner", "")); w.Code != http.StatusOK {
+		t.Errorf("video owner: status %d, want 200: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// NewWindowLimiter exposes the fixed-window limiter the routes use for tests
+func NewWindowLimiter(limit int, window time.Duration) *windowLimiter {
+	return newWindowLimiter(limit, window)
+}
+
+// SetClock replaces the limiter's clock for tests
+func (l *windowLimiter) SetClock(now func() time.Time) { l.now = now }
+
+// SetClock replaces the limiter's clock for tests; the next sweep is due a minute
+// after now
+func (l *TokenBucketLimiter) SetClock(now func() time.Time) {
+	l.now = now
+	l.lastSweep = now()
+}
+
+// Buckets is how many callers the limiter is tracking
+func (l *TokenBucketLimiter) Buckets() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// Idempotent exposes the idempotency middleware for routes tests build themselves
+func Idempotent(store *services.IdempotencyStore, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return idempotent(store, logger)
+}
+
+// ActAs makes requests act as userID, as resolveIdentity would for X-User-ID
+func ActAs(userID string) gin.HandlerFunc {
+	return func(c *gin.Context) { c.Set(identityKey, Identity{UserID: userID, ActorID: userID}) }
+}
@@ -0,0 +1,89 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/flags"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// installFlags makes a flag set built from the current environment the default
+// for the rest of the test
+func installFlags(t *testing.T) {
+	t.Helper()
+	set, err := flags.New(nil, zap.NewNop().Sugar(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags.SetDefault(set)
+	t.Cleanup(func() { flags.SetDefault(nil) })
+}
+
+// TestReactionsFlagRollout rolls reactions out to half the users: the endpoints
+// exist only for users in the rollout, by the same bucket on every request
+func TestReactionsFlagRollout(t *testing.T) {
+	t.Setenv("FLAG_VIDEO_REACTIONS", "50%")
+	installFlags(t)
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+	})
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Status: models.StatusReady}
+	db.Create(&video)
+
+	var in, out string
+	for i := 0; in == "" || out == ""; i++ {
+		user := "user-" + itoa(uint(i))
+		if flags.Enabled(flags.WithUser(context.Background(), user), flags.VideoReactions) {
+			in = user
+		} else {
+			out = user
+		}
+	}
+	like := func(user string) int {
+		return serve(router, adminRequest(http.MethodPost, "/api/v1/videos/"+itoa(video.ID)+"/like", "", user, "")).Code
+	}
+	for i := 0; i < 3; i++ {
+		if code := like(in); code != http.StatusOK {
+			t.Errorf("%s, in the rollout: status %d, want 200", in, code)
+		}
+		if code := like(out); code != http.StatusNotFound {
+			t.Errorf("%s, outside the rollout: status %d, want 404", out, code)
+		}
+	}
+}
+
+// TestThumbnailResizeFlagOff redirects to the original without resizing
+func TestThumbnailResizeFlagOff(t *testing.T) {
+	t.Setenv("FLAG_THUMBNAIL_RESIZE", "false")
+	installFlags(t)
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:     services.NewVideoService(db, nil, videoSettings, log),
+		Thumbnails: services.NewThumbnailService(log, nil, []int{320}, 1, 1<<20, time.Second),
+	})
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", ThumbnailURL: "https://cdn.example/thumbs/up-1.png"}
+	bare := models.Video{UploadID: "up-2", UserID: "owner", Title: "t"}
+	db.Create(&video)
+	db.Create(&bare)
+
+	w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/"+itoa(video.ID)+"/thumbnail?w=320", "", "", ""))
+	if w.Code != http.StatusFound || w.Header().Get("Location") != video.ThumbnailURL {
+		t.Errorf("status %d, location %q; want a redirect to the original", w.Code, w.Header().Get("Location"))
+	}
+	w = serve(router, adminRequest(http.MethodGet, "/api/v1/videos/"+itoa(bare.ID)+"/thumbnail?w=320", "", "", ""))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("no thumbnail: status %d, want 404", w.Code)
+	}
+}
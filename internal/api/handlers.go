@@ -1,136 +1,507 @@
 package api
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/streamhive/video-catalog-api/internal/cache"
+	"github.com/streamhive/video-catalog-api/internal/cursor"
+	"github.com/streamhive/video-catalog-api/internal/db"
+	"github.com/streamhive/video-catalog-api/internal/jobs"
 	"github.com/streamhive/video-catalog-api/internal/models"
 	"github.com/streamhive/video-catalog-api/internal/services"
 )
 
 // VideoHandler handles video-related HTTP requests
 type VideoHandler struct {
-	videoService *services.VideoService
-	commentSvc   *services.CommentService
-	logger       *zap.SugaredLogger
+	videoService    *services.VideoService
+	commentSvc      *services.CommentService
+	counterSvc      *services.CounterService
+	bundleSvc       *services.SupportBundleService
+	moderationSvc   *services.ModerationService
+	accessLog       *services.AccessLogService
+	tagMigrationSvc *services.TagMigrationService
+	notificationSvc *services.NotificationService
+	dataExportSvc   *services.DataExportService
+	quarantineSvc   *services.EventQuarantineService
+	inboundEvents   *services.InboundEventArchive
+	jobs            *jobs.Runner
+	cursors         *cursor.Codec
+	recentWrites    *cache.RecentWrites
+	audit           *services.AuditService
+	anonymous       *services.AnonymousSessionService
+	views           *services.ViewService
+	thumbnails      *services.ThumbnailService
+	reactions       *services.ReactionService
+	eventLog        *services.PublicEventLog
+	tags            *services.TagService
+	categories      *services.CategoryService
+	userContentSvc  *services.ContentDeletionService
+	searchIndex     *services.SearchIndex
+	reports         *services.ReportService
+	progress        *services.WatchProgressService
+	maxThumbBytes   int64
+	logger          *zap.SugaredLogger
+}
+
+// Dependencies groups the services the HTTP layer is built from
+type Dependencies struct {
+	Videos        *services.VideoService
+	Comments      *services.CommentService
+	Counters      *services.CounterService
+	Bundles       *services.SupportBundleService
+	Moderation    *services.ModerationService
+	AccessLog     *services.AccessLogService
+	TagMigrations *services.TagMigrationService
+	Notifications *services.NotificationService
+	DataExports   *services.DataExportService
+	Quarantine    *services.EventQuarantineService
+	InboundEvents *services.InboundEventArchive
+	Jobs          *jobs.Runner
+	Cursors       *cursor.Codec
+	RecentWrites  *cache.RecentWrites
+	Audit         *services.AuditService
+	Anonymous     *services.AnonymousSessionService
+	Views         *services.ViewService
+	Thumbnails    *services.ThumbnailService
+	Reactions     *services.ReactionService
+	EventLog      *services.PublicEventLog
+	Tags          *services.TagService
+	Categories    *services.CategoryService
+	UserContent   *services.ContentDeletionService
+	Reports       *services.ReportService
+	Progress      *services.WatchProgressService
+	// Search is the OpenSearch index behind video search; nil searches the database
+	Search *services.SearchIndex
+	// Auth verifies callers' credentials; nil trusts the gateway headers
+	Auth *Authenticator
+	// Impersonation gates X-Impersonate-User; its Audit is usually the same service as above
+	Impersonation ImpersonationConfig
+	// RateLimits are the per-caller read and write limits for all API routes
+	RateLimits RateLimits
+	// Idempotency stores responses for writes retried with an Idempotency-Key; nil ignores the header
+	Idempotency *services.IdempotencyStore
+	// MaxBodyBytes caps API request bodies; 0 leaves them unlimited
+	MaxBodyBytes int64
+	// MaxThumbnailBytes caps uploaded thumbnail images, which are exempt from MaxBodyBytes
+	MaxThumbnailBytes int64
 }
 
 // NewVideoHandler creates a new video handler
-func NewVideoHandler(videoService *services.VideoService, commentSvc *services.CommentService, logger *zap.SugaredLogger) *VideoHandler {
+func NewVideoHandler(deps Dependencies, logger *zap.SugaredLogger) *VideoHandler {
 	return &VideoHandler{
-		videoService: videoService,
-	commentSvc:   commentSvc,
-		logger:       logger,
+		videoService:    deps.Videos,
+		commentSvc:      deps.Comments,
+		counterSvc:      deps.Counters,
+		bundleSvc:       deps.Bundles,
+		moderationSvc:   deps.Moderation,
+		accessLog:       deps.AccessLog,
+		tagMigrationSvc: deps.TagMigrations,
+		notificationSvc: deps.Notifications,
+		dataExportSvc:   deps.DataExports,
+		quarantineSvc:   deps.Quarantine,
+		inboundEvents:   deps.InboundEvents,
+		jobs:            deps.Jobs,
+		cursors:         deps.Cursors,
+		recentWrites:    deps.RecentWrites,
+		audit:           deps.Audit,
+		anonymous:       deps.Anonymous,
+		views:           deps.Views,
+		thumbnails:      deps.Thumbnails,
+		reactions:       deps.Reactions,
+		eventLog:        deps.EventLog,
+		tags:            deps.Tags,
+		categories:      deps.Categories,
+		userContentSvc:  deps.UserContent,
+		searchIndex:     deps.Search,
+		reports:         deps.Reports,
+		progress:        deps.Progress,
+		maxThumbBytes:   deps.MaxThumbnailBytes,
+		logger:          logger,
 	}
 }
 
 // SetupRoutes sets up all API routes
-func SetupRoutes(router *gin.Engine, videoService *services.VideoService, logger *zap.SugaredLogger) {
-	commentSvc := services.NewCommentService(videoService.DB(), logger)
-	handler := NewVideoHandler(videoService, commentSvc, logger)
+func SetupRoutes(router *gin.Engine, deps Dependencies, logger *zap.SugaredLogger) {
+	handler := NewVideoHandler(deps, logger)
+	useJSONFieldNames()
 
-	api := router.Group("/api/v1")
+	// Caps request bodies and resolves the effective user (and admin impersonation)
+	// for every API route; mutating routes need an authenticated user unless listed
+	// in anonymousWrites
+	api := router.Group("/api/v1", limitRequestBody(deps.MaxBodyBytes, map[string]int64{
+		// The multipart envelope around the image needs a little room of its own
+		"/api/v1/videos/:id/thumbnail": deps.MaxThumbnailBytes + multipartOverheadBytes,
+	}),
+		resolveIdentity(deps.Auth, deps.Impersonation, logger), resolveAnonymous(deps.Anonymous),
+		rateLimitRequests(deps.RateLimits), requireUserForWrites(), flagIdentity())
 	{
+		// Writes clients retry on flaky networks; see idempotent
+		idem := idempotent(deps.Idempotency, logger)
+
 		videos := api.Group("/videos")
 		{
 			videos.GET("", handler.ListVideos)
-			videos.POST("", handler.CreateVideo)
+			videos.POST("", idem, handler.CreateVideo)
 			videos.GET("/:id", handler.GetVideo)
 			videos.PUT("/:id", handler.UpdateVideo)
+			videos.PATCH("/:id", handler.PatchVideo)
 			videos.DELETE("/:id", handler.DeleteVideo)
+			videos.POST("/:id/restore", handler.RestoreVideo)
+			videos.POST("/:id/share-token", handler.RotateShareToken)
+			videos.GET("/deletions/:jobID", handler.GetDeletion)
+			videos.POST("/deletions/:jobID/retry", handler.RetryDeletion)
 			videos.GET("/search", handler.SearchVideos)
+			videos.GET("/batch", handler.BatchGetVideos)
 			videos.GET("/upload/:uploadId", handler.GetVideoByUploadID)
 			// Comments on a video
 			videos.GET("/:id/comments", handler.ListComments)
-			videos.POST("/:id/comments", handler.AddComment)
+			videos.POST("/:id/comments", idem, handler.AddComment)
+			videos.GET("/:id/access-log", handler.GetAccessLog)
+			videos.GET("/:id/history", handler.GetStatusHistory)
+			videos.GET("/:id/thumbnail", handler.GetThumbnail)
+			videos.POST("/:id/thumbnail", handler.UploadThumbnail)
+			videos.GET("/:id/thumbnails", handler.ListThumbnails)
+			videos.POST("/:id/thumbnail/select", handler.SelectThumbnail)
+			videos.GET("/:id/playback", handler.GetPlayback)
+			videos.POST("/:id/view", rateLimitByUser(newWindowLimiter(120, time.Minute)), handler.RecordView)
+			reactionLimit := rateLimitByUser(newWindowLimiter(60, time.Minute))
+			videos.POST("/:id/like", reactionLimit, handler.LikeVideo)
+			videos.POST("/:id/dislike", reactionLimit, handler.DislikeVideo)
+			videos.DELETE("/:id/reaction", reactionLimit, handler.ClearReaction)
+			videos.POST("/:id/notifications/mute", handler.MuteVideoNotifications)
+			videos.POST("/:id/notifications/unmute", handler.UnmuteVideoNotifications)
+			videos.POST("/:id/report", rateLimitByUser(newWindowLimiter(20, time.Minute)), handler.ReportVideo)
+			videos.GET("/:id/progress", handler.GetWatchProgress)
+			videos.PUT("/:id/progress", handler.SaveWatchProgress)
 		}
 
+		// Tag cloud and tag suggestions
+		api.GET("/tags", handler.ListTags)
+		api.GET("/tags/suggest", handler.SuggestTags)
+		api.GET("/categories", handler.ListCategories)
+
+		// Notifications for the calling user
+		api.GET("/notifications", handler.ListNotifications)
+		api.POST("/notifications/:notificationID/read", handler.MarkNotificationRead)
+
 		// User-specific routes
 		users := api.Group("/users/:userID/videos")
 		{
 			users.GET("", handler.ListUserVideos)
 		}
+		api.GET("/users/:userID/summary", handler.GetChannelSummary)
+		api.GET("/users/:userID/continue-watching", handler.ContinueWatching)
+
+		// Anonymous sessions for logged-out engagement, merged into the account on sign-up
+		api.POST("/sessions/anonymous", rateLimitByUser(newWindowLimiter(20, time.Minute)), handler.CreateAnonymousSession)
+		api.POST("/users/:userID/merge-anonymous", handler.MergeAnonymousSession)
+
+		// Personal data export (GDPR portability)
+		api.GET("/users/:userID/data-export", handler.ExportUserData)
+		api.GET("/users/:userID/export", handler.DownloadUserData)
+		api.GET("/users/:userID/data-exports/:exportID", handler.GetDataExport)
+		api.GET("/users/:userID/data-exports/:exportID/download", handler.DownloadDataExport)
+
+		// Account deletion (GDPR erasure), called by the user service with an admin role
+		api.DELETE("/users/:userID/content", requireRole("admin"), handler.DeleteUserContent)
+		api.GET("/users/:userID/content-deletions/:deletionID", requireRole("admin"), handler.GetUserContentDeletion)
+		api.POST("/users/:userID/content-deletions/:deletionID/retry", requireRole("admin"), handler.RetryUserContentDeletion)
+
+		// Comment management
+		api.GET("/comments/:commentID/replies", handler.ListReplies)
+		api.PUT("/comments/:commentID", handler.UpdateComment)
+		api.PATCH("/comments/:commentID/pin", handler.PinComment)
+		commentLikeLimit := rateLimitByUser(newWindowLimiter(60, time.Minute))
+		api.POST("/comments/:commentID/like", commentLikeLimit, handler.LikeComment)
+		api.DELETE("/comments/:commentID/like", commentLikeLimit, handler.UnlikeComment)
+		api.DELETE("/comments/:commentID", handler.DeleteComment)
 
-	// Comment management
-	api.DELETE("/comments/:commentID", handler.DeleteComment)
+		// Admin / support routes
+		admin := api.Group("/admin", requireRole("admin"))
+		{
+			// Moderation: any video or comment regardless of owner; each action is audited
+			admin.GET("/videos", handler.AdminListVideos)
+			admin.DELETE("/videos/:id", handler.AdminDeleteVideo)
+			admin.PATCH("/videos/:id/status", handler.AdminSetVideoStatus)
+			admin.POST("/videos/:id/deletion/retry", handler.AdminRetryDeletion)
+			admin.DELETE("/comments/:commentID", handler.AdminDeleteComment)
+			admin.POST("/videos/:id/recount", handler.RecountVideo)
+			// Bundles fan out to storage, so keep support tooling from hammering it
+			admin.GET("/videos/:id/support-bundle", rateLimitByUser(newWindowLimiter(30, time.Minute)), handler.GetSupportBundle)
+			admin.GET("/moderation/flags", handler.ListModerationFlags)
+			admin.POST("/moderation/flags/:flagID/resolve", handler.AdminResolveModerationFlag)
+			admin.GET("/reports", handler.AdminListReports)
+			admin.GET("/reports/videos", handler.AdminListReportedVideos)
+			admin.POST("/reports/:reportID/resolve", handler.AdminResolveReport)
+			admin.POST("/migrations/tags/backfill", handler.StartTagsBackfill)
+			admin.GET("/migrations/tags/verify", handler.VerifyTagsMigration)
+			admin.POST("/search/reindex", handler.StartSearchReindex)
+			admin.GET("/events/quarantine", handler.ListQuarantinedEvents)
+			admin.POST("/events/quarantine/replay", handler.ReplayUploadEvents)
+			admin.POST("/events/quarantine/:eventID/replay", handler.ReplayQuarantinedEvent)
+			admin.GET("/events", handler.ListInboundEvents)
+			admin.GET("/events/:eventID", handler.GetInboundEvent)
+			admin.POST("/events/:eventID/reprocess", handler.ReprocessInboundEvent)
+			admin.GET("/jobs", handler.ListJobs)
+			admin.POST("/jobs/:name/run", handler.RunJob)
+			admin.GET("/audit", handler.ListAuditLog)
+			admin.GET("/event-log", handler.ListPublicEvents)
+			admin.POST("/categories", handler.AdminCreateCategory)
+			admin.PUT("/categories/:slug", handler.AdminUpdateCategory)
+			admin.DELETE("/categories/:slug", handler.AdminDeleteCategory)
+		}
 	}
 }
 
 // ListVideos handles GET /api/v1/videos
 func (h *VideoHandler) ListVideos(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	h.listVideos(c, "", false)
+}
+
+// ListUserVideos handles GET /api/v1/users/:userID/videos
+func (h *VideoHandler) ListUserVideos(c *gin.Context) {
+	userID := c.Param("userID")
+	requesterID := currentUser(c)
 
+	// Include private only if caller is the owner
+	includePrivate := requesterID != "" && requesterID == userID
+
+	h.listVideos(c, userID, includePrivate)
+}
+
+// videosSort is the sort spec video list cursors are bound to
+const videosSort = "created_at_desc"
+
+// listVideos serves the video list endpoints with page/per_page offset paging, or
+// keyset paging when a cursor is given. Cursors are bound to the owner filter and
+// to whether private videos were included, so an owner's cursor is useless to anyone
+// else. ?view=compact answers with cards instead of full videos.
+func (h *VideoHandler) listVideos(c *gin.Context, userID string, includePrivate bool) {
+	compact, ok := compactView(c)
+	if !ok {
+		return
+	}
+	videoFilters := services.VideoFilters{
+		Category: c.Query("category"),
+		Status:   models.VideoStatus(c.Query("status")),
+	}
+	if tag := c.Query("tag"); tag != "" {
+		videoFilters.Tags = []string{tag}
+	}
+	filters := cursor.Filters{
+		"list":     "videos",
+		"user_id":  userID,
+		"private":  strconv.FormatBool(includePrivate),
+		"category": videoFilters.Category,
+		"status":   string(videoFilters.Status),
+		"tag":      c.Query("tag"),
+	}
+	if token := c.Query("cursor"); token != "" {
+		h.listVideosByCursor(c, userID, includePrivate, compact, videoFilters, token, filters)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	if page < 1 {
 		page = 1
 	}
-	if perPage < 1 || perPage > 100 {
-		perPage = 20
+	perPage := perPageFor(c, 0)
+	// Offer a cursor so clients can switch to keyset paging from here; only the
+	// default order has a stable keyset
+	offerCursor := func(total int64, n int) bool {
+		return int64(page*perPage) < total && n > 0 && requestedSort(c).IsDefault()
 	}
 
-	response, err := h.videoService.ListVideos("", page, perPage, false)
-	if err != nil {
-		h.logger.Errorw("Failed to list videos", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list videos"})
+	if compact {
+		response, err := h.videoService.ListVideoCards(c.Request.Context(), userID, page, perPage, includePrivate, requestedSort(c), videoFilters)
+		if err != nil {
+			h.listVideosFailed(c, err, userID)
+			return
+		}
+		if offerCursor(response.Total, len(response.Videos)) {
+			response.NextCursor = h.nextVideosCursor(filters, cardPosition(response.Videos), perPage)
+		}
+		c.JSON(http.StatusOK, response)
 		return
 	}
 
+	response, err := h.videoService.ListVideos(c.Request.Context(), userID, page, perPage, includePrivate, requestedSort(c), videoFilters)
+	if err != nil {
+		h.listVideosFailed(c, err, userID)
+		return
+	}
+	if offerCursor(response.Total, len(response.Videos)) {
+		response.NextCursor = h.nextVideosCursor(filters, videoPosition(response.Videos), perPage)
+	}
+	h.attachListReactions(c, response)
 	c.JSON(http.StatusOK, response)
 }
 
-// ListUserVideos handles GET /api/v1/users/:userID/videos
-func (h *VideoHandler) ListUserVideos(c *gin.Context) {
-	userID := c.Param("userID")
-	requesterID := c.GetHeader("X-User-ID")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+// listVideosFailed answers a failed video list: 400 for a bad filter, else 500
+func (h *VideoHandler) listVideosFailed(c *gin.Context, err error, userID string) {
+	if h.invalidVideoFilter(c, err) {
+		return
+	}
+	h.log(c).Errorw("Failed to list videos", "error", err, "userID", userID)
+	respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list videos", nil)
+}
 
-	if page < 1 {
-		page = 1
+// Values of the view query parameter on video lists
+const (
+	viewFull    = "full"
+	viewCompact = "compact"
+)
+
+// compactView reads ?view=, answering 400 for an unknown view with ok false
+func compactView(c *gin.Context) (compact, ok bool) {
+	switch view := c.Query("view"); view {
+	case "", viewFull:
+		return false, true
+	case viewCompact:
+		return true, true
+	default:
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "unknown view "+strconv.Quote(view),
+			gin.H{"parameter": "view", "allowed_views": []string{viewFull, viewCompact}})
+		return false, false
 	}
-	if perPage < 1 || perPage > 100 {
-		perPage = 20
+}
+
+// listVideosByCursor serves listVideos when a cursor is given: keyset paging with no
+// totals. The page size is per_page if given, else the one the cursor carries.
+func (h *VideoHandler) listVideosByCursor(c *gin.Context, userID string, includePrivate, compact bool, videoFilters services.VideoFilters, token string, filters cursor.Filters) {
+	if !requestedSort(c).IsDefault() {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "cursor paging supports the default order (created_at desc) only", nil)
+		return
+	}
+	var after pagedPosition
+	if err := h.cursors.Decode(token, videosSort, filters, &after); err != nil {
+		invalidCursor(c, err)
+		return
+	}
+	limit := perPageFor(c, after.PerPage)
+	if compact {
+		cards, more, err := h.videoService.ListVideoCardsAfter(c.Request.Context(), userID, includePrivate, videoFilters, &after.TimeID, limit)
+		if err != nil {
+			h.listVideosFailed(c, err, userID)
+			return
+		}
+		response := &models.VideoCardListResponse{Videos: cards, PerPage: limit}
+		if more {
+			response.NextCursor = h.nextVideosCursor(filters, cardPosition(cards), limit)
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	videos, more, err := h.videoService.ListVideosAfter(c.Request.Context(), userID, includePrivate, videoFilters, &after.TimeID, limit)
+	if err != nil {
+		h.listVideosFailed(c, err, userID)
+		return
+	}
+	response := &models.VideoListResponse{Videos: videos, PerPage: limit}
+	if more {
+		response.NextCursor = h.nextVideosCursor(filters, videoPosition(videos), limit)
 	}
+	h.attachListReactions(c, response)
+	c.JSON(http.StatusOK, response)
+}
 
-	// Include private only if caller is the owner
-	includePrivate := requesterID != "" && requesterID == userID
+// videoPosition is the keyset position of the last of videos
+func videoPosition(videos []models.Video) cursor.TimeID {
+	last := videos[len(videos)-1]
+	return cursor.TimeID{CreatedAt: last.CreatedAt, ID: last.ID}
+}
 
-	response, err := h.videoService.ListVideos(userID, page, perPage, includePrivate)
+// cardPosition is the keyset position of the last of cards
+func cardPosition(cards []models.VideoCard) cursor.TimeID {
+	last := cards[len(cards)-1]
+	return cursor.TimeID{CreatedAt: last.CreatedAt, ID: last.ID}
+}
+
+// nextVideosCursor encodes the position after last; on failure the response just
+// has no next_cursor
+func (h *VideoHandler) nextVideosCursor(filters cursor.Filters, last cursor.TimeID, perPage int) string {
+	next, err := h.cursors.Encode(videosSort, filters, pagedPosition{TimeID: last, PerPage: perPage})
 	if err != nil {
-		h.logger.Errorw("Failed to list user videos", "error", err, "userID", userID)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list videos"})
-		return
+		h.logger.Errorw("Failed to encode cursor", "error", err)
+		return ""
+	}
+	return next
+}
+
+// invalidVideoFilter answers 400 for a list request with a bad sort or status and
+// reports whether it did
+func (h *VideoHandler) invalidVideoFilter(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrInvalidSort):
+		respondError(c, http.StatusBadRequest, CodeInvalidSort, err.Error(), nil)
+	case errors.Is(err, services.ErrInvalidStatus):
+		respondError(c, http.StatusBadRequest, CodeInvalidStatus, err.Error(), gin.H{"allowed_statuses": models.VideoStatuses})
+	default:
+		return false
 	}
+	return true
+}
 
-	c.JSON(http.StatusOK, response)
+// requestedSort reads ?sort= and ?order=; the service validates them
+func requestedSort(c *gin.Context) services.VideoSort {
+	return services.VideoSort{Key: c.Query("sort"), Order: c.Query("order")}
 }
 
 // CreateVideo handles POST /api/v1/videos
 func (h *VideoHandler) CreateVideo(c *gin.Context) {
 	var req models.VideoCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
 	if req.UploadID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "upload_id is required (obtain from UploadService)"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "upload_id is required (obtain from UploadService)", nil)
 		return
 	}
 
-	userID := c.GetHeader("X-User-ID")
+	userID := currentUser(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID required"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
 		return
 	}
 
-	video, err := h.videoService.CreateVideo(userID, &req)
+	video, err := h.videoService.CreateVideo(c.Request.Context(), userID, &req)
 	if err != nil {
-		h.logger.Errorw("Failed to create video", "error", err, "userID", userID)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create video"})
+		// Usually a client retrying after a timeout: point it at what it already created
+		var dup *services.DuplicateUploadError
+		if errors.As(err, &dup) && dup.UserID == userID {
+			respondError(c, http.StatusConflict, CodeDuplicateUploadID, "Video already exists for upload_id", gin.H{"video_id": dup.VideoID})
+			return
+		}
+		if errors.Is(err, services.ErrDuplicateUploadID) {
+			respondError(c, http.StatusConflict, CodeDuplicateUploadID, "Video already exists for upload_id", nil)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidVisibility) {
+			respondError(c, http.StatusBadRequest, CodeInvalidVisibility, "Invalid visibility", gin.H{"allowed_visibilities": models.Visibilities})
+			return
+		}
+		var badCategory *services.InvalidCategoryError
+		if errors.As(err, &badCategory) {
+			respondError(c, http.StatusBadRequest, CodeInvalidCategory, badCategory.Error(), gin.H{"allowed_categories": badCategory.Allowed})
+			return
+		}
+		var invalid *services.ValidationError
+		if errors.As(err, &invalid) {
+			respondFieldErrors(c, invalid.Error(), invalid.Fields)
+			return
+		}
+		h.log(c).Errorw("Failed to create video", "error", err, "userID", userID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to create video", nil)
 		return
 	}
 
@@ -141,87 +512,345 @@ func (h *VideoHandler) CreateVideo(c *gin.Context) {
 func (h *VideoHandler) GetVideo(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
 		return
 	}
 
-	video, err := h.videoService.GetVideo(uint(id))
+	video, err := h.videoService.GetVideoCached(c.Request.Context(), uint(id))
 	if err != nil {
-		if err.Error() == "video not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		if errors.Is(err, services.ErrVideoNotFound) {
+			respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
 			return
 		}
-		h.logger.Errorw("Failed to get video", "error", err, "videoID", id)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		h.log(c).Errorw("Failed to get video", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get video", nil)
 		return
 	}
 
+	if takenDown(c, video) {
+		respondTakenDown(c)
+		return
+	}
+	if !canView(c, video) {
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+		return
+	}
+
+	if requester := currentUser(c); requester != video.UserID || identityFrom(c).Impersonating {
+		h.recordAccess(c, video, requester, "video")
+	}
+	hideShareToken(c, video)
+	h.attachReactions(c, video)
+	c.Header("ETag", videoETag(video))
 	c.JSON(http.StatusOK, video)
 }
 
+// videoLookupFailed answers a failed video lookup: 404 for a missing video, 500 for anything else
+func (h *VideoHandler) videoLookupFailed(c *gin.Context, err error, videoID uint) {
+	if errors.Is(err, services.ErrVideoNotFound) {
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+		return
+	}
+	h.log(c).Errorw("Failed to get video", "error", err, "videoID", videoID)
+	respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get video", nil)
+}
+
+// canView reports whether the requester may see a video. Unlisted and private
+// videos are only visible to their owner or with their share token in ?token=;
+// everyone else gets the same 404 as for a missing video. Taken-down videos are
+// visible to their owner only.
+func canView(c *gin.Context, video *models.Video) bool {
+	if currentUser(c) == video.UserID {
+		return true
+	}
+	if video.IsTakenDown() {
+		return false
+	}
+	return video.IsPublic() || video.ShareTokenMatches(c.Query("token"))
+}
+
+// takenDown reports whether the requester could see a video but for its takedown,
+// so it should be answered with 451 rather than 404
+func takenDown(c *gin.Context, video *models.Video) bool {
+	return video.IsTakenDown() && currentUser(c) != video.UserID &&
+		(video.IsPublic() || video.ShareTokenMatches(c.Query("token")))
+}
+
+// respondTakenDown answers 451 for a video taken down by moderation
+func respondTakenDown(c *gin.Context) {
+	respondError(c, http.StatusUnavailableForLegalReasons, CodeVideoTakenDown, "Video has been taken down", nil)
+}
+
+// hideShareToken drops the share token from a video about to be shown to anyone
+// but its owner
+func hideShareToken(c *gin.Context, video *models.Video) {
+	if currentUser(c) != video.UserID {
+		video.ShareToken = ""
+	}
+}
+
 // ListComments handles GET /api/v1/videos/:id/comments
 func (h *VideoHandler) ListComments(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"}); return }
-	video, err := h.videoService.GetVideo(uint(id))
-	if err != nil { c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"}); return }
-	requester := c.GetHeader("X-User-ID")
-	// Enforce privacy: if private, only owner sees comments
-	if video.IsPrivate && video.UserID != requester {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"}); return
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+	requester := currentUser(c)
+	// Enforce privacy: unless public, only the owner or a share token holder sees comments
+	if !canView(c, video) {
+		respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+		return
+	}
+	if h.recentWrites.Recent(commentWriteKey(requester, uint(id))) {
+		// The requester just commented here: read from the primary and keep caches out of it
+		c.Request = c.Request.WithContext(db.WithPrimary(c.Request.Context()))
+		c.Header("Cache-Control", "no-store")
+	}
+	sort, err := services.ParseCommentSort(c.Query("sort"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidSort, err.Error(), gin.H{"allowed_sorts": services.CommentSorts})
+		return
+	}
+	filters := cursor.Filters{"video_id": strconv.FormatUint(id, 10)}
+	if token := c.Query("cursor"); token != "" {
+		if sort != services.CommentSortNewest {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "cursor paging supports the newest order only", nil)
+			return
+		}
+		h.listCommentsByCursor(c, uint(id), token, filters)
+		return
+	}
+	first, err := firstPageSize(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error(), nil)
+		return
 	}
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
-	if page < 1 { page = 1 }
-	if perPage < 1 || perPage > 100 { perPage = 20 }
-	comments, total, err := h.commentSvc.ListComments(uint(id), page, perPage)
-	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list comments"}); return }
-	totalPages := (int(total) + perPage - 1) / perPage
-	c.JSON(http.StatusOK, gin.H{
-		"comments": comments,
-		"total": total,
-		"page": page,
-		"per_page": perPage,
-		"total_pages": totalPages,
-	})
+	if page < 1 {
+		page = 1
+	}
+	if first > 0 && page > 1 {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "first applies to the first page only; continue with next_cursor", nil)
+		return
+	}
+	perPage := perPageFor(c, 0)
+	// pageSize is what this response holds; perPage is what later pages will hold
+	pageSize := perPage
+	if first > 0 {
+		pageSize = first
+	}
+	comments, total, err := h.commentSvc.ListComments(c.Request.Context(), uint(id), sort, page, pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list comments", nil)
+		return
+	}
+	h.attachCommentLikes(c, comments)
+	resp := gin.H{
+		"comments":  comments,
+		"total":     total,
+		"page":      page,
+		"per_page":  perPage,
+		"page_size": pageSize,
+	}
+	more := int64((page-1)*pageSize+len(comments)) < total
+	if first == 0 {
+		// Page numbers only line up when every page is the same size
+		resp["total_pages"] = (int(total) + perPage - 1) / perPage
+	}
+	// Offer a cursor so clients can switch to keyset paging from here. Only newest
+	// has a keyset, and it skips the pinned comment, so it can't start after it.
+	if more && len(comments) > 0 && sort == services.CommentSortNewest && !comments[len(comments)-1].Pinned {
+		last := comments[len(comments)-1]
+		pos := pagedPosition{TimeID: cursor.TimeID{CreatedAt: last.CreatedAt, ID: last.ID}, PerPage: perPage}
+		if next, err := h.cursors.Encode(commentsSort, filters, pos); err == nil {
+			resp["next_cursor"] = next
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// commentsSort is the sort spec comment cursors are bound to
+const commentsSort = "created_at_desc"
+
+// listCommentsByCursor serves ListComments when a cursor is given: keyset paging
+// with no totals. The page size is per_page if given, else the one the cursor
+// carries; first is ignored since this is never the initial page.
+func (h *VideoHandler) listCommentsByCursor(c *gin.Context, videoID uint, token string, filters cursor.Filters) {
+	var after pagedPosition
+	if err := h.cursors.Decode(token, commentsSort, filters, &after); err != nil {
+		invalidCursor(c, err)
+		return
+	}
+	limit := perPageFor(c, after.PerPage)
+	comments, more, err := h.commentSvc.ListCommentsAfter(c.Request.Context(), videoID, &after.TimeID, limit)
+	if err != nil {
+		h.log(c).Errorw("Failed to list comments", "error", err, "videoID", videoID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list comments", nil)
+		return
+	}
+	h.attachCommentLikes(c, comments)
+	resp := gin.H{"comments": comments, "per_page": limit, "page_size": limit}
+	if more {
+		last := comments[len(comments)-1]
+		next, err := h.cursors.Encode(commentsSort, filters, pagedPosition{TimeID: cursor.TimeID{CreatedAt: last.CreatedAt, ID: last.ID}, PerPage: limit})
+		if err != nil {
+			h.log(c).Errorw("Failed to encode cursor", "error", err)
+			respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list comments", nil)
+			return
+		}
+		resp["next_cursor"] = next
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // AddComment handles POST /api/v1/videos/:id/comments
 func (h *VideoHandler) AddComment(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"}); return }
-	requester := c.GetHeader("X-User-ID")
-	if requester == "" { c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID required"}); return }
-	video, err := h.videoService.GetVideo(uint(id))
-	if err != nil { c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"}); return }
-	// If private, only owner can comment (policy; adjust as needed)
-	if video.IsPrivate && video.UserID != requester {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"}); return
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+	// Unless public, only the owner or a share token holder can comment (policy; adjust as needed)
+	if !canView(c, video) {
+		respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+		return
+	}
+	// The thread stays readable; only new comments are refused
+	if !video.CommentsEnabled {
+		respondCommentsDisabled(c)
+		return
 	}
 	var req models.CommentCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
-	cmt, err := h.commentSvc.AddComment(uint(id), requester, req.AuthorName, req.Content)
-	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"}); return }
-	c.JSON(http.StatusCreated, cmt)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	// Display name: explicit author_name, else the one the caller's credentials carry (may be empty)
+	username := req.AuthorName
+	if username == "" {
+		username = identityFrom(c).Username
+		if r := []rune(username); len(r) > 120 {
+			username = string(r[:120])
+		}
+	}
+	cmt, err := h.commentSvc.AddComment(c.Request.Context(), uint(id), requester, username, req.Content, req.ParentID)
+	if err != nil {
+		var invalid *services.ValidationError
+		if errors.As(err, &invalid) {
+			respondFieldErrors(c, invalid.Error(), invalid.Fields)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidParent) {
+			respondError(c, http.StatusBadRequest, CodeInvalidParent, "parent_id must be a visible comment on this video", nil)
+			return
+		}
+		if errors.Is(err, services.ErrCommentsDisabled) {
+			respondCommentsDisabled(c)
+			return
+		}
+		if errors.Is(err, services.ErrReplyTooDeep) {
+			respondError(c, http.StatusBadRequest, CodeReplyTooDeep, "Replies are nested too deeply", gin.H{"max_depth": h.commentSvc.MaxDepth()})
+			return
+		}
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to add comment", nil)
+		return
+	}
+	h.recentWrites.Mark(commentWriteKey(requester, uint(id)))
+	resp := commentPosted{Comment: cmt, Position: 1, ReadYourWritesMs: h.recentWrites.Window().Milliseconds()}
+	// Best effort: the comment is committed, so a failed count only leaves total at zero
+	if cmt.ParentID != nil {
+		// Replies read oldest first, so a new one is the last of its parent's
+		if total, err := h.commentSvc.ReplyCount(db.WithPrimary(c.Request.Context()), *cmt.ParentID); err == nil {
+			resp.Total, resp.Position = total, int(total)
+		} else {
+			h.log(c).Warnw("Failed to count replies after insert", "error", err, "commentID", *cmt.ParentID)
+		}
+	} else if total, err := h.commentSvc.VisibleCount(db.WithPrimary(c.Request.Context()), uint(id)); err == nil {
+		resp.Total = total
+	} else {
+		h.log(c).Warnw("Failed to count comments after insert", "error", err, "videoID", id)
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// respondCommentsDisabled refuses a comment on a video whose owner turned comments off
+func respondCommentsDisabled(c *gin.Context) {
+	respondError(c, http.StatusForbidden, CodeCommentsDisabled, "Comments are disabled for this video", nil)
+}
+
+// commentPosted is the AddComment response: the comment itself plus where it lands
+// on the first newest-first page, so clients needn't re-fetch to show it. For a
+// reply, Total counts the parent's replies and Position is the last of them.
+type commentPosted struct {
+	*models.Comment
+	Position int   `json:"position"`
+	Total    int64 `json:"total"`
+	// ReadYourWritesMs is how long this user's comment reads for the video bypass replicas and caches
+	ReadYourWritesMs int64 `json:"read_your_writes_ms"`
+}
+
+// commentWriteKey identifies a user's writes to one video's comments
+func commentWriteKey(userID string, videoID uint) string {
+	return userID + ":" + strconv.FormatUint(uint64(videoID), 10)
 }
 
 // DeleteComment handles DELETE /api/v1/comments/:commentID
 func (h *VideoHandler) DeleteComment(c *gin.Context) {
 	cid, err := strconv.ParseUint(c.Param("commentID"), 10, 32)
-	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"}); return }
-	requester := c.GetHeader("X-User-ID")
-	if requester == "" { c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID required"}); return }
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid comment ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
 	// Load comment and video to determine permission: author or video owner can delete
-	var comment models.Comment
-	if err := h.videoService.DB().First(&comment, uint(cid)).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"}); return
+	comment, err := h.commentSvc.GetComment(c.Request.Context(), uint(cid))
+	if err != nil {
+		if errors.Is(err, services.ErrCommentNotFound) {
+			respondError(c, http.StatusNotFound, CodeCommentNotFound, "Comment not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to get comment", "error", err, "commentID", cid)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to delete comment", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), comment.VideoID)
+	if err != nil {
+		h.videoLookupFailed(c, err, comment.VideoID)
+		return
 	}
-	video, err := h.videoService.GetVideo(comment.VideoID)
-	if err != nil { c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"}); return }
 	isOwnerOrAuthor := (comment.UserID == requester) || (video.UserID == requester)
-	if err := h.commentSvc.DeleteComment(uint(cid), requester, isOwnerOrAuthor); err != nil {
-		if err.Error() == "forbidden" { c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"}); return }
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"}); return
+	if err := h.commentSvc.DeleteComment(c.Request.Context(), uint(cid), requester, isOwnerOrAuthor); err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+			return
+		}
+		if errors.Is(err, services.ErrCommentNotFound) {
+			respondError(c, http.StatusNotFound, CodeCommentNotFound, "Comment not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to delete comment", "error", err, "commentID", cid)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to delete comment", nil)
+		return
 	}
 	c.JSON(http.StatusOK, gin.H{"deleted": true})
 }
@@ -230,55 +859,160 @@ func (h *VideoHandler) DeleteComment(c *gin.Context) {
 func (h *VideoHandler) UpdateVideo(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+
+	ifVersion, ok := ifMatchVersion(c)
+	if !ok {
 		return
 	}
 
 	var req models.VideoUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
-	video, err := h.videoService.UpdateVideo(uint(id), &req)
+	h.saveVideoUpdate(c, uint(id), requester, ifVersion, &req)
+}
+
+// saveVideoUpdate applies a PUT or PATCH update and answers with the saved video
+func (h *VideoHandler) saveVideoUpdate(c *gin.Context, id uint, requester string, ifVersion int64, req *models.VideoUpdateRequest) {
+	video, err := h.videoService.UpdateVideoForUser(c.Request.Context(), id, requester, ifVersion, req)
 	if err != nil {
-		if err.Error() == "video not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		if errors.Is(err, services.ErrVideoNotFound) {
+			respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+			return
+		}
+		if errors.Is(err, services.ErrVersionConflict) {
+			h.versionConflict(c, id)
+			return
+		}
+		if errors.Is(err, services.ErrForbidden) {
+			respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidVisibility) {
+			respondError(c, http.StatusBadRequest, CodeInvalidVisibility, "Invalid visibility", gin.H{"allowed_visibilities": models.Visibilities})
+			return
+		}
+		var badCategory *services.InvalidCategoryError
+		if errors.As(err, &badCategory) {
+			respondError(c, http.StatusBadRequest, CodeInvalidCategory, badCategory.Error(), gin.H{"allowed_categories": badCategory.Allowed})
+			return
+		}
+		var invalid *services.ValidationError
+		if errors.As(err, &invalid) {
+			respondFieldErrors(c, invalid.Error(), invalid.Fields)
 			return
 		}
-		h.logger.Errorw("Failed to update video", "error", err, "videoID", id)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update video"})
+		h.log(c).Errorw("Failed to update video", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to update video", nil)
 		return
 	}
 
+	c.Header("ETag", videoETag(video))
 	c.JSON(http.StatusOK, video)
 }
 
-// DeleteVideo handles DELETE /api/v1/videos/:id - permanently removes video and all files
+// versionConflict answers 412 to an update whose If-Match no longer matches, with
+// the video as it is now so the client can merge and retry
+func (h *VideoHandler) versionConflict(c *gin.Context, id uint) {
+	latest, err := h.videoService.GetVideo(c.Request.Context(), id)
+	if err != nil {
+		h.videoLookupFailed(c, err, id)
+		return
+	}
+	h.attachReactions(c, latest)
+	c.Header("ETag", videoETag(latest))
+	respondError(c, http.StatusPreconditionFailed, CodeVersionConflict, "Video was modified since the given version", gin.H{"video": latest})
+}
+
+// DeleteVideo handles DELETE /api/v1/videos/:id - soft-deletes the video and returns
+// 202 with the job that purges its files once the restore window closes
 func (h *VideoHandler) DeleteVideo(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
 		return
 	}
 
-	if err := h.videoService.DeleteVideo(uint(id)); err != nil {
-		if err.Error() == "video not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+
+	job, err := h.videoService.DeleteVideoForUser(c.Request.Context(), uint(id), requester)
+	if err != nil {
+		if errors.Is(err, services.ErrVideoNotFound) {
+			respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
 			return
 		}
-		h.logger.Errorw("Failed to delete video", "error", err, "videoID", id)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete video"})
+		if errors.Is(err, services.ErrForbidden) {
+			respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to delete video", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to delete video", nil)
 		return
 	}
 
-	h.logger.Infow("Video permanently deleted", "videoID", id)
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Video and all associated files have been permanently deleted",
-		"video_id": id,
+	h.log(c).Infow("Video deleted", "videoID", id, "userID", requester, "deletionID", job.ID, "purgeAfter", job.RunAfter)
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":     "Video deleted; it can be restored until purge_after, when its files are purged",
+		"video_id":    id,
+		"job_id":      job.ID,
+		"status":      job.Status,
+		"purge_after": job.RunAfter,
 	})
 }
 
+// RestoreVideo handles POST /api/v1/videos/:id/restore - brings back the owner's
+// deleted video while its restore window is open
+func (h *VideoHandler) RestoreVideo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+
+	video, err := h.videoService.RestoreVideo(c.Request.Context(), uint(id), requester)
+	var dup *services.DuplicateUploadError
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, video)
+	case errors.Is(err, services.ErrVideoNotFound):
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+	case errors.Is(err, services.ErrForbidden):
+		respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+	case errors.Is(err, services.ErrVideoPurged):
+		respondError(c, http.StatusGone, CodeVideoPurged, "Video has been permanently deleted", nil)
+	case errors.Is(err, services.ErrVideoNotDeleted):
+		respondError(c, http.StatusConflict, CodeVideoNotDeleted, "Video is not deleted", nil)
+	case errors.As(err, &dup) && dup.UserID == requester:
+		respondError(c, http.StatusConflict, CodeDuplicateUploadID, "Another video now has this upload_id", gin.H{"video_id": dup.VideoID})
+	case errors.Is(err, services.ErrDuplicateUploadID):
+		respondError(c, http.StatusConflict, CodeDuplicateUploadID, "Another video now has this upload_id", nil)
+	default:
+		h.log(c).Errorw("Failed to restore video", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to restore video", nil)
+	}
+}
+
 // SearchVideos handles GET /api/v1/videos/search
 func (h *VideoHandler) SearchVideos(c *gin.Context) {
 	query := c.Query("q")
@@ -292,32 +1026,130 @@ func (h *VideoHandler) SearchVideos(c *gin.Context) {
 		perPage = 20
 	}
 
-	response, err := h.videoService.SearchVideos(query, page, perPage)
+	filters, ok := searchFilters(c)
+	if !ok {
+		return
+	}
+	compact, ok := compactView(c)
+	if !ok {
+		return
+	}
+
+	if compact {
+		response, err := h.videoService.SearchVideoCards(c.Request.Context(), query, page, perPage, requestedSort(c), filters)
+		if err != nil {
+			h.searchFailed(c, err, query)
+			return
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	response, err := h.videoService.SearchVideos(c.Request.Context(), query, page, perPage, requestedSort(c), filters)
 	if err != nil {
-		h.logger.Errorw("Failed to search videos", "error", err, "query", query)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search videos"})
+		h.searchFailed(c, err, query)
 		return
 	}
 
+	h.attachListReactions(c, response)
 	c.JSON(http.StatusOK, response)
 }
 
+// searchFailed answers a failed search: 400 for a bad filter, else 500
+func (h *VideoHandler) searchFailed(c *gin.Context, err error, query string) {
+	if h.invalidVideoFilter(c, err) {
+		return
+	}
+	h.log(c).Errorw("Failed to search videos", "error", err, "query", query)
+	respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to search videos", nil)
+}
+
+// searchFilters reads the search filters: category, tags (comma separated, all
+// required), min_duration/max_duration in seconds and uploaded_after/uploaded_before
+// in RFC3339. A bad value is answered with 400 naming the parameter and ok is false.
+func searchFilters(c *gin.Context) (filters services.VideoFilters, ok bool) {
+	fail := func(param, msg string) (services.VideoFilters, bool) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, param+" "+msg, gin.H{"parameter": param})
+		return filters, false
+	}
+	filters.Category = c.Query("category")
+	for _, tag := range strings.Split(c.Query("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			filters.Tags = append(filters.Tags, tag)
+		}
+	}
+	durations := []struct {
+		param string
+		dst   **float64
+	}{{"min_duration", &filters.MinDuration}, {"max_duration", &filters.MaxDuration}}
+	for _, d := range durations {
+		param, dst := d.param, d.dst
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(raw, 64)
+		if err != nil || seconds < 0 || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+			return fail(param, "must be a non-negative number of seconds")
+		}
+		*dst = &seconds
+	}
+	dates := []struct {
+		param string
+		dst   **time.Time
+	}{{"uploaded_after", &filters.UploadedAfter}, {"uploaded_before", &filters.UploadedBefore}}
+	for _, d := range dates {
+		param, dst := d.param, d.dst
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return fail(param, "must be an RFC3339 timestamp such as 2024-01-31T00:00:00Z")
+		}
+		*dst = &at
+	}
+	if filters.MinDuration != nil && filters.MaxDuration != nil && *filters.MinDuration > *filters.MaxDuration {
+		return fail("min_duration", "must not be greater than max_duration")
+	}
+	if filters.UploadedAfter != nil && filters.UploadedBefore != nil && filters.UploadedAfter.After(*filters.UploadedBefore) {
+		return fail("uploaded_after", "must not be later than uploaded_before")
+	}
+	return filters, true
+}
+
 // GetVideoByUploadID handles GET /api/v1/videos/upload/:uploadId
 func (h *VideoHandler) GetVideoByUploadID(c *gin.Context) {
 	uploadID := c.Param("uploadId")
 	if uploadID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "uploadId required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "uploadId required", nil)
 		return
 	}
-	video, err := h.videoService.GetVideoByUploadID(uploadID)
+	video, err := h.videoService.GetVideoByUploadID(c.Request.Context(), uploadID)
+	notFound := func() {
+		// The uploaded event may not have been consumed yet; tell pollers when to come back
+		retryAfter := int(math.Ceil(h.videoService.UploadMissTTL().Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+	}
 	if err != nil {
-		if err.Error() == "video not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		if errors.Is(err, services.ErrVideoNotFound) {
+			notFound()
 			return
 		}
-		h.logger.Errorw("Failed to get video by uploadId", "error", err, "uploadId", uploadID)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		h.log(c).Errorw("Failed to get video by uploadId", "error", err, "uploadId", uploadID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get video", nil)
+		return
+	}
+	// Same response as a miss, so other users can't probe private uploads
+	if !canView(c, video) {
+		notFound()
 		return
 	}
+	hideShareToken(c, video)
+	h.attachReactions(c, video)
 	c.JSON(http.StatusOK, video)
 }
@@ -0,0 +1,43 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/config"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+// videoSettings are the video service defaults Load would give
+var videoSettings = config.Videos{
+	UploadMissTTL:     2 * time.Second,
+	DeleteGrace:       7 * 24 * time.Hour,
+	PlaybackURLTTL:    time.Hour,
+	ChannelSummaryTTL: 30 * time.Second,
+}
+
+// commentSettings are the comment service defaults Load would give
+var commentSettings = config.Comments{MaxDepth: 2, EditWindow: 15 * time.Minute}
+
+// newRouter serves the API routes for deps
+func newRouter(deps api.Dependencies) *gin.Engine {
+	router := gin.New()
+	api.SetupRoutes(router, deps, zap.NewNop().Sugar())
+	return router
+}
+
+// serve sends one request through router and returns the response
+func serve(router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func itoa(id uint) string { return strconv.FormatUint(uint64(id), 10) }
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+const (
+	idempotencyKeyHeader   = "Idempotency-Key"
+	idempotentReplayHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLen   = 255
+)
+
+// idempotent makes a write safe to retry: a request carrying an Idempotency-Key
+// header that the caller already sent to the same route gets the first response
+// back, status and body, instead of running again. Reusing a key for a different
+// path or body is a 422, and a retry arriving while the first is still running is
+// a 409. 5xx responses aren't stored, so those can be retried. Requests without
+// the header, or without a caller to scope the key to, pass straight through.
+func idempotent(store *services.IdempotencyStore, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		user := engagementIdentity(c)
+		if store == nil || key == "" || user == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			abortWithError(c, http.StatusBadRequest, CodeInvalidRequest, "Idempotency-Key too long", gin.H{"max": maxIdempotencyKeyLen})
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if respondTooLarge(c, err) {
+			return
+		}
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, CodeInvalidRequest, "Failed to read request body", nil)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		route := c.FullPath()
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.URL.Path))
+		hash.Write([]byte{0})
+		hash.Write(body)
+		ctx := c.Request.Context()
+		record, claimed, err := store.Begin(ctx, user, c.Request.Method+" "+route, key, hex.EncodeToString(hash.Sum(nil)))
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			metrics.IdempotentRequestsTotal.WithLabelValues(route, "reused").Inc()
+			abortWithError(c, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "Idempotency-Key was already used for a different request", nil)
+			return
+		case errors.Is(err, services.ErrIdempotencyKeyInFlight):
+			metrics.IdempotentRequestsTotal.WithLabelValues(route, "in_flight").Inc()
+			abortWithError(c, http.StatusConflict, CodeIdempotencyKeyInFlight, "A request with this Idempotency-Key is still in progress", nil)
+			return
+		case err != nil:
+			// Running the write without the key could apply it twice; let the client retry
+			metrics.IdempotentRequestsTotal.WithLabelValues(route, "error").Inc()
+			logger.Errorw("Failed to check idempotency key", "error", err, "route", route, "user", user)
+			abortWithError(c, http.StatusServiceUnavailable, CodeUnavailable, "Failed to check Idempotency-Key", nil)
+			return
+		}
+		if !claimed {
+			metrics.IdempotentRequestsTotal.WithLabelValues(route, "replayed").Inc()
+			c.Header(idempotentReplayHeader, "true")
+			c.Data(record.Status, record.ContentType, record.Body)
+			c.Abort()
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		stored := false
+		// Runs on panics too, so a crashed handler doesn't hold the key
+		defer func() {
+			if stored {
+				return
+			}
+			metrics.IdempotentRequestsTotal.WithLabelValues(route, "released").Inc()
+			if err := store.Release(context.WithoutCancel(ctx), record); err != nil {
+				logger.Errorw("Failed to release idempotency key", "error", err, "route", route, "user", user)
+			}
+		}()
+		c.Next()
+
+		if w.Status() >= http.StatusInternalServerError {
+			return
+		}
+		if err := store.Complete(context.WithoutCancel(ctx), record, w.Status(), w.Header().Get("Content-Type"), w.body.Bytes()); err != nil {
+			logger.Errorw("Failed to store idempotent response", "error", err, "route", route, "user", user)
+			return
+		}
+		stored = true
+		metrics.IdempotentRequestsTotal.WithLabelValues(route, "stored").Inc()
+	}
+}
+
+// recordingWriter keeps a copy of the response body as it is written
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
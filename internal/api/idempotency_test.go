@@ -0,0 +1,193 @@
+package api_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// idempotentRequest is a request from user carrying an Idempotency-Key
+func idempotentRequest(method, target, body, user, key string) *http.Request {
+	req := adminRequest(method, target, body, user, "")
+	req.Header.Set("Idempotency-Key", key)
+	return req
+}
+
+func idempotencyRouter(t *testing.T) (*gorm.DB, http.Handler) {
+	t.Helper()
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	return db, newRouter(api.Dependencies{
+		Videos:      services.NewVideoService(db, nil, videoSettings, log),
+		Comments:    services.NewCommentService(db, commentSettings, log),
+		Idempotency: services.NewIdempotencyStore(db, log, 24*time.Hour),
+	})
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	db, router := idempotencyRouter(t)
+	create := `{"upload_id":"up-1","title":"t"}`
+
+	first := serve(router, idempotentRequest(http.MethodPost, "/api/v1/videos", create, "alice", "k1"))
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first: status %d, replayed %q: %s", first.Code, first.Header().Get("Idempotent-Replayed"), first.Body)
+	}
+	retry := serve(router, idempotentRequest(http.MethodPost, "/api/v1/videos", create, "alice", "k1"))
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() || retry.Header().Get("Idempotent-Replayed") != "true" ||
+		retry.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Errorf("retry: status %d, replayed %q: %s; want the first response replayed", retry.Code, retry.Header().Get("Idempotent-Replayed"), retry.Body)
+	}
+	var count int64
+	db.Model(&models.Video{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d videos after a retry, want 1", count)
+	}
+
+	// Stored 4xx responses replay too
+	conflict := serve(router, idempotentRequest(http.MethodPost, "/api/v1/videos", create, "alice", "k2"))
+	if conflict.Code != http.StatusConflict {
+		t.Fatalf("duplicate upload: status %d", conflict.Code)
+	}
+	db.Where("upload_id = ?", "up-1").Delete(&models.Video{})
+	if again := serve(router, idempotentRequest(http.MethodPost, "/api/v1/videos", create, "alice", "k2")); again.Code != http.StatusConflict || again.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry of a 409: status %d, want the 409 replayed", again.Code)
+	}
+
+	// Another caller's key is their own; requests without a key always run
+	if w := serve(router, idempotentRequest(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-2","title":"t"}`, "bob", "k1")); w.Code != http.StatusCreated {
+		t.Errorf("bob's k1: status %d, want his request run", w.Code)
+	}
+	for i := 0; i < 2; i++ {
+		if w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-3","title":"t"}`, "alice", "")); w.Header().Get("Idempotent-Replayed") != "" {
+			t.Error("request without a key was replayed")
+		}
+	}
+}
+
+func TestIdempotencyKeyReused(t *testing.T) {
+	db, router := idempotencyRouter(t)
+	var videos [2]models.Video
+	for i := range videos {
+		videos[i] = models.Video{UploadID: "up-" + itoa(uint(i)), UserID: "owner", Title: "t", Status: models.StatusReady, CommentsEnabled: true}
+		db.Create(&videos[i])
+	}
+	comments := func(v models.Video) string { return "/api/v1/videos/" + itoa(v.ID) + "/comments" }
+	if w := serve(router, idempotentRequest(http.MethodPost, comments(videos[0]), `{"content":"hi"}`, "alice", "k1")); w.Code != http.StatusCreated {
+		t.Fatalf("first: status %d: %s", w.Code, w.Body)
+	}
+
+	tests := []struct{ name, path, body string }{
+		{"different body", comments(videos[0]), `{"content":"bye"}`},
+		{"different path", comments(videos[1]), `{"content":"hi"}`},
+	}
+	for _, tt := range tests {
+		w := serve(router, idempotentRequest(http.MethodPost, tt.path, tt.body, "alice", "k1"))
+		if w.Code != http.StatusUnprocessableEntity || errorCode(w) != api.CodeIdempotencyKeyReused {
+			t.Errorf("%s: %d %s, want 422 %s", tt.name, w.Code, w.Body, api.CodeIdempotencyKeyReused)
+		}
+	}
+	var count int64
+	db.Model(&models.Comment{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d comments, want only the first", count)
+	}
+
+	w := serve(router, idempotentRequest(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-9","title":"t"}`, "alice", strings.Repeat("k", 256)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("256-character key: status %d, want 400", w.Code)
+	}
+}
+
+// TestIdempotencyKeyInFlight sends a retry while the first request is still held
+// in its handler
+func TestIdempotencyKeyInFlight(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	store := services.NewIdempotencyStore(db, log, 24*time.Hour)
+	entered, release := make(chan struct{}), make(chan struct{})
+	router := gin.New()
+	router.POST("/slow", api.ActAs("alice"), api.Idempotent(store, log), func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- serve(router, idempotentRequest(http.MethodPost, "/slow", "{}", "", "k1")) }()
+	<-entered
+	w := serve(router, idempotentRequest(http.MethodPost, "/slow", "{}", "", "k1"))
+	if w.Code != http.StatusConflict || errorCode(w) != api.CodeIdempotencyKeyInFlight {
+		t.Errorf("retry in flight: %d %s, want 409 %s", w.Code, w.Body, api.CodeIdempotencyKeyInFlight)
+	}
+	close(release)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Fatalf("first: status %d", first.Code)
+	}
+	if w := serve(router, idempotentRequest(http.MethodPost, "/slow", "{}", "", "k1")); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry after: status %d, want the response replayed", w.Code)
+	}
+}
+
+// TestIdempotencyReleasesFailures checks 5xx responses and panics release the key,
+// so the retry runs again
+func TestIdempotencyReleasesFailures(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	store := services.NewIdempotencyStore(db, log, 24*time.Hour)
+	var calls atomic.Int32
+	router := gin.New()
+	router.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, _ any) { c.AbortWithStatus(http.StatusInternalServerError) }))
+	router.POST("/fail", api.ActAs("alice"), api.Idempotent(store, log), func(c *gin.Context) {
+		calls.Add(1)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "try later"})
+	})
+	router.POST("/panic", api.ActAs("alice"), api.Idempotent(store, log), func(c *gin.Context) {
+		calls.Add(1)
+		panic("handler bug")
+	})
+
+	for _, path := range []string{"/fail", "/panic"} {
+		calls.Store(0)
+		for i := 0; i < 2; i++ {
+			w := serve(router, idempotentRequest(http.MethodPost, path, "{}", "", "k-"+path))
+			if w.Code < http.StatusInternalServerError || w.Header().Get("Idempotent-Replayed") != "" {
+				t.Errorf("%s attempt %d: status %d, replayed %q; want the handler run again", path, i+1, w.Code, w.Header().Get("Idempotent-Replayed"))
+			}
+		}
+		if calls.Load() != 2 {
+			t.Errorf("%s: handler ran %d times, want 2", path, calls.Load())
+		}
+	}
+	var held int64
+	db.Model(&models.IdempotencyKey{}).Count(&held)
+	if held != 0 {
+		t.Errorf("%d keys held after failures, want none", held)
+	}
+}
+
+// TestIdempotencyExpiredKey checks a key past its TTL runs the request again
+func TestIdempotencyExpiredKey(t *testing.T) {
+	db, router := idempotencyRouter(t)
+	create := `{"upload_id":"up-1","title":"t"}`
+	if w := serve(router, idempotentRequest(http.MethodPost, "/api/v1/videos", create, "alice", "k1")); w.Code != http.StatusCreated {
+		t.Fatalf("first: status %d", w.Code)
+	}
+	db.Model(&models.IdempotencyKey{}).Where("key = ?", "k1").Update("created_at", time.Now().UTC().Add(-25*time.Hour))
+	w := serve(router, idempotentRequest(http.MethodPost, "/api/v1/videos", create, "alice", "k1"))
+	if w.Code != http.StatusConflict || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("after expiry: status %d, replayed %q; want the create run again and refused as a duplicate", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/flags"
+	"github.com/streamhive/video-catalog-api/internal/logging"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+const identityKey = "identity"
+
+// Identity is who a request acts as. During impersonation UserID is the target user
+// and ActorID is the admin actually making the request; otherwise they are equal.
+type Identity struct {
+	UserID        string
+	Roles         string
+	ActorID       string
+	Impersonating bool
+	// AnonymousID is the verified anonymous session of a caller without a UserID
+	AnonymousID string
+	// Username is the caller's display name, if their credentials carry one
+	Username string
+}
+
+// ImpersonationConfig controls the X-Impersonate-User mechanism
+type ImpersonationConfig struct {
+	Enabled bool
+	Audit   *services.AuditService
+}
+
+// resolveIdentity derives the effective identity from the caller's credentials,
+// rejecting any that don't verify. An admin may send X-Impersonate-User to act as
+// that user for read-only requests; each such request, allowed or not, is audited
+// first and refused if the audit write fails.
+func resolveIdentity(auth *Authenticator, cfg ImpersonationConfig, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := auth.identify(c)
+		if err != nil {
+			rejectCredentials(c, err)
+			return
+		}
+		target := c.GetHeader("X-Impersonate-User")
+		if target == "" {
+			c.Set(identityKey, id)
+			c.Next()
+			return
+		}
+
+		if !cfg.Enabled {
+			abortWithError(c, http.StatusForbidden, CodeImpersonationNotAllowed, "Impersonation is disabled", nil)
+			return
+		}
+		if id.ActorID == "" {
+			abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+			return
+		}
+		if !hasRole(id.Roles, "admin") {
+			abortWithError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+			return
+		}
+		outcome := "served"
+		if !impersonationAllowed(c) {
+			outcome = "rejected"
+		}
+		entry := &models.AuditLog{
+			Action:    models.AuditActionImpersonate,
+			ActorID:   id.ActorID,
+			SubjectID: target,
+			Method:    c.Request.Method,
+			Route:     c.FullPath(),
+			Path:      c.Request.URL.Path,
+			Outcome:   outcome,
+		}
+		if err := cfg.Audit.Record(c.Request.Context(), entry); err != nil {
+			logging.For(c.Request.Context(), logger).Errorw("Failed to audit impersonated request", "error", err, "admin", id.ActorID, "target", target)
+			metrics.ImpersonatedRequestsTotal.WithLabelValues(id.ActorID, "audit_failed").Inc()
+			abortWithError(c, http.StatusServiceUnavailable, CodeAuditUnavailable, "Audit unavailable", nil)
+			return
+		}
+		metrics.ImpersonatedRequestsTotal.WithLabelValues(id.ActorID, outcome).Inc()
+		if outcome == "rejected" {
+			abortWithError(c, http.StatusForbidden, CodeImpersonationNotAllowed, "Impersonation is read-only", nil)
+			return
+		}
+
+		// The target's roles are unknown here, so the effective identity gets none
+		c.Set(identityKey, Identity{UserID: target, ActorID: id.ActorID, Impersonating: true})
+		c.Next()
+	}
+}
+
+// identityFrom returns the request's resolved identity; anonymous outside routes
+// that run resolveIdentity
+func identityFrom(c *gin.Context) Identity {
+	if v, ok := c.Get(identityKey); ok {
+		return v.(Identity)
+	}
+	return Identity{}
+}
+
+// currentUser is the user the request acts as, or "" if anonymous
+func currentUser(c *gin.Context) string { return identityFrom(c).UserID }
+
+// engagementIdentity is the identity stored by engagement features (views,
+// progress, history): the user ID, or "anon:<session>" for an anonymous session.
+// Only endpoints that explicitly accept anonymous sessions may use it.
+func engagementIdentity(c *gin.Context) string {
+	id := identityFrom(c)
+	if id.UserID != "" {
+		return id.UserID
+	}
+	if id.AnonymousID != "" {
+		return models.AnonymousIdentity(id.AnonymousID)
+	}
+	return ""
+}
+
+// resolveAnonymous accepts an X-Anonymous-Session token from callers without a
+// user ID. A bad or expired token is rejected so the client knows to get a new one.
+func resolveAnonymous(sessions *services.AnonymousSessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader("X-Anonymous-Session")
+		id := identityFrom(c)
+		if token == "" || id.UserID != "" || sessions == nil {
+			c.Next()
+			return
+		}
+		sessionID, err := sessions.Verify(token)
+		if err != nil {
+			abortWithError(c, http.StatusUnauthorized, CodeInvalidAnonymousSession, "Invalid anonymous session", gin.H{"reason": err.Error()})
+			return
+		}
+		id.AnonymousID = sessionID
+		c.Set(identityKey, id)
+		c.Next()
+	}
+}
+
+// flagIdentity carries the caller's identity into the request context, where
+// feature flag percentage rollouts read it. It runs after resolveAnonymous so
+// logged-out sessions are bucketed too.
+func flagIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if id := engagementIdentity(c); id != "" {
+			c.Request = c.Request.WithContext(flags.WithUser(c.Request.Context(), id))
+		}
+		c.Next()
+	}
+}
+
+// restrictedReads are GET routes off limits while impersonating: ones that change
+// state, and ones that hand over a user's whole data set, which an admin exports
+// through the same routes without impersonation so the export is audited as one
+var restrictedReads = map[string]bool{
+	"/api/v1/users/:userID/data-export":                     true,
+	"/api/v1/users/:userID/export":                          true,
+	"/api/v1/users/:userID/data-exports/:exportID/download": true,
+}
+
+// impersonationAllowed reports whether an impersonated request may proceed: only
+// reads, never admin routes, and never restricted reads
+func impersonationAllowed(c *gin.Context) bool {
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+	route := c.FullPath()
+	return !restrictedReads[route] && !strings.HasPrefix(route, "/api/v1/admin")
+}
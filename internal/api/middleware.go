@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireRole only lets through callers whose credentials carry role: 401 without
+// a user, 403 without the role. An impersonating admin carries no roles, so these
+// routes are closed to them.
+func requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := identityFrom(c)
+		if id.UserID == "" {
+			abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+			return
+		}
+		if id.Impersonating || !hasRole(id.Roles, role) {
+			abortWithError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasRole(header, role string) bool {
+	for _, r := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(r), role) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitRequestBody caps request bodies at max bytes, answering 413 once a handler
+// reads past it; a declared Content-Length over the cap is refused up front.
+// routes overrides the cap for the route paths it lists, such as uploads. A cap
+// <= 0 leaves bodies unlimited.
+func limitRequestBody(defaultMax int64, routes map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		max := defaultMax
+		if routeMax, ok := routes[c.FullPath()]; ok {
+			max = routeMax
+		}
+		if max <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > max {
+			abortWithError(c, http.StatusRequestEntityTooLarge, CodeRequestTooLarge, "Request body too large", gin.H{"max_bytes": max})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		c.Next()
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// MuteVideoNotifications handles POST /api/v1/videos/:id/notifications/mute (owner only)
+func (h *VideoHandler) MuteVideoNotifications(c *gin.Context) {
+	h.setNotificationsMuted(c, true)
+}
+
+// UnmuteVideoNotifications handles POST /api/v1/videos/:id/notifications/unmute (owner only)
+func (h *VideoHandler) UnmuteVideoNotifications(c *gin.Context) {
+	h.setNotificationsMuted(c, false)
+}
+
+func (h *VideoHandler) setNotificationsMuted(c *gin.Context, muted bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrVideoNotFound) {
+			respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to get video", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get video", nil)
+		return
+	}
+	if video.UserID != requester {
+		respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+		return
+	}
+
+	if err := h.notificationSvc.SetMuted(c.Request.Context(), uint(id), muted); err != nil {
+		h.log(c).Errorw("Failed to update notification mute", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to update notification settings", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"video_id": id, "notifications_muted": muted})
+}
+
+// ListNotifications handles GET /api/v1/notifications for the calling user
+func (h *VideoHandler) ListNotifications(c *gin.Context) {
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, total, err := h.notificationSvc.List(c.Request.Context(), requester, unreadOnly, page, perPage)
+	if err != nil {
+		h.log(c).Errorw("Failed to list notifications", "error", err, "userID", requester)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list notifications", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"total":         total,
+		"page":          page,
+		"per_page":      perPage,
+		"total_pages":   (int(total) + perPage - 1) / perPage,
+	})
+}
+
+// MarkNotificationRead handles POST /api/v1/notifications/:notificationID/read
+func (h *VideoHandler) MarkNotificationRead(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("notificationID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid notification ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+
+	if err := h.notificationSvc.MarkRead(c.Request.Context(), requester, uint(id)); err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			respondError(c, http.StatusNotFound, CodeNotificationNotFound, "Notification not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to mark notification read", "error", err, "notificationID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to mark notification read", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"read": true})
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/cursor"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// invalidCursor rejects a tampered, mismatched or expired pagination cursor
+func invalidCursor(c *gin.Context, err error) {
+	respondError(c, http.StatusBadRequest, CodeInvalidCursor, "Invalid cursor", gin.H{"reason": err.Error()})
+}
+
+// pagedPosition is a keyset position plus the page size the following pages use,
+// so a short ?first= page can hand off to regular-sized pages. It is signed with
+// the rest of the cursor; zero (cursors issued before it existed) means unset.
+type pagedPosition struct {
+	cursor.TimeID
+	PerPage int `json:"n,omitempty"`
+}
+
+// perPageFor resolves the regular page size: an explicit per_page wins, then the
+// size carried by the cursor, then the default. Out-of-range values fall back.
+func perPageFor(c *gin.Context, carried int) int {
+	perPage := carried
+	if raw, ok := c.GetQuery("per_page"); ok {
+		perPage, _ = strconv.Atoi(raw)
+	}
+	if perPage < 1 || perPage > maxPerPage {
+		perPage = defaultPerPage
+	}
+	return perPage
+}
+
+// firstPageSize reads ?first=, the size of the initial page only; 0 means not given
+func firstPageSize(c *gin.Context) (int, error) {
+	raw, ok := c.GetQuery("first")
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > maxPerPage {
+		return 0, fmt.Errorf("first must be between 1 and %d", maxPerPage)
+	}
+	return n, nil
+}
@@ -0,0 +1,80 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/cursor"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestCommentCursorPaging(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:   services.NewVideoService(db, nil, videoSettings, log),
+		Comments: services.NewCommentService(db, commentSettings, log),
+		Cursors:  cursor.NewCodec([]byte("secret"), time.Hour),
+	})
+	var videos [2]models.Video
+	for i := range videos {
+		videos[i] = models.Video{UploadID: "up" + itoa(uint(i)), UserID: "owner", Title: "t", Visibility: models.VisibilityPublic, Status: models.StatusReady, CommentsEnabled: true}
+		db.Create(&videos[i])
+	}
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		db.Create(&models.Comment{VideoID: videos[0].ID, UserID: "u", Content: "c", Status: models.CommentVisible, CreatedAt: base.Add(time.Duration(i) * time.Minute)})
+	}
+
+	type page struct {
+		Comments   []models.Comment `json:"comments"`
+		NextCursor string           `json:"next_cursor"`
+		Code       string           `json:"code"`
+	}
+	get := func(videoID uint, query string) (int, page) {
+		w := serve(router, httptest.NewRequest(http.MethodGet, "/api/v1/videos/"+itoa(videoID)+"/comments?"+query, nil))
+		var p page
+		json.Unmarshal(w.Body.Bytes(), &p)
+		return w.Code, p
+	}
+
+	status, first := get(videos[0].ID, "per_page=2")
+	if status != http.StatusOK || len(first.Comments) != 2 || first.NextCursor == "" {
+		t.Fatalf("first page: %d %+v", status, first)
+	}
+	var seen []uint
+	for _, c := range first.Comments {
+		seen = append(seen, c.ID)
+	}
+	for next := first.NextCursor; next != ""; {
+		status, p := get(videos[0].ID, "cursor="+url.QueryEscape(next))
+		if status != http.StatusOK {
+			t.Fatalf("cursor page: status %d", status)
+		}
+		for _, c := range p.Comments {
+			seen = append(seen, c.ID)
+		}
+		next = p.NextCursor
+	}
+	if len(seen) != 5 || seen[0] != 5 || seen[4] != 1 {
+		t.Errorf("paged through %v, want comments 5..1 once each", seen)
+	}
+
+	// A cursor issued for one video can't be replayed against another
+	status, p := get(videos[1].ID, "cursor="+url.QueryEscape(first.NextCursor))
+	if status != http.StatusBadRequest || p.Code != api.CodeInvalidCursor {
+		t.Errorf("cursor from another video: %d %q", status, p.Code)
+	}
+	if status, _ := get(videos[0].ID, "cursor=forged.token"); status != http.StatusBadRequest {
+		t.Errorf("forged cursor: status %d, want 400", status)
+	}
+}
@@ -0,0 +1,130 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func limitedRouter(t *testing.T, maxBody int64) (*models.Video, http.Handler) {
+	t.Helper()
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	video := models.Video{UploadID: "up-0", UserID: "owner", Title: "t", Visibility: models.VisibilityPublic, CommentsEnabled: true}
+	db.Create(&video)
+	return &video, newRouter(api.Dependencies{
+		Videos:       services.NewVideoService(db, nil, videoSettings, log),
+		Comments:     services.NewCommentService(db, commentSettings, log),
+		MaxBodyBytes: maxBody,
+	})
+}
+
+// createBody is a valid create request exactly size bytes long
+func createBody(uploadID string, size int) string {
+	prefix := `{"upload_id":"` + uploadID + `","title":"t","description":"`
+	return prefix + strings.Repeat("x", size-len(prefix)-2) + `"}`
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	const max = 512
+	_, router := limitedRouter(t, max)
+
+	if w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos", createBody("up-1", max), "owner", "")); w.Code != http.StatusCreated {
+		t.Errorf("body at the limit: %d %s, want 201", w.Code, w.Body)
+	}
+	w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos", createBody("up-2", max+1), "owner", ""))
+	if body := decodeError(t, w.Body.Bytes()); w.Code != http.StatusRequestEntityTooLarge || body.Code != api.CodeRequestTooLarge ||
+		!sameJSON(t, body.Details, `{"max_bytes":512}`) {
+		t.Errorf("one byte over: %d %s, want 413 request_too_large", w.Code, w.Body)
+	}
+
+	// Without a Content-Length the cap applies while the handler reads
+	req := adminRequest(http.MethodPost, "/api/v1/videos", createBody("up-3", 4*max), "owner", "")
+	req.ContentLength = -1
+	if w := serve(router, req); w.Code != http.StatusRequestEntityTooLarge || errorCode(w) != api.CodeRequestTooLarge {
+		t.Errorf("chunked body over the limit: %d %s, want 413", w.Code, w.Body)
+	}
+
+	// 0 leaves bodies unlimited
+	_, unlimited := limitedRouter(t, 0)
+	if w := serve(unlimited, adminRequest(http.MethodPost, "/api/v1/videos", createBody("up-4", 8<<10), "owner", "")); w.Code != http.StatusCreated {
+		t.Errorf("unlimited: %d %s, want 201", w.Code, w.Body)
+	}
+}
+
+func TestVideoMetadataLimits(t *testing.T) {
+	video, router := limitedRouter(t, 1<<20)
+	x := func(n int) string { return strings.Repeat("x", n) }
+	tags := func(n, length int) string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = `"` + strings.Repeat(string(rune('a'+i%26)), length) + `"`
+		}
+		return "[" + strings.Join(out, ",") + "]"
+	}
+
+	creates := []struct {
+		name, fields, field, rule string
+	}{
+		{"all at the limit", `"title":"` + x(200) + `","description":"` + x(10000) + `","category":"` + x(50) + `","tags":` + tags(25, 50), "", ""},
+		{"title one over", `"title":"` + x(201) + `"`, "title", "max"},
+		{"description one over", `"title":"t","description":"` + x(10001) + `"`, "description", "max"},
+		{"category one over", `"title":"t","category":"` + x(51) + `"`, "category", "max"},
+		{"one tag too many", `"title":"t","tags":` + tags(26, 3), "tags", "max"},
+		{"tag one over", `"title":"t","tags":["ok","` + x(51) + `"]`, "tags[1]", "max"},
+	}
+	for i, tt := range creates {
+		body := `{"upload_id":"up-` + itoa(uint(i+1)) + `",` + tt.fields + `}`
+		w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos", body, "owner", ""))
+		if tt.field == "" {
+			if w.Code != http.StatusCreated {
+				t.Errorf("create, %s: %d %s, want 201", tt.name, w.Code, w.Body)
+			}
+			continue
+		}
+		resp := decodeError(t, w.Body.Bytes())
+		if w.Code != http.StatusBadRequest || resp.Code != api.CodeValidationFailed || !strings.Contains(string(resp.Details), `"field":"`+tt.field+`","rule":"`+tt.rule+`"`) {
+			t.Errorf("create, %s: %d %s, want 400 on %s", tt.name, w.Code, w.Body, tt.field)
+		}
+	}
+
+	// Updates bind into pointers, and PATCH skips binding, so both check the limits too
+	path := "/api/v1/videos/" + itoa(video.ID)
+	for _, method := range []string{http.MethodPut, http.MethodPatch} {
+		req := adminRequest(method, path, `{"description":"`+x(10001)+`"}`, "owner", "")
+		if method == http.MethodPatch {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+		}
+		w := serve(router, req)
+		resp := decodeError(t, w.Body.Bytes())
+		if w.Code != http.StatusBadRequest || resp.Code != api.CodeValidationFailed || !strings.Contains(string(resp.Details), `"field":"description"`) {
+			t.Errorf("%s with a long description: %d %s, want 400 on description", method, w.Code, w.Body)
+		}
+	}
+	if w := serve(router, adminRequest(http.MethodPut, path, `{"title":"`+x(200)+`"}`, "owner", "")); w.Code != http.StatusOK {
+		t.Errorf("PUT a title at the limit: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestCommentContentLimit(t *testing.T) {
+	video, router := limitedRouter(t, 1<<20)
+	path := "/api/v1/videos/" + itoa(video.ID) + "/comments"
+
+	// Counted in characters, so 2000 two-byte runes are within it
+	atLimit := strings.Repeat("ü", models.MaxCommentLen)
+	if w := serve(router, adminRequest(http.MethodPost, path, `{"content":"`+atLimit+`"}`, "alice", "")); w.Code != http.StatusCreated {
+		t.Errorf("comment at the limit: %d %s, want 201", w.Code, w.Body)
+	}
+	w := serve(router, adminRequest(http.MethodPost, path, `{"content":"`+atLimit+`ü"}`, "alice", ""))
+	resp := decodeError(t, w.Body.Bytes())
+	if w.Code != http.StatusBadRequest || resp.Code != api.CodeValidationFailed || !strings.Contains(string(resp.Details), `"field":"content"`) {
+		t.Errorf("comment one over: %d %s, want 400 on content", w.Code, w.Body)
+	}
+}
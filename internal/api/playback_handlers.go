@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// GetPlayback handles GET /api/v1/videos/:id/playback - the master playlist URL to
+// play. A public video's URL is returned as stored; an unlisted or private one's is
+// a signed, time-limited URL, only for its owner or with its share token.
+func (h *VideoHandler) GetPlayback(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+	requester := currentUser(c)
+	if takenDown(c, video) {
+		respondTakenDown(c)
+		return
+	}
+	if !canView(c, video) {
+		respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+		return
+	}
+
+	playback, err := h.videoService.PlaybackURL(c.Request.Context(), video)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNoPlayback):
+			respondError(c, http.StatusConflict, CodeNotReady, "Video is not ready for playback", gin.H{"status": video.Status})
+		case errors.Is(err, services.ErrPlaybackUnavailable):
+			respondError(c, http.StatusServiceUnavailable, CodePlaybackUnavailable, "Playback of private videos is unavailable", nil)
+		default:
+			respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get playback URL", nil)
+		}
+		return
+	}
+
+	if video.IsPrivate() {
+		if requester != video.UserID || identityFrom(c).Impersonating {
+			h.recordAccess(c, video, requester, "playback")
+		}
+		// A signed URL is a credential; keep it out of shared caches
+		c.Header("Cache-Control", "no-store")
+	}
+	c.JSON(http.StatusOK, playback)
+}
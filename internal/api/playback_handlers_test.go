@@ -0,0 +1,105 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// signingStorage is a storage client that only signs URLs, the way Azure's SAS
+// URLs carry their expiry and signature in the query
+type signingStorage struct{ services.StorageClient }
+
+func (signingStorage) GenerateSASURL(_ context.Context, blobPath string, ttl time.Duration) (string, error) {
+	q := url.Values{"sp": {"r"}, "se": {time.Now().UTC().Add(ttl).Format(time.RFC3339)}, "sig": {"c2lnbmVk"}}
+	return "https://acct.blob.core.windows.net/videos/" + blobPath + "?" + q.Encode(), nil
+}
+
+func TestGetPlayback(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	loader := services.NewStorageLoader(func() (services.StorageClient, error) { return signingStorage{}, nil }, 0, log)
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, loader, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+	})
+	const master = "https://acct.blob.core.windows.net/videos/hls/owner/up-1/master.m3u8"
+	private := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Visibility: models.VisibilityPrivate,
+		HLSMasterURL: master, ShareToken: "share-1"}
+	db.Create(&private)
+	public := models.Video{UploadID: "up-2", UserID: "owner", Title: "t", Visibility: models.VisibilityPublic,
+		HLSMasterURL: "https://acct.blob.core.windows.net/videos/hls/owner/up-2/master.m3u8"}
+	db.Create(&public)
+	pending := models.Video{UploadID: "up-3", UserID: "owner", Title: "t", Visibility: models.VisibilityPrivate}
+	db.Create(&pending)
+
+	get := func(id uint, query, user string) (*http.Response, services.Playback) {
+		t.Helper()
+		w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/"+itoa(id)+"/playback"+query, "", user, ""))
+		var playback services.Playback
+		json.Unmarshal(w.Body.Bytes(), &playback)
+		return w.Result(), playback
+	}
+
+	for _, tt := range []struct {
+		name, query, user string
+	}{
+		{"owner", "", "owner"},
+		{"share token", "?token=share-1", "viewer"},
+	} {
+		resp, playback := get(private.ID, tt.query, tt.user)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status %d", tt.name, resp.StatusCode)
+			continue
+		}
+		signed, _ := url.Parse(playback.URL)
+		q := signed.Query()
+		if !playback.Signed || playback.ExpiresAt == nil || q.Get("sig") == "" || q.Get("se") == "" ||
+			signed.Path != "/videos/hls/owner/up-1/master.m3u8" {
+			t.Errorf("%s: playback = %+v, want a signed master playlist URL with its expiry", tt.name, playback)
+		}
+		if cc := resp.Header.Get("Cache-Control"); cc != "no-store" {
+			t.Errorf("%s: Cache-Control = %q, want no-store", tt.name, cc)
+		}
+	}
+
+	for _, tt := range []struct {
+		name, query, user string
+	}{
+		{"stranger", "", "viewer"},
+		{"wrong share token", "?token=share-2", "viewer"},
+		{"anonymous", "", ""},
+	} {
+		if resp, _ := get(private.ID, tt.query, tt.user); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s: status %d, want 403", tt.name, resp.StatusCode)
+		}
+	}
+
+	resp, playback := get(public.ID, "", "")
+	if resp.StatusCode != http.StatusOK || playback.Signed || playback.URL != public.HLSMasterURL {
+		t.Errorf("public video: status %d, %+v; want its stored URL", resp.StatusCode, playback)
+	}
+	if resp, _ := get(pending.ID, "", "owner"); resp.StatusCode != http.StatusConflict {
+		t.Errorf("video without HLS: status %d, want 409", resp.StatusCode)
+	}
+	if resp, _ := get(999, "", "owner"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown video: status %d, want 404", resp.StatusCode)
+	}
+
+	// Without storage that can sign, a private video can't be played
+	router = newRouter(api.Dependencies{Videos: services.NewVideoService(db, nil, videoSettings, log), Reactions: services.NewReactionService(db, log)})
+	w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/"+itoa(private.ID)+"/playback", "", "owner", ""))
+	if w.Code != http.StatusServiceUnavailable || errorCode(w) != api.CodePlaybackUnavailable {
+		t.Errorf("without a signer: status %d: %s, want 503 %s", w.Code, w.Body, api.CodePlaybackUnavailable)
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// videoETag is a video's entity tag: its version, quoted
+func videoETag(video *models.Video) string {
+	return `"` + strconv.FormatInt(video.Version, 10) + `"`
+}
+
+// ifMatchVersion reads the version an update is conditional on from If-Match,
+// accepting the ETag or a bare version number. No header, or "*", is 0: the
+// update is unconditional. Anything else answers 400 and returns false.
+func ifMatchVersion(c *gin.Context) (int64, bool) {
+	raw := strings.TrimSpace(c.GetHeader("If-Match"))
+	if raw == "" || raw == "*" {
+		return 0, true
+	}
+	version, err := strconv.ParseInt(strings.Trim(raw, `"`), 10, 64)
+	if err != nil || version < 1 {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid If-Match; expected a single video ETag", nil)
+		return 0, false
+	}
+	return version, true
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+)
+
+// RateLimiter decides whether a caller may make another request. The limiters here
+// are in-memory and so per replica; one backed by a shared store such as Redis can
+// implement it to limit across replicas.
+type RateLimiter interface {
+	// Allow records a request for key and reports whether it is within the limit,
+	// and if not, how long until the next one would be
+	Allow(ctx context.Context, key string) (ok bool, retryAfter time.Duration, err error)
+}
+
+// RateLimits are the API-wide limits applied to every /api/v1 route on top of any
+// route's own limit; a nil limiter doesn't limit
+type RateLimits struct {
+	Reads  RateLimiter
+	Writes RateLimiter
+}
+
+// windowLimiter is a small fixed-window limiter keyed by caller
+type windowLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	buckets map[string]*windowBucket
+	now     func() time.Time
+}
+
+type windowBucket struct {
+	start time.Time
+	count int
+}
+
+func newWindowLimiter(limit int, window time.Duration) *windowLimiter {
+	return &windowLimiter{limit: limit, window: window, buckets: make(map[string]*windowBucket), now: time.Now}
+}
+
+// Allow records a hit for key and reports whether it is within the limit, plus the
+// time until the current window resets.
+func (l *windowLimiter) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok || now.Sub(b.start) >= l.window {
+		// Opportunistically drop expired buckets so the map doesn't grow forever
+		for k, old := range l.buckets {
+			if now.Sub(old.start) >= l.window {
+				delete(l.buckets, k)
+			}
+		}
+		b = &windowBucket{start: now}
+		l.buckets[key] = b
+	}
+	b.count++
+	return b.count <= l.limit, b.start.Add(l.window).Sub(now), nil
+}
+
+// TokenBucketLimiter allows a steady rate per caller with bursts up to one minute's
+// worth. It keeps one bucket per key in memory.
+type TokenBucketLimiter struct {
+	mu      sync.Mutex
+	perSec  float64
+	burst   float64
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+	// lastSweep is when idle buckets were last evicted
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucketLimiter creates a limiter allowing perMinute requests a minute per key
+func NewTokenBucketLimiter(perMinute int) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		perSec:    float64(perMinute) / 60,
+		burst:     float64(perMinute),
+		buckets:   make(map[string]*tokenBucket),
+		now:       time.Now,
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from key's bucket if one is left
+func (l *TokenBucketLimiter) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.perSec)
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.perSec * float64(time.Second)), nil
+	}
+	b.tokens--
+	return true, 0, nil
+}
+
+// sweep evicts, at most once a minute, buckets idle long enough to have refilled.
+// A full bucket is what a new caller gets anyway, so dropping it changes nothing.
+func (l *TokenBucketLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	refill := time.Duration(l.burst / l.perSec * float64(time.Second))
+	for k, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// Rate limit classes, as labelled in catalog_rate_limited_total
+const (
+	rateLimitRead  = "read"
+	rateLimitWrite = "write"
+	rateLimitRoute = "route"
+)
+
+// rateLimitByUser throttles requests per authenticated caller (falling back to
+// client IP). Impersonated requests count against the admin.
+func rateLimitByUser(l RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if throttle(c, l, rateLimitRoute) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitRequests applies the API-wide read or write limit by request method
+func rateLimitRequests(limits RateLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l, class := limits.Writes, rateLimitWrite
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			l, class = limits.Reads, rateLimitRead
+		}
+		if l != nil && throttle(c, l, class) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// throttle answers 429 with Retry-After when the caller is over l's limit and
+// reports whether it did. A limiter that fails lets the request through: an
+// unavailable shared store must not take the API down with it.
+func throttle(c *gin.Context, l RateLimiter, class string) bool {
+	key := identityFrom(c).ActorID
+	if key == "" {
+		key = c.ClientIP()
+	}
+	ok, retryAfter, err := l.Allow(c.Request.Context(), key)
+	if err != nil || ok {
+		return false
+	}
+	metrics.RateLimitedTotal.WithLabelValues(c.FullPath(), class).Inc()
+	c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+	abortWithError(c, http.StatusTooManyRequests, CodeRateLimited, "Rate limit exceeded", nil)
+	return true
+}
@@ -0,0 +1,206 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// fakeClock is a clock tests move by hand
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+func newFakeClock() *fakeClock               { return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)} }
+
+// recordingLimiter answers every request the same way and records the keys it saw
+type recordingLimiter struct {
+	mu   sync.Mutex
+	ok   bool
+	err  error
+	keys []string
+}
+
+func (l *recordingLimiter) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.keys = append(l.keys, key)
+	return l.ok, 4500 * time.Millisecond, l.err
+}
+
+func TestWindowLimiter(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	l := api.NewWindowLimiter(2, time.Minute)
+	l.SetClock(clock.Now)
+
+	for i := 0; i < 2; i++ {
+		if ok, _, err := l.Allow(ctx, "alice"); !ok || err != nil {
+			t.Fatalf("request %d refused: %v", i+1, err)
+		}
+	}
+	clock.Advance(20 * time.Second)
+	ok, retryAfter, _ := l.Allow(ctx, "alice")
+	if ok || retryAfter != 40*time.Second {
+		t.Errorf("over the limit = %v, retry after %s; want refused, 40s until the window resets", ok, retryAfter)
+	}
+	if ok, _, _ := l.Allow(ctx, "bob"); !ok {
+		t.Error("another caller was refused")
+	}
+	clock.Advance(40 * time.Second)
+	if ok, _, _ := l.Allow(ctx, "alice"); !ok {
+		t.Error("refused after the window reset")
+	}
+}
+
+func TestTokenBucketLimiter(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	l := api.NewTokenBucketLimiter(6) // a token every 10s, bursts of 6
+	l.SetClock(clock.Now)
+
+	for i := 0; i < 6; i++ {
+		if ok, _, _ := l.Allow(ctx, "alice"); !ok {
+			t.Fatalf("burst request %d refused", i+1)
+		}
+	}
+	ok, retryAfter, _ := l.Allow(ctx, "alice")
+	if ok || retryAfter != 10*time.Second {
+		t.Errorf("empty bucket = %v, retry after %s; want refused, 10s", ok, retryAfter)
+	}
+	clock.Advance(4 * time.Second)
+	if ok, retryAfter, _ := l.Allow(ctx, "alice"); ok || retryAfter.Round(time.Millisecond) != 6*time.Second {
+		t.Errorf("part-refilled bucket = %v, retry after %s; want refused, 6s", ok, retryAfter)
+	}
+	clock.Advance(6 * time.Second)
+	if ok, _, _ := l.Allow(ctx, "alice"); !ok {
+		t.Error("refused once a token refilled")
+	}
+	if ok, _, _ := l.Allow(ctx, "alice"); ok {
+		t.Error("allowed a second request on one refilled token")
+	}
+	if ok, _, _ := l.Allow(ctx, "bob"); !ok {
+		t.Error("another caller was refused")
+	}
+
+	// Refills cap at the burst
+	clock.Advance(time.Hour)
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if ok, _, _ := l.Allow(ctx, "alice"); ok {
+			allowed++
+		}
+	}
+	if allowed != 6 {
+		t.Errorf("after an hour idle allowed %d, want a burst of 6", allowed)
+	}
+}
+
+// TestTokenBucketSweep checks idle buckets are evicted once they would have refilled,
+// and no more often than once a minute
+func TestTokenBucketSweep(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	l := api.NewTokenBucketLimiter(60) // refills fully in a minute
+	l.SetClock(clock.Now)
+
+	l.Allow(ctx, "idle")
+	clock.Advance(30 * time.Second)
+	l.Allow(ctx, "recent")
+	if got := l.Buckets(); got != 2 {
+		t.Fatalf("tracking %d callers, want 2", got)
+	}
+
+	// A minute on, the idle bucket has refilled but the recent one hasn't
+	clock.Advance(30 * time.Second)
+	l.Allow(ctx, "new")
+	if got := l.Buckets(); got != 2 {
+		t.Errorf("after the first sweep tracking %d callers, want recent and new", got)
+	}
+
+	// Everything is refilled now, but the next sweep isn't due for a minute
+	clock.Advance(59 * time.Second)
+	l.Allow(ctx, "other")
+	if got := l.Buckets(); got != 3 {
+		t.Errorf("swept early: tracking %d callers, want 3", got)
+	}
+	clock.Advance(2 * time.Second)
+	l.Allow(ctx, "other")
+	if got := l.Buckets(); got != 1 {
+		t.Errorf("after the second sweep tracking %d callers, want only the active one", got)
+	}
+}
+
+func TestRateLimitRequests(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	reads, writes := &recordingLimiter{}, &recordingLimiter{ok: true}
+	router := newRouter(api.Dependencies{
+		Videos:     services.NewVideoService(db, nil, videoSettings, log),
+		Reactions:  services.NewReactionService(db, log),
+		RateLimits: api.RateLimits{Reads: reads, Writes: writes},
+	})
+	limited := metrics.RateLimitedTotal.WithLabelValues("/api/v1/videos", "read")
+	before := testutil.ToFloat64(limited)
+
+	// Reads go to the read limiter only, here over its limit
+	w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos", "", "alice", ""))
+	if w.Code != http.StatusTooManyRequests || errorCode(w) != api.CodeRateLimited || w.Header().Get("Retry-After") != "5" {
+		t.Errorf("over the read limit: status %d %s, Retry-After %q; want 429, 5", w.Code, errorCode(w), w.Header().Get("Retry-After"))
+	}
+	if got := testutil.ToFloat64(limited) - before; got != 1 {
+		t.Errorf("rate limited total rose by %v, want 1", got)
+	}
+	if len(writes.keys) != 0 {
+		t.Errorf("reads consulted the write limiter: %v", writes.keys)
+	}
+
+	// Writes go to the write limiter, keyed by caller
+	w = serve(router, adminRequest(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-1","title":"t"}`, "alice", ""))
+	if w.Code != http.StatusCreated {
+		t.Errorf("write within its limit: status %d: %s", w.Code, w.Body)
+	}
+	if len(reads.keys) != 1 || len(writes.keys) != 1 || writes.keys[0] != "alice" {
+		t.Errorf("reads saw %v, writes saw %v; want the write counted once against alice", reads.keys, writes.keys)
+	}
+
+	// Signed-out callers are keyed by IP
+	reads.ok = true
+	serve(router, adminRequest(http.MethodGet, "/api/v1/videos", "", "", ""))
+	if got := reads.keys[len(reads.keys)-1]; got != "192.0.2.1" {
+		t.Errorf("signed-out caller keyed %q, want the client IP", got)
+	}
+}
+
+// TestRateLimitFailsOpen checks a failing limiter lets requests through
+func TestRateLimitFailsOpen(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	broken := &recordingLimiter{err: errors.New("redis: connection refused")}
+	router := newRouter(api.Dependencies{
+		Videos:     services.NewVideoService(db, nil, videoSettings, log),
+		Reactions:  services.NewReactionService(db, log),
+		RateLimits: api.RateLimits{Reads: broken, Writes: broken},
+	})
+
+	if w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos", "", "alice", "")); w.Code != http.StatusOK {
+		t.Errorf("read with a failing limiter: status %d, want 200", w.Code)
+	}
+	if w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-1","title":"t"}`, "alice", "")); w.Code != http.StatusCreated {
+		t.Errorf("write with a failing limiter: status %d, want 201: %s", w.Code, w.Body)
+	}
+	if len(broken.keys) != 2 {
+		t.Errorf("limiter consulted %d times, want 2", len(broken.keys))
+	}
+}
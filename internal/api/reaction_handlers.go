@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/flags"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// LikeVideo handles POST /api/v1/videos/:id/like
+func (h *VideoHandler) LikeVideo(c *gin.Context) {
+	h.react(c, models.ReactionLike)
+}
+
+// DislikeVideo handles POST /api/v1/videos/:id/dislike
+func (h *VideoHandler) DislikeVideo(c *gin.Context) {
+	h.react(c, models.ReactionDislike)
+}
+
+// ClearReaction handles DELETE /api/v1/videos/:id/reaction. Clearing when there is
+// no reaction succeeds and changes nothing.
+func (h *VideoHandler) ClearReaction(c *gin.Context) {
+	h.react(c, 0)
+}
+
+// react sets (or with value 0 clears) the caller's reaction and responds with the
+// video's updated counts. While the video_reactions flag is off for the caller the
+// endpoints don't exist.
+func (h *VideoHandler) react(c *gin.Context, value int) {
+	if !flags.Enabled(c.Request.Context(), flags.VideoReactions) {
+		respondError(c, http.StatusNotFound, CodeNotFound, "Not found", nil)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+	if !canView(c, video) {
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+		return
+	}
+
+	var counts *services.ReactionCounts
+	if value == 0 {
+		counts, err = h.reactions.ClearReaction(c.Request.Context(), video.ID, requester)
+	} else {
+		counts, err = h.reactions.React(c.Request.Context(), video.ID, requester, value)
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrVideoNotFound) {
+			respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to update reaction", "error", err, "videoID", video.ID, "userID", requester)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to update reaction", nil)
+		return
+	}
+	c.JSON(http.StatusOK, counts)
+}
+
+// attachReactions fills in the caller's own reaction on each video. Anonymous
+// callers get none, nor do callers with video_reactions off, and a failed lookup
+// only leaves the field out.
+func (h *VideoHandler) attachReactions(c *gin.Context, videos ...*models.Video) {
+	requester := currentUser(c)
+	if requester == "" || len(videos) == 0 || !flags.Enabled(c.Request.Context(), flags.VideoReactions) {
+		return
+	}
+	ids := make([]uint, len(videos))
+	for i, v := range videos {
+		ids[i] = v.ID
+	}
+	mine, err := h.reactions.UserReactions(c.Request.Context(), requester, ids)
+	if err != nil {
+		h.log(c).Warnw("Failed to load caller's reactions", "error", err, "userID", requester)
+		return
+	}
+	for _, v := range videos {
+		value := mine[v.ID]
+		v.MyReaction = &value
+	}
+}
+
+// attachListReactions is attachReactions for a page of videos
+func (h *VideoHandler) attachListReactions(c *gin.Context, response *models.VideoListResponse) {
+	videos := make([]*models.Video, len(response.Videos))
+	for i := range response.Videos {
+		videos[i] = &response.Videos[i]
+	}
+	h.attachReactions(c, videos...)
+}
@@ -0,0 +1,81 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestReactionEndpoints(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+	})
+	public := models.Video{UploadID: "up-public", UserID: "owner", Title: "t", Status: models.StatusReady}
+	private := models.Video{UploadID: "up-private", UserID: "owner", Title: "t", Status: models.StatusReady, Visibility: models.VisibilityPrivate}
+	db.Create(&public)
+	db.Create(&private)
+
+	react := func(method, path, user string) (int, services.ReactionCounts) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		if user != "" {
+			req.Header.Set("X-User-ID", user)
+		}
+		w := serve(router, req)
+		var counts services.ReactionCounts
+		json.Unmarshal(w.Body.Bytes(), &counts)
+		return w.Code, counts
+	}
+	base := "/api/v1/videos/" + itoa(public.ID)
+
+	tests := []struct {
+		name            string
+		method, path    string
+		user            string
+		status          int
+		likes, dislikes int64
+	}{
+		{"like", http.MethodPost, base + "/like", "u-1", http.StatusOK, 1, 0},
+		{"like twice", http.MethodPost, base + "/like", "u-1", http.StatusOK, 1, 0},
+		{"switch to dislike", http.MethodPost, base + "/dislike", "u-1", http.StatusOK, 0, 1},
+		{"clear", http.MethodDelete, base + "/reaction", "u-1", http.StatusOK, 0, 0},
+		{"clear with none", http.MethodDelete, base + "/reaction", "u-1", http.StatusOK, 0, 0},
+		{"anonymous", http.MethodPost, base + "/like", "", http.StatusUnauthorized, 0, 0},
+		{"private, not the owner", http.MethodPost, "/api/v1/videos/" + itoa(private.ID) + "/like", "mallory", http.StatusNotFound, 0, 0},
+		{"private, owner", http.MethodPost, "/api/v1/videos/" + itoa(private.ID) + "/like", "owner", http.StatusOK, 1, 0},
+		{"missing video", http.MethodPost, "/api/v1/videos/999/like", "u-1", http.StatusNotFound, 0, 0},
+	}
+	for _, tt := range tests {
+		status, counts := react(tt.method, tt.path, tt.user)
+		if status != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, status, tt.status)
+			continue
+		}
+		if status == http.StatusOK && (counts.LikeCount != tt.likes || counts.DislikeCount != tt.dislikes) {
+			t.Errorf("%s: %+v, want %d likes and %d dislikes", tt.name, counts, tt.likes, tt.dislikes)
+		}
+	}
+
+	// The caller's own reaction comes back on the video, and only for them
+	react(http.MethodPost, base+"/dislike", "u-1")
+	for user, want := range map[string]int{"u-1": models.ReactionDislike, "u-2": 0} {
+		req := httptest.NewRequest(http.MethodGet, base, nil)
+		req.Header.Set("X-User-ID", user)
+		var video models.Video
+		json.Unmarshal(serve(router, req).Body.Bytes(), &video)
+		if video.MyReaction == nil || *video.MyReaction != want {
+			t.Errorf("%s: my_reaction %v, want %d", user, video.MyReaction, want)
+		}
+	}
+}
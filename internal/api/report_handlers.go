@@ -0,0 +1,205 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// ReportVideo handles POST /api/v1/videos/:id/report. Any signed-in user who can
+// see the video may report it, once until their report is resolved.
+func (h *VideoHandler) ReportVideo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	var req models.VideoReportCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if !req.Reason.Valid() {
+		respondError(c, http.StatusBadRequest, CodeInvalidReason, "Invalid report reason", gin.H{"allowed_reasons": models.ReportReasons})
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+	if !canView(c, video) {
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+		return
+	}
+
+	report, err := h.reports.Report(c.Request.Context(), video.ID, requester, req.Reason, req.Details)
+	if err != nil {
+		if errors.Is(err, services.ErrReportExists) {
+			respondError(c, http.StatusConflict, CodeAlreadyReported, "You already have an open report on this video", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to report video", "error", err, "videoID", video.ID, "userID", requester)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to report video", nil)
+		return
+	}
+	c.JSON(http.StatusCreated, report)
+}
+
+// AdminListReports handles GET /api/v1/admin/reports?status=&video_id=. Open
+// reports are listed unless status says otherwise; status=all lists every report.
+func (h *VideoHandler) AdminListReports(c *gin.Context) {
+	status := models.ReportStatus(c.DefaultQuery("status", string(models.ReportOpen)))
+	if status == "all" {
+		status = ""
+	} else if !status.Valid() {
+		respondError(c, http.StatusBadRequest, CodeInvalidStatus, "Invalid report status", gin.H{"allowed_statuses": models.ReportStatuses})
+		return
+	}
+	var videoID uint64
+	if raw := c.Query("video_id"); raw != "" {
+		var err error
+		if videoID, err = strconv.ParseUint(raw, 10, 32); err != nil {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+			return
+		}
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	reports, total, err := h.reports.ListReports(c.Request.Context(), status, uint(videoID), page, perPage)
+	if err != nil {
+		h.log(c).Errorw("Failed to list reports", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list reports", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports":     reports,
+		"total":       total,
+		"page":        page,
+		"per_page":    perPage,
+		"total_pages": (int(total) + perPage - 1) / perPage,
+	})
+}
+
+// AdminListReportedVideos handles GET /api/v1/admin/reports/videos: the videos
+// with open reports and their report counts, most open reports first
+func (h *VideoHandler) AdminListReportedVideos(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	videos, total, err := h.reports.ReportedVideos(c.Request.Context(), page, perPage)
+	if err != nil {
+		h.log(c).Errorw("Failed to list reported videos", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list reported videos", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"videos":      videos,
+		"total":       total,
+		"page":        page,
+		"per_page":    perPage,
+		"total_pages": (int(total) + perPage - 1) / perPage,
+	})
+}
+
+// AdminResolveReport handles POST /api/v1/admin/reports/:reportID/resolve, marking
+// an open report reviewed or dismissed and optionally setting the video's
+// moderation status
+func (h *VideoHandler) AdminResolveReport(c *gin.Context) {
+	rid, err := strconv.ParseUint(c.Param("reportID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid report ID", nil)
+		return
+	}
+	var req models.VideoReportResolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if req.Status != models.ReportReviewed && req.Status != models.ReportDismissed {
+		respondError(c, http.StatusBadRequest, CodeInvalidStatus, "Invalid report status",
+			gin.H{"allowed_statuses": []models.ReportStatus{models.ReportReviewed, models.ReportDismissed}})
+		return
+	}
+	if req.ModerationStatus != nil && !req.ModerationStatus.Valid() {
+		respondError(c, http.StatusBadRequest, CodeInvalidModerationStatus, "Invalid moderation status",
+			gin.H{"allowed_moderation_statuses": models.ModerationStatuses})
+		return
+	}
+	report, err := h.reports.GetReport(c.Request.Context(), uint(rid))
+	if err != nil {
+		h.reportFailed(c, err, uint(rid))
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), report.VideoID)
+	if err != nil {
+		h.videoLookupFailed(c, err, report.VideoID)
+		return
+	}
+
+	admin := identityFrom(c).ActorID
+	detail := fmt.Sprintf("report %d on video %d -> %s", report.ID, report.VideoID, req.Status)
+	if req.ModerationStatus != nil {
+		detail += fmt.Sprintf("; moderation %s -> %s", video.ModerationStatus, *req.ModerationStatus)
+	}
+	if req.Note != "" {
+		detail += ": " + req.Note
+	}
+	entry, ok := h.beginAudit(c, models.AuditActionResolveReport, video.UserID, "video:"+strconv.FormatUint(uint64(video.ID), 10), detail)
+	if !ok {
+		return
+	}
+	resolution, err := h.reports.Resolve(c.Request.Context(), report.ID, req.Status, req.ModerationStatus, admin, req.Note)
+	h.finishAudit(c, entry, err)
+	if err != nil {
+		h.reportFailed(c, err, report.ID)
+		return
+	}
+
+	h.log(c).Infow("Report resolved by admin", "reportID", report.ID, "videoID", report.VideoID, "status", req.Status,
+		"alsoResolved", resolution.AlsoResolved, "admin", admin)
+	c.JSON(http.StatusOK, resolution)
+}
+
+// reportFailed answers a failed report lookup or resolution
+func (h *VideoHandler) reportFailed(c *gin.Context, err error, reportID uint) {
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		respondError(c, http.StatusNotFound, CodeReportNotFound, "Report not found", nil)
+	case errors.Is(err, services.ErrReportResolved):
+		respondError(c, http.StatusConflict, CodeReportResolved, "Report is already resolved", nil)
+	case errors.Is(err, services.ErrVideoNotFound):
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+	default:
+		h.log(c).Errorw("Failed to resolve report", "error", err, "reportID", reportID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to resolve report", nil)
+	}
+}
@@ -0,0 +1,165 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestReportVideo(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	videos := services.NewVideoService(db, nil, videoSettings, log)
+	router := newRouter(api.Dependencies{Videos: videos, Reports: services.NewReportService(db, videos, log)})
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t", Status: models.StatusReady}
+	private := models.Video{UploadID: "up-2", UserID: "owner", Title: "p", Status: models.StatusReady, Visibility: models.VisibilityPrivate}
+	db.Create(&video)
+	db.Create(&private)
+	path := "/api/v1/videos/" + itoa(video.ID) + "/report"
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		user   string
+		status int
+	}{
+		{"anonymous", path, `{"reason":"spam"}`, "", http.StatusUnauthorized},
+		{"unknown reason", path, `{"reason":"boring"}`, "alice", http.StatusBadRequest},
+		{"report", path, `{"reason":"spam","details":"ads"}`, "alice", http.StatusCreated},
+		{"report twice", path, `{"reason":"hate"}`, "alice", http.StatusConflict},
+		{"another user", path, `{"reason":"hate"}`, "bob", http.StatusCreated},
+		{"video they can't see", "/api/v1/videos/" + itoa(private.ID) + "/report", `{"reason":"spam"}`, "alice", http.StatusNotFound},
+		{"missing video", "/api/v1/videos/999/report", `{"reason":"spam"}`, "alice", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := serve(router, adminRequest(http.MethodPost, tt.path, tt.body, tt.user, ""))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+		}
+	}
+	var count int64
+	db.Model(&models.VideoReport{}).Where("video_id = ? AND status = ?", video.ID, models.ReportOpen).Count(&count)
+	if count != 2 {
+		t.Errorf("%d open reports, want 2", count)
+	}
+}
+
+func TestReportCountsAreAdminOnly(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	videos := services.NewVideoService(db, nil, videoSettings, log)
+	reports := services.NewReportService(db, videos, log)
+	router := newRouter(api.Dependencies{Videos: videos, Reports: reports, Reactions: services.NewReactionService(db, log)})
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t", Status: models.StatusReady}
+	db.Create(&video)
+	for _, user := range []string{"alice", "bob"} {
+		w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos/"+itoa(video.ID)+"/report", `{"reason":"spam"}`, user, ""))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("report: %d %s", w.Code, w.Body)
+		}
+	}
+
+	for _, target := range []string{"/api/v1/admin/reports", "/api/v1/admin/reports/videos", "/api/v1/admin/reports?video_id=" + itoa(video.ID)} {
+		for _, user := range []string{"owner", "alice"} {
+			if w := serve(router, adminRequest(http.MethodGet, target, "", user, "user")); w.Code != http.StatusForbidden {
+				t.Errorf("%s as %s: status %d, want 403", target, user, w.Code)
+			}
+		}
+	}
+	// The video itself carries no report counts, for anyone
+	for _, user := range []string{"owner", "alice", ""} {
+		w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/"+itoa(video.ID), "", user, ""))
+		if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "report") {
+			t.Errorf("video as %q: %d %s", user, w.Code, w.Body)
+		}
+	}
+
+	w := serve(router, adminRequest(http.MethodGet, "/api/v1/admin/reports?video_id="+itoa(video.ID), "", "admin-1", "admin"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin list: %d %s", w.Code, w.Body)
+	}
+	var page struct {
+		Reports []models.VideoReport `json:"reports"`
+		Total   int64                `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || len(page.Reports) != 2 || page.Reports[0].ReporterID != "bob" {
+		t.Errorf("admin list = %+v, want both reports newest first", page)
+	}
+}
+
+func TestResolveReportTakesVideoDown(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	videos := services.NewVideoService(db, nil, videoSettings, log)
+	reports := services.NewReportService(db, videos, log)
+	router := newRouter(api.Dependencies{Videos: videos, Reports: reports, Reactions: services.NewReactionService(db, log),
+		Audit: services.NewAuditService(db, log)})
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "holiday", Category: "music", Status: models.StatusReady}
+	kept := models.Video{UploadID: "up-2", UserID: "owner", Title: "holiday too", Category: "music", Status: models.StatusReady}
+	db.Create(&video)
+	db.Create(&kept)
+	report, err := reports.Report(context.Background(), video.ID, "alice", models.ReportViolence, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/v1/admin/reports/" + itoa(report.ID) + "/resolve"
+
+	tests := []struct {
+		name   string
+		body   string
+		roles  string
+		status int
+	}{
+		{"not an admin", `{"status":"reviewed","moderation_status":"taken_down"}`, "user", http.StatusForbidden},
+		{"still open", `{"status":"open"}`, "admin", http.StatusBadRequest},
+		{"unknown moderation status", `{"status":"reviewed","moderation_status":"hidden"}`, "admin", http.StatusBadRequest},
+		{"take down", `{"status":"reviewed","moderation_status":"taken_down","note":"gore"}`, "admin", http.StatusOK},
+		{"already resolved", `{"status":"dismissed"}`, "admin", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		w := serve(router, adminRequest(http.MethodPost, path, tt.body, "admin-1", tt.roles))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+		}
+	}
+	var stored models.Video
+	db.First(&stored, video.ID)
+	if stored.ModerationStatus != models.ModerationStatusTakenDown {
+		t.Fatalf("moderation_status = %s, want taken_down", stored.ModerationStatus)
+	}
+
+	// Non-owners are told the video was taken down; the owner still sees it
+	get := "/api/v1/videos/" + itoa(video.ID)
+	for user, want := range map[string]int{"": http.StatusUnavailableForLegalReasons, "alice": http.StatusUnavailableForLegalReasons, "owner": http.StatusOK} {
+		if w := serve(router, adminRequest(http.MethodGet, get, "", user, "")); w.Code != want {
+			t.Errorf("GET as %q: status %d, want %d", user, w.Code, want)
+		}
+	}
+	// Search runs filter-only here: SQLite has no ILIKE for a text query
+	for _, target := range []string{"/api/v1/videos", "/api/v1/videos/search?category=music"} {
+		w := serve(router, adminRequest(http.MethodGet, target, "", "alice", ""))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", target, w.Code, w.Body)
+		}
+		var list models.VideoListResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+			t.Fatal(err)
+		}
+		if list.Total != 1 || len(list.Videos) != 1 || list.Videos[0].ID != kept.ID {
+			t.Errorf("%s = %+v, want only the video still up", target, list.Videos)
+		}
+	}
+}
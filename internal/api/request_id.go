@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/logging"
+)
+
+// HeaderRequestID carries the request's correlation ID in and out
+const HeaderRequestID = "X-Request-ID"
+
+// requestIDKey is where RequestID stores the ID in the gin context
+const requestIDKey = "requestID"
+
+// maxRequestIDLen bounds a caller-supplied ID; longer or unprintable ones are replaced
+const maxRequestIDLen = 128
+
+// RequestID takes the caller's X-Request-ID, or generates a UUID when there is none,
+// echoes it in the response and tags the request context with it so logging.For
+// attaches it to every log line written on the request's behalf
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(HeaderRequestID)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDKey, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(HeaderRequestID, id)
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// AccessLog is gin's request log with the request ID appended, so it can be
+// matched with the handler and service lines for the same request
+func AccessLog() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		id, _ := p.Keys[requestIDKey].(string)
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v | requestID=%s\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			p.StatusCode,
+			p.Latency.Round(time.Microsecond),
+			p.ClientIP,
+			p.Method,
+			p.Path,
+			id,
+			p.ErrorMessage,
+		)
+	})
+}
+
+// log is the handler's logger tagged with the request's ID
+func (h *VideoHandler) log(c *gin.Context) *zap.SugaredLogger {
+	return logging.For(c.Request.Context(), h.logger)
+}
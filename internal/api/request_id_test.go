@@ -0,0 +1,97 @@
+package api_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/logging"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestRequestID(t *testing.T) {
+	router := gin.New()
+	router.Use(api.RequestID())
+	router.GET("/id", func(c *gin.Context) { c.String(http.StatusOK, logging.RequestID(c.Request.Context())) })
+
+	tests := []struct {
+		name, header string
+		kept         bool
+	}{
+		{"caller's ID", "gw-3f9c1a", true},
+		{"longest allowed", strings.Repeat("a", 128), true},
+		{"absent", "", false},
+		{"too long", strings.Repeat("a", 129), false},
+		{"with a space", "gw 3f9c1a", false},
+		{"with a control character", "gw\x013f9c1a", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/id", nil)
+		if tt.header != "" {
+			req.Header.Set(api.HeaderRequestID, tt.header)
+		}
+		w := serve(router, req)
+		echoed := w.Header().Get(api.HeaderRequestID)
+		if echoed != w.Body.String() {
+			t.Errorf("%s: echoed %q but the context carries %q", tt.name, echoed, w.Body)
+		}
+		if tt.kept {
+			if echoed != tt.header {
+				t.Errorf("%s: echoed %q, want the caller's ID", tt.name, echoed)
+			}
+		} else if _, err := uuid.Parse(echoed); err != nil {
+			t.Errorf("%s: echoed %q, want a generated UUID", tt.name, echoed)
+		}
+	}
+
+	// Each request without an ID gets its own
+	first := serve(router, httptest.NewRequest(http.MethodGet, "/id", nil)).Body.String()
+	if second := serve(router, httptest.NewRequest(http.MethodGet, "/id", nil)).Body.String(); first == second {
+		t.Errorf("two requests shared the generated ID %q", first)
+	}
+}
+
+// TestRequestIDReachesServiceLogs follows one request's ID from its header through
+// the handler into the service's error line and the access log
+func TestRequestIDReachesServiceLogs(t *testing.T) {
+	db := dbtest.Open(t)
+	core, logs := observer.New(zap.InfoLevel)
+	log := zap.New(core).Sugar()
+	var access bytes.Buffer
+	stdout := gin.DefaultWriter
+	gin.DefaultWriter = &access
+	t.Cleanup(func() { gin.DefaultWriter = stdout })
+
+	router := gin.New()
+	router.Use(api.RequestID(), api.AccessLog())
+	api.SetupRoutes(router, api.Dependencies{
+		Videos:   services.NewVideoService(db, nil, videoSettings, log),
+		Comments: services.NewCommentService(db, commentSettings, log),
+	}, log)
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Status: models.StatusReady, CommentsEnabled: true}
+	db.Create(&video)
+	db.Migrator().DropTable(&models.Comment{})
+
+	req := adminRequest(http.MethodPost, "/api/v1/videos/"+itoa(video.ID)+"/comments", `{"content":"hi"}`, "alice", "")
+	req.Header.Set(api.HeaderRequestID, "gw-3f9c1a")
+	if w := serve(router, req); w.Code != http.StatusInternalServerError {
+		t.Fatalf("comment without a comments table: status %d", w.Code)
+	}
+	failed := logs.FilterMessage("create comment").All()
+	if len(failed) != 1 || failed[0].ContextMap()["requestID"] != "gw-3f9c1a" {
+		t.Errorf("service error line %v, want it tagged with the request ID", failed)
+	}
+	if !strings.Contains(access.String(), "requestID=gw-3f9c1a") {
+		t.Errorf("access log %q lacks the request ID", access.String())
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+)
+
+// unmatchedRoute labels requests no route matched, so stray paths can't grow the
+// histogram's label set
+const unmatchedRoute = "unmatched"
+
+// RequestMetrics observes each request's latency in catalog_http_request_duration_seconds,
+// labeled with the route template (/api/v1/videos/:id) rather than the raw path
+func RequestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(started).Seconds())
+	}
+}
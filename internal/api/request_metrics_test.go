@@ -0,0 +1,54 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// requestsObserved is the sample count of catalog_http_request_duration_seconds for the labels
+func requestsObserved(t *testing.T, method, route, status string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.HTTPRequestDuration.WithLabelValues(method, route, status).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("read histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestRequestMetrics(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := gin.New()
+	router.Use(api.RequestMetrics())
+	api.SetupRoutes(router, api.Dependencies{Videos: services.NewVideoService(db, nil, videoSettings, log)}, log)
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Status: models.StatusReady, Visibility: models.VisibilityPublic}
+	db.Create(&video)
+
+	const route = "/api/v1/videos/:id"
+	for _, tt := range []struct {
+		target, route, status string
+	}{
+		// Requests are labeled by route template, so both IDs share a series per status
+		{"/api/v1/videos/" + itoa(video.ID), route, "200"},
+		{"/api/v1/videos/999", route, "404"},
+		{"/api/v1/videos/998", route, "404"},
+		{"/no/such/path", "unmatched", "404"},
+	} {
+		before := requestsObserved(t, http.MethodGet, tt.route, tt.status)
+		serve(router, adminRequest(http.MethodGet, tt.target, "", "", ""))
+		if got := requestsObserved(t, http.MethodGet, tt.route, tt.status) - before; got != 1 {
+			t.Errorf("GET %s: %s %s observed +%d times, want once", tt.target, tt.route, tt.status, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// TestSearchFilterErrors sends each search filter bad values: every one is a 400
+// whose details name the parameter, in both the full and compact views
+func TestSearchFilterErrors(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+	})
+
+	tests := []struct {
+		param string
+		query url.Values
+	}{
+		{"min_duration", url.Values{"min_duration": {"abc"}}},
+		{"min_duration", url.Values{"min_duration": {"-1"}}},
+		{"min_duration", url.Values{"min_duration": {"NaN"}}},
+		{"max_duration", url.Values{"max_duration": {"ten"}}},
+		{"max_duration", url.Values{"max_duration": {"+Inf"}}},
+		{"min_duration", url.Values{"min_duration": {"60"}, "max_duration": {"30"}}},
+		{"uploaded_after", url.Values{"uploaded_after": {"2024-01-31"}}},
+		{"uploaded_after", url.Values{"uploaded_after": {"yesterday"}}},
+		{"uploaded_before", url.Values{"uploaded_before": {"1706659200"}}},
+		{"uploaded_after", url.Values{"uploaded_after": {"2024-02-01T00:00:00Z"}, "uploaded_before": {"2024-01-01T00:00:00Z"}}},
+	}
+	for _, tt := range tests {
+		for _, view := range []string{"", "compact"} {
+			query := url.Values{"q": {"holiday"}}
+			for k, v := range tt.query {
+				query[k] = v
+			}
+			if view != "" {
+				query.Set("view", view)
+			}
+			t.Run(query.Encode(), func(t *testing.T) {
+				w := serve(router, httptest.NewRequest(http.MethodGet, "/api/v1/videos/search?"+query.Encode(), nil))
+				if w.Code != http.StatusBadRequest {
+					t.Fatalf("status %d, want 400: %s", w.Code, w.Body)
+				}
+				var body struct {
+					api.ErrorResponse
+					Details struct {
+						Parameter string `json:"parameter"`
+					} `json:"details"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				if body.Code != api.CodeInvalidRequest || body.Details.Parameter != tt.param || !strings.HasPrefix(body.Message, tt.param+" ") {
+					t.Errorf("%s, want invalid_request naming %s", w.Body, tt.param)
+				}
+			})
+		}
+	}
+}
+
+// TestSearchFiltersApplied checks good filter values reach the search
+func TestSearchFiltersApplied(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+	})
+	for _, v := range []models.Video{
+		{UploadID: "up-1", Title: "short", Category: "music", Duration: 30},
+		{UploadID: "up-2", Title: "long", Category: "music", Duration: 600},
+		{UploadID: "up-3", Title: "game", Category: "gaming", Duration: 600},
+	} {
+		v.UserID, v.Status = "owner", models.StatusReady
+		db.Create(&v)
+	}
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/api/v1/videos/search?category=music&min_duration=60&uploaded_after=2000-01-01T00:00:00Z", nil))
+	var page models.VideoListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if page.Total != 1 || len(page.Videos) != 1 || page.Videos[0].Title != "long" {
+		t.Errorf("found %+v, want only the long music video", page.Videos)
+	}
+}
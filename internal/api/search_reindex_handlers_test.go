@@ -0,0 +1,74 @@
+package api_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/search"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// heldCluster accepts every OpenSearch request, holding bulk writes until release
+// is closed
+type heldCluster struct {
+	release chan struct{}
+	bulks   atomic.Int64
+}
+
+func (c *heldCluster) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := `{}`
+	if req.URL.Path == "/_bulk" {
+		<-c.release
+		c.bulks.Add(1)
+		body = `{"errors":false,"items":[]}`
+	}
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+func TestStartSearchReindex(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	db.Create(&models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Visibility: models.VisibilityPublic})
+	cluster := &heldCluster{release: make(chan struct{})}
+	client := search.NewClient(search.Config{URL: "http://search:9200", Index: "videos", Timeout: time.Second}, cluster)
+	router := newRouter(api.Dependencies{
+		Videos: services.NewVideoService(db, nil, videoSettings, log),
+		Search: services.NewSearchIndex(db, client, log, 10, 10, time.Hour),
+	})
+	const path = "/api/v1/admin/search/reindex?batch_size=100"
+
+	if w := serve(router, adminRequest(http.MethodPost, path, "", "mallory", "")); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: %d, want 403", w.Code)
+	}
+	w := serve(router, adminRequest(http.MethodPost, path, "", "root", "admin"))
+	if w.Code != http.StatusAccepted || !strings.Contains(w.Body.String(), `"batch_size":100`) {
+		t.Fatalf("start: %d %s, want 202", w.Code, w.Body)
+	}
+	w = serve(router, adminRequest(http.MethodPost, path, "", "root", "admin"))
+	if w.Code != http.StatusConflict || errorCode(w) != api.CodeAlreadyRunning {
+		t.Errorf("second start while running: %d %s, want 409", w.Code, w.Body)
+	}
+	close(cluster.release)
+	deadline := time.Now().Add(time.Second)
+	for cluster.bulks.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if cluster.bulks.Load() != 1 {
+		t.Errorf("%d bulk writes, want the one batch", cluster.bulks.Load())
+	}
+
+	// Without a search backend there's nothing to reindex
+	plain := newRouter(api.Dependencies{Videos: services.NewVideoService(db, nil, videoSettings, log)})
+	if w := serve(plain, adminRequest(http.MethodPost, path, "", "root", "admin")); w.Code != http.StatusServiceUnavailable || errorCode(w) != api.CodeSearchUnavailable {
+		t.Errorf("no backend: %d %s, want 503", w.Code, w.Body)
+	}
+}
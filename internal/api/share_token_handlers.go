@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// RotateShareToken handles POST /api/v1/videos/:id/share-token - issues a new share
+// token for the caller's unlisted or private video; links with the old one stop
+// working
+func (h *VideoHandler) RotateShareToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	video, err := h.videoService.RotateShareToken(c.Request.Context(), uint(id), requester)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrVideoNotFound):
+			respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+		case errors.Is(err, services.ErrForbidden):
+			respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+		case errors.Is(err, services.ErrVideoPublic):
+			respondError(c, http.StatusConflict, CodeVideoPublic, "Public videos have no share token", nil)
+		default:
+			respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to rotate share token", nil)
+		}
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"video_id": video.ID, "visibility": video.Visibility, "share_token": video.ShareToken})
+}
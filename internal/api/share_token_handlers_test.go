@@ -0,0 +1,167 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// TestShareTokens unlists and privates videos through the API and opens them with
+// and without their share token; only the owner ever sees the token
+func TestShareTokens(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+	})
+
+	// setVisibility changes a video's visibility as its owner and returns its token
+	setVisibility := func(id uint, visibility models.Visibility) string {
+		t.Helper()
+		w := serve(router, adminRequest(http.MethodPatch, "/api/v1/videos/"+itoa(id), `{"visibility":"`+string(visibility)+`"}`, "owner", ""))
+		var video models.Video
+		if err := json.Unmarshal(w.Body.Bytes(), &video); err != nil || w.Code != http.StatusOK || video.Visibility != visibility {
+			t.Fatalf("set %s: status %d: %s", visibility, w.Code, w.Body)
+		}
+		return video.ShareToken
+	}
+	get := func(id uint, user, token string) (int, models.Video) {
+		t.Helper()
+		target := "/api/v1/videos/" + itoa(id)
+		if token != "" {
+			target += "?token=" + token
+		}
+		w := serve(router, adminRequest(http.MethodGet, target, "", user, ""))
+		var video models.Video
+		json.Unmarshal(w.Body.Bytes(), &video)
+		return w.Code, video
+	}
+
+	for _, visibility := range []models.Visibility{models.VisibilityUnlisted, models.VisibilityPrivate} {
+		t.Run(string(visibility), func(t *testing.T) {
+			video := models.Video{UploadID: "up-" + string(visibility), UserID: "owner", Title: "t", Status: models.StatusReady}
+			db.Create(&video)
+			token := setVisibility(video.ID, visibility)
+			if len(token) != 64 {
+				t.Fatalf("share token %q, want 64 hex characters", token)
+			}
+			wrong := "0" + token[1:]
+			if token[0] == '0' {
+				wrong = "1" + token[1:]
+			}
+
+			tests := []struct {
+				name, user, token string
+				status            int
+				seesToken         bool
+			}{
+				{"stranger without the token", "stranger", "", http.StatusNotFound, false},
+				{"signed out without the token", "", "", http.StatusNotFound, false},
+				{"wrong token", "stranger", wrong, http.StatusNotFound, false},
+				{"stranger with the token", "stranger", token, http.StatusOK, false},
+				{"signed out with the token", "", token, http.StatusOK, false},
+				{"owner", "owner", "", http.StatusOK, true},
+			}
+			for _, tt := range tests {
+				status, got := get(video.ID, tt.user, tt.token)
+				if status != tt.status {
+					t.Errorf("%s: status %d, want %d", tt.name, status, tt.status)
+					continue
+				}
+				if status == http.StatusOK && (got.ShareToken == token) != tt.seesToken {
+					t.Errorf("%s: share_token %q, want it shown %v", tt.name, got.ShareToken, tt.seesToken)
+				}
+			}
+
+			// Rotating revokes the old link
+			rotate := func(user string) (int, string) {
+				w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos/"+itoa(video.ID)+"/share-token", "", user, ""))
+				var body struct {
+					ShareToken string `json:"share_token"`
+				}
+				json.Unmarshal(w.Body.Bytes(), &body)
+				return w.Code, body.ShareToken
+			}
+			if status, _ := rotate("stranger"); status != http.StatusForbidden {
+				t.Errorf("rotate by a stranger: status %d, want 403", status)
+			}
+			if status, _ := rotate(""); status != http.StatusUnauthorized {
+				t.Errorf("rotate signed out: status %d, want 401", status)
+			}
+			status, rotated := rotate("owner")
+			if status != http.StatusOK || rotated == "" || rotated == token {
+				t.Fatalf("rotate: status %d, token %q", status, rotated)
+			}
+			if status, _ := get(video.ID, "stranger", token); status != http.StatusNotFound {
+				t.Errorf("revoked token: status %d, want 404", status)
+			}
+			if status, _ := get(video.ID, "stranger", rotated); status != http.StatusOK {
+				t.Errorf("rotated token: status %d, want 200", status)
+			}
+
+			// Making it public drops the token; hiding it again issues a fresh one
+			if token := setVisibility(video.ID, models.VisibilityPublic); token != "" {
+				t.Errorf("public video kept share token %q", token)
+			}
+			if status, _ := rotate("owner"); status != http.StatusConflict {
+				t.Errorf("rotate a public video: status %d, want 409", status)
+			}
+			if status, _ := get(video.ID, "stranger", ""); status != http.StatusOK {
+				t.Errorf("public video: status %d, want 200", status)
+			}
+			if again := setVisibility(video.ID, visibility); again == "" || again == rotated {
+				t.Errorf("hidden again with token %q, want a new one", again)
+			}
+			if status, _ := get(video.ID, "stranger", rotated); status != http.StatusNotFound {
+				t.Errorf("token from before going public: status %d, want 404", status)
+			}
+		})
+	}
+}
+
+// TestShareTokenHiddenFromLists checks unlisted videos stay out of public lists and
+// that batch lookups redact the token for everyone but the owner
+func TestShareTokenHiddenFromLists(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+	})
+	public := models.Video{UploadID: "up-1", UserID: "owner", Title: "public", Status: models.StatusReady}
+	unlisted := models.Video{UploadID: "up-2", UserID: "owner", Title: "unlisted", Status: models.StatusReady,
+		Visibility: models.VisibilityUnlisted, ShareToken: "secret-token"}
+	db.Create(&public)
+	db.Create(&unlisted)
+
+	w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos", "", "stranger", ""))
+	var page models.VideoListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || len(page.Videos) != 1 || page.Videos[0].ID != public.ID {
+		t.Errorf("public list = %+v, want only the public video", page.Videos)
+	}
+
+	batch := "/api/v1/videos/batch?ids=" + itoa(unlisted.ID) + "&token=secret-token"
+	for user, want := range map[string]string{"stranger": "", "owner": "secret-token"} {
+		w := serve(router, adminRequest(http.MethodGet, batch, "", user, ""))
+		var body struct {
+			Videos []models.Video `json:"videos"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Videos) != 1 {
+			t.Fatalf("%s: batch %s", user, w.Body)
+		}
+		if body.Videos[0].ShareToken != want {
+			t.Errorf("%s: batch share_token %q, want %q", user, body.Videos[0].ShareToken, want)
+		}
+	}
+}
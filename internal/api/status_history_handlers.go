@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetStatusHistory handles GET /api/v1/videos/:id/history - every status change of
+// the video, oldest first, with what made it. Only the owner and admins may read
+// it; anyone else gets 404, as if the video didn't exist.
+func (h *VideoHandler) GetStatusHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	identity := identityFrom(c)
+	if identity.UserID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+	if video.UserID != identity.UserID && !hasRole(identity.Roles, "admin") {
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+		return
+	}
+	history, err := h.videoService.StatusHistory(c.Request.Context(), video.ID)
+	if err != nil {
+		h.log(c).Errorw("Failed to get status history", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get status history", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"video_id": video.ID, "status": video.Status, "history": history})
+}
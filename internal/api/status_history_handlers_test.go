@@ -0,0 +1,64 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestGetStatusHistory(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, zap.NewNop().Sugar())
+	router := newRouter(api.Dependencies{Videos: videos})
+	ctx := context.Background()
+	video, err := videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-1", Title: "t", Visibility: models.VisibilityPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-1", UserID: "owner", Title: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/v1/videos/" + itoa(video.ID) + "/history"
+
+	tests := []struct {
+		name, user, roles string
+		status            int
+	}{
+		{"owner", "owner", "", http.StatusOK},
+		{"admin", "root", "admin", http.StatusOK},
+		// Other users can't tell the video exists
+		{"someone else", "stranger", "", http.StatusNotFound},
+		{"anonymous", "", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		w := serve(router, adminRequest(http.MethodGet, path, "", tt.user, tt.roles))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var body struct {
+			VideoID uint                       `json:"video_id"`
+			Status  models.VideoStatus         `json:"status"`
+			History []models.VideoStatusChange `json:"history"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if body.VideoID != video.ID || body.Status != models.StatusProcessing || len(body.History) != 2 ||
+			body.History[0].To != models.StatusUploaded || body.History[1].Source != models.StatusSourceUploadEvent {
+			t.Errorf("%s: %s", tt.name, w.Body)
+		}
+	}
+	if w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/999/history", "", "owner", "")); w.Code != http.StatusNotFound {
+		t.Errorf("unknown video: status %d, want 404", w.Code)
+	}
+}
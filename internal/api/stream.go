@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/streaming"
+)
+
+// streamArray writes every row of query as {"<key>":[...],"count":N,"truncated":bool}
+// without holding the result set in memory
+func streamArray[T any](h *VideoHandler, c *gin.Context, key string, query *gorm.DB) {
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+	res, err := streaming.WriteArray[T](c.Request.Context(), c.Writer, key, query, streaming.DefaultLimits)
+	if err != nil {
+		h.log(c).Warnw("Streamed response ended early", "error", err, "path", c.FullPath(), "rows", res.Rows)
+		return
+	}
+	if res.Truncated {
+		h.log(c).Infow("Streamed response truncated", "path", c.FullPath(), "rows", res.Rows, "bytes", res.Bytes)
+	}
+}
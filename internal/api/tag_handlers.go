@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTagSuggestions caps GET /tags/suggest
+const maxTagSuggestions = 10
+
+// ListTags handles GET /api/v1/tags?page=&per_page= - the most used tags on public,
+// ready videos with their counts
+func (h *VideoHandler) ListTags(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	perPage := perPageFor(c, 0)
+	tags, total, err := h.tags.Popular(c.Request.Context(), (page-1)*perPage, perPage)
+	if err != nil {
+		h.log(c).Errorw("Failed to list tags", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list tags", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"tags":        tags,
+		"total":       total,
+		"page":        page,
+		"per_page":    perPage,
+		"total_pages": (total + perPage - 1) / perPage,
+	})
+}
+
+// SuggestTags handles GET /api/v1/tags/suggest?q=&limit= - up to 10 popular tags
+// starting with q, ignoring case
+func (h *VideoHandler) SuggestTags(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "q is required", nil)
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(maxTagSuggestions)))
+	if err != nil || limit < 1 || limit > maxTagSuggestions {
+		limit = maxTagSuggestions
+	}
+	tags, err := h.tags.Suggest(c.Request.Context(), q, limit)
+	if err != nil {
+		h.log(c).Errorw("Failed to suggest tags", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to suggest tags", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/flags"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// GetThumbnail handles GET /api/v1/videos/:id/thumbnail?w=320. Only the configured
+// widths are served, so arbitrary sizes can't be used to bust the cache. If
+// resizing fails, or the thumbnail_resize flag is off, the client is redirected to
+// the original.
+func (h *VideoHandler) GetThumbnail(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	width, err := strconv.Atoi(c.Query("w"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "w must be one of the allowed widths", gin.H{"allowed": h.thumbnails.Widths()})
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+	if !canView(c, video) {
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+		return
+	}
+
+	if !flags.Enabled(c.Request.Context(), flags.ThumbnailResize) {
+		if video.ThumbnailURL == "" {
+			respondError(c, http.StatusNotFound, CodeThumbnailNotFound, "Video has no thumbnail", nil)
+			return
+		}
+		c.Redirect(http.StatusFound, video.ThumbnailURL)
+		return
+	}
+
+	thumb, err := h.thumbnails.Resized(c.Request.Context(), video, width)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrThumbnailWidth):
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "w must be one of the allowed widths", gin.H{"allowed": h.thumbnails.Widths()})
+		case errors.Is(err, services.ErrNoThumbnail):
+			respondError(c, http.StatusNotFound, CodeThumbnailNotFound, "Video has no thumbnail", nil)
+		default:
+			h.log(c).Warnw("Thumbnail resize failed; redirecting to original", "error", err, "videoID", video.ID, "width", width)
+			c.Redirect(http.StatusFound, video.ThumbnailURL)
+		}
+		return
+	}
+
+	// The URL changes meaning only when the video does, which the ETag tracks
+	if video.IsPrivate() {
+		c.Header("Cache-Control", "private, max-age=3600")
+	} else {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	c.Header("ETag", thumb.ETag)
+	c.Header("Content-Type", thumb.ContentType)
+	// ServeContent answers Range and conditional requests
+	http.ServeContent(c.Writer, c.Request, "", video.UpdatedAt, bytes.NewReader(thumb.Data))
+}
+
+// multipartOverheadBytes is the room allowed for the multipart envelope around an
+// uploaded thumbnail, on top of its size limit
+const multipartOverheadBytes = 64 << 10
+
+// ListThumbnails handles GET /api/v1/videos/:id/thumbnails (owner only): the
+// video's thumbnail candidates, with the current one marked selected
+func (h *VideoHandler) ListThumbnails(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+	if video.UserID != requester {
+		respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+		return
+	}
+
+	thumbnails, err := h.videoService.ListThumbnails(c.Request.Context(), video)
+	if err != nil {
+		h.log(c).Errorw("Failed to list thumbnails", "error", err, "videoID", video.ID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list thumbnails", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"video_id": video.ID, "thumbnail_url": video.ThumbnailURL, "thumbnails": thumbnails})
+}
+
+// SelectThumbnail handles POST /api/v1/videos/:id/thumbnail/select with
+// {"thumbnail_id": n} (owner only), making that candidate the video's thumbnail
+func (h *VideoHandler) SelectThumbnail(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	var req models.ThumbnailSelectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	video, err := h.videoService.SelectThumbnail(c.Request.Context(), uint(id), requester, req.ThumbnailID)
+	if err != nil {
+		h.thumbnailChangeFailed(c, err, uint(id))
+		return
+	}
+	c.Header("ETag", videoETag(video))
+	c.JSON(http.StatusOK, video)
+}
+
+// UploadThumbnail handles POST /api/v1/videos/:id/thumbnail (owner only), a
+// multipart form whose file field is a JPEG or PNG image. The image is stored as a
+// candidate and becomes the video's thumbnail.
+func (h *VideoHandler) UploadThumbnail(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		if respondTooLarge(c, err) {
+			return
+		}
+		respondFieldErrors(c, "invalid thumbnail", map[string]string{"file": "a multipart file field is required"})
+		return
+	}
+	if header.Size > h.maxThumbBytes {
+		respondError(c, http.StatusRequestEntityTooLarge, CodeRequestTooLarge, "Thumbnail too large", gin.H{"max_bytes": h.maxThumbBytes})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		h.log(c).Errorw("Failed to open uploaded thumbnail", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to upload thumbnail", nil)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, h.maxThumbBytes+1))
+	if err != nil {
+		h.log(c).Errorw("Failed to read uploaded thumbnail", "error", err, "videoID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to upload thumbnail", nil)
+		return
+	}
+
+	thumb, video, err := h.videoService.UploadThumbnail(c.Request.Context(), uint(id), requester, data)
+	if err != nil {
+		h.thumbnailChangeFailed(c, err, uint(id))
+		return
+	}
+	c.Header("ETag", videoETag(video))
+	c.JSON(http.StatusCreated, gin.H{"thumbnail": thumb, "video": video})
+}
+
+// thumbnailChangeFailed answers a failed thumbnail selection or upload
+func (h *VideoHandler) thumbnailChangeFailed(c *gin.Context, err error, videoID uint) {
+	var invalid *services.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		respondFieldErrors(c, invalid.Error(), invalid.Fields)
+	case errors.Is(err, services.ErrVideoNotFound):
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+	case errors.Is(err, services.ErrForbidden):
+		respondError(c, http.StatusForbidden, CodeForbidden, "Forbidden", nil)
+	case errors.Is(err, services.ErrThumbnailNotFound):
+		respondError(c, http.StatusNotFound, CodeThumbnailNotFound, "Thumbnail not found", nil)
+	case errors.Is(err, services.ErrStorageUnavailable):
+		respondError(c, http.StatusServiceUnavailable, CodeStorageUnavailable, "Thumbnail uploads need blob storage", nil)
+	default:
+		h.log(c).Errorw("Failed to change thumbnail", "error", err, "videoID", videoID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to change thumbnail", nil)
+	}
+}
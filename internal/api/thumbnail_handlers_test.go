@@ -0,0 +1,276 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/flags"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestGetThumbnail(t *testing.T) {
+	var original bytes.Buffer
+	png.Encode(&original, image.NewRGBA(image.Rect(0, 0, 640, 360)))
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/thumbs/ok.png" {
+			http.Error(w, "gone", http.StatusInternalServerError)
+			return
+		}
+		w.Write(original.Bytes())
+	}))
+	defer origin.Close()
+
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:     services.NewVideoService(db, nil, videoSettings, log),
+		Thumbnails: services.NewThumbnailService(log, nil, []int{160, 320}, 1, 1<<20, time.Second),
+	})
+	public := models.Video{UploadID: "pub", UserID: "owner", Title: "t", ThumbnailURL: origin.URL + "/thumbs/ok.png"}
+	private := models.Video{UploadID: "priv", UserID: "owner", Title: "t", Visibility: models.VisibilityPrivate, ThumbnailURL: origin.URL + "/thumbs/ok.png"}
+	broken := models.Video{UploadID: "broken", UserID: "owner", Title: "t", ThumbnailURL: origin.URL + "/thumbs/broken.png"}
+	bare := models.Video{UploadID: "bare", UserID: "owner", Title: "t"}
+	for _, v := range []*models.Video{&public, &private, &broken, &bare} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	get := func(id uint, query, user string) *httptest.ResponseRecorder {
+		return serve(router, adminRequest(http.MethodGet, "/api/v1/videos/"+itoa(id)+"/thumbnail"+query, "", user, ""))
+	}
+
+	t.Run("resized", func(t *testing.T) {
+		w := get(public.ID, "?w=320", "")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" {
+			t.Fatalf("status %d, type %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+		}
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+		if err != nil || cfg.Width != 320 || cfg.Height != 180 {
+			t.Errorf("image %dx%d (%v), want 320x180", cfg.Width, cfg.Height, err)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=31536000, immutable" {
+			t.Errorf("Cache-Control %q", cc)
+		}
+		etag := w.Header().Get("ETag")
+		if etag == "" {
+			t.Fatal("no ETag")
+		}
+
+		req := adminRequest(http.MethodGet, "/api/v1/videos/"+itoa(public.ID)+"/thumbnail?w=320", "", "", "")
+		req.Header.Set("If-None-Match", etag)
+		if w := serve(router, req); w.Code != http.StatusNotModified {
+			t.Errorf("If-None-Match: status %d, want 304", w.Code)
+		}
+		req = adminRequest(http.MethodGet, "/api/v1/videos/"+itoa(public.ID)+"/thumbnail?w=320", "", "", "")
+		req.Header.Set("Range", "bytes=0-9")
+		if w := serve(router, req); w.Code != http.StatusPartialContent || w.Body.Len() != 10 {
+			t.Errorf("Range: status %d with %d bytes, want 206 with 10", w.Code, w.Body.Len())
+		}
+	})
+
+	t.Run("width not allowed", func(t *testing.T) {
+		for _, query := range []string{"?w=321", "", "?w=big"} {
+			w := get(public.ID, query, "")
+			var body struct {
+				Code    string `json:"code"`
+				Details struct {
+					Allowed []int `json:"allowed"`
+				} `json:"details"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if w.Code != http.StatusBadRequest || body.Code != api.CodeInvalidRequest || len(body.Details.Allowed) != 2 {
+				t.Errorf("%q: status %d, body %s; want 400 listing the widths", query, w.Code, w.Body)
+			}
+		}
+	})
+
+	t.Run("private video", func(t *testing.T) {
+		for _, user := range []string{"", "stranger"} {
+			if w := get(private.ID, "?w=160", user); w.Code != http.StatusNotFound {
+				t.Errorf("user %q: status %d, want 404", user, w.Code)
+			}
+		}
+		w := get(private.ID, "?w=160", "owner")
+		if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "private, max-age=3600" {
+			t.Errorf("owner: status %d, Cache-Control %q", w.Code, w.Header().Get("Cache-Control"))
+		}
+	})
+
+	t.Run("resize failure redirects to the original", func(t *testing.T) {
+		w := get(broken.ID, "?w=320", "")
+		if w.Code != http.StatusFound || w.Header().Get("Location") != broken.ThumbnailURL {
+			t.Errorf("status %d, Location %q", w.Code, w.Header().Get("Location"))
+		}
+	})
+
+	t.Run("no thumbnail", func(t *testing.T) {
+		w := get(bare.ID, "?w=320", "")
+		if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), api.CodeThumbnailNotFound) {
+			t.Errorf("status %d: %s", w.Code, w.Body)
+		}
+		if w := get(bare.ID+100, "?w=320", ""); w.Code != http.StatusNotFound {
+			t.Errorf("missing video: status %d", w.Code)
+		}
+	})
+
+	t.Run("flag off redirects", func(t *testing.T) {
+		t.Setenv("FLAG_THUMBNAIL_RESIZE", "false")
+		set, err := flags.New(nil, log, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		flags.SetDefault(set)
+		defer flags.SetDefault(nil)
+		w := get(public.ID, "?w=320", "")
+		if w.Code != http.StatusFound || w.Header().Get("Location") != public.ThumbnailURL {
+			t.Errorf("status %d, Location %q", w.Code, w.Header().Get("Location"))
+		}
+	})
+}
+
+// uploadStorage is a storage client that keeps uploaded blobs in memory
+type uploadStorage struct {
+	services.StorageClient
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (s *uploadStorage) UploadBlob(_ context.Context, blobPath string, data []byte, _ string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[blobPath] = data
+	return "https://blobs.example/" + blobPath, nil
+}
+
+func (s *uploadStorage) DeleteBlob(_ context.Context, blobPath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, blobPath)
+	return nil
+}
+
+// thumbnailUpload builds a multipart upload of data as the file field
+func thumbnailUpload(t *testing.T, videoID uint, data []byte, user string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "thumb.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	form.Close()
+	req := adminRequest(http.MethodPost, "/api/v1/videos/"+itoa(videoID)+"/thumbnail", "", user, "")
+	req.Body = io.NopCloser(&body)
+	req.ContentLength = int64(body.Len())
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestThumbnailSelectAndUpload(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	storage := &uploadStorage{blobs: map[string][]byte{}}
+	loader := services.NewStorageLoader(func() (services.StorageClient, error) { return storage, nil }, 0, log)
+	router := newRouter(api.Dependencies{
+		Videos:            services.NewVideoService(db, loader, videoSettings, log),
+		MaxThumbnailBytes: 64 << 10,
+	})
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Status: models.StatusReady, ThumbnailURL: "https://cdn.example/a.jpg"}
+	db.Create(&video)
+	other := models.Video{UploadID: "up-2", UserID: "owner", Title: "t"}
+	db.Create(&other)
+	candidate := models.VideoThumbnail{VideoID: video.ID, Source: models.ThumbnailFromTranscoder, URL: "https://cdn.example/b.jpg"}
+	foreign := models.VideoThumbnail{VideoID: other.ID, Source: models.ThumbnailFromTranscoder, URL: "https://cdn.example/c.jpg"}
+	db.Create(&candidate)
+	db.Create(&foreign)
+
+	selectPath := "/api/v1/videos/" + itoa(video.ID) + "/thumbnail/select"
+	selects := []struct {
+		name, user, body string
+		status           int
+		code             string
+	}{
+		{"signed out", "", `{"thumbnail_id":` + itoa(candidate.ID) + `}`, http.StatusUnauthorized, api.CodeUnauthorized},
+		{"not the owner", "mallory", `{"thumbnail_id":` + itoa(candidate.ID) + `}`, http.StatusForbidden, api.CodeForbidden},
+		{"missing candidate", "owner", `{"thumbnail_id":999}`, http.StatusNotFound, api.CodeThumbnailNotFound},
+		{"another video's candidate", "owner", `{"thumbnail_id":` + itoa(foreign.ID) + `}`, http.StatusNotFound, api.CodeThumbnailNotFound},
+		{"owner", "owner", `{"thumbnail_id":` + itoa(candidate.ID) + `}`, http.StatusOK, ""},
+	}
+	for _, tt := range selects {
+		w := serve(router, adminRequest(http.MethodPost, selectPath, tt.body, tt.user, ""))
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.code) {
+			t.Errorf("select, %s: status %d: %s; want %d %s", tt.name, w.Code, w.Body, tt.status, tt.code)
+		}
+	}
+	if w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos/999/thumbnail/select", `{"thumbnail_id":1}`, "owner", "")); w.Code != http.StatusNotFound {
+		t.Errorf("select on a missing video: status %d", w.Code)
+	}
+	if w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/"+itoa(video.ID)+"/thumbnails", "", "mallory", "")); w.Code != http.StatusForbidden {
+		t.Errorf("list, not the owner: status %d", w.Code)
+	}
+
+	uploads := []struct {
+		name   string
+		data   []byte
+		user   string
+		status int
+		code   string
+	}{
+		{"not the owner", encodePNG(t, 640, 360), "mallory", http.StatusForbidden, api.CodeForbidden},
+		{"too small", encodePNG(t, 319, 180), "owner", http.StatusBadRequest, api.CodeValidationFailed},
+		{"too large", encodePNG(t, 3841, 2160), "owner", http.StatusBadRequest, api.CodeValidationFailed},
+		{"not an image", []byte("GIF89a"), "owner", http.StatusBadRequest, api.CodeValidationFailed},
+		{"over the size limit", bytes.Repeat([]byte{0}, 64<<10+1), "owner", http.StatusRequestEntityTooLarge, api.CodeRequestTooLarge},
+	}
+	for _, tt := range uploads {
+		w := serve(router, thumbnailUpload(t, video.ID, tt.data, tt.user))
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.code) {
+			t.Errorf("upload, %s: status %d: %s; want %d %s", tt.name, w.Code, w.Body, tt.status, tt.code)
+		}
+	}
+	if len(storage.blobs) != 0 {
+		t.Errorf("refused uploads stored %d blobs", len(storage.blobs))
+	}
+
+	w := serve(router, thumbnailUpload(t, video.ID, encodePNG(t, 640, 360), "owner"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload: status %d: %s", w.Code, w.Body)
+	}
+	var created struct {
+		Thumbnail models.VideoThumbnail `json:"thumbnail"`
+		Video     models.Video          `json:"video"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Thumbnail.Width != 640 || created.Video.ThumbnailURL != created.Thumbnail.URL || len(storage.blobs) != 1 {
+		t.Errorf("upload = %+v with %d blobs stored", created, len(storage.blobs))
+	}
+}
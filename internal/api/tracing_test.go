@@ -0,0 +1,53 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/db"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+	"github.com/streamhive/video-catalog-api/internal/spantest"
+)
+
+func TestGetVideoTraced(t *testing.T) {
+	gdb := dbtest.Open(t)
+	if err := db.Trace(gdb); err != nil {
+		t.Fatal(err)
+	}
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Status: models.StatusReady, Visibility: models.VisibilityPublic}
+	gdb.Create(&video)
+	spans := spantest.Record(t)
+	log := zap.NewNop().Sugar()
+	router := gin.New()
+	router.Use(otelgin.Middleware("video-catalog-api"))
+	api.SetupRoutes(router, api.Dependencies{Videos: services.NewVideoService(gdb, nil, videoSettings, log)}, log)
+
+	if w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/"+itoa(video.ID), "", "", "")); w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	handlers := spantest.Named(spans, "/api/v1/videos/:id")
+	if len(handlers) != 1 || handlers[0].SpanKind() != trace.SpanKindServer {
+		t.Fatalf("handler spans = %v, want one server span named for the route", handlers)
+	}
+	handler := handlers[0].SpanContext()
+	queries := spantest.Named(spans, "db.query")
+	if len(queries) == 0 {
+		t.Fatal("no db.query span")
+	}
+	for _, q := range queries {
+		if q.Parent().SpanID() != handler.SpanID() || q.SpanContext().TraceID() != handler.TraceID() {
+			t.Errorf("db.query span's parent is %s, want the handler span %s", q.Parent().SpanID(), handler.SpanID())
+		}
+		if q.SpanKind() != trace.SpanKindClient {
+			t.Errorf("db.query span kind = %s, want client", q.SpanKind())
+		}
+	}
+}
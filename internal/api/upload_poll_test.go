@@ -0,0 +1,39 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestUploadPollRetryAfterAndInvalidation(t *testing.T) {
+	videos := services.NewVideoService(dbtest.Open(t), nil, videoSettings, zap.NewNop().Sugar())
+	router := newRouter(api.Dependencies{Videos: videos})
+	poll := func() *httptest.ResponseRecorder {
+		return serve(router, httptest.NewRequest(http.MethodGet, "/api/v1/videos/upload/upload-1", nil))
+	}
+
+	w := poll()
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("poll before the event: status %d, want 404", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("404 for a pending upload has no Retry-After")
+	}
+
+	event := &models.UploadedEvent{UploadID: "upload-1", UserID: "owner", Title: "Holiday"}
+	if err := videos.HandleUploadedEvent(context.Background(), event); err != nil {
+		t.Fatalf("HandleUploadedEvent: %v", err)
+	}
+	if w := poll(); w.Code != http.StatusOK {
+		t.Fatalf("poll right after the event: status %d, want 200: %s", w.Code, w.Body)
+	}
+}
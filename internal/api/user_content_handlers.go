@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// DeleteUserContent handles DELETE /api/v1/users/:userID/content - purges a deleted
+// account's catalog footprint (admin only, audited). Returns 202 with the job and
+// the counts it found; a job already pending or running for the user is returned
+// as is.
+func (h *VideoHandler) DeleteUserContent(c *gin.Context) {
+	userID := c.Param("userID")
+	admin := identityFrom(c).ActorID
+	entry, ok := h.beginAudit(c, models.AuditActionDeleteUserContent, userID, "user:"+userID, "")
+	if !ok {
+		return
+	}
+	job, created, err := h.userContentSvc.DeleteUserContent(c.Request.Context(), userID, admin, models.ContentDeletionTriggerAPI)
+	h.finishAudit(c, entry, err)
+	if err != nil {
+		h.log(c).Errorw("Failed to queue user content deletion", "error", err, "userID", userID, "admin", admin)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to delete user content", nil)
+		return
+	}
+	if created {
+		h.log(c).Infow("User content deletion queued by admin", "userID", userID, "deletionID", job.ID, "admin", admin)
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetUserContentDeletion handles GET /api/v1/users/:userID/content-deletions/:deletionID
+// - a content deletion job's progress, including its video purges (admin only)
+func (h *VideoHandler) GetUserContentDeletion(c *gin.Context) {
+	id, ok := contentDeletionID(c)
+	if !ok {
+		return
+	}
+	job, err := h.userContentSvc.GetDeletion(c.Request.Context(), c.Param("userID"), id)
+	if err != nil {
+		h.contentDeletionFailed(c, err, id)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// RetryUserContentDeletion handles POST
+// /api/v1/users/:userID/content-deletions/:deletionID/retry - resumes a failed job
+// where it stopped (admin only)
+func (h *VideoHandler) RetryUserContentDeletion(c *gin.Context) {
+	id, ok := contentDeletionID(c)
+	if !ok {
+		return
+	}
+	job, err := h.userContentSvc.RetryDeletion(c.Request.Context(), c.Param("userID"), id)
+	if err != nil {
+		h.contentDeletionFailed(c, err, id)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func contentDeletionID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("deletionID"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid deletion ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *VideoHandler) contentDeletionFailed(c *gin.Context, err error, id uint) {
+	switch {
+	case errors.Is(err, services.ErrContentDeletionNotFound):
+		respondError(c, http.StatusNotFound, CodeDeletionNotFound, "Content deletion job not found", nil)
+	case errors.Is(err, services.ErrDeletionNotFailed):
+		respondError(c, http.StatusConflict, CodeNotRetryable, "Only a failed content deletion job can be retried", nil)
+	default:
+		h.log(c).Errorw("Failed to get content deletion job", "error", err, "deletionID", id)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get content deletion job", nil)
+	}
+}
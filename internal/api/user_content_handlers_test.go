@@ -0,0 +1,89 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestDeleteUserContent(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	videos := services.NewVideoService(db, nil, videoSettings, log)
+	router := newRouter(api.Dependencies{
+		Videos:      videos,
+		UserContent: services.NewContentDeletionService(db, log, videos, 10),
+		Audit:       services.NewAuditService(db, log),
+	})
+	db.Create(&models.Video{UploadID: "up-1", UserID: "gone", Title: "t"})
+	other := models.Video{UploadID: "up-2", UserID: "owner", Title: "u"}
+	db.Create(&other)
+	db.Create(&models.Comment{VideoID: other.ID, UserID: "gone", Content: "hi", Status: models.CommentVisible})
+
+	for _, tt := range []struct {
+		name, user, roles string
+		status            int
+	}{
+		{"signed out", "", "", http.StatusUnauthorized},
+		{"the user themselves", "gone", "", http.StatusForbidden},
+	} {
+		if w := serve(router, adminRequest(http.MethodDelete, "/api/v1/users/gone/content", "", tt.user, tt.roles)); w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+	}
+
+	w := serve(router, adminRequest(http.MethodDelete, "/api/v1/users/gone/content", "", "admin-1", "admin"))
+	var job models.UserContentDeletion
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil || w.Code != http.StatusAccepted || job.ID == 0 {
+		t.Fatalf("delete: status %d: %s", w.Code, w.Body)
+	}
+	if job.UserID != "gone" || job.ActorID != "admin-1" || job.Trigger != models.ContentDeletionTriggerAPI || job.VideosTotal != 1 || job.CommentsTotal != 1 {
+		t.Errorf("job = %+v", job)
+	}
+	var audited int64
+	db.Model(&models.AuditLog{}).Where("action = ? AND subject_id = ?", models.AuditActionDeleteUserContent, "gone").Count(&audited)
+	if audited != 1 {
+		t.Errorf("%d audit entries, want 1", audited)
+	}
+
+	// Progress is readable until the job completes
+	path := "/api/v1/users/gone/content-deletions/" + itoa(job.ID)
+	deadline := time.Now().Add(time.Second)
+	for {
+		w = serve(router, adminRequest(http.MethodGet, path, "", "admin-1", "admin"))
+		json.Unmarshal(w.Body.Bytes(), &job)
+		if w.Code != http.StatusOK || job.Status == models.DeletionCompleted || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if job.Status != models.DeletionCompleted || job.VideosDeleted != 1 || job.CommentsAnonymized != 1 || job.VideoPurges[models.DeletionPending] != 1 {
+		t.Fatalf("progress: status %d: %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name, method, path, user, roles string
+		status                          int
+		code                            string
+	}{
+		{"progress as a non-admin", http.MethodGet, path, "gone", "", http.StatusForbidden, api.CodeForbidden},
+		{"progress under another user", http.MethodGet, "/api/v1/users/owner/content-deletions/" + itoa(job.ID), "admin-1", "admin", http.StatusNotFound, api.CodeDeletionNotFound},
+		{"progress of a bad id", http.MethodGet, "/api/v1/users/gone/content-deletions/abc", "admin-1", "admin", http.StatusBadRequest, api.CodeInvalidRequest},
+		{"retry a completed job", http.MethodPost, path + "/retry", "admin-1", "admin", http.StatusConflict, api.CodeNotRetryable},
+		{"retry a missing job", http.MethodPost, "/api/v1/users/gone/content-deletions/999/retry", "admin-1", "admin", http.StatusNotFound, api.CodeDeletionNotFound},
+	}
+	for _, tt := range tests {
+		w := serve(router, adminRequest(tt.method, tt.path, "", tt.user, tt.roles))
+		if w.Code != tt.status || errorCode(w) != tt.code {
+			t.Errorf("%s: %d %s, want %d %s", tt.name, w.Code, w.Body, tt.status, tt.code)
+		}
+	}
+}
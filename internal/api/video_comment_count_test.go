@@ -0,0 +1,110 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/cursor"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// countQueries counts the statements db reads with from now on
+func countQueries(t *testing.T, db *gorm.DB) *atomic.Int64 {
+	t.Helper()
+	var n atomic.Int64
+	count := func(*gorm.DB) { n.Add(1) }
+	for name, err := range map[string]error{
+		"query": db.Callback().Query().After("gorm:query").Register("test:count_query", count),
+		"row":   db.Callback().Row().After("gorm:row").Register("test:count_row", count),
+		"raw":   db.Callback().Raw().After("gorm:raw").Register("test:count_raw", count),
+	} {
+		if err != nil {
+			t.Fatalf("register %s counter: %v", name, err)
+		}
+	}
+	return &n
+}
+
+func TestVideoListCommentCounts(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	comments := services.NewCommentService(db, commentSettings, log)
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+		Cursors:   cursor.NewCodec([]byte("secret"), time.Hour),
+	})
+	ctx := context.Background()
+	want := map[uint]int64{}
+	for i := 0; i < 20; i++ {
+		video := models.Video{UploadID: fmt.Sprintf("up-%02d", i), UserID: "owner", Title: "t", Status: models.StatusReady, CommentsEnabled: true}
+		db.Create(&video)
+		for j := 0; j < i%4; j++ {
+			if _, err := comments.AddComment(ctx, video.ID, "viewer", "", "nice", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want[video.ID] = int64(i % 4)
+		if i%5 == 0 {
+			// Neither a deleted comment nor one held for moderation counts
+			deleted, err := comments.AddComment(ctx, video.ID, "viewer", "", "oops", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := comments.DeleteComment(ctx, deleted.ID, "viewer", true); err != nil {
+				t.Fatal(err)
+			}
+			db.Create(&models.Comment{VideoID: video.ID, UserID: "viewer", Content: "held", Status: models.CommentPending})
+		}
+	}
+
+	queries := countQueries(t, db)
+	list := func(perPage int) (int64, map[uint]int64) {
+		t.Helper()
+		before := queries.Load()
+		w := serve(router, adminRequest(http.MethodGet, fmt.Sprintf("/api/v1/videos?per_page=%d", perPage), "", "", ""))
+		if w.Code != http.StatusOK {
+			t.Fatalf("list: status %d: %s", w.Code, w.Body)
+		}
+		var page struct {
+			Videos []struct {
+				ID           uint  `json:"id"`
+				CommentCount int64 `json:"comment_count"`
+			} `json:"videos"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		got := map[uint]int64{}
+		for _, v := range page.Videos {
+			got[v.ID] = v.CommentCount
+		}
+		return queries.Load() - before, got
+	}
+
+	one, _ := list(1)
+	twenty, got := list(20)
+	if len(got) != 20 {
+		t.Fatalf("listed %d videos, want 20", len(got))
+	}
+	for id, n := range want {
+		if got[id] != n {
+			t.Errorf("video %d: comment_count %d, want %d", id, got[id], n)
+		}
+	}
+	// Counts come with the rows, not from a query per video
+	if one == 0 || twenty != one {
+		t.Errorf("a page of 20 took %d queries, a page of 1 took %d", twenty, one)
+	}
+}
@@ -0,0 +1,161 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/cursor"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// cardFields are the only members a compact video may have
+var cardFields = []string{"id", "title", "thumbnail_url", "duration", "username", "view_count", "like_count", "created_at"}
+
+// recordSelects collects the SQL of every query db runs from now on
+func recordSelects(t *testing.T, db *gorm.DB) *[]string {
+	t.Helper()
+	var statements []string
+	if err := db.Callback().Query().After("gorm:query").Register("test:record_sql", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return &statements
+}
+
+func compactRouter(t *testing.T) (*gorm.DB, *gin.Engine) {
+	t.Helper()
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		db.Create(&models.Video{
+			UploadID: fmt.Sprintf("up-%02d", i), UserID: "owner", Username: "olive", Title: fmt.Sprintf("video %d", i),
+			Description: strings.Repeat("words ", 50), Status: models.StatusReady, Visibility: models.VisibilityPublic,
+			VideoCodec: "h264", VideoBitrate: 4500, AudioCodec: "aac", AudioBitrate: 128, Duration: 61.5,
+			ThumbnailURL: "https://cdn.example/thumb.jpg", ViewCount: int64(i), CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	router := gin.New()
+	router.Use(api.Compress(1024))
+	api.SetupRoutes(router, api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+		Cursors:   cursor.NewCodec([]byte("secret"), time.Hour),
+	}, log)
+	return db, router
+}
+
+// decodeCards reads a compact page, checking each video has only card fields
+func decodeCards(t *testing.T, what, body string) (videos []map[string]interface{}, next string) {
+	t.Helper()
+	var page struct {
+		Videos     []map[string]interface{} `json:"videos"`
+		NextCursor string                   `json:"next_cursor"`
+	}
+	if err := json.Unmarshal([]byte(body), &page); err != nil {
+		t.Fatalf("%s: decode %s: %v", what, body, err)
+	}
+	for _, v := range page.Videos {
+		if len(v) != len(cardFields) {
+			t.Errorf("%s: video has %d members, want %d: %v", what, len(v), len(cardFields), v)
+		}
+		for _, field := range cardFields {
+			if _, ok := v[field]; !ok {
+				t.Errorf("%s: video is missing %s: %v", what, field, v)
+			}
+		}
+		for _, field := range []string{"video_codec", "video_bitrate", "audio_codec", "audio_bitrate", "description", "hls_master_url"} {
+			if _, ok := v[field]; ok {
+				t.Errorf("%s: compact video has %s", what, field)
+			}
+		}
+	}
+	return page.Videos, page.NextCursor
+}
+
+func TestCompactVideoLists(t *testing.T) {
+	db, router := compactRouter(t)
+	statements := recordSelects(t, db)
+
+	w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos?view=compact&per_page=10", "", "", ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("compact list: %d %s", w.Code, w.Body)
+	}
+	videos, next := decodeCards(t, "compact list", w.Body.String())
+	if len(videos) != 10 || videos[0]["title"] != "video 29" || videos[0]["username"] != "olive" || videos[0]["duration"] != 61.5 {
+		t.Fatalf("compact list = %v", videos)
+	}
+	// The projection is in the SQL, not only the JSON
+	var selected bool
+	for _, sql := range *statements {
+		if strings.Contains(sql, "ORDER BY") {
+			selected = true
+			if strings.Contains(sql, "*") || strings.Contains(sql, "video_codec") || strings.Contains(sql, "description") {
+				t.Errorf("compact list read %s", sql)
+			}
+		}
+	}
+	if !selected {
+		t.Errorf("no page query among %v", *statements)
+	}
+
+	// Cursor pages stay compact
+	if next == "" {
+		t.Fatal("no next_cursor on the first compact page")
+	}
+	w = serve(router, adminRequest(http.MethodGet, "/api/v1/videos?view=compact&cursor="+next, "", "", ""))
+	if videos, _ := decodeCards(t, "compact cursor page", w.Body.String()); w.Code != http.StatusOK || len(videos) != 10 || videos[0]["title"] != "video 19" {
+		t.Errorf("compact cursor page: %d %v", w.Code, videos)
+	}
+
+	w = serve(router, adminRequest(http.MethodGet, "/api/v1/videos/search?view=compact", "", "", ""))
+	if videos, _ := decodeCards(t, "compact search", w.Body.String()); w.Code != http.StatusOK || len(videos) != 20 {
+		t.Errorf("compact search: %d, %d videos", w.Code, len(videos))
+	}
+
+	// The full view still carries the encoding details
+	w = serve(router, adminRequest(http.MethodGet, "/api/v1/videos?per_page=1", "", "", ""))
+	if !strings.Contains(w.Body.String(), `"video_codec":"h264"`) {
+		t.Errorf("full list = %s", w.Body)
+	}
+	w = serve(router, adminRequest(http.MethodGet, "/api/v1/videos?view=tiny", "", "", ""))
+	if w.Code != http.StatusBadRequest || errorCode(w) != api.CodeInvalidRequest {
+		t.Errorf("unknown view: %d %s, want 400", w.Code, w.Body)
+	}
+}
+
+func TestVideoListCompressed(t *testing.T) {
+	_, router := compactRouter(t)
+	req := adminRequest(http.MethodGet, "/api/v1/videos?per_page=30", "", "", "")
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := serve(router, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("list with Accept-Encoding: gzip: %d, Content-Encoding %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+	plain := gunzip(t, w.Body.Bytes())
+	if w.Body.Len() >= len(plain) {
+		t.Errorf("gzipped body %d bytes, plain %d", w.Body.Len(), len(plain))
+	}
+	var page videoPage
+	if err := json.Unmarshal([]byte(plain), &page); err != nil || len(page.Videos) != 30 {
+		t.Errorf("decoded %d videos, %v", len(page.Videos), err)
+	}
+
+	// Without the header the list goes out as it is
+	w = serve(router, adminRequest(http.MethodGet, "/api/v1/videos?per_page=30", "", "", ""))
+	if w.Header().Get("Content-Encoding") != "" || !json.Valid(w.Body.Bytes()) {
+		t.Errorf("list without Accept-Encoding: Content-Encoding %q", w.Header().Get("Content-Encoding"))
+	}
+}
@@ -0,0 +1,84 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestCreateVideoRetryAfterSuccess(t *testing.T) {
+	db := dbtest.Open(t)
+	router := newRouter(api.Dependencies{Videos: services.NewVideoService(db, nil, videoSettings, zap.NewNop().Sugar())})
+	const body = `{"upload_id":"up-1","title":"Holiday"}`
+
+	w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos", body, "owner", ""))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("first POST: status %d: %s", w.Code, w.Body)
+	}
+	var created struct {
+		ID uint `json:"id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	type conflict struct {
+		Code    string `json:"code"`
+		Details struct {
+			VideoID uint `json:"video_id"`
+		} `json:"details"`
+	}
+	// The client timed out and retries the same POST
+	w = serve(router, adminRequest(http.MethodPost, "/api/v1/videos", body, "owner", ""))
+	var retry conflict
+	json.Unmarshal(w.Body.Bytes(), &retry)
+	if w.Code != http.StatusConflict || retry.Code != api.CodeDuplicateUploadID || retry.Details.VideoID != created.ID {
+		t.Fatalf("retry: status %d, body %s; want 409 pointing at video %d", w.Code, w.Body, created.ID)
+	}
+
+	// Someone else's upload ID conflicts too, without revealing their video
+	w = serve(router, adminRequest(http.MethodPost, "/api/v1/videos", body, "mallory", ""))
+	var other conflict
+	json.Unmarshal(w.Body.Bytes(), &other)
+	if w.Code != http.StatusConflict || other.Details.VideoID != 0 {
+		t.Errorf("other user: status %d, body %s; want 409 without a video ID", w.Code, w.Body)
+	}
+}
+
+func TestInvalidTagsRejected(t *testing.T) {
+	db := dbtest.Open(t)
+	router := newRouter(api.Dependencies{Videos: services.NewVideoService(db, nil, videoSettings, zap.NewNop().Sugar())})
+	type rejection struct {
+		Code    string `json:"code"`
+		Details []struct {
+			Field string `json:"field"`
+		} `json:"details"`
+	}
+
+	w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-1","title":"t","tags":["ok","a,b"]}`, "owner", ""))
+	var created rejection
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusBadRequest || created.Code != api.CodeValidationFailed || len(created.Details) != 1 || created.Details[0].Field != "tags[1]" {
+		t.Fatalf("create: status %d: %s; want 400 naming tags[1]", w.Code, w.Body)
+	}
+
+	w = serve(router, adminRequest(http.MethodPost, "/api/v1/videos", `{"upload_id":"up-1","title":"t","tags":[" Music","music"]}`, "owner", ""))
+	var video struct {
+		ID   uint     `json:"id"`
+		Tags []string `json:"tags"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &video)
+	if w.Code != http.StatusCreated || len(video.Tags) != 1 || video.Tags[0] != "music" {
+		t.Fatalf("create: status %d: %s; want the tags normalized", w.Code, w.Body)
+	}
+	w = serve(router, adminRequest(http.MethodPut, "/api/v1/videos/"+itoa(video.ID), `{"tags":["{x}"]}`, "owner", ""))
+	var updated rejection
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusBadRequest || len(updated.Details) != 1 || updated.Details[0].Field != "tags[0]" {
+		t.Errorf("update: status %d: %s; want 400 naming tags[0]", w.Code, w.Body)
+	}
+}
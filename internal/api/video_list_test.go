@@ -0,0 +1,283 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/cursor"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+type videoPage struct {
+	Videos []struct {
+		ID uint `json:"id"`
+	} `json:"videos"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor"`
+	Code       string `json:"code"`
+}
+
+// videoListRouter serves 250 videos by owner, one in ten private, whose
+// created_at repeats in runs of seven
+func videoListRouter(t *testing.T) http.Handler {
+	t.Helper()
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	videos := make([]models.Video, 250)
+	for i := range videos {
+		videos[i] = models.Video{
+			UploadID:  fmt.Sprintf("up-%03d", i),
+			UserID:    "owner",
+			Title:     "t",
+			Status:    models.StatusReady,
+			CreatedAt: base.Add(time.Duration(i/7) * time.Minute),
+		}
+		if i%10 == 0 {
+			videos[i].Visibility = models.VisibilityPrivate
+		}
+	}
+	if err := db.CreateInBatches(videos, 100).Error; err != nil {
+		t.Fatal(err)
+	}
+	return newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+		Cursors:   cursor.NewCodec([]byte("secret"), time.Hour),
+	})
+}
+
+func getVideoPage(t *testing.T, router http.Handler, path, user string) (int, videoPage) {
+	t.Helper()
+	w := serve(router, adminRequest(http.MethodGet, path, "", user, ""))
+	var p videoPage
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("%s: %v: %s", path, err, w.Body)
+	}
+	return w.Code, p
+}
+
+// walkVideos follows next_cursor from the first page of path and returns the IDs
+// seen, failing on a repeat
+func walkVideos(t *testing.T, router http.Handler, path, user string) []uint {
+	t.Helper()
+	var ids []uint
+	seen := map[uint]bool{}
+	next := path
+	for pages := 0; next != ""; pages++ {
+		if pages > 100 {
+			t.Fatal("paging doesn't end")
+		}
+		code, p := getVideoPage(t, router, next, user)
+		if code != http.StatusOK {
+			t.Fatalf("%s: status %d (%s)", next, code, p.Code)
+		}
+		for _, v := range p.Videos {
+			if seen[v.ID] {
+				t.Fatalf("video %d returned twice", v.ID)
+			}
+			seen[v.ID] = true
+			ids = append(ids, v.ID)
+		}
+		next = ""
+		if p.NextCursor != "" {
+			next = path + "&cursor=" + url.QueryEscape(p.NextCursor)
+		}
+	}
+	return ids
+}
+
+func TestVideoListCursorWalk(t *testing.T) {
+	router := videoListRouter(t)
+	tests := []struct {
+		path, user string
+		want       int
+	}{
+		{"/api/v1/videos?per_page=20", "", 225},
+		{"/api/v1/videos?per_page=20&view=compact", "", 225},
+		{"/api/v1/users/owner/videos?per_page=20", "owner", 250},
+		{"/api/v1/users/owner/videos?per_page=33", "owner", 250},
+		{"/api/v1/users/owner/videos?per_page=20", "stranger", 225},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path+" as "+tc.user, func(t *testing.T) {
+			ids := walkVideos(t, router, tc.path, tc.user)
+			if len(ids) != tc.want {
+				t.Errorf("walked %d videos, want %d", len(ids), tc.want)
+			}
+			// Same time, higher ID first
+			for i := 1; i < len(ids); i++ {
+				if ids[i] > ids[i-1] {
+					t.Fatalf("video %d follows %d; want created_at, id descending", ids[i], ids[i-1])
+				}
+			}
+		})
+	}
+}
+
+func TestVideoListOffsetPagingStillWorks(t *testing.T) {
+	router := videoListRouter(t)
+	code, first := getVideoPage(t, router, "/api/v1/videos?page=1&per_page=20", "")
+	if code != http.StatusOK || first.Total != 225 || len(first.Videos) != 20 || first.NextCursor == "" {
+		t.Fatalf("page 1: status %d, total %d, %d videos, cursor %q", code, first.Total, len(first.Videos), first.NextCursor)
+	}
+	_, second := getVideoPage(t, router, "/api/v1/videos?page=2&per_page=20", "")
+	_, viaCursor := getVideoPage(t, router, "/api/v1/videos?cursor="+url.QueryEscape(first.NextCursor), "")
+	if len(second.Videos) != 20 || len(viaCursor.Videos) != 20 {
+		t.Fatalf("page 2 has %d videos, the cursor page %d", len(second.Videos), len(viaCursor.Videos))
+	}
+	for i := range second.Videos {
+		if second.Videos[i].ID != viaCursor.Videos[i].ID {
+			t.Fatalf("page 2 and the cursor from page 1 disagree at %d: %d vs %d", i, second.Videos[i].ID, viaCursor.Videos[i].ID)
+		}
+	}
+	// The last offset page offers no cursor
+	if _, last := getVideoPage(t, router, "/api/v1/videos?page=12&per_page=20", ""); len(last.Videos) != 5 || last.NextCursor != "" {
+		t.Errorf("last page: %d videos, cursor %q", len(last.Videos), last.NextCursor)
+	}
+}
+
+func TestVideoListCursorRejected(t *testing.T) {
+	router := videoListRouter(t)
+	_, own := getVideoPage(t, router, "/api/v1/users/owner/videos?per_page=20", "owner")
+	if own.NextCursor == "" {
+		t.Fatal("no cursor")
+	}
+	token := url.QueryEscape(own.NextCursor)
+	tests := []struct {
+		name, path, user string
+	}{
+		// The owner's cursor includes private videos; it is bound to that listing
+		{"owner cursor as stranger", "/api/v1/users/owner/videos?cursor=" + token, "stranger"},
+		{"owner cursor on the public list", "/api/v1/videos?cursor=" + token, "owner"},
+		{"with a sort", "/api/v1/users/owner/videos?sort=views&cursor=" + token, "owner"},
+		{"garbage", "/api/v1/videos?cursor=not-a-cursor", ""},
+	}
+	for _, tc := range tests {
+		if code, p := getVideoPage(t, router, tc.path, tc.user); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d (%s), want 400", tc.name, code, p.Code)
+		}
+	}
+}
+
+func TestVideoListFilters(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	for i, v := range []models.Video{
+		{Category: "music", Status: models.StatusReady},
+		{Category: "music", Status: models.StatusReady},
+		{Category: "music", Status: models.StatusProcessing},
+		{Category: "music", Status: models.StatusReady, Visibility: models.VisibilityPrivate},
+		{Category: "gaming", Status: models.StatusReady},
+	} {
+		v.UploadID, v.UserID, v.Title = fmt.Sprintf("up-%d", i), "owner", "t"
+		db.Create(&v)
+	}
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+		Cursors:   cursor.NewCodec([]byte("secret"), time.Hour),
+	})
+
+	for _, tt := range []struct {
+		path, user string
+		want       int
+	}{
+		{"/api/v1/videos?category=music", "", 3},
+		{"/api/v1/videos?category=music&status=ready", "", 2},
+		{"/api/v1/videos?category=music&status=ready&per_page=1", "", 2},
+		{"/api/v1/videos?status=processing", "", 1},
+		{"/api/v1/users/owner/videos?category=music&status=ready", "owner", 3},
+		{"/api/v1/users/owner/videos?category=music&status=ready", "stranger", 2},
+	} {
+		code, p := getVideoPage(t, router, tt.path, tt.user)
+		if code != http.StatusOK || p.Total != int64(tt.want) {
+			t.Errorf("%s as %q: status %d, total %d, want %d", tt.path, tt.user, code, p.Total, tt.want)
+		}
+	}
+
+	w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos?status=published", "", "", ""))
+	var body struct {
+		Code    string `json:"code"`
+		Details struct {
+			AllowedStatuses []string `json:"allowed_statuses"`
+		} `json:"details"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusBadRequest || body.Code != "invalid_status" || len(body.Details.AllowedStatuses) != len(models.VideoStatuses) {
+		t.Errorf("unknown status: %d %s", w.Code, w.Body)
+	}
+
+	// A cursor is only good for the filters it was issued under
+	_, first := getVideoPage(t, router, "/api/v1/videos?category=music&per_page=1", "")
+	if first.NextCursor == "" {
+		t.Fatal("no cursor on a partial page")
+	}
+	if code, _ := getVideoPage(t, router, "/api/v1/videos?category=gaming&cursor="+url.QueryEscape(first.NextCursor), ""); code != http.StatusBadRequest {
+		t.Errorf("cursor with other filters: status %d, want 400", code)
+	}
+	ids := walkVideos(t, router, "/api/v1/videos?category=music&status=ready&per_page=1", "")
+	if len(ids) != 2 {
+		t.Errorf("cursor walk of the filtered list saw %d videos, want 2", len(ids))
+	}
+}
+
+func TestVideoListSort(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	for i, title := range []string{"b", "c", "a", "b"} {
+		db.Create(&models.Video{UploadID: fmt.Sprintf("up-%d", i), UserID: "owner", Title: title})
+	}
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+		Cursors:   cursor.NewCodec([]byte("secret"), time.Hour),
+	})
+
+	for path, want := range map[string]string{
+		"/api/v1/videos?sort=title&order=asc":        "a:3 b:1 b:4 c:2",
+		"/api/v1/videos?sort=title&order=desc":       "c:2 b:4 b:1 a:3",
+		"/api/v1/users/owner/videos?sort=title":      "c:2 b:4 b:1 a:3",
+		"/api/v1/videos/search?sort=title&order=asc": "a:3 b:1 b:4 c:2",
+	} {
+		w := serve(router, adminRequest(http.MethodGet, path, "", "owner", ""))
+		var body struct {
+			Videos []struct {
+				ID    uint   `json:"id"`
+				Title string `json:"title"`
+			} `json:"videos"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", path, w.Code, w.Body)
+		}
+		got := ""
+		for _, v := range body.Videos {
+			got += fmt.Sprintf(" %s:%d", v.Title, v.ID)
+		}
+		if got != " "+want {
+			t.Errorf("%s =%s, want %s", path, got, want)
+		}
+	}
+
+	for _, path := range []string{
+		"/api/v1/videos?sort=uploaded",
+		"/api/v1/videos?sort=title&order=sideways",
+		"/api/v1/videos?sort=title%3B%20DROP%20TABLE%20videos",
+		"/api/v1/users/owner/videos?sort=uploaded",
+		"/api/v1/videos/search?sort=uploaded",
+	} {
+		code, p := getVideoPage(t, router, path, "owner")
+		if code != http.StatusBadRequest || p.Code != "invalid_sort" {
+			t.Errorf("%s: status %d code %q, want 400 invalid_sort", path, code, p.Code)
+		}
+	}
+}
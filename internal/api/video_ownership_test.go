@@ -0,0 +1,49 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestVideoWritesRequireOwner(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		user   string
+		status int
+	}{
+		{"update without a user", http.MethodPut, `{"title":"renamed"}`, "", http.StatusUnauthorized},
+		{"update by another user", http.MethodPut, `{"title":"renamed"}`, "mallory", http.StatusForbidden},
+		{"update by the owner", http.MethodPut, `{"title":"renamed"}`, "owner", http.StatusOK},
+		{"delete without a user", http.MethodDelete, "", "", http.StatusUnauthorized},
+		{"delete by another user", http.MethodDelete, "", "mallory", http.StatusForbidden},
+		{"delete by the owner", http.MethodDelete, "", "owner", http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t)
+			router := newRouter(api.Dependencies{Videos: services.NewVideoService(db, nil, videoSettings, zap.NewNop().Sugar())})
+			video := models.Video{UploadID: "up", UserID: "owner", Title: "original", Status: models.StatusReady}
+			db.Create(&video)
+
+			w := serve(router, adminRequest(tt.method, "/api/v1/videos/"+itoa(video.ID), tt.body, tt.user, ""))
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			var after models.Video
+			db.Unscoped().First(&after, video.ID)
+			changed := after.Title != "original" || after.DeletedAt.Valid
+			if changed != (tt.status < 300) {
+				t.Errorf("video changed = %v after a %d (title %q, deleted %v)", changed, w.Code, after.Title, after.DeletedAt.Valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// PatchVideo handles PATCH /api/v1/videos/:id - a JSON Merge Patch (RFC 7386) of
+// the video, for changes PUT can't express: null clears a field and arrays replace
+// it. Takes If-Match like PUT.
+func (h *VideoHandler) PatchVideo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+
+	requester := currentUser(c)
+	if requester == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID required", nil)
+		return
+	}
+
+	// Plain JSON is accepted too; a merge patch is an ordinary JSON object
+	if ct := c.ContentType(); ct != models.MergePatchContentType && ct != "application/json" {
+		respondError(c, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Content-Type must be "+models.MergePatchContentType, nil)
+		return
+	}
+
+	ifVersion, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if respondTooLarge(c, err) {
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Failed to read request body", nil)
+		return
+	}
+	req, fields := models.ParseVideoMergePatch(body)
+	if fields != nil {
+		respondFieldErrors(c, "Invalid merge patch", fields)
+		return
+	}
+
+	h.saveVideoUpdate(c, uint(id), requester, ifVersion, req)
+}
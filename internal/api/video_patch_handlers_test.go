@@ -0,0 +1,93 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestPatchVideo(t *testing.T) {
+	db := dbtest.Open(t)
+	router := newRouter(api.Dependencies{Videos: services.NewVideoService(db, nil, videoSettings, zap.NewNop().Sugar())})
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "Holiday", Description: "Beach trip",
+		TagsList: []string{"beach", "summer"}, Visibility: models.VisibilityPrivate, CommentsEnabled: true}
+	db.Create(&video)
+	path := "/api/v1/videos/" + itoa(video.ID)
+	patch := func(body, contentType, user string) *httptest.ResponseRecorder {
+		req := adminRequest(http.MethodPatch, path, body, user, "")
+		req.Header.Set("Content-Type", contentType)
+		return serve(router, req)
+	}
+	current := func() models.Video {
+		var v models.Video
+		db.First(&v, video.ID)
+		return v
+	}
+
+	steps := []struct {
+		name  string
+		body  string
+		check func(v models.Video) bool
+	}{
+		{"clear description", `{"description":null}`, func(v models.Video) bool {
+			return v.Description == "" && v.Title == "Holiday" && len(v.TagsList) == 2 && v.Visibility == models.VisibilityPrivate
+		}},
+		{"empty tags", `{"tags":[]}`, func(v models.Video) bool { return len(v.TagsList) == 0 && v.Description == "" }},
+		{"replace tags", `{"tags":["Sea"]}`, func(v models.Video) bool { return len(v.TagsList) == 1 && v.TagsList[0] == "sea" }},
+		{"clear tags", `{"tags":null}`, func(v models.Video) bool { return len(v.TagsList) == 0 }},
+		{"is_private false", `{"is_private":false}`, func(v models.Video) bool { return v.Visibility == models.VisibilityPublic }},
+		{"absent members untouched", `{"title":"Renamed"}`, func(v models.Video) bool {
+			return v.Title == "Renamed" && v.Visibility == models.VisibilityPublic && v.CommentsEnabled
+		}},
+	}
+	for _, step := range steps {
+		w := patch(step.body, models.MergePatchContentType, "owner")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", step.name, w.Code, w.Body)
+		}
+		if v := current(); !step.check(v) {
+			t.Errorf("%s: video is now %+v", step.name, v)
+		}
+	}
+
+	refused := []struct {
+		name, body, contentType, user string
+		status                        int
+		code                          string
+	}{
+		{"null title", `{"title":null}`, models.MergePatchContentType, "owner", http.StatusBadRequest, api.CodeValidationFailed},
+		{"read-only member", `{"view_count":9}`, models.MergePatchContentType, "owner", http.StatusBadRequest, api.CodeValidationFailed},
+		{"not an object", `["x"]`, models.MergePatchContentType, "owner", http.StatusBadRequest, api.CodeValidationFailed},
+		{"wrong media type", `{"title":"x"}`, "text/plain", "owner", http.StatusUnsupportedMediaType, api.CodeUnsupportedMediaType},
+		{"not the owner", `{"title":"x"}`, models.MergePatchContentType, "mallory", http.StatusForbidden, ""},
+		{"anonymous", `{"title":"x"}`, models.MergePatchContentType, "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range refused {
+		w := patch(tt.body, tt.contentType, tt.user)
+		if w.Code != tt.status || tt.code != "" && errorCode(w) != tt.code {
+			t.Errorf("%s: status %d %q, want %d %q", tt.name, w.Code, errorCode(w), tt.status, tt.code)
+		}
+	}
+	if v := current(); v.Title != "Renamed" {
+		t.Errorf("title %q after refused patches", v.Title)
+	}
+
+	// Plain JSON is a merge patch too, and PUT still works
+	if w := patch(`{"description":"Back again"}`, "application/json", "owner"); w.Code != http.StatusOK {
+		t.Errorf("application/json patch: status %d: %s", w.Code, w.Body)
+	}
+	w := serve(router, adminRequest(http.MethodPut, path, `{"title":"Put","description":null}`, "owner", ""))
+	var put models.Video
+	json.Unmarshal(w.Body.Bytes(), &put)
+	if w.Code != http.StatusOK || put.Title != "Put" || put.Description != "Back again" {
+		t.Errorf("PUT: status %d: %s; want null to leave the description alone", w.Code, w.Body)
+	}
+}
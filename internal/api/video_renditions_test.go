@@ -0,0 +1,66 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/cursor"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestVideoRenditionsJSON(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+		Cursors:   cursor.NewCodec([]byte("secret"), time.Hour),
+	})
+	var ids []uint
+	for i, n := range []int{1, 3} {
+		video := models.Video{UploadID: fmt.Sprintf("up-%d", i), UserID: "owner", Title: "t", Status: models.StatusReady}
+		db.Create(&video)
+		for j := 0; j < n; j++ {
+			db.Create(&models.VideoRendition{VideoID: video.ID, Name: fmt.Sprintf("r%d", j), Bandwidth: int64(j), PlaylistURL: "https://cdn/r.m3u8", FileSize: 10})
+		}
+		ids = append(ids, video.ID)
+	}
+
+	queries := countQueries(t, db)
+	get := func(id uint) (int64, []map[string]interface{}) {
+		t.Helper()
+		before := queries.Load()
+		w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/"+itoa(id), "", "", ""))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %d: status %d: %s", id, w.Code, w.Body)
+		}
+		var body struct {
+			Renditions []map[string]interface{} `json:"renditions"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return queries.Load() - before, body.Renditions
+	}
+	one, _ := get(ids[0])
+	three, renditions := get(ids[1])
+	if len(renditions) != 3 || renditions[0]["name"] != "r2" || renditions[0]["playlist_url"] != "https://cdn/r.m3u8" || renditions[0]["file_size"] != 10.0 {
+		t.Errorf("renditions = %v", renditions)
+	}
+	// Loaded in one query however many there are
+	if three != one {
+		t.Errorf("3 renditions took %d queries, 1 took %d", three, one)
+	}
+
+	w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos", "", "", ""))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "renditions") {
+		t.Errorf("list: status %d, want no renditions: %s", w.Code, w.Body)
+	}
+}
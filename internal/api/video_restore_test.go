@@ -0,0 +1,121 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestRestoreVideoUploadIDTaken(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{Videos: services.NewVideoService(db, nil, videoSettings, log), Reactions: services.NewReactionService(db, log)})
+	const body = `{"upload_id":"up-1","title":"Holiday"}`
+	create := func() uint {
+		t.Helper()
+		w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos", body, "owner", ""))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("POST: status %d: %s", w.Code, w.Body)
+		}
+		var created struct {
+			ID uint `json:"id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &created)
+		return created.ID
+	}
+	remove := func(id uint) {
+		t.Helper()
+		if w := serve(router, adminRequest(http.MethodDelete, "/api/v1/videos/"+itoa(id), "", "owner", "")); w.Code >= 300 {
+			t.Fatalf("DELETE %d: status %d: %s", id, w.Code, w.Body)
+		}
+	}
+
+	// The owner deletes a video and uploads it again under the same upload ID
+	old := create()
+	remove(old)
+	replacement := create()
+
+	w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos/"+itoa(old)+"/restore", "", "owner", ""))
+	var conflict struct {
+		Code    string `json:"code"`
+		Details struct {
+			VideoID uint `json:"video_id"`
+		} `json:"details"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &conflict)
+	if w.Code != http.StatusConflict || conflict.Code != api.CodeDuplicateUploadID || conflict.Details.VideoID != replacement {
+		t.Fatalf("restore over a live video: status %d, body %s; want 409 pointing at video %d", w.Code, w.Body, replacement)
+	}
+
+	// With the replacement gone too, the original comes back
+	remove(replacement)
+	if w := serve(router, adminRequest(http.MethodPost, "/api/v1/videos/"+itoa(old)+"/restore", "", "owner", "")); w.Code != http.StatusOK {
+		t.Fatalf("restore: status %d: %s", w.Code, w.Body)
+	}
+	if w := serve(router, adminRequest(http.MethodGet, "/api/v1/videos/"+itoa(old), "", "owner", "")); w.Code != http.StatusOK {
+		t.Errorf("restored video: status %d", w.Code)
+	}
+}
+
+func TestDeleteAndRestoreVideo(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{Videos: services.NewVideoService(db, nil, videoSettings, log), Reactions: services.NewReactionService(db, log)})
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t"}
+	db.Create(&video)
+	path := "/api/v1/videos/" + itoa(video.ID)
+	call := func(method, target, user string) *httptest.ResponseRecorder {
+		return serve(router, adminRequest(method, target, "", user, ""))
+	}
+
+	if w := call(http.MethodDelete, path, "mallory"); w.Code != http.StatusForbidden {
+		t.Errorf("delete by another user: status %d", w.Code)
+	}
+	w := call(http.MethodDelete, path, "owner")
+	var deleted struct {
+		Status     string    `json:"status"`
+		PurgeAfter time.Time `json:"purge_after"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &deleted)
+	if w.Code != http.StatusAccepted || deleted.Status != models.DeletionPending || !deleted.PurgeAfter.After(time.Now()) {
+		t.Fatalf("delete: %d %s", w.Code, w.Body)
+	}
+	if w := call(http.MethodGet, path, "owner"); w.Code != http.StatusNotFound {
+		t.Errorf("GET of a deleted video: status %d", w.Code)
+	}
+
+	if w := call(http.MethodPost, path+"/restore", "mallory"); w.Code != http.StatusForbidden {
+		t.Errorf("restore by another user: status %d", w.Code)
+	}
+	if w := call(http.MethodPost, path+"/restore", "owner"); w.Code != http.StatusOK {
+		t.Fatalf("restore: %d %s", w.Code, w.Body)
+	}
+	if w := call(http.MethodGet, path, "owner"); w.Code != http.StatusOK {
+		t.Errorf("GET of the restored video: status %d", w.Code)
+	}
+	if w := call(http.MethodPost, path+"/restore", "owner"); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), api.CodeVideoNotDeleted) {
+		t.Errorf("restore of a live video: %d %s", w.Code, w.Body)
+	}
+
+	// Once purged, restoring is no longer possible
+	if w := call(http.MethodDelete, path, "owner"); w.Code != http.StatusAccepted {
+		t.Fatalf("second delete: status %d", w.Code)
+	}
+	db.Unscoped().Delete(&models.Video{}, video.ID)
+	if w := call(http.MethodPost, path+"/restore", "owner"); w.Code != http.StatusGone || !strings.Contains(w.Body.String(), api.CodeVideoPurged) {
+		t.Errorf("restore after the purge: %d %s", w.Code, w.Body)
+	}
+	if w := call(http.MethodPost, "/api/v1/videos/999/restore", "owner"); w.Code != http.StatusNotFound {
+		t.Errorf("restore of an unknown video: status %d", w.Code)
+	}
+}
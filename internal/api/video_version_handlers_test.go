@@ -0,0 +1,112 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// TestIfMatch saves a video from two editor tabs: the one holding a stale ETag
+// gets 412 with the latest video to merge against
+func TestIfMatch(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	videos := services.NewVideoService(db, nil, videoSettings, log)
+	router := newRouter(api.Dependencies{Videos: videos, Reactions: services.NewReactionService(db, log)})
+	video := models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Status: models.StatusReady}
+	db.Create(&video)
+	path := "/api/v1/videos/" + itoa(video.ID)
+
+	save := func(method, body, ifMatch string) (int, string, models.Video) {
+		t.Helper()
+		req := adminRequest(method, path, body, "owner", "")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := serve(router, req)
+		var got models.Video
+		json.Unmarshal(w.Body.Bytes(), &got)
+		return w.Code, w.Header().Get("ETag"), got
+	}
+
+	w := serve(router, adminRequest(http.MethodGet, path, "", "owner", ""))
+	var read models.Video
+	json.Unmarshal(w.Body.Bytes(), &read)
+	if etag := w.Header().Get("ETag"); etag != `"1"` || read.Version != 1 {
+		t.Fatalf("GET: ETag %s, version %d; want \"1\" and 1", etag, read.Version)
+	}
+
+	status, etag, saved := save(http.MethodPut, `{"description":"first tab"}`, `"1"`)
+	if status != http.StatusOK || etag != `"2"` || saved.Version != 2 {
+		t.Fatalf("PUT at \"1\": status %d, ETag %s, version %d", status, etag, saved.Version)
+	}
+
+	// The second tab still holds "1"
+	for _, method := range []string{http.MethodPut, http.MethodPatch} {
+		req := adminRequest(method, path, `{"description":"second tab"}`, "owner", "")
+		req.Header.Set("If-Match", `"1"`)
+		w := serve(router, req)
+		var body struct {
+			api.ErrorResponse
+			Details struct {
+				Video models.Video `json:"video"`
+			} `json:"details"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusPreconditionFailed || body.Code != api.CodeVersionConflict || w.Header().Get("ETag") != `"2"` {
+			t.Errorf("%s at a stale ETag: %d %s, ETag %s; want 412 %s", method, w.Code, w.Body, w.Header().Get("ETag"), api.CodeVersionConflict)
+		}
+		if body.Details.Video.Description != "first tab" || body.Details.Video.Version != 2 {
+			t.Errorf("%s at a stale ETag: latest video %+v, want the first tab's save", method, body.Details.Video)
+		}
+	}
+	var stored models.Video
+	db.First(&stored, video.ID)
+	if stored.Description != "first tab" || stored.Version != 2 {
+		t.Errorf("after refused saves: %q at version %d", stored.Description, stored.Version)
+	}
+
+	// The current ETag, a bare version, "*" and no header all apply
+	tests := []struct {
+		method, ifMatch string
+		version         int64
+	}{
+		{http.MethodPatch, `"2"`, 3},
+		{http.MethodPut, "3", 4},
+		{http.MethodPut, "*", 5},
+		{http.MethodPut, "", 6},
+	}
+	for _, tt := range tests {
+		status, etag, saved := save(tt.method, `{"title":"t"}`, tt.ifMatch)
+		if status != http.StatusOK || saved.Version != tt.version || etag != `"`+itoa(uint(tt.version))+`"` {
+			t.Errorf("%s with If-Match %q: status %d, ETag %s, version %d; want version %d", tt.method, tt.ifMatch, status, etag, saved.Version, tt.version)
+		}
+	}
+
+	// An event moves the version on, so a tab from before it is stale
+	if err := videos.HandleTranscodedEvent(context.Background(), &models.TranscodedEvent{UploadID: "up-1", UserID: "owner", Ready: true}); err != nil {
+		t.Fatal(err)
+	}
+	if status, _, _ := save(http.MethodPut, `{"title":"u"}`, `"6"`); status != http.StatusPreconditionFailed {
+		t.Errorf("save from before an event: status %d, want 412", status)
+	}
+
+	for _, bad := range []string{`"abc"`, `"0"`, `"1", "2"`, `W/"1"`} {
+		if status, _, _ := save(http.MethodPut, `{"title":"t"}`, bad); status != http.StatusBadRequest {
+			t.Errorf("If-Match %s: status %d, want 400", bad, status)
+		}
+	}
+	req := adminRequest(http.MethodPut, "/api/v1/videos/999", `{"title":"t"}`, "owner", "")
+	req.Header.Set("If-Match", `"1"`)
+	if w := serve(router, req); w.Code != http.StatusNotFound {
+		t.Errorf("missing video: status %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestPrivateVideoLookups(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+	})
+	const rawPath = "raw/owner/secret-cut.mp4"
+	video := models.Video{UploadID: "up-private", UserID: "owner", Title: "t", Visibility: models.VisibilityPrivate,
+		Status: models.StatusReady, RawVideoPath: rawPath}
+	db.Create(&video)
+
+	paths := []string{"/api/v1/videos/" + itoa(video.ID), "/api/v1/videos/upload/up-private"}
+	tests := []struct {
+		name   string
+		user   string
+		status int
+	}{
+		{"owner", "owner", http.StatusOK},
+		{"anonymous", "", http.StatusNotFound},
+		{"other user", "mallory", http.StatusNotFound},
+	}
+	for _, path := range paths {
+		for _, tt := range tests {
+			t.Run(path+"/"+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				if tt.user != "" {
+					req.Header.Set("X-User-ID", tt.user)
+				}
+				w := serve(router, req)
+				if w.Code != tt.status {
+					t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+				}
+				if leaked := strings.Contains(w.Body.String(), rawPath); leaked != (tt.status == http.StatusOK) {
+					t.Errorf("raw path in body = %v: %s", leaked, w.Body)
+				}
+			})
+		}
+	}
+
+	// A miss and a hidden video look the same, so private upload IDs can't be probed
+	hidden := serve(router, httptest.NewRequest(http.MethodGet, paths[1], nil))
+	missing := serve(router, httptest.NewRequest(http.MethodGet, "/api/v1/videos/upload/up-none", nil))
+	if hidden.Body.String() != missing.Body.String() || (hidden.Header().Get("Retry-After") == "") != (missing.Header().Get("Retry-After") == "") {
+		t.Errorf("hidden upload %q differs from a missing one %q", hidden.Body, missing.Body)
+	}
+}
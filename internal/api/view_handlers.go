@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// RecordView handles POST /api/v1/videos/:id/view. Signed-in callers are counted by
+// user ID, logged-out ones by anonymous session, else by client IP; repeats within
+// the dedup interval are not counted again.
+func (h *VideoHandler) RecordView(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+	if !canView(c, video) {
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+		return
+	}
+
+	viewer := engagementIdentity(c)
+	if viewer == "" {
+		viewer = services.ViewerIPPrefix + c.ClientIP()
+	}
+	counted, err := h.views.RecordView(c.Request.Context(), video.ID, viewer)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAnonymousQuota):
+			respondError(c, http.StatusTooManyRequests, CodeAnonymousQuotaExceeded, "Anonymous data limit reached", nil)
+		case errors.Is(err, services.ErrVideoNotFound):
+			respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+		default:
+			h.log(c).Errorw("Failed to record view", "error", err, "videoID", video.ID)
+			respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to record view", nil)
+		}
+		return
+	}
+
+	viewCount := video.ViewCount
+	if counted {
+		viewCount++
+	}
+	c.JSON(http.StatusOK, gin.H{"video_id": video.ID, "counted": counted, "view_count": viewCount})
+}
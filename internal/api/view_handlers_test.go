@@ -0,0 +1,109 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestRecordViewEndpoint(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Views:     services.NewViewService(db, log, time.Hour),
+		Reactions: services.NewReactionService(db, log),
+	})
+	public := models.Video{UploadID: "up-public", UserID: "owner", Title: "t", Status: models.StatusReady}
+	private := models.Video{UploadID: "up-private", UserID: "owner", Title: "t", Status: models.StatusReady, Visibility: models.VisibilityPrivate}
+	db.Create(&public)
+	db.Create(&private)
+
+	view := func(id uint, user, remoteAddr string) (int, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/videos/"+itoa(id)+"/view", nil)
+		if user != "" {
+			req.Header.Set("X-User-ID", user)
+		}
+		req.RemoteAddr = remoteAddr
+		w := serve(router, req)
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+
+	tests := []struct {
+		name      string
+		video     uint
+		user      string
+		addr      string
+		status    int
+		counted   bool
+		viewCount float64
+	}{
+		{"first view", public.ID, "u-1", "10.0.0.1:1", http.StatusOK, true, 1},
+		{"repeat by the same user", public.ID, "u-1", "10.0.0.2:1", http.StatusOK, false, 1},
+		{"logged out, by address", public.ID, "", "10.0.0.1:1", http.StatusOK, true, 2},
+		{"same address again", public.ID, "", "10.0.0.1:2", http.StatusOK, false, 2},
+		{"another address", public.ID, "", "10.0.0.3:1", http.StatusOK, true, 3},
+		{"private, not the owner", private.ID, "mallory", "10.0.0.4:1", http.StatusNotFound, false, 0},
+		{"private, owner", private.ID, "owner", "10.0.0.4:1", http.StatusOK, true, 1},
+		{"missing video", public.ID + 100, "u-1", "10.0.0.1:1", http.StatusNotFound, false, 0},
+	}
+	for _, tt := range tests {
+		status, body := view(tt.video, tt.user, tt.addr)
+		if status != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, status, tt.status)
+			continue
+		}
+		if status == http.StatusOK && (body["counted"] != tt.counted || body["view_count"] != tt.viewCount) {
+			t.Errorf("%s: %v, want counted %v and view_count %v", tt.name, body, tt.counted, tt.viewCount)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/videos/abc/view", nil)
+	if w := serve(router, req); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid ID: status %d, want 400", w.Code)
+	}
+}
+
+// TestListVideosByViews sorts by the counter views increment, under both names
+func TestListVideosByViews(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:    services.NewVideoService(db, nil, videoSettings, log),
+		Reactions: services.NewReactionService(db, log),
+	})
+	for _, v := range []models.Video{
+		{UploadID: "up-a", Title: "a", ViewCount: 5},
+		{UploadID: "up-b", Title: "b", ViewCount: 50},
+		{UploadID: "up-c", Title: "c", ViewCount: 0},
+	} {
+		v.UserID, v.Status = "owner", models.StatusReady
+		db.Create(&v)
+	}
+	for _, key := range []string{"views", "view_count"} {
+		w := serve(router, httptest.NewRequest(http.MethodGet, "/api/v1/videos?sort="+key+"&order=desc", nil))
+		var page models.VideoListResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("sort=%s: %d %s", key, w.Code, w.Body)
+		}
+		var titles string
+		for _, v := range page.Videos {
+			titles += v.Title
+		}
+		if titles != "bac" {
+			t.Errorf("sort=%s desc: %q, want bac", key, titles)
+		}
+	}
+}
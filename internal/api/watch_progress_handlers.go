@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// SaveWatchProgress handles PUT /api/v1/videos/:id/progress with
+// {"position_seconds": n}. Signed-in users and anonymous sessions may save progress
+// on videos they can see; the position is clamped to the video's duration.
+func (h *VideoHandler) SaveWatchProgress(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	viewer := engagementIdentity(c)
+	if viewer == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID or anonymous session required", nil)
+		return
+	}
+	var req models.WatchProgressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+	if !canView(c, video) {
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+		return
+	}
+
+	update, err := h.progress.SaveProgress(c.Request.Context(), video, viewer, *req.PositionSeconds)
+	if err != nil {
+		if errors.Is(err, services.ErrAnonymousQuota) {
+			respondError(c, http.StatusTooManyRequests, CodeAnonymousQuotaExceeded, "Anonymous data limit reached", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to save watch progress", "error", err, "videoID", video.ID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to save watch progress", nil)
+		return
+	}
+	c.JSON(http.StatusOK, update)
+}
+
+// GetWatchProgress handles GET /api/v1/videos/:id/progress: the caller's saved
+// position in the video, or 404 when there is none
+func (h *VideoHandler) GetWatchProgress(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid video ID", nil)
+		return
+	}
+	viewer := engagementIdentity(c)
+	if viewer == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User ID or anonymous session required", nil)
+		return
+	}
+	video, err := h.videoService.GetVideo(c.Request.Context(), uint(id))
+	if err != nil {
+		h.videoLookupFailed(c, err, uint(id))
+		return
+	}
+	if !canView(c, video) {
+		respondError(c, http.StatusNotFound, CodeVideoNotFound, "Video not found", nil)
+		return
+	}
+
+	progress, err := h.progress.GetProgress(c.Request.Context(), video.ID, viewer)
+	if err != nil {
+		if errors.Is(err, services.ErrProgressNotFound) {
+			respondError(c, http.StatusNotFound, CodeProgressNotFound, "No watch progress for this video", nil)
+			return
+		}
+		h.log(c).Errorw("Failed to get watch progress", "error", err, "videoID", video.ID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get watch progress", nil)
+		return
+	}
+	c.JSON(http.StatusOK, progress)
+}
+
+// ContinueWatching handles GET /api/v1/users/:userID/continue-watching (owner only):
+// ready videos the user started and hasn't finished, most recently watched first
+func (h *VideoHandler) ContinueWatching(c *gin.Context) {
+	userID := c.Param("userID")
+	if !h.requireSelf(c, userID) {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	entries, total, err := h.progress.ContinueWatching(c.Request.Context(), userID, page, perPage)
+	if err != nil {
+		h.log(c).Errorw("Failed to list continue watching", "error", err, "userID", userID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to list continue watching", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"videos":      entries,
+		"total":       total,
+		"page":        page,
+		"per_page":    perPage,
+		"total_pages": (int(total) + perPage - 1) / perPage,
+	})
+}
@@ -0,0 +1,58 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/api"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestContinueWatchingIsOwnerOnly(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	router := newRouter(api.Dependencies{
+		Videos:   services.NewVideoService(db, nil, videoSettings, log),
+		Progress: services.NewWatchProgressService(db, log, 0),
+	})
+	video := models.Video{UploadID: "up", UserID: "owner", Title: "t", Status: models.StatusReady, Duration: 100}
+	db.Create(&video)
+	db.Create(&models.WatchProgress{UserID: "alice", VideoID: video.ID, PositionSeconds: 10, DurationAtTime: 100})
+
+	tests := []struct {
+		name   string
+		user   string
+		roles  string
+		status int
+	}{
+		{"anonymous", "", "", http.StatusUnauthorized},
+		{"another user", "bob", "", http.StatusForbidden},
+		{"an admin", "bob", "admin", http.StatusForbidden},
+		{"the user", "alice", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := serve(router, adminRequest(http.MethodGet, "/api/v1/users/alice/continue-watching", "", tt.user, tt.roles))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var page struct {
+			Videos []models.ContinueWatchingEntry `json:"videos"`
+			Total  int64                          `json:"total"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatalf("decode %s: %v", w.Body, err)
+		}
+		if page.Total != 1 || len(page.Videos) != 1 || page.Videos[0].ID != video.ID || page.Videos[0].PositionSeconds != 10 {
+			t.Errorf("%s: page = %+v", tt.name, page)
+		}
+	}
+}
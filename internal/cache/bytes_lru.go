@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// BytesLRU is a least-recently-used cache of byte slices bounded by their total size
+type BytesLRU struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List // front is most recently used
+	entries  map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// NewBytesLRU creates a cache holding at most maxBytes of values
+func NewBytesLRU(maxBytes int64) *BytesLRU {
+	return &BytesLRU{maxBytes: maxBytes, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// Get returns the value for key and marks it recently used
+func (c *BytesLRU) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true
+}
+
+// Add stores value under key, evicting the least recently used entries to make
+// room. A value larger than the whole cache is not stored.
+func (c *BytesLRU) Add(key string, value []byte) {
+	n := int64(len(value))
+	if n > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.size += n - int64(len(el.Value.(*lruEntry).value))
+		el.Value.(*lruEntry).value = value
+		c.order.MoveToFront(el)
+	} else {
+		c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+		c.size += n
+	}
+	for c.size > c.maxBytes {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*lruEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.size -= int64(len(entry.value))
+	}
+}
+
+// Size returns the total bytes held
+func (c *BytesLRU) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
@@ -0,0 +1,68 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/cache"
+)
+
+func TestBytesLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := cache.NewBytesLRU(10)
+	c.Add("a", []byte("aaaa"))
+	c.Add("b", []byte("bbb"))
+	c.Add("c", []byte("cc"))
+	// Reading a makes b the least recently used
+	if got, ok := c.Get("a"); !ok || string(got) != "aaaa" {
+		t.Fatalf("Get(a) = %q, %v", got, ok)
+	}
+	c.Add("d", []byte("ddd"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b was kept; it was the least recently used")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if got := c.Size(); got != 9 {
+		t.Errorf("Size = %d, want 9", got)
+	}
+}
+
+func TestBytesLRUReplace(t *testing.T) {
+	c := cache.NewBytesLRU(10)
+	c.Add("a", []byte("aaaa"))
+	c.Add("b", []byte("bb"))
+	c.Add("a", []byte("a"))
+	if got, _ := c.Get("a"); string(got) != "a" {
+		t.Errorf("Get(a) = %q after replace", got)
+	}
+	if got := c.Size(); got != 3 {
+		t.Errorf("Size = %d, want 3 after shrinking a value", got)
+	}
+
+	// Growing a value evicts others, never the value itself
+	c.Add("a", []byte("aaaaaaaaa"))
+	if _, ok := c.Get("b"); ok {
+		t.Error("b kept past the size bound")
+	}
+	if got := c.Size(); got != 9 {
+		t.Errorf("Size = %d, want 9", got)
+	}
+}
+
+func TestBytesLRUOversizedValue(t *testing.T) {
+	c := cache.NewBytesLRU(4)
+	c.Add("small", []byte("ab"))
+	c.Add("big", []byte("abcde"))
+	if _, ok := c.Get("big"); ok {
+		t.Error("a value larger than the cache was stored")
+	}
+	if _, ok := c.Get("small"); !ok {
+		t.Error("storing an oversized value evicted the others")
+	}
+	if got := c.Size(); got != 2 {
+		t.Errorf("Size = %d, want 2", got)
+	}
+}
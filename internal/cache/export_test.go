@@ -0,0 +1,9 @@
+package cache
+
+import "time"
+
+// SetClock replaces the cache's clock for tests
+func (c *NegativeCache) SetClock(now func() time.Time) { c.now = now }
+
+// SetClock replaces the tracker's clock for tests
+func (r *RecentWrites) SetClock(now func() time.Time) { r.now = now }
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// NegativeCache remembers recent lookup misses for a short TTL so repeated polls for
+// a key that doesn't exist yet don't each hit the database.
+type NegativeCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	maxSize   int
+	entries   map[string]time.Time
+	now       func() time.Time
+	lastSweep time.Time
+	// generation is bumped by every Invalidate so a lookup that raced with a
+	// creation doesn't record a stale miss after the invalidation ran.
+	generation uint64
+}
+
+// NewNegativeCache creates a negative cache holding at most maxSize keys for ttl each
+func NewNegativeCache(ttl time.Duration, maxSize int) *NegativeCache {
+	return &NegativeCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// TTL returns how long a miss is remembered
+func (c *NegativeCache) TTL() time.Duration { return c.ttl }
+
+// IsMiss reports whether key was recorded as missing and the entry hasn't expired
+func (c *NegativeCache) IsMiss(key string) bool {
+	if c == nil || c.ttl <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	exp, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	if c.now().After(exp) {
+		delete(c.entries, key)
+		return false
+	}
+	return true
+}
+
+// Generation returns a token to pass to RecordMiss; take it before the lookup
+func (c *NegativeCache) Generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// RecordMiss remembers that key was not found, unless an invalidation happened
+// since gen was taken (the row may have been created while we were querying).
+func (c *NegativeCache) RecordMiss(key string, gen uint64) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.generation {
+		return
+	}
+	now := c.now()
+	c.sweepLocked(now)
+	if len(c.entries) >= c.maxSize {
+		// Still full of live entries: skip caching rather than grow unbounded
+		return
+	}
+	c.entries[key] = now.Add(c.ttl)
+}
+
+// Invalidate forgets a recorded miss; call it once the key has been created
+func (c *NegativeCache) Invalidate(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.generation++
+	c.mu.Unlock()
+}
+
+// sweepLocked drops expired entries at most once per TTL, or immediately when full
+func (c *NegativeCache) sweepLocked(now time.Time) {
+	if len(c.entries) < c.maxSize && now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	for k, exp := range c.entries {
+		if now.After(exp) {
+			delete(c.entries, k)
+		}
+	}
+	c.lastSweep = now
+}
@@ -0,0 +1,99 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/cache"
+)
+
+// TestNegativeCacheGeneration interleaves lookups (Generation, then RecordMiss once
+// the query found nothing) with Invalidate and checks which misses stick. Steps:
+// "gen" takes a token, "record" records a miss with the last token, "invalidate"
+// runs Invalidate, "miss"/"hit" assert IsMiss.
+func TestNegativeCacheGeneration(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []string
+	}{
+		{"miss recorded", []string{"gen", "record", "miss"}},
+		{"invalidate forgets miss", []string{"gen", "record", "invalidate", "hit"}},
+		{"creation during lookup drops stale miss", []string{"gen", "invalidate", "record", "hit"}},
+		{"token taken after invalidation records", []string{"invalidate", "gen", "record", "miss"}},
+		{"second invalidation during lookup", []string{"gen", "record", "gen", "invalidate", "record", "hit"}},
+		{"fresh lookup after race records", []string{"gen", "invalidate", "record", "hit", "gen", "record", "miss"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cache.NewNegativeCache(time.Minute, 10)
+			var gen uint64
+			for i, step := range tt.steps {
+				switch step {
+				case "gen":
+					gen = c.Generation()
+				case "record":
+					c.RecordMiss("upload-1", gen)
+				case "invalidate":
+					c.Invalidate("upload-1")
+				case "miss", "hit":
+					if got := c.IsMiss("upload-1"); got != (step == "miss") {
+						t.Fatalf("step %d: IsMiss = %v, want %v", i, got, step == "miss")
+					}
+				default:
+					t.Fatalf("unknown step %q", step)
+				}
+			}
+		})
+	}
+}
+
+func TestNegativeCacheInvalidateOtherKey(t *testing.T) {
+	c := cache.NewNegativeCache(time.Minute, 10)
+	gen := c.Generation()
+	c.RecordMiss("a", gen)
+	c.Invalidate("b")
+	if !c.IsMiss("a") {
+		t.Error("invalidating another key forgot a")
+	}
+	// The generation is global, so a lookup of a that raced with b's creation is dropped
+	c.RecordMiss("c", gen)
+	if c.IsMiss("c") {
+		t.Error("miss recorded with a token from before an invalidation")
+	}
+}
+
+func TestNegativeCacheExpiryAndSize(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	c := cache.NewNegativeCache(time.Minute, 2)
+	c.SetClock(func() time.Time { return now })
+
+	c.RecordMiss("a", c.Generation())
+	c.RecordMiss("b", c.Generation())
+	c.RecordMiss("c", c.Generation())
+	if c.IsMiss("c") {
+		t.Error("full cache recorded a third key")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if c.IsMiss("a") {
+		t.Error("expired miss still reported")
+	}
+	c.RecordMiss("c", c.Generation())
+	if !c.IsMiss("c") {
+		t.Error("expired entries weren't swept to make room")
+	}
+}
+
+func TestNegativeCacheDisabled(t *testing.T) {
+	var nilCache *cache.NegativeCache
+	nilCache.RecordMiss("a", nilCache.Generation())
+	nilCache.Invalidate("a")
+	if nilCache.IsMiss("a") {
+		t.Error("nil cache reported a miss")
+	}
+	c := cache.NewNegativeCache(0, 10)
+	c.RecordMiss("a", c.Generation())
+	if c.IsMiss("a") {
+		t.Error("zero TTL cache reported a miss")
+	}
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// RecentWrites remembers which keys (typically user+resource) were written within
+// a short window, so reads for them can be made read-your-writes consistent.
+type RecentWrites struct {
+	mu        sync.Mutex
+	window    time.Duration
+	maxSize   int
+	entries   map[string]time.Time
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewRecentWrites creates a tracker holding at most maxSize keys for window each
+func NewRecentWrites(window time.Duration, maxSize int) *RecentWrites {
+	return &RecentWrites{
+		window:  window,
+		maxSize: maxSize,
+		entries: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Window returns how long a write is remembered
+func (r *RecentWrites) Window() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return r.window
+}
+
+// Mark records a write to key now
+func (r *RecentWrites) Mark(key string) {
+	if r == nil || r.window <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	r.sweepLocked(now)
+	if _, ok := r.entries[key]; !ok && len(r.entries) >= r.maxSize {
+		// Full of live entries: the worst case is one stale read, so don't grow unbounded
+		return
+	}
+	r.entries[key] = now.Add(r.window)
+}
+
+// Recent reports whether key was written within the window
+func (r *RecentWrites) Recent(key string) bool {
+	if r == nil || r.window <= 0 {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	exp, ok := r.entries[key]
+	if !ok {
+		return false
+	}
+	if r.now().After(exp) {
+		delete(r.entries, key)
+		return false
+	}
+	return true
+}
+
+// sweepLocked drops expired entries at most once per window, or immediately when full
+func (r *RecentWrites) sweepLocked(now time.Time) {
+	if len(r.entries) < r.maxSize && now.Sub(r.lastSweep) < r.window {
+		return
+	}
+	for k, exp := range r.entries {
+		if now.After(exp) {
+			delete(r.entries, k)
+		}
+	}
+	r.lastSweep = now
+}
@@ -0,0 +1,67 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/cache"
+)
+
+func TestRecentWritesWindow(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	r := cache.NewRecentWrites(5*time.Second, 10)
+	r.SetClock(func() time.Time { return now })
+
+	if r.Recent("alice:1") {
+		t.Fatal("unwritten key reported recent")
+	}
+	r.Mark("alice:1")
+	now = now.Add(5 * time.Second)
+	if !r.Recent("alice:1") || r.Recent("alice:2") || r.Recent("bob:1") {
+		t.Fatal("only alice:1 should be recent inside the window")
+	}
+	now = now.Add(time.Millisecond)
+	if r.Recent("alice:1") {
+		t.Error("key still recent after the window")
+	}
+
+	// Marking again extends the window
+	r.Mark("alice:1")
+	now = now.Add(3 * time.Second)
+	r.Mark("alice:1")
+	now = now.Add(3 * time.Second)
+	if !r.Recent("alice:1") {
+		t.Error("re-marked key expired from its first mark")
+	}
+}
+
+func TestRecentWritesBounded(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	r := cache.NewRecentWrites(time.Minute, 2)
+	r.SetClock(func() time.Time { return now })
+	r.Mark("a")
+	r.Mark("b")
+	r.Mark("c")
+	if !r.Recent("a") || !r.Recent("b") || r.Recent("c") {
+		t.Error("a full tracker should drop new keys, not evict live ones")
+	}
+	// Once the live entries expire there is room again
+	now = now.Add(2 * time.Minute)
+	r.Mark("c")
+	if !r.Recent("c") {
+		t.Error("key dropped after the old entries expired")
+	}
+}
+
+func TestRecentWritesDisabled(t *testing.T) {
+	var nilTracker *cache.RecentWrites
+	nilTracker.Mark("a")
+	if nilTracker.Recent("a") || nilTracker.Window() != 0 {
+		t.Error("nil tracker should remember nothing")
+	}
+	off := cache.NewRecentWrites(0, 10)
+	off.Mark("a")
+	if off.Recent("a") {
+		t.Error("zero window should remember nothing")
+	}
+}
@@ -0,0 +1,189 @@
+package config_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+)
+
+// writeCert writes a self-signed certificate and its key to dir as PEM files
+func writeCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "rabbitmq.test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeFile(t, certFile, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	writeFile(t, keyFile, string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAMQPTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir)
+	notPEM := filepath.Join(dir, "ca.txt")
+	writeFile(t, notPEM, "not a certificate")
+
+	tests := []struct {
+		name    string
+		amqp    config.AMQP
+		wantErr string
+		// wantRoots and wantCerts say whether the CA pool and client cert are set
+		wantRoots, wantCerts bool
+	}{
+		{name: "plaintext", amqp: config.AMQP{}},
+		{name: "CA bundle", amqp: config.AMQP{CAFile: certFile}, wantRoots: true},
+		{name: "client certificate", amqp: config.AMQP{CertFile: certFile, KeyFile: keyFile}, wantCerts: true},
+		{name: "both", amqp: config.AMQP{CAFile: certFile, CertFile: certFile, KeyFile: keyFile}, wantRoots: true, wantCerts: true},
+		{name: "missing CA", amqp: config.AMQP{CAFile: filepath.Join(dir, "none.pem")}, wantErr: "CA file not found: " + filepath.Join(dir, "none.pem")},
+		{name: "CA not PEM", amqp: config.AMQP{CAFile: notPEM}, wantErr: "has no PEM certificates"},
+		{name: "cert without key", amqp: config.AMQP{CertFile: certFile}, wantErr: "needs both a cert file and a key file"},
+		{name: "key without cert", amqp: config.AMQP{KeyFile: keyFile}, wantErr: "needs both a cert file and a key file"},
+		{name: "key that isn't one", amqp: config.AMQP{CertFile: certFile, KeyFile: notPEM}, wantErr: "load client certificate"},
+	}
+	for _, tt := range tests {
+		got, err := tt.amqp.TLSConfig()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !tt.wantRoots && !tt.wantCerts {
+			if got != nil {
+				t.Errorf("%s: TLS config %+v, want none", tt.name, got)
+			}
+			continue
+		}
+		if (got.RootCAs != nil) != tt.wantRoots || (len(got.Certificates) == 1) != tt.wantCerts {
+			t.Errorf("%s: roots %v, %d client certs", tt.name, got.RootCAs != nil, len(got.Certificates))
+		}
+	}
+}
+
+func TestLoadAMQPDefaults(t *testing.T) {
+	cfg, err := load(t, nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// No credentials beyond the URL's
+	if a := cfg.AMQP; a.Username != "" || a.Password != "" || a.VHost != "" || a.Heartbeat != 10*time.Second || a.DialTimeout != 30*time.Second {
+		t.Errorf("amqp = %+v", a)
+	}
+}
+
+func TestLoadAMQPConnection(t *testing.T) {
+	certFile, keyFile := writeCert(t, t.TempDir())
+	cfg, err := load(t, map[string]string{
+		"AMQP_URL":          "amqps://rabbitmq.internal:5671/",
+		"AMQP_USERNAME":     "catalog",
+		"AMQP_PASSWORD":     "hunter2",
+		"AMQP_VHOST":        "streamhive",
+		"AMQP_CA_FILE":      certFile,
+		"AMQP_CERT_FILE":    certFile,
+		"AMQP_KEY_FILE":     keyFile,
+		"AMQP_HEARTBEAT":    "5s",
+		"AMQP_DIAL_TIMEOUT": "2s",
+	})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	a := cfg.AMQP
+	if a.Username != "catalog" || a.Password != "hunter2" || a.VHost != "streamhive" {
+		t.Errorf("credentials %q / %q on vhost %q", a.Username, a.Password, a.VHost)
+	}
+	if a.CAFile != certFile || a.CertFile != certFile || a.KeyFile != keyFile || a.Heartbeat != 5*time.Second || a.DialTimeout != 2*time.Second {
+		t.Errorf("amqp = %+v", a)
+	}
+}
+
+func TestLoadAMQPSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	config.SetSecretsDir(t, dir)
+	// Trailing newlines, as mounted secrets usually have, are trimmed
+	writeFile(t, filepath.Join(dir, "amqp-username"), "catalog\n")
+	writeFile(t, filepath.Join(dir, "amqp-password"), "from-file\n")
+	writeFile(t, filepath.Join(dir, "amqp-vhost"), "streamhive")
+
+	cfg, err := load(t, map[string]string{"AMQP_USERNAME": "env-user", "AMQP_PASSWORD": "env-password"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if a := cfg.AMQP; a.Username != "catalog" || a.Password != "from-file" || a.VHost != "streamhive" {
+		t.Errorf("credentials %q / %q on vhost %q, want the mounted ones over the environment", a.Username, a.Password, a.VHost)
+	}
+}
+
+func TestLoadAMQPInvalid(t *testing.T) {
+	certFile, _ := writeCert(t, t.TempDir())
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"bad URL", map[string]string{"AMQP_URL": "http://rabbitmq"}, "AMQP_URL"},
+		{"password without a username", map[string]string{"AMQP_URL": "amqp://rabbitmq/", "AMQP_PASSWORD": "p"},
+			"AMQP_USERNAME (AMQP_PASSWORD is set): required"},
+		{"TLS files over plaintext", map[string]string{"AMQP_URL": "amqp://rabbitmq/", "AMQP_CA_FILE": certFile},
+			"AMQP_CA_FILE, AMQP_CERT_FILE and AMQP_KEY_FILE need an amqps:// URL"},
+		{"missing CA", map[string]string{"AMQP_URL": "amqps://rabbitmq/", "AMQP_CA_FILE": "/nonexistent/ca.pem"},
+			"AMQP TLS: CA file not found: /nonexistent/ca.pem"},
+		{"cert without key", map[string]string{"AMQP_URL": "amqps://rabbitmq/", "AMQP_CERT_FILE": certFile},
+			"AMQP TLS: client certificate needs both a cert file and a key file"},
+		{"zero dial timeout", map[string]string{"AMQP_DIAL_TIMEOUT": "0s"}, `AMQP_DIAL_TIMEOUT`},
+		{"negative heartbeat", map[string]string{"AMQP_HEARTBEAT": "-1s"}, `AMQP_HEARTBEAT`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.env)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	t.Run("password with the URL's username", func(t *testing.T) {
+		if _, err := load(t, map[string]string{"AMQP_URL": "amqp://catalog@rabbitmq/", "AMQP_PASSWORD": "p"}); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package config_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+)
+
+func TestAzureResolveAuthMode(t *testing.T) {
+	const conn = "DefaultEndpointsProtocol=https;AccountName=acct;AccountKey=a2V5;EndpointSuffix=core.windows.net"
+	tests := []struct {
+		name    string
+		azure   config.Azure
+		want    string
+		wantErr string
+	}{
+		{"auto prefers the connection string", config.Azure{ConnectionString: conn, Account: "acct", Key: "a2V5"}, config.AzureAuthConnectionString, ""},
+		{"auto with account and key", config.Azure{Account: "acct", Key: "a2V5"}, config.AzureAuthSharedKey, ""},
+		{"empty mode is auto", config.Azure{AuthMode: "", Account: "acct", Key: "a2V5"}, config.AzureAuthSharedKey, ""},
+		// auto never picks managed identity: an account alone is not enough
+		{"auto with an account only", config.Azure{AuthMode: config.AzureAuthAuto, Account: "acct"}, "",
+			"AZURE_AUTH_MODE=auto: no Azure storage credentials"},
+		{"auto with nothing", config.Azure{}, "", "AZURE_AUTH_MODE=managed_identity with AZURE_STORAGE_ACCOUNT"},
+		{"connection string", config.Azure{AuthMode: config.AzureAuthConnectionString, ConnectionString: conn}, config.AzureAuthConnectionString, ""},
+		{"connection string missing", config.Azure{AuthMode: config.AzureAuthConnectionString, Account: "acct", Key: "a2V5"}, "",
+			"AZURE_AUTH_MODE=connection_string: AZURE_STORAGE_CONNECTION_STRING required"},
+		// An explicit mode ignores credentials for the others
+		{"shared key beside a connection string", config.Azure{AuthMode: config.AzureAuthSharedKey, ConnectionString: conn, Account: "acct", Key: "a2V5"},
+			config.AzureAuthSharedKey, ""},
+		{"shared key without key", config.Azure{AuthMode: config.AzureAuthSharedKey, Account: "acct"}, "",
+			"AZURE_AUTH_MODE=shared_key: AZURE_STORAGE_KEY required"},
+		{"shared key without either", config.Azure{AuthMode: config.AzureAuthSharedKey}, "",
+			"AZURE_AUTH_MODE=shared_key: AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_KEY required"},
+		{"managed identity", config.Azure{AuthMode: config.AzureAuthManagedIdentity, Account: "acct"}, config.AzureAuthManagedIdentity, ""},
+		{"managed identity by URL", config.Azure{AuthMode: config.AzureAuthManagedIdentity, AccountURL: "https://blobs.internal/"},
+			config.AzureAuthManagedIdentity, ""},
+		{"managed identity without an account", config.Azure{AuthMode: config.AzureAuthManagedIdentity, Key: "a2V5"}, "",
+			"AZURE_AUTH_MODE=managed_identity: AZURE_STORAGE_ACCOUNT (or AZURE_STORAGE_ACCOUNT_URL) required"},
+		{"unknown mode", config.Azure{AuthMode: "sas", Account: "acct"}, "",
+			"AZURE_AUTH_MODE=sas: want one of auto, connection_string, shared_key, managed_identity"},
+	}
+	for _, tt := range tests {
+		got, err := tt.azure.ResolveAuthMode()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: %q, %v; want error %q", tt.name, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestAzureServiceURL(t *testing.T) {
+	if got := (config.Azure{Account: "acct"}).ServiceURL(); got != "https://acct.blob.core.windows.net/" {
+		t.Errorf("ServiceURL = %q", got)
+	}
+	if got := (config.Azure{Account: "acct", AccountURL: "https://blobs.internal/acct/"}).ServiceURL(); got != "https://blobs.internal/acct/" {
+		t.Errorf("ServiceURL with AZURE_STORAGE_ACCOUNT_URL = %q", got)
+	}
+}
+
+func TestLoadAzureAuthMode(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantMode     string
+		wantExplicit bool
+		wantErr      string
+	}{
+		// Storage is optional by default, so no credentials is not an error
+		{"nothing set", nil, config.AzureAuthAuto, false, ""},
+		{"auto with a key", map[string]string{"AZURE_STORAGE_ACCOUNT": "acct", "AZURE_STORAGE_KEY": "a2V5"}, config.AzureAuthAuto, false, ""},
+		{"managed identity", map[string]string{"AZURE_AUTH_MODE": "managed_identity", "AZURE_STORAGE_ACCOUNT": "acct"},
+			config.AzureAuthManagedIdentity, true, ""},
+		{"managed identity by URL", map[string]string{"AZURE_AUTH_MODE": "managed_identity", "AZURE_STORAGE_ACCOUNT_URL": "https://blobs.internal/"},
+			config.AzureAuthManagedIdentity, true, ""},
+		// Naming a mode makes its credentials required at startup
+		{"managed identity without an account", map[string]string{"AZURE_AUTH_MODE": "managed_identity"}, "", true,
+			"AZURE_AUTH_MODE=managed_identity: AZURE_STORAGE_ACCOUNT (or AZURE_STORAGE_ACCOUNT_URL) required"},
+		{"shared key without a key", map[string]string{"AZURE_AUTH_MODE": "shared_key", "AZURE_STORAGE_ACCOUNT": "acct"}, "", true,
+			"AZURE_AUTH_MODE=shared_key: AZURE_STORAGE_KEY required"},
+		{"explicit backend without credentials", map[string]string{"STORAGE_BACKEND": "azure"}, "", true,
+			"AZURE_AUTH_MODE=auto: no Azure storage credentials"},
+		{"unknown mode", map[string]string{"AZURE_AUTH_MODE": "sas"}, "", false, `AZURE_AUTH_MODE="sas"`},
+		// The S3 backend doesn't look at Azure credentials
+		{"s3 backend", map[string]string{"STORAGE_BACKEND": "s3", "S3_BUCKET": "b", "S3_ACCESS_KEY_ID": "id", "S3_SECRET_ACCESS_KEY": "secret"},
+			config.AzureAuthAuto, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.SetSecretsDir(t, t.TempDir())
+			cfg, err := load(t, tt.env)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Storage.Azure.AuthMode != tt.wantMode || cfg.Storage.Explicit != tt.wantExplicit {
+				t.Errorf("mode %q, explicit %v; want %q, %v", cfg.Storage.Azure.AuthMode, cfg.Storage.Explicit, tt.wantMode, tt.wantExplicit)
+			}
+		})
+	}
+}
+
+func TestLoadAzureSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	config.SetSecretsDir(t, dir)
+	writeFile(t, filepath.Join(dir, "azure-storage-account"), "mounted\n")
+	cfg, err := load(t, map[string]string{"AZURE_AUTH_MODE": "managed_identity", "AZURE_STORAGE_ACCOUNT": "from-env"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Storage.Azure.Account != "mounted" || cfg.Storage.Azure.ServiceURL() != "https://mounted.blob.core.windows.net/" {
+		t.Errorf("account %q, service URL %q; want the mounted account", cfg.Storage.Azure.Account, cfg.Storage.Azure.ServiceURL())
+	}
+}
@@ -0,0 +1,17 @@
+package config
+
+import "testing"
+
+// ResetErrors forgets the parse errors recorded by earlier tests
+func ResetErrors() {
+	mu.Lock()
+	defer mu.Unlock()
+	errs = nil
+}
+
+// SetSecretsDir looks for mounted secrets in dir until the test ends
+func SetSecretsDir(t *testing.T, dir string) {
+	old := secretsDir
+	secretsDir = dir + "/"
+	t.Cleanup(func() { secretsDir = old })
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/logging"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// Auth configures how callers are authenticated
+type Auth struct {
+	// Mode is jwt, or header to trust X-User-ID as sent (local development only)
+	Mode      string
+	Algorithm string
+	Secret    []byte
+	// PublicKeyPEM is read from AUTH_JWT_PUBLIC_KEY_FILE
+	PublicKeyPEM []byte
+	JWKSURL      string
+	Issuer       string
+	Audience     string
+	RolesClaim   string
+	Leeway       time.Duration
+	// Impersonation lets admins view the API as a user via X-Impersonate-User
+	Impersonation bool
+}
+
+// Sessions holds the signing secrets and lifetimes of pagination cursors and
+// anonymous sessions. An empty secret is replaced with a random per-process one.
+type Sessions struct {
+	CursorSecret []byte
+	CursorTTL    time.Duration
+	AnonSecret   []byte
+	AnonTTL      time.Duration
+	// AnonRetention is how long an expired session's data is kept; AnonMaxRows caps
+	// how much of it one session may create
+	AnonRetention time.Duration
+	AnonMaxRows   int
+}
+
+// Streaming caps the endpoints that stream large result sets
+type Streaming struct {
+	MaxRows    int
+	MaxBytes   int64
+	FlushEvery int
+}
+
+// Moderation providers selectable with MODERATION_PROVIDER
+const (
+	ModerationProviderNone = "none"
+	ModerationProviderHTTP = "http"
+)
+
+// Moderation picks the provider content is scored by and decides what asynchronous
+// moderation does with its scores
+type Moderation struct {
+	// Provider is none or http; URL and Timeout address the http provider
+	Provider string
+	URL      string
+	Timeout  time.Duration
+	// Content scoring at least FlagThreshold is flagged, at least
+	// HighSeverityThreshold hidden; both are between 0 and 1
+	FlagThreshold         float64
+	HighSeverityThreshold float64
+	MaxInflight           int
+}
+
+// Videos tunes the video service's caches and time windows
+type Videos struct {
+	// UploadMissTTL is how long an unknown upload ID is remembered as missing
+	UploadMissTTL time.Duration
+	// DeleteGrace is how long a deleted video can be restored before it is purged
+	DeleteGrace time.Duration
+	// PlaybackURLTTL is how long a private video's signed playback URL is valid
+	PlaybackURLTTL time.Duration
+	// ChannelSummaryTTL is how long public channel summaries are cached; 0 disables it
+	ChannelSummaryTTL time.Duration
+}
+
+// Comments configures reply nesting and editing
+type Comments struct {
+	// MaxDepth is how deep replies may nest; 1 allows replies to top-level comments only
+	MaxDepth int
+	// EditWindow is how long after posting the author may edit a comment
+	EditWindow time.Duration
+}
+
+// Buffer sizes an in-memory write queue flushed in batches
+type Buffer struct {
+	Size  int
+	Batch int
+	Flush time.Duration
+}
+
+// Engagement tunes comments, views, progress and tags
+type Engagement struct {
+	// Comment notifications collapse into one once a video gets this many per window
+	NotifyCollapseThreshold int
+	NotifyCollapseWindow    time.Duration
+	// After commenting, a user's reads skip the replica for ReadYourWrites; at most
+	// ReadYourWritesMax users are tracked
+	ReadYourWrites       time.Duration
+	ReadYourWritesMax    int
+	ViewDedupInterval    time.Duration
+	WatchProgressMinStep time.Duration
+	TagsCacheInterval    time.Duration
+	TagsCacheMax         int
+}
+
+// Thumbnails configures on-demand thumbnail resizing
+type Thumbnails struct {
+	Widths       []int
+	Workers      int
+	CacheSize    int64
+	FetchTimeout time.Duration
+}
+
+// Events switches what the catalog publishes and records about events
+type Events struct {
+	// Catalog publishes catalog.video.updated and catalog.video.deleted
+	Catalog bool
+	// CacheInvalidation publishes one video.cache.invalidate per video per window
+	CacheInvalidation     bool
+	CacheInvalidateWindow time.Duration
+	WatchMilestones       bool
+	// Archive records every consumed message with its outcome
+	Archive        bool
+	Inbound        Buffer
+	InboundMaxBody int64
+	// StrictFields rejects events with fields the catalog doesn't know
+	StrictFields bool
+}
+
+// Outbox configures the transactional outbox dispatcher
+type Outbox struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BackoffMin   time.Duration
+	BackoffMax   time.Duration
+	Retention    time.Duration
+}
+
+// Jobs configures the background jobs and their retention windows
+type Jobs struct {
+	CounterRepairSample   int
+	CounterRepairInterval time.Duration
+	ContentDeletionBatch  int
+	// DeletionWorkers purge deleted videos' files and rows
+	DeletionWorkers      int
+	DeletionPollInterval time.Duration
+	DeletionTimeout      time.Duration
+	PurgeBatch           int
+	PurgeInterval        time.Duration
+	StatusMetrics        time.Duration
+	FeatureFlagsPoll     time.Duration
+
+	AccessLogRetention      time.Duration
+	ViewDedupRetention      time.Duration
+	InboundEventRetention   time.Duration
+	ProcessedEventRetention time.Duration
+	IdempotencyKeyTTL       time.Duration
+}
+
+// tuning reads the settings with safe defaults: limits, batch sizes, intervals and
+// feature switches
+func (l *loader) tuning(cfg *Config) {
+	cfg.Auth = Auth{
+		Mode:          l.oneOf("AUTH_MODE", "jwt", "jwt", "header"),
+		Algorithm:     l.str("AUTH_JWT_ALGORITHM", "RS256"),
+		Secret:        []byte(os.Getenv("AUTH_JWT_SECRET")),
+		JWKSURL:       os.Getenv("AUTH_JWKS_URL"),
+		Issuer:        os.Getenv("AUTH_JWT_ISSUER"),
+		Audience:      os.Getenv("AUTH_JWT_AUDIENCE"),
+		RolesClaim:    l.str("AUTH_JWT_ROLES_CLAIM", "roles"),
+		Leeway:        Duration("AUTH_JWT_LEEWAY", 30*time.Second),
+		Impersonation: l.boolean("IMPERSONATION_ENABLED", true),
+	}
+	if path := os.Getenv("AUTH_JWT_PUBLIC_KEY_FILE"); path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			l.invalid("AUTH_JWT_PUBLIC_KEY_FILE", err)
+		}
+		cfg.Auth.PublicKeyPEM = pem
+	}
+	cfg.Sessions = Sessions{
+		CursorSecret:  []byte(os.Getenv("CURSOR_SECRET")),
+		CursorTTL:     Duration("CURSOR_TTL", time.Hour),
+		AnonSecret:    []byte(os.Getenv("ANON_SESSION_SECRET")),
+		AnonTTL:       Duration("ANON_SESSION_TTL", 365*24*time.Hour),
+		AnonRetention: Duration("ANON_DATA_RETENTION", 90*24*time.Hour),
+		AnonMaxRows:   l.integer("ANON_MAX_ROWS", 5000, 1),
+	}
+	cfg.Streaming = Streaming{
+		MaxRows:    l.integer("STREAM_MAX_ROWS", 100000, 1),
+		MaxBytes:   Size("STREAM_MAX_BYTES", 64<<20),
+		FlushEvery: l.integer("STREAM_FLUSH_EVERY", 500, 1),
+	}
+	cfg.Moderation = Moderation{
+		Provider:              l.oneOf("MODERATION_PROVIDER", ModerationProviderNone, ModerationProviderNone, "noop", ModerationProviderHTTP),
+		URL:                   l.str("MODERATION_URL", ""),
+		Timeout:               Duration("MODERATION_TIMEOUT", 3*time.Second),
+		FlagThreshold:         l.fraction("MODERATION_FLAG_THRESHOLD", 0.5),
+		HighSeverityThreshold: l.fraction("MODERATION_HIGH_SEVERITY_THRESHOLD", 0.9),
+		MaxInflight:           l.integer("MODERATION_MAX_INFLIGHT", 16, 1),
+	}
+	// noop is an older name for none
+	if cfg.Moderation.Provider == "noop" {
+		cfg.Moderation.Provider = ModerationProviderNone
+	}
+	if cfg.Moderation.Provider == ModerationProviderHTTP && cfg.Moderation.URL == "" {
+		l.missing("MODERATION_URL (MODERATION_PROVIDER=http)")
+	}
+	cfg.Videos = Videos{
+		UploadMissTTL:     Duration("CATALOG_UPLOAD_MISS_TTL", 2*time.Second),
+		DeleteGrace:       Duration("VIDEO_DELETE_GRACE", 7*24*time.Hour),
+		PlaybackURLTTL:    Duration("PLAYBACK_URL_TTL", time.Hour),
+		ChannelSummaryTTL: Duration("CHANNEL_SUMMARY_TTL", 30*time.Second),
+	}
+	cfg.Comments = Comments{
+		MaxDepth:   l.integer("COMMENT_MAX_DEPTH", 2, 0),
+		EditWindow: Duration("COMMENT_EDIT_WINDOW", 15*time.Minute),
+	}
+	// User-generated text in logs is hashed by default
+	cfg.LogContentPolicy = l.oneOf("LOG_CONTENT_POLICY", logging.PolicyRedact, logging.PolicyRedact, logging.PolicyTruncate, logging.PolicyFull)
+	// Tags column migration: legacy -> dual (backfill + verify) -> new
+	cfg.TagsStorageMode = l.oneOf("TAGS_STORAGE_MODE", models.TagStorageLegacy, models.TagStorageLegacy, models.TagStorageDual, models.TagStorageNew)
+	cfg.AccessLog = Buffer{
+		Size:  l.integer("ACCESS_LOG_BUFFER", 1000, 1),
+		Batch: l.integer("ACCESS_LOG_BATCH", 200, 1),
+		Flush: Duration("ACCESS_LOG_FLUSH", 2*time.Second),
+	}
+	cfg.Engagement = Engagement{
+		NotifyCollapseThreshold: l.integer("NOTIFY_COLLAPSE_THRESHOLD", 5, 1),
+		NotifyCollapseWindow:    Duration("NOTIFY_COLLAPSE_WINDOW", 10*time.Minute),
+		ReadYourWrites:          Duration("COMMENT_READ_YOUR_WRITES", 5*time.Second),
+		ReadYourWritesMax:       l.integer("COMMENT_READ_YOUR_WRITES_MAX", 100000, 1),
+		ViewDedupInterval:       Duration("VIEW_DEDUP_INTERVAL", 30*time.Minute),
+		WatchProgressMinStep:    Duration("WATCH_PROGRESS_MIN_STEP", 5*time.Second),
+		TagsCacheInterval:       Duration("TAGS_CACHE_INTERVAL", 5*time.Minute),
+		TagsCacheMax:            l.integer("TAGS_CACHE_MAX", 5000, 1),
+	}
+	cfg.Thumbnails = Thumbnails{
+		Widths:       l.integers("THUMBNAIL_WIDTHS", []int{160, 320, 640, 1280}),
+		Workers:      l.integer("THUMBNAIL_WORKERS", runtime.NumCPU(), 1),
+		CacheSize:    Size("THUMBNAIL_CACHE_SIZE", 64<<20),
+		FetchTimeout: Duration("THUMBNAIL_FETCH_TIMEOUT", 5*time.Second),
+	}
+	cfg.Events = Events{
+		Catalog:               l.boolean("CATALOG_EVENTS_ENABLED", true),
+		CacheInvalidation:     l.boolean("CACHE_INVALIDATE_ENABLED", true),
+		CacheInvalidateWindow: Duration("CACHE_INVALIDATE_WINDOW", 2*time.Second),
+		WatchMilestones:       l.boolean("WATCH_MILESTONES_ENABLED", true),
+		Archive:               l.boolean("INBOUND_EVENT_ARCHIVE_ENABLED", true),
+		Inbound: Buffer{
+			Size:  l.integer("INBOUND_EVENT_BUFFER", 1000, 1),
+			Batch: l.integer("INBOUND_EVENT_BATCH", 100, 1),
+			Flush: Duration("INBOUND_EVENT_FLUSH", time.Second),
+		},
+		InboundMaxBody: Size("INBOUND_EVENT_MAX_BODY", 256<<10),
+		StrictFields:   l.boolean("EVENT_STRICT_FIELDS", false),
+	}
+	cfg.Outbox = Outbox{
+		PollInterval: Duration("OUTBOX_POLL_INTERVAL", time.Second),
+		BatchSize:    l.integer("OUTBOX_BATCH_SIZE", 100, 1),
+		MaxAttempts:  l.integer("OUTBOX_MAX_ATTEMPTS", 10, 1),
+		BackoffMin:   Duration("OUTBOX_BACKOFF_MIN", time.Second),
+		BackoffMax:   Duration("OUTBOX_BACKOFF_MAX", 5*time.Minute),
+		Retention:    Duration("OUTBOX_RETENTION", 7*24*time.Hour),
+	}
+	cfg.Jobs = Jobs{
+		CounterRepairSample:     l.integer("CATALOG_COUNTER_REPAIR_SAMPLE", 500, 1),
+		CounterRepairInterval:   Duration("CATALOG_COUNTER_REPAIR_INTERVAL", time.Hour),
+		ContentDeletionBatch:    l.integer("CONTENT_DELETION_BATCH", 100, 1),
+		DeletionWorkers:         l.integer("VIDEO_DELETION_WORKERS", 2, 1),
+		DeletionPollInterval:    Duration("VIDEO_DELETION_POLL_INTERVAL", 10*time.Second),
+		DeletionTimeout:         Duration("VIDEO_DELETION_TIMEOUT", 30*time.Minute),
+		PurgeBatch:              l.integer("VIDEO_PURGE_BATCH", 100, 1),
+		PurgeInterval:           Duration("VIDEO_PURGE_INTERVAL", time.Hour),
+		StatusMetrics:           Duration("VIDEO_STATUS_METRICS_INTERVAL", time.Minute),
+		FeatureFlagsPoll:        Duration("FEATURE_FLAGS_POLL_INTERVAL", 30*time.Second),
+		AccessLogRetention:      Duration("ACCESS_LOG_RETENTION", 90*24*time.Hour),
+		ViewDedupRetention:      Duration("VIEW_DEDUP_RETENTION", 7*24*time.Hour),
+		InboundEventRetention:   Duration("INBOUND_EVENT_RETENTION", 14*24*time.Hour),
+		ProcessedEventRetention: Duration("PROCESSED_EVENTS_RETENTION", 7*24*time.Hour),
+		IdempotencyKeyTTL:       Duration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+	}
+	cfg.WarmupDeadline = Duration("WARMUP_DEADLINE", 20*time.Second)
+}
+
+// fraction reads a number between 0 and 1
+func (l *loader) fraction(name string, def float64) float64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || f > 1 {
+		l.invalid(name, fmt.Errorf("want a number between 0 and 1"))
+		return def
+	}
+	return f
+}
+
+// integers reads a comma-separated list of positive integers
+func (l *loader) integers(name string, def []int) []int {
+	var out []int
+	for _, part := range l.list(name, nil) {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 1 {
+			l.invalid(name, fmt.Errorf("%q is not a positive integer", part))
+			return def
+		}
+		out = append(out, n)
+	}
+	if len(out) == 0 {
+		return def
+	}
+	return out
+}
+
+// list reads a comma-separated list, dropping blank entries
+func (l *loader) list(name string, def []string) []string {
+	var out []string
+	for _, part := range strings.Split(os.Getenv(name), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	if len(out) == 0 {
+		return def
+	}
+	return out
+}
@@ -0,0 +1,166 @@
+// Package config reads typed settings from the environment. Durations and sizes
+// accept unit suffixes ("500ms", "3s", "2m", "90d", "10MB"); a value that doesn't
+// parse is recorded and reported by Validate so startup fails instead of silently
+// running with the default.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	mu     sync.Mutex
+	errs   []error
+	logger = zap.NewNop().Sugar()
+)
+
+// SetLogger sets where effective values and deprecation warnings are logged; call it first
+func SetLogger(l *zap.SugaredLogger) {
+	mu.Lock()
+	defer mu.Unlock()
+	logger = l
+}
+
+// Validate returns every parse error recorded so far, naming each variable
+func Validate() error {
+	mu.Lock()
+	defer mu.Unlock()
+	return errors.Join(errs...)
+}
+
+func fail(name, raw string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	errs = append(errs, fmt.Errorf("%s=%q: %w", name, raw, err))
+}
+
+func log() *zap.SugaredLogger {
+	mu.Lock()
+	defer mu.Unlock()
+	return logger
+}
+
+// legacyUnits are the unit-suffixed variable names that predate duration strings.
+// A bare number in NAME_MS is read as milliseconds, and so on.
+var legacyUnits = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"_MS", time.Millisecond},
+	{"_HOURS", time.Hour},
+	{"_DAYS", 24 * time.Hour},
+}
+
+// Duration reads name as a duration string. If name is unset, the deprecated
+// unit-suffixed forms (name_MS, name_HOURS, name_DAYS) are honoured with a warning.
+// Negative values are rejected.
+func Duration(name string, def time.Duration) time.Duration {
+	if raw, ok := os.LookupEnv(name); ok && raw != "" {
+		d, err := ParseDuration(raw)
+		if err != nil {
+			fail(name, raw, err)
+			return def
+		}
+		log().Infow("Config value", "var", name, "raw", raw, "value", d.String())
+		return d
+	}
+	for _, legacy := range legacyUnits {
+		legacyName := name + legacy.suffix
+		raw, ok := os.LookupEnv(legacyName)
+		if !ok || raw == "" {
+			continue
+		}
+		var d time.Duration
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil && n >= 0 {
+			d = time.Duration(n) * legacy.unit
+		} else if parsed, perr := ParseDuration(raw); perr == nil {
+			d = parsed
+		} else {
+			fail(legacyName, raw, perr)
+			return def
+		}
+		log().Warnw("Deprecated config variable; use a duration string instead",
+			"var", legacyName, "replacement", name, "raw", raw, "value", d.String())
+		return d
+	}
+	return def
+}
+
+// ParseDuration parses a Go duration string, plus whole days ("7d"). A bare
+// number is rejected since its unit would be a guess.
+func ParseDuration(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasSuffix(raw, "d") {
+		n, err := strconv.ParseInt(strings.TrimSuffix(raw, "d"), 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration")
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	if n, err := strconv.ParseFloat(raw, 64); err == nil {
+		if n == 0 {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("missing unit (e.g. 500ms, 3s, 2m)")
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration")
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return d, nil
+}
+
+// Size reads name as a byte size such as "64MB", "512KiB" or a plain byte count
+func Size(name string, def int64) int64 {
+	raw, ok := os.LookupEnv(name)
+	if !ok || raw == "" {
+		return def
+	}
+	n, err := ParseSize(raw)
+	if err != nil {
+		fail(name, raw, err)
+		return def
+	}
+	log().Infow("Config value", "var", name, "raw", raw, "value", n)
+	return n
+}
+
+// sizeUnits are checked longest suffix first so "MiB" isn't read as "B"
+var sizeUnits = []struct {
+	suffix string
+	mult   int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseSize parses a byte size with an optional decimal (KB, MB, GB) or binary
+// (KiB, MiB, GiB; K, M, G) unit
+func ParseSize(raw string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(raw))
+	mult := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size (e.g. 10MB, 64MiB, 1048576)")
+	}
+	return int64(n * float64(mult)), nil
+}
@@ -0,0 +1,132 @@
+package cursor_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/cursor"
+)
+
+var (
+	secret  = []byte("cursor-test-secret")
+	issued  = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	filters = cursor.Filters{"user_id": "alice", "visibility": "public"}
+	pos     = cursor.TimeID{CreatedAt: issued.Add(-time.Hour), ID: 42}
+)
+
+func newCodec(ttl time.Duration, now time.Time) *cursor.Codec {
+	c := cursor.NewCodec(secret, ttl)
+	c.SetNow(func() time.Time { return now })
+	return c
+}
+
+func TestRoundTrip(t *testing.T) {
+	c := newCodec(time.Hour, issued)
+	token, err := c.Encode("created_at_desc", filters, pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Filter order doesn't matter, only the values
+	reordered := cursor.Filters{"visibility": "public", "user_id": "alice"}
+	var got cursor.TimeID
+	if err := c.Decode(token, "created_at_desc", reordered, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !got.CreatedAt.Equal(pos.CreatedAt) || got.ID != pos.ID {
+		t.Errorf("position = %+v, want %+v", got, pos)
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	c := newCodec(time.Hour, issued)
+	token, _ := c.Encode("created_at_desc", filters, pos)
+	body, sig, _ := strings.Cut(token, ".")
+
+	// A client rewrites the position and re-encodes it without the key
+	raw, _ := base64.RawURLEncoding.DecodeString(body)
+	forged := strings.Replace(string(raw), `"i":42`, `"i":1`, 1)
+	if forged == string(raw) {
+		t.Fatal("test payload has no id to forge")
+	}
+	forgedToken := base64.RawURLEncoding.EncodeToString([]byte(forged)) + "." + sig
+
+	tests := []struct {
+		name    string
+		codec   *cursor.Codec
+		token   string
+		sort    string
+		filters cursor.Filters
+		reason  string
+	}{
+		{"forged position", c, forgedToken, "created_at_desc", filters, "bad signature"},
+		{"truncated signature", c, token[:len(token)-2], "created_at_desc", filters, "bad signature"},
+		{"signature with extra bytes", c, token + "AA", "created_at_desc", filters, "bad signature"},
+		{"signed by another deployment", cursor.NewCodec([]byte("other"), time.Hour), token, "created_at_desc", filters, "bad signature"},
+		{"no signature", c, body, "created_at_desc", filters, "malformed"},
+		{"not base64", c, "!!!." + sig, "created_at_desc", filters, "malformed"},
+		{"different sort", c, token, "created_at_asc", filters, "different sort"},
+		{"different filter value", c, token, "created_at_desc", cursor.Filters{"user_id": "bob", "visibility": "public"}, "different filters"},
+		{"filter dropped", c, token, "created_at_desc", cursor.Filters{"user_id": "alice"}, "different filters"},
+		{"filter added", c, token, "created_at_desc", cursor.Filters{"user_id": "alice", "visibility": "public", "category": "music"}, "different filters"},
+		{"expired", newCodec(time.Hour, issued.Add(61*time.Minute)), token, "created_at_desc", filters, "expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got cursor.TimeID
+			err := tt.codec.Decode(tt.token, tt.sort, tt.filters, &got)
+			if !errors.Is(err, cursor.ErrInvalid) || !strings.Contains(err.Error(), tt.reason) {
+				t.Fatalf("err = %v, want invalid cursor: %s", err, tt.reason)
+			}
+		})
+	}
+}
+
+func TestExpiryDisabled(t *testing.T) {
+	token, _ := newCodec(0, issued).Encode("s", filters, pos)
+	var got cursor.TimeID
+	if err := newCodec(0, issued.Add(365*24*time.Hour)).Decode(token, "s", filters, &got); err != nil {
+		t.Fatalf("ttl 0 should never expire: %v", err)
+	}
+}
+
+func TestVersionUpgrade(t *testing.T) {
+	c := newCodec(time.Hour, issued)
+	// A version-0 cursor kept its position as a bare id
+	old := cursor.Payload{Version: cursor.CurrentVersion - 1, Sort: "created_at_desc", Filter: filters.Fingerprint(),
+		IssuedAt: issued.Unix(), Keys: json.RawMessage(`42`)}
+	token := c.EncodePayload(old)
+
+	var got cursor.TimeID
+	err := c.Decode(token, "created_at_desc", filters, &got)
+	if !errors.Is(err, cursor.ErrInvalid) || !strings.Contains(err.Error(), "unsupported version") {
+		t.Fatalf("without an upgrade: err = %v, want unsupported version", err)
+	}
+
+	cursor.SetUpgrade(t, old.Version, func(p cursor.Payload) (cursor.Payload, error) {
+		var id uint
+		if err := json.Unmarshal(p.Keys, &id); err != nil {
+			return p, err
+		}
+		p.Keys, _ = json.Marshal(cursor.TimeID{ID: id})
+		p.Version++
+		return p, nil
+	})
+	if err := c.Decode(token, "created_at_desc", filters, &got); err != nil || got.ID != 42 {
+		t.Fatalf("upgraded cursor = %+v, %v", got, err)
+	}
+
+	// A failing upgrade and a cursor from a newer build are both invalid
+	cursor.SetUpgrade(t, old.Version, func(p cursor.Payload) (cursor.Payload, error) { return p, errors.New("no id") })
+	if err := c.Decode(token, "created_at_desc", filters, &got); !errors.Is(err, cursor.ErrInvalid) {
+		t.Errorf("failed upgrade: err = %v", err)
+	}
+	future := old
+	future.Version = cursor.CurrentVersion + 1
+	if err := c.Decode(c.EncodePayload(future), "created_at_desc", filters, &got); !errors.Is(err, cursor.ErrInvalid) {
+		t.Errorf("newer version: err = %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package cursor
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// Payload exposes the signed body so tests can mint cursors of older versions
+type Payload = payload
+
+// CurrentVersion is the version new cursors carry
+const CurrentVersion = currentVersion
+
+// SetNow replaces the codec's clock
+func (c *Codec) SetNow(now func() time.Time) { c.now = now }
+
+// EncodePayload signs p as-is, as an older or newer build of the service would have
+func (c *Codec) EncodePayload(p Payload) string {
+	body, _ := json.Marshal(p)
+	return b64(body) + "." + b64(c.sign(body))
+}
+
+// SetUpgrade registers an upgrade from version for the rest of the test
+func SetUpgrade(t testing.TB, version int, upgrade func(Payload) (Payload, error)) {
+	upgrades[version] = upgrade
+	t.Cleanup(func() { delete(upgrades, version) })
+}
@@ -1,61 +1,311 @@
 package db
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"os"
+	"strings"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/logging"
 	"github.com/streamhive/video-catalog-api/internal/models"
 )
 
-// NewConnection creates a new database connection
-func NewConnection() (*gorm.DB, error) {
-	dsn := getDSN()
+// NewConnection creates a new database connection to the primary cfg names
+func NewConnection(cfg config.Database) (*gorm.DB, error) {
+	return open(cfg.DSN(), cfg)
+}
 
+// open connects to dsn with cfg's logging and query timeout
+func open(dsn string, cfg config.Database) (*gorm.DB, error) {
 	config := &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
+		// Map driver errors such as unique violations onto gorm's sentinel errors
+		TranslateError: true,
 	}
 
-	if os.Getenv("DB_LOG_LEVEL") == "debug" {
+	if cfg.Debug {
 		config.Logger = logger.Default.LogMode(logger.Info)
+		// SQL traces would otherwise interpolate titles, descriptions and comments
+		if logging.ContentPolicy() != logging.PolicyFull {
+			config.Logger = logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+				SlowThreshold:        200 * time.Millisecond,
+				LogLevel:             logger.Info,
+				Colorful:             true,
+				ParameterizedQueries: true,
+			})
+		}
 	}
 
 	db, err := gorm.Open(postgres.Open(dsn), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	if err := Trace(db); err != nil {
+		return nil, fmt.Errorf("register query tracing: %w", err)
+	}
+	if cfg.QueryTimeout > 0 {
+		if err := BoundQueries(db, cfg.QueryTimeout); err != nil {
+			return nil, fmt.Errorf("register query timeout: %w", err)
+		}
+	}
 
 	return db, nil
 }
 
+// legacyIndexes were replaced by differently shaped indexes. AutoMigrate only adds
+// indexes, so these are dropped once their replacements exist.
+var legacyIndexes = []string{
+	// unique upload_id over all rows, including soft-deleted; now idx_videos_upload_id_active
+	"idx_videos_upload_id",
+}
+
 // RunMigrations runs database migrations
 func RunMigrations(db *gorm.DB) error {
+	// Checked before AutoMigrate adds the columns, which starts every row at zero
+	var backfills []counterBackfill
+	for _, b := range counterBackfills {
+		if db.Migrator().HasTable(b.model) && !db.Migrator().HasColumn(b.model, b.column) {
+			backfills = append(backfills, b)
+		}
+	}
+	seedCategories := !db.Migrator().HasTable(&models.Category{})
+	if err := AutoMigrate(db); err != nil {
+		return err
+	}
+	if seedCategories {
+		if err := migrateCategories(db); err != nil {
+			return err
+		}
+	}
+	for _, b := range backfills {
+		if err := db.Exec(b.update, b.args...).Error; err != nil {
+			return fmt.Errorf("backfill %s: %w", b.column, err)
+		}
+	}
+	if err := migrateVisibility(db); err != nil {
+		return err
+	}
+	for _, name := range legacyIndexes {
+		if err := db.Exec("DROP INDEX IF EXISTS " + name).Error; err != nil {
+			return fmt.Errorf("drop legacy index %s: %w", name, err)
+		}
+	}
+	return protectAppendOnly(db)
+}
+
+// counterBackfill sets a denormalized counter on existing rows when its column is
+// first added, so the counter repair job doesn't find the whole table drifted. Each
+// counts what the repair job counts.
+type counterBackfill struct {
+	model  interface{}
+	column string
+	update string
+	args   []interface{}
+}
+
+var counterBackfills = []counterBackfill{
+	// Visible comments that aren't soft-deleted
+	{&models.Video{}, "comment_count", `UPDATE videos SET comment_count = (
+		SELECT COUNT(*) FROM comments c
+		WHERE c.video_id = videos.id AND c.deleted_at IS NULL AND c.status = ?)`, []interface{}{models.CommentVisible}},
+	{&models.Video{}, "like_count", `UPDATE videos SET like_count = (
+		SELECT COUNT(*) FROM video_reactions r WHERE r.video_id = videos.id AND r.value = ?)`, []interface{}{models.ReactionLike}},
+	{&models.Video{}, "dislike_count", `UPDATE videos SET dislike_count = (
+		SELECT COUNT(*) FROM video_reactions r WHERE r.video_id = videos.id AND r.value = ?)`, []interface{}{models.ReactionDislike}},
+	{&models.Comment{}, "like_count", `UPDATE comments SET like_count = (
+		SELECT COUNT(*) FROM comment_reactions r WHERE r.comment_id = comments.id)`, nil},
+}
+
+// migrateCategories runs once, when the categories table is created: it seeds
+// models.DefaultCategories, then moves every free-text category already on videos
+// onto a slug. A value matching a category's slug once slugified, or its display
+// name ignoring case, takes that category; anything else becomes a new active
+// category named after its most common spelling, for admins to tidy up. Values
+// with nothing to slugify are cleared.
+func migrateCategories(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		seed := make([]models.Category, len(models.DefaultCategories))
+		copy(seed, models.DefaultCategories)
+		for i := range seed {
+			seed[i].Active = true
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+			return fmt.Errorf("seed categories: %w", err)
+		}
+		known := map[string]string{}
+		for _, c := range seed {
+			known[c.Slug] = c.Slug
+			known[strings.ToLower(c.DisplayName)] = c.Slug
+		}
+
+		// Soft-deleted videos too, so a restored video comes back with a valid category
+		var values []string
+		if err := tx.Raw(`SELECT category FROM videos WHERE category <> ''
+			GROUP BY category ORDER BY COUNT(*) DESC, category`).Scan(&values).Error; err != nil {
+			return fmt.Errorf("list video categories: %w", err)
+		}
+		for _, value := range values {
+			slug := models.CategorySlug(value)
+			if match, ok := known[strings.ToLower(strings.TrimSpace(value))]; ok {
+				slug = match
+			} else if _, ok := known[slug]; !ok && slug != "" {
+				name := []rune(strings.TrimSpace(value))
+				if len(name) > models.MaxCategoryNameLen {
+					name = name[:models.MaxCategoryNameLen]
+				}
+				if err := tx.Create(&models.Category{Slug: slug, DisplayName: string(name), Active: true}).Error; err != nil {
+					return fmt.Errorf("create category %s: %w", slug, err)
+				}
+				known[slug] = slug
+			}
+			if slug == value {
+				continue
+			}
+			if err := tx.Exec("UPDATE videos SET category = ? WHERE category = ?", slug, value).Error; err != nil {
+				return fmt.Errorf("map category %q to %q: %w", value, slug, err)
+			}
+		}
+		return nil
+	})
+}
+
+// migrateVisibility maps the is_private flag that visibility replaced onto it
+// (private stays private, everything else is public), gives the private rows share
+// tokens, and drops the old column. It does nothing once the column is gone.
+func migrateVisibility(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.Video{}, "is_private") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Two random UUIDs without dashes: 64 hex characters, like newShareToken
+		if err := tx.Exec(`UPDATE videos SET visibility = ?,
+			share_token = replace(gen_random_uuid()::text || gen_random_uuid()::text, '-', '')
+			WHERE is_private`, models.VisibilityPrivate).Error; err != nil {
+			return fmt.Errorf("map is_private to visibility: %w", err)
+		}
+		if err := tx.Exec("ALTER TABLE videos DROP COLUMN is_private").Error; err != nil {
+			return fmt.Errorf("drop is_private: %w", err)
+		}
+		return nil
+	})
+}
+
+// appendOnlyTables keep a permanent record: the database rejects UPDATE, DELETE
+// and TRUNCATE on them, whoever connects
+var appendOnlyTables = []string{"public_event_log"}
+
+// protectAppendOnly (re)creates the triggers guarding appendOnlyTables
+func protectAppendOnly(db *gorm.DB) error {
+	if err := db.Exec(`CREATE OR REPLACE FUNCTION reject_append_only_change() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END
+$$ LANGUAGE plpgsql`).Error; err != nil {
+		return fmt.Errorf("create append-only function: %w", err)
+	}
+	for _, table := range appendOnlyTables {
+		for _, stmt := range []string{
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %[1]s_append_only ON %[1]s", table),
+			fmt.Sprintf("CREATE TRIGGER %[1]s_append_only BEFORE UPDATE OR DELETE ON %[1]s FOR EACH ROW EXECUTE FUNCTION reject_append_only_change()", table),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %[1]s_no_truncate ON %[1]s", table),
+			fmt.Sprintf("CREATE TRIGGER %[1]s_no_truncate BEFORE TRUNCATE ON %[1]s FOR EACH STATEMENT EXECUTE FUNCTION reject_append_only_change()", table),
+		} {
+			if err := db.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("protect %s: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// AutoMigrate creates or updates every model's table. RunMigrations runs it along
+// with the data migrations; tests use it on its own.
+func AutoMigrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&models.Video{},
-	&models.Comment{},
+		&models.Comment{},
+		&models.ModerationFlag{},
+		&models.VideoAccessLog{},
+		&models.MigrationCheckpoint{},
+		&models.Notification{},
+		&models.DataExport{},
+		&models.QuarantinedEvent{},
+		&models.JobRun{},
+		&models.AuditLog{},
+		&models.AnonymousSession{},
+		&models.ProcessedEvent{},
+		&models.VideoView{},
+		&models.VideoReaction{},
+		&models.PublicEvent{},
+		&models.BlobCleanupCheckpoint{},
+		&models.FeatureFlag{},
+		&models.OutboxMessage{},
+		&models.VideoDeletion{},
+		&models.DeletionItem{},
+		&models.VideoStatusChange{},
+		&models.VideoRendition{},
+		&models.Category{},
+		&models.UserContentDeletion{},
+		&models.IdempotencyKey{},
+		&models.VideoReport{},
+		&models.CommentReaction{},
+		&models.WatchProgress{}, &models.VideoThumbnail{}, &models.InboundEvent{},
 	)
 }
 
-// getDSN constructs the database connection string from environment variables
-func getDSN() string {
-	host := getEnv("DB_HOST", "localhost")
-	port := getEnv("DB_PORT", "5432")
-	user := getEnv("DB_USER", "postgres")
-	password := getEnv("DB_PASSWORD", "postgres")
-	dbname := getEnv("DB_NAME", "video_catalog")
-	sslmode := getEnv("DB_SSLMODE", "disable")
-	timezone := getEnv("DB_TIMEZONE", "UTC")
+// migrationLockKey is the Postgres advisory lock that serializes migrations across replicas
+const migrationLockKey = 7423001
 
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
-		host, port, user, password, dbname, sslmode, timezone)
+// RunMigrationsLocked runs migrations while holding a session advisory lock, so
+// replicas booting together migrate one at a time and none proceeds before the
+// schema is current. ctx bounds the wait for the lock.
+func RunMigrationsLocked(ctx context.Context, db *gorm.DB) error {
+	// Waiting for another replica's migrations can take far longer than a query may
+	return db.WithContext(WithoutQueryTimeout(ctx)).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error; err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		// Unlock even if ctx is done; the connection goes back to the pool still holding it otherwise
+		defer conn.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(?)", migrationLockKey)
+		return RunMigrations(conn)
+	})
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// Ping verifies the database is reachable
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
 	}
-	return defaultValue
+	return sqlDB.PingContext(ctx)
+}
+
+// SelfCheck is a cheap query exercising one index the hot paths depend on
+type SelfCheck struct {
+	Name  string
+	Query string
+}
+
+// IndexSelfChecks touches each critical index once so the first real requests
+// don't pay for cold index pages.
+var IndexSelfChecks = []SelfCheck{
+	{Name: "videos_upload_id", Query: "SELECT id FROM videos WHERE upload_id = '' AND deleted_at IS NULL LIMIT 1"},
+	{Name: "videos_user_id", Query: "SELECT id FROM videos WHERE user_id = '' AND deleted_at IS NULL LIMIT 1"},
+	{Name: "videos_public_listing", Query: "SELECT id FROM videos WHERE visibility = 'public' AND deleted_at IS NULL ORDER BY created_at DESC LIMIT 20"},
+	{Name: "comments_video_id", Query: "SELECT id FROM comments WHERE video_id = 0 AND deleted_at IS NULL LIMIT 1"},
+	{Name: "moderation_flags_status", Query: "SELECT id FROM moderation_flags WHERE status = '' LIMIT 1"},
+}
+
+// RunSelfCheck executes a self-check query and discards the result
+func RunSelfCheck(ctx context.Context, db *gorm.DB, check SelfCheck) error {
+	var ids []uint
+	return db.WithContext(ctx).Raw(check.Query).Scan(&ids).Error
 }
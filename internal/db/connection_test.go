@@ -0,0 +1,105 @@
+package db_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/db"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+func TestRunMigrationsBackfillsCommentCount(t *testing.T) {
+	gdb := dbtest.Open(t)
+	videos := []models.Video{{UploadID: "up-1", UserID: "owner", Title: "t"}, {UploadID: "up-2", UserID: "owner", Title: "t"}}
+	if err := gdb.Create(&videos).Error; err != nil {
+		t.Fatal(err)
+	}
+	comments := []models.Comment{
+		{VideoID: videos[0].ID, UserID: "a", Content: "x", Status: models.CommentVisible},
+		{VideoID: videos[0].ID, UserID: "b", Content: "x", Status: models.CommentVisible},
+		{VideoID: videos[0].ID, UserID: "c", Content: "x", Status: models.CommentPending},
+		{VideoID: videos[0].ID, UserID: "d", Content: "x", Status: models.CommentVisible},
+		{VideoID: videos[1].ID, UserID: "e", Content: "x", Status: models.CommentVisible},
+	}
+	if err := gdb.Create(&comments).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := gdb.Delete(&comments[3]).Error; err != nil {
+		t.Fatal(err)
+	}
+	// As before the counter existed
+	if err := gdb.Migrator().DropColumn(&models.Video{}, "comment_count"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The append-only triggers are plpgsql, so on SQLite the run stops there, after
+	// the backfills
+	if err := db.RunMigrations(gdb); err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Fatalf("RunMigrations: %v, want it to reach the append-only triggers", err)
+	}
+	var got []models.Video
+	gdb.Order("id").Find(&got)
+	if len(got) != 2 || got[0].CommentCount != 2 || got[1].CommentCount != 1 {
+		t.Errorf("backfilled counts = %+v, want 2 and 1 visible, undeleted comments", got)
+	}
+
+	// Once the column exists its values are left alone
+	gdb.Model(&models.Video{}).Where("id = ?", videos[1].ID).Update("comment_count", 7)
+	db.RunMigrations(gdb)
+	var second models.Video
+	gdb.First(&second, videos[1].ID)
+	if second.CommentCount != 7 {
+		t.Errorf("comment_count = %d after a second run, want it untouched", second.CommentCount)
+	}
+}
+
+func TestRunMigrationsMapsCategories(t *testing.T) {
+	gdb := dbtest.Open(t)
+	// Free-text categories, as before the table existed
+	if err := gdb.Migrator().DropTable(&models.Category{}); err != nil {
+		t.Fatal(err)
+	}
+	values := []string{"Music", "music", "Müsic", "Cooking Tips", "Cooking Tips", "cooking tips", "!!!", ""}
+	for i, value := range values {
+		video := models.Video{UploadID: "up-" + strings.Repeat("x", i+1), UserID: "owner", Title: "t", Category: value}
+		if err := gdb.Create(&video).Error; err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 {
+			gdb.Delete(&video)
+		}
+	}
+
+	if err := db.RunMigrations(gdb); err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Fatalf("RunMigrations: %v, want it to reach the append-only triggers", err)
+	}
+	var got []string
+	gdb.Unscoped().Model(&models.Video{}).Order("id").Pluck("category", &got)
+	want := []string{"music", "music", "music", "cooking-tips", "cooking-tips", "cooking-tips", "", ""}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("categories = %q, want %q", got, want)
+	}
+
+	var categories []models.Category
+	gdb.Order("slug").Find(&categories)
+	if len(categories) != len(models.DefaultCategories)+1 {
+		t.Errorf("%d categories, want the defaults and cooking-tips", len(categories))
+	}
+	var cooking models.Category
+	if err := gdb.First(&cooking, "slug = ?", "cooking-tips").Error; err != nil || cooking.DisplayName != "Cooking Tips" || !cooking.Active {
+		t.Errorf("cooking-tips = %+v, %v; want an active category named after its most common spelling", cooking, err)
+	}
+
+	// Seeding and mapping run only when the table is created
+	gdb.Model(&models.Category{}).Where("slug = ?", "music").Update("active", false)
+	gdb.Model(&models.Video{}).Where("category = ?", "cooking-tips").Update("category", "Cooking Tips")
+	db.RunMigrations(gdb)
+	var music models.Category
+	gdb.First(&music, "slug = ?", "music")
+	var unmapped int64
+	gdb.Model(&models.Video{}).Where("category = ?", "Cooking Tips").Count(&unmapped)
+	if music.Active || unmapped != 3 {
+		t.Errorf("second run touched categories: music active %v, %d videos unmapped", music.Active, unmapped)
+	}
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+)
+
+// NewReplicaConnection opens the optional read replica at cfg.ReplicaHost (and
+// ReplicaPort, defaulting to the primary's port). It returns nil when no replica is
+// configured.
+func NewReplicaConnection(cfg config.Database) (*gorm.DB, error) {
+	if cfg.ReplicaHost == "" {
+		return nil, nil
+	}
+	replica, err := open(cfg.ReplicaDSN(), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("replica: %w", err)
+	}
+	return replica, nil
+}
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so reads made with it go to the primary, for callers that
+// must see their own recent writes despite replica lag
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// PrimaryRequested reports whether ctx was marked by WithPrimary
+func PrimaryRequested(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryKey{}).(bool)
+	return v
+}
+
+// Reader picks the connection for a read: the replica if there is one, unless ctx
+// asks for the primary
+func Reader(ctx context.Context, primary, replica *gorm.DB) *gorm.DB {
+	if replica == nil || PrimaryRequested(ctx) {
+		return primary.WithContext(ctx)
+	}
+	return replica.WithContext(ctx)
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// timeoutKey holds a statement's cancel func and the context it replaced
+const timeoutKey = "catalog:timeout"
+
+type noTimeoutKey struct{}
+
+// WithoutQueryTimeout marks ctx so statements run with it aren't bounded by the
+// per-query timeout, for work that is bounded some other way: migrations waiting on
+// the migration lock, and background jobs with a timeout of their own
+func WithoutQueryTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noTimeoutKey{}, true)
+}
+
+type boundedStatement struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+// BoundQueries bounds every statement run on gdb by timeout. NewConnection does
+// this when DB_QUERY_TIMEOUT is set.
+func BoundQueries(gdb *gorm.DB, timeout time.Duration) error {
+	return gdb.Use(timeoutPlugin{timeout: timeout})
+}
+
+// timeoutPlugin bounds each statement by timeout, on top of whatever deadline its
+// context already has, so a slow database fails requests instead of piling up
+// goroutines. Row and Rows are left alone: their result is read after the statement
+// returns, and cancelling would cut the read short.
+type timeoutPlugin struct {
+	timeout time.Duration
+}
+
+func (timeoutPlugin) Name() string { return "catalog:query_timeout" }
+
+func (p timeoutPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		name   string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("catalog:timeout_before_"+h.name, p.bound); err != nil {
+			return err
+		}
+		if err := h.after("catalog:timeout_after_"+h.name, release); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p timeoutPlugin) bound(tx *gorm.DB) {
+	parent := tx.Statement.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	if exempt, _ := parent.Value(noTimeoutKey{}).(bool); exempt {
+		return
+	}
+	ctx, cancel := context.WithTimeout(parent, p.timeout)
+	tx.Statement.Context = ctx
+	tx.InstanceSet(timeoutKey, boundedStatement{parent: parent, cancel: cancel})
+}
+
+// release cancels the statement's timeout and restores its context, since a chained
+// query (Count then Find) runs its next statement on the same one
+func release(tx *gorm.DB) {
+	v, ok := tx.InstanceGet(timeoutKey)
+	if !ok {
+		return
+	}
+	b := v.(boundedStatement)
+	b.cancel()
+	tx.Statement.Context = b.parent
+}
@@ -0,0 +1,82 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/db"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// slowStatement counts to rows one row at a time; a few million take SQLite
+// well over the timeouts used here
+func slowStatement(rows int) string {
+	return "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n LIMIT " + strconv.Itoa(rows) + ") SELECT COUNT(*) FROM n"
+}
+
+func boundedDB(t *testing.T, timeout time.Duration) *gorm.DB {
+	t.Helper()
+	gdb := dbtest.Open(t)
+	if err := db.BoundQueries(gdb, timeout); err != nil {
+		t.Fatal(err)
+	}
+	return gdb
+}
+
+func TestQueryTimeout(t *testing.T) {
+	gdb := boundedDB(t, 20*time.Millisecond)
+	ctx := context.Background()
+
+	start := time.Now()
+	err := gdb.WithContext(ctx).Exec(slowStatement(50_000_000)).Error
+	if err == nil {
+		t.Fatal("slow statement finished despite the query timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("slow statement ran for %s with a 20ms timeout", elapsed)
+	}
+
+	// Fast statements are unaffected, and a chained query doesn't inherit the
+	// previous statement's cancelled context
+	gdb.Create(&models.Video{UploadID: "up-1", UserID: "owner", Title: "t"})
+	tx := gdb.WithContext(ctx).Model(&models.Video{}).Where("user_id = ?", "owner")
+	var n int64
+	var videos []models.Video
+	if err := tx.Count(&n).Error; err != nil || n != 1 {
+		t.Fatalf("Count = %d, %v", n, err)
+	}
+	if err := tx.Find(&videos).Error; err != nil || len(videos) != 1 {
+		t.Errorf("Find after Count = %d videos, %v", len(videos), err)
+	}
+}
+
+func TestWithoutQueryTimeout(t *testing.T) {
+	gdb := boundedDB(t, 20*time.Millisecond)
+	// Long enough to outlast the timeout many times over, short enough to finish
+	if err := gdb.WithContext(db.WithoutQueryTimeout(context.Background())).Exec(slowStatement(2_000_000)).Error; err != nil {
+		t.Errorf("exempt statement: %v", err)
+	}
+}
+
+func TestQueryTimeoutKeepsCallerDeadline(t *testing.T) {
+	gdb := boundedDB(t, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := gdb.WithContext(ctx).Exec(slowStatement(50_000_000)).Error
+	if err == nil || ctx.Err() == nil {
+		t.Fatalf("statement returned %v, want it cut short by the caller's deadline", err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	var video models.Video
+	if err := gdb.WithContext(cancelled).First(&video).Error; !errors.Is(err, context.Canceled) {
+		t.Errorf("query with a cancelled context: %v, want context.Canceled", err)
+	}
+}
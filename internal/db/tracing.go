@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/tracing"
+)
+
+// spanKey is where a statement's span and the context it replaced wait between
+// the before and after callbacks
+const spanKey = "catalog:span"
+
+type tracedStatement struct {
+	parent context.Context
+	span   trace.Span
+}
+
+// Trace registers query tracing on gdb. NewConnection does this for every
+// connection it opens.
+func Trace(gdb *gorm.DB) error {
+	return gdb.Use(tracingPlugin{})
+}
+
+// tracingPlugin traces every statement as a client span under the span in the
+// statement's context. Queries run without WithContext start a trace of their own.
+// Only the parameterized SQL is recorded, never the bound values.
+type tracingPlugin struct{}
+
+func (tracingPlugin) Name() string { return "catalog:tracing" }
+
+func (tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		name   string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		// Ended before preloading, so preload queries are siblings rather than children
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Before("gorm:preload").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("catalog:trace_before_"+h.name, startSpan(h.name)); err != nil {
+			return err
+		}
+		if err := h.after("catalog:trace_after_"+h.name, endSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startSpan(op string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		parent := tx.Statement.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, span := tracing.Start(parent, "db."+op, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("db.system", "postgresql"), attribute.String("db.operation", op)))
+		tx.Statement.Context = ctx
+		tx.InstanceSet(spanKey, tracedStatement{parent: parent, span: span})
+	}
+}
+
+func endSpan(tx *gorm.DB) {
+	v, ok := tx.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	traced := v.(tracedStatement)
+	span := traced.span
+	// A chained query (Count then Find) runs its next statement on the same Statement
+	tx.Statement.Context = traced.parent
+	if tx.Statement.Table != "" {
+		span.SetAttributes(attribute.String("db.sql.table", tx.Statement.Table))
+	}
+	span.SetAttributes(attribute.String("db.statement", tx.Statement.SQL.String()), attribute.Int64("db.rows_affected", tx.RowsAffected))
+	err := tx.Error
+	// An empty lookup is an answer, not a failed query
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,147 @@
+// Package dbtest opens throwaway databases for tests. SQLite stands in for
+// Postgres: every model's table is created, and the few Postgres functions the
+// services call are registered on each connection. Code that needs more of
+// Postgres (row locks, jsonb operators, xmax) is tested above or below
+// the database instead.
+package dbtest
+
+import (
+	"database/sql"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/streamhive/video-catalog-api/internal/db"
+)
+
+const driverName = "sqlite3_catalog_test"
+
+var register sync.Once
+
+// Open returns a migrated database private to t, removed when t ends
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	register.Do(func() {
+		sql.Register(driverName, &sqlite3.SQLiteDriver{ConnectHook: postgresFunctions})
+	})
+	// Transactions take the write lock up front, as the advisory locks would on
+	// Postgres; a deferred one upgrading under contention fails without waiting
+	dsn := filepath.Join(t.TempDir(), "catalog.db") + "?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+	gdb, err := gorm.Open(sqlite.Dialector{DriverName: driverName, DSN: dsn}, &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := db.AutoMigrate(gdb); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		t.Fatalf("test database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return gdb
+}
+
+// postgresFunctions registers the Postgres functions SQLite lacks
+func postgresFunctions(conn *sqlite3.SQLiteConn) error {
+	if err := conn.RegisterFunc("greatest", greatest, true); err != nil {
+		return err
+	}
+	if err := conn.RegisterFunc("least", least, true); err != nil {
+		return err
+	}
+	if err := conn.RegisterFunc("hashtext", hashtext, true); err != nil {
+		return err
+	}
+	if err := conn.RegisterFunc("now", now, false); err != nil {
+		return err
+	}
+	if err := conn.RegisterFunc("strpos", strpos, true); err != nil {
+		return err
+	}
+	// SQLite already serializes writers, so transaction-scoped advisory locks have nothing to add
+	return conn.RegisterFunc("pg_advisory_xact_lock", func(key int64) interface{} { return nil }, false)
+}
+
+// hashtext hashes a lock name to an advisory lock key
+func hashtext(s string) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return int64(int32(h.Sum32()))
+}
+
+// now is the current time in the format the SQLite driver reads back as a time
+func now() string {
+	return time.Now().UTC().Format("2006-01-02 15:04:05.999999999-07:00")
+}
+
+// strpos is the 1-based character position of substr in s, or 0 if it isn't there
+func strpos(s, substr string) int {
+	i := strings.Index(s, substr)
+	if i < 0 {
+		return 0
+	}
+	return utf8.RuneCountInString(s[:i]) + 1
+}
+
+func greatest(args ...interface{}) interface{} {
+	return pick(args, func(c int) bool { return c > 0 })
+}
+
+func least(args ...interface{}) interface{} {
+	return pick(args, func(c int) bool { return c < 0 })
+}
+
+// pick returns the argument better wins for, ignoring NULLs as Postgres does.
+// Numbers compare by value; text, which is how SQLite stores timestamps, compares
+// as strings.
+func pick(args []interface{}, better func(c int) bool) interface{} {
+	var best interface{}
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+		if best == nil || better(compare(arg, best)) {
+			best = arg
+		}
+	}
+	return best
+}
+
+func compare(a, b interface{}) int {
+	if as, ok := a.(string); ok {
+		if bs, ok := b.(string); ok {
+			return strings.Compare(as, bs)
+		}
+	}
+	x, y := number(a), number(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func number(v interface{}) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// envelopeFields are payload keys read outside the event structs
+var envelopeFields = []string{"producedAt"}
+
+// knownFields caches the lower-cased JSON names declared by each event type
+var knownFields sync.Map // reflect.Type -> map[string]bool
+
+// UnknownFields returns, sorted, the top-level keys of a JSON object payload that
+// the json tags of v's type don't declare. Matching is case-insensitive, as in
+// encoding/json. A payload that isn't an object yields nothing.
+func UnknownFields(body []byte, v interface{}) []string {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+	known := fieldsOf(reflect.TypeOf(v))
+	var unknown []string
+	for key := range payload {
+		if !known[strings.ToLower(key)] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+func fieldsOf(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if cached, ok := knownFields.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+	known := map[string]bool{}
+	for _, name := range envelopeFields {
+		known[strings.ToLower(name)] = true
+	}
+	collectFields(t, known)
+	knownFields.Store(t, known)
+	return known
+}
+
+func collectFields(t reflect.Type, known map[string]bool) {
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			collectFields(f.Type, known)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		known[strings.ToLower(name)] = true
+	}
+}
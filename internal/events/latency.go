@@ -0,0 +1,134 @@
+package events
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+)
+
+// Latency stages, both measured from the producer timestamp
+const (
+	// StageReceived is when the message was taken off the queue
+	StageReceived = "received"
+	// StageHandled is when the event was applied and its effect visible in the catalog
+	StageHandled = "handled"
+)
+
+// latencyWindowSize is how many recent samples per kind and stage feed the status quantiles
+const latencyWindowSize = 1024
+
+// ProducedAt returns the producer timestamp of a message: "producedAt" in the JSON
+// payload, then the envelope's (x-produced-at header or AMQP timestamp). The second
+// result is false when the producer supplied none.
+func ProducedAt(body []byte, md Metadata) (time.Time, bool) {
+	var probe struct {
+		ProducedAt *time.Time `json:"producedAt"`
+	}
+	if err := json.Unmarshal(body, &probe); err == nil && probe.ProducedAt != nil && !probe.ProducedAt.IsZero() {
+		return *probe.ProducedAt, true
+	}
+	if !md.ProducedAt.IsZero() {
+		return md.ProducedAt, true
+	}
+	return time.Time{}, false
+}
+
+// ObserveLatency records at − producedAt for an event kind and stage. A negative
+// value means the producer's clock is ahead of ours; it is recorded as zero and
+// counted as skewed.
+func ObserveLatency(kind, stage string, producedAt, at time.Time) {
+	d := at.Sub(producedAt)
+	if d < 0 {
+		metrics.EventLatencySkewedTotal.WithLabelValues(kind, stage).Inc()
+		d = 0
+	}
+	metrics.EventLatency.WithLabelValues(kind, stage).Observe(d.Seconds())
+	latencies.observe(latencyKey{kind, stage}, d)
+}
+
+// LatencyStats summarizes recent latencies for one kind and stage
+type LatencyStats struct {
+	Kind    string `json:"kind"`
+	Stage   string `json:"stage"`
+	Samples int    `json:"samples"`
+	P50Ms   int64  `json:"p50_ms"`
+	P95Ms   int64  `json:"p95_ms"`
+}
+
+// RecentLatencies returns quantiles over the last samples of each kind and stage
+func RecentLatencies() []LatencyStats {
+	return latencies.stats()
+}
+
+// latencyWindows keeps a ring of recent samples per kind/stage
+type latencyWindows struct {
+	mu      sync.Mutex
+	windows map[latencyKey]*latencyRing
+}
+
+type latencyKey struct{ kind, stage string }
+
+type latencyRing struct {
+	samples []time.Duration
+	next    int
+}
+
+var latencies = &latencyWindows{windows: map[latencyKey]*latencyRing{}}
+
+func (w *latencyWindows) observe(key latencyKey, d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r, ok := w.windows[key]
+	if !ok {
+		r = &latencyRing{}
+		w.windows[key] = r
+	}
+	if len(r.samples) < latencyWindowSize {
+		r.samples = append(r.samples, d)
+		return
+	}
+	r.samples[r.next] = d
+	r.next = (r.next + 1) % latencyWindowSize
+}
+
+func (w *latencyWindows) stats() []LatencyStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]LatencyStats, 0, len(w.windows))
+	for key, r := range w.windows {
+		sorted := append([]time.Duration(nil), r.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		out = append(out, LatencyStats{
+			Kind:    key.kind,
+			Stage:   key.stage,
+			Samples: len(sorted),
+			P50Ms:   quantile(sorted, 0.50).Milliseconds(),
+			P95Ms:   quantile(sorted, 0.95).Milliseconds(),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Stage < out[j].Stage
+	})
+	return out
+}
+
+// quantile is the nearest-rank quantile of sorted samples
+func quantile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(q*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
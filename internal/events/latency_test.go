@@ -0,0 +1,116 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+)
+
+func TestProducedAt(t *testing.T) {
+	payload := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	envelope := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		body string
+		md   events.Metadata
+		want time.Time
+		ok   bool
+	}{
+		{"payload wins over envelope", `{"producedAt":"2024-05-01T10:00:00Z"}`, events.Metadata{ProducedAt: envelope}, payload, true},
+		{"payload only", `{"producedAt":"2024-05-01T10:00:00Z"}`, events.Metadata{}, payload, true},
+		{"envelope when payload has none", `{"uploadId":"u1"}`, events.Metadata{ProducedAt: envelope}, envelope, true},
+		{"envelope when payload value is zero", `{"producedAt":"0001-01-01T00:00:00Z"}`, events.Metadata{ProducedAt: envelope}, envelope, true},
+		{"envelope when payload is not JSON", `not json`, events.Metadata{ProducedAt: envelope}, envelope, true},
+		{"envelope when payload value is malformed", `{"producedAt":"yesterday"}`, events.Metadata{ProducedAt: envelope}, envelope, true},
+		{"none", `{"uploadId":"u1"}`, events.Metadata{}, time.Time{}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := events.ProducedAt([]byte(tc.body), tc.md)
+			if ok != tc.ok || !got.Equal(tc.want) {
+				t.Errorf("ProducedAt = %v, %v; want %v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+// histogram returns the sample count and sum recorded for kind and stage
+func histogram(t *testing.T, kind, stage string) (uint64, float64) {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.EventLatency.WithLabelValues(kind, stage).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("read histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
+func TestObserveLatency(t *testing.T) {
+	const kind = "latency-test"
+	produced := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	events.ObserveLatency(kind, events.StageReceived, produced, produced.Add(1500*time.Millisecond))
+	if n, sum := histogram(t, kind, events.StageReceived); n != 1 || sum != 1.5 {
+		t.Errorf("histogram = %d samples summing %v, want 1 summing 1.5", n, sum)
+	}
+	if got := testutil.ToFloat64(metrics.EventLatencySkewedTotal.WithLabelValues(kind, events.StageReceived)); got != 0 {
+		t.Errorf("skewed = %v for a positive latency", got)
+	}
+
+	// The producer's clock is a minute ahead of ours
+	events.ObserveLatency(kind, events.StageReceived, produced, produced.Add(-time.Minute))
+	if n, sum := histogram(t, kind, events.StageReceived); n != 2 || sum != 1.5 {
+		t.Errorf("histogram = %d samples summing %v, want the skewed sample recorded as zero", n, sum)
+	}
+	if got := testutil.ToFloat64(metrics.EventLatencySkewedTotal.WithLabelValues(kind, events.StageReceived)); got != 1 {
+		t.Errorf("skewed = %v, want 1", got)
+	}
+	if n, _ := histogram(t, kind, events.StageHandled); n != 0 {
+		t.Errorf("%d samples leaked into the handled stage", n)
+	}
+}
+
+func TestRecentLatencies(t *testing.T) {
+	const kind = "latency-quantiles"
+	produced := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for ms := 1; ms <= 100; ms++ {
+		events.ObserveLatency(kind, events.StageHandled, produced, produced.Add(time.Duration(ms)*time.Millisecond))
+	}
+	events.ObserveLatency(kind, events.StageReceived, produced, produced.Add(-time.Second))
+
+	stats := map[string]events.LatencyStats{}
+	for _, s := range events.RecentLatencies() {
+		if s.Kind == kind {
+			stats[s.Stage] = s
+		}
+	}
+	if got, want := stats[events.StageHandled], (events.LatencyStats{Kind: kind, Stage: events.StageHandled, Samples: 100, P50Ms: 50, P95Ms: 95}); got != want {
+		t.Errorf("handled = %+v, want %+v", got, want)
+	}
+	if got, want := stats[events.StageReceived], (events.LatencyStats{Kind: kind, Stage: events.StageReceived, Samples: 1}); got != want {
+		t.Errorf("received = %+v, want %+v with the skewed sample clamped", got, want)
+	}
+}
+
+func TestRecentLatenciesKeepsLatestWindow(t *testing.T) {
+	const kind = "latency-window"
+	produced := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	// An hour-long backlog followed by a full window of fast events: the backlog
+	// ages out of the quantiles
+	for i := 0; i < 100; i++ {
+		events.ObserveLatency(kind, events.StageHandled, produced, produced.Add(time.Hour))
+	}
+	for i := 0; i < 1024; i++ {
+		events.ObserveLatency(kind, events.StageHandled, produced, produced.Add(10*time.Millisecond))
+	}
+	for _, s := range events.RecentLatencies() {
+		if s.Kind == kind && (s.Samples != 1024 || s.P95Ms != 10) {
+			t.Errorf("stats = %+v, want 1024 samples with p95 10ms", s)
+		}
+	}
+}
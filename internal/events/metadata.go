@@ -0,0 +1,69 @@
+// Package events carries broker message metadata through event handling and
+// holds the ordering rules applied when quarantined events are replayed.
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/logging"
+)
+
+// Header names carried on broker messages and preserved across quarantine and replay
+const (
+	HeaderCorrelationID = "x-correlation-id"
+	HeaderRetryCount    = "x-retry-count"
+	HeaderEventTime     = "x-event-timestamp"
+	HeaderProducedAt    = "x-produced-at"
+	HeaderReplayed      = "x-replayed"
+)
+
+// Metadata is the envelope information of an event as originally received
+type Metadata struct {
+	RoutingKey    string `json:"routing_key"`
+	MessageID     string `json:"message_id,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	RetryCount    int    `json:"retry_count"`
+	// Timestamp is when the producer emitted the event; zero if unknown
+	Timestamp time.Time `json:"timestamp,omitempty"`
+	// ProducedAt is when the producer published the message (x-produced-at, else
+	// the AMQP timestamp property); zero if unknown
+	ProducedAt time.Time              `json:"produced_at,omitempty"`
+	ReceivedAt time.Time              `json:"received_at"`
+	Headers    map[string]interface{} `json:"headers,omitempty"`
+	// DeliveryTag and Redelivered describe the delivery this envelope came from;
+	// they mean nothing after quarantine
+	DeliveryTag uint64 `json:"-"`
+	Redelivered bool   `json:"-"`
+	// Replay is set when the event is being re-applied from quarantine
+	Replay bool `json:"-"`
+	// Force skips the replay ordering guard
+	Force bool `json:"-"`
+}
+
+type metadataKey struct{}
+
+// WithMetadata attaches event metadata to ctx. The correlation ID (else the message
+// ID) becomes the request ID, so service log lines for the event carry it.
+func WithMetadata(ctx context.Context, md Metadata) context.Context {
+	id := md.CorrelationID
+	if id == "" {
+		id = md.MessageID
+	}
+	return context.WithValue(logging.WithRequestID(ctx, id), metadataKey{}, md)
+}
+
+// FromContext returns the event metadata attached to ctx, if any
+func FromContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(metadataKey{}).(Metadata)
+	return md, ok
+}
+
+// EventTime picks the best available timestamp for an event: the one embedded in
+// the payload, then the envelope timestamp
+func EventTime(embedded *time.Time, md Metadata) time.Time {
+	if embedded != nil && !embedded.IsZero() {
+		return *embedded
+	}
+	return md.Timestamp
+}
@@ -0,0 +1,30 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/logging"
+)
+
+func TestWithMetadataSetsRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		md   events.Metadata
+		want string
+	}{
+		{"correlation ID", events.Metadata{CorrelationID: "corr-1", MessageID: "msg-1"}, "corr-1"},
+		{"message ID when uncorrelated", events.Metadata{MessageID: "msg-1"}, "msg-1"},
+		{"neither", events.Metadata{RoutingKey: "video.uploaded"}, ""},
+	}
+	for _, tt := range tests {
+		ctx := events.WithMetadata(context.Background(), tt.md)
+		if got := logging.RequestID(ctx); got != tt.want {
+			t.Errorf("%s: request ID %q, want %q", tt.name, got, tt.want)
+		}
+		if md, ok := events.FromContext(ctx); !ok || md.RoutingKey != tt.md.RoutingKey {
+			t.Errorf("%s: metadata %+v, %v", tt.name, md, ok)
+		}
+	}
+}
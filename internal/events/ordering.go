@@ -0,0 +1,60 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// statusRank orders video statuses along the processing lifecycle. Ready and
+// failed are both terminal outcomes of a transcode.
+var statusRank = map[models.VideoStatus]int{
+	models.StatusUploaded:   0,
+	models.StatusProcessing: 1,
+	models.StatusReady:      2,
+	models.StatusFailed:     2,
+}
+
+// ReplayRejectedError is returned when a replayed event would move a video's
+// state backwards
+type ReplayRejectedError struct {
+	Current models.VideoStatus
+	Target  models.VideoStatus
+	Reason  string
+}
+
+func (e *ReplayRejectedError) Error() string {
+	return fmt.Sprintf("replay rejected (%s -> %s): %s", e.Current, e.Target, e.Reason)
+}
+
+// CheckReplayOrder decides whether a replayed event that would set target may be
+// applied to a video currently in current, last updated at updatedAt.
+//
+//   - A replay never moves the status to an earlier lifecycle stage.
+//   - A strictly forward transition is always safe.
+//   - At the same stage (e.g. re-applying a transcode to a ready or failed video)
+//     the event must be known to be at least as new as the video's last update,
+//     otherwise it would overwrite newer data with older.
+func CheckReplayOrder(current models.VideoStatus, updatedAt time.Time, target models.VideoStatus, eventTime time.Time) error {
+	curRank, ok := statusRank[current]
+	if !ok {
+		return &ReplayRejectedError{Current: current, Target: target, Reason: "unknown current status"}
+	}
+	targetRank, ok := statusRank[target]
+	if !ok {
+		return &ReplayRejectedError{Current: current, Target: target, Reason: "unknown target status"}
+	}
+	switch {
+	case targetRank < curRank:
+		return &ReplayRejectedError{Current: current, Target: target, Reason: "status would move backwards"}
+	case targetRank > curRank:
+		return nil
+	case eventTime.IsZero():
+		return &ReplayRejectedError{Current: current, Target: target, Reason: "event has no timestamp to order against the current state"}
+	case eventTime.Before(updatedAt):
+		return &ReplayRejectedError{Current: current, Target: target,
+			Reason: fmt.Sprintf("event at %s predates last update at %s", eventTime.UTC().Format(time.RFC3339), updatedAt.UTC().Format(time.RFC3339))}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package events_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+func TestCheckReplayOrder(t *testing.T) {
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	times := []struct {
+		name string
+		at   time.Time
+	}{
+		{"no timestamp", time.Time{}},
+		{"older", updatedAt.Add(-time.Minute)},
+		{"same instant", updatedAt},
+		{"newer", updatedAt.Add(time.Minute)},
+	}
+
+	// outcome is "apply" for every event time, "reject" for every event time, or
+	// "ordered" when only an event at least as new as the last update applies
+	const (
+		apply   = "apply"
+		reject  = "reject"
+		ordered = "ordered"
+	)
+	up, proc, ready, failed := models.StatusUploaded, models.StatusProcessing, models.StatusReady, models.StatusFailed
+	tests := []struct {
+		current, target models.VideoStatus
+		want            string
+	}{
+		{up, up, ordered},
+		{up, proc, apply},
+		{up, ready, apply},
+		{up, failed, apply},
+		{proc, up, reject},
+		{proc, proc, ordered},
+		{proc, ready, apply},
+		{proc, failed, apply},
+		{ready, up, reject},
+		{ready, proc, reject},
+		{ready, ready, ordered},
+		{ready, failed, ordered},
+		{failed, up, reject},
+		{failed, proc, reject},
+		{failed, ready, ordered},
+		{failed, failed, ordered},
+		{"archived", ready, reject},
+		{ready, "archived", reject},
+	}
+	for _, tt := range tests {
+		for _, et := range times {
+			t.Run(string(tt.current)+"->"+string(tt.target)+"/"+et.name, func(t *testing.T) {
+				err := events.CheckReplayOrder(tt.current, updatedAt, tt.target, et.at)
+				wantApply := tt.want == apply || (tt.want == ordered && !et.at.IsZero() && !et.at.Before(updatedAt))
+				if wantApply {
+					if err != nil {
+						t.Fatalf("rejected: %v", err)
+					}
+					return
+				}
+				var rejected *events.ReplayRejectedError
+				if !errors.As(err, &rejected) {
+					t.Fatalf("err = %v, want a ReplayRejectedError", err)
+				}
+				if rejected.Current != tt.current || rejected.Target != tt.target || rejected.Reason == "" {
+					t.Errorf("rejection = %+v", rejected)
+				}
+				if !strings.Contains(err.Error(), string(tt.current)+" -> "+string(tt.target)) {
+					t.Errorf("message %q doesn't name the transition", err)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,289 @@
+// Package flags evaluates feature flags. Every flag is declared here with a code
+// default; FLAG_<NAME> in the environment overrides it per deployment, and a row
+// in feature_flags overrides both at runtime. A flag can be on for a percentage of
+// users, picked by a stable hash of the user ID, so a user sees the same state on
+// every request and every replica.
+package flags
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// Flag names
+const (
+	VideoReactions  = "video_reactions"
+	ThumbnailResize = "thumbnail_resize"
+)
+
+// Definition declares a flag and its code default
+type Definition struct {
+	Name        string
+	Description string
+	Default     bool
+}
+
+// Definitions lists every flag the service evaluates
+var Definitions = []Definition{
+	{Name: VideoReactions, Description: "Like/dislike endpoints and my_reaction on video responses", Default: true},
+	{Name: ThumbnailResize, Description: "Resized thumbnails; when off the thumbnail proxy redirects to the original", Default: true},
+}
+
+// Where a flag's effective state comes from
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceTable   = "table"
+)
+
+// State is a flag's effective setting
+type State struct {
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	Default        bool   `json:"default"`
+	Enabled        bool   `json:"enabled"`
+	RolloutPercent int    `json:"rollout_percent"`
+	Source         string `json:"source"`
+}
+
+// Status is the flag set as reported on /internal/status
+type Status struct {
+	Flags     []State    `json:"flags"`
+	LoadedAt  *time.Time `json:"loaded_at,omitempty"`
+	LoadError string     `json:"load_error,omitempty"`
+}
+
+// defaultPollInterval is used when New is given no poll interval
+const defaultPollInterval = 30 * time.Second
+
+type setting struct {
+	enabled bool
+	percent int
+}
+
+// on reports whether the setting is on for user
+func (st setting) on(name, user string) bool {
+	switch {
+	case !st.enabled || st.percent <= 0:
+		return false
+	case st.percent >= 100:
+		return true
+	case user == "":
+		// Partial rollouts need someone to bucket
+		return false
+	}
+	return bucket(name, user) < st.percent
+}
+
+// bucket places user in 0-99 for a flag. The flag name is part of the hash so
+// flags rolled out to the same percentage reach different users.
+func bucket(name, user string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(user))
+	return int(h.Sum32() % 100)
+}
+
+// Set holds the flags' current settings
+type Set struct {
+	db       *gorm.DB
+	logger   *zap.SugaredLogger
+	interval time.Duration
+	env      map[string]setting
+
+	mu       sync.RWMutex
+	table    map[string]setting
+	loadedAt time.Time
+	loadErr  string
+}
+
+// New creates a flag set with the FLAG_<NAME> overrides from the environment, which
+// take "true", "false" or a rollout percentage such as "25%". It fails naming any
+// override that doesn't parse. Until Load succeeds only defaults and the
+// environment apply.
+func New(db *gorm.DB, logger *zap.SugaredLogger, interval time.Duration) (*Set, error) {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	s := &Set{db: db, logger: logger, interval: interval, env: map[string]setting{}}
+	for _, def := range Definitions {
+		key := "FLAG_" + strings.ToUpper(def.Name)
+		raw := os.Getenv(key)
+		if raw == "" {
+			continue
+		}
+		st, err := parseOverride(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s=%q: %w", key, raw, err)
+		}
+		s.env[def.Name] = st
+	}
+	return s, nil
+}
+
+func parseOverride(raw string) (setting, error) {
+	raw = strings.TrimSpace(raw)
+	if pct, ok := strings.CutSuffix(raw, "%"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(pct))
+		if err != nil || n < 0 || n > 100 {
+			return setting{}, fmt.Errorf("rollout must be between 0%% and 100%%")
+		}
+		return setting{enabled: n > 0, percent: n}, nil
+	}
+	on, err := strconv.ParseBool(raw)
+	if err != nil {
+		return setting{}, fmt.Errorf("want true, false or a percentage such as 25%%")
+	}
+	return setting{enabled: on, percent: 100}, nil
+}
+
+// Load reads the runtime overrides from feature_flags. Rows for flags not declared
+// in Definitions are ignored. On failure the previous overrides stay in effect.
+func (s *Set) Load(ctx context.Context) error {
+	var rows []models.FeatureFlag
+	if err := s.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		s.mu.Lock()
+		s.loadErr = err.Error()
+		s.mu.Unlock()
+		return fmt.Errorf("load feature flags: %w", err)
+	}
+	table := make(map[string]setting, len(rows))
+	for _, row := range rows {
+		table[row.Name] = setting{enabled: row.Enabled, percent: row.RolloutPercent}
+	}
+	s.mu.Lock()
+	s.table, s.loadedAt, s.loadErr = table, time.Now().UTC(), ""
+	s.mu.Unlock()
+	return nil
+}
+
+// Run reloads feature_flags every interval until ctx is cancelled
+func (s *Set) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Load(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Warnw("Failed to reload feature flags; keeping previous values", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// resolve returns the effective setting for def and where it came from
+func (s *Set) resolve(def Definition) (setting, string) {
+	s.mu.RLock()
+	st, ok := s.table[def.Name]
+	s.mu.RUnlock()
+	if ok {
+		return st, SourceTable
+	}
+	if st, ok := s.env[def.Name]; ok {
+		return st, SourceEnv
+	}
+	return setting{enabled: def.Default, percent: 100}, SourceDefault
+}
+
+// Enabled reports whether the flag is on for the user carried by ctx. Unknown flags
+// are off, as are partial rollouts for requests without a user.
+func (s *Set) Enabled(ctx context.Context, name string) bool {
+	on := false
+	if def, ok := definition(name); ok {
+		st, _ := s.resolve(def)
+		on = st.on(name, userFrom(ctx))
+	}
+	result := "off"
+	if on {
+		result = "on"
+	}
+	metrics.FlagEvaluationsTotal.WithLabelValues(name, result).Inc()
+	return on
+}
+
+// Status returns every flag's effective state and when the table was last read
+func (s *Set) Status() Status {
+	out := Status{Flags: make([]State, 0, len(Definitions))}
+	for _, def := range Definitions {
+		st, source := s.resolve(def)
+		out.Flags = append(out.Flags, State{
+			Name:           def.Name,
+			Description:    def.Description,
+			Default:        def.Default,
+			Enabled:        st.enabled,
+			RolloutPercent: st.percent,
+			Source:         source,
+		})
+	}
+	s.mu.RLock()
+	if !s.loadedAt.IsZero() {
+		loadedAt := s.loadedAt
+		out.LoadedAt = &loadedAt
+	}
+	out.LoadError = s.loadErr
+	s.mu.RUnlock()
+	return out
+}
+
+func definition(name string) (Definition, bool) {
+	for _, def := range Definitions {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return Definition{}, false
+}
+
+// defaults answers for the package-level functions until SetDefault is called
+var defaults = &Set{logger: zap.NewNop().Sugar(), env: map[string]setting{}}
+
+var current atomic.Pointer[Set]
+
+// SetDefault makes s the set the package-level Enabled and Snapshot use
+func SetDefault(s *Set) { current.Store(s) }
+
+func active() *Set {
+	if s := current.Load(); s != nil {
+		return s
+	}
+	return defaults
+}
+
+// Enabled reports whether the flag is on for the user carried by ctx, using the
+// set installed with SetDefault (code defaults before that)
+func Enabled(ctx context.Context, name string) bool { return active().Enabled(ctx, name) }
+
+// Snapshot returns the installed set's Status
+func Snapshot() Status { return active().Status() }
+
+type userKey struct{}
+
+// WithUser returns ctx carrying the identity percentage rollouts are keyed on: a
+// user ID, or an anonymous session identity
+func WithUser(ctx context.Context, userID string) context.Context {
+	if userID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+func userFrom(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
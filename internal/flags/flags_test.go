@@ -0,0 +1,194 @@
+package flags_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/flags"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+func newSet(t *testing.T) *flags.Set {
+	t.Helper()
+	set, err := flags.New(dbtest.Open(t), zap.NewNop().Sugar(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+func as(user string) context.Context { return flags.WithUser(context.Background(), user) }
+
+func source(set *flags.Set, name string) flags.State {
+	for _, st := range set.Status().Flags {
+		if st.Name == name {
+			return st
+		}
+	}
+	return flags.State{}
+}
+
+func TestFlagPrecedence(t *testing.T) {
+	t.Setenv("FLAG_VIDEO_REACTIONS", "false")
+	db := dbtest.Open(t)
+	set, err := flags.New(db, zap.NewNop().Sugar(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := as("u-1")
+
+	// The code default until overridden
+	if !set.Enabled(ctx, flags.ThumbnailResize) || source(set, flags.ThumbnailResize).Source != flags.SourceDefault {
+		t.Errorf("thumbnail_resize: %+v, want on by default", source(set, flags.ThumbnailResize))
+	}
+	// The environment over the default
+	if set.Enabled(ctx, flags.VideoReactions) || source(set, flags.VideoReactions).Source != flags.SourceEnv {
+		t.Errorf("video_reactions: %+v, want off from the environment", source(set, flags.VideoReactions))
+	}
+	// The table over both, once loaded
+	db.Create(&models.FeatureFlag{Name: flags.VideoReactions, Enabled: true, RolloutPercent: 100})
+	db.Create(&models.FeatureFlag{Name: flags.ThumbnailResize, Enabled: false, RolloutPercent: 100})
+	db.Create(&models.FeatureFlag{Name: "not_declared", Enabled: true, RolloutPercent: 100})
+	if set.Enabled(ctx, flags.VideoReactions) {
+		t.Error("table row applied before Load")
+	}
+	if err := set.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !set.Enabled(ctx, flags.VideoReactions) || set.Enabled(ctx, flags.ThumbnailResize) {
+		t.Error("table rows not applied after Load")
+	}
+	status := set.Status()
+	if len(status.Flags) != len(flags.Definitions) || status.LoadedAt == nil || status.LoadError != "" {
+		t.Errorf("status %+v", status)
+	}
+	if source(set, flags.VideoReactions).Source != flags.SourceTable {
+		t.Errorf("video_reactions source %q, want table", source(set, flags.VideoReactions).Source)
+	}
+	// Undeclared flags are off whatever the table says
+	if set.Enabled(ctx, "not_declared") {
+		t.Error("undeclared flag on")
+	}
+}
+
+func TestFlagEnvironmentOverrides(t *testing.T) {
+	for raw, want := range map[string]flags.State{
+		"true":  {Enabled: true, RolloutPercent: 100},
+		"FALSE": {Enabled: false, RolloutPercent: 100},
+		"25%":   {Enabled: true, RolloutPercent: 25},
+		" 0% ":  {Enabled: false, RolloutPercent: 0},
+	} {
+		t.Run(raw, func(t *testing.T) {
+			t.Setenv("FLAG_THUMBNAIL_RESIZE", raw)
+			got := source(newSet(t), flags.ThumbnailResize)
+			if got.Enabled != want.Enabled || got.RolloutPercent != want.RolloutPercent || got.Source != flags.SourceEnv {
+				t.Errorf("%+v, want %+v from env", got, want)
+			}
+		})
+	}
+	for _, raw := range []string{"yes please", "101%", "-5%", "half%"} {
+		t.Run(raw, func(t *testing.T) {
+			t.Setenv("FLAG_THUMBNAIL_RESIZE", raw)
+			_, err := flags.New(nil, zap.NewNop().Sugar(), time.Minute)
+			if err == nil || !strings.Contains(err.Error(), "FLAG_THUMBNAIL_RESIZE") {
+				t.Errorf("New: %v, want an error naming the variable", err)
+			}
+		})
+	}
+}
+
+// TestFlagRollout puts a flag on for 30% of users: each user's answer is stable,
+// about 30% get it, and requests without a user don't
+func TestFlagRollout(t *testing.T) {
+	t.Setenv("FLAG_VIDEO_REACTIONS", "30%")
+	set := newSet(t)
+	on := 0
+	for i := 0; i < 2000; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		first := set.Enabled(as(user), flags.VideoReactions)
+		for j := 0; j < 3; j++ {
+			if set.Enabled(as(user), flags.VideoReactions) != first {
+				t.Fatalf("%s got a different answer on a later request", user)
+			}
+		}
+		if first {
+			on++
+		}
+	}
+	if on < 500 || on > 700 {
+		t.Errorf("on for %d of 2000 users, want about 600", on)
+	}
+	if set.Enabled(context.Background(), flags.VideoReactions) {
+		t.Error("partial rollout on for a request without a user")
+	}
+	// Flags rolled out to the same share reach different users
+	t.Setenv("FLAG_THUMBNAIL_RESIZE", "30%")
+	set = newSet(t)
+	same := 0
+	for i := 0; i < 2000; i++ {
+		ctx := as(fmt.Sprintf("user-%d", i))
+		if set.Enabled(ctx, flags.VideoReactions) == set.Enabled(ctx, flags.ThumbnailResize) {
+			same++
+		}
+	}
+	if same == 2000 {
+		t.Error("two flags at 30% picked exactly the same users")
+	}
+}
+
+// TestFlagLoadFailure keeps the last good table settings and reports the error
+func TestFlagLoadFailure(t *testing.T) {
+	db := dbtest.Open(t)
+	set, _ := flags.New(db, zap.NewNop().Sugar(), time.Minute)
+	db.Create(&models.FeatureFlag{Name: flags.VideoReactions, Enabled: false, RolloutPercent: 100})
+	if err := set.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	db.Migrator().DropTable(&models.FeatureFlag{})
+	if err := set.Load(context.Background()); err == nil {
+		t.Fatal("Load succeeded without the table")
+	}
+	if set.Enabled(as("u-1"), flags.VideoReactions) {
+		t.Error("previous table setting dropped after a failed load")
+	}
+	if status := set.Status(); status.LoadError == "" || status.LoadedAt == nil {
+		t.Errorf("status %+v, want the load error and the last load time", status)
+	}
+}
+
+// TestFlagRunReloads picks up a table change on the next poll
+func TestFlagRunReloads(t *testing.T) {
+	db := dbtest.Open(t)
+	set, _ := flags.New(db, zap.NewNop().Sugar(), 10*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { set.Run(ctx); close(done) }()
+	defer func() { cancel(); <-done }()
+
+	db.Create(&models.FeatureFlag{Name: flags.ThumbnailResize, Enabled: false, RolloutPercent: 100})
+	deadline := time.Now().Add(2 * time.Second)
+	for set.Enabled(as("u-1"), flags.ThumbnailResize) {
+		if time.Now().After(deadline) {
+			t.Fatal("table change not picked up by Run")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDefaultSet(t *testing.T) {
+	t.Cleanup(func() { flags.SetDefault(nil) })
+	if !flags.Enabled(context.Background(), flags.VideoReactions) {
+		t.Error("package-level Enabled off before SetDefault; want the code default")
+	}
+	t.Setenv("FLAG_VIDEO_REACTIONS", "false")
+	flags.SetDefault(newSet(t))
+	if flags.Enabled(context.Background(), flags.VideoReactions) || flags.Snapshot().Flags[0].Source != flags.SourceEnv {
+		t.Error("package-level Enabled doesn't use the installed set")
+	}
+}
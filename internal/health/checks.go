@@ -0,0 +1,50 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/db"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// Database checks the database answers a ping
+func Database(database *gorm.DB) Check {
+	return Check{Name: "database", Run: func(ctx context.Context) error { return db.Ping(ctx, database) }}
+}
+
+// Broker is the connection events arrive on; the queue consumer is one
+type Broker interface {
+	IsConnected() bool
+	State() string
+}
+
+// AMQP checks the consumer's broker connection and channel are open
+func AMQP(broker Broker) Check {
+	return Check{Name: "amqp", Run: func(context.Context) error {
+		if !broker.IsConnected() {
+			return fmt.Errorf("amqp connection %s", broker.State())
+		}
+		return nil
+	}}
+}
+
+// Storage reports a storage client that couldn't be built (deletes are degraded to
+// database-only) and, when probe is set, whether storage answers. It never makes
+// the pod unready on its own, since only deletes and thumbnails need storage.
+func Storage(storage *services.StorageLoader, probe bool) Check {
+	return Check{Name: "storage", Optional: true, Run: func(ctx context.Context) error {
+		client, err := storage.Client()
+		if err != nil {
+			return fmt.Errorf("deletes are database-only: %w", err)
+		}
+		if !probe {
+			return nil
+		}
+		// A HEAD on a name that needn't exist: any answer means storage is reachable
+		_, err = client.BlobExists(ctx, "readyz-probe")
+		return err
+	}}
+}
@@ -0,0 +1,98 @@
+// Package health runs the dependency checks behind the readiness probe.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Check outcomes
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// Check is one dependency verified on every readiness probe
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+	// Optional checks are reported but don't make the pod unready
+	Optional bool
+}
+
+// Result records how a check went
+type Result struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	Optional   bool   `json:"optional,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Report is the readiness probe's response
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Checker runs a fixed set of checks concurrently, each bounded by timeout
+type Checker struct {
+	timeout time.Duration
+	checks  []Check
+}
+
+// NewChecker creates a checker; timeout bounds each check
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{timeout: timeout, checks: checks}
+}
+
+// Run runs every check and reports whether all required ones passed, with a result
+// per check in the order they were given
+func (c *Checker) Run(ctx context.Context) (bool, []Result) {
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+			start := time.Now()
+			err := check.Run(checkCtx)
+			result := Result{Name: check.Name, Status: StatusOK, Optional: check.Optional, DurationMs: time.Since(start).Milliseconds()}
+			if err == nil && checkCtx.Err() != nil {
+				// A check that ignored its deadline still took too long
+				err = checkCtx.Err()
+			}
+			if err != nil {
+				result.Status, result.Error = StatusFailed, err.Error()
+			}
+			results[i] = result
+		}(i, check)
+	}
+	wg.Wait()
+
+	ready := true
+	for _, r := range results {
+		if r.Status != StatusOK && !r.Optional {
+			ready = false
+		}
+	}
+	return ready, results
+}
+
+// ServeHTTP runs the checks and answers 200 "ready" when every required one passed,
+// or 503 "unavailable", with each check's result
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ready, results := c.Run(r.Context())
+	report, code := Report{Status: "ready", Checks: results}, http.StatusOK
+	if !ready {
+		report.Status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
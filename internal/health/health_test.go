@@ -0,0 +1,170 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/health"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// fakeBroker is a consumer connection that is open or not
+type fakeBroker struct {
+	connected bool
+	state     string
+}
+
+func (b fakeBroker) IsConnected() bool { return b.connected }
+func (b fakeBroker) State() string     { return b.state }
+
+// probedStorage answers the readiness HEAD with err
+type probedStorage struct {
+	services.StorageClient
+	err error
+}
+
+func (s probedStorage) BlobExists(context.Context, string) (bool, error) { return false, s.err }
+
+func loader(client services.StorageClient, err error) *services.StorageLoader {
+	return services.NewStorageLoader(func() (services.StorageClient, error) { return client, err }, time.Minute, zap.NewNop().Sugar())
+}
+
+// downDatabase is a database whose connections are closed, as after Postgres went away
+func downDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	gdb := dbtest.Open(t)
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+	return gdb
+}
+
+func probe(t *testing.T, checker *health.Checker) (int, health.Report) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	checker.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report health.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	return w.Code, report
+}
+
+func TestReadinessDependencies(t *testing.T) {
+	up, down := dbtest.Open(t), downDatabase(t)
+	connected := fakeBroker{connected: true, state: "connected"}
+	reconnecting := fakeBroker{state: "reconnecting"}
+	reachable := loader(probedStorage{}, nil)
+
+	tests := []struct {
+		name    string
+		db      *gorm.DB
+		broker  health.Broker
+		storage *services.StorageLoader
+		probe   bool
+		status  int
+		failed  []string
+	}{
+		{"all up", up, connected, reachable, true, http.StatusOK, nil},
+		{"database down", down, connected, reachable, true, http.StatusServiceUnavailable, []string{"database"}},
+		{"broker down", up, reconnecting, reachable, true, http.StatusServiceUnavailable, []string{"amqp"}},
+		{"database and broker down", down, reconnecting, reachable, true, http.StatusServiceUnavailable, []string{"database", "amqp"}},
+		// Storage is optional: reported, but the pod stays ready
+		{"no storage client", up, connected, loader(nil, errors.New("no credentials")), false, http.StatusOK, []string{"storage"}},
+		{"no storage loader", up, connected, nil, false, http.StatusOK, []string{"storage"}},
+		{"storage unreachable", up, connected, loader(probedStorage{err: errors.New("dial tcp: timeout")}, nil), true, http.StatusOK, []string{"storage"}},
+		{"storage unreachable, not probed", up, connected, loader(probedStorage{err: errors.New("dial tcp: timeout")}, nil), false, http.StatusOK, nil},
+		{"everything down", down, reconnecting, loader(nil, errors.New("no credentials")), true, http.StatusServiceUnavailable, []string{"database", "amqp", "storage"}},
+	}
+	for _, tt := range tests {
+		checker := health.NewChecker(time.Second, health.Database(tt.db), health.AMQP(tt.broker), health.Storage(tt.storage, tt.probe))
+		status, report := probe(t, checker)
+		wantStatus := "ready"
+		if tt.status != http.StatusOK {
+			wantStatus = "unavailable"
+		}
+		if status != tt.status || report.Status != wantStatus {
+			t.Errorf("%s: %d %q, want %d %q", tt.name, status, report.Status, tt.status, wantStatus)
+		}
+		var failed []string
+		for i, r := range report.Checks {
+			if name := []string{"database", "amqp", "storage"}[i]; r.Name != name {
+				t.Errorf("%s: check %d is %s, want %s", tt.name, i, r.Name, name)
+			}
+			if r.Status == health.StatusFailed {
+				failed = append(failed, r.Name)
+				if r.Error == "" {
+					t.Errorf("%s: %s failed without an error", tt.name, r.Name)
+				}
+			}
+		}
+		if strings.Join(failed, ",") != strings.Join(tt.failed, ",") {
+			t.Errorf("%s: failed checks %v, want %v", tt.name, failed, tt.failed)
+		}
+	}
+	if _, report := probe(t, health.NewChecker(time.Second, health.AMQP(reconnecting))); report.Checks[0].Error != "amqp connection reconnecting" {
+		t.Errorf("broker error = %q, want its state", report.Checks[0].Error)
+	}
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	blocking := health.Check{Name: "blocking", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	// Returns nil, but only after its deadline has passed
+	slow := health.Check{Name: "slow", Run: func(context.Context) error {
+		time.Sleep(80 * time.Millisecond)
+		return nil
+	}}
+	fast := health.Check{Name: "fast", Run: func(context.Context) error { return nil }}
+
+	start := time.Now()
+	ready, results := health.NewChecker(20*time.Millisecond, blocking, slow, fast).Run(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("checks took %s; they should run concurrently", elapsed)
+	}
+	if ready {
+		t.Error("ready despite two checks past their deadline")
+	}
+	for _, r := range results[:2] {
+		if r.Status != health.StatusFailed || !strings.Contains(r.Error, "deadline exceeded") {
+			t.Errorf("%s = %+v, want failed on its deadline", r.Name, r)
+		}
+	}
+	if results[2].Status != health.StatusOK {
+		t.Errorf("fast = %+v", results[2])
+	}
+}
+
+func TestStorageCheckRecovers(t *testing.T) {
+	// The secrets volume mounts after the first probe
+	calls := 0
+	storage := services.NewStorageLoader(func() (services.StorageClient, error) {
+		if calls++; calls == 1 {
+			return nil, errors.New("no credentials")
+		}
+		return probedStorage{}, nil
+	}, 0, zap.NewNop().Sugar())
+	checker := health.NewChecker(time.Second, health.Storage(storage, true))
+
+	_, report := probe(t, checker)
+	if r := report.Checks[0]; r.Status != health.StatusFailed || !strings.Contains(r.Error, "deletes are database-only") {
+		t.Errorf("before the client is built: %+v, want failed as database-only", r)
+	}
+	if _, report = probe(t, checker); report.Checks[0].Status != health.StatusOK {
+		t.Errorf("once the client is built: %+v", report.Checks[0])
+	}
+}
@@ -0,0 +1,9 @@
+package jobs
+
+import "time"
+
+// SetInstance names the runner, so tests can run several as separate replicas
+func (r *Runner) SetInstance(name string) { r.instance = name }
+
+// SetMaxPoll caps the wait between schedule checks below the minute replicas use
+func (r *Runner) SetMaxPoll(d time.Duration) { r.maxPoll = d }
@@ -0,0 +1,266 @@
+// Package jobs runs named background jobs on an interval. Each run is guarded by
+// a lease row in job_runs, so with several replicas a job runs once per interval
+// across the deployment rather than once per pod.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/db"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// Errors returned by Trigger
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrJobRunning = errors.New("job is already running")
+)
+
+// maxPollInterval caps how long a replica waits between checks of a job's
+// schedule, so a job whose lease holder died is picked up promptly
+const maxPollInterval = time.Minute
+
+// Job is a unit of background work
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// Timeout bounds a single run and is also the lease length; defaults to Interval
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// Status is a job's configuration joined with its last recorded run
+type Status struct {
+	Name       string         `json:"name"`
+	IntervalMs int64          `json:"interval_ms"`
+	Running    bool           `json:"running"`
+	Run        *models.JobRun `json:"run,omitempty"`
+}
+
+// Runner schedules registered jobs
+type Runner struct {
+	db       *gorm.DB
+	logger   *zap.SugaredLogger
+	instance string
+	// maxPoll is maxPollInterval outside tests
+	maxPoll time.Duration
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+	ctx  context.Context
+	wg   sync.WaitGroup
+}
+
+// NewRunner creates a job runner identified by the host name
+func NewRunner(db *gorm.DB, logger *zap.SugaredLogger) *Runner {
+	host, _ := os.Hostname()
+	return &Runner{
+		db:       db,
+		logger:   logger,
+		instance: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		maxPoll:  maxPollInterval,
+		jobs:     map[string]*Job{},
+		ctx:      context.Background(),
+	}
+}
+
+// Register adds a job; call before Start
+func (r *Runner) Register(job Job) {
+	if job.Timeout <= 0 {
+		job.Timeout = job.Interval
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[job.Name] = &job
+}
+
+// Start ensures each job has a job_runs row and schedules it until ctx is cancelled
+func (r *Runner) Start(ctx context.Context) error {
+	r.mu.Lock()
+	r.ctx = ctx
+	jobs := make([]*Job, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		jobs = append(jobs, job)
+	}
+	r.mu.Unlock()
+
+	for _, job := range jobs {
+		if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.JobRun{Name: job.Name}).Error; err != nil {
+			return fmt.Errorf("init job %s: %w", job.Name, err)
+		}
+		r.wg.Add(1)
+		go r.schedule(ctx, job)
+	}
+	return nil
+}
+
+// Wait blocks until every scheduled job loop and in-flight run has returned
+func (r *Runner) Wait() { r.wg.Wait() }
+
+func (r *Runner) schedule(ctx context.Context, job *Job) {
+	defer r.wg.Done()
+	poll := job.Interval
+	if poll > r.maxPoll {
+		poll = r.maxPoll
+	}
+	if poll < time.Second {
+		poll = time.Second
+	}
+	// Stagger replicas so they don't all contend for the lease at once
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(poll))))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			r.tryRun(ctx, job)
+			timer.Reset(poll)
+		}
+	}
+}
+
+// Trigger runs a job now, regardless of its schedule, unless another run holds the lease
+func (r *Runner) Trigger(ctx context.Context, name string) error {
+	r.mu.Lock()
+	job, ok := r.jobs[name]
+	base := r.ctx
+	r.mu.Unlock()
+	if !ok {
+		return ErrUnknownJob
+	}
+	acquired, err := r.acquire(ctx, job, true)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return ErrJobRunning
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.execute(base, job, "manual")
+	}()
+	return nil
+}
+
+func (r *Runner) tryRun(ctx context.Context, job *Job) {
+	acquired, err := r.acquire(ctx, job, false)
+	if err != nil {
+		r.logger.Errorw("Failed to acquire job lease", "job", job.Name, "error", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+	r.execute(ctx, job, "schedule")
+}
+
+// acquire takes the job's lease if it is free and, unless force is set, the job is due
+func (r *Runner) acquire(ctx context.Context, job *Job, force bool) (bool, error) {
+	now := time.Now().UTC()
+	expires := now.Add(job.Timeout)
+	query := r.db.WithContext(ctx).Model(&models.JobRun{}).
+		Where("name = ?", job.Name).
+		Where("lease_expires_at IS NULL OR lease_expires_at < ?", now)
+	if !force {
+		query = query.Where("next_run_at IS NULL OR next_run_at <= ?", now)
+	}
+	res := query.Updates(map[string]interface{}{
+		"lease_owner":      r.instance,
+		"lease_expires_at": expires,
+		"last_started_at":  now,
+	})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *Runner) execute(ctx context.Context, job *Job, trigger string) {
+	start := time.Now()
+	// The job's own timeout bounds its statements in place of the per-query one
+	runCtx, cancel := context.WithTimeout(db.WithoutQueryTimeout(ctx), job.Timeout)
+	err := r.runSafely(runCtx, job)
+	cancel()
+	duration := time.Since(start)
+
+	outcome := "success"
+	updates := map[string]interface{}{
+		"lease_owner":      "",
+		"lease_expires_at": nil,
+		"last_finished_at": time.Now().UTC(),
+		"last_duration_ms": duration.Milliseconds(),
+		"last_success":     err == nil,
+		"last_error":       "",
+		"next_run_at":      start.UTC().Add(job.Interval),
+		"run_count":        gorm.Expr("run_count + 1"),
+	}
+	if err != nil {
+		outcome = "failure"
+		updates["last_error"] = err.Error()
+		updates["failure_count"] = gorm.Expr("failure_count + 1")
+		r.logger.Errorw("Background job failed", "job", job.Name, "trigger", trigger, "error", err, "duration", duration)
+	} else {
+		metrics.JobLastSuccess.WithLabelValues(job.Name).SetToCurrentTime()
+	}
+	metrics.JobRunsTotal.WithLabelValues(job.Name, outcome).Inc()
+	metrics.JobRunDuration.WithLabelValues(job.Name).Observe(duration.Seconds())
+
+	// Release with a fresh context: the run may have ended because ctx was cancelled
+	releaseCtx, cancelRelease := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelRelease()
+	if err := r.db.WithContext(releaseCtx).Model(&models.JobRun{}).
+		Where("name = ? AND lease_owner = ?", job.Name, r.instance).
+		Updates(updates).Error; err != nil {
+		r.logger.Errorw("Failed to record job run", "job", job.Name, "error", err)
+	}
+}
+
+func (r *Runner) runSafely(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic: %v", rec)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// List returns every registered job with its last recorded run
+func (r *Runner) List(ctx context.Context) ([]Status, error) {
+	var rows []models.JobRun
+	if err := r.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("list job runs: %w", err)
+	}
+	byName := make(map[string]*models.JobRun, len(rows))
+	for i := range rows {
+		byName[rows[i].Name] = &rows[i]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	out := make([]Status, 0, len(r.jobs))
+	for name, job := range r.jobs {
+		st := Status{Name: name, IntervalMs: job.Interval.Milliseconds(), Run: byName[name]}
+		if run := st.Run; run != nil && run.LeaseExpiresAt != nil && run.LeaseExpiresAt.After(now) {
+			st.Running = true
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
@@ -0,0 +1,172 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/jobs"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// replica creates a runner named like one pod of a deployment sharing db
+func replica(db *gorm.DB, name string, job jobs.Job) *jobs.Runner {
+	r := jobs.NewRunner(db, zap.NewNop().Sugar())
+	r.SetInstance(name)
+	r.Register(job)
+	return r
+}
+
+// start runs r's schedule until the test ends
+func start(t *testing.T, r *jobs.Runner) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := r.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { cancel(); r.Wait() })
+}
+
+func jobRun(t *testing.T, db *gorm.DB, name string) models.JobRun {
+	t.Helper()
+	var run models.JobRun
+	if err := db.First(&run, "name = ?", name).Error; err != nil {
+		t.Fatalf("job_runs row for %s: %v", name, err)
+	}
+	return run
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestTriggerHoldsLease triggers a job on one replica while it runs: neither
+// replica can start it again until the run has released the lease
+func TestTriggerHoldsLease(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	release := make(chan struct{})
+	var runs atomic.Int32
+	job := jobs.Job{Name: "prune", Interval: time.Hour, Run: func(context.Context) error {
+		runs.Add(1)
+		<-release
+		return nil
+	}}
+	a, b := replica(db, "pod-a", job), replica(db, "pod-b", job)
+	start(t, a)
+	start(t, b)
+
+	if err := a.Trigger(ctx, "prune"); err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+	waitFor(t, "the run to start", func() bool { return runs.Load() == 1 })
+	for name, r := range map[string]*jobs.Runner{"same replica": a, "other replica": b} {
+		if err := r.Trigger(ctx, "prune"); !errors.Is(err, jobs.ErrJobRunning) {
+			t.Errorf("%s while running: %v, want ErrJobRunning", name, err)
+		}
+	}
+	if run := jobRun(t, db, "prune"); run.LeaseOwner != "pod-a" {
+		t.Errorf("lease held by %q, want pod-a", run.LeaseOwner)
+	}
+	statuses, err := b.List(ctx)
+	if err != nil || len(statuses) != 1 || !statuses[0].Running {
+		t.Errorf("List while running = %+v, %v", statuses, err)
+	}
+
+	started := time.Now()
+	close(release)
+	waitFor(t, "the run to be recorded", func() bool { return jobRun(t, db, "prune").RunCount == 1 })
+	run := jobRun(t, db, "prune")
+	if run.LeaseOwner != "" || run.LeaseExpiresAt != nil || !run.LastSuccess || run.NextRunAt == nil ||
+		run.NextRunAt.Before(started.Add(59*time.Minute)) {
+		t.Errorf("after the run: %+v", run)
+	}
+	// A manual run ignores the schedule once the lease is free
+	if err := b.Trigger(ctx, "prune"); err != nil {
+		t.Errorf("Trigger after the run: %v", err)
+	}
+	if err := b.Trigger(ctx, "missing"); !errors.Is(err, jobs.ErrUnknownJob) {
+		t.Errorf("unknown job: %v", err)
+	}
+}
+
+func TestRunnerRecordsFailures(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	var mu sync.Mutex
+	failures := []func() error{
+		func() error { return errors.New("disk full") },
+		func() error { panic("boom") },
+	}
+	r := replica(db, "pod-a", jobs.Job{Name: "sweep", Interval: time.Hour, Run: func(context.Context) error {
+		mu.Lock()
+		fail := failures[0]
+		failures = failures[1:]
+		mu.Unlock()
+		return fail()
+	}})
+	start(t, r)
+	failed := testutil.ToFloat64(metrics.JobRunsTotal.WithLabelValues("sweep", "failure"))
+
+	for i, want := range []string{"disk full", "panic: boom"} {
+		waitFor(t, "the lease to be free", func() bool { return r.Trigger(ctx, "sweep") == nil })
+		waitFor(t, "the failure to be recorded", func() bool { return jobRun(t, db, "sweep").RunCount == int64(i+1) })
+		run := jobRun(t, db, "sweep")
+		if run.LastSuccess || run.LastError != want || run.FailureCount != int64(i+1) {
+			t.Errorf("run %d: %+v, want error %q", i+1, run, want)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.JobRunsTotal.WithLabelValues("sweep", "failure")) - failed; got != 2 {
+		t.Errorf("failure runs +%v, want +2", got)
+	}
+}
+
+// TestScheduleRunsOncePerInterval starts the same job on two replicas: it runs once
+// for the interval between them, and again as soon as a dead holder's lease expires
+func TestScheduleRunsOncePerInterval(t *testing.T) {
+	db := dbtest.Open(t)
+	var runs atomic.Int32
+	job := jobs.Job{Name: "repair", Interval: time.Hour, Run: func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}}
+	for _, name := range []string{"pod-a", "pod-b"} {
+		r := replica(db, name, job)
+		r.SetMaxPoll(time.Second)
+		start(t, r)
+	}
+
+	waitFor(t, "the first scheduled run", func() bool { return jobRun(t, db, "repair").RunCount == 1 })
+	// Both replicas poll at least once more; the job isn't due again for an hour
+	time.Sleep(1200 * time.Millisecond)
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("ran %d times within the interval, want once", n)
+	}
+
+	// A replica died holding an expired lease on a due job
+	past := time.Now().UTC().Add(-time.Minute)
+	db.Model(&models.JobRun{}).Where("name = ?", "repair").Updates(map[string]interface{}{
+		"lease_owner": "pod-dead", "lease_expires_at": past, "next_run_at": past,
+	})
+	waitFor(t, "the job to be picked up", func() bool { return jobRun(t, db, "repair").RunCount == 2 })
+	if n := runs.Load(); n != 2 {
+		t.Errorf("ran %d times, want 2", n)
+	}
+}
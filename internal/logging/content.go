@@ -0,0 +1,76 @@
+// Package logging holds zap helpers for keeping user-generated content out of logs.
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// Content policies for user-generated text (titles, descriptions, comments, tags)
+const (
+	// PolicyRedact replaces content with a short hash and its length, so identical
+	// values can still be correlated across log lines
+	PolicyRedact = "redact"
+	// PolicyTruncate keeps a short prefix
+	PolicyTruncate = "truncate"
+	// PolicyFull logs content verbatim; for local development only
+	PolicyFull = "full"
+)
+
+// truncateRunes is how much of a value PolicyTruncate keeps
+const truncateRunes = 24
+
+var contentPolicy = PolicyRedact
+
+// SetContentPolicy selects how user content is logged; call once at startup
+func SetContentPolicy(policy string) error {
+	switch policy {
+	case "":
+		contentPolicy = PolicyRedact
+	case PolicyRedact, PolicyTruncate, PolicyFull:
+		contentPolicy = policy
+	default:
+		return fmt.Errorf("invalid log content policy %q (want redact|truncate|full)", policy)
+	}
+	return nil
+}
+
+// ContentPolicy returns the active content policy
+func ContentPolicy() string { return contentPolicy }
+
+// Text applies the active policy to a user-generated value
+func Text(value string) string {
+	switch contentPolicy {
+	case PolicyFull:
+		return value
+	case PolicyTruncate:
+		if utf8.RuneCountInString(value) <= truncateRunes {
+			return value
+		}
+		runes := []rune(value)
+		return fmt.Sprintf("%s…(%d chars)", string(runes[:truncateRunes]), len(runes))
+	default:
+		if value == "" {
+			return ""
+		}
+		sum := sha256.Sum256([]byte(value))
+		return fmt.Sprintf("sha256:%s len=%d", hex.EncodeToString(sum[:6]), utf8.RuneCountInString(value))
+	}
+}
+
+// Content is a zap field for an arbitrary user-generated value. Fields can be
+// mixed into SugaredLogger key/value lists.
+func Content(key, value string) zap.Field { return zap.String(key, Text(value)) }
+
+// Title is the field for a video title
+func Title(value string) zap.Field { return Content("title", value) }
+
+// Description is the field for a video description
+func Description(value string) zap.Field { return Content("description", value) }
+
+// CommentText is the field for a comment body
+func CommentText(value string) zap.Field { return Content("content", value) }
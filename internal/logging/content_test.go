@@ -0,0 +1,78 @@
+package logging_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/streamhive/video-catalog-api/internal/logging"
+)
+
+func TestContentFieldsFollowPolicy(t *testing.T) {
+	t.Cleanup(func() { logging.SetContentPolicy(logging.PolicyRedact) })
+	title := "My holiday in Lisbon with the whole family"
+	description := "Private notes about where we stayed"
+	comment := "call me on 555-0100 after eight"
+
+	tests := []struct {
+		policy string
+		check  func(t *testing.T, key, raw, logged string)
+	}{
+		{logging.PolicyRedact, func(t *testing.T, key, raw, logged string) {
+			if strings.Contains(logged, raw[:8]) || !strings.HasPrefix(logged, "sha256:") {
+				t.Errorf("%s logged as %q, want a digest", key, logged)
+			}
+			if logged != logging.Text(raw) {
+				t.Errorf("%s digest not stable: %q vs %q", key, logged, logging.Text(raw))
+			}
+		}},
+		{logging.PolicyTruncate, func(t *testing.T, key, raw, logged string) {
+			if len(raw) > 24 && strings.Contains(logged, raw) {
+				t.Errorf("%s logged in full: %q", key, logged)
+			}
+			if !strings.HasPrefix(logged, string([]rune(raw)[:min(24, len(raw))])) {
+				t.Errorf("%s logged as %q, want its prefix", key, logged)
+			}
+		}},
+		{logging.PolicyFull, func(t *testing.T, key, raw, logged string) {
+			if logged != raw {
+				t.Errorf("%s logged as %q, want it verbatim", key, logged)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			if err := logging.SetContentPolicy(tt.policy); err != nil {
+				t.Fatal(err)
+			}
+			core, logs := observer.New(zap.InfoLevel)
+			zap.New(core).Sugar().Infow("video updated", "videoID", 7,
+				logging.Title(title), logging.Description(description), logging.CommentText(comment))
+
+			entries := logs.All()
+			if len(entries) != 1 {
+				t.Fatalf("%d entries, want 1", len(entries))
+			}
+			fields := entries[0].ContextMap()
+			for key, raw := range map[string]string{"title": title, "description": description, "content": comment} {
+				logged, _ := fields[key].(string)
+				tt.check(t, key, raw, logged)
+			}
+		})
+	}
+}
+
+func TestSetContentPolicy(t *testing.T) {
+	t.Cleanup(func() { logging.SetContentPolicy(logging.PolicyRedact) })
+	if err := logging.SetContentPolicy("verbose"); err == nil {
+		t.Error("unknown policy accepted")
+	}
+	if err := logging.SetContentPolicy(""); err != nil || logging.ContentPolicy() != logging.PolicyRedact {
+		t.Errorf("empty policy = %q, %v; want redact", logging.ContentPolicy(), err)
+	}
+	if got := logging.Text(""); got != "" {
+		t.Errorf("empty value redacted to %q", got)
+	}
+}
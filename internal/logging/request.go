@@ -0,0 +1,36 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID tags ctx with the ID that correlates a request's (or message's) log
+// lines across services
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID ctx was tagged with, or ""
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// For returns logger with ctx's request ID attached as requestID, or logger as is
+// when ctx carries none
+func For(ctx context.Context, logger *zap.SugaredLogger) *zap.SugaredLogger {
+	if id := RequestID(ctx); id != "" {
+		return logger.With("requestID", id)
+	}
+	return logger
+}
@@ -0,0 +1,414 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Catalog domain metrics. Everything is registered on the default registry so the
+// existing /metrics handler exposes it alongside the Go/process collectors.
+var (
+	// CounterDriftTotal accumulates the absolute drift corrected per denormalized counter.
+	CounterDriftTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_counter_drift_total",
+		Help: "Sum of absolute drift corrected on denormalized video and comment counters",
+	}, []string{"counter"})
+
+	// CounterDriftLastRun records the absolute drift found by the most recent repair run.
+	CounterDriftLastRun = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "catalog_counter_drift_last_run",
+		Help: "Absolute drift found by the most recent counter repair run",
+	}, []string{"counter"})
+
+	// CounterRepairsTotal counts videos whose counters had to be corrected.
+	CounterRepairsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_counter_repairs_total",
+		Help: "Number of videos whose denormalized counters were corrected",
+	}, []string{"counter", "trigger"})
+
+	// CounterRepairRunDuration observes how long a repair pass takes.
+	CounterRepairRunDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "catalog_counter_repair_duration_seconds",
+		Help:    "Duration of counter repair runs",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// ModerationRequestsTotal counts moderation provider calls by content kind and outcome.
+	ModerationRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_moderation_requests_total",
+		Help: "Moderation provider calls by content kind and outcome",
+	}, []string{"kind", "outcome"})
+
+	// ModerationDuration observes moderation provider latency.
+	ModerationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "catalog_moderation_duration_seconds",
+		Help:    "Latency of moderation provider calls",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"kind"})
+
+	// ModerationFlagsTotal counts stored moderation flags by target type and resulting status.
+	ModerationFlagsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_moderation_flags_total",
+		Help: "Moderation flags recorded by target type and status",
+	}, []string{"target_type", "status"})
+
+	// WarmupDuration records how long the startup warmup phase took.
+	WarmupDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "catalog_warmup_duration_seconds",
+		Help: "Duration of the startup warmup phase",
+	})
+
+	// WarmupStepDuration records how long each warmup step took.
+	WarmupStepDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "catalog_warmup_step_duration_seconds",
+		Help: "Duration of each startup warmup step",
+	}, []string{"step"})
+
+	// WarmupStepsTotal counts warmup step outcomes (ok/failed/incomplete).
+	WarmupStepsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_warmup_steps_total",
+		Help: "Warmup step outcomes",
+	}, []string{"step", "outcome"})
+
+	// AccessLogEntriesTotal counts access-log entries by outcome (written/dropped/failed).
+	AccessLogEntriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_access_log_entries_total",
+		Help: "Access log entries by outcome",
+	}, []string{"outcome"})
+
+	// MigrationRowsTotal counts rows rewritten by batched data migrations.
+	MigrationRowsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_migration_rows_total",
+		Help: "Rows migrated by batched data migrations",
+	}, []string{"migration"})
+
+	// MigrationCheckpointID exposes the last primary key processed by a data migration.
+	MigrationCheckpointID = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "catalog_migration_checkpoint_id",
+		Help: "Last primary key processed by a batched data migration",
+	}, []string{"migration"})
+
+	// NotificationsTotal counts notification generation attempts by outcome.
+	NotificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_notifications_total",
+		Help: "Notifications generated by type and outcome (created, collapsed, muted, skipped, failed)",
+	}, []string{"type", "outcome"})
+
+	// DataExportsTotal counts personal data exports by mode (stream, job) and outcome.
+	DataExportsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_data_exports_total",
+		Help: "Personal data exports by mode and outcome",
+	}, []string{"mode", "outcome"})
+
+	// DataExportDuration tracks how long assembling an export takes.
+	DataExportDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "catalog_data_export_duration_seconds",
+		Help:    "Time to assemble a personal data export",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"mode"})
+
+	// EventsQuarantinedTotal counts broker events parked after their handler failed.
+	EventsQuarantinedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_events_quarantined_total",
+		Help: "Events quarantined after a handler failure, by kind",
+	}, []string{"kind"})
+
+	// InboundEventsTotal counts inbound event archive writes by outcome (written/dropped/failed).
+	InboundEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_inbound_events_total",
+		Help: "Inbound event archive entries by outcome",
+	}, []string{"outcome"})
+
+	// EventReprocessesTotal counts reprocessing of archived events by outcome (ok, rejected, error).
+	EventReprocessesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_event_reprocesses_total",
+		Help: "Reprocessing of archived inbound events by kind and outcome",
+	}, []string{"kind", "outcome"})
+
+	// EventReplaysTotal counts replays of quarantined events by outcome (ok, rejected, error).
+	EventReplaysTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_event_replays_total",
+		Help: "Replays of quarantined events by kind and outcome",
+	}, []string{"kind", "outcome"})
+
+	// JobRunsTotal counts background job runs by outcome (success, failure).
+	JobRunsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_job_runs_total",
+		Help: "Background job runs by job and outcome",
+	}, []string{"job", "outcome"})
+
+	// JobRunDuration tracks how long background job runs take.
+	JobRunDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "catalog_job_run_duration_seconds",
+		Help:    "Background job run duration",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 14),
+	}, []string{"job"})
+
+	// JobLastSuccess is the unix time of each job's last successful run on this replica.
+	JobLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "catalog_job_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful run of a background job",
+	}, []string{"job"})
+
+	// ImpersonatedRequestsTotal counts requests admins made while impersonating a user.
+	ImpersonatedRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_impersonated_requests_total",
+		Help: "Requests served under admin impersonation by admin and outcome",
+	}, []string{"admin", "outcome"})
+
+	// VideoChangesTotal counts video mutations reported to the change hub, by kind.
+	VideoChangesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_video_changes_total",
+		Help: "Video mutations reported to the change hub by kind",
+	}, []string{"kind"})
+
+	// StartupPhaseDuration records how long each startup phase took.
+	StartupPhaseDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "catalog_startup_phase_duration_seconds",
+		Help: "Duration of each startup phase",
+	}, []string{"phase"})
+
+	// AMQPConnected is 1 while the consumer's broker connection is up.
+	AMQPConnected = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "catalog_amqp_connected",
+		Help: "Whether the RabbitMQ consumer is connected (1) or not (0)",
+	})
+
+	// AMQPReconnectsTotal counts consumer reconnect attempts by outcome (ok/failed).
+	AMQPReconnectsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_amqp_reconnects_total",
+		Help: "RabbitMQ consumer reconnect attempts by outcome",
+	}, []string{"outcome"})
+
+	// AnonymousSessionsTotal counts anonymous session lifecycle events (issued/merged/purged).
+	AnonymousSessionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_anonymous_sessions_total",
+		Help: "Anonymous sessions issued, merged into accounts and purged",
+	}, []string{"event"})
+
+	// EventLatency records producer-to-catalog latency of events by kind and stage (received/handled).
+	EventLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "catalog_event_latency_seconds",
+		Help:    "Time from the producer timestamp to receipt or handling of an event",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600},
+	}, []string{"kind", "stage"})
+
+	// EventLatencySkewedTotal counts latency samples that were negative (producer clock ahead) and clamped to zero.
+	EventLatencySkewedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_event_latency_skewed_total",
+		Help: "Event latency samples clamped to zero because the producer timestamp was in the future",
+	}, []string{"kind", "stage"})
+
+	// EventsWithoutProducedAtTotal counts events whose producer supplied no timestamp.
+	EventsWithoutProducedAtTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_events_without_produced_at_total",
+		Help: "Events received without a producer timestamp, by kind",
+	}, []string{"kind"})
+
+	// EventsIgnoredTotal counts broker events dropped without being applied.
+	EventsIgnoredTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_events_ignored_total",
+		Help: "Events ignored without being applied, by kind and reason",
+	}, []string{"kind", "reason"})
+
+	// CacheInvalidationsTotal counts video.cache.invalidate messages by reason and outcome.
+	CacheInvalidationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_cache_invalidations_total",
+		Help: "Cache invalidation messages, by reason and outcome (published/error)",
+	}, []string{"reason", "outcome"})
+
+	// CacheInvalidationsCoalescedTotal counts video changes folded into a pending invalidation.
+	CacheInvalidationsCoalescedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "catalog_cache_invalidations_coalesced_total",
+		Help: "Video changes folded into an already pending cache invalidation",
+	})
+
+	// EventUnknownFieldsTotal counts unknown top-level payload fields seen in strict mode.
+	EventUnknownFieldsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_event_unknown_fields_total",
+		Help: "Top-level event payload fields not declared by the catalog's event types, by kind and field",
+	}, []string{"kind", "field"})
+
+	// VideoViewsTotal counts view reports by outcome.
+	VideoViewsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_video_views_total",
+		Help: "Video view reports, by outcome (counted/deduped)",
+	}, []string{"outcome"})
+
+	// ThumbnailRequestsTotal counts resized thumbnail requests by outcome.
+	ThumbnailRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_thumbnail_requests_total",
+		Help: "Resized thumbnail requests, by outcome (hit/resized/error)",
+	}, []string{"outcome"})
+
+	// ThumbnailResizeDuration observes how long one resize takes.
+	ThumbnailResizeDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "catalog_thumbnail_resize_duration_seconds",
+		Help:    "Time to decode, scale and re-encode one thumbnail",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 10),
+	})
+
+	// ThumbnailCacheBytes is the size of the resized thumbnail cache.
+	ThumbnailCacheBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "catalog_thumbnail_cache_bytes",
+		Help: "Bytes held by the in-memory resized thumbnail cache",
+	})
+
+	// VideoReactionsTotal counts like/dislike requests by reaction and outcome.
+	VideoReactionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_video_reactions_total",
+		Help: "Video reaction requests, by reaction (like/dislike) and outcome (added/switched/unchanged/cleared)",
+	}, []string{"reaction", "outcome"})
+
+	// CommentLikesTotal counts comment like/unlike requests by outcome.
+	CommentLikesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_comment_likes_total",
+		Help: "Comment like and unlike requests, by outcome (liked/unliked/unchanged)",
+	}, []string{"outcome"})
+
+	// PublicEventsTotal counts entries appended to the public event log by type.
+	PublicEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_public_events_total",
+		Help: "Entries appended to the public event log, by type",
+	}, []string{"type"})
+
+	// WatchProgressTotal counts watch progress reports by outcome.
+	WatchProgressTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_watch_progress_total",
+		Help: "Watch progress reports, by outcome (saved/skipped)",
+	}, []string{"outcome"})
+
+	// WatchMilestonesTotal counts video.watch.milestone publishes by milestone and outcome.
+	WatchMilestonesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_watch_milestones_total",
+		Help: "Watch milestone messages, by milestone percentage and outcome (published/error)",
+	}, []string{"milestone", "outcome"})
+
+	// FlagEvaluationsTotal counts feature flag checks by flag and result.
+	FlagEvaluationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_feature_flag_evaluations_total",
+		Help: "Feature flag evaluations, by flag and result (on/off)",
+	}, []string{"flag", "result"})
+
+	// AuthFailuresTotal counts requests rejected for bad credentials, by reason.
+	AuthFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_auth_failures_total",
+		Help: "Requests rejected because their token did not verify, by reason (expired/signature/malformed/claims)",
+	}, []string{"reason"})
+
+	// AMQPPublishesTotal counts broker publishes by confirm outcome.
+	AMQPPublishesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_amqp_publishes_total",
+		Help: "Messages published to RabbitMQ, by outcome (acked/nacked/timeout/error)",
+	}, []string{"outcome"})
+
+	// OutboxMessagesTotal counts outbox publish attempts by routing key and outcome.
+	OutboxMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_outbox_messages_total",
+		Help: "Outbox publish attempts, by routing key and outcome (published/retried/exhausted)",
+	}, []string{"routing_key", "outcome"})
+
+	// OutboxBacklog is the number of outbox messages not yet published.
+	OutboxBacklog = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "catalog_outbox_backlog",
+		Help: "Outbox messages waiting to be published, including those backing off after a failure",
+	})
+
+	// RateLimitedTotal counts requests answered 429, by route and limit class.
+	RateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_rate_limited_total",
+		Help: "Requests rejected with 429, by route and limit (read/write/route)",
+	}, []string{"route", "class"})
+
+	// IdempotentRequestsTotal counts writes sent with an Idempotency-Key, by route and outcome.
+	IdempotentRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_idempotent_requests_total",
+		Help: "Writes with an Idempotency-Key by route and outcome (stored, replayed, released, reused, in_flight, error)",
+	}, []string{"route", "outcome"})
+
+	// VideoDeletionsTotal counts video deletion job attempts by outcome.
+	VideoDeletionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_video_deletions_total",
+		Help: "Video deletion job attempts, by outcome (completed/failed/interrupted)",
+	}, []string{"outcome"})
+
+	// EventsProcessedTotal counts consumed broker messages by source, routing key and outcome.
+	EventsProcessedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_events_processed_total",
+		Help: "Consumed broker messages, by event source, routing key and outcome (ok, error when the handler failed and the message was quarantined, dead_lettered when it was nacked, skipped when it failed and could be neither, requeued when shutdown interrupted it)",
+	}, []string{"source", "routing_key", "outcome"})
+
+	// EventHandleDuration observes how long handling one broker message takes.
+	EventHandleDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "catalog_event_handle_duration_seconds",
+		Help:    "Time to handle one consumed broker message, by routing key",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"source", "routing_key"})
+
+	// HTTPRequestDuration observes API latency by route template, method and status.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "catalog_http_request_duration_seconds",
+		Help:    "HTTP request latency, by method, route template and status code",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Videos is the number of catalog videos by processing status, refreshed periodically.
+	Videos = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "catalog_videos",
+		Help: "Videos in the catalog by status, excluding deleted ones",
+	}, []string{"status"})
+
+	// StorageDeletionsTotal counts blob storage delete calls by operation (blob/page) and outcome (ok/error).
+	StorageDeletionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_storage_deletions_total",
+		Help: "Blob storage delete calls, by operation (blob/page) and outcome (ok/error)",
+	}, []string{"op", "outcome"})
+
+	// StorageClientInitsTotal counts attempts to build the blob storage client by outcome.
+	StorageClientInitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_storage_client_inits_total",
+		Help: "Attempts to build the blob storage client, by outcome (ok/error); failures are retried on later use",
+	}, []string{"outcome"})
+
+	// DatabaseOnlyPurgesTotal counts videos purged without storage cleanup.
+	DatabaseOnlyPurgesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "catalog_video_purges_database_only_total",
+		Help: "Videos purged from the database only because no blob storage client was available, leaving their files behind",
+	})
+
+	// StorageBlobsDeletedTotal counts blobs removed from storage.
+	StorageBlobsDeletedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "catalog_storage_blobs_deleted_total",
+		Help: "Blobs removed from storage by video cleanup",
+	})
+
+	// CacheRequestsTotal counts shared cache lookups by cache (video/list) and result (hit/miss/error).
+	CacheRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_cache_requests_total",
+		Help: "Shared cache lookups, by cache (video/list) and result (hit/miss/error)",
+	}, []string{"cache", "result"})
+
+	// SearchIndexOpsTotal counts search index writes by outcome (written/failed/dropped/reindexed).
+	SearchIndexOpsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_search_index_ops_total",
+		Help: "Search index writes, by outcome (written/failed/dropped/reindexed)",
+	}, []string{"outcome"})
+
+	// SearchFallbacksTotal counts searches answered from the database because the index failed.
+	SearchFallbacksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_search_fallbacks_total",
+		Help: "Searches that fell back to the database, by reason (unavailable/error)",
+	}, []string{"reason"})
+
+	// VideoReportsTotal counts abuse reports filed by users, by reason.
+	VideoReportsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_video_reports_total",
+		Help: "Abuse reports filed against videos, by reason",
+	}, []string{"reason"})
+
+	// VideoReportsResolvedTotal counts reports closed by admins, by resolution (reviewed/dismissed).
+	VideoReportsResolvedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_video_reports_resolved_total",
+		Help: "Abuse reports closed by admins, by resolution (reviewed/dismissed)",
+	}, []string{"status"})
+)
@@ -0,0 +1,27 @@
+package metrics_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+)
+
+func TestCatalogMetricsRegistered(t *testing.T) {
+	for name, c := range map[string]prometheus.Collector{
+		"catalog_events_processed_total":        metrics.EventsProcessedTotal,
+		"catalog_event_handle_duration_seconds": metrics.EventHandleDuration,
+		"catalog_http_request_duration_seconds": metrics.HTTPRequestDuration,
+		"catalog_videos":                        metrics.Videos,
+		"catalog_storage_deletions_total":       metrics.StorageDeletionsTotal,
+		"catalog_storage_blobs_deleted_total":   metrics.StorageBlobsDeletedTotal,
+	} {
+		// Registering again is refused only if /metrics already exposes it
+		var already prometheus.AlreadyRegisteredError
+		if err := prometheus.DefaultRegisterer.Register(c); !errors.As(err, &already) {
+			t.Errorf("%s is not on the default registry (Register: %v)", name, err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// AnonymousPrefix marks an anonymous-session identity wherever a user ID is stored
+const AnonymousPrefix = "anon:"
+
+// AnonymousSession is a logged-out visitor issued by POST /sessions/anonymous.
+// LastSeenAt drives retention; MergedInto is set once the visitor signs up.
+type AnonymousSession struct {
+	ID         string     `json:"id" gorm:"primaryKey;size:32"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" gorm:"index"`
+	MergedInto string     `json:"merged_into,omitempty" gorm:"size:191"`
+	MergedAt   *time.Time `json:"merged_at,omitempty"`
+}
+
+// AnonymousIdentity is the identity stored for rows written by an anonymous session
+func AnonymousIdentity(sessionID string) string { return AnonymousPrefix + sessionID }
+
+// IsAnonymousIdentity reports whether a stored user ID belongs to an anonymous session
+func IsAnonymousIdentity(userID string) bool { return strings.HasPrefix(userID, AnonymousPrefix) }
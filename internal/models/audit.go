@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Audit actions
+const (
+	// AuditActionImpersonate records a request an admin made as another user
+	AuditActionImpersonate = "impersonate"
+	// AuditActionDeleteVideo records an admin deleting someone's video
+	AuditActionDeleteVideo = "admin.delete_video"
+	// AuditActionDeleteComment records an admin deleting someone's comment
+	AuditActionDeleteComment = "admin.delete_comment"
+	// AuditActionSetStatus records an admin forcing a video's status
+	AuditActionSetStatus = "admin.set_status"
+	// AuditActionRetryDeletion records an admin retrying a failed video deletion
+	AuditActionRetryDeletion = "admin.retry_deletion"
+	// AuditActionCreateCategory, AuditActionUpdateCategory and AuditActionDeleteCategory
+	// record an admin managing the video categories
+	AuditActionCreateCategory = "admin.create_category"
+	AuditActionUpdateCategory = "admin.update_category"
+	AuditActionDeleteCategory = "admin.delete_category"
+	// AuditActionDeleteUserContent records an admin purging a deleted account's content
+	AuditActionDeleteUserContent = "admin.delete_user_content"
+	// AuditActionExportUserData records an admin downloading another user's data export
+	AuditActionExportUserData = "admin.export_user_data"
+	// AuditActionResolveReport records an admin resolving an abuse report, and any
+	// moderation status it set on the video
+	AuditActionResolveReport = "admin.resolve_report"
+	// AuditActionResolveModerationFlag records an admin approving or upholding a
+	// moderation flag, which may show a hidden comment again
+	AuditActionResolveModerationFlag = "admin.resolve_moderation_flag"
+)
+
+// Audit outcomes for admin actions. The entry is written as started before the
+// action runs, so an action is never performed unaudited, then updated.
+const (
+	AuditOutcomeStarted   = "started"
+	AuditOutcomeSucceeded = "succeeded"
+	AuditOutcomeFailed    = "failed"
+)
+
+// AuditLog records a privileged action: who did it, on whose behalf, and where.
+// SubjectID is the user affected (the impersonated user, or the owner of the
+// content acted on) and Target the object, such as "video:12".
+type AuditLog struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Action    string    `json:"action" gorm:"size:40;not null;index"`
+	ActorID   string    `json:"actor_id" gorm:"size:191;not null;index"`
+	SubjectID string    `json:"subject_id" gorm:"size:191;index"`
+	Target    string    `json:"target,omitempty" gorm:"size:100;index"`
+	Detail    string    `json:"detail,omitempty" gorm:"type:text"`
+	Method    string    `json:"method" gorm:"size:10"`
+	Route     string    `json:"route" gorm:"size:255"`
+	Path      string    `json:"path" gorm:"size:1024"`
+	Outcome   string    `json:"outcome" gorm:"size:20"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// BlobCleanupCheckpoint tracks a video's prefix deletion page by page. Marker is
+// the listing continuation of the next page to delete, so a failed cleanup resumes
+// there instead of re-listing everything already deleted.
+type BlobCleanupCheckpoint struct {
+	ID           uint       `json:"id" gorm:"primarykey"`
+	VideoID      uint       `json:"video_id" gorm:"not null;uniqueIndex:idx_blob_cleanup_key,priority:1"`
+	Prefix       string     `json:"prefix" gorm:"size:1024;not null;uniqueIndex:idx_blob_cleanup_key,priority:2"`
+	Marker       string     `json:"marker" gorm:"type:text"`
+	PagesDone    int        `json:"pages_done" gorm:"not null;default:0"`
+	BlobsDeleted int64      `json:"blobs_deleted" gorm:"not null;default:0"`
+	LastError    string     `json:"last_error,omitempty" gorm:"type:text"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxCategorySlugLen and MaxCategoryNameLen bound a category's slug and display name
+const (
+	MaxCategorySlugLen = 50
+	MaxCategoryNameLen = 100
+)
+
+// Category is one of the managed values a video's Category may hold. Videos store
+// the slug; inactive categories stay on the videos that have them but can't be
+// newly chosen. The slug never changes once created.
+type Category struct {
+	Slug        string    `json:"slug" gorm:"primarykey;size:50"`
+	DisplayName string    `json:"display_name" gorm:"size:100;not null"`
+	Active      bool      `json:"active" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DefaultCategories are seeded when the categories table is first created
+var DefaultCategories = []Category{
+	{Slug: "autos-vehicles", DisplayName: "Autos & Vehicles"},
+	{Slug: "comedy", DisplayName: "Comedy"},
+	{Slug: "education", DisplayName: "Education"},
+	{Slug: "entertainment", DisplayName: "Entertainment"},
+	{Slug: "film-animation", DisplayName: "Film & Animation"},
+	{Slug: "gaming", DisplayName: "Gaming"},
+	{Slug: "howto-style", DisplayName: "Howto & Style"},
+	{Slug: "music", DisplayName: "Music"},
+	{Slug: "news-politics", DisplayName: "News & Politics"},
+	{Slug: "nonprofits-activism", DisplayName: "Nonprofits & Activism"},
+	{Slug: "people-blogs", DisplayName: "People & Blogs"},
+	{Slug: "pets-animals", DisplayName: "Pets & Animals"},
+	{Slug: "science-technology", DisplayName: "Science & Technology"},
+	{Slug: "sports", DisplayName: "Sports"},
+	{Slug: "travel-events", DisplayName: "Travel & Events"},
+}
+
+// CategorySlug derives a slug from free text: accents are stripped, letters
+// lowercased, and every run of anything other than a-z and 0-9 becomes one dash,
+// so "Müsic", "music" and " MUSIC " all give "music". It returns "" when nothing
+// usable is left.
+func CategorySlug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	slug := b.String()
+	if len(slug) > MaxCategorySlugLen {
+		slug = strings.TrimRight(slug[:MaxCategorySlugLen], "-")
+	}
+	return slug
+}
+
+// ValidCategorySlug reports whether s is already in the form CategorySlug produces
+func ValidCategorySlug(s string) bool {
+	return s != "" && CategorySlug(s) == s
+}
+
+// CategoryCreateRequest is the body of POST /admin/categories
+type CategoryCreateRequest struct {
+	Slug        string `json:"slug" binding:"required"`
+	DisplayName string `json:"display_name" binding:"required"`
+	// Active defaults to true
+	Active *bool `json:"active"`
+}
+
+// CategoryUpdateRequest is the body of PUT /admin/categories/:slug; omitted fields
+// are left alone
+type CategoryUpdateRequest struct {
+	DisplayName *string `json:"display_name"`
+	Active      *bool   `json:"active"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Chapter limits
+const (
+	MaxChapters        = 100
+	MaxChapterTitleLen = 200
+)
+
+// Chapter marks where a named section of a video starts
+type Chapter struct {
+	Title        string  `json:"title"`
+	StartSeconds float64 `json:"start_seconds"`
+}
+
+// ChapterInfo is a chapter detected by the transcoder, as carried in a transcoded event
+type ChapterInfo struct {
+	Title        string  `json:"title"`
+	StartSeconds float64 `json:"startSeconds"`
+}
+
+// ValidateChapters checks chapters set by a creator: at most MaxChapters, each
+// with a title, starting at or after 0 and after the previous chapter, and before
+// duration when it is known (non-zero). It returns a message per offending field,
+// keyed like "chapters[2].start_seconds", or nil if all is well.
+func ValidateChapters(chapters []Chapter, duration float64) map[string]string {
+	fields := map[string]string{}
+	if len(chapters) > MaxChapters {
+		fields["chapters"] = fmt.Sprintf("at most %d chapters", MaxChapters)
+	}
+	for i, ch := range chapters {
+		title := fmt.Sprintf("chapters[%d].title", i)
+		start := fmt.Sprintf("chapters[%d].start_seconds", i)
+		switch t := strings.TrimSpace(ch.Title); {
+		case t == "":
+			fields[title] = "required"
+		case len([]rune(t)) > MaxChapterTitleLen:
+			fields[title] = fmt.Sprintf("at most %d characters", MaxChapterTitleLen)
+		}
+		switch {
+		case ch.StartSeconds < 0:
+			fields[start] = "must not be negative"
+		case i > 0 && ch.StartSeconds <= chapters[i-1].StartSeconds:
+			fields[start] = fmt.Sprintf("must be after chapters[%d].start_seconds", i-1)
+		case duration > 0 && ch.StartSeconds >= duration:
+			fields[start] = fmt.Sprintf("must be before the end of the video (%gs)", duration)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// ChaptersFromEvent turns detected chapters into stored ones, sorted by start.
+// Unlike creator input, bad entries are dropped rather than rejected: untitled
+// ones, negative or duplicate starts, and starts past duration when it is known.
+func ChaptersFromEvent(in []ChapterInfo, duration float64) []Chapter {
+	sorted := append([]ChapterInfo{}, in...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].StartSeconds < sorted[j].StartSeconds })
+	var out []Chapter
+	for _, ch := range sorted {
+		title := strings.TrimSpace(ch.Title)
+		if title == "" || ch.StartSeconds < 0 || (duration > 0 && ch.StartSeconds >= duration) {
+			continue
+		}
+		if n := len(out); n > 0 && ch.StartSeconds <= out[n-1].StartSeconds {
+			continue
+		}
+		if r := []rune(title); len(r) > MaxChapterTitleLen {
+			title = string(r[:MaxChapterTitleLen])
+		}
+		out = append(out, Chapter{Title: title, StartSeconds: ch.StartSeconds})
+		if len(out) == MaxChapters {
+			break
+		}
+	}
+	return out
+}
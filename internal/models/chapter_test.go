@@ -0,0 +1,78 @@
+package models_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+func TestValidateChapters(t *testing.T) {
+	ch := func(title string, start float64) models.Chapter {
+		return models.Chapter{Title: title, StartSeconds: start}
+	}
+	tests := []struct {
+		name     string
+		chapters []models.Chapter
+		duration float64
+		want     []string // offending fields
+	}{
+		{"none", nil, 0, nil},
+		{"ascending", []models.Chapter{ch("Intro", 0), ch("Main", 30.5), ch("Outro", 590)}, 600, nil},
+		{"duration unknown", []models.Chapter{ch("Intro", 0), ch("Late", 1e6)}, 0, nil},
+		{"negative start", []models.Chapter{ch("Intro", -1)}, 0, []string{"chapters[0].start_seconds"}},
+		{"out of order", []models.Chapter{ch("A", 10), ch("B", 5)}, 0, []string{"chapters[1].start_seconds"}},
+		{"same start", []models.Chapter{ch("A", 10), ch("B", 10)}, 0, []string{"chapters[1].start_seconds"}},
+		{"at the end", []models.Chapter{ch("A", 0), ch("B", 600)}, 600, []string{"chapters[1].start_seconds"}},
+		{"blank title", []models.Chapter{ch("  ", 0)}, 0, []string{"chapters[0].title"}},
+		{"long title", []models.Chapter{ch(strings.Repeat("é", models.MaxChapterTitleLen+1), 0)}, 0, []string{"chapters[0].title"}},
+		{"several problems", []models.Chapter{ch("", -2), ch("B", 1)}, 0, []string{"chapters[0].start_seconds", "chapters[0].title"}},
+	}
+	for _, tt := range tests {
+		fields := models.ValidateChapters(tt.chapters, tt.duration)
+		var got []string
+		for field := range fields {
+			got = append(got, field)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: fields %v, want %v", tt.name, fields, tt.want)
+			continue
+		}
+		for _, field := range tt.want {
+			if fields[field] == "" {
+				t.Errorf("%s: fields %v, want a message for %s", tt.name, fields, field)
+			}
+		}
+	}
+
+	many := make([]models.Chapter, models.MaxChapters+1)
+	for i := range many {
+		many[i] = ch("c", float64(i))
+	}
+	if fields := models.ValidateChapters(many, 0); len(fields) != 1 || fields["chapters"] == "" {
+		t.Errorf("too many chapters: %v", fields)
+	}
+}
+
+func TestChaptersFromEvent(t *testing.T) {
+	in := []models.ChapterInfo{
+		{Title: "Outro", StartSeconds: 500},
+		{Title: " Intro ", StartSeconds: 0},
+		{Title: "", StartSeconds: 100},       // untitled
+		{Title: "Before", StartSeconds: -5},  // negative
+		{Title: "Again", StartSeconds: 0},    // duplicate start
+		{Title: "After", StartSeconds: 700},  // past the end
+		{Title: "Main", StartSeconds: 60.25}, // out of order
+	}
+	want := []models.Chapter{{Title: "Intro", StartSeconds: 0}, {Title: "Main", StartSeconds: 60.25}, {Title: "Outro", StartSeconds: 500}}
+	if got := models.ChaptersFromEvent(in, 600); !reflect.DeepEqual(got, want) {
+		t.Errorf("ChaptersFromEvent = %+v, want %+v", got, want)
+	}
+	if got := models.ChaptersFromEvent(in, 0); len(got) != 4 || got[3].Title != "After" {
+		t.Errorf("duration unknown = %+v, want the late chapter kept", got)
+	}
+	if got := models.ChaptersFromEvent([]models.ChapterInfo{{Title: strings.Repeat("x", models.MaxChapterTitleLen+5)}}, 0); len([]rune(got[0].Title)) != models.MaxChapterTitleLen {
+		t.Errorf("long title kept %d characters, want it cut to %d", len([]rune(got[0].Title)), models.MaxChapterTitleLen)
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Data export job states
+const (
+	DataExportPending = "pending"
+	DataExportRunning = "running"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+	DataExportExpired = "expired"
+)
+
+// DataExport tracks an asynchronous personal data export for a user
+type DataExport struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	UserID      string     `json:"user_id" gorm:"size:191;not null;index"`
+	Status      string     `json:"status" gorm:"size:20;not null;index"`
+	Trigger     string     `json:"trigger" gorm:"size:20;not null"`
+	FilePath    string     `json:"-"`
+	SizeBytes   int64      `json:"size_bytes"`
+	Rows        int64      `json:"rows"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// DataExportRequestedEvent asks for a user's personal data to be exported
+type DataExportRequestedEvent struct {
+	UserID string `json:"user_id"`
+}
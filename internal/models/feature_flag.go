@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// FeatureFlag overrides a flag's code default and environment setting at runtime.
+// Replicas poll the table, so a change applies everywhere within the poll interval.
+// Deleting the row returns the flag to its environment or code default.
+type FeatureFlag struct {
+	Name    string `json:"name" gorm:"primarykey;size:100"`
+	Enabled bool   `json:"enabled" gorm:"not null;default:false"`
+	// RolloutPercent is the share of users, by a stable hash of the user ID, for
+	// whom an enabled flag is on
+	RolloutPercent int       `json:"rollout_percent" gorm:"not null;default:100;check:rollout_percent BETWEEN 0 AND 100"`
+	UpdatedBy      string    `json:"updated_by,omitempty" gorm:"size:191"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers the response to a write sent with an Idempotency-Key
+// header, so a client retrying the same request gets that response back instead
+// of repeating the write. Keys are scoped to the caller and the route. A row with
+// Status 0 is a request still being handled.
+type IdempotencyKey struct {
+	UserID   string `json:"user_id" gorm:"primaryKey;size:191"`
+	Endpoint string `json:"endpoint" gorm:"primaryKey;size:191"`
+	Key      string `json:"key" gorm:"primaryKey;size:255"`
+	// RequestHash is a SHA-256 of the request path and body; reusing the key for
+	// a different request is refused
+	RequestHash string `json:"request_hash" gorm:"size:64;not null"`
+	Status      int    `json:"status" gorm:"not null;default:0"`
+	ContentType string `json:"content_type" gorm:"size:100"`
+	Body        []byte `json:"-"`
+	// ResponseHash is a SHA-256 of Body
+	ResponseHash string    `json:"response_hash" gorm:"size:64"`
+	CreatedAt    time.Time `json:"created_at" gorm:"not null;index"`
+}
+
+// Completed reports whether the original request has finished and its response is stored
+func (k *IdempotencyKey) Completed() bool { return k.Status != 0 }
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Inbound event states
+const (
+	InboundProcessed = "processed"
+	// InboundFailed events were quarantined or dead-lettered; Error says why
+	InboundFailed = "failed"
+	// InboundRequeued events were interrupted by shutdown and handed back to the broker
+	InboundRequeued    = "requeued"
+	InboundReprocessed = "reprocessed"
+)
+
+// InboundEvent is a broker message as received, with how handling it went, kept for
+// a retention period so operators can see what producers sent and reprocess it
+type InboundEvent struct {
+	ID         uint   `json:"id" gorm:"primarykey"`
+	Kind       string `json:"kind" gorm:"size:40;not null"`
+	RoutingKey string `json:"routing_key" gorm:"size:191"`
+	UploadID   string `json:"upload_id,omitempty" gorm:"size:191;index"`
+	// Body is the payload, cut to the archive's size cap on a rune boundary, with
+	// invalid UTF-8 and NULs replaced by U+FFFD; Truncated events can't be reprocessed
+	Body      string `json:"body,omitempty" gorm:"type:text;not null"`
+	BodySize  int    `json:"body_size" gorm:"not null"`
+	Truncated bool   `json:"truncated" gorm:"not null;default:false"`
+	// Metadata is the envelope (headers, message and correlation IDs, timestamps)
+	Metadata map[string]interface{} `json:"metadata" gorm:"type:jsonb;serializer:json"`
+	Status   string                 `json:"status" gorm:"size:20;not null;index:idx_inbound_events_status,priority:1"`
+	Error    string                 `json:"error,omitempty" gorm:"type:text"`
+	// QuarantinedEventID links a failed event to its quarantine entry
+	QuarantinedEventID *uint      `json:"quarantined_event_id,omitempty"`
+	ReprocessCount     int        `json:"reprocess_count" gorm:"not null;default:0"`
+	ReprocessError     string     `json:"reprocess_error,omitempty" gorm:"type:text"`
+	ReprocessedAt      *time.Time `json:"reprocessed_at,omitempty"`
+	ReceivedAt         time.Time  `json:"received_at" gorm:"not null;index;index:idx_inbound_events_status,priority:2"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// JobRun is the coordination and bookkeeping row for one background job. The
+// lease columns make sure only one replica runs a job at a time.
+type JobRun struct {
+	Name           string     `json:"name" gorm:"primarykey;size:100"`
+	LeaseOwner     string     `json:"lease_owner,omitempty" gorm:"size:191"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty"`
+	LastDurationMs int64      `json:"last_duration_ms"`
+	LastSuccess    bool       `json:"last_success"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty"`
+	RunCount       int64      `json:"run_count" gorm:"not null;default:0"`
+	FailureCount   int64      `json:"failure_count" gorm:"not null;default:0"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
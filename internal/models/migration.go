@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// MigrationCheckpoint tracks progress of a resumable batched data migration
+type MigrationCheckpoint struct {
+	Name      string    `json:"name" gorm:"primarykey;size:100"`
+	LastID    uint      `json:"last_id"`
+	Migrated  int64     `json:"migrated"`
+	Done      bool      `json:"done"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Moderation target types
+const (
+	ModerationTargetVideo   = "video"
+	ModerationTargetComment = "comment"
+)
+
+// Moderation flag statuses
+const (
+	// ModerationFlagged marks an item scored above the flag threshold; informational
+	ModerationFlagged = "flagged"
+	// ModerationNeedsReview marks a high-severity video waiting for an admin decision
+	ModerationNeedsReview = "needs_review"
+	// ModerationAutoHidden marks a high-severity comment that was moved to pending
+	ModerationAutoHidden = "auto_hidden"
+	// ModerationApproved marks content an admin cleared; a comment hidden for it is shown again
+	ModerationApproved = "approved"
+	// ModerationUpheld marks content an admin agreed with the provider about; it stays as it is
+	ModerationUpheld = "upheld"
+)
+
+// Admin decisions on a moderation flag
+const (
+	ModerationDecisionApprove = "approve"
+	ModerationDecisionUphold  = "uphold"
+)
+
+// ModerationFlag stores a moderation provider result for a piece of user content
+type ModerationFlag struct {
+	ID         uint               `json:"id" gorm:"primarykey"`
+	TargetType string             `json:"target_type" gorm:"size:20;not null;index:idx_moderation_flags_target"`
+	TargetID   uint               `json:"target_id" gorm:"not null;index:idx_moderation_flags_target"`
+	Field      string             `json:"field" gorm:"size:40;not null"`
+	Categories map[string]float64 `json:"categories" gorm:"type:jsonb;serializer:json"`
+	MaxScore   float64            `json:"max_score"`
+	Severity   string             `json:"severity" gorm:"size:20;not null"`
+	Status     string             `json:"status" gorm:"size:20;not null;index"`
+	Provider   string             `json:"provider" gorm:"size:40"`
+	// ResolvedBy and ResolvedAt are set once an admin approves or upholds the flag
+	ResolvedBy string     `json:"resolved_by,omitempty" gorm:"size:191"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ModerationFlagResolveRequest is an admin's decision on a flag
+type ModerationFlagResolveRequest struct {
+	// Decision is approve or uphold
+	Decision string `json:"decision" binding:"required"`
+	Note     string `json:"note" binding:"max=1000"`
+}
+
+// ModerationStatus is an admin's decision on a video, kept apart from its
+// processing status and from the owner's visibility
+type ModerationStatus string
+
+const (
+	// ModerationStatusActive videos are shown as their visibility allows
+	ModerationStatusActive ModerationStatus = "active"
+	// ModerationStatusUnderReview videos are being looked at but stay visible
+	ModerationStatusUnderReview ModerationStatus = "under_review"
+	// ModerationStatusTakenDown videos are hidden from everyone but their owner
+	ModerationStatusTakenDown ModerationStatus = "taken_down"
+)
+
+// ModerationStatuses lists every valid moderation status
+var ModerationStatuses = []ModerationStatus{ModerationStatusActive, ModerationStatusUnderReview, ModerationStatusTakenDown}
+
+// Valid reports whether s is a known moderation status
+func (s ModerationStatus) Valid() bool {
+	for _, known := range ModerationStatuses {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Notification types
+const (
+	NotificationComment = "comment"
+	// NotificationCommentBatch is a rolling "X new comments" notification updated in place
+	NotificationCommentBatch = "comment_batch"
+	// NotificationDataExportReady tells a user their personal data export can be downloaded
+	NotificationDataExportReady = "data_export_ready"
+)
+
+// Notification is an in-app notification for a user
+type Notification struct {
+	ID        uint   `json:"id" gorm:"primarykey"`
+	UserID    string `json:"user_id" gorm:"size:191;not null;index:idx_notifications_user_video,priority:1;index:idx_notifications_user_created,priority:1"`
+	VideoID   *uint  `json:"video_id,omitempty" gorm:"index:idx_notifications_user_video,priority:2"`
+	Type      string `json:"type" gorm:"size:30;not null"`
+	ActorID   string `json:"actor_id,omitempty" gorm:"size:191"`
+	CommentID *uint  `json:"comment_id,omitempty"`
+	// Count is the number of events represented; above 1 only for batch notifications
+	Count   int    `json:"count" gorm:"not null;default:1"`
+	Message string `json:"message"`
+	// WindowEndsAt bounds how long a batch notification keeps absorbing new events
+	WindowEndsAt *time.Time `json:"-"`
+	ReadAt       *time.Time `json:"read_at"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"index:idx_notifications_user_created,priority:2"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
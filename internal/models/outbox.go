@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// OutboxMessage is a broker message written in the same transaction as the change
+// it announces, then published by the outbox dispatcher. It is pending until SentAt
+// is set. A message that failed MaxAttempts times stays unsent with its LastError,
+// for an operator to inspect and requeue.
+type OutboxMessage struct {
+	ID            uint64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	RoutingKey    string     `json:"routing_key" gorm:"size:255;not null"`
+	Body          string     `json:"body" gorm:"type:text;not null"`
+	Attempts      int        `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts   int        `json:"max_attempts" gorm:"not null"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"not null;index:idx_outbox_pending,where:sent_at IS NULL"`
+	LastError     string     `json:"last_error,omitempty" gorm:"type:text"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty" gorm:"index"`
+}
+
+// TableName keeps the table name short; operators query it directly
+func (OutboxMessage) TableName() string { return "outbox" }
@@ -0,0 +1,45 @@
+package models
+
+// PreviewInfo carries hover-scrub preview assets in a transcoded event: a sprite
+// sheet with its grid layout, a WebVTT thumbnails track, or both.
+type PreviewInfo struct {
+	SpriteURL       string  `json:"spriteUrl,omitempty"`
+	TileWidth       int     `json:"tileWidth,omitempty"`
+	TileHeight      int     `json:"tileHeight,omitempty"`
+	Columns         int     `json:"columns,omitempty"`
+	IntervalSeconds float64 `json:"intervalSeconds,omitempty"`
+	Count           int     `json:"count,omitempty"`
+	VTTURL          string  `json:"vttUrl,omitempty"`
+}
+
+// VideoPreviews is the stored form of a video's preview assets
+type VideoPreviews struct {
+	SpriteURL       string  `json:"sprite_url,omitempty"`
+	TileWidth       int     `json:"tile_width,omitempty"`
+	TileHeight      int     `json:"tile_height,omitempty"`
+	Columns         int     `json:"columns,omitempty"`
+	IntervalSeconds float64 `json:"interval_seconds,omitempty"`
+	Count           int     `json:"count,omitempty"`
+	VTTURL          string  `json:"vtt_url,omitempty"`
+}
+
+// ToVideoPreviews returns the usable parts of the event payload, or nil if neither
+// a complete sprite grid nor a VTT track was supplied
+func (p *PreviewInfo) ToVideoPreviews() *VideoPreviews {
+	if p == nil {
+		return nil
+	}
+	out := &VideoPreviews{VTTURL: p.VTTURL}
+	if p.SpriteURL != "" && p.TileWidth > 0 && p.TileHeight > 0 && p.IntervalSeconds > 0 && p.Count > 0 {
+		out.SpriteURL = p.SpriteURL
+		out.TileWidth = p.TileWidth
+		out.TileHeight = p.TileHeight
+		out.Columns = p.Columns
+		out.IntervalSeconds = p.IntervalSeconds
+		out.Count = p.Count
+	}
+	if out.SpriteURL == "" && out.VTTURL == "" {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package models_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+func TestToVideoPreviews(t *testing.T) {
+	sprite := models.PreviewInfo{SpriteURL: "https://cdn/s.jpg", TileWidth: 160, TileHeight: 90, Columns: 10, IntervalSeconds: 2, Count: 50}
+	grid := models.VideoPreviews{SpriteURL: "https://cdn/s.jpg", TileWidth: 160, TileHeight: 90, Columns: 10, IntervalSeconds: 2, Count: 50}
+	noTiles := sprite
+	noTiles.Count = 0
+	noTilesWithVTT := noTiles
+	noTilesWithVTT.VTTURL = "https://cdn/p.vtt"
+	both := sprite
+	both.VTTURL = "https://cdn/p.vtt"
+	bothWant := grid
+	bothWant.VTTURL = "https://cdn/p.vtt"
+
+	tests := []struct {
+		name string
+		in   *models.PreviewInfo
+		want *models.VideoPreviews
+	}{
+		{"absent", nil, nil},
+		{"empty", &models.PreviewInfo{}, nil},
+		{"sprite grid", &sprite, &grid},
+		{"VTT track", &models.PreviewInfo{VTTURL: "https://cdn/p.vtt"}, &models.VideoPreviews{VTTURL: "https://cdn/p.vtt"}},
+		{"both", &both, &bothWant},
+		// An incomplete grid can't be drawn, so it is dropped but the track kept
+		{"incomplete grid", &noTiles, nil},
+		{"incomplete grid beside a VTT track", &noTilesWithVTT, &models.VideoPreviews{VTTURL: "https://cdn/p.vtt"}},
+	}
+	for _, tt := range tests {
+		if got := tt.in.ToVideoPreviews(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVideoJSONHidesDisabledPreviews(t *testing.T) {
+	video := models.Video{Title: "t", Previews: &models.VideoPreviews{VTTURL: "https://cdn/p.vtt"}}
+	body, _ := json.Marshal(video)
+	if !strings.Contains(string(body), `"previews":{"vtt_url":"https://cdn/p.vtt"}`) {
+		t.Errorf("previews missing from %s", body)
+	}
+
+	video.PreviewsDisabled = true
+	body, _ = json.Marshal(video)
+	if strings.Contains(string(body), "vtt_url") || !strings.Contains(string(body), `"previews_disabled":true`) {
+		t.Errorf("disabled previews serialized as %s", body)
+	}
+	// Hiding them in the response doesn't discard them
+	if video.Previews == nil {
+		t.Error("marshalling cleared the video's previews")
+	}
+}
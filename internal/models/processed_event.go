@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ProcessedEvent records a broker event that has been applied, so a redelivery of
+// the same event is recognised and skipped. It is written in the same transaction
+// as the event's effect.
+type ProcessedEvent struct {
+	// Key is "<kind>:msg:<message id>" when the producer set a message ID, else
+	// "<kind>:<upload id>:<sha256 of the event>"
+	Key         string    `json:"key" gorm:"primarykey;size:191"`
+	Kind        string    `json:"kind" gorm:"size:50;not null"`
+	UploadID    string    `json:"upload_id" gorm:"size:191"`
+	MessageID   string    `json:"message_id,omitempty" gorm:"size:191"`
+	DeliveryTag uint64    `json:"delivery_tag,omitempty"`
+	ProcessedAt time.Time `json:"processed_at" gorm:"not null;index"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Public event types: externally significant changes recorded in the public event log
+const (
+	PublicEventPublished            = "video.published"
+	PublicEventTakenDown            = "video.taken_down"
+	PublicEventDeleted              = "video.deleted"
+	PublicEventRestored             = "video.restored"
+	PublicEventOwnershipTransferred = "video.ownership_transferred"
+)
+
+// PublicEventTypes lists every type the log accepts
+var PublicEventTypes = []string{
+	PublicEventPublished,
+	PublicEventTakenDown,
+	PublicEventDeleted,
+	PublicEventRestored,
+	PublicEventOwnershipTransferred,
+}
+
+// PublicEvent is an entry in the append-only public event log. Seq increases in
+// commit order, so readers can page with after_seq and never miss an entry. Rows
+// are never updated or deleted; a trigger rejects both.
+type PublicEvent struct {
+	Seq        int64                  `json:"seq" gorm:"primaryKey;autoIncrement"`
+	Type       string                 `json:"type" gorm:"size:64;not null;index"`
+	VideoID    uint                   `json:"video_id" gorm:"not null;index"`
+	UploadID   string                 `json:"upload_id" gorm:"size:191"`
+	OwnerID    string                 `json:"owner_id" gorm:"size:191"`
+	ActorID    string                 `json:"actor_id" gorm:"size:191"`
+	Details    map[string]interface{} `json:"details,omitempty" gorm:"type:jsonb;serializer:json"`
+	OccurredAt time.Time              `json:"occurred_at" gorm:"not null"`
+}
+
+// TableName keeps the table name explicit; the log's name is part of its contract
+func (PublicEvent) TableName() string { return "public_event_log" }
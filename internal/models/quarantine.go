@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Quarantined event states
+const (
+	QuarantineHeld     = "quarantined"
+	QuarantineReplayed = "replayed"
+	// QuarantineRejected means a replay was refused by the ordering guard or the
+	// status state machine
+	QuarantineRejected = "rejected"
+)
+
+// QuarantinedEvent is a broker message whose handler failed, kept with its original
+// envelope so it can be inspected and replayed later
+type QuarantinedEvent struct {
+	ID         uint   `json:"id" gorm:"primarykey"`
+	Kind       string `json:"kind" gorm:"size:40;not null"`
+	RoutingKey string `json:"routing_key" gorm:"size:191"`
+	UploadID   string `json:"upload_id" gorm:"size:191;index"`
+	Body       string `json:"body" gorm:"type:text;not null"`
+	// Metadata is the original envelope (headers, correlation ID, retry count, timestamps)
+	Metadata    map[string]interface{} `json:"metadata" gorm:"type:jsonb;serializer:json"`
+	Error       string                 `json:"error"`
+	Status      string                 `json:"status" gorm:"size:20;not null;index"`
+	ReplayCount int                    `json:"replay_count" gorm:"not null;default:0"`
+	ReplayError string                 `json:"replay_error,omitempty"`
+	ReplayedAt  *time.Time             `json:"replayed_at,omitempty"`
+	// EventTime is the producer timestamp, used to replay events in their original order
+	EventTime *time.Time `json:"event_time,omitempty" gorm:"index"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
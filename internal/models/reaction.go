@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Reaction values stored in video_reactions
+const (
+	ReactionLike    = 1
+	ReactionDislike = -1
+)
+
+// VideoReaction is one user's like or dislike of a video. A user has at most one
+// reaction per video; switching from like to dislike updates the row.
+type VideoReaction struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	VideoID   uint      `json:"video_id" gorm:"not null;uniqueIndex:idx_video_reactions_key,priority:1"`
+	UserID    string    `json:"user_id" gorm:"size:191;not null;uniqueIndex:idx_video_reactions_key,priority:2;index"`
+	Value     int       `json:"value" gorm:"type:smallint;not null;check:value IN (-1, 1)"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CommentReaction is one user's like of a comment. Comments can only be liked, so
+// the row's existence is the like.
+type CommentReaction struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CommentID uint      `json:"comment_id" gorm:"not null;uniqueIndex:idx_comment_reactions_key,priority:1"`
+	UserID    string    `json:"user_id" gorm:"size:191;not null;uniqueIndex:idx_comment_reactions_key,priority:2;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
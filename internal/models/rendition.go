@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// RenditionInfo describes one HLS rendition in a transcoded event
+type RenditionInfo struct {
+	Name       string `json:"name"`
+	Resolution string `json:"resolution,omitempty"`
+	// Bandwidth is the peak bitrate advertised in the master playlist, in bits/s
+	Bandwidth   int64  `json:"bandwidth,omitempty"`
+	PlaylistURL string `json:"playlistUrl"`
+	FileSize    int64  `json:"fileSize,omitempty"`
+}
+
+// VideoRendition is one stored HLS rendition of a video (1080p, 720p, ...). Rows
+// are replaced whenever a transcoded event carries renditions, and removed with
+// the video when it is purged.
+type VideoRendition struct {
+	ID          uint      `json:"-" gorm:"primarykey"`
+	VideoID     uint      `json:"-" gorm:"not null;index"`
+	Name        string    `json:"name" gorm:"size:64;not null"`
+	Resolution  string    `json:"resolution,omitempty" gorm:"size:32"`
+	Bandwidth   int64     `json:"bandwidth"`
+	PlaylistURL string    `json:"playlist_url" gorm:"type:text;not null"`
+	FileSize    int64     `json:"file_size"`
+	CreatedAt   time.Time `json:"-"`
+}
+
+// ToVideoRenditions returns the usable renditions of an event payload, skipping
+// any without a name or playlist URL
+func ToVideoRenditions(in []RenditionInfo) []VideoRendition {
+	var out []VideoRendition
+	for _, r := range in {
+		if r.Name == "" || r.PlaylistURL == "" {
+			continue
+		}
+		out = append(out, VideoRendition{
+			Name:        r.Name,
+			Resolution:  r.Resolution,
+			Bandwidth:   r.Bandwidth,
+			PlaylistURL: r.PlaylistURL,
+			FileSize:    r.FileSize,
+		})
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package models_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+func TestTranscodedEventRenditions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []models.RenditionInfo
+	}{
+		{"master only", `{"uploadId":"up-1","hls":{"masterUrl":"https://cdn/master.m3u8"}}`, nil},
+		{"empty list", `{"uploadId":"up-1","hls":{"masterUrl":"https://cdn/master.m3u8","renditions":[]}}`, []models.RenditionInfo{}},
+		{"renditions", `{"uploadId":"up-1","hls":{"masterUrl":"https://cdn/master.m3u8","renditions":[
+			{"name":"1080p","resolution":"1920x1080","bandwidth":5000000,"playlistUrl":"https://cdn/1080p.m3u8","fileSize":104857600},
+			{"name":"480p","playlistUrl":"https://cdn/480p.m3u8"}]}}`,
+			[]models.RenditionInfo{
+				{Name: "1080p", Resolution: "1920x1080", Bandwidth: 5000000, PlaylistURL: "https://cdn/1080p.m3u8", FileSize: 104857600},
+				{Name: "480p", PlaylistURL: "https://cdn/480p.m3u8"},
+			}},
+	}
+	for _, tt := range tests {
+		var event models.TranscodedEvent
+		if err := json.Unmarshal([]byte(tt.body), &event); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if event.HLS.MasterURL != "https://cdn/master.m3u8" || !reflect.DeepEqual(event.HLS.Renditions, tt.want) {
+			t.Errorf("%s: hls = %+v, want renditions %+v", tt.name, event.HLS, tt.want)
+		}
+	}
+}
+
+func TestToVideoRenditions(t *testing.T) {
+	got := models.ToVideoRenditions([]models.RenditionInfo{
+		{Name: "720p", Resolution: "1280x720", Bandwidth: 2800000, PlaylistURL: "https://cdn/720p.m3u8", FileSize: 42},
+		{Name: "", PlaylistURL: "https://cdn/nameless.m3u8"},
+		{Name: "no-playlist"},
+	})
+	want := []models.VideoRendition{{Name: "720p", Resolution: "1280x720", Bandwidth: 2800000, PlaylistURL: "https://cdn/720p.m3u8", FileSize: 42}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("renditions = %+v, want only the complete one", got)
+	}
+	if got := models.ToVideoRenditions(nil); got != nil {
+		t.Errorf("no renditions = %+v", got)
+	}
+}
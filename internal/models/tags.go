@@ -0,0 +1,229 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TagArray is a []string stored as a native Postgres text[] with proper quoting,
+// so tags containing commas, quotes, braces or backslashes round-trip intact.
+type TagArray []string
+
+// Value implements driver.Valuer
+func (a TagArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, item := range a {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('"')
+		for _, r := range item {
+			if r == '"' || r == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+// Scan implements sql.Scanner
+func (a *TagArray) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		out, err := parsePostgresArray(v)
+		if err != nil {
+			return err
+		}
+		*a = out
+		return nil
+	case []byte:
+		out, err := parsePostgresArray(string(v))
+		if err != nil {
+			return err
+		}
+		*a = out
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into TagArray", src)
+	}
+}
+
+// parsePostgresArray parses a one-dimensional Postgres array literal such as
+// {plain,"quoted, with comma","with \"escapes\""}. NULL elements are skipped.
+func parsePostgresArray(s string) ([]string, error) {
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, fmt.Errorf("malformed array literal (%d bytes)", len(s))
+	}
+	body := s[1 : len(s)-1]
+	out := []string{}
+	if body == "" {
+		return out, nil
+	}
+
+	var cur strings.Builder
+	quoted, inQuotes, escaped := false, false, false
+	for i := 0; i < len(body); i++ {
+		ch := body[i]
+		switch {
+		case escaped:
+			cur.WriteByte(ch)
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case ch == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case ch == ',' && !inQuotes:
+			if quoted || cur.String() != "NULL" {
+				out = append(out, cur.String())
+			}
+			cur.Reset()
+			quoted = false
+		default:
+			cur.WriteByte(ch)
+		}
+	}
+	if inQuotes || escaped {
+		return nil, fmt.Errorf("malformed array literal (%d bytes)", len(s))
+	}
+	if quoted || cur.String() != "NULL" {
+		out = append(out, cur.String())
+	}
+	return out, nil
+}
+
+// Tag limits applied by NormalizeTags
+const (
+	MaxTagLen = 50
+	MaxTags   = 25
+)
+
+// NormalizeTags is the single normalization every tag write goes through: tags are
+// trimmed and lowercased, empties and repeats dropped (keeping the first
+// occurrence's position), and at most MaxTags kept. Tags longer than MaxTagLen
+// characters or containing commas or braces, which the legacy array column can't
+// hold reliably, are dropped too. problems explains everything dropped other than
+// empties and repeats, keyed like "tags[3]" (or "tags" for the count), so API
+// callers can reject the request while event handlers just log it.
+func NormalizeTags(in []string) (tags []string, problems map[string]string) {
+	problems = map[string]string{}
+	seen := make(map[string]bool, len(in))
+	tags = make([]string, 0, len(in))
+	for i, raw := range in {
+		tag := strings.ToLower(strings.TrimSpace(raw))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		key := fmt.Sprintf("tags[%d]", i)
+		switch {
+		case strings.ContainsAny(tag, ",{}"):
+			problems[key] = "must not contain commas or braces"
+			continue
+		case len([]rune(tag)) > MaxTagLen:
+			problems[key] = fmt.Sprintf("at most %d characters", MaxTagLen)
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > MaxTags {
+		problems["tags"] = fmt.Sprintf("at most %d distinct tags", MaxTags)
+		tags = tags[:MaxTags]
+	}
+	if len(problems) == 0 {
+		problems = nil
+	}
+	return tags, problems
+}
+
+// Tag storage modes for the online migration from the legacy hand-rolled `tags`
+// column to the typed `tags_array` column.
+const (
+	// TagStorageLegacy reads and writes only the legacy column
+	TagStorageLegacy = "legacy"
+	// TagStorageDual writes both columns and reads the typed one, falling back to legacy
+	TagStorageDual = "dual"
+	// TagStorageNew reads and writes only the typed column
+	TagStorageNew = "new"
+)
+
+var tagStorageMode = TagStorageLegacy
+
+// SetTagStorageMode selects how tags are persisted; call once at startup
+func SetTagStorageMode(mode string) error {
+	switch mode {
+	case "":
+		tagStorageMode = TagStorageLegacy
+	case TagStorageLegacy, TagStorageDual, TagStorageNew:
+		tagStorageMode = mode
+	default:
+		return fmt.Errorf("invalid tag storage mode %q (want legacy|dual|new)", mode)
+	}
+	return nil
+}
+
+// TagStorageMode returns the active tag storage mode
+func TagStorageMode() string { return tagStorageMode }
+
+// TagsReadExpr is the SQL expression for a video's tags under the active mode
+func TagsReadExpr() string {
+	switch tagStorageMode {
+	case TagStorageDual:
+		return "COALESCE(tags_array, tags)"
+	case TagStorageNew:
+		return "tags_array"
+	default:
+		return "tags"
+	}
+}
+
+// writeTags fills the tag columns for the active mode before an insert or update
+func (v *Video) writeTags(tx *gorm.DB) {
+	switch tagStorageMode {
+	case TagStorageDual:
+		v.Tags = convertSliceToPostgresArray(v.TagsList)
+		v.TagsArray = TagArray(nonNilTags(v.TagsList))
+	case TagStorageNew:
+		v.TagsArray = TagArray(nonNilTags(v.TagsList))
+		// Stop writing the legacy column entirely; inserts fall back to its '{}' default
+		tx.Statement.Omits = append(tx.Statement.Omits, "tags")
+	default:
+		v.Tags = convertSliceToPostgresArray(v.TagsList)
+	}
+}
+
+// readTags fills TagsList from the columns for the active mode after a query
+func (v *Video) readTags() {
+	switch tagStorageMode {
+	case TagStorageDual:
+		if v.TagsArray != nil {
+			v.TagsList = []string(v.TagsArray)
+			return
+		}
+		v.TagsList = convertPostgresArrayToSlice(v.Tags)
+	case TagStorageNew:
+		v.TagsList = nonNilTags(v.TagsArray)
+	default:
+		v.TagsList = convertPostgresArrayToSlice(v.Tags)
+	}
+}
+
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
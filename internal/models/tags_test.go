@@ -0,0 +1,153 @@
+package models_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+var awkwardTags = []string{"a,b", `he said "hi"`, `back\slash`, "{braced}", "日本語", "café ☕", "", "NULL", "plain"}
+
+func TestTagArrayRoundTrip(t *testing.T) {
+	value, err := models.TagArray(awkwardTags).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []interface{}{value, []byte(value.(string))} {
+		var got models.TagArray
+		if err := got.Scan(src); err != nil {
+			t.Fatalf("Scan(%T %q): %v", src, value, err)
+		}
+		if !reflect.DeepEqual([]string(got), awkwardTags) {
+			t.Errorf("round trip = %q, want %q", got, awkwardTags)
+		}
+	}
+
+	var null models.TagArray = []string{"x"}
+	if err := null.Scan(nil); err != nil || null != nil {
+		t.Errorf("Scan(nil) = %q, %v", null, err)
+	}
+	if v, _ := models.TagArray(nil).Value(); v != nil {
+		t.Errorf("nil TagArray stored as %v, want NULL", v)
+	}
+	if err := null.Scan(42); err == nil {
+		t.Error("Scan(int) accepted")
+	}
+}
+
+func TestParseLegacyTags(t *testing.T) {
+	tests := []struct {
+		name, column string
+		want         []string
+	}{
+		{"empty", "{}", []string{}},
+		{"blank", "", []string{}},
+		{"old unquoted encoder", "{music,live}", []string{"music", "live"}},
+		{"canonical quoting from Postgres", `{music,"a,b","he said \"hi\"","back\\slash"}`, []string{"music", "a,b", `he said "hi"`, `back\slash`}},
+		{"unicode", `{日本語,"café ☕"}`, []string{"日本語", "café ☕"}},
+		{"NULL element skipped", `{a,NULL,"NULL"}`, []string{"a", "NULL"}},
+		{"unparseable falls back to a split", `{"open,b}`, []string{"open", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := models.ParseLegacyTags(tt.column); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLegacyTags(%q) = %q, want %q", tt.column, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestVideoTagsPersist stores awkward tags through each storage mode and checks
+// they read back, and serialize, exactly as written
+func TestVideoTagsPersist(t *testing.T) {
+	t.Cleanup(func() { models.SetTagStorageMode(models.TagStorageLegacy) })
+	for _, mode := range []string{models.TagStorageLegacy, models.TagStorageDual, models.TagStorageNew} {
+		t.Run(mode, func(t *testing.T) {
+			if err := models.SetTagStorageMode(mode); err != nil {
+				t.Fatal(err)
+			}
+			db := dbtest.Open(t)
+			video := models.Video{UploadID: "up", UserID: "u", Title: "t", TagsList: awkwardTags}
+			if err := db.Create(&video).Error; err != nil {
+				t.Fatal(err)
+			}
+			var got models.Video
+			db.First(&got, video.ID)
+			if !reflect.DeepEqual(got.TagsList, awkwardTags) {
+				t.Fatalf("tags read back as %q", got.TagsList)
+			}
+			body, _ := json.Marshal(got)
+			var out struct {
+				Tags []string `json:"tags"`
+			}
+			json.Unmarshal(body, &out)
+			if !reflect.DeepEqual(out.Tags, awkwardTags) {
+				t.Errorf("JSON tags = %q", out.Tags)
+			}
+		})
+	}
+}
+
+// TestDualModeReadsLegacyRows covers rows written before the typed column existed
+func TestDualModeReadsLegacyRows(t *testing.T) {
+	t.Cleanup(func() { models.SetTagStorageMode(models.TagStorageLegacy) })
+	db := dbtest.Open(t)
+	models.SetTagStorageMode(models.TagStorageLegacy)
+	old := models.Video{UploadID: "old", UserID: "u", Title: "t", TagsList: []string{"a,b", "日本語"}}
+	db.Create(&old)
+
+	models.SetTagStorageMode(models.TagStorageDual)
+	var got models.Video
+	db.First(&got, old.ID)
+	if got.TagsArray != nil || !reflect.DeepEqual(got.TagsList, []string{"a,b", "日本語"}) {
+		t.Errorf("legacy row read as %q (typed column %q)", got.TagsList, got.TagsArray)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	long := strings.Repeat("é", models.MaxTagLen)
+	many := make([]string, models.MaxTags+3)
+	for i := range many {
+		many[i] = fmt.Sprintf("T%d", i)
+	}
+	manyWant := make([]string, models.MaxTags)
+	for i := range manyWant {
+		manyWant[i] = fmt.Sprintf("t%d", i)
+	}
+	tests := []struct {
+		name     string
+		in       []string
+		want     []string
+		problems map[string]string
+	}{
+		{"nil", nil, []string{}, nil},
+		{"trimmed and lowercased", []string{"  Music ", "LIVE"}, []string{"music", "live"}, nil},
+		{"repeats keep the first position", []string{"b", "A", "B ", "a", "c"}, []string{"b", "a", "c"}, nil},
+		{"empties dropped", []string{"", "  ", "x", "\t"}, []string{"x"}, nil},
+		{"unicode lowercased", []string{"CAFÉ", "café"}, []string{"café"}, nil},
+		// Length counts characters, not bytes
+		{"longest allowed", []string{long}, []string{long}, nil},
+		{"too long", []string{"ok", long + "x"}, []string{"ok"}, map[string]string{"tags[1]": "at most 50 characters"}},
+		{"commas and braces", []string{"a,b", "ok", "{x", "y}"}, []string{"ok"}, map[string]string{
+			"tags[0]": "must not contain commas or braces",
+			"tags[2]": "must not contain commas or braces",
+			"tags[3]": "must not contain commas or braces",
+		}},
+		{"too many", many, manyWant, map[string]string{"tags": "at most 25 distinct tags"}},
+		// Repeats don't count towards the limit
+		{"repeats under the limit", append(append([]string{}, manyWant...), "T0", "t1", "T2"), manyWant, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, problems := models.NormalizeTags(tt.in)
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(problems, tt.problems) {
+				t.Errorf("NormalizeTags(%q) = %q, %v; want %q, %v", tt.in, got, problems, tt.want, tt.problems)
+			}
+		})
+	}
+}
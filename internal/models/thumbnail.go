@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// ThumbnailSource is where a thumbnail candidate came from
+type ThumbnailSource string
+
+const (
+	// ThumbnailFromTranscoder candidates arrive in transcoded events
+	ThumbnailFromTranscoder ThumbnailSource = "transcoder"
+	// ThumbnailFromUpload candidates were uploaded by the video's owner
+	ThumbnailFromUpload ThumbnailSource = "upload"
+)
+
+// ThumbnailInfo is one thumbnail candidate in a transcoded event
+type ThumbnailInfo struct {
+	URL string `json:"url"`
+	// Path is the candidate's blob name, when it is in the catalog's storage
+	Path        string  `json:"path,omitempty"`
+	Width       int     `json:"width,omitempty"`
+	Height      int     `json:"height,omitempty"`
+	TimeSeconds float64 `json:"timeSeconds,omitempty"`
+}
+
+// VideoThumbnail is a thumbnail the owner can pick for a video. Candidates are only
+// ever added, so a selected one stays valid, and are removed with the video when it
+// is purged.
+type VideoThumbnail struct {
+	ID       uint            `json:"id" gorm:"primarykey"`
+	VideoID  uint            `json:"-" gorm:"not null;uniqueIndex:idx_video_thumbnails_url,priority:1"`
+	Source   ThumbnailSource `json:"source" gorm:"size:16;not null"`
+	URL      string          `json:"url" gorm:"type:text;not null;uniqueIndex:idx_video_thumbnails_url,priority:2"`
+	BlobPath string          `json:"-" gorm:"type:text"`
+	Width    int             `json:"width,omitempty"`
+	Height   int             `json:"height,omitempty"`
+	// TimeSeconds is where in the video a transcoder candidate was taken
+	TimeSeconds float64   `json:"time_seconds,omitempty"`
+	Selected    bool      `json:"selected" gorm:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ToVideoThumbnails returns the usable candidates of an event payload, skipping
+// any without a URL
+func ToVideoThumbnails(in []ThumbnailInfo) []VideoThumbnail {
+	var out []VideoThumbnail
+	for _, t := range in {
+		if t.URL == "" {
+			continue
+		}
+		out = append(out, VideoThumbnail{
+			Source:      ThumbnailFromTranscoder,
+			URL:         t.URL,
+			BlobPath:    t.Path,
+			Width:       t.Width,
+			Height:      t.Height,
+			TimeSeconds: t.TimeSeconds,
+		})
+	}
+	return out
+}
+
+// ThumbnailSelectRequest picks one of a video's thumbnail candidates
+type ThumbnailSelectRequest struct {
+	ThumbnailID uint `json:"thumbnail_id" binding:"required"`
+}
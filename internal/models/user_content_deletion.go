@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// DeletedUserID replaces the author of a deleted account's comments, and
+// DeletedContent their text, so replies and threads stay intact
+const (
+	DeletedUserID  = "deleted-user"
+	DeletedContent = "[deleted]"
+)
+
+// User content deletion triggers
+const (
+	ContentDeletionTriggerAPI   = "api"
+	ContentDeletionTriggerEvent = "event"
+)
+
+// UserContentDeletion is a background job removing a deleted account's catalog
+// footprint: every video is deleted with no restore window and handed to the video
+// deletion jobs, and every comment is anonymized. It uses the Deletion* states.
+// Each step only touches rows not yet handled, so a failed or interrupted job
+// resumes where it stopped.
+type UserContentDeletion struct {
+	ID      uint   `json:"id" gorm:"primarykey"`
+	UserID  string `json:"user_id" gorm:"size:191;not null;index"`
+	ActorID string `json:"actor_id" gorm:"size:191"`
+	Trigger string `json:"trigger" gorm:"size:16;not null"`
+	Status  string `json:"status" gorm:"size:16;not null;index"`
+	// VideosTotal and CommentsTotal are what the user had when the job was created
+	VideosTotal   int64 `json:"videos_total" gorm:"not null;default:0"`
+	CommentsTotal int64 `json:"comments_total" gorm:"not null;default:0"`
+	// VideosDeleted and CommentsAnonymized are totals across attempts
+	VideosDeleted      int64      `json:"videos_deleted" gorm:"not null;default:0"`
+	CommentsAnonymized int64      `json:"comments_anonymized" gorm:"not null;default:0"`
+	Attempts           int        `json:"attempts" gorm:"not null;default:0"`
+	Error              string     `json:"error,omitempty" gorm:"type:text"`
+	StartedAt          *time.Time `json:"started_at,omitempty"`
+	FinishedAt         *time.Time `json:"finished_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+
+	// VideoPurges counts the user's video deletion jobs by status, filled in when a
+	// single job is read. The files are gone once they are all completed.
+	VideoPurges map[string]int64 `json:"video_purges,omitempty" gorm:"-"`
+}
+
+// UserDeletedEvent says an account was deleted upstream
+type UserDeletedEvent struct {
+	UserID string `json:"user_id"`
+}
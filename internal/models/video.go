@@ -1,26 +1,43 @@
 package models
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"gorm.io/gorm"
 )
 
 // Video represents a video in the catalog
 type Video struct {
-	ID          uint        `json:"id" gorm:"primarykey"`
-	UploadID    string      `json:"upload_id" gorm:"uniqueIndex;not null"`
-	UserID      string      `json:"user_id" gorm:"index;not null"`
-	Username    string      `json:"username"`
-	Title       string      `json:"title" gorm:"not null"`
-	Description string      `json:"description"`
-	Tags        string      `json:"-" gorm:"type:text[]"`
-	TagsList    []string    `json:"tags" gorm:"-"`
-	IsPrivate   bool        `json:"is_private" gorm:"default:false"`
-	Category    string      `json:"category"`
-	Status      VideoStatus `json:"status" gorm:"default:'uploaded'"`
+	ID          uint     `json:"id" gorm:"primarykey;index:idx_videos_created_id,priority:2"`
+	UploadID    string   `json:"upload_id" gorm:"not null;index:idx_videos_upload_id_all;uniqueIndex:idx_videos_upload_id_active,where:deleted_at IS NULL"`
+	UserID      string   `json:"user_id" gorm:"index;not null"`
+	Username    string   `json:"username"`
+	Title       string   `json:"title" gorm:"not null"`
+	Description string   `json:"description"`
+	Tags        string   `json:"-" gorm:"type:text[];default:'{}'"`
+	TagsArray   TagArray `json:"-" gorm:"column:tags_array;type:text[]"`
+	TagsList    []string `json:"tags" gorm:"-"`
+	// Visibility decides who can see the video and whether it is listed; the JSON
+	// also carries the derived is_private for older clients
+	Visibility Visibility `json:"visibility" gorm:"size:16;not null;default:'public';index"`
+	// ShareToken lets anyone holding it open an unlisted or private video; empty
+	// for public videos. Catalog events leave it out.
+	ShareToken string      `json:"share_token,omitempty" gorm:"size:64"`
+	Category   string      `json:"category"`
+	Status     VideoStatus `json:"status" gorm:"default:'uploaded'"`
+	// FailureReason is the transcoder's error for a failed video; cleared once a transcode succeeds
+	FailureReason string `json:"failure_reason,omitempty" gorm:"type:text"`
+	// ModerationStatus is set by admins resolving reports. A taken-down video is
+	// unlisted and unavailable to everyone but its owner, whatever its visibility.
+	ModerationStatus ModerationStatus `json:"moderation_status" gorm:"size:20;not null;default:'active';index"`
+
+	// NotificationsMuted stops comment notifications to the owner for this video
+	NotificationsMuted bool `json:"notifications_muted" gorm:"not null;default:false"`
 
 	// File information
 	OriginalFilename string `json:"original_filename"`
@@ -28,6 +45,29 @@ type Video struct {
 	HLSMasterURL     string `json:"hls_master_url"`
 	ThumbnailURL     string `json:"thumbnail_url"`
 
+	// ThumbnailID is the candidate the owner picked as ThumbnailURL, and
+	// ThumbnailPath its blob; while unset, transcoded events set the thumbnail.
+	// Thumbnails are the candidates, loaded only by the thumbnail endpoints.
+	ThumbnailID   *uint            `json:"thumbnail_id,omitempty"`
+	ThumbnailPath string           `json:"-" gorm:"type:text"`
+	Thumbnails    []VideoThumbnail `json:"-" gorm:"foreignKey:VideoID;constraint:OnDelete:CASCADE"`
+
+	// Previews are hover-scrub assets from the transcoder. PreviewsDisabled hides
+	// them from responses without discarding them, for owners who consider them spoilers.
+	Previews         *VideoPreviews `json:"previews,omitempty" gorm:"type:jsonb;serializer:json"`
+	PreviewsDisabled bool           `json:"previews_disabled" gorm:"not null;default:false"`
+
+	// CommentsEnabled off stops new comments; the existing thread stays readable
+	CommentsEnabled bool `json:"comments_enabled" gorm:"not null;default:true"`
+
+	// Chapters are sorted by start time. Null means none were ever set; an empty
+	// array means the creator removed them.
+	Chapters []Chapter `json:"chapters" gorm:"type:jsonb;serializer:json"`
+
+	// Renditions are the HLS variants behind the master playlist. Only single-video
+	// reads load them, so they are absent from list responses.
+	Renditions []VideoRendition `json:"renditions,omitempty" gorm:"foreignKey:VideoID;constraint:OnDelete:CASCADE"`
+
 	// Video metadata
 	Duration     float64 `json:"duration"`
 	FileSize     int64   `json:"file_size"`
@@ -39,27 +79,89 @@ type Video struct {
 	AudioBitrate int     `json:"audio_bitrate"`
 	FrameRate    float64 `json:"frame_rate"`
 
+	// Denormalized counters, maintained transactionally by the comment and reaction
+	// services and periodically reconciled by the counter repair job. view_count is
+	// only ever incremented; its dedup rows are pruned, so it has nothing to be
+	// repaired from.
+	CommentCount  int64 `json:"comment_count" gorm:"not null;default:0"`
+	ViewCount     int64 `json:"view_count" gorm:"not null;default:0"`
+	LikeCount     int64 `json:"like_count" gorm:"not null;default:0"`
+	DislikeCount  int64 `json:"dislike_count" gorm:"not null;default:0"`
+	CountersDirty bool  `json:"-" gorm:"not null;default:false;index"`
+
+	// MyReaction is the caller's own reaction (1, -1, or 0 for none), set only for
+	// requests with a user
+	MyReaction *int `json:"my_reaction,omitempty" gorm:"-"`
+
+	// Version goes up by one on every save of the video: edits, broker events, admin
+	// status changes, share token rotation, deletes and restores. Counters and the
+	// notification mute leave it alone. It is the video's ETag, and PUT /videos/:id
+	// with If-Match only applies while it still matches.
+	Version int64 `json:"version" gorm:"not null;default:1"`
+
 	// Timestamps
-	CreatedAt time.Time      `json:"created_at"`
+	CreatedAt time.Time      `json:"created_at" gorm:"index:idx_videos_created_id,priority:1"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	// PurgeAfter is set on a soft-deleted video: until then the owner can restore
+	// it, afterwards the purge job removes its files and row for good
+	PurgeAfter *time.Time `json:"purge_after,omitempty" gorm:"index"`
 }
 
 // Comment represents a comment on a video
 type Comment struct {
 	ID        uint           `json:"id" gorm:"primarykey"`
 	VideoID   uint           `json:"video_id" gorm:"index;not null"`
+	ParentID  *uint          `json:"parent_id,omitempty" gorm:"index"` // replied-to comment; nil at top level
+	Depth     int            `json:"depth" gorm:"not null;default:0"`  // top-level comments are 0
 	UserID    string         `json:"user_id" gorm:"index;not null"`
 	Username  string         `json:"author_name" gorm:"size:120"`
 	Content   string         `json:"content" gorm:"type:text;not null"`
+	Status    string         `json:"status" gorm:"size:20;not null;default:'visible';index"`
+	Pinned    bool           `json:"pinned" gorm:"not null;default:false"` // set by the video owner; sorts first
+	LikeCount int64          `json:"like_count" gorm:"not null;default:0"` // maintained with comment_reactions
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
+	EditedAt  *time.Time     `json:"edited_at,omitempty"` // last content edit by the author
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	// ReplyCount is how many replies are shown under the comment; set by listings
+	ReplyCount int64 `json:"reply_count" gorm:"-"`
+	// Liked says whether the caller likes the comment; set by listings only for
+	// requests with a user
+	Liked *bool `json:"liked,omitempty" gorm:"-"`
 }
 
+// Comment visibility states
+const (
+	CommentVisible = "visible"
+	// CommentPending comments are hidden until moderation clears them
+	CommentPending = "pending"
+	// CommentDeleted comments were deleted while they had replies. They stay in
+	// threads as tombstones so the replies still read in context.
+	CommentDeleted = "deleted"
+)
+
+// CommentTombstone replaces the content of a deleted comment that has replies
+const CommentTombstone = "[deleted]"
+
+// MaxCommentLen is the longest comment content accepted, in characters
+const MaxCommentLen = 2000
+
 type CommentCreateRequest struct {
 	Content    string `json:"content" binding:"required,min=1,max=2000"`
 	AuthorName string `json:"author_name" binding:"omitempty,max=120"`
+	// ParentID makes the comment a reply to another comment on the same video
+	ParentID *uint `json:"parent_id" binding:"omitempty,min=1"`
+}
+
+// CommentPinRequest pins or unpins a comment
+type CommentPinRequest struct {
+	Pinned *bool `json:"pinned" binding:"required"`
+}
+
+// CommentUpdateRequest edits a comment's content; the same length rules apply
+type CommentUpdateRequest struct {
+	Content string `json:"content" binding:"required,min=1,max=2000"`
 }
 
 // VideoStatus represents the processing status of a video
@@ -72,25 +174,177 @@ const (
 	StatusFailed     VideoStatus = "failed"
 )
 
+// Visibility controls who can see a video and where it appears
+type Visibility string
+
+const (
+	// VisibilityPublic videos are listed, searchable and open to everyone
+	VisibilityPublic Visibility = "public"
+	// VisibilityUnlisted videos open for anyone with the link (the share token) but
+	// are never listed or searched
+	VisibilityUnlisted Visibility = "unlisted"
+	// VisibilityPrivate videos open only for the owner or with the share token
+	VisibilityPrivate Visibility = "private"
+)
+
+// Visibilities lists every valid visibility
+var Visibilities = []Visibility{VisibilityPublic, VisibilityUnlisted, VisibilityPrivate}
+
+// Valid reports whether v is a known visibility
+func (v Visibility) Valid() bool {
+	for _, known := range Visibilities {
+		if v == known {
+			return true
+		}
+	}
+	return false
+}
+
+// VisibilityFromPrivate maps the legacy is_private flag onto a visibility
+func VisibilityFromPrivate(private bool) Visibility {
+	if private {
+		return VisibilityPrivate
+	}
+	return VisibilityPublic
+}
+
+// IsPublic reports whether the video is listed and open to everyone
+func (v *Video) IsPublic() bool {
+	return v.Visibility == "" || v.Visibility == VisibilityPublic
+}
+
+// IsTakenDown reports whether moderation has taken the video down
+func (v *Video) IsTakenDown() bool {
+	return v.ModerationStatus == ModerationStatusTakenDown
+}
+
+// IsListed reports whether the video may appear in public listings and search:
+// public and not taken down
+func (v *Video) IsListed() bool {
+	return v.IsPublic() && !v.IsTakenDown()
+}
+
+// ShareTokenMatches reports whether token opens this video
+func (v *Video) ShareTokenMatches(token string) bool {
+	return v.ShareToken != "" && subtle.ConstantTimeCompare([]byte(v.ShareToken), []byte(token)) == 1
+}
+
+// IsPrivate is the legacy flag derived from Visibility: true unless the video is
+// public, since neither unlisted nor private videos may be listed
+func (v *Video) IsPrivate() bool {
+	return !v.IsPublic()
+}
+
+// VideoStatuses lists every valid status
+var VideoStatuses = []VideoStatus{StatusUploaded, StatusProcessing, StatusReady, StatusFailed}
+
+// Valid reports whether s is a known status
+func (s VideoStatus) Valid() bool {
+	for _, known := range VideoStatuses {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// videoStatusTransitions lists the statuses each status may move to. Ready is
+// terminal; failed can go back to processing when the transcode is retried.
+var videoStatusTransitions = map[VideoStatus][]VideoStatus{
+	StatusUploaded:   {StatusProcessing},
+	StatusProcessing: {StatusReady, StatusFailed},
+	StatusFailed:     {StatusProcessing},
+}
+
+// NextStatuses lists the statuses s may move to, not counting s itself
+func (s VideoStatus) NextStatuses() []VideoStatus {
+	return append([]VideoStatus{}, videoStatusTransitions[s]...)
+}
+
+// CanTransitionTo reports whether a video in status s may move to next. Staying
+// in the same status is always allowed, so redelivered events stay idempotent.
+func (s VideoStatus) CanTransitionTo(next VideoStatus) bool {
+	if s == next {
+		return s.Valid()
+	}
+	for _, allowed := range videoStatusTransitions[s] {
+		if next == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Video metadata limits, in characters. The binding tags below repeat them;
+// ValidateVideoUpdate applies them to updates, whose pointer fields the tags
+// can't require.
+const (
+	MaxTitleLen       = 200
+	MaxDescriptionLen = 10000
+	MaxCategoryLen    = 50
+)
+
 // VideoCreateRequest represents the request payload for creating a video
 // Now requires an upload_id so that catalog rows map to upload/transcode events
 // Clients should first upload via UploadService to obtain this ID.
 type VideoCreateRequest struct {
 	UploadID    string   `json:"upload_id" binding:"required"`
-	Title       string   `json:"title" binding:"required"`
-	Description string   `json:"description"`
-	Tags        []string `json:"tags"`
-	IsPrivate   bool     `json:"is_private"`
-	Category    string   `json:"category"`
+	Title       string   `json:"title" binding:"required,max=200"`
+	Description string   `json:"description" binding:"max=10000"`
+	Tags        []string `json:"tags" binding:"max=25,dive,max=50"`
+	// Visibility wins over the legacy IsPrivate when both are set
+	Visibility Visibility `json:"visibility"`
+	IsPrivate  bool       `json:"is_private"`
+	Category   string     `json:"category" binding:"max=50"`
 }
 
 // VideoUpdateRequest represents the request payload for updating a video
 type VideoUpdateRequest struct {
-	Title       *string  `json:"title,omitempty"`
-	Description *string  `json:"description,omitempty"`
-	Tags        []string `json:"tags,omitempty"`
-	IsPrivate   *bool    `json:"is_private,omitempty"`
-	Category    *string  `json:"category,omitempty"`
+	Title       *string  `json:"title,omitempty" binding:"omitempty,max=200"`
+	Description *string  `json:"description,omitempty" binding:"omitempty,max=10000"`
+	Tags        []string `json:"tags,omitempty" binding:"omitempty,max=25,dive,max=50"`
+	// Visibility wins over the legacy IsPrivate when both are set
+	Visibility *Visibility `json:"visibility,omitempty"`
+	IsPrivate  *bool       `json:"is_private,omitempty"`
+	Category   *string     `json:"category,omitempty" binding:"omitempty,max=50"`
+	// PreviewsDisabled hides hover-scrub previews for this video
+	PreviewsDisabled *bool `json:"previews_disabled,omitempty"`
+	// CommentsEnabled turns new comments on or off for this video
+	CommentsEnabled *bool `json:"comments_enabled,omitempty"`
+	// Chapters replaces the video's chapters; an empty array removes them
+	Chapters *[]Chapter `json:"chapters,omitempty"`
+}
+
+// ValidateVideoUpdate checks the fields an update sets against the metadata
+// limits, returning a problem per field or nil. A title can't be blanked.
+func ValidateVideoUpdate(req *VideoUpdateRequest) map[string]string {
+	fields := map[string]string{}
+	if req.Title != nil {
+		switch n := utf8.RuneCountInString(*req.Title); {
+		case strings.TrimSpace(*req.Title) == "":
+			fields["title"] = "must not be empty"
+		case n > MaxTitleLen:
+			fields["title"] = fmt.Sprintf("must be at most %d characters", MaxTitleLen)
+		}
+	}
+	if req.Description != nil && utf8.RuneCountInString(*req.Description) > MaxDescriptionLen {
+		fields["description"] = fmt.Sprintf("must be at most %d characters", MaxDescriptionLen)
+	}
+	if req.Category != nil && utf8.RuneCountInString(*req.Category) > MaxCategoryLen {
+		fields["category"] = fmt.Sprintf("must be at most %d characters", MaxCategoryLen)
+	}
+	if len(req.Tags) > MaxTags {
+		fields["tags"] = fmt.Sprintf("must have at most %d items", MaxTags)
+	}
+	for i, tag := range req.Tags {
+		if utf8.RuneCountInString(tag) > MaxTagLen {
+			fields[fmt.Sprintf("tags[%d]", i)] = fmt.Sprintf("must be at most %d characters", MaxTagLen)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
 }
 
 // VideoListResponse represents the response for listing videos
@@ -100,6 +354,7 @@ type VideoListResponse struct {
 	Page       int     `json:"page"`
 	PerPage    int     `json:"per_page"`
 	TotalPages int     `json:"total_pages"`
+	NextCursor string  `json:"next_cursor,omitempty"`
 }
 
 // TranscodedEvent represents the event received when a video is transcoded
@@ -118,6 +373,12 @@ type TranscodedEvent struct {
 	ThumbnailURL     string         `json:"thumbnailUrl,omitempty"`
 	Ready            bool           `json:"ready"`
 	Metadata         *VideoMetadata `json:"metadata,omitempty"`
+	// Previews is optional; absent for transcoders that don't generate them
+	Previews *PreviewInfo `json:"previews,omitempty"`
+	// Thumbnails are candidates the owner can pick from; ThumbnailURL stays the default
+	Thumbnails []ThumbnailInfo `json:"thumbnails,omitempty"`
+	// OccurredAt is when the transcode finished, if the producer sets it
+	OccurredAt *time.Time `json:"occurredAt,omitempty"`
 }
 
 // UploadedEvent represents the initial upload event published by UploadService
@@ -134,11 +395,24 @@ type UploadedEvent struct {
 	RawVideoPath  string   `json:"rawVideoPath"`
 	ContainerName string   `json:"containerName"`
 	BlobURL       string   `json:"blobUrl"`
+	// OccurredAt is when the upload completed, if the producer sets it
+	OccurredAt *time.Time `json:"occurredAt,omitempty"`
+}
+
+// TranscodeFailedEvent is published when a transcode job gives up on an upload
+type TranscodeFailedEvent struct {
+	UploadID     string `json:"uploadId"`
+	UserID       string `json:"userId"`
+	ErrorMessage string `json:"errorMessage"`
+	// FailedAt is when the transcode failed, if the producer sets it
+	FailedAt *time.Time `json:"failedAt,omitempty"`
 }
 
 // HLSInfo contains HLS-related information
 type HLSInfo struct {
 	MasterURL string `json:"masterUrl"`
+	// Renditions is optional; older transcoders only send the master playlist
+	Renditions []RenditionInfo `json:"renditions,omitempty"`
 }
 
 // VideoMetadata contains video file metadata
@@ -152,6 +426,8 @@ type VideoMetadata struct {
 	AudioCodec   string  `json:"audioCodec"`
 	AudioBitrate int     `json:"audioBitrate"`
 	FrameRate    float64 `json:"frameRate"`
+	// Chapters is optional; set when the transcoder detects chapter marks
+	Chapters []ChapterInfo `json:"chapters,omitempty"`
 }
 
 // UnmarshalJSON implements custom unmarshaling for UploadedEvent to handle tags
@@ -272,21 +548,21 @@ func (e *UploadedEvent) SanitizeTags() {
 	e.Tags = sanitizedTags
 }
 
-// BeforeCreate hook to convert TagsList to Tags before database insert
+// BeforeCreate hook to convert TagsList to the tag columns before database insert
 func (v *Video) BeforeCreate(tx *gorm.DB) error {
-	v.Tags = convertSliceToPostgresArray(v.TagsList)
+	v.writeTags(tx)
 	return nil
 }
 
-// BeforeUpdate hook to convert TagsList to Tags before database update
+// BeforeUpdate hook to convert TagsList to the tag columns before database update
 func (v *Video) BeforeUpdate(tx *gorm.DB) error {
-	v.Tags = convertSliceToPostgresArray(v.TagsList)
+	v.writeTags(tx)
 	return nil
 }
 
-// AfterFind hook to convert Tags to TagsList after database query
+// AfterFind hook to convert the tag columns to TagsList after database query
 func (v *Video) AfterFind(tx *gorm.DB) error {
-	v.TagsList = convertPostgresArrayToSlice(v.Tags)
+	v.readTags()
 	return nil
 }
 
@@ -294,54 +570,56 @@ func (v *Video) AfterFind(tx *gorm.DB) error {
 func (v Video) MarshalJSON() ([]byte, error) {
 	type Alias Video
 	aux := &struct {
-		Tags []string `json:"tags"`
+		Tags      []string `json:"tags"`
+		IsPrivate bool     `json:"is_private"`
 		*Alias
 	}{
-		Tags:  v.TagsList,
-		Alias: (*Alias)(&v),
+		Tags:      v.TagsList,
+		IsPrivate: v.IsPrivate(),
+		Alias:     (*Alias)(&v),
 	}
 	// Remove the TagsList field from JSON output by setting it to nil in the alias
 	aux.Alias.TagsList = nil
+	// v is a copy, so dropping disabled previews doesn't touch the caller's video
+	if v.PreviewsDisabled {
+		aux.Alias.Previews = nil
+	}
 	return json.Marshal(aux)
 }
 
-// Helper function to convert Go slice to PostgreSQL array string
+// ParseLegacyTags decodes the legacy tags column representation
+func ParseLegacyTags(pgArray string) []string {
+	return convertPostgresArrayToSlice(pgArray)
+}
+
+// convertSliceToPostgresArray encodes tags as a Postgres array literal, quoting
+// every element so commas, braces, quotes and backslashes survive
 func convertSliceToPostgresArray(slice []string) string {
 	if len(slice) == 0 {
 		return "{}"
 	}
-
-	// Escape quotes and build array string
-	var escaped []string
-	for _, item := range slice {
-		// Escape quotes by doubling them
-		escaped = append(escaped, `"`+strings.ReplaceAll(item, `"`, `""`)+`"`)
-	}
-
-	return "{" + strings.Join(escaped, ",") + "}"
+	v, _ := TagArray(slice).Value()
+	return v.(string)
 }
 
-// Helper function to convert PostgreSQL array string to Go slice
+// convertPostgresArrayToSlice decodes the legacy tags column. Postgres always
+// returns arrays in its canonical quoted form, so rows written by the old encoder
+// parse the same way; anything unparseable falls back to the old naive split.
 func convertPostgresArrayToSlice(pgArray string) []string {
 	if pgArray == "" || pgArray == "{}" {
 		return []string{}
 	}
+	if tags, err := parsePostgresArray(pgArray); err == nil {
+		return tags
+	}
 
-	// Remove braces and split by comma
 	trimmed := strings.Trim(pgArray, "{}")
 	if trimmed == "" {
 		return []string{}
 	}
-
-	parts := strings.Split(trimmed, ",")
 	var result []string
-
-	for _, part := range parts {
-		// Remove quotes and unescape
-		cleaned := strings.Trim(part, `"`)
-		cleaned = strings.ReplaceAll(cleaned, `""`, `"`)
-		result = append(result, cleaned)
+	for _, part := range strings.Split(trimmed, ",") {
+		result = append(result, strings.ReplaceAll(strings.Trim(part, `"`), `""`, `"`))
 	}
-
 	return result
 }
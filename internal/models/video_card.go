@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// VideoCard is the compact projection of a video that list screens render as a
+// card, served by the list endpoints with ?view=compact
+type VideoCard struct {
+	ID           uint      `json:"id"`
+	Title        string    `json:"title"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Duration     float64   `json:"duration"`
+	Username     string    `json:"username"`
+	ViewCount    int64     `json:"view_count"`
+	LikeCount    int64     `json:"like_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// VideoCardColumns are the videos columns a VideoCard is read from
+var VideoCardColumns = []string{"id", "title", "thumbnail_url", "duration", "username", "view_count", "like_count", "created_at"}
+
+// VideoCardListResponse is VideoListResponse with cards in place of full videos
+type VideoCardListResponse struct {
+	Videos     []VideoCard `json:"videos"`
+	Total      int64       `json:"total"`
+	Page       int         `json:"page"`
+	PerPage    int         `json:"per_page"`
+	TotalPages int         `json:"total_pages"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
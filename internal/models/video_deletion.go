@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Video deletion job states
+const (
+	DeletionPending   = "pending"
+	DeletionRunning   = "running"
+	DeletionCompleted = "completed"
+	DeletionFailed    = "failed"
+	// DeletionCancelled jobs were for videos restored before their purge
+	DeletionCancelled = "cancelled"
+)
+
+// VideoDeletion is a background job purging a deleted video's files and row. It is
+// created with the soft delete and runs once RunAfter, the end of the restore window,
+// has passed. A failed job keeps the prefixes it didn't finish and its per-path
+// outcomes in deletion_items, and can be retried.
+type VideoDeletion struct {
+	ID       uint   `json:"id" gorm:"primarykey"`
+	VideoID  uint   `json:"video_id" gorm:"not null;index"`
+	UploadID string `json:"upload_id" gorm:"size:191"`
+	// UserID is the video's owner, who may poll and retry the job
+	UserID   string    `json:"user_id" gorm:"size:191;not null;index"`
+	ActorID  string    `json:"actor_id" gorm:"size:191"`
+	Status   string    `json:"status" gorm:"size:16;not null;index:idx_video_deletions_due,priority:1"`
+	RunAfter time.Time `json:"run_after" gorm:"not null;index:idx_video_deletions_due,priority:2"`
+	Attempts int       `json:"attempts" gorm:"not null;default:0"`
+	// FilesDeleted and BlobsDeleted are totals across attempts; while a job runs,
+	// BlobsDeleted is read from its cleanup checkpoints
+	FilesDeleted      int        `json:"files_deleted" gorm:"not null;default:0"`
+	BlobsDeleted      int64      `json:"blobs_deleted" gorm:"not null;default:0"`
+	RemainingPrefixes []string   `json:"remaining_prefixes,omitempty" gorm:"type:jsonb;serializer:json"`
+	Error             string     `json:"error,omitempty" gorm:"type:text"`
+	StartedAt         *time.Time `json:"started_at,omitempty"`
+	FinishedAt        *time.Time `json:"finished_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// Items is the per-path outcome, filled in when a single job is read
+	Items []DeletionItem `json:"items,omitempty" gorm:"-"`
+}
+
+// Deletion item kinds
+const (
+	DeletionItemFile   = "file"
+	DeletionItemPrefix = "prefix"
+)
+
+// Deletion item states
+const (
+	DeletionItemPending = "pending"
+	DeletionItemDeleted = "deleted"
+	DeletionItemFailed  = "failed"
+)
+
+// DeletionItem is one storage path a deletion job removes: a single blob or every
+// blob under a prefix. An attempt skips items already deleted, so a retry only
+// re-runs the ones that failed. The video row is removed only once every item is
+// deleted.
+type DeletionItem struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	DeletionID   uint      `json:"deletion_id" gorm:"not null;uniqueIndex:idx_deletion_items_path,priority:1"`
+	VideoID      uint      `json:"video_id" gorm:"not null;index"`
+	Kind         string    `json:"kind" gorm:"size:16;not null"`
+	Path         string    `json:"path" gorm:"size:1024;not null;uniqueIndex:idx_deletion_items_path,priority:2"`
+	Status       string    `json:"status" gorm:"size:16;not null"`
+	Attempts     int       `json:"attempts" gorm:"not null;default:0"`
+	BlobsDeleted int64     `json:"blobs_deleted" gorm:"not null;default:0"`
+	Error        string    `json:"error,omitempty" gorm:"type:text"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
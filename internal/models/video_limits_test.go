@@ -0,0 +1,53 @@
+package models_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+func TestValidateVideoUpdate(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tags := func(n, length int) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = strings.Repeat("t", length)
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		req  models.VideoUpdateRequest
+		want map[string]string
+	}{
+		{"nothing set", models.VideoUpdateRequest{}, nil},
+		{"at every limit", models.VideoUpdateRequest{
+			Title:       str(strings.Repeat("x", models.MaxTitleLen)),
+			Description: str(strings.Repeat("x", models.MaxDescriptionLen)),
+			Category:    str(strings.Repeat("x", models.MaxCategoryLen)),
+			Tags:        tags(models.MaxTags, models.MaxTagLen),
+		}, nil},
+		// Limits count characters, not bytes
+		{"multibyte title at the limit", models.VideoUpdateRequest{Title: str(strings.Repeat("é", models.MaxTitleLen))}, nil},
+		{"title one over", models.VideoUpdateRequest{Title: str(strings.Repeat("é", models.MaxTitleLen+1))},
+			map[string]string{"title": "must be at most 200 characters"}},
+		{"blank title", models.VideoUpdateRequest{Title: str("  ")}, map[string]string{"title": "must not be empty"}},
+		{"empty description", models.VideoUpdateRequest{Description: str("")}, nil},
+		{"description one over", models.VideoUpdateRequest{Description: str(strings.Repeat("x", models.MaxDescriptionLen+1))},
+			map[string]string{"description": "must be at most 10000 characters"}},
+		{"category one over", models.VideoUpdateRequest{Category: str(strings.Repeat("x", models.MaxCategoryLen+1))},
+			map[string]string{"category": "must be at most 50 characters"}},
+		{"one tag too many", models.VideoUpdateRequest{Tags: tags(models.MaxTags+1, 3)}, map[string]string{"tags": "must have at most 25 items"}},
+		{"tag one over", models.VideoUpdateRequest{Tags: []string{"ok", strings.Repeat("t", models.MaxTagLen+1)}},
+			map[string]string{"tags[1]": "must be at most 50 characters"}},
+		{"several at once", models.VideoUpdateRequest{Title: str(""), Category: str(strings.Repeat("x", 51))},
+			map[string]string{"title": "must not be empty", "category": "must be at most 50 characters"}},
+	}
+	for _, tt := range tests {
+		if got := models.ValidateVideoUpdate(&tt.req); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// MergePatchContentType is the media type of a JSON Merge Patch (RFC 7386)
+const MergePatchContentType = "application/merge-patch+json"
+
+// ParseVideoMergePatch turns a JSON Merge Patch of a video into the equivalent
+// update request. Absent members are left alone and arrays replace the current
+// value. null clears a field: description and category become empty, tags and
+// chapters an empty list, previews_disabled false. title, visibility and
+// is_private always have a value, so null is refused for them. Problems are
+// returned per member, or nil if there are none.
+func ParseVideoMergePatch(body []byte) (*VideoUpdateRequest, map[string]string) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return nil, map[string]string{"body": "must be a JSON object"}
+	}
+
+	req := &VideoUpdateRequest{}
+	fields := map[string]string{}
+	for name, raw := range members {
+		null := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+		var err error
+		switch name {
+		case "title":
+			if null {
+				fields[name] = "cannot be cleared"
+				continue
+			}
+			err = json.Unmarshal(raw, &req.Title)
+		case "description":
+			req.Description = new(string)
+			err = json.Unmarshal(raw, req.Description)
+		case "category":
+			req.Category = new(string)
+			err = json.Unmarshal(raw, req.Category)
+		case "tags":
+			err = json.Unmarshal(raw, &req.Tags)
+			if req.Tags == nil {
+				req.Tags = []string{}
+			}
+		case "chapters":
+			var chapters []Chapter
+			err = json.Unmarshal(raw, &chapters)
+			if chapters == nil {
+				chapters = []Chapter{}
+			}
+			req.Chapters = &chapters
+		case "previews_disabled":
+			req.PreviewsDisabled = new(bool)
+			err = json.Unmarshal(raw, req.PreviewsDisabled)
+		case "comments_enabled":
+			// Clearing restores the default of comments on
+			req.CommentsEnabled = new(bool)
+			*req.CommentsEnabled = true
+			if !null {
+				err = json.Unmarshal(raw, req.CommentsEnabled)
+			}
+		case "visibility":
+			if null {
+				fields[name] = "cannot be cleared"
+				continue
+			}
+			err = json.Unmarshal(raw, &req.Visibility)
+		case "is_private":
+			if null {
+				fields[name] = "cannot be cleared"
+				continue
+			}
+			err = json.Unmarshal(raw, &req.IsPrivate)
+		default:
+			fields[name] = "unknown or read-only field"
+		}
+		if err != nil {
+			fields[name] = "invalid value: " + err.Error()
+		}
+	}
+	if len(fields) > 0 {
+		return nil, fields
+	}
+	return req, nil
+}
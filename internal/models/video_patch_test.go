@@ -0,0 +1,84 @@
+package models_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+func TestParseVideoMergePatch(t *testing.T) {
+	str := func(s string) *string { return &s }
+	boolean := func(b bool) *bool { return &b }
+	visibility := func(v models.Visibility) *models.Visibility { return &v }
+	chapters := func(c ...models.Chapter) *[]models.Chapter {
+		if c == nil {
+			c = []models.Chapter{}
+		}
+		return &c
+	}
+	tests := []struct {
+		name string
+		body string
+		want models.VideoUpdateRequest
+	}{
+		{"empty patch", `{}`, models.VideoUpdateRequest{}},
+		{"set title", `{"title":"New"}`, models.VideoUpdateRequest{Title: str("New")}},
+		{"clear description", `{"description":null}`, models.VideoUpdateRequest{Description: str("")}},
+		{"clear category", `{"category":null}`, models.VideoUpdateRequest{Category: str("")}},
+		{"empty tags", `{"tags":[]}`, models.VideoUpdateRequest{Tags: []string{}}},
+		{"clear tags", `{"tags":null}`, models.VideoUpdateRequest{Tags: []string{}}},
+		{"replace tags", `{"tags":["a","b"]}`, models.VideoUpdateRequest{Tags: []string{"a", "b"}}},
+		{"is_private false", `{"is_private":false}`, models.VideoUpdateRequest{IsPrivate: boolean(false)}},
+		{"is_private true", `{"is_private":true}`, models.VideoUpdateRequest{IsPrivate: boolean(true)}},
+		{"visibility", `{"visibility":"unlisted"}`, models.VideoUpdateRequest{Visibility: visibility(models.VisibilityUnlisted)}},
+		{"clear previews_disabled", `{"previews_disabled":null}`, models.VideoUpdateRequest{PreviewsDisabled: boolean(false)}},
+		{"clear comments_enabled", `{"comments_enabled":null}`, models.VideoUpdateRequest{CommentsEnabled: boolean(true)}},
+		{"comments off", `{"comments_enabled":false}`, models.VideoUpdateRequest{CommentsEnabled: boolean(false)}},
+		{"clear chapters", `{"chapters":null}`, models.VideoUpdateRequest{Chapters: chapters()}},
+		{"replace chapters", `{"chapters":[{"title":"Intro","start_seconds":0}]}`,
+			models.VideoUpdateRequest{Chapters: chapters(models.Chapter{Title: "Intro"})}},
+		{"several at once", ` {"description": null, "tags": [], "is_private": false} `,
+			models.VideoUpdateRequest{Description: str(""), Tags: []string{}, IsPrivate: boolean(false)}},
+	}
+	for _, tt := range tests {
+		got, fields := models.ParseVideoMergePatch([]byte(tt.body))
+		if fields != nil || got == nil || !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: got %s, %v", tt.name, describe(got), fields)
+		}
+	}
+}
+
+func TestParseVideoMergePatchRefused(t *testing.T) {
+	tests := []struct {
+		body   string
+		fields []string
+	}{
+		{`[]`, []string{"body"}},
+		{`null`, []string{"body"}},
+		{`{"title":`, []string{"body"}},
+		{`{"title":null}`, []string{"title"}},
+		{`{"visibility":null,"is_private":null}`, []string{"visibility", "is_private"}},
+		{`{"id":7,"view_count":1}`, []string{"id", "view_count"}},
+		{`{"tags":"music"}`, []string{"tags"}},
+		{`{"is_private":"no","description":3}`, []string{"is_private", "description"}},
+	}
+	for _, tt := range tests {
+		got, fields := models.ParseVideoMergePatch([]byte(tt.body))
+		if got != nil || len(fields) != len(tt.fields) {
+			t.Errorf("%s: got %s, %v; want problems with %v", tt.body, describe(got), fields, tt.fields)
+			continue
+		}
+		for _, name := range tt.fields {
+			if fields[name] == "" {
+				t.Errorf("%s: no problem reported for %s: %v", tt.body, name, fields)
+			}
+		}
+	}
+}
+
+func describe(req *models.VideoUpdateRequest) string {
+	out, _ := json.Marshal(req)
+	return string(out)
+}
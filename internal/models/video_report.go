@@ -0,0 +1,105 @@
+package models
+
+import "time"
+
+// ReportReason is why a user reported a video
+type ReportReason string
+
+const (
+	ReportSpam          ReportReason = "spam"
+	ReportHarassment    ReportReason = "harassment"
+	ReportHate          ReportReason = "hate"
+	ReportViolence      ReportReason = "violence"
+	ReportSexualContent ReportReason = "sexual_content"
+	ReportChildSafety   ReportReason = "child_safety"
+	ReportCopyright     ReportReason = "copyright"
+	ReportMisleading    ReportReason = "misleading"
+	ReportOther         ReportReason = "other"
+)
+
+// ReportReasons lists every valid report reason
+var ReportReasons = []ReportReason{ReportSpam, ReportHarassment, ReportHate, ReportViolence,
+	ReportSexualContent, ReportChildSafety, ReportCopyright, ReportMisleading, ReportOther}
+
+// Valid reports whether r is a known reason
+func (r ReportReason) Valid() bool {
+	for _, known := range ReportReasons {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportStatus is where a report is in review
+type ReportStatus string
+
+const (
+	// ReportOpen reports wait in the admin queue
+	ReportOpen ReportStatus = "open"
+	// ReportReviewed reports were acted on
+	ReportReviewed ReportStatus = "reviewed"
+	// ReportDismissed reports were looked at and needed no action
+	ReportDismissed ReportStatus = "dismissed"
+)
+
+// ReportStatuses lists every valid report status
+var ReportStatuses = []ReportStatus{ReportOpen, ReportReviewed, ReportDismissed}
+
+// Valid reports whether s is a known status
+func (s ReportStatus) Valid() bool {
+	for _, known := range ReportStatuses {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxReportDetailsLen is the longest report details accepted, in characters
+const MaxReportDetailsLen = 1000
+
+// VideoReport is a user's abuse report about a video. A user has at most one open
+// report per video; once it is resolved they may report the video again.
+type VideoReport struct {
+	ID         uint         `json:"id" gorm:"primarykey"`
+	VideoID    uint         `json:"video_id" gorm:"not null;index;uniqueIndex:idx_video_reports_open,priority:1,where:status = 'open'"`
+	ReporterID string       `json:"reporter_id" gorm:"size:191;not null;index;uniqueIndex:idx_video_reports_open,priority:2,where:status = 'open'"`
+	Reason     ReportReason `json:"reason" gorm:"size:32;not null"`
+	Details    string       `json:"details,omitempty" gorm:"type:text"`
+	Status     ReportStatus `json:"status" gorm:"size:16;not null;default:'open';index"`
+	// ResolvedBy is the admin who reviewed or dismissed the report, and
+	// ResolutionNote their note on it
+	ResolvedBy     string     `json:"resolved_by,omitempty" gorm:"size:191"`
+	ResolutionNote string     `json:"resolution_note,omitempty" gorm:"type:text"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// VideoReportCreateRequest reports a video
+type VideoReportCreateRequest struct {
+	Reason  ReportReason `json:"reason" binding:"required"`
+	Details string       `json:"details" binding:"max=1000"`
+}
+
+// VideoReportResolveRequest closes a report, optionally moderating the video
+type VideoReportResolveRequest struct {
+	// Status is reviewed or dismissed
+	Status ReportStatus `json:"status" binding:"required"`
+	// ModerationStatus, when set, is applied to the reported video, and the video's
+	// other open reports are resolved the same way
+	ModerationStatus *ModerationStatus `json:"moderation_status,omitempty"`
+	Note             string            `json:"note" binding:"max=1000"`
+}
+
+// ReportedVideo counts the reports against one video, for the admin queue
+type ReportedVideo struct {
+	VideoID          uint             `json:"video_id"`
+	Title            string           `json:"title"`
+	UserID           string           `json:"user_id"`
+	ModerationStatus ModerationStatus `json:"moderation_status"`
+	OpenReports      int64            `json:"open_reports"`
+	TotalReports     int64            `json:"total_reports"`
+	LastReportedAt   time.Time        `json:"last_reported_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// What changed a video's status, as recorded in its status history
+const (
+	StatusSourceAPI             = "api"
+	StatusSourceUploadEvent     = "upload-event"
+	StatusSourceTranscodedEvent = "transcoded-event"
+	StatusSourceFailedEvent     = "failed-event"
+)
+
+// VideoStatusChange is one entry in a video's status history, written in the same
+// transaction as the change. From is empty for the status a video was created with.
+type VideoStatusChange struct {
+	ID      uint        `json:"id" gorm:"primarykey"`
+	VideoID uint        `json:"video_id" gorm:"not null;index:idx_video_status_history_video,priority:1"`
+	From    VideoStatus `json:"from,omitempty" gorm:"column:from_status;size:20"`
+	To      VideoStatus `json:"to" gorm:"column:to_status;size:20;not null"`
+	Source  string      `json:"source" gorm:"size:32;not null"`
+	// ActorID is the user or admin behind an API change; empty for broker events
+	ActorID string `json:"actor_id,omitempty" gorm:"size:191"`
+	// Reason is the admin's reason or the transcoder's error
+	Reason    string    `json:"reason,omitempty" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_video_status_history_video,priority:2"`
+}
+
+// TableName names the table after what it holds rather than the row type
+func (VideoStatusChange) TableName() string { return "video_status_history" }
@@ -0,0 +1,55 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+func TestVideoStatusTransitions(t *testing.T) {
+	const (
+		uploaded   = models.StatusUploaded
+		processing = models.StatusProcessing
+		ready      = models.StatusReady
+		failed     = models.StatusFailed
+	)
+	allowed := map[[2]models.VideoStatus]bool{
+		{uploaded, uploaded}:     true,
+		{uploaded, processing}:   true,
+		{processing, processing}: true,
+		{processing, ready}:      true,
+		{processing, failed}:     true,
+		{ready, ready}:           true,
+		{failed, failed}:         true,
+		{failed, processing}:     true,
+	}
+	statuses := append([]models.VideoStatus{"", "deleted"}, models.VideoStatuses...)
+	for _, from := range statuses {
+		for _, to := range statuses {
+			if got, want := from.CanTransitionTo(to), allowed[[2]models.VideoStatus{from, to}]; got != want {
+				t.Errorf("%q -> %q allowed = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestNextStatuses(t *testing.T) {
+	for _, from := range models.VideoStatuses {
+		next := from.NextStatuses()
+		for _, to := range next {
+			if to == from || !from.CanTransitionTo(to) {
+				t.Errorf("%s lists %s as next", from, to)
+			}
+		}
+		// The result is a copy the caller may change
+		if len(next) > 0 {
+			next[0] = "changed"
+			if from.NextStatuses()[0] == "changed" {
+				t.Errorf("%s: NextStatuses shares its slice", from)
+			}
+		}
+	}
+	if next := models.StatusReady.NextStatuses(); len(next) != 0 {
+		t.Errorf("ready moves on to %v, want nothing", next)
+	}
+}
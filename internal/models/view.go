@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// VideoView dedups view counting: one row per video, viewer and UTC day. Viewer is
+// a user ID, an anon:<session> identity, or ip:<address> for callers with neither.
+type VideoView struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	VideoID       uint      `json:"video_id" gorm:"not null;uniqueIndex:idx_video_views_key,priority:1"`
+	Viewer        string    `json:"viewer" gorm:"size:191;not null;uniqueIndex:idx_video_views_key,priority:2;index"`
+	ViewDate      time.Time `json:"view_date" gorm:"type:date;not null;uniqueIndex:idx_video_views_key,priority:3;index"`
+	Views         int64     `json:"views" gorm:"not null;default:0"`
+	LastCountedAt time.Time `json:"last_counted_at" gorm:"not null"`
+}
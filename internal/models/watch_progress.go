@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// WatchProgress is where a viewer last was in a video, for resuming playback. UserID
+// is a user ID or an anon:<session> identity; there is one row per viewer and video.
+type WatchProgress struct {
+	ID              uint    `json:"-" gorm:"primarykey"`
+	UserID          string  `json:"user_id" gorm:"size:191;not null;uniqueIndex:idx_watch_progress_key,priority:1;index:idx_watch_progress_recent,priority:1"`
+	VideoID         uint    `json:"video_id" gorm:"not null;uniqueIndex:idx_watch_progress_key,priority:2;index"`
+	PositionSeconds float64 `json:"position_seconds" gorm:"not null;default:0"`
+	// DurationAtTime is the video's duration when the position was saved
+	DurationAtTime float64 `json:"duration_at_time" gorm:"not null;default:0"`
+	// Milestones is the mask of watch milestones reached; see services.MilestoneMask
+	Milestones uint8     `json:"-" gorm:"type:smallint;not null;default:0"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"index:idx_watch_progress_recent,priority:2,sort:desc"`
+}
+
+// TableName keeps the table name singular; GORM would pluralize it to watch_progresses
+func (WatchProgress) TableName() string { return "watch_progress" }
+
+// WatchProgressRequest reports the player's position in a video
+type WatchProgressRequest struct {
+	PositionSeconds *float64 `json:"position_seconds" binding:"required"`
+}
+
+// ContinueWatchingEntry is a video the viewer started and hasn't finished, with
+// where they left off
+type ContinueWatchingEntry struct {
+	VideoCard
+	PositionSeconds   float64   `json:"position_seconds"`
+	ProgressUpdatedAt time.Time `json:"progress_updated_at"`
+}
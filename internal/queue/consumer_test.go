@@ -0,0 +1,176 @@
+package queue_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/queue"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// uploadRecorder is an Uploaded handler that keeps the upload IDs it saw
+type uploadRecorder struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (r *uploadRecorder) handle(_ context.Context, e *models.UploadedEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ids = append(r.ids, e.UploadID)
+	return nil
+}
+
+func (r *uploadRecorder) seen() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.ids...)
+}
+
+func TestConsumerResumesAfterConnectionDrop(t *testing.T) {
+	broker := newFakeBroker()
+	consumer := broker.newConsumer(t)
+	uploads := &uploadRecorder{}
+	started := make(chan error, 1)
+	go func() { started <- consumer.Start(context.Background(), queue.EventHandlers{Uploaded: uploads.handle}) }()
+
+	broker.publish(testAMQP.UploadedQueue, `{"uploadId":"before","userId":"u"}`)
+	waitFor(t, "the first delivery to be acked", func() bool { acked, _ := broker.settled(); return acked == 1 })
+	declaresPerConnect := broker.declares
+
+	// The broker restarts and refuses the first two reconnect attempts; a message
+	// published meanwhile waits in its queue
+	broker.mu.Lock()
+	broker.failDials = 2
+	broker.mu.Unlock()
+	broker.drop()
+	broker.publish(testAMQP.UploadedQueue, `{"uploadId":"during","userId":"u"}`)
+
+	waitFor(t, "consumption to resume", func() bool { acked, _ := broker.settled(); return acked == 2 })
+	if got := uploads.seen(); len(got) != 2 || got[0] != "before" || got[1] != "during" {
+		t.Errorf("handled %v, want [before during]", got)
+	}
+	broker.mu.Lock()
+	dials, declares := broker.dials, broker.declares
+	broker.mu.Unlock()
+	if dials != 4 {
+		t.Errorf("%d dials, want the initial one plus three reconnect attempts", dials)
+	}
+	if declares != 2*declaresPerConnect {
+		t.Errorf("queues declared %d times over two connections, want %d", declares, 2*declaresPerConnect)
+	}
+	if consumer.State() != queue.StateConnected || !consumer.IsConnected() {
+		t.Errorf("state after reconnect = %s", consumer.State())
+	}
+
+	broker.publish(testAMQP.UploadedQueue, `{"uploadId":"after","userId":"u"}`)
+	waitFor(t, "a delivery on the new connection", func() bool { return len(uploads.seen()) == 3 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := consumer.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := <-started; err != nil {
+		t.Errorf("Start returned %v after Stop", err)
+	}
+	if consumer.State() != queue.StateClosed {
+		t.Errorf("state after Stop = %s", consumer.State())
+	}
+}
+
+func TestConsumerReportsReconnecting(t *testing.T) {
+	broker := newFakeBroker()
+	consumer := broker.newConsumer(t)
+	consumer.SetReconnectBackoff(time.Hour, time.Hour)
+	go consumer.Start(context.Background(), queue.EventHandlers{Uploaded: (&uploadRecorder{}).handle})
+
+	waitFor(t, "consuming to start", func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return len(broker.consumers) == 3
+	})
+	broker.drop()
+	waitFor(t, "the reconnecting state", func() bool { return consumer.State() == queue.StateReconnecting })
+	if consumer.IsConnected() {
+		t.Error("IsConnected while the connection is down")
+	}
+	// Closing while waiting to reconnect ends the wait
+	consumer.Close()
+	if consumer.State() != queue.StateClosed {
+		t.Errorf("state after Close = %s", consumer.State())
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	consumer := newFakeBroker().newConsumer(t)
+	consumer.SetReconnectBackoff(100*time.Millisecond, 2*time.Second)
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, 1600 * time.Millisecond},
+		{6, 2 * time.Second},
+		{40, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 50; i++ {
+			// Jitter keeps each delay within the upper half of its base
+			if d := consumer.Backoff(tt.attempt); d < tt.base/2 || d > tt.base {
+				t.Fatalf("attempt %d: backoff %s outside [%s, %s]", tt.attempt, d, tt.base/2, tt.base)
+			}
+		}
+	}
+}
+
+func TestConsumerDeliversTranscodeFailed(t *testing.T) {
+	broker := newFakeBroker()
+	consumer := broker.newConsumer(t)
+	failures := make(chan *models.TranscodeFailedEvent, 1)
+	go consumer.Start(context.Background(), queue.EventHandlers{
+		TranscodeFailed: func(_ context.Context, e *models.TranscodeFailedEvent) error {
+			failures <- e
+			return nil
+		},
+	})
+
+	broker.publish(testAMQP.TranscodeFailedQueue, `{"uploadId":"up-1","userId":"u","errorMessage":"unsupported codec","failedAt":"2024-05-01T10:00:00Z"}`)
+	select {
+	case e := <-failures:
+		if e.UploadID != "up-1" || e.UserID != "u" || e.ErrorMessage != "unsupported codec" || e.FailedAt == nil || !e.FailedAt.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+			t.Errorf("event = %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("transcode failure not delivered")
+	}
+	waitFor(t, "the delivery to be acked", func() bool { acked, _ := broker.settled(); return acked == 1 })
+}
+
+// TestConsumerWithoutVideoHandlers starts the consumer as EVENT_BUS=kafka does:
+// only the user-event queue is consumed, and video events wait in their queues
+func TestConsumerWithoutVideoHandlers(t *testing.T) {
+	broker := newFakeBroker()
+	consumer := broker.newConsumer(t)
+	consumer.SetDataExports(services.NewDataExportService(dbtest.Open(t), zap.NewNop().Sugar(), t.TempDir(), time.Hour, 0))
+	go consumer.Start(context.Background(), queue.EventHandlers{})
+
+	waitFor(t, "the consumer to attach", func() bool { return len(broker.consuming()) > 0 })
+	if got := fmt.Sprint(broker.consuming()); got != "["+testAMQP.UserQueue+"]" {
+		t.Errorf("consuming %s, want only the user queue", got)
+	}
+	broker.publish(testAMQP.UploadedQueue, `{"uploadId":"up-1","userId":"u"}`)
+	time.Sleep(20 * time.Millisecond)
+	if acked, nacked := broker.settled(); acked+nacked != 0 {
+		t.Errorf("%d video events settled, want them left queued", acked+nacked)
+	}
+}
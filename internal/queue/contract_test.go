@@ -0,0 +1,223 @@
+package queue_test
+
+// Contract tests replay recorded producer payloads through the consumer: each is
+// unmarshaled, checked and handled exactly as a broker delivery would be, against
+// a throwaway database, and the resulting video is compared with what the
+// fixture expects.
+//
+// Fixtures live in testdata/contracts/<kind>/, one directory per event queue
+// (uploaded, transcoded, transcode_failed). To add one when a producer changes
+// its payload:
+//
+//  1. Capture a real message, e.g. from the RabbitMQ management UI ("Get
+//     messages" on the queue) or from the catalog's inbound event archive.
+//  2. Save it as testdata/contracts/<kind>/v<N>-<what changed>.json with
+//     "source" naming the producer version and what is new, and the message
+//     body, unchanged apart from scrubbing personal data, as "payload". Keep the
+//     older fixtures: producers are upgraded one at a time, so every shape
+//     still in flight must keep working.
+//  3. Under "expect", list the video's fields after handling, as the API
+//     returns them (snake_case JSON). Only the fields listed are compared.
+//  4. If the payload carries fields the event structs don't declare, list them
+//     under "unknownFields"; strict mode reports exactly those.
+//  5. Run go test ./internal/queue -run TestContracts. A failure means the
+//     catalog would mishandle the new shape: fix the event struct or handler,
+//     never the fixture.
+//
+// Each fixture runs alone against an empty catalog, so a transcoded or failure
+// payload also exercises the placeholder created when its upload event is late.
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/queue"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// contractFixture is one recorded payload and what handling it must produce
+type contractFixture struct {
+	Source        string                     `json:"source"`
+	Payload       json.RawMessage            `json:"payload"`
+	Expect        map[string]json.RawMessage `json:"expect"`
+	UnknownFields []string                   `json:"unknownFields"`
+}
+
+// contractKinds maps each fixture directory to its queue and event struct
+var contractKinds = map[string]struct {
+	queue string
+	event func() interface{}
+}{
+	services.EventKindUploaded:        {testAMQP.UploadedQueue, func() interface{} { return &models.UploadedEvent{} }},
+	services.EventKindTranscoded:      {testAMQP.TranscodedQueue, func() interface{} { return &models.TranscodedEvent{} }},
+	services.EventKindTranscodeFailed: {testAMQP.TranscodeFailedQueue, func() interface{} { return &models.TranscodeFailedEvent{} }},
+}
+
+func TestContracts(t *testing.T) {
+	dirs, err := os.ReadDir(filepath.Join("testdata", "contracts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		kind, ok := contractKinds[dir.Name()]
+		if !ok {
+			t.Errorf("testdata/contracts/%s: not an event kind", dir.Name())
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join("testdata", "contracts", dir.Name(), "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) < 2 {
+			t.Errorf("testdata/contracts/%s: %d fixtures, want every payload version still produced", dir.Name(), len(files))
+		}
+		for _, file := range files {
+			name := dir.Name() + "/" + strings.TrimSuffix(filepath.Base(file), ".json")
+			t.Run(name, func(t *testing.T) {
+				fixture := readFixture(t, file)
+				video := runContract(t, dir.Name(), kind.queue, fixture)
+				checkVideo(t, video, fixture.Expect)
+				if got := events.UnknownFields(fixture.Payload, kind.event()); !equalFields(got, fixture.UnknownFields) {
+					t.Errorf("unknown fields %v, fixture lists %v", got, fixture.UnknownFields)
+				}
+			})
+		}
+	}
+}
+
+func readFixture(t *testing.T, file string) contractFixture {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture contractFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatalf("parse fixture: %v", err)
+	}
+	if fixture.Source == "" || len(fixture.Payload) == 0 || len(fixture.Expect) == 0 {
+		t.Fatal("fixture needs source, payload and expect")
+	}
+	if _, ok := fixture.Expect["upload_id"]; !ok {
+		t.Fatal("fixture must expect an upload_id, to find the video by")
+	}
+	return fixture
+}
+
+// runContract delivers the fixture's payload to a consumer in strict mode, backed
+// by the real video handlers on a fresh database, and returns the video it left
+func runContract(t *testing.T, kind, queueName string, fixture contractFixture) *models.Video {
+	t.Helper()
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, zap.NewNop().Sugar())
+	broker := newFakeBroker()
+	consumer := broker.newConsumer(t)
+	consumer.SetStrictFields(true)
+
+	// Keep the handler's error, which the consumer only logs
+	var mu sync.Mutex
+	var handleErr error
+	record := func(err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handleErr = err
+		return err
+	}
+	handlers := queue.VideoEventHandlers(videos)
+	go consumer.Start(context.Background(), queue.EventHandlers{
+		Uploaded: func(ctx context.Context, e *models.UploadedEvent) error {
+			return record(handlers.Uploaded(ctx, e))
+		},
+		Transcoded: func(ctx context.Context, e *models.TranscodedEvent) error {
+			return record(handlers.Transcoded(ctx, e))
+		},
+		TranscodeFailed: func(ctx context.Context, e *models.TranscodeFailedEvent) error {
+			return record(handlers.TranscodeFailed(ctx, e))
+		},
+	})
+
+	unknown := map[string]float64{}
+	for _, field := range fixture.UnknownFields {
+		unknown[field] = testutil.ToFloat64(metrics.EventUnknownFieldsTotal.WithLabelValues(kind, field))
+	}
+	broker.publish(queueName, string(fixture.Payload))
+	waitFor(t, "the delivery to be settled", func() bool { acked, nacked := broker.settled(); return acked+nacked == 1 })
+	if _, nacked := broker.settled(); nacked != 0 {
+		mu.Lock()
+		defer mu.Unlock()
+		t.Fatalf("payload rejected: %v", handleErr)
+	}
+	for field, before := range unknown {
+		if got := testutil.ToFloat64(metrics.EventUnknownFieldsTotal.WithLabelValues(kind, field)) - before; got != 1 {
+			t.Errorf("strict mode counted %s %v times, want once", field, got)
+		}
+	}
+
+	var uploadID string
+	json.Unmarshal(fixture.Expect["upload_id"], &uploadID)
+	found, err := videos.GetVideoByUploadID(context.Background(), uploadID)
+	if err != nil {
+		t.Fatalf("video for %s: %v", uploadID, err)
+	}
+	// The single-video read loads what the lookup by upload ID leaves out
+	video, err := videos.GetVideo(context.Background(), found.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return video
+}
+
+// checkVideo compares the video's JSON fields with those the fixture expects
+func checkVideo(t *testing.T, video *models.Video, expect map[string]json.RawMessage) {
+	t.Helper()
+	body, err := json.Marshal(video)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 0, len(expect))
+	for key := range expect {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var want interface{}
+		if err := json.Unmarshal(expect[key], &want); err != nil {
+			t.Fatalf("expect.%s: %v", key, err)
+		}
+		if !reflect.DeepEqual(got[key], want) {
+			t.Errorf("%s = %s, want %s", key, jsonString(got[key]), expect[key])
+		}
+	}
+}
+
+func jsonString(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func equalFields(got, want []string) bool {
+	if len(got) == 0 && len(want) == 0 {
+		return true
+	}
+	want = append([]string(nil), want...)
+	sort.Strings(want)
+	return reflect.DeepEqual(got, want)
+}
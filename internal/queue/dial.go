@@ -0,0 +1,94 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+)
+
+// dial connects to cfg's broker, applying the credential, vhost, TLS, heartbeat and
+// dial timeout settings on top of cfg.URL
+func dial(cfg config.AMQP) (*amqp091.Connection, error) {
+	dialCfg, err := dialConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return amqp091.DialConfig(cfg.URL, dialCfg)
+}
+
+// dialConfig assembles the connection settings for cfg: the URL's credentials
+// unless cfg overrides them, cfg's vhost, TLS and timeouts
+func dialConfig(cfg config.AMQP) (amqp091.Config, error) {
+	uri, err := amqp091.ParseURI(cfg.URL)
+	if err != nil {
+		return amqp091.Config{}, fmt.Errorf("parse AMQP URL: %w", err)
+	}
+	tlsConfig, err := cfg.TLSConfig()
+	if err != nil {
+		return amqp091.Config{}, err
+	}
+	auth := &amqp091.PlainAuth{Username: uri.Username, Password: uri.Password}
+	if cfg.Username != "" {
+		auth.Username = cfg.Username
+	}
+	if cfg.Password != "" {
+		auth.Password = cfg.Password
+	}
+	return amqp091.Config{
+		SASL: []amqp091.Authentication{auth},
+		// Empty keeps the URL's vhost
+		Vhost:           cfg.VHost,
+		Heartbeat:       cfg.Heartbeat,
+		TLSClientConfig: tlsConfig,
+		Locale:          "en_US",
+		Dial:            amqp091.DefaultDial(cfg.DialTimeout),
+	}, nil
+}
+
+// amqpConnection is the part of an AMQP connection the consumer uses, so tests can
+// stand in for the broker
+type amqpConnection interface {
+	Channel() (amqpChannel, error)
+	NotifyClose(receiver chan *amqp091.Error) chan *amqp091.Error
+	IsClosed() bool
+	Close() error
+}
+
+// amqpChannel is the part of an AMQP channel the consumer uses
+type amqpChannel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp091.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp091.Table) (amqp091.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp091.Table) error
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp091.Table) (<-chan amqp091.Delivery, error)
+	Cancel(consumer string, noWait bool) error
+	Get(queue string, autoAck bool) (amqp091.Delivery, bool, error)
+	NotifyClose(receiver chan *amqp091.Error) chan *amqp091.Error
+	IsClosed() bool
+	Close() error
+}
+
+// brokerConnection is a real broker connection as an amqpConnection
+type brokerConnection struct {
+	*amqp091.Connection
+}
+
+// Channel opens a channel on the connection
+func (b brokerConnection) Channel() (amqpChannel, error) {
+	channel, err := b.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// dialBroker dials cfg's broker for the consumer
+func dialBroker(cfg config.AMQP) (amqpConnection, error) {
+	conn, err := dial(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return brokerConnection{conn}, nil
+}
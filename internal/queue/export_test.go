@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+)
+
+// AMQPConnection and AMQPChannel let tests implement a stand-in broker
+type (
+	AMQPConnection = amqpConnection
+	AMQPChannel    = amqpChannel
+)
+
+// NewConsumerWithDial creates a consumer that reaches the broker through dial
+func NewConsumerWithDial(cfg config.AMQP, logger *zap.SugaredLogger, dial func(config.AMQP) (AMQPConnection, error)) (*Consumer, error) {
+	return newConsumer(cfg, logger, dial)
+}
+
+// Backoff is the delay before reconnect attempt
+func (c *Consumer) Backoff(attempt int) time.Duration { return c.backoff(attempt) }
+
+// InjectTrace adds ctx's trace context to headers, as Publish does
+func InjectTrace(ctx context.Context, headers amqp091.Table) { injectTrace(ctx, headers) }
+
+// OrderingKey and LaneFor are how the consumer picks a delivery's worker
+var (
+	OrderingKey = orderingKey
+	LaneFor     = laneFor
+)
+
+// DialConfig is the connection settings dial uses for cfg
+var DialConfig = dialConfig
+
+// KafkaReader lets tests stand in a fake Kafka cluster
+type KafkaReader = kafkaReader
+
+// NewKafkaSourceWithReader creates a Kafka source that fetches through reader
+func NewKafkaSourceWithReader(cfg config.Kafka, reader KafkaReader, logger *zap.SugaredLogger) *KafkaSource {
+	return newKafkaSource(cfg, reader, logger)
+}
@@ -0,0 +1,541 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/queue"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// testAMQP names the queues and routing keys the fake broker serves
+var testAMQP = config.AMQP{
+	Exchange:                  "streamhive",
+	UploadedQueue:             "catalog.uploaded",
+	TranscodedQueue:           "catalog.transcoded",
+	TranscodeFailedQueue:      "catalog.transcode_failed",
+	UserQueue:                 "catalog.user",
+	UploadedRoutingKey:        "video.uploaded",
+	TranscodedRoutingKey:      "video.transcoded",
+	TranscodeFailedRoutingKey: "video.transcode_failed",
+	DataExportRoutingKey:      "user.data_export.requested",
+	UserDeletedRoutingKey:     "user.deleted",
+}
+
+// videoSettings are the video service defaults Load would give
+var videoSettings = config.Videos{
+	UploadMissTTL:     2 * time.Second,
+	DeleteGrace:       7 * 24 * time.Hour,
+	PlaybackURLTTL:    time.Hour,
+	ChannelSummaryTTL: 30 * time.Second,
+}
+
+// routingKeys maps each queue to the routing key its messages carry
+var routingKeys = map[string]string{
+	testAMQP.UploadedQueue:        testAMQP.UploadedRoutingKey,
+	testAMQP.TranscodedQueue:      testAMQP.TranscodedRoutingKey,
+	testAMQP.TranscodeFailedQueue: testAMQP.TranscodeFailedRoutingKey,
+}
+
+// fakeBroker stands in for RabbitMQ: it holds messages per queue, hands them to
+// the consumer on the current connection and records acks and nacks. Messages
+// unacked when a connection drops are redelivered on the next one.
+type fakeBroker struct {
+	mu        sync.Mutex
+	pending   map[string][]amqp091.Delivery
+	consumers map[string]chan amqp091.Delivery
+	unacked   map[uint64]amqp091.Delivery
+	conn      *fakeConn
+	nextTag   uint64
+	// failDials makes that many of the next dials fail
+	failDials int
+	dials     int
+	declares  int
+	// prefetch is the last QoS prefetch count a channel asked for
+	prefetch int
+	acked    []amqp091.Delivery
+	nacked   []amqp091.Delivery
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		pending:   map[string][]amqp091.Delivery{},
+		consumers: map[string]chan amqp091.Delivery{},
+		unacked:   map[uint64]amqp091.Delivery{},
+	}
+}
+
+// newConsumer connects a consumer to b with near-instant reconnects
+func (b *fakeBroker) newConsumer(t *testing.T) *queue.Consumer {
+	t.Helper()
+	c, err := queue.NewConsumerWithDial(testAMQP, zap.NewNop().Sugar(), b.dial)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	c.SetReconnectBackoff(time.Millisecond, 5*time.Millisecond)
+	t.Cleanup(c.Close)
+	return c
+}
+
+func (b *fakeBroker) dial(config.AMQP) (queue.AMQPConnection, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dials++
+	if b.failDials > 0 {
+		b.failDials--
+		return nil, errors.New("connection refused")
+	}
+	b.conn = &fakeConn{broker: b}
+	return b.conn, nil
+}
+
+// publish routes body to queue, as the exchange would
+func (b *fakeBroker) publish(queueName string, body string) {
+	b.publishDelivery(queueName, amqp091.Delivery{RoutingKey: routingKeys[queueName], Body: []byte(body)})
+}
+
+// publishDelivery routes a delivery with its own envelope to queue
+func (b *fakeBroker) publishDelivery(queueName string, msg amqp091.Delivery) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextTag++
+	msg.Acknowledger, msg.DeliveryTag, msg.ConsumerTag = b, b.nextTag, queueName
+	b.pending[queueName] = append(b.pending[queueName], msg)
+	b.dispatchLocked(queueName)
+}
+
+func (b *fakeBroker) dispatchLocked(queueName string) {
+	consumer, ok := b.consumers[queueName]
+	if !ok {
+		return
+	}
+	for _, msg := range b.pending[queueName] {
+		b.unacked[msg.DeliveryTag] = msg
+		consumer <- msg
+	}
+	b.pending[queueName] = nil
+}
+
+// drop closes the current connection the way a broker restart does
+func (b *fakeBroker) drop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn != nil {
+		b.conn.closeLocked(&amqp091.Error{Code: amqp091.ConnectionForced, Reason: "broker restart"})
+	}
+}
+
+// settled returns how many deliveries were acked and nacked
+func (b *fakeBroker) settled() (acked, nacked int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.acked), len(b.nacked)
+}
+
+func (b *fakeBroker) Ack(tag uint64, multiple bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if msg, ok := b.unacked[tag]; ok {
+		delete(b.unacked, tag)
+		b.acked = append(b.acked, msg)
+	}
+	return nil
+}
+
+func (b *fakeBroker) Nack(tag uint64, multiple, requeue bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	msg, ok := b.unacked[tag]
+	if !ok {
+		return nil
+	}
+	delete(b.unacked, tag)
+	if requeue {
+		msg.Redelivered = true
+		b.pending[msg.ConsumerTag] = append(b.pending[msg.ConsumerTag], msg)
+		return nil
+	}
+	b.nacked = append(b.nacked, msg)
+	return nil
+}
+
+func (b *fakeBroker) Reject(tag uint64, requeue bool) error { return b.Nack(tag, false, requeue) }
+
+// fakeConn is one connection to the fake broker, with a single channel
+type fakeConn struct {
+	broker   *fakeBroker
+	closed   bool
+	notify   []chan *amqp091.Error
+	channels []*fakeChannel
+}
+
+func (c *fakeConn) Channel() (queue.AMQPChannel, error) {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	if c.closed {
+		return nil, amqp091.ErrClosed
+	}
+	ch := &fakeChannel{conn: c}
+	c.channels = append(c.channels, ch)
+	return ch, nil
+}
+
+func (c *fakeConn) NotifyClose(receiver chan *amqp091.Error) chan *amqp091.Error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	c.notify = append(c.notify, receiver)
+	return receiver
+}
+
+func (c *fakeConn) IsClosed() bool {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	return c.closed
+}
+
+func (c *fakeConn) Close() error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	c.closeLocked(nil)
+	return nil
+}
+
+// closeLocked closes the connection and its channel: consumers' delivery channels
+// close, unacked deliveries go back to their queues and reason is sent to every
+// NotifyClose listener (nil for a clean close, as amqp091 does)
+func (c *fakeConn) closeLocked(reason *amqp091.Error) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	b := c.broker
+	for _, ch := range c.channels {
+		ch.closeLocked(reason)
+	}
+	for queueName, consumer := range b.consumers {
+		close(consumer)
+		delete(b.consumers, queueName)
+	}
+	for tag, msg := range b.unacked {
+		delete(b.unacked, tag)
+		msg.Redelivered = true
+		b.pending[msg.ConsumerTag] = append(b.pending[msg.ConsumerTag], msg)
+	}
+	for _, n := range c.notify {
+		if reason != nil {
+			n <- reason
+		}
+		close(n)
+	}
+}
+
+type fakeChannel struct {
+	conn   *fakeConn
+	closed bool
+	notify []chan *amqp091.Error
+}
+
+func (ch *fakeChannel) ExchangeDeclare(string, string, bool, bool, bool, bool, amqp091.Table) error {
+	return nil
+}
+
+func (ch *fakeChannel) QueueDeclare(name string, _, _, _, _ bool, _ amqp091.Table) (amqp091.Queue, error) {
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.declares++
+	return amqp091.Queue{Name: name}, nil
+}
+
+func (ch *fakeChannel) QueueBind(string, string, string, bool, amqp091.Table) error { return nil }
+
+func (ch *fakeChannel) Qos(prefetchCount, _ int, _ bool) error {
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.prefetch = prefetchCount
+	return nil
+}
+
+func (ch *fakeChannel) Consume(queueName, _ string, _, _, _, _ bool, _ amqp091.Table) (<-chan amqp091.Delivery, error) {
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ch.closed {
+		return nil, amqp091.ErrClosed
+	}
+	consumer := make(chan amqp091.Delivery, 1000)
+	b.consumers[queueName] = consumer
+	b.dispatchLocked(queueName)
+	return consumer, nil
+}
+
+// Cancel stops deliveries for a consumer tag, which the consumer sets to the queue name
+func (ch *fakeChannel) Cancel(consumerTag string, _ bool) error {
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if consumer, ok := b.consumers[consumerTag]; ok {
+		close(consumer)
+		delete(b.consumers, consumerTag)
+	}
+	return nil
+}
+
+func (ch *fakeChannel) Get(queueName string, _ bool) (amqp091.Delivery, bool, error) {
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending[queueName]) == 0 {
+		return amqp091.Delivery{}, false, nil
+	}
+	msg := b.pending[queueName][0]
+	b.pending[queueName] = b.pending[queueName][1:]
+	b.unacked[msg.DeliveryTag] = msg
+	return msg, true, nil
+}
+
+func (ch *fakeChannel) NotifyClose(receiver chan *amqp091.Error) chan *amqp091.Error {
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch.notify = append(ch.notify, receiver)
+	return receiver
+}
+
+func (ch *fakeChannel) IsClosed() bool {
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return ch.closed
+}
+
+func (ch *fakeChannel) Close() error {
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch.closeLocked(nil)
+	return nil
+}
+
+func (ch *fakeChannel) closeLocked(reason *amqp091.Error) {
+	if ch.closed {
+		return
+	}
+	ch.closed = true
+	for _, n := range ch.notify {
+		if reason != nil {
+			n <- reason
+		}
+		close(n)
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+// consuming returns the queues with a consumer attached
+func (b *fakeBroker) consuming() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var queues []string
+	for name := range b.consumers {
+		queues = append(queues, name)
+	}
+	sort.Strings(queues)
+	return queues
+}
+
+// testKafka names the topics the fake Kafka cluster serves
+var testKafka = config.Kafka{
+	Brokers:              []string{"kafka:9092"},
+	GroupID:              "video-catalog",
+	UploadedTopic:        "video.uploaded",
+	TranscodedTopic:      "video.transcoded",
+	TranscodeFailedTopic: "video.transcode.failed",
+	Workers:              4,
+	ShutdownTimeout:      time.Second,
+}
+
+// kafkaTopics maps each event kind to its topic
+var kafkaTopics = map[string]string{
+	services.EventKindUploaded:        testKafka.UploadedTopic,
+	services.EventKindTranscoded:      testKafka.TranscodedTopic,
+	services.EventKindTranscodeFailed: testKafka.TranscodeFailedTopic,
+}
+
+// fakeKafka stands in for a Kafka cluster with one consumer group: each topic is a
+// log per partition, a message's key picks its partition, and the offsets the group
+// commits outlive the readers that commit them, so a new reader resumes there
+type fakeKafka struct {
+	mu         sync.Mutex
+	partitions int
+	logs       map[string][][]kafka.Message
+	committed  map[string][]int64
+	// regressed is set if a commit ever moved a partition's offset backwards
+	regressed bool
+	// produced is closed and replaced whenever a message is appended
+	produced chan struct{}
+}
+
+func newFakeKafka(partitions int) *fakeKafka {
+	k := &fakeKafka{
+		partitions: partitions,
+		logs:       map[string][][]kafka.Message{},
+		committed:  map[string][]int64{},
+		produced:   make(chan struct{}),
+	}
+	for _, topic := range kafkaTopics {
+		k.logs[topic] = make([][]kafka.Message, partitions)
+		k.committed[topic] = make([]int64, partitions)
+	}
+	return k
+}
+
+// newSource joins the group with a new reader, as a restarted replica does
+func (k *fakeKafka) newSource(t *testing.T, cfg config.Kafka) *queue.KafkaSource {
+	t.Helper()
+	s := queue.NewKafkaSourceWithReader(cfg, &fakeKafkaReader{kafka: k, next: map[string][]int64{}, closed: make(chan struct{})}, zap.NewNop().Sugar())
+	t.Cleanup(s.Close)
+	return s
+}
+
+// produce appends a message with key to topic, on the partition the key hashes to
+func (k *fakeKafka) produce(topic, key, value string) kafka.Message {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	partition := int(h.Sum32() % uint32(k.partitions))
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	msg := kafka.Message{
+		Topic:     topic,
+		Partition: partition,
+		Offset:    int64(len(k.logs[topic][partition])),
+		Key:       []byte(key),
+		Value:     []byte(value),
+		Time:      time.Now(),
+	}
+	k.logs[topic][partition] = append(k.logs[topic][partition], msg)
+	close(k.produced)
+	k.produced = make(chan struct{})
+	return msg
+}
+
+// committedOffset is the group's next offset to read on a partition
+func (k *fakeKafka) committedOffset(topic string, partition int) int64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.committed[topic][partition]
+}
+
+// totalCommitted is how many messages the group has committed past on every topic
+func (k *fakeKafka) totalCommitted() int64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var n int64
+	for _, offsets := range k.committed {
+		for _, offset := range offsets {
+			n += offset
+		}
+	}
+	return n
+}
+
+// fakeKafkaReader is one group member's reader; it is assigned every partition
+type fakeKafkaReader struct {
+	kafka *fakeKafka
+	// next is the offset to fetch next per topic partition, read from the group's
+	// commits the first time a topic is fetched
+	next   map[string][]int64
+	closed chan struct{}
+	once   sync.Once
+}
+
+var _ queue.KafkaReader = (*fakeKafkaReader)(nil)
+
+// FetchMessage returns the next unread message, blocking until one is produced,
+// ctx ends or the reader is closed
+func (r *fakeKafkaReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	k := r.kafka
+	for {
+		k.mu.Lock()
+		if msg, ok := r.nextLocked(); ok {
+			k.mu.Unlock()
+			return msg, nil
+		}
+		produced := k.produced
+		k.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-r.closed:
+			return kafka.Message{}, io.EOF
+		case <-produced:
+		}
+	}
+}
+
+func (r *fakeKafkaReader) nextLocked() (kafka.Message, bool) {
+	topics := make([]string, 0, len(r.kafka.logs))
+	for topic := range r.kafka.logs {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	for _, topic := range topics {
+		if r.next[topic] == nil {
+			r.next[topic] = append([]int64(nil), r.kafka.committed[topic]...)
+		}
+		for partition, log := range r.kafka.logs[topic] {
+			if offset := r.next[topic][partition]; offset < int64(len(log)) {
+				r.next[topic][partition]++
+				return log[offset], true
+			}
+		}
+	}
+	return kafka.Message{}, false
+}
+
+// CommitMessages moves the group's offset on each message's partition past it
+func (r *fakeKafkaReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	select {
+	case <-r.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+	k := r.kafka
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, msg := range msgs {
+		offsets := k.committed[msg.Topic]
+		if msg.Offset+1 < offsets[msg.Partition] {
+			k.regressed = true
+		}
+		offsets[msg.Partition] = msg.Offset + 1
+	}
+	return nil
+}
+
+func (r *fakeKafkaReader) Close() error {
+	r.once.Do(func() { close(r.closed) })
+	return nil
+}
@@ -0,0 +1,390 @@
+package queue
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+	"github.com/streamhive/video-catalog-api/internal/tracing"
+)
+
+// kafkaLaneBuffer is how many fetched messages may wait for each worker before
+// fetching blocks
+const kafkaLaneBuffer = 16
+
+// kafkaRetryDelay is how long fetching pauses after the reader returns an error
+const kafkaRetryDelay = time.Second
+
+// kafkaReader is the part of kafka.Reader the source uses, so tests can stand in a
+// fake broker
+type kafkaReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaSource consumes video events from Kafka as a member of a consumer group.
+// Producers key each message by upload ID, so one upload's events share a
+// partition; messages with the same key always go to the same worker and are
+// handled in partition order. A partition's offset is committed only once every
+// message fetched before it has been handled, so a restart resumes from the first
+// message that wasn't.
+type KafkaSource struct {
+	reader  kafkaReader
+	logger  *zap.SugaredLogger
+	workers int
+	// kinds maps each topic to the event kind its messages carry
+	kinds map[string]string
+	// quarantine parks failed events for replay; archive keeps every handled message
+	quarantine *services.EventQuarantineService
+	archive    *services.InboundEventArchive
+	commits    offsetTracker
+
+	// fetchCtx ends when the source stops fetching; handlerCtx is the parent of
+	// every handler's context and ends when Stop's deadline passes or on Close
+	fetchCtx       context.Context
+	stopFetching   context.CancelFunc
+	handlerCtx     context.Context
+	cancelHandlers context.CancelFunc
+	// loops counts running Start calls, including their messages in flight
+	loops sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+}
+
+var _ EventSource = (*KafkaSource)(nil)
+
+// NewKafkaSource creates a source reading cfg's topics as cfg's consumer group. It
+// connects on the first fetch.
+func NewKafkaSource(cfg config.Kafka, logger *zap.SugaredLogger) *KafkaSource {
+	dialer := &kafka.Dialer{Timeout: 10 * time.Second, DualStack: true}
+	if cfg.TLS {
+		dialer.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     cfg.Brokers,
+		GroupID:     cfg.GroupID,
+		GroupTopics: []string{cfg.UploadedTopic, cfg.TranscodedTopic, cfg.TranscodeFailedTopic},
+		Dialer:      dialer,
+		// A new group starts from the oldest retained message rather than missing
+		// what was produced before it joined
+		StartOffset: kafka.FirstOffset,
+		// Commits are batched; only the highest offset per partition is sent
+		CommitInterval: time.Second,
+		MaxWait:        500 * time.Millisecond,
+		ErrorLogger:    kafka.LoggerFunc(logger.Named("kafka").Errorf),
+	})
+	return newKafkaSource(cfg, reader, logger)
+}
+
+// newKafkaSource creates a source that fetches through reader
+func newKafkaSource(cfg config.Kafka, reader kafkaReader, logger *zap.SugaredLogger) *KafkaSource {
+	fetchCtx, stopFetching := context.WithCancel(context.Background())
+	handlerCtx, cancelHandlers := context.WithCancel(context.Background())
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	return &KafkaSource{
+		reader:  reader,
+		logger:  logger,
+		workers: workers,
+		kinds: map[string]string{
+			cfg.UploadedTopic:        services.EventKindUploaded,
+			cfg.TranscodedTopic:      services.EventKindTranscoded,
+			cfg.TranscodeFailedTopic: services.EventKindTranscodeFailed,
+		},
+		commits:        offsetTracker{partitions: map[topicPartition][]*trackedMessage{}},
+		fetchCtx:       fetchCtx,
+		stopFetching:   stopFetching,
+		handlerCtx:     handlerCtx,
+		cancelHandlers: cancelHandlers,
+	}
+}
+
+// SetQuarantine makes failed events be quarantined, then committed past
+func (s *KafkaSource) SetQuarantine(q *services.EventQuarantineService) { s.quarantine = q }
+
+// SetArchive records every handled message and its outcome in archive
+func (s *KafkaSource) SetArchive(a *services.InboundEventArchive) { s.archive = a }
+
+// Name reports the source as the kafka event source
+func (s *KafkaSource) Name() string { return SourceKafka }
+
+// Start fetches messages and hands them to the workers until the source is stopped
+// or closed, which ctx ending also does. The reader reconnects and rejoins the
+// group by itself, so broker outages only pause it.
+func (s *KafkaSource) Start(ctx context.Context, handlers EventHandlers) error {
+	defer context.AfterFunc(ctx, s.Close)()
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrConsumerClosed
+	}
+	s.loops.Add(1)
+	s.mu.Unlock()
+	defer s.loops.Done()
+
+	lanes := make([]chan *trackedMessage, s.workers)
+	var wg sync.WaitGroup
+	for i := range lanes {
+		lanes[i] = make(chan *trackedMessage, kafkaLaneBuffer)
+		wg.Add(1)
+		go func(lane <-chan *trackedMessage) {
+			defer wg.Done()
+			for m := range lane {
+				s.process(m, handlers)
+			}
+		}(lanes[i])
+	}
+	s.logger.Infow("Started consuming Kafka messages", "topics", s.kinds, "workers", s.workers)
+
+	next := 0
+	for {
+		msg, err := s.reader.FetchMessage(s.fetchCtx)
+		if err != nil {
+			if s.fetchCtx.Err() != nil {
+				break
+			}
+			s.logger.Errorw("Kafka fetch failed; retrying", "error", err)
+			select {
+			case <-s.fetchCtx.Done():
+			case <-time.After(kafkaRetryDelay):
+			}
+			continue
+		}
+		lane := next
+		if key := messageKey(msg); key != "" {
+			lane = laneFor(key, len(lanes))
+		} else {
+			next = (next + 1) % len(lanes)
+		}
+		lanes[lane] <- s.commits.fetched(msg)
+	}
+	for _, lane := range lanes {
+		close(lane)
+	}
+	wg.Wait()
+	return nil
+}
+
+// messageKey is the key a message must be handled in order with: its Kafka key,
+// else the ordering key of its payload
+func messageKey(msg kafka.Message) string {
+	if len(msg.Key) > 0 {
+		return "key:" + string(msg.Key)
+	}
+	return orderingKey(msg.Value)
+}
+
+// process handles one message, then marks it done so its offset can be committed.
+// A message cut short by shutdown is left uncommitted and fetched again after the
+// restart; one that fails is quarantined, or skipped when it can't be.
+func (s *KafkaSource) process(m *trackedMessage, handlers EventHandlers) {
+	msg := m.msg
+	md, headers := metadataFromMessage(msg)
+	kind := s.kinds[msg.Topic]
+	producedAt, hasProducedAt := events.ProducedAt(msg.Value, md)
+	if hasProducedAt {
+		events.ObserveLatency(kind, events.StageReceived, producedAt, md.ReceivedAt)
+	} else {
+		metrics.EventsWithoutProducedAtTotal.WithLabelValues(kind).Inc()
+	}
+	started := time.Now()
+	ctx, span := tracing.Start(extractTrace(s.handlerCtx, headers), msg.Topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.Int("messaging.kafka.destination.partition", msg.Partition),
+			attribute.Int64("messaging.kafka.message.offset", msg.Offset),
+		))
+	err := handlers.dispatch(events.WithMetadata(ctx, md), kind, msg.Value)
+	tracing.End(span, err)
+	metrics.EventHandleDuration.WithLabelValues(SourceKafka, msg.Topic).Observe(time.Since(started).Seconds())
+	if err != nil && s.handlerCtx.Err() != nil {
+		s.logger.Warnw("Handling interrupted by shutdown; leaving it uncommitted", "error", err, "kind", kind,
+			"partition", msg.Partition, "offset", msg.Offset)
+		metrics.EventsProcessedTotal.WithLabelValues(SourceKafka, msg.Topic, "requeued").Inc()
+		s.archive.Record(kind, md, msg.Value, models.InboundRequeued, err, nil)
+		return
+	}
+	switch {
+	case err == nil:
+		if hasProducedAt {
+			events.ObserveLatency(kind, events.StageHandled, producedAt, time.Now())
+		}
+		metrics.EventsProcessedTotal.WithLabelValues(SourceKafka, msg.Topic, "ok").Inc()
+		s.archive.Record(kind, md, msg.Value, models.InboundProcessed, nil, nil)
+	case s.quarantine != nil:
+		s.logger.Errorw("Failed to handle message", "error", err, "kind", kind, "correlationID", md.CorrelationID)
+		q, qerr := s.quarantine.Quarantine(context.Background(), kind, md, msg.Value, err)
+		if qerr == nil {
+			metrics.EventsProcessedTotal.WithLabelValues(SourceKafka, msg.Topic, "error").Inc()
+			s.archive.Record(kind, md, msg.Value, models.InboundFailed, err, &q.ID)
+			break
+		}
+		s.logger.Errorw("Failed to quarantine message; skipping it", "error", qerr, "kind", kind,
+			"partition", msg.Partition, "offset", msg.Offset)
+		metrics.EventsProcessedTotal.WithLabelValues(SourceKafka, msg.Topic, "skipped").Inc()
+		s.archive.Record(kind, md, msg.Value, models.InboundFailed, err, nil)
+	default:
+		// Kafka has no dead-letter queue to hand it to, and retrying would hold up
+		// the rest of the partition
+		s.logger.Errorw("Failed to handle message; skipping it", "error", err, "kind", kind,
+			"partition", msg.Partition, "offset", msg.Offset, "correlationID", md.CorrelationID)
+		metrics.EventsProcessedTotal.WithLabelValues(SourceKafka, msg.Topic, "skipped").Inc()
+		s.archive.Record(kind, md, msg.Value, models.InboundFailed, err, nil)
+	}
+	if err := s.commits.done(m, s.reader); err != nil {
+		s.logger.Warnw("Failed to commit Kafka offset", "error", err, "topic", msg.Topic, "partition", msg.Partition)
+	}
+}
+
+// Stop stops fetching and waits, up to ctx's deadline, for messages in flight to be
+// handled, then closes the reader, which sends the last commits. Past the deadline
+// the remaining handlers' context is cancelled and their messages are left
+// uncommitted. It is safe to call more than once and whether or not Start was.
+func (s *KafkaSource) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	s.stopFetching()
+	idle := make(chan struct{})
+	go func() {
+		s.loops.Wait()
+		close(idle)
+	}()
+	var err error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		err = fmt.Errorf("wait for in-flight events: %w", ctx.Err())
+		s.logger.Warnw("Shutdown deadline reached with Kafka messages in flight; cancelling them")
+		s.cancelHandlers()
+		select {
+		case <-idle:
+		case <-time.After(stopGrace):
+			s.logger.Errorw("Event handlers ignored cancellation; closing anyway", "grace", stopGrace)
+		}
+	}
+	s.cancelHandlers()
+	return errors.Join(err, s.reader.Close())
+}
+
+// Close stops at once: handlers are cancelled and their messages left
+// uncommitted, to be fetched again by whichever replica gets the partition
+func (s *KafkaSource) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.stopFetching()
+	s.cancelHandlers()
+	if err := s.reader.Close(); err != nil {
+		s.logger.Warnw("Failed to close Kafka reader", "error", err)
+	}
+}
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// trackedMessage is a fetched message and whether it has been handled
+type trackedMessage struct {
+	msg  kafka.Message
+	done bool
+}
+
+// offsetTracker holds each partition's fetched messages, in fetch order, until
+// they and every message before them are handled. Workers finish out of order, so
+// committing a message's own offset could skip one still in flight.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[topicPartition][]*trackedMessage
+}
+
+func (t *offsetTracker) fetched(msg kafka.Message) *trackedMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m := &trackedMessage{msg: msg}
+	tp := topicPartition{msg.Topic, msg.Partition}
+	t.partitions[tp] = append(t.partitions[tp], m)
+	return m
+}
+
+// done marks m handled and commits the last of the handled messages at the front
+// of its partition, if that moved. Commits are made under the lock so they reach
+// the reader in offset order.
+func (t *offsetTracker) done(m *trackedMessage, reader kafkaReader) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m.done = true
+	tp := topicPartition{m.msg.Topic, m.msg.Partition}
+	pending := t.partitions[tp]
+	n := 0
+	for n < len(pending) && pending[n].done {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	last := pending[n-1].msg
+	t.partitions[tp] = pending[n:]
+	return reader.CommitMessages(context.Background(), last)
+}
+
+// metadataFromMessage captures a message's envelope from its headers, which use
+// the same names as AMQP headers, along with the headers as a table for trace
+// extraction
+func metadataFromMessage(msg kafka.Message) (events.Metadata, amqp091.Table) {
+	headers := amqp091.Table{}
+	for _, h := range msg.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	md := events.Metadata{
+		RoutingKey: msg.Topic,
+		// Kafka has no message ID; its position is unique
+		MessageID:  fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset),
+		Timestamp:  msg.Time,
+		ProducedAt: msg.Time,
+		ReceivedAt: time.Now().UTC(),
+		Headers:    plainTable(headers),
+	}
+	if v, ok := headers[events.HeaderCorrelationID].(string); ok {
+		md.CorrelationID = v
+	}
+	if n, ok := headerInt(headers[events.HeaderRetryCount]); ok {
+		md.RetryCount = n
+	}
+	if t, ok := headerTime(headers[events.HeaderProducedAt]); ok {
+		md.ProducedAt = t
+	}
+	if t, ok := headerTime(headers[events.HeaderEventTime]); ok {
+		md.Timestamp = t
+	}
+	return md, headers
+}
@@ -0,0 +1,246 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/queue"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func kafkaProcessed(outcome string) float64 {
+	return testutil.ToFloat64(metrics.EventsProcessedTotal.WithLabelValues(queue.SourceKafka, testKafka.UploadedTopic, outcome))
+}
+
+// uploadedBody is an uploaded event for upload whose title is n
+func uploadedBody(upload string, n int) string {
+	return fmt.Sprintf(`{"uploadId":%q,"userId":"u-1","title":"%d"}`, upload, n)
+}
+
+// TestKafkaSourceKeepsKeyOrder spreads several uploads' events over partitions and
+// workers that finish at random: each upload's events are still handled in the
+// order they were produced, and every offset is committed without going backwards
+func TestKafkaSourceKeepsKeyOrder(t *testing.T) {
+	const uploads, perUpload = 8, 5
+	cluster := newFakeKafka(3)
+	source := cluster.newSource(t, testKafka)
+	var mu sync.Mutex
+	seen := map[string][]string{}
+	go source.Start(context.Background(), queue.EventHandlers{Uploaded: func(_ context.Context, e *models.UploadedEvent) error {
+		time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+		mu.Lock()
+		defer mu.Unlock()
+		seen[e.UploadID] = append(seen[e.UploadID], e.Title)
+		return nil
+	}})
+
+	for n := 0; n < perUpload; n++ {
+		for u := 0; u < uploads; u++ {
+			upload := fmt.Sprintf("up-%d", u)
+			cluster.produce(testKafka.UploadedTopic, upload, uploadedBody(upload, n))
+		}
+	}
+	waitFor(t, "every message to be committed", func() bool { return cluster.totalCommitted() == uploads*perUpload })
+
+	mu.Lock()
+	defer mu.Unlock()
+	for u := 0; u < uploads; u++ {
+		upload := fmt.Sprintf("up-%d", u)
+		if got := fmt.Sprint(seen[upload]); got != "[0 1 2 3 4]" {
+			t.Errorf("%s handled in order %s", upload, got)
+		}
+	}
+	if cluster.regressed {
+		t.Error("a commit moved an offset backwards")
+	}
+}
+
+// keysOnDifferentLanes returns two keys the source hands to different workers out of n
+func keysOnDifferentLanes(n int) (string, string) {
+	first := "up-a"
+	for i := 0; ; i++ {
+		other := fmt.Sprintf("up-%d", i)
+		if queue.LaneFor("key:"+other, n) != queue.LaneFor("key:"+first, n) {
+			return first, other
+		}
+	}
+}
+
+// TestKafkaSourceCommitsHandledPrefix finishes a later message on one partition
+// before an earlier one: the offset isn't committed past the earlier message until
+// it is handled too
+func TestKafkaSourceCommitsHandledPrefix(t *testing.T) {
+	cluster := newFakeKafka(1)
+	cfg := testKafka
+	cfg.Workers = 2
+	source := cluster.newSource(t, cfg)
+	slow, fast := keysOnDifferentLanes(cfg.Workers)
+	release, fastDone := make(chan struct{}), make(chan struct{})
+	go source.Start(context.Background(), queue.EventHandlers{Uploaded: func(_ context.Context, e *models.UploadedEvent) error {
+		if e.UploadID == slow {
+			<-release
+		} else {
+			close(fastDone)
+		}
+		return nil
+	}})
+
+	cluster.produce(testKafka.UploadedTopic, slow, uploadedBody(slow, 0))
+	cluster.produce(testKafka.UploadedTopic, fast, uploadedBody(fast, 0))
+	<-fastDone
+	time.Sleep(20 * time.Millisecond)
+	if got := cluster.committedOffset(testKafka.UploadedTopic, 0); got != 0 {
+		t.Fatalf("committed offset %d while offset 0 is still being handled, want 0", got)
+	}
+	close(release)
+	waitFor(t, "both offsets to be committed", func() bool { return cluster.committedOffset(testKafka.UploadedTopic, 0) == 2 })
+}
+
+// TestKafkaSourceRestart stops a source and starts another in the same group, as a
+// redeploy does: handled messages aren't fetched again, and one whose handler was
+// still running at the shutdown deadline is
+func TestKafkaSourceRestart(t *testing.T) {
+	cluster := newFakeKafka(1)
+	var mu sync.Mutex
+	var handled []string
+	record := func(_ context.Context, e *models.UploadedEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, e.UploadID)
+		return nil
+	}
+	taken := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		out := handled
+		handled = nil
+		return out
+	}
+
+	first := cluster.newSource(t, testKafka)
+	go first.Start(context.Background(), queue.EventHandlers{Uploaded: record})
+	cluster.produce(testKafka.UploadedTopic, "up-1", uploadedBody("up-1", 0))
+	cluster.produce(testKafka.UploadedTopic, "up-2", uploadedBody("up-2", 0))
+	waitFor(t, "the first source to commit", func() bool { return cluster.totalCommitted() == 2 })
+	if err := first.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	taken()
+
+	// The second source's handler for up-3 outlives the shutdown deadline
+	cluster.produce(testKafka.UploadedTopic, "up-3", uploadedBody("up-3", 0))
+	second := cluster.newSource(t, testKafka)
+	started := make(chan struct{})
+	go second.Start(context.Background(), queue.EventHandlers{Uploaded: func(ctx context.Context, e *models.UploadedEvent) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	<-started
+	requeued := kafkaProcessed("requeued")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := second.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop past the deadline = %v, want DeadlineExceeded", err)
+	}
+	if got := kafkaProcessed("requeued") - requeued; got != 1 {
+		t.Errorf("requeued = +%v, want +1", got)
+	}
+	if got := cluster.committedOffset(testKafka.UploadedTopic, 0); got != 2 {
+		t.Fatalf("committed offset %d after the interrupted message, want 2", got)
+	}
+
+	third := cluster.newSource(t, testKafka)
+	go third.Start(context.Background(), queue.EventHandlers{Uploaded: record})
+	waitFor(t, "the interrupted message to be committed", func() bool { return cluster.totalCommitted() == 3 })
+	if got := fmt.Sprint(taken()); got != "[up-3]" {
+		t.Errorf("after the restart handled %s, want only the interrupted up-3", got)
+	}
+}
+
+// TestKafkaSourceFailedEvents checks a failed event is quarantined when the source
+// has a quarantine and skipped when it doesn't; either way the partition moves on
+func TestKafkaSourceFailedEvents(t *testing.T) {
+	failing := queue.EventHandlers{Uploaded: func(context.Context, *models.UploadedEvent) error {
+		return errors.New("no such user")
+	}}
+
+	t.Run("skipped", func(t *testing.T) {
+		cluster := newFakeKafka(1)
+		source := cluster.newSource(t, testKafka)
+		go source.Start(context.Background(), failing)
+		skipped := kafkaProcessed("skipped")
+		cluster.produce(testKafka.UploadedTopic, "up-1", uploadedBody("up-1", 0))
+		waitFor(t, "the failed message to be committed", func() bool { return cluster.totalCommitted() == 1 })
+		if got := kafkaProcessed("skipped") - skipped; got != 1 {
+			t.Errorf("skipped = +%v, want +1", got)
+		}
+	})
+
+	t.Run("quarantined", func(t *testing.T) {
+		db := dbtest.Open(t)
+		videos := services.NewVideoService(db, nil, videoSettings, zap.NewNop().Sugar())
+		cluster := newFakeKafka(1)
+		source := cluster.newSource(t, testKafka)
+		source.SetQuarantine(services.NewEventQuarantineService(db, zap.NewNop().Sugar(), videos))
+		go source.Start(context.Background(), failing)
+		failed := kafkaProcessed("error")
+		cluster.produce(testKafka.UploadedTopic, "up-1", uploadedBody("up-1", 0))
+		waitFor(t, "the failed message to be committed", func() bool { return cluster.totalCommitted() == 1 })
+		if got := kafkaProcessed("error") - failed; got != 1 {
+			t.Errorf("error = +%v, want +1", got)
+		}
+		var parked models.QuarantinedEvent
+		if err := db.First(&parked).Error; err != nil {
+			t.Fatalf("no quarantined event: %v", err)
+		}
+		if parked.Kind != services.EventKindUploaded || parked.UploadID != "up-1" {
+			t.Errorf("quarantined %+v", parked)
+		}
+	})
+}
+
+func TestKafkaSourceStopAndClose(t *testing.T) {
+	cluster := newFakeKafka(1)
+	// Stop before Start leaves nothing to start
+	idle := cluster.newSource(t, testKafka)
+	if err := idle.Stop(context.Background()); err != nil {
+		t.Errorf("Stop before Start: %v", err)
+	}
+	if err := idle.Start(context.Background(), queue.EventHandlers{}); !errors.Is(err, queue.ErrConsumerClosed) {
+		t.Errorf("Start after Stop = %v, want ErrConsumerClosed", err)
+	}
+
+	// Cancelling Start's context closes the source
+	source := cluster.newSource(t, testKafka)
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() {
+		started <- source.Start(ctx, queue.EventHandlers{Uploaded: func(context.Context, *models.UploadedEvent) error { return nil }})
+	}()
+	cluster.produce(testKafka.UploadedTopic, "up-1", uploadedBody("up-1", 0))
+	waitFor(t, "the message to be committed", func() bool { return cluster.totalCommitted() == 1 })
+	cancel()
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Errorf("Start returned %v after its context ended", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start still running after its context ended")
+	}
+	if err := source.Stop(context.Background()); err != nil {
+		t.Errorf("Stop after Close: %v", err)
+	}
+}
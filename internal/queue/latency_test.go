@@ -0,0 +1,101 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/queue"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// receivedLatency returns the sample count and sum of the uploaded receive latency
+func receivedLatency(t *testing.T) (uint64, float64) {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.EventLatency.WithLabelValues(services.EventKindUploaded, events.StageReceived).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("read histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
+func TestConsumerRecordsProducerLatency(t *testing.T) {
+	broker := newFakeBroker()
+	consumer := broker.newConsumer(t)
+	go consumer.Start(context.Background(), queue.EventHandlers{Uploaded: (&uploadRecorder{}).handle})
+
+	now := time.Now().UTC()
+	hourAgo, twoHoursAgo := now.Add(-time.Hour), now.Add(-2*time.Hour)
+	cases := []struct {
+		name    string
+		msg     amqp091.Delivery
+		latency time.Duration
+		// skewed marks a producer clock ahead of ours, missing a message without a timestamp
+		skewed, missing bool
+	}{
+		{"AMQP timestamp", amqp091.Delivery{Timestamp: hourAgo}, time.Hour, false, false},
+		{"header wins over AMQP timestamp", amqp091.Delivery{
+			Timestamp: hourAgo,
+			Headers:   amqp091.Table{events.HeaderProducedAt: twoHoursAgo.Format(time.RFC3339Nano)},
+		}, 2 * time.Hour, false, false},
+		{"header in unix seconds", amqp091.Delivery{
+			Headers: amqp091.Table{events.HeaderProducedAt: twoHoursAgo.Unix()},
+		}, 2 * time.Hour, false, false},
+		{"unparseable header falls back to AMQP timestamp", amqp091.Delivery{
+			Timestamp: hourAgo,
+			Headers:   amqp091.Table{events.HeaderProducedAt: "two hours ago"},
+		}, time.Hour, false, false},
+		{"payload wins over envelope", amqp091.Delivery{
+			Timestamp: twoHoursAgo,
+			Body:      []byte(`{"uploadId":"p","userId":"u","producedAt":"` + hourAgo.Format(time.RFC3339Nano) + `"}`),
+		}, time.Hour, false, false},
+		{"producer clock ahead", amqp091.Delivery{Timestamp: now.Add(time.Hour)}, 0, true, false},
+		{"no timestamp", amqp091.Delivery{}, 0, false, true},
+	}
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			count, sum := receivedLatency(t)
+			skewed := testutil.ToFloat64(metrics.EventLatencySkewedTotal.WithLabelValues(services.EventKindUploaded, events.StageReceived))
+			missing := testutil.ToFloat64(metrics.EventsWithoutProducedAtTotal.WithLabelValues(services.EventKindUploaded))
+
+			msg := tc.msg
+			msg.RoutingKey = testAMQP.UploadedRoutingKey
+			if msg.Body == nil {
+				msg.Body = []byte(`{"uploadId":"latency","userId":"u"}`)
+			}
+			broker.publishDelivery(testAMQP.UploadedQueue, msg)
+			waitFor(t, "the delivery to be acked", func() bool { acked, _ := broker.settled(); return acked == i+1 })
+
+			gotCount, gotSum := receivedLatency(t)
+			gotSkewed := testutil.ToFloat64(metrics.EventLatencySkewedTotal.WithLabelValues(services.EventKindUploaded, events.StageReceived))
+			gotMissing := testutil.ToFloat64(metrics.EventsWithoutProducedAtTotal.WithLabelValues(services.EventKindUploaded))
+			if tc.missing {
+				if gotCount != count || gotMissing != missing+1 {
+					t.Errorf("samples +%d, without timestamp +%v; want +0 and +1", gotCount-count, gotMissing-missing)
+				}
+				return
+			}
+			if gotCount != count+1 || gotMissing != missing {
+				t.Fatalf("samples +%d, without timestamp +%v; want +1 and +0", gotCount-count, gotMissing-missing)
+			}
+			latency := time.Duration((gotSum - sum) * float64(time.Second))
+			if latency < tc.latency || latency > tc.latency+time.Minute {
+				t.Errorf("latency = %s, want about %s", latency, tc.latency)
+			}
+			wantSkewed := skewed
+			if tc.skewed {
+				wantSkewed++
+			}
+			if gotSkewed != wantSkewed {
+				t.Errorf("skewed +%v, want +%v", gotSkewed-skewed, wantSkewed-skewed)
+			}
+		})
+	}
+}
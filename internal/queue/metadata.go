@@ -0,0 +1,127 @@
+package queue
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/streamhive/video-catalog-api/internal/events"
+)
+
+// metadataFromDelivery captures a delivery's envelope so it survives quarantine
+// and replay. Explicit headers win over AMQP properties, which many publishers
+// leave unset.
+func metadataFromDelivery(msg amqp091.Delivery) events.Metadata {
+	md := events.Metadata{
+		RoutingKey:    msg.RoutingKey,
+		MessageID:     msg.MessageId,
+		CorrelationID: msg.CorrelationId,
+		Timestamp:     msg.Timestamp,
+		ProducedAt:    msg.Timestamp,
+		ReceivedAt:    time.Now().UTC(),
+		DeliveryTag:   msg.DeliveryTag,
+		Redelivered:   msg.Redelivered,
+		Headers:       plainTable(msg.Headers),
+	}
+	if v, ok := msg.Headers[events.HeaderCorrelationID].(string); ok && v != "" {
+		md.CorrelationID = v
+	}
+	if n, ok := headerInt(msg.Headers[events.HeaderRetryCount]); ok {
+		md.RetryCount = n
+	}
+	if t, ok := headerTime(msg.Headers[events.HeaderProducedAt]); ok {
+		md.ProducedAt = t
+	}
+	if t, ok := headerTime(msg.Headers[events.HeaderEventTime]); ok {
+		md.Timestamp = t
+	}
+	// Dead-lettered messages record where they originally came from
+	if deaths, ok := msg.Headers["x-death"].([]interface{}); ok && len(deaths) > 0 {
+		if death, ok := deaths[0].(amqp091.Table); ok {
+			if keys, ok := death["routing-keys"].([]interface{}); ok && len(keys) > 0 {
+				if key, ok := keys[0].(string); ok {
+					md.RoutingKey = key
+				}
+			}
+			if n, ok := headerInt(death["count"]); ok && n > md.RetryCount {
+				md.RetryCount = n
+			}
+		}
+	}
+	return md
+}
+
+// plainTable converts AMQP header values into JSON-friendly types
+func plainTable(t amqp091.Table) map[string]interface{} {
+	if len(t) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(t))
+	for k, v := range t {
+		out[k] = plainValue(v)
+	}
+	return out
+}
+
+func plainValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case amqp091.Table:
+		return plainTable(val)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i := range val {
+			out[i] = plainValue(val[i])
+		}
+		return out
+	case amqp091.Decimal:
+		return val.Value
+	case []byte:
+		return string(val)
+	case time.Time:
+		return val.UTC().Format(time.RFC3339Nano)
+	default:
+		return val
+	}
+}
+
+func headerInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int8:
+		return int(n), true
+	case int16:
+		return int(n), true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case uint8:
+		return int(n), true
+	case uint16:
+		return int(n), true
+	case uint32:
+		return int(n), true
+	case float64:
+		return int(n), true
+	case string:
+		i, err := strconv.Atoi(n)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func headerTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, !t.IsZero()
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		return parsed, err == nil
+	}
+	if n, ok := headerInt(v); ok && n > 0 {
+		return time.Unix(int64(n), 0).UTC(), true
+	}
+	return time.Time{}, false
+}
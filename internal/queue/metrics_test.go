@@ -0,0 +1,55 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/queue"
+)
+
+func eventsProcessed(outcome string) float64 {
+	return testutil.ToFloat64(metrics.EventsProcessedTotal.WithLabelValues(queue.SourceRabbitMQ, testAMQP.UploadedRoutingKey, outcome))
+}
+
+func handled(t *testing.T) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.EventHandleDuration.WithLabelValues(queue.SourceRabbitMQ, testAMQP.UploadedRoutingKey).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("read histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestConsumerEventMetrics(t *testing.T) {
+	broker := newFakeBroker()
+	consumer := broker.newConsumer(t)
+	// Without a quarantine a failed event is dead-lettered
+	go consumer.Start(context.Background(), queue.EventHandlers{Uploaded: func(_ context.Context, e *models.UploadedEvent) error {
+		if e.UploadID == "bad" {
+			return errors.New("no such user")
+		}
+		return nil
+	}})
+	ok, deadLettered, samples := eventsProcessed("ok"), eventsProcessed("dead_lettered"), handled(t)
+
+	broker.publish(testAMQP.UploadedQueue, `{"uploadId":"good","userId":"u"}`)
+	broker.publish(testAMQP.UploadedQueue, `{"uploadId":"bad","userId":"u"}`)
+	waitFor(t, "both deliveries to settle", func() bool { acked, nacked := broker.settled(); return acked == 1 && nacked == 1 })
+
+	if got := eventsProcessed("ok") - ok; got != 1 {
+		t.Errorf("ok events = +%v, want +1", got)
+	}
+	if got := eventsProcessed("dead_lettered") - deadLettered; got != 1 {
+		t.Errorf("dead-lettered events = +%v, want +1", got)
+	}
+	if got := handled(t) - samples; got != 2 {
+		t.Errorf("handle duration samples = +%d, want one per event", got)
+	}
+}
@@ -0,0 +1,36 @@
+package queue
+
+import (
+	"encoding/json"
+	"hash/fnv"
+)
+
+// orderingFields are the payload fields deliveries are serialized by: the upload
+// ID of video events, else the user ID of user events
+type orderingFields struct {
+	UploadID string `json:"uploadId"`
+	UserID   string `json:"user_id"`
+}
+
+// orderingKey is the key a delivery must be handled in order with, or "" if the
+// payload has none and may go to any worker
+func orderingKey(body []byte) string {
+	var f orderingFields
+	if err := json.Unmarshal(body, &f); err != nil {
+		return ""
+	}
+	if f.UploadID != "" {
+		return "upload:" + f.UploadID
+	}
+	if f.UserID != "" {
+		return "user:" + f.UserID
+	}
+	return ""
+}
+
+// laneFor picks the worker for key out of n
+func laneFor(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
@@ -0,0 +1,213 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+)
+
+// ErrPublisherClosed is returned by Publish after Close
+var ErrPublisherClosed = errors.New("publisher closed")
+
+// ErrPublishNacked means the broker refused responsibility for a message
+var ErrPublishNacked = errors.New("broker nacked the message")
+
+// errNotConnected is returned while the shared connection is being re-established
+var errNotConnected = errors.New("not connected to RabbitMQ")
+
+// defaultConfirmTimeout bounds the wait for a publisher confirm
+const defaultConfirmTimeout = 5 * time.Second
+
+// Publisher sends JSON messages to the streamhive exchange on a channel in confirm
+// mode: Publish returns only once the broker has acked the message, or fails on a
+// nack or when no confirm arrives within the confirm timeout. The channel is opened
+// lazily and, after a failed publish, dropped so the next publish opens a new one.
+type Publisher struct {
+	exchange string
+	// connection returns the connection to open the channel on. For a publisher
+	// sharing the consumer's connection it returns the consumer's current one;
+	// otherwise it dials and the publisher owns (and closes) the result.
+	connection     func() (*amqp091.Connection, error)
+	owned          bool
+	confirmTimeout time.Duration
+	logger         *zap.SugaredLogger
+
+	mu      sync.Mutex
+	conn    *amqp091.Connection
+	channel *amqp091.Channel
+	closed  bool
+}
+
+// NewPublisher creates a publisher with its own connection to cfg's broker and
+// exchange; nothing is dialed until the first publish. Prefer
+// Consumer.NewPublisher, which shares the consumer's connection.
+func NewPublisher(cfg config.AMQP, logger *zap.SugaredLogger) *Publisher {
+	return newPublisher(cfg.Exchange, logger, func() (*amqp091.Connection, error) { return dial(cfg) }, true)
+}
+
+// NewPublisher creates a publisher that opens its channel on the consumer's
+// connection, following it across reconnects. Publishes fail while the consumer
+// is reconnecting.
+func (c *Consumer) NewPublisher() *Publisher {
+	return newPublisher(c.cfg.Exchange, c.logger, c.currentConnection, false)
+}
+
+func newPublisher(exchange string, logger *zap.SugaredLogger, connection func() (*amqp091.Connection, error), owned bool) *Publisher {
+	return &Publisher{
+		exchange:       exchange,
+		connection:     connection,
+		owned:          owned,
+		confirmTimeout: defaultConfirmTimeout,
+		logger:         logger,
+	}
+}
+
+// SetConfirmTimeout sets how long Publish waits for the broker's confirm
+func (p *Publisher) SetConfirmTimeout(d time.Duration) {
+	if d > 0 {
+		p.confirmTimeout = d
+	}
+}
+
+// Publish sends body with the given routing key as a persistent JSON message
+// stamped with the publish time, and waits for the broker to confirm it
+func (p *Publisher) Publish(ctx context.Context, routingKey string, body []byte) error {
+	channel, confirm, err := p.send(ctx, routingKey, body)
+	if err != nil {
+		metrics.AMQPPublishesTotal.WithLabelValues("error").Inc()
+		return err
+	}
+	// Wait outside the lock so concurrent publishes share the channel's round trips
+	waitCtx, cancel := context.WithTimeout(ctx, p.confirmTimeout)
+	defer cancel()
+	acked, err := confirm.WaitContext(waitCtx)
+	if err != nil {
+		// The message's fate is unknown; start over on a fresh channel
+		p.drop(channel)
+		metrics.AMQPPublishesTotal.WithLabelValues("timeout").Inc()
+		return fmt.Errorf("publish %s: no confirm: %w", routingKey, err)
+	}
+	if !acked {
+		metrics.AMQPPublishesTotal.WithLabelValues("nacked").Inc()
+		return fmt.Errorf("publish %s: %w", routingKey, ErrPublishNacked)
+	}
+	metrics.AMQPPublishesTotal.WithLabelValues("acked").Inc()
+	return nil
+}
+
+// send publishes on the current channel, opening one if needed, and returns the
+// channel used with the pending confirm
+func (p *Publisher) send(ctx context.Context, routingKey string, body []byte) (*amqp091.Channel, *amqp091.DeferredConfirmation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, nil, ErrPublisherClosed
+	}
+	if p.channel == nil || p.channel.IsClosed() {
+		if err := p.connectLocked(); err != nil {
+			return nil, nil, err
+		}
+	}
+	now := time.Now().UTC()
+	headers := amqp091.Table{events.HeaderProducedAt: now.Format(time.RFC3339Nano)}
+	// Consumers continue the publishing request's or event's trace
+	injectTrace(ctx, headers)
+	confirm, err := p.channel.PublishWithDeferredConfirmWithContext(ctx, p.exchange, routingKey, false, false, amqp091.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp091.Persistent,
+		Timestamp:    now,
+		Headers:      headers,
+		Body:         body,
+	})
+	if err != nil {
+		p.dropLocked()
+		return nil, nil, fmt.Errorf("publish %s: %w", routingKey, err)
+	}
+	return p.channel, confirm, nil
+}
+
+func (p *Publisher) connectLocked() error {
+	p.dropLocked()
+	conn, err := p.connection()
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	closeConn := func() {
+		if p.owned {
+			conn.Close()
+		}
+	}
+	channel, err := conn.Channel()
+	if err != nil {
+		closeConn()
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	if err := channel.ExchangeDeclare(p.exchange, "topic", true, false, false, false, nil); err != nil {
+		channel.Close()
+		closeConn()
+		return fmt.Errorf("declare exchange: %w", err)
+	}
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		closeConn()
+		return fmt.Errorf("enable publisher confirms: %w", err)
+	}
+	if p.owned {
+		p.conn = conn
+	}
+	p.channel = channel
+	p.logger.Infow("Publisher connected to RabbitMQ", "exchange", p.exchange, "sharedConnection", !p.owned)
+	return nil
+}
+
+// drop discards channel if it is still the current one
+func (p *Publisher) drop(channel *amqp091.Channel) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.channel == channel {
+		p.dropLocked()
+	}
+}
+
+func (p *Publisher) dropLocked() {
+	if p.channel != nil {
+		p.channel.Close()
+	}
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	p.conn, p.channel = nil, nil
+}
+
+// Close closes the publisher's channel, and its connection if it owns one; later
+// publishes fail with ErrPublisherClosed
+func (p *Publisher) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.dropLocked()
+}
+
+// currentConnection is the consumer's live connection, for publishers sharing it
+func (c *Consumer) currentConnection() (*amqp091.Connection, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrConsumerClosed
+	}
+	// Publishers need a real broker connection, not a stand-in
+	broker, ok := c.conn.(brokerConnection)
+	if !ok || broker.IsClosed() {
+		return nil, errNotConnected
+	}
+	return broker.Connection, nil
+}
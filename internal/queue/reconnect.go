@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+)
+
+// reconnect re-dials until it succeeds, sleeping with exponential backoff and
+// jitter between attempts. It returns false if the consumer is closed meanwhile.
+func (c *Consumer) reconnect() bool {
+	for attempt := 1; ; attempt++ {
+		wait := c.backoff(attempt)
+		select {
+		case <-c.stop:
+			return false
+		case <-time.After(wait):
+		}
+		err := c.connect()
+		if err == nil {
+			metrics.AMQPReconnectsTotal.WithLabelValues("ok").Inc()
+			c.logger.Infow("Reconnected to RabbitMQ", "attempts", attempt)
+			return true
+		}
+		if c.isClosed() {
+			return false
+		}
+		metrics.AMQPReconnectsTotal.WithLabelValues("failed").Inc()
+		c.logger.Warnw("RabbitMQ reconnect failed", "error", err, "attempt", attempt, "retryIn", c.backoff(attempt+1))
+	}
+}
+
+// backoff is the delay before the given attempt: backoffMin doubled per attempt,
+// capped at backoffMax, with up to half of it randomized so replicas don't
+// reconnect in lockstep after a broker restart
+func (c *Consumer) backoff(attempt int) time.Duration {
+	d := c.backoffMax
+	if attempt < 32 {
+		if exp := c.backoffMin << (attempt - 1); exp > 0 && exp < c.backoffMax {
+			d = exp
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (c *Consumer) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// setState records the connection state; the caller must not hold c.mu
+func (c *Consumer) setState(state string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setStateLocked(state)
+}
+
+// setStateLocked records the connection state; the caller holds c.mu
+func (c *Consumer) setStateLocked(state string) {
+	if c.closed && state != StateClosed {
+		return
+	}
+	c.state = state
+	connected := 0.0
+	if state == StateConnected {
+		connected = 1
+	}
+	metrics.AMQPConnected.Set(connected)
+}
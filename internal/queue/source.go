@@ -0,0 +1,78 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// Event buses, as named by EVENT_BUS and in the source label of event metrics
+const (
+	SourceRabbitMQ = "rabbitmq"
+	SourceKafka    = "kafka"
+)
+
+// EventHandlers receive decoded video events from an EventSource
+type EventHandlers struct {
+	Uploaded        func(context.Context, *models.UploadedEvent) error
+	Transcoded      func(context.Context, *models.TranscodedEvent) error
+	TranscodeFailed func(context.Context, *models.TranscodeFailedEvent) error
+}
+
+// videoEvents reports whether h handles video events; a source started without
+// them consumes only what else it carries
+func (h EventHandlers) videoEvents() bool {
+	return h.Uploaded != nil || h.Transcoded != nil || h.TranscodeFailed != nil
+}
+
+// dispatch decodes body as an event of kind and passes it to the matching handler
+func (h EventHandlers) dispatch(ctx context.Context, kind string, body []byte) error {
+	switch kind {
+	case services.EventKindUploaded:
+		var event models.UploadedEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return fmt.Errorf("unmarshal uploaded: %w", err)
+		}
+		return h.Uploaded(ctx, &event)
+	case services.EventKindTranscoded:
+		var event models.TranscodedEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return fmt.Errorf("unmarshal transcoded: %w", err)
+		}
+		return h.Transcoded(ctx, &event)
+	case services.EventKindTranscodeFailed:
+		var event models.TranscodeFailedEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return fmt.Errorf("unmarshal transcode failed: %w", err)
+		}
+		return h.TranscodeFailed(ctx, &event)
+	}
+	return fmt.Errorf("unknown event kind %q", kind)
+}
+
+// VideoEventHandlers hands events to the video service
+func VideoEventHandlers(v *services.VideoService) EventHandlers {
+	return EventHandlers{
+		Uploaded:        v.HandleUploadedEvent,
+		Transcoded:      v.HandleTranscodedEvent,
+		TranscodeFailed: v.HandleTranscodeFailedEvent,
+	}
+}
+
+// EventSource is a message bus the catalog consumes video events from. Every source
+// decodes the same JSON payloads, keeps events for one upload in order, and labels
+// its metrics with Name.
+type EventSource interface {
+	Name() string
+	// Start consumes until the source is stopped or ctx is done
+	Start(ctx context.Context, handlers EventHandlers) error
+	// Stop stops consuming and waits, up to ctx's deadline, for events in flight
+	Stop(ctx context.Context) error
+	// Close stops at once, leaving events in flight to be redelivered
+	Close()
+}
+
+var _ EventSource = (*Consumer)(nil)
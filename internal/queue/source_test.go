@@ -0,0 +1,207 @@
+package queue_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/queue"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// fakeSource is an in-memory EventSource: deliver hands it a JSON payload, which
+// Start decodes and passes to the matching handler, one event at a time
+type fakeSource struct {
+	events  chan fakeEvent
+	stopped chan struct{}
+	once    sync.Once
+}
+
+type fakeEvent struct {
+	kind string
+	body []byte
+	// handled receives the handler's error
+	handled chan error
+}
+
+var _ queue.EventSource = (*fakeSource)(nil)
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{events: make(chan fakeEvent), stopped: make(chan struct{})}
+}
+
+func (s *fakeSource) Name() string { return "fake" }
+
+func (s *fakeSource) Start(ctx context.Context, handlers queue.EventHandlers) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.stopped:
+			return nil
+		case e := <-s.events:
+			e.handled <- dispatch(ctx, handlers, e.kind, e.body)
+		}
+	}
+}
+
+func dispatch(ctx context.Context, handlers queue.EventHandlers, kind string, body []byte) error {
+	switch kind {
+	case services.EventKindUploaded:
+		var event models.UploadedEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return err
+		}
+		return handlers.Uploaded(ctx, &event)
+	case services.EventKindTranscoded:
+		var event models.TranscodedEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return err
+		}
+		return handlers.Transcoded(ctx, &event)
+	case services.EventKindTranscodeFailed:
+		var event models.TranscodeFailedEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return err
+		}
+		return handlers.TranscodeFailed(ctx, &event)
+	}
+	return fmt.Errorf("unknown event kind %q", kind)
+}
+
+// deliver hands the source one event and returns the handler's error
+func (s *fakeSource) deliver(kind, body string) error {
+	handled := make(chan error, 1)
+	s.events <- fakeEvent{kind: kind, body: []byte(body), handled: handled}
+	return <-handled
+}
+
+func (s *fakeSource) Stop(context.Context) error {
+	s.Close()
+	return nil
+}
+
+func (s *fakeSource) Close() { s.once.Do(func() { close(s.stopped) }) }
+
+// sourceUnderTest is an EventSource with a way to publish to it and wait until the
+// event has been handled
+type sourceUnderTest struct {
+	source  queue.EventSource
+	publish func(t *testing.T, kind, body string)
+}
+
+var eventQueues = map[string]string{
+	services.EventKindUploaded:        testAMQP.UploadedQueue,
+	services.EventKindTranscoded:      testAMQP.TranscodedQueue,
+	services.EventKindTranscodeFailed: testAMQP.TranscodeFailedQueue,
+}
+
+func fakeSourceUnderTest(t *testing.T) sourceUnderTest {
+	s := newFakeSource()
+	t.Cleanup(s.Close)
+	return sourceUnderTest{source: s, publish: func(t *testing.T, kind, body string) {
+		t.Helper()
+		if err := s.deliver(kind, body); err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+	}}
+}
+
+func rabbitMQUnderTest(t *testing.T) sourceUnderTest {
+	broker := newFakeBroker()
+	published := 0
+	return sourceUnderTest{source: broker.newConsumer(t), publish: func(t *testing.T, kind, body string) {
+		t.Helper()
+		published++
+		broker.publish(eventQueues[kind], body)
+		waitFor(t, kind+" to be settled", func() bool { acked, nacked := broker.settled(); return acked+nacked == published })
+		if _, nacked := broker.settled(); nacked != 0 {
+			t.Fatalf("%s was rejected", kind)
+		}
+	}}
+}
+
+func kafkaUnderTest(t *testing.T) sourceUnderTest {
+	cluster := newFakeKafka(3)
+	var published int64
+	return sourceUnderTest{source: cluster.newSource(t, testKafka), publish: func(t *testing.T, kind, body string) {
+		t.Helper()
+		published++
+		cluster.produce(kafkaTopics[kind], "up-1", body)
+		waitFor(t, kind+" to be committed", func() bool { return cluster.totalCommitted() == published })
+	}}
+}
+
+// TestEventSourcesFeedVideoHandlers runs an upload's events through each source
+// into the real video handlers: whichever bus they arrive on, the service ends in
+// the same state
+func TestEventSourcesFeedVideoHandlers(t *testing.T) {
+	for name, newSource := range map[string]func(*testing.T) sourceUnderTest{
+		"fake":               fakeSourceUnderTest,
+		queue.SourceRabbitMQ: rabbitMQUnderTest,
+		queue.SourceKafka:    kafkaUnderTest,
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := dbtest.Open(t)
+			videos := services.NewVideoService(db, nil, videoSettings, zap.NewNop().Sugar())
+			s := newSource(t)
+			if s.source.Name() != name {
+				t.Errorf("Name() = %q, want %q", s.source.Name(), name)
+			}
+			started := make(chan error, 1)
+			go func() { started <- s.source.Start(context.Background(), queue.VideoEventHandlers(videos)) }()
+
+			uploaded := `{"uploadId":"up-1","userId":"u-1","title":"Holiday","tags":["beach"]}`
+			s.publish(t, services.EventKindUploaded, uploaded)
+			// A redelivered upload event is absorbed by the upsert
+			s.publish(t, services.EventKindUploaded, uploaded)
+			s.publish(t, services.EventKindTranscoded, `{"uploadId":"up-1","userId":"u-1","ready":true,
+				"hls":{"masterUrl":"https://cdn.example/up-1/master.m3u8"},"metadata":{"duration":12.5}}`)
+
+			var count int64
+			db.Model(&models.Video{}).Where("upload_id = ?", "up-1").Count(&count)
+			if count != 1 {
+				t.Fatalf("%d videos for up-1, want 1", count)
+			}
+			video, err := videos.GetVideoByUploadID(context.Background(), "up-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if video.Title != "Holiday" || video.UserID != "u-1" || video.Status != models.StatusReady ||
+				video.HLSMasterURL != "https://cdn.example/up-1/master.m3u8" || video.Duration != 12.5 {
+				t.Errorf("video = %+v", video)
+			}
+
+			if err := s.source.Stop(context.Background()); err != nil {
+				t.Fatalf("Stop: %v", err)
+			}
+			if err := <-started; err != nil {
+				t.Errorf("Start returned %v after Stop", err)
+			}
+		})
+	}
+}
+
+// TestEventSourceTranscodeFailed checks a failure event reaches the handler too
+func TestEventSourceTranscodeFailed(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, zap.NewNop().Sugar())
+	s := fakeSourceUnderTest(t)
+	go s.source.Start(context.Background(), queue.VideoEventHandlers(videos))
+
+	s.publish(t, services.EventKindUploaded, `{"uploadId":"up-1","userId":"u-1","title":"t"}`)
+	s.publish(t, services.EventKindTranscodeFailed, `{"uploadId":"up-1","userId":"u-1","errorMessage":"unsupported codec"}`)
+	video, err := videos.GetVideoByUploadID(context.Background(), "up-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if video.Status != models.StatusFailed {
+		t.Errorf("status %q, want failed", video.Status)
+	}
+}
@@ -0,0 +1,154 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/queue"
+)
+
+// blockingHandler is an Uploaded handler that signals entered and then holds until
+// release is closed or its context ends, returning the context's error if so
+type blockingHandler struct {
+	entered chan struct{}
+	release chan struct{}
+	ctxErr  chan error
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{entered: make(chan struct{}, 1), release: make(chan struct{}), ctxErr: make(chan error, 1)}
+}
+
+func (h *blockingHandler) handle(ctx context.Context, _ *models.UploadedEvent) error {
+	h.entered <- struct{}{}
+	select {
+	case <-h.release:
+		return nil
+	case <-ctx.Done():
+		h.ctxErr <- ctx.Err()
+		return ctx.Err()
+	}
+}
+
+// startBlocked starts consuming into h and waits for a delivery to be in h
+func startBlocked(t *testing.T, broker *fakeBroker, h *blockingHandler) (*queue.Consumer, <-chan error) {
+	t.Helper()
+	consumer := broker.newConsumer(t)
+	started := make(chan error, 1)
+	go func() { started <- consumer.Start(context.Background(), queue.EventHandlers{Uploaded: h.handle}) }()
+	broker.publish(testAMQP.UploadedQueue, `{"uploadId":"in-flight","userId":"u"}`)
+	select {
+	case <-h.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the delivery never reached the handler")
+	}
+	return consumer, started
+}
+
+func connClosed(b *fakeBroker) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.conn.closed
+}
+
+func TestStopAcksInFlightDelivery(t *testing.T) {
+	broker := newFakeBroker()
+	h := newBlockingHandler()
+	consumer, started := startBlocked(t, broker, h)
+
+	stopped := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stopped <- consumer.Stop(ctx)
+	}()
+
+	// Consumption is cancelled at once, so nothing more is delivered...
+	waitFor(t, "the consumer tags to be cancelled", func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return len(broker.consumers) == 0
+	})
+	broker.publish(testAMQP.UploadedQueue, `{"uploadId":"late","userId":"u"}`)
+	// ...but the connection stays up while the handler runs
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned %v with a handler in flight", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if connClosed(broker) {
+		t.Fatal("the connection closed with a handler in flight")
+	}
+
+	close(h.release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	// The in-flight delivery was acked before Stop returned, not left to redeliver
+	broker.mu.Lock()
+	acked, unacked, pending := len(broker.acked), len(broker.unacked), len(broker.pending[testAMQP.UploadedQueue])
+	broker.mu.Unlock()
+	if acked != 1 || unacked != 0 {
+		t.Errorf("%d acked and %d unacked when Stop returned, want 1 and 0", acked, unacked)
+	}
+	if pending != 1 {
+		t.Errorf("%d messages left in the queue, want the one published after Stop", pending)
+	}
+	if !connClosed(broker) || consumer.State() != queue.StateClosed {
+		t.Errorf("connection closed %v, state %s after Stop", connClosed(broker), consumer.State())
+	}
+	if err := <-started; err != nil {
+		t.Errorf("Start returned %v after Stop", err)
+	}
+}
+
+func TestStopDeadlineCancelsHandlers(t *testing.T) {
+	broker := newFakeBroker()
+	h := newBlockingHandler()
+	consumer, _ := startBlocked(t, broker, h)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := consumer.Stop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop = %v, want the deadline", err)
+	}
+	select {
+	case err := <-h.ctxErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("handler context ended with %v, want it cancelled", err)
+		}
+	default:
+		t.Fatal("the handler's context was not cancelled at the deadline")
+	}
+
+	// Cut short by shutdown rather than failed: the delivery goes back for redelivery
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if len(broker.nacked) != 0 || len(broker.acked) != 0 {
+		t.Errorf("%d acked and %d dead-lettered, want neither", len(broker.acked), len(broker.nacked))
+	}
+	if pending := broker.pending[testAMQP.UploadedQueue]; len(pending) != 1 || !pending[0].Redelivered {
+		t.Errorf("queue holds %+v, want the interrupted delivery requeued", pending)
+	}
+}
+
+func TestStopIdle(t *testing.T) {
+	broker := newFakeBroker()
+	consumer := broker.newConsumer(t)
+	// Never started: Stop just closes
+	if err := consumer.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if !connClosed(broker) || consumer.State() != queue.StateClosed {
+		t.Errorf("connection closed %v, state %s", connClosed(broker), consumer.State())
+	}
+	// A second Stop, or a Close after it, is a no-op
+	if err := consumer.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+	consumer.Close()
+}
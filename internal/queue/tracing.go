@@ -0,0 +1,49 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+)
+
+// headerCarrier lets the OpenTelemetry propagator read and write trace context in
+// AMQP message headers
+type headerCarrier amqp091.Table
+
+func (h headerCarrier) Get(key string) string {
+	switch v := h[key].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (h headerCarrier) Set(key, value string) { h[key] = value }
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// extractTrace continues the producer's trace, if its headers carry one
+func extractTrace(ctx context.Context, headers amqp091.Table) context.Context {
+	if headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, headerCarrier(headers))
+}
+
+// injectTrace adds ctx's trace context to outgoing headers
+func injectTrace(ctx context.Context, headers amqp091.Table) {
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(headers))
+}
@@ -0,0 +1,52 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/queue"
+	"github.com/streamhive/video-catalog-api/internal/spantest"
+	"github.com/streamhive/video-catalog-api/internal/tracing"
+)
+
+func TestConsumerContinuesPublishersTrace(t *testing.T) {
+	spans := spantest.Record(t)
+	broker := newFakeBroker()
+	consumer := broker.newConsumer(t)
+	handlerSpans := make(chan trace.SpanContext, 1)
+	go consumer.Start(context.Background(), queue.EventHandlers{Uploaded: func(ctx context.Context, _ *models.UploadedEvent) error {
+		handlerSpans <- trace.SpanContextFromContext(ctx)
+		return nil
+	}})
+
+	// What a publisher continuing its request's trace puts in the headers
+	ctx, publishing := tracing.Start(context.Background(), "upload request")
+	headers := amqp091.Table{}
+	queue.InjectTrace(ctx, headers)
+	publishing.End()
+	if headers["traceparent"] == nil {
+		t.Fatalf("headers = %v, want a traceparent", headers)
+	}
+	broker.publishDelivery(testAMQP.UploadedQueue, amqp091.Delivery{RoutingKey: testAMQP.UploadedRoutingKey, Headers: headers,
+		Body: []byte(`{"uploadId":"traced","userId":"u"}`)})
+	handler := <-handlerSpans
+	waitFor(t, "the delivery to be acked", func() bool { acked, _ := broker.settled(); return acked == 1 })
+
+	process := spantest.Named(spans, testAMQP.UploadedRoutingKey+" process")
+	if len(process) != 1 {
+		t.Fatalf("%d process spans, want 1", len(process))
+	}
+	span := process[0]
+	if span.SpanKind() != trace.SpanKindConsumer || span.Parent().SpanID() != publishing.SpanContext().SpanID() ||
+		span.SpanContext().TraceID() != publishing.SpanContext().TraceID() {
+		t.Errorf("process span %s (parent %s, trace %s), want a consumer span under the publisher's %s",
+			span.SpanKind(), span.Parent().SpanID(), span.SpanContext().TraceID(), publishing.SpanContext().SpanID())
+	}
+	if handler.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("handler ran under span %s, want the process span %s", handler.SpanID(), span.SpanContext().SpanID())
+	}
+}
@@ -0,0 +1,38 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/queue"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// TestConsumerDeletesUserContent checks a user.deleted event queues the purge of
+// the user's content as the system
+func TestConsumerDeletesUserContent(t *testing.T) {
+	db := dbtest.Open(t)
+	log := zap.NewNop().Sugar()
+	db.Create(&models.Video{UploadID: "up-1", UserID: "gone", Title: "t"})
+	broker := newFakeBroker()
+	consumer := broker.newConsumer(t)
+	consumer.SetContentDeletions(services.NewContentDeletionService(db, log, services.NewVideoService(db, nil, videoSettings, log), 10))
+	go consumer.Start(context.Background(), queue.EventHandlers{})
+
+	broker.publishDelivery(testAMQP.UserQueue, amqp091.Delivery{RoutingKey: testAMQP.UserDeletedRoutingKey, Body: []byte(`{"user_id":"gone"}`)})
+	waitFor(t, "the event to be acked", func() bool { acked, _ := broker.settled(); return acked == 1 })
+
+	var job models.UserContentDeletion
+	waitFor(t, "the content deletion to complete", func() bool {
+		db.Where("user_id = ?", "gone").First(&job)
+		return job.Status == models.DeletionCompleted
+	})
+	if job.Trigger != models.ContentDeletionTriggerEvent || job.ActorID != services.ActorSystem || job.VideosDeleted != 1 {
+		t.Errorf("job = %+v, want the event's deletion of one video by the system", job)
+	}
+}
@@ -0,0 +1,249 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/queue"
+)
+
+func TestOrderingKey(t *testing.T) {
+	tests := []struct {
+		body, want string
+	}{
+		{`{"uploadId":"up-1","userId":"u"}`, "upload:up-1"},
+		{`{"user_id":"u-1","export_id":7}`, "user:u-1"},
+		// A video event is keyed by its upload even when it names a user
+		{`{"uploadId":"up-1","user_id":"u-1"}`, "upload:up-1"},
+		{`{"event":"ping"}`, ""},
+		{`not json`, ""},
+	}
+	for _, tt := range tests {
+		if got := queue.OrderingKey([]byte(tt.body)); got != tt.want {
+			t.Errorf("OrderingKey(%s) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("upload:up-%d", i)
+		lane := queue.LaneFor(key, 7)
+		if lane < 0 || lane >= 7 || queue.LaneFor(key, 7) != lane {
+			t.Fatalf("LaneFor(%s, 7) = %d, want a stable lane in [0, 7)", key, lane)
+		}
+	}
+}
+
+// concurrencyProbe is an Uploaded handler that records, per upload, the order it
+// saw events in and how many handlers were running at once
+type concurrencyProbe struct {
+	delay   func() time.Duration
+	running atomic.Int64
+	peak    atomic.Int64
+	mu      sync.Mutex
+	seen    map[string][]string
+	// perKey counts handlers running for each upload; more than one is a violation
+	perKey     map[string]int
+	violations int
+}
+
+func newConcurrencyProbe(delay func() time.Duration) *concurrencyProbe {
+	return &concurrencyProbe{delay: delay, seen: map[string][]string{}, perKey: map[string]int{}}
+}
+
+func (p *concurrencyProbe) handle(_ context.Context, e *models.UploadedEvent) error {
+	n := p.running.Add(1)
+	defer p.running.Add(-1)
+	for peak := p.peak.Load(); n > peak && !p.peak.CompareAndSwap(peak, n); peak = p.peak.Load() {
+	}
+	p.mu.Lock()
+	p.perKey[e.UploadID]++
+	if p.perKey[e.UploadID] > 1 {
+		p.violations++
+	}
+	p.mu.Unlock()
+
+	time.Sleep(p.delay())
+
+	p.mu.Lock()
+	p.perKey[e.UploadID]--
+	p.seen[e.UploadID] = append(p.seen[e.UploadID], e.Title)
+	p.mu.Unlock()
+	return nil
+}
+
+// startConsumer runs a consumer with the given concurrency against broker
+func startConsumer(t *testing.T, broker *fakeBroker, prefetch, workers int, handlers queue.EventHandlers) {
+	t.Helper()
+	consumer := broker.newConsumer(t)
+	consumer.SetConcurrency(prefetch, workers)
+	go consumer.Start(context.Background(), handlers)
+	waitFor(t, "consuming to start", func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return len(broker.consumers) > 0
+	})
+}
+
+func TestConsumerWorkersKeepPerUploadOrder(t *testing.T) {
+	broker := newFakeBroker()
+	probe := newConcurrencyProbe(func() time.Duration { return time.Duration(rand.Intn(3)) * time.Millisecond })
+	startConsumer(t, broker, 16, 4, queue.EventHandlers{Uploaded: probe.handle})
+
+	const uploads, perUpload = 8, 10
+	for seq := 0; seq < perUpload; seq++ {
+		for u := 0; u < uploads; u++ {
+			broker.publish(testAMQP.UploadedQueue, fmt.Sprintf(`{"uploadId":"up-%d","userId":"u","title":"%02d"}`, u, seq))
+		}
+	}
+	waitFor(t, "every delivery to be acked", func() bool { acked, _ := broker.settled(); return acked == uploads*perUpload })
+
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+	if probe.violations != 0 {
+		t.Errorf("%d times two workers handled the same upload at once", probe.violations)
+	}
+	for u := 0; u < uploads; u++ {
+		titles := probe.seen[fmt.Sprintf("up-%d", u)]
+		for seq, title := range titles {
+			if title != fmt.Sprintf("%02d", seq) {
+				t.Fatalf("up-%d handled in order %v, want publication order", u, titles)
+			}
+		}
+	}
+	if peak := probe.peak.Load(); peak < 2 || peak > 4 {
+		t.Errorf("%d handlers ran at once, want between 2 and the 4 workers", peak)
+	}
+	broker.mu.Lock()
+	prefetch := broker.prefetch
+	broker.mu.Unlock()
+	if prefetch != 16 {
+		t.Errorf("QoS prefetch %d, want 16", prefetch)
+	}
+}
+
+func TestConsumerWorkersSettleTheirOwnDeliveries(t *testing.T) {
+	broker := newFakeBroker()
+	startConsumer(t, broker, 8, 4, queue.EventHandlers{Uploaded: func(_ context.Context, e *models.UploadedEvent) error {
+		if e.Title == "bad" {
+			return errors.New("constraint violation")
+		}
+		return nil
+	}})
+	for i := 0; i < 20; i++ {
+		title := "ok"
+		if i%5 == 0 {
+			title = "bad"
+		}
+		broker.publish(testAMQP.UploadedQueue, fmt.Sprintf(`{"uploadId":"up-%d","userId":"u","title":"%s"}`, i, title))
+	}
+	waitFor(t, "every delivery to be settled", func() bool { acked, nacked := broker.settled(); return acked+nacked == 20 })
+	if acked, nacked := broker.settled(); acked != 16 || nacked != 4 {
+		t.Errorf("%d acked and %d nacked, want 16 and 4", acked, nacked)
+	}
+}
+
+func TestSetConcurrency(t *testing.T) {
+	tests := []struct {
+		prefetch, workers int
+		wantPrefetch      int
+	}{
+		{16, 4, 16},
+		// Workers beyond the prefetch would idle, so it is raised
+		{2, 8, 8},
+		// Nonsense leaves the default of one at a time
+		{0, 4, 1},
+		{4, 0, 1},
+	}
+	for _, tt := range tests {
+		broker := newFakeBroker()
+		startConsumer(t, broker, tt.prefetch, tt.workers, queue.EventHandlers{Uploaded: (&uploadRecorder{}).handle})
+		broker.mu.Lock()
+		prefetch := broker.prefetch
+		broker.mu.Unlock()
+		if prefetch != tt.wantPrefetch {
+			t.Errorf("SetConcurrency(%d, %d): QoS prefetch %d, want %d", tt.prefetch, tt.workers, prefetch, tt.wantPrefetch)
+		}
+	}
+}
+
+// drainWith publishes n uploads to a consumer with workers workers whose handler
+// takes delay each, and returns how long it took to handle them all
+func drainWith(tb testing.TB, workers, n int, delay time.Duration) time.Duration {
+	tb.Helper()
+	broker := newFakeBroker()
+	consumer, err := queue.NewConsumerWithDial(testAMQP, zap.NewNop().Sugar(), broker.dial)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer consumer.Close()
+	consumer.SetConcurrency(2*workers, workers)
+	var handled atomic.Int64
+	go consumer.Start(context.Background(), queue.EventHandlers{Uploaded: func(context.Context, *models.UploadedEvent) error {
+		time.Sleep(delay)
+		handled.Add(1)
+		return nil
+	}})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		broker.mu.Lock()
+		started := len(broker.consumers) > 0
+		broker.mu.Unlock()
+		if started {
+			break
+		}
+		if time.Now().After(deadline) {
+			tb.Fatal("consumer never started")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		broker.publish(testAMQP.UploadedQueue, fmt.Sprintf(`{"uploadId":"up-%d","userId":"u"}`, i))
+	}
+	for handled.Load() < int64(n) {
+		if time.Now().After(deadline.Add(time.Minute)) {
+			tb.Fatalf("handled %d of %d", handled.Load(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return time.Since(start)
+}
+
+// TestConsumerThroughputScalesWithWorkers drains the same burst of DB-bound-like
+// events with one worker and with eight
+func TestConsumerThroughputScalesWithWorkers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing test")
+	}
+	const n, delay = 48, 5 * time.Millisecond
+	serial := drainWith(t, 1, n, delay)
+	pooled := drainWith(t, 8, n, delay)
+	if serial < n*delay {
+		t.Fatalf("one worker took %s for %d events of %s each", serial, n, delay)
+	}
+	// Upload IDs hash unevenly over the lanes, so this is well short of 8x
+	if pooled*3 > serial {
+		t.Errorf("8 workers took %s against one worker's %s, want at least 3x faster", pooled, serial)
+	}
+}
+
+func BenchmarkConsumerWorkers(b *testing.B) {
+	for _, workers := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			var total time.Duration
+			for i := 0; i < b.N; i++ {
+				total += drainWith(b, workers, 64, time.Millisecond)
+			}
+			b.ReportMetric(float64(64*b.N)/total.Seconds(), "events/s")
+		})
+	}
+}
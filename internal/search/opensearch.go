@@ -0,0 +1,385 @@
+// Package search is a small client for the OpenSearch REST API, covering what the
+// catalog needs: creating the video index, bulk writes and searches. It speaks
+// plain HTTP so tests can swap the transport.
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// ErrUnavailable means the cluster couldn't be reached or was too busy to answer;
+// callers fall back to the database
+var ErrUnavailable = errors.New("search cluster unavailable")
+
+// Document is a video as stored in the index. Only public videos are indexed.
+type Document struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Tags        []string  `json:"tags"`
+	Category    string    `json:"category"`
+	Status      string    `json:"status"`
+	Username    string    `json:"username"`
+	Duration    float64   `json:"duration"`
+	FileSize    int64     `json:"file_size"`
+	ViewCount   int64     `json:"view_count"`
+	LikeCount   int64     `json:"like_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DocumentFor builds the index document for video
+func DocumentFor(video *models.Video) Document {
+	tags := video.TagsList
+	if tags == nil {
+		tags = []string{}
+	}
+	return Document{
+		ID:          video.ID,
+		Title:       video.Title,
+		Description: video.Description,
+		Tags:        tags,
+		Category:    video.Category,
+		Status:      string(video.Status),
+		Username:    video.Username,
+		Duration:    video.Duration,
+		FileSize:    video.FileSize,
+		ViewCount:   video.ViewCount,
+		LikeCount:   video.LikeCount,
+		CreatedAt:   video.CreatedAt,
+	}
+}
+
+// Mapping is the index definition EnsureIndex creates. Tags, category and status
+// are exact-match keywords; title.raw is the keyword copy titles sort on.
+const Mapping = `{
+  "mappings": {
+    "dynamic": "strict",
+    "properties": {
+      "id":          {"type": "long"},
+      "title":       {"type": "text", "fields": {"raw": {"type": "keyword", "ignore_above": 256}}},
+      "description": {"type": "text"},
+      "tags":        {"type": "keyword"},
+      "category":    {"type": "keyword"},
+      "status":      {"type": "keyword"},
+      "username":    {"type": "keyword"},
+      "duration":    {"type": "double"},
+      "file_size":   {"type": "long"},
+      "view_count":  {"type": "long"},
+      "like_count":  {"type": "long"},
+      "created_at":  {"type": "date"}
+    }
+  }
+}`
+
+// Config addresses the cluster and the index
+type Config struct {
+	URL      string
+	Index    string
+	Username string
+	Password string
+	// Timeout bounds each request; searches must give up quickly enough for the
+	// database fallback to still answer in time
+	Timeout time.Duration
+}
+
+// Client talks to one index on an OpenSearch cluster
+type Client struct {
+	baseURL  string
+	index    string
+	username string
+	password string
+	http     *http.Client
+}
+
+// NewClient creates a client; a nil transport uses http.DefaultTransport
+func NewClient(cfg Config, transport http.RoundTripper) *Client {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Client{
+		baseURL:  strings.TrimRight(cfg.URL, "/"),
+		index:    cfg.Index,
+		username: cfg.Username,
+		password: cfg.Password,
+		http:     &http.Client{Transport: transport, Timeout: cfg.Timeout},
+	}
+}
+
+// Index is the name of the index the client writes to
+func (c *Client) Index() string { return c.index }
+
+// EnsureIndex creates the index with Mapping unless it already exists
+func (c *Client) EnsureIndex(ctx context.Context) error {
+	res, err := c.do(ctx, http.MethodHead, "/"+c.index, "", nil)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	if res.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("check index %s: status %d", c.index, res.StatusCode)
+	}
+	res, err = c.do(ctx, http.MethodPut, "/"+c.index, "application/json", strings.NewReader(Mapping))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		// Another replica may have created it first
+		if res.StatusCode == http.StatusBadRequest && bytes.Contains(body, []byte("resource_already_exists_exception")) {
+			return nil
+		}
+		return fmt.Errorf("create index %s: status %d: %s", c.index, res.StatusCode, body)
+	}
+	return nil
+}
+
+// Op is one write in a bulk request: the document to index, or a delete of ID
+type Op struct {
+	ID     uint
+	Delete bool
+	Doc    *Document
+}
+
+// Bulk applies ops in order in one request. A rejected op fails the call after
+// the rest were applied; deleting a document that isn't there is not an error.
+func (c *Client) Bulk(ctx context.Context, ops []Op) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, op := range ops {
+		id := strconv.FormatUint(uint64(op.ID), 10)
+		if op.Delete {
+			_ = enc.Encode(map[string]interface{}{"delete": map[string]string{"_index": c.index, "_id": id}})
+			continue
+		}
+		_ = enc.Encode(map[string]interface{}{"index": map[string]string{"_index": c.index, "_id": id}})
+		if err := enc.Encode(op.Doc); err != nil {
+			return fmt.Errorf("encode document %d: %w", op.ID, err)
+		}
+	}
+	res, err := c.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", &body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("bulk write: status %d: %s", res.StatusCode, msg)
+	}
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string          `json:"_id"`
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(bufio.NewReader(res.Body)).Decode(&result); err != nil {
+		return fmt.Errorf("decode bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+	failed, first := 0, ""
+	for _, item := range result.Items {
+		for action, r := range item {
+			if r.Error == nil || (action == "delete" && r.Status == http.StatusNotFound) {
+				continue
+			}
+			if failed == 0 {
+				first = fmt.Sprintf("%s %s: %s", action, r.ID, r.Error)
+			}
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("bulk write: %d of %d ops failed, first: %s", failed, len(ops), first)
+}
+
+// Sort fields a Query may order by
+const (
+	SortRelevance = ""
+	SortCreatedAt = "created_at"
+	SortTitle     = "title.raw"
+	SortDuration  = "duration"
+	SortFileSize  = "file_size"
+	SortViewCount = "view_count"
+)
+
+// Query is a search over the index. Text is matched fuzzily against title,
+// description and tags; the other fields filter exactly, as the SQL search does.
+type Query struct {
+	Text           string
+	Category       string
+	Status         string
+	Tags           []string
+	MinDuration    *float64
+	MaxDuration    *float64
+	UploadedAfter  *time.Time
+	UploadedBefore *time.Time
+	// SortField is one of the Sort constants; relevance falls back to newest first
+	// when there is no Text
+	SortField string
+	SortAsc   bool
+	From      int
+	Size      int
+}
+
+// Result is one page of matching video IDs, best first, and the total match count
+type Result struct {
+	IDs   []uint
+	Total int64
+}
+
+// Body is the search request body for q
+func (q Query) Body() map[string]interface{} {
+	var must interface{} = map[string]interface{}{"match_all": map[string]interface{}{}}
+	if q.Text != "" {
+		must = map[string]interface{}{"multi_match": map[string]interface{}{
+			"query":     q.Text,
+			"fields":    []string{"title^3", "tags^2", "description"},
+			"fuzziness": "AUTO",
+		}}
+	}
+	filter := []interface{}{}
+	term := func(field, value string) {
+		filter = append(filter, map[string]interface{}{"term": map[string]interface{}{field: value}})
+	}
+	if q.Category != "" {
+		term("category", q.Category)
+	}
+	if q.Status != "" {
+		term("status", q.Status)
+	}
+	for _, tag := range q.Tags {
+		term("tags", tag)
+	}
+	if q.MinDuration != nil || q.MaxDuration != nil {
+		bounds := map[string]interface{}{}
+		if q.MinDuration != nil {
+			bounds["gte"] = *q.MinDuration
+		}
+		if q.MaxDuration != nil {
+			bounds["lte"] = *q.MaxDuration
+		}
+		filter = append(filter, map[string]interface{}{"range": map[string]interface{}{"duration": bounds}})
+	}
+	if q.UploadedAfter != nil || q.UploadedBefore != nil {
+		bounds := map[string]interface{}{}
+		if q.UploadedAfter != nil {
+			bounds["gte"] = q.UploadedAfter.UTC().Format(time.RFC3339Nano)
+		}
+		if q.UploadedBefore != nil {
+			bounds["lte"] = q.UploadedBefore.UTC().Format(time.RFC3339Nano)
+		}
+		filter = append(filter, map[string]interface{}{"range": map[string]interface{}{"created_at": bounds}})
+	}
+
+	order := "desc"
+	if q.SortAsc {
+		order = "asc"
+	}
+	var sort []interface{}
+	switch {
+	case q.SortField != SortRelevance:
+		sort = append(sort, map[string]interface{}{q.SortField: map[string]string{"order": order}})
+	case q.Text != "":
+		sort = append(sort, "_score")
+	default:
+		sort = append(sort, map[string]interface{}{SortCreatedAt: map[string]string{"order": "desc"}})
+	}
+	// Same tie-break as the SQL path, so pages don't overlap
+	sort = append(sort, map[string]interface{}{"id": map[string]string{"order": order}})
+
+	return map[string]interface{}{
+		"from":             q.From,
+		"size":             q.Size,
+		"track_total_hits": true,
+		"_source":          false,
+		"query":            map[string]interface{}{"bool": map[string]interface{}{"must": must, "filter": filter}},
+		"sort":             sort,
+	}
+}
+
+// Search runs q and returns the matching IDs
+func (c *Client) Search(ctx context.Context, q Query) (*Result, error) {
+	body, err := json.Marshal(q.Body())
+	if err != nil {
+		return nil, fmt.Errorf("encode search: %w", err)
+	}
+	res, err := c.do(ctx, http.MethodPost, "/"+c.index+"/_search", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("search: status %d: %s", res.StatusCode, msg)
+	}
+	var parsed struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("decode search response: %w", err)
+	}
+	out := &Result{IDs: make([]uint, 0, len(parsed.Hits.Hits)), Total: parsed.Hits.Total.Value}
+	for _, hit := range parsed.Hits.Hits {
+		id, err := strconv.ParseUint(hit.ID, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("search hit with non-numeric id %q", hit.ID)
+		}
+		out.IDs = append(out.IDs, uint(id))
+	}
+	return out, nil
+}
+
+// do sends one request. Transport failures and 429/5xx answers come back as
+// ErrUnavailable; the latter are returned with their body already closed.
+func (c *Client) do(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("build %s %s: %w", method, path, err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %s: %v", ErrUnavailable, method, path, err)
+	}
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError {
+		res.Body.Close()
+		return nil, fmt.Errorf("%w: %s %s: status %d", ErrUnavailable, method, path, res.StatusCode)
+	}
+	return res, nil
+}
@@ -0,0 +1,262 @@
+package search_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/search"
+)
+
+// transport answers each request with respond, recording what was sent
+type transport struct {
+	requests []recorded
+	respond  func(req *http.Request, body string) (*http.Response, error)
+}
+
+type recorded struct {
+	method, path, contentType, body string
+	user, password                  string
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	user, password, _ := req.BasicAuth()
+	t.requests = append(t.requests, recorded{req.Method, req.URL.Path, req.Header.Get("Content-Type"), string(body), user, password})
+	return t.respond(req, string(body))
+}
+
+func reply(status int, body string) (*http.Response, error) {
+	return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+func newClient(respond func(*http.Request, string) (*http.Response, error)) (*search.Client, *transport) {
+	tr := &transport{respond: respond}
+	return search.NewClient(search.Config{URL: "http://search:9200/", Index: "videos", Username: "catalog", Password: "pw", Timeout: time.Second}, tr), tr
+}
+
+func TestDocumentMatchesMapping(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	video := &models.Video{
+		ID: 7, Title: "Holiday", Description: "beach", TagsList: []string{"travel"}, Category: "vlog",
+		Status: models.StatusReady, Username: "olive", Duration: 61.5, FileSize: 1 << 20, ViewCount: 3, LikeCount: 2,
+		CreatedAt: created, VideoCodec: "h264", UserID: "owner",
+	}
+	doc := search.DocumentFor(video)
+	want := search.Document{ID: 7, Title: "Holiday", Description: "beach", Tags: []string{"travel"}, Category: "vlog",
+		Status: "ready", Username: "olive", Duration: 61.5, FileSize: 1 << 20, ViewCount: 3, LikeCount: 2, CreatedAt: created}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("DocumentFor = %+v, want %+v", doc, want)
+	}
+	// Untagged videos index an empty array, not null
+	if doc := search.DocumentFor(&models.Video{ID: 8}); doc.Tags == nil {
+		t.Error("nil tags in the document")
+	}
+
+	// The mapping is strict, so every document field must be mapped and nothing else
+	var mapping struct {
+		Mappings struct {
+			Dynamic    string                     `json:"dynamic"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(search.Mapping), &mapping); err != nil {
+		t.Fatalf("Mapping isn't JSON: %v", err)
+	}
+	raw, _ := json.Marshal(doc)
+	var fields map[string]interface{}
+	json.Unmarshal(raw, &fields)
+	var docKeys, mapped []string
+	for k := range fields {
+		docKeys = append(docKeys, k)
+	}
+	for k := range mapping.Mappings.Properties {
+		mapped = append(mapped, k)
+	}
+	sort.Strings(docKeys)
+	sort.Strings(mapped)
+	if mapping.Mappings.Dynamic != "strict" || !reflect.DeepEqual(docKeys, mapped) {
+		t.Errorf("document fields %v, mapped %v (dynamic %q)", docKeys, mapped, mapping.Mappings.Dynamic)
+	}
+}
+
+func TestEnsureIndex(t *testing.T) {
+	ctx := context.Background()
+
+	exists, tr := newClient(func(*http.Request, string) (*http.Response, error) { return reply(http.StatusOK, "") })
+	if err := exists.EnsureIndex(ctx); err != nil || len(tr.requests) != 1 || tr.requests[0].method != http.MethodHead || tr.requests[0].path != "/videos" {
+		t.Errorf("existing index: %v, requests %+v", err, tr.requests)
+	}
+
+	missing, tr := newClient(func(req *http.Request, _ string) (*http.Response, error) {
+		if req.Method == http.MethodHead {
+			return reply(http.StatusNotFound, "")
+		}
+		return reply(http.StatusOK, `{"acknowledged":true}`)
+	})
+	if err := missing.EnsureIndex(ctx); err != nil || len(tr.requests) != 2 {
+		t.Fatalf("missing index: %v, requests %+v", err, tr.requests)
+	}
+	if put := tr.requests[1]; put.method != http.MethodPut || put.path != "/videos" || put.body != search.Mapping || put.user != "catalog" || put.password != "pw" {
+		t.Errorf("create request = %+v", put)
+	}
+
+	// Another replica got there first
+	raced, _ := newClient(func(req *http.Request, _ string) (*http.Response, error) {
+		if req.Method == http.MethodHead {
+			return reply(http.StatusNotFound, "")
+		}
+		return reply(http.StatusBadRequest, `{"error":{"type":"resource_already_exists_exception"}}`)
+	})
+	if err := raced.EnsureIndex(ctx); err != nil {
+		t.Errorf("index created concurrently: %v", err)
+	}
+
+	refused, _ := newClient(func(req *http.Request, _ string) (*http.Response, error) {
+		if req.Method == http.MethodHead {
+			return reply(http.StatusNotFound, "")
+		}
+		return reply(http.StatusBadRequest, `{"error":{"type":"mapper_parsing_exception"}}`)
+	})
+	if err := refused.EnsureIndex(ctx); err == nil || errors.Is(err, search.ErrUnavailable) {
+		t.Errorf("rejected mapping: %v, want a plain error", err)
+	}
+
+	down, _ := newClient(func(*http.Request, string) (*http.Response, error) { return reply(http.StatusServiceUnavailable, "") })
+	if err := down.EnsureIndex(ctx); !errors.Is(err, search.ErrUnavailable) {
+		t.Errorf("503: %v, want ErrUnavailable", err)
+	}
+}
+
+func TestBulk(t *testing.T) {
+	ctx := context.Background()
+	client, tr := newClient(func(*http.Request, string) (*http.Response, error) {
+		return reply(http.StatusOK, `{"errors":true,"items":[{"index":{"_id":"1","status":201}},{"delete":{"_id":"2","status":404,"error":{"type":"not_found"}}}]}`)
+	})
+	doc := search.Document{ID: 1, Title: "a", Tags: []string{}}
+	if err := client.Bulk(ctx, []search.Op{{ID: 1, Doc: &doc}, {ID: 2, Delete: true}}); err != nil {
+		t.Fatalf("Bulk: %v, want a missing delete to be fine", err)
+	}
+	req := tr.requests[0]
+	if req.method != http.MethodPost || req.path != "/_bulk" || req.contentType != "application/x-ndjson" {
+		t.Errorf("bulk request = %+v", req)
+	}
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(req.body))
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("bulk line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 3 || !strings.HasSuffix(req.body, "\n") {
+		t.Fatalf("bulk body = %q, want action, document, action", req.body)
+	}
+	if !reflect.DeepEqual(lines[0], map[string]interface{}{"index": map[string]interface{}{"_index": "videos", "_id": "1"}}) ||
+		lines[1]["title"] != "a" ||
+		!reflect.DeepEqual(lines[2], map[string]interface{}{"delete": map[string]interface{}{"_index": "videos", "_id": "2"}}) {
+		t.Errorf("bulk lines = %v", lines)
+	}
+
+	if err := client.Bulk(ctx, nil); err != nil || len(tr.requests) != 1 {
+		t.Errorf("empty bulk: %v, %d requests", err, len(tr.requests))
+	}
+
+	rejected, _ := newClient(func(*http.Request, string) (*http.Response, error) {
+		return reply(http.StatusOK, `{"errors":true,"items":[{"index":{"_id":"1","status":201}},{"index":{"_id":"3","status":400,"error":{"type":"strict_dynamic_mapping_exception"}}}]}`)
+	})
+	if err := rejected.Bulk(ctx, []search.Op{{ID: 1, Doc: &doc}, {ID: 3, Doc: &doc}}); err == nil || !strings.Contains(err.Error(), "1 of 2 ops failed") {
+		t.Errorf("rejected op: %v", err)
+	}
+
+	busy, _ := newClient(func(*http.Request, string) (*http.Response, error) { return reply(http.StatusTooManyRequests, "") })
+	if err := busy.Bulk(ctx, []search.Op{{ID: 1, Delete: true}}); !errors.Is(err, search.ErrUnavailable) {
+		t.Errorf("429: %v, want ErrUnavailable", err)
+	}
+}
+
+func TestQueryBody(t *testing.T) {
+	minDuration, after := 60.0, time.Date(2024, 1, 1, 0, 0, 0, 0, time.FixedZone("CET", 3600))
+	tests := []struct {
+		name  string
+		query search.Query
+		want  string
+	}{
+		{"everything, newest first", search.Query{Size: 20},
+			`{"from":0,"size":20,"track_total_hits":true,"_source":false,
+			  "query":{"bool":{"must":{"match_all":{}},"filter":[]}},
+			  "sort":[{"created_at":{"order":"desc"}},{"id":{"order":"desc"}}]}`},
+		{"text by relevance", search.Query{Text: "holliday", From: 40, Size: 20},
+			`{"from":40,"size":20,"track_total_hits":true,"_source":false,
+			  "query":{"bool":{"must":{"multi_match":{"query":"holliday","fields":["title^3","tags^2","description"],"fuzziness":"AUTO"}},"filter":[]}},
+			  "sort":["_score",{"id":{"order":"desc"}}]}`},
+		{"filters and an explicit sort", search.Query{Category: "vlog", Status: "ready", Tags: []string{"a", "b"}, MinDuration: &minDuration,
+			UploadedAfter: &after, SortField: search.SortTitle, SortAsc: true, Size: 10},
+			`{"from":0,"size":10,"track_total_hits":true,"_source":false,
+			  "query":{"bool":{"must":{"match_all":{}},"filter":[
+			    {"term":{"category":"vlog"}},{"term":{"status":"ready"}},{"term":{"tags":"a"}},{"term":{"tags":"b"}},
+			    {"range":{"duration":{"gte":60}}},{"range":{"created_at":{"gte":"2023-12-31T23:00:00Z"}}}]}},
+			  "sort":[{"title.raw":{"order":"asc"}},{"id":{"order":"asc"}}]}`},
+	}
+	for _, tt := range tests {
+		got, _ := json.Marshal(tt.query.Body())
+		var g, w interface{}
+		json.Unmarshal(got, &g)
+		if err := json.Unmarshal([]byte(tt.want), &w); err != nil {
+			t.Fatalf("%s: bad want: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(g, w) {
+			t.Errorf("%s: body %s", tt.name, got)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	client, tr := newClient(func(*http.Request, string) (*http.Response, error) {
+		return reply(http.StatusOK, `{"hits":{"total":{"value":42},"hits":[{"_id":"9"},{"_id":"3"}]}}`)
+	})
+	result, err := client.Search(ctx, search.Query{Text: "cats", Size: 2})
+	if err != nil || result.Total != 42 || !reflect.DeepEqual(result.IDs, []uint{9, 3}) {
+		t.Fatalf("Search = %+v, %v", result, err)
+	}
+	if req := tr.requests[0]; req.method != http.MethodPost || req.path != "/videos/_search" || !strings.Contains(req.body, `"fuzziness":"AUTO"`) {
+		t.Errorf("search request = %+v", req)
+	}
+
+	bad, _ := newClient(func(*http.Request, string) (*http.Response, error) {
+		return reply(http.StatusOK, `{"hits":{"total":{"value":1},"hits":[{"_id":"abc"}]}}`)
+	})
+	if _, err := bad.Search(ctx, search.Query{}); err == nil || errors.Is(err, search.ErrUnavailable) {
+		t.Errorf("non-numeric hit: %v, want a plain error", err)
+	}
+	malformed, _ := newClient(func(*http.Request, string) (*http.Response, error) {
+		return reply(http.StatusBadRequest, `{"error":{"type":"parsing_exception"}}`)
+	})
+	if _, err := malformed.Search(ctx, search.Query{}); err == nil || errors.Is(err, search.ErrUnavailable) {
+		t.Errorf("400: %v, want a plain error", err)
+	}
+	unreachable, _ := newClient(func(*http.Request, string) (*http.Response, error) {
+		return nil, errors.New("dial tcp: connection refused")
+	})
+	if _, err := unreachable.Search(ctx, search.Query{}); !errors.Is(err, search.ErrUnavailable) {
+		t.Errorf("transport failure: %v, want ErrUnavailable", err)
+	}
+	failing, _ := newClient(func(*http.Request, string) (*http.Response, error) { return reply(http.StatusBadGateway, "") })
+	if _, err := failing.Search(ctx, search.Query{}); !errors.Is(err, search.ErrUnavailable) {
+		t.Errorf("502: %v, want ErrUnavailable", err)
+	}
+}
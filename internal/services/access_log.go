@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// AccessLogService records accesses to non-public videos. Writes go through a
+// buffered channel and are flushed in batches so request latency is unaffected.
+type AccessLogService struct {
+	db            *gorm.DB
+	logger        *zap.SugaredLogger
+	entries       chan models.VideoAccessLog
+	batchSize     int
+	flushInterval time.Duration
+	done          chan struct{}
+}
+
+// NewAccessLogService creates an access log service with a buffer of bufferSize entries
+func NewAccessLogService(db *gorm.DB, logger *zap.SugaredLogger, bufferSize, batchSize int, flushInterval time.Duration) *AccessLogService {
+	return &AccessLogService{
+		db:            db,
+		logger:        logger,
+		entries:       make(chan models.VideoAccessLog, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+	}
+}
+
+// Record queues an access for a video. Public videos are never logged. When the
+// buffer is full the entry is dropped rather than blocking playback.
+func (s *AccessLogService) Record(video *models.Video, viewerID, viewerType, endpoint, platform string) {
+	if s == nil || video.IsPublic() {
+		return
+	}
+	entry := models.VideoAccessLog{
+		VideoID:    video.ID,
+		ViewerID:   viewerID,
+		ViewerType: viewerType,
+		Endpoint:   endpoint,
+		Platform:   platform,
+		AccessedAt: time.Now().UTC(),
+	}
+	select {
+	case s.entries <- entry:
+	default:
+		metrics.AccessLogEntriesTotal.WithLabelValues("dropped").Inc()
+	}
+}
+
+// Run flushes buffered entries until ctx is cancelled, then drains what's left
+func (s *AccessLogService) Run(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.VideoAccessLog, 0, s.batchSize)
+	for {
+		select {
+		case entry := <-s.entries:
+			batch = append(batch, entry)
+			if len(batch) >= s.batchSize {
+				batch = s.flush(batch)
+			}
+		case <-ticker.C:
+			batch = s.flush(batch)
+		case <-ctx.Done():
+			for {
+				select {
+				case entry := <-s.entries:
+					batch = append(batch, entry)
+				default:
+					s.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// Wait blocks until Run has drained the buffer after cancellation
+func (s *AccessLogService) Wait() { <-s.done }
+
+func (s *AccessLogService) flush(batch []models.VideoAccessLog) []models.VideoAccessLog {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := s.db.CreateInBatches(batch, s.batchSize).Error; err != nil {
+		metrics.AccessLogEntriesTotal.WithLabelValues("failed").Add(float64(len(batch)))
+		s.logger.Errorw("Failed to write access log batch", "error", err, "entries", len(batch))
+	} else {
+		metrics.AccessLogEntriesTotal.WithLabelValues("written").Add(float64(len(batch)))
+	}
+	return batch[:0]
+}
+
+// List returns a video's access log newest first, optionally filtered by viewer
+func (s *AccessLogService) List(ctx context.Context, videoID uint, viewerID string, page, perPage int) ([]models.VideoAccessLog, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.VideoAccessLog{}).Where("video_id = ?", videoID)
+	if viewerID != "" {
+		query = query.Where("viewer_id = ?", viewerID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count access log: %w", err)
+	}
+	var out []models.VideoAccessLog
+	if err := query.Order("accessed_at DESC, id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&out).Error; err != nil {
+		return nil, 0, fmt.Errorf("list access log: %w", err)
+	}
+	return out, total, nil
+}
+
+// Prune deletes entries older than retention
+func (s *AccessLogService) Prune(ctx context.Context, retention time.Duration) (int64, error) {
+	res := s.db.WithContext(ctx).Where("accessed_at < ?", time.Now().UTC().Add(-retention)).Delete(&models.VideoAccessLog{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("prune access log: %w", res.Error)
+	}
+	if res.RowsAffected > 0 {
+		s.logger.Infow("Pruned access log", "deleted", res.RowsAffected, "retention", retention)
+	}
+	return res.RowsAffected, nil
+}
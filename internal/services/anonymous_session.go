@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// AnonymousData is an engagement table that may hold rows under an anon: identity.
+// Features that accept anonymous sessions register their table here so its rows
+// count against the per-session cap, move to the account on merge and are purged
+// with the session.
+type AnonymousData struct {
+	Name   string
+	Table  string
+	Column string
+	// Merge rewrites from's rows to to. Nil means a plain UPDATE of Column; tables
+	// with a unique key per user must supply one that resolves collisions.
+	Merge func(tx *gorm.DB, from, to string) (int64, error)
+}
+
+// AnonymousSessionService issues and verifies signed anonymous session tokens and
+// manages the data held under them
+type AnonymousSessionService struct {
+	db        *gorm.DB
+	logger    *zap.SugaredLogger
+	secret    []byte
+	ttl       time.Duration
+	retention time.Duration
+	maxRows   int64
+
+	mu   sync.RWMutex
+	data []AnonymousData
+}
+
+// NewAnonymousSessionService creates the service. Tokens expire after ttl, sessions
+// untouched for retention are purged with their data, and each session may hold at
+// most maxRows rows across all registered tables.
+func NewAnonymousSessionService(db *gorm.DB, logger *zap.SugaredLogger, secret []byte, ttl, retention time.Duration, maxRows int64) *AnonymousSessionService {
+	return &AnonymousSessionService{db: db, logger: logger, secret: secret, ttl: ttl, retention: retention, maxRows: maxRows}
+}
+
+// RegisterData adds a table holding anonymous rows
+func (s *AnonymousSessionService) RegisterData(d AnonymousData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = append(s.data, d)
+}
+
+func (s *AnonymousSessionService) tables() []AnonymousData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]AnonymousData(nil), s.data...)
+}
+
+// Issue creates a new anonymous session and returns its token and expiry
+func (s *AnonymousSessionService) Issue(ctx context.Context) (string, *models.AnonymousSession, time.Time, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("generate session id: %w", err)
+	}
+	now := time.Now().UTC()
+	session := &models.AnonymousSession{ID: hex.EncodeToString(raw), CreatedAt: now, LastSeenAt: now}
+	if err := s.db.WithContext(ctx).Create(session).Error; err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("create anonymous session: %w", err)
+	}
+	expires := now.Add(s.ttl)
+	metrics.AnonymousSessionsTotal.WithLabelValues("issued").Inc()
+	return s.sign(session.ID, expires), session, expires, nil
+}
+
+// sign builds "<base64(id.expiry)>.<base64(hmac)>"
+func (s *AnonymousSessionService) sign(id string, expires time.Time) string {
+	body := id + "." + strconv.FormatInt(expires.Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(body)) + "." + base64.RawURLEncoding.EncodeToString(s.mac([]byte(body)))
+}
+
+func (s *AnonymousSessionService) mac(body []byte) []byte {
+	m := hmac.New(sha256.New, s.secret)
+	m.Write(body)
+	return m.Sum(nil)[:16]
+}
+
+// Verify checks a token's signature and expiry and returns its session ID
+func (s *AnonymousSessionService) Verify(token string) (string, error) {
+	bodyPart, sigPart, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", fmt.Errorf("%w: malformed", ErrInvalidAnonymousSession)
+	}
+	body, err := base64.RawURLEncoding.DecodeString(bodyPart)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed", ErrInvalidAnonymousSession)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigPart)
+	if err != nil || !hmac.Equal(sig, s.mac(body)) {
+		return "", fmt.Errorf("%w: bad signature", ErrInvalidAnonymousSession)
+	}
+	id, expiry, ok := strings.Cut(string(body), ".")
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if !ok || err != nil || id == "" {
+		return "", fmt.Errorf("%w: malformed", ErrInvalidAnonymousSession)
+	}
+	if time.Now().After(time.Unix(unix, 0)) {
+		return "", fmt.Errorf("%w: expired", ErrInvalidAnonymousSession)
+	}
+	return id, nil
+}
+
+// Touch marks a session as active, recreating its row if retention already purged
+// it. Call it whenever anonymous engagement data is written.
+func (s *AnonymousSessionService) Touch(ctx context.Context, sessionID string) error {
+	now := time.Now().UTC()
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"last_seen_at": now}),
+	}).Create(&models.AnonymousSession{ID: sessionID, CreatedAt: now, LastSeenAt: now}).Error
+	if err != nil {
+		return fmt.Errorf("touch anonymous session: %w", err)
+	}
+	return nil
+}
+
+// CheckQuota returns ErrAnonymousQuota if an anonymous identity already holds
+// maxRows rows across the registered tables. Real user IDs are never limited.
+func (s *AnonymousSessionService) CheckQuota(ctx context.Context, identity string) error {
+	if !models.IsAnonymousIdentity(identity) || s.maxRows <= 0 {
+		return nil
+	}
+	var total int64
+	for _, d := range s.tables() {
+		var n int64
+		if err := s.db.WithContext(ctx).Table(d.Table).Where(d.Column+" = ?", identity).Count(&n).Error; err != nil {
+			return fmt.Errorf("count %s: %w", d.Name, err)
+		}
+		total += n
+	}
+	if total >= s.maxRows {
+		return fmt.Errorf("%s: %w", identity, ErrAnonymousQuota)
+	}
+	return nil
+}
+
+// MergeResult reports what a merge moved
+type MergeResult struct {
+	SessionID string           `json:"session_id"`
+	UserID    string           `json:"user_id"`
+	Rows      map[string]int64 `json:"rows"`
+	// AlreadyMerged is set when the session had been merged into this user before
+	AlreadyMerged bool `json:"already_merged"`
+}
+
+// Merge moves every row held by the token's session to userID. Repeating a merge
+// into the same user is harmless; merging into a different user fails with
+// ErrAnonymousSessionMerged.
+func (s *AnonymousSessionService) Merge(ctx context.Context, token, userID string) (*MergeResult, error) {
+	sessionID, err := s.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	from := models.AnonymousIdentity(sessionID)
+	result := &MergeResult{SessionID: sessionID, UserID: userID, Rows: map[string]int64{}}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var session models.AnonymousSession
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&session, "id = ?", sessionID).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return fmt.Errorf("load anonymous session: %w", err)
+		}
+		if err == nil && session.MergedInto != "" {
+			if session.MergedInto != userID {
+				return fmt.Errorf("session %s: %w", sessionID, ErrAnonymousSessionMerged)
+			}
+			result.AlreadyMerged = true
+		}
+
+		for _, d := range s.tables() {
+			var n int64
+			if d.Merge != nil {
+				n, err = d.Merge(tx, from, userID)
+			} else {
+				res := tx.Table(d.Table).Where(d.Column+" = ?", from).Update(d.Column, userID)
+				n, err = res.RowsAffected, res.Error
+			}
+			if err != nil {
+				return fmt.Errorf("merge %s: %w", d.Name, err)
+			}
+			result.Rows[d.Name] = n
+		}
+
+		now := time.Now().UTC()
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"merged_into", "merged_at", "last_seen_at"}),
+		}).Create(&models.AnonymousSession{ID: sessionID, CreatedAt: now, LastSeenAt: now, MergedInto: userID, MergedAt: &now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !result.AlreadyMerged {
+		metrics.AnonymousSessionsTotal.WithLabelValues("merged").Inc()
+	}
+	s.logger.Infow("Anonymous session merged", "sessionID", sessionID, "userID", userID, "rows", result.Rows)
+	return result, nil
+}
+
+// anonymousPurgeBatch bounds how many sessions one purge transaction removes
+const anonymousPurgeBatch = 1000
+
+// PurgeExpired deletes sessions untouched for the retention period together with
+// the rows they still hold, in batches
+func (s *AnonymousSessionService) PurgeExpired(ctx context.Context) (int64, error) {
+	cutoff := time.Now().UTC().Add(-s.retention)
+	var purged int64
+	for {
+		n, err := s.purgeBatch(ctx, cutoff)
+		purged += n
+		if err != nil || n < anonymousPurgeBatch {
+			if purged > 0 {
+				metrics.AnonymousSessionsTotal.WithLabelValues("purged").Add(float64(purged))
+				s.logger.Infow("Purged expired anonymous sessions", "sessions", purged, "retention", s.retention)
+			}
+			return purged, err
+		}
+	}
+}
+
+func (s *AnonymousSessionService) purgeBatch(ctx context.Context, cutoff time.Time) (int64, error) {
+	var ids []string
+	if err := s.db.WithContext(ctx).Model(&models.AnonymousSession{}).
+		Where("last_seen_at < ?", cutoff).Limit(anonymousPurgeBatch).Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("find expired anonymous sessions: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	identities := make([]string, len(ids))
+	for i, id := range ids {
+		identities[i] = models.AnonymousIdentity(id)
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, d := range s.tables() {
+			if err := tx.Exec("DELETE FROM "+d.Table+" WHERE "+d.Column+" IN ?", identities).Error; err != nil {
+				return fmt.Errorf("purge %s: %w", d.Name, err)
+			}
+		}
+		return tx.Where("id IN ?", ids).Delete(&models.AnonymousSession{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
@@ -0,0 +1,193 @@
+package services_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestAnonymousTokens(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	sessions := services.NewAnonymousSessionService(db, nopLogger(), []byte("secret"), time.Hour, 24*time.Hour, 0)
+	token, session, expires, err := sessions.Issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := sessions.Verify(token); err != nil || id != session.ID {
+		t.Fatalf("Verify = %q, %v; want %q", id, err, session.ID)
+	}
+	if until := time.Until(expires); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("expires in %v, want the TTL", until)
+	}
+
+	body, sig, _ := strings.Cut(token, ".")
+	// Another session's ID under the original signature
+	forged := base64.RawURLEncoding.EncodeToString([]byte("0123456789abcdef." + strings.SplitN(decode(t, body), ".", 2)[1]))
+	flipped := []byte(sig)
+	flipped[0] ^= 1
+	other := services.NewAnonymousSessionService(db, nopLogger(), []byte("other secret"), time.Hour, 24*time.Hour, 0)
+	otherToken, _, _, err := other.Issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := services.NewAnonymousSessionService(db, nopLogger(), []byte("secret"), -time.Second, 24*time.Hour, 0)
+	expiredToken, _, _, err := expired.Issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, bad := range map[string]string{
+		"tampered body":      forged + "." + sig,
+		"tampered signature": body + "." + string(flipped),
+		"other secret":       otherToken,
+		"expired":            expiredToken,
+		"no signature":       body,
+		"not base64":         "!!." + sig,
+	} {
+		if _, err := sessions.Verify(bad); !errors.Is(err, services.ErrInvalidAnonymousSession) {
+			t.Errorf("%s: %v, want ErrInvalidAnonymousSession", name, err)
+		}
+	}
+	if _, err := sessions.Merge(ctx, expiredToken, "u-1"); !errors.Is(err, services.ErrInvalidAnonymousSession) {
+		t.Errorf("merging an expired token: %v", err)
+	}
+}
+
+func decode(t *testing.T, s string) string {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// TestAnonymousMergeTwice merges a session's views into an account that viewed
+// one of the same videos: the shared day folds into the user's row, and merging
+// again moves and adds nothing
+func TestAnonymousMergeTwice(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	sessions := services.NewAnonymousSessionService(db, nopLogger(), []byte("secret"), time.Hour, 24*time.Hour, 0)
+	views := services.NewViewService(db, nopLogger(), time.Hour)
+	views.SetAnonymousSessions(sessions)
+	shared := createVideo(t, db, models.Video{Title: "shared"})
+	only := createVideo(t, db, models.Video{Title: "anonymous only"})
+
+	token, session, _, err := sessions.Issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anon := models.AnonymousIdentity(session.ID)
+	for _, view := range []struct {
+		video  uint
+		viewer string
+	}{{shared.ID, "u-1"}, {shared.ID, anon}, {only.ID, anon}} {
+		if _, err := views.RecordView(ctx, view.video, view.viewer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	userViews := func() map[uint]int64 {
+		t.Helper()
+		var rows []models.VideoView
+		db.Where("viewer = ?", "u-1").Find(&rows)
+		out := map[uint]int64{}
+		for _, r := range rows {
+			out[r.VideoID] += r.Views
+		}
+		return out
+	}
+	result, err := sessions.Merge(ctx, token, "u-1")
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if result.AlreadyMerged || result.Rows["views"] != 2 {
+		t.Errorf("first merge = %+v, want 2 view rows moved", result)
+	}
+	want := map[uint]int64{shared.ID: 2, only.ID: 1}
+	if got := userViews(); got[shared.ID] != want[shared.ID] || got[only.ID] != want[only.ID] {
+		t.Errorf("user's views after merging: %v, want %v", got, want)
+	}
+
+	again, err := sessions.Merge(ctx, token, "u-1")
+	if err != nil {
+		t.Fatalf("second Merge: %v", err)
+	}
+	if !again.AlreadyMerged || again.Rows["views"] != 0 {
+		t.Errorf("second merge = %+v, want already merged with nothing moved", again)
+	}
+	if got := userViews(); got[shared.ID] != want[shared.ID] || got[only.ID] != want[only.ID] {
+		t.Errorf("user's views after merging twice: %v, want %v", got, want)
+	}
+	var left int64
+	db.Model(&models.VideoView{}).Where("viewer = ?", anon).Count(&left)
+	if left != 0 {
+		t.Errorf("%d rows left under the anonymous identity", left)
+	}
+	// Counted views on the videos themselves don't change on merge
+	var video models.Video
+	db.First(&video, shared.ID)
+	if video.ViewCount != 2 {
+		t.Errorf("view_count %d, want 2", video.ViewCount)
+	}
+
+	if _, err := sessions.Merge(ctx, token, "u-2"); !errors.Is(err, services.ErrAnonymousSessionMerged) {
+		t.Errorf("merging into another user: %v, want ErrAnonymousSessionMerged", err)
+	}
+}
+
+// TestAnonymousRowCap fills an anonymous session's quota across registered tables:
+// the next write is refused, while signed-in users are never limited
+func TestAnonymousRowCap(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	sessions := services.NewAnonymousSessionService(db, nopLogger(), []byte("secret"), time.Hour, 24*time.Hour, 3)
+	views := services.NewViewService(db, nopLogger(), time.Hour)
+	views.SetAnonymousSessions(sessions)
+	services.NewWatchProgressService(db, nopLogger(), time.Second).SetAnonymousSessions(sessions)
+	var videos []*models.Video
+	for i := 0; i < 4; i++ {
+		videos = append(videos, createVideo(t, db, models.Video{Title: fmt.Sprint(i)}))
+	}
+	_, session, _, err := sessions.Issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anon := models.AnonymousIdentity(session.ID)
+
+	// One saved position and two views reach the cap of three rows
+	db.Create(&models.WatchProgress{UserID: anon, VideoID: videos[0].ID, PositionSeconds: 10})
+	for _, v := range videos[:2] {
+		if _, err := views.RecordView(ctx, v.ID, anon); err != nil {
+			t.Fatalf("view under the cap: %v", err)
+		}
+	}
+	if _, err := views.RecordView(ctx, videos[2].ID, anon); !errors.Is(err, services.ErrAnonymousQuota) {
+		t.Errorf("view past the cap: %v, want ErrAnonymousQuota", err)
+	}
+	var video models.Video
+	db.First(&video, videos[2].ID)
+	if video.ViewCount != 0 {
+		t.Errorf("refused view was counted")
+	}
+	// The cap is per identity
+	_, second, _, _ := sessions.Issue(ctx)
+	if _, err := views.RecordView(ctx, videos[2].ID, models.AnonymousIdentity(second.ID)); err != nil {
+		t.Errorf("another session's first view: %v", err)
+	}
+	for _, v := range videos {
+		if _, err := views.RecordView(ctx, v.ID, "u-1"); err != nil {
+			t.Errorf("signed-in view: %v", err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// AuditService persists audit entries for privileged actions
+type AuditService struct {
+	db     *gorm.DB
+	logger *zap.SugaredLogger
+}
+
+// NewAuditService creates an audit service
+func NewAuditService(db *gorm.DB, logger *zap.SugaredLogger) *AuditService {
+	return &AuditService{db: db, logger: logger}
+}
+
+// Record writes an audit entry synchronously so callers can refuse the action if it fails
+func (s *AuditService) Record(ctx context.Context, entry *models.AuditLog) error {
+	if err := s.db.WithContext(ctx).Create(entry).Error; err != nil {
+		return fmt.Errorf("write audit entry: %w", err)
+	}
+	return nil
+}
+
+// SetOutcome records how an action audited as started ended. It runs after the
+// action, so a failure is only logged.
+func (s *AuditService) SetOutcome(ctx context.Context, entry *models.AuditLog, outcome string) {
+	if err := s.db.WithContext(ctx).Model(entry).Update("outcome", outcome).Error; err != nil {
+		s.logger.Errorw("Failed to record audit outcome", "error", err, "auditID", entry.ID, "outcome", outcome)
+		return
+	}
+	entry.Outcome = outcome
+}
+
+// List returns audit entries newest first, optionally filtered by action, actor, subject and target
+func (s *AuditService) List(ctx context.Context, action, actorID, subjectID, target string, page, perPage int) ([]models.AuditLog, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.AuditLog{})
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if actorID != "" {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if subjectID != "" {
+		query = query.Where("subject_id = ?", subjectID)
+	}
+	if target != "" {
+		query = query.Where("target = ?", target)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count audit log: %w", err)
+	}
+	var out []models.AuditLog
+	if err := query.Order("id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&out).Error; err != nil {
+		return nil, 0, fmt.Errorf("list audit log: %w", err)
+	}
+	return out, total, nil
+}
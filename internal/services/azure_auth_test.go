@@ -0,0 +1,157 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// devStorageKey is the well-known development storage key; the fake servers don't
+// check signatures
+const devStorageKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+// azureConfigs are the same account at url in each mode that needs no network to
+// build a client
+func azureConfigs(url string) map[string]config.Azure {
+	return map[string]config.Azure{
+		config.AzureAuthConnectionString: {
+			AuthMode:         config.AzureAuthConnectionString,
+			ConnectionString: "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;AccountKey=" + devStorageKey + ";BlobEndpoint=" + url + "/devstoreaccount1;",
+			Container:        "videos",
+		},
+		config.AzureAuthSharedKey: {
+			AuthMode:   config.AzureAuthSharedKey,
+			Account:    "devstoreaccount1",
+			Key:        devStorageKey,
+			AccountURL: url + "/devstoreaccount1/",
+			Container:  "videos",
+		},
+	}
+}
+
+func TestAzureClientAuthModes(t *testing.T) {
+	backend := &blobServer{blobs: map[string]bool{"raw/a.mp4": true, "raw/b.mp4": true}, gone: map[string]bool{}, pageSize: 2, deletes: map[string]int{}}
+	srv := httptest.NewServer(backend)
+	defer srv.Close()
+	breaker := config.Breaker{Reset: time.Minute, ConsecutiveFailures: 2, Retries: 2, AttemptTimeout: 5 * time.Second}
+
+	blob := map[string]string{config.AzureAuthConnectionString: "raw/a.mp4", config.AzureAuthSharedKey: "raw/b.mp4"}
+	for mode, cfg := range azureConfigs(srv.URL) {
+		adapter, err := services.NewAzureClientAdapter(cfg, breaker)
+		if err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		if adapter.AuthMode() != mode {
+			t.Errorf("%s: AuthMode() = %q", mode, adapter.AuthMode())
+		}
+		if err := adapter.DeleteBlob(context.Background(), blob[mode]); err != nil {
+			t.Errorf("%s: DeleteBlob: %v", mode, err)
+		}
+	}
+	if len(backend.blobs) != 0 {
+		t.Errorf("blobs left: %v", backend.blobs)
+	}
+
+	// auto resolves to the mode the credentials allow
+	auto := azureConfigs(srv.URL)[config.AzureAuthSharedKey]
+	auto.AuthMode = config.AzureAuthAuto
+	if adapter, err := services.NewAzureClientAdapter(auto, breaker); err != nil || adapter.AuthMode() != config.AzureAuthSharedKey {
+		t.Errorf("auto: %v, %v; want shared_key", adapter, err)
+	}
+}
+
+func TestAzureClientCredentialErrors(t *testing.T) {
+	breaker := config.Breaker{Reset: time.Minute, ConsecutiveFailures: 5, AttemptTimeout: time.Second}
+	tests := []struct {
+		name string
+		cfg  config.Azure
+		want string
+	}{
+		{"nothing", config.Azure{}, "missing Azure storage credentials: AZURE_AUTH_MODE=auto"},
+		{"managed identity without an account", config.Azure{AuthMode: config.AzureAuthManagedIdentity},
+			"missing Azure storage credentials: AZURE_AUTH_MODE=managed_identity: AZURE_STORAGE_ACCOUNT (or AZURE_STORAGE_ACCOUNT_URL) required"},
+		{"key that isn't base64", config.Azure{AuthMode: config.AzureAuthSharedKey, Account: "acct", Key: "not base64!"},
+			"AZURE_AUTH_MODE=shared_key: invalid AZURE_STORAGE_KEY for account acct"},
+		{"malformed connection string", config.Azure{AuthMode: config.AzureAuthConnectionString, ConnectionString: "nonsense"},
+			"AZURE_AUTH_MODE=connection_string: failed to create client from connection string"},
+	}
+	for _, tt := range tests {
+		_, err := services.NewAzureClientAdapter(tt.cfg, breaker)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+// deniedServer refuses every request with 403, which azblob doesn't retry itself,
+// so each request it sees is one attempt by the storage guard
+type deniedServer struct {
+	mu       sync.Mutex
+	requests int
+}
+
+func (s *deniedServer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	s.mu.Unlock()
+	w.Header().Set("x-ms-error-code", "AuthorizationFailure")
+	w.WriteHeader(http.StatusForbidden)
+}
+
+func (s *deniedServer) take() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.requests
+	s.requests = 0
+	return n
+}
+
+// TestAzureClientGuardSameInEveryMode runs the same failing deletes through a client
+// of each mode: the retries and the point the breaker opens don't depend on how
+// the client authenticates
+func TestAzureClientGuardSameInEveryMode(t *testing.T) {
+	breaker := config.Breaker{Reset: time.Minute, ConsecutiveFailures: 3, Retries: 1, AttemptTimeout: 5 * time.Second}
+	type call struct {
+		requests int
+		open     bool
+	}
+	runs := map[string][]call{}
+	for mode := range azureConfigs("") {
+		backend := &deniedServer{}
+		srv := httptest.NewServer(backend)
+		adapter, err := services.NewAzureClientAdapter(azureConfigs(srv.URL)[mode], breaker)
+		if err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		for i := 0; i < 3; i++ {
+			err := adapter.DeleteBlob(context.Background(), "raw/a.mp4")
+			if err == nil {
+				t.Fatalf("%s: delete %d succeeded against a server that refuses everything", mode, i)
+			}
+			runs[mode] = append(runs[mode], call{backend.take(), errors.Is(err, gobreaker.ErrOpenState)})
+		}
+		srv.Close()
+	}
+
+	want := runs[config.AzureAuthConnectionString]
+	// One retry per delete until the third consecutive failure trips the breaker,
+	// after which nothing reaches the server
+	if len(want) != 3 || want[0] != (call{2, false}) || want[1] != (call{1, true}) || want[2] != (call{0, true}) {
+		t.Fatalf("connection string: %+v", want)
+	}
+	for i, got := range runs[config.AzureAuthSharedKey] {
+		if got != want[i] {
+			t.Errorf("shared key delete %d: %+v, connection string %+v", i, got, want[i])
+		}
+	}
+}
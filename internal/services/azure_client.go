@@ -2,132 +2,166 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"strings"
+	"io"
 	"time"
-	"strconv"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
-	"github.com/sony/gobreaker"
-)
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
 
-// Helper function to read secret from file or fallback to environment variable
-func getSecret(filePath, envVar string) string {
-	if data, err := ioutil.ReadFile(filePath); err == nil {
-		return strings.TrimSpace(string(data))
-	}
-	return os.Getenv(envVar)
-}
+	"github.com/streamhive/video-catalog-api/internal/config"
+)
 
-// AzureClientAdapter wraps Azure storage client for video deletion
+// AzureClientAdapter is the StorageClient for Azure Blob Storage
 type AzureClientAdapter struct {
 	service   *azblob.Client
 	container string
-	breaker   *gobreaker.CircuitBreaker
+	authMode  string
+	guard     *storageGuard
 }
 
-// NewAzureClientAdapterFromEnv creates an Azure client from environment variables
-func NewAzureClientAdapterFromEnv() (*AzureClientAdapter, error) {
-	container := getSecret("/mnt/secrets-store/azure-storage-raw-container", "AZURE_BLOB_CONTAINER")
-	if container == "" {
-		container = "uploadservicecontainer"
-	}
+// azureStorageScope is the token scope for Blob Storage
+const azureStorageScope = "https://storage.azure.com/.default"
 
-	acct := getSecret("/mnt/secrets-store/azure-storage-account", "AZURE_STORAGE_ACCOUNT")
-	connStr := getSecret("/mnt/secrets-store/azure-storage-connection-string", "AZURE_STORAGE_CONNECTION_STRING")
-	key := getSecret("/mnt/secrets-store/azure-storage-key", "AZURE_STORAGE_KEY")
+// azureTokenTimeout bounds the token request that checks a managed identity
+const azureTokenTimeout = 10 * time.Second
 
-	var svc *azblob.Client
-	var err error
+// NewAzureClientAdapter creates an Azure client from cfg's credentials, in the mode
+// cfg.AuthMode selects, calling through the breaker and retry policy cfg describes.
+// Every mode shares that policy.
+func NewAzureClientAdapter(cfg config.Azure, breaker config.Breaker) (*AzureClientAdapter, error) {
+	mode, err := cfg.ResolveAuthMode()
+	if err != nil {
+		return nil, fmt.Errorf("missing Azure storage credentials: %w", err)
+	}
 
-	// Try connection string first
-	if connStr != "" {
-		svc, err = azblob.NewClientFromConnectionString(connStr, nil)
+	var svc *azblob.Client
+	switch mode {
+	case config.AzureAuthConnectionString:
+		svc, err = azblob.NewClientFromConnectionString(cfg.ConnectionString, nil)
+		if err != nil {
+			return nil, fmt.Errorf("AZURE_AUTH_MODE=%s: failed to create client from connection string: %w", mode, err)
+		}
+	case config.AzureAuthSharedKey:
+		cred, err := azblob.NewSharedKeyCredential(cfg.Account, cfg.Key)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create client from connection string: %w", err)
+			return nil, fmt.Errorf("AZURE_AUTH_MODE=%s: invalid AZURE_STORAGE_KEY for account %s: %w", mode, cfg.Account, err)
 		}
-	} else if acct != "" && key != "" {
-		// Use account + key
-		cred, err := azblob.NewSharedKeyCredential(acct, key)
+		svc, err = azblob.NewClientWithSharedKeyCredential(cfg.ServiceURL(), cred, nil)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create credentials: %w", err)
+			return nil, fmt.Errorf("AZURE_AUTH_MODE=%s: failed to create client: %w", mode, err)
 		}
-		serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", acct)
-		svc, err = azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+	case config.AzureAuthManagedIdentity:
+		svc, err = newManagedIdentityClient(cfg)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create client: %w", err)
+			return nil, fmt.Errorf("AZURE_AUTH_MODE=%s: %w", mode, err)
 		}
-	} else {
-		return nil, fmt.Errorf("missing Azure storage credentials - need either AZURE_STORAGE_CONNECTION_STRING or AZURE_STORAGE_ACCOUNT+AZURE_STORAGE_KEY")
 	}
 
-	// Circuit breaker settings from env (optional)
-	cbTimeout := 10 * time.Second
-	if v := os.Getenv("CATALOG_CB_RESET_MS"); v != "" {
-		if d, err := time.ParseDuration(v + "ms"); err == nil { cbTimeout = d }
+	return &AzureClientAdapter{
+		service:   svc,
+		container: cfg.Container,
+		authMode:  mode,
+		guard:     newStorageGuard("azure-client", breaker),
+	}, nil
+}
+
+// newManagedIdentityClient creates a client for cfg's account that authenticates
+// with DefaultAzureCredential: workload identity in AKS, else the environment or
+// the VM's managed identity
+func newManagedIdentityClient(cfg config.Azure) (*azblob.Client, error) {
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DefaultAzureCredential: %w", err)
 	}
-	cbFailures := uint32(5)
-	if v := os.Getenv("CATALOG_CB_CONSECUTIVE_FAILS"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 { cbFailures = uint32(n) }
+	// Ask for a token now, so a misconfigured identity fails here, with azidentity's
+	// account of each credential it tried, rather than on the first delete
+	ctx, cancel := context.WithTimeout(context.Background(), azureTokenTimeout)
+	defer cancel()
+	if _, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{azureStorageScope}}); err != nil {
+		return nil, fmt.Errorf("no storage token for %s (for workload identity, check the pod's service account "+
+			"is federated and AZURE_CLIENT_ID, AZURE_TENANT_ID and AZURE_FEDERATED_TOKEN_FILE are injected): %w", cfg.ServiceURL(), err)
 	}
-	breaker := gobreaker.NewCircuitBreaker(gobreaker.Settings{
-		Name:    "azure-client",
-		Timeout: cbTimeout,
-		ReadyToTrip: func(c gobreaker.Counts) bool { return c.ConsecutiveFailures >= cbFailures },
-	})
-
-	return &AzureClientAdapter{ service: svc, container: container, breaker: breaker }, nil
+	svc, err := azblob.NewClient(cfg.ServiceURL(), cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for %s: %w", cfg.ServiceURL(), err)
+	}
+	return svc, nil
 }
 
+// AuthMode is the authentication mode the client was created with
+func (a *AzureClientAdapter) AuthMode() string { return a.authMode }
+
 // DeleteBlob deletes a single blob from Azure storage
 func (a *AzureClientAdapter) DeleteBlob(ctx context.Context, blobPath string) error {
-	attemptTimeout := 3 * time.Second
-	if v := os.Getenv("CATALOG_AZURE_TIMEOUT_MS"); v != "" {
-		if d, err := time.ParseDuration(v + "ms"); err == nil { attemptTimeout = d }
-	}
-	retries := 2
-	if v := os.Getenv("CATALOG_AZURE_RETRIES"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 { retries = n }
-	}
-	var last error
-	backoff := 200 * time.Millisecond
-	for i := 0; i <= retries; i++ {
-		c, cancel := context.WithTimeout(ctx, attemptTimeout)
-		_, err := a.breaker.Execute(func() (interface{}, error) {
-			return a.service.DeleteBlob(c, a.container, blobPath, nil)
-		})
-		cancel()
-		if err == nil { return nil }
-		last = err
-		if i < retries { time.Sleep(backoff); if backoff < 1500*time.Millisecond { backoff *= 2 } }
-	}
-	return last
+	return a.guard.retry(ctx, "DeleteBlob", blobPath, func(c context.Context) error {
+		_, err := a.service.DeleteBlob(c, a.container, blobPath, nil)
+		// Already gone is what we wanted; don't let it trip the breaker or burn retries
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil
+		}
+		return err
+	})
 }
 
 // DeleteBlobsWithPrefix deletes all blobs with the given prefix from Azure storage
-func (a *AzureClientAdapter) DeleteBlobsWithPrefix(ctx context.Context, prefix string) error {
-	pager := a.service.NewListBlobsFlatPager(a.container, &azblob.ListBlobsFlatOptions{ Prefix: &prefix })
-	for pager.More() {
-		// Wrap each page retrieval with breaker
-		pageAny, err := a.breaker.Execute(func() (interface{}, error) { return pager.NextPage(ctx) })
-		if err != nil { return fmt.Errorf("failed to list blobs with prefix %s: %w", prefix, err) }
-		page := pageAny.(azblob.ListBlobsFlatResponse)
-		for _, b := range page.Segment.BlobItems {
-			if b.Name != nil {
-				if err := a.DeleteBlob(ctx, *b.Name); err != nil { return fmt.Errorf("failed to delete blob %s: %w", *b.Name, err) }
-			}
+// and returns how many it deleted, including those deleted before a failure
+func (a *AzureClientAdapter) DeleteBlobsWithPrefix(ctx context.Context, prefix string) (int, error) {
+	return deleteAllPages(ctx, prefix, a.DeleteBlobPage)
+}
+
+// prefixDeletePageSize is how many blobs one DeleteBlobPage call lists and deletes
+const prefixDeletePageSize = 500
+
+// DeleteBlobPage lists one page of blobs under prefix, starting at marker ("" for
+// the first page), and deletes them. It returns the marker of the next page, ""
+// once the listing is exhausted, and how many blobs it deleted. A caller that saves
+// the marker after each page can resume a failed cleanup where it stopped. A marker
+// the service no longer accepts fails with ErrStaleMarker.
+func (a *AzureClientAdapter) DeleteBlobPage(ctx context.Context, prefix, marker string) (string, int, error) {
+	opts := &azblob.ListBlobsFlatOptions{Prefix: &prefix, MaxResults: to.Ptr(int32(prefixDeletePageSize))}
+	if marker != "" {
+		opts.Marker = &marker
+	}
+	pager := a.service.NewListBlobsFlatPager(a.container, opts)
+	if !pager.More() {
+		return "", 0, nil
+	}
+	pageAny, err := a.guard.execute(ctx, "ListBlobs", prefix, func() (interface{}, error) { return pager.NextPage(ctx) })
+	if err != nil {
+		if marker != "" && bloberror.HasCode(err, bloberror.InvalidQueryParameterValue) {
+			return "", 0, fmt.Errorf("list blobs with prefix %s: %w", prefix, ErrStaleMarker)
+		}
+		return "", 0, fmt.Errorf("failed to list blobs with prefix %s: %w", prefix, err)
+	}
+	page := pageAny.(azblob.ListBlobsFlatResponse)
+	deleted := 0
+	for _, b := range page.Segment.BlobItems {
+		if b.Name == nil {
+			continue
+		}
+		if err := a.DeleteBlob(ctx, *b.Name); err != nil {
+			return "", deleted, fmt.Errorf("failed to delete blob %s: %w", *b.Name, err)
 		}
+		deleted++
+	}
+	if page.NextMarker == nil {
+		return "", deleted, nil
 	}
-	return nil
+	return *page.NextMarker, deleted, nil
 }
 
 // BlobExists checks if a blob exists in Azure storage
 func (a *AzureClientAdapter) BlobExists(ctx context.Context, blobPath string) (bool, error) {
 	pager := a.service.NewListBlobsFlatPager(a.container, &azblob.ListBlobsFlatOptions{ Prefix: &blobPath })
 	if pager.More() {
-		pageAny, err := a.breaker.Execute(func() (interface{}, error) { return pager.NextPage(ctx) })
+		pageAny, err := a.guard.execute(ctx, "BlobExists", blobPath, func() (interface{}, error) { return pager.NextPage(ctx) })
 		if err != nil { return false, fmt.Errorf("failed to check blob existence: %w", err) }
 		page := pageAny.(azblob.ListBlobsFlatResponse)
 		for _, b := range page.Segment.BlobItems {
@@ -136,3 +170,64 @@ func (a *AzureClientAdapter) BlobExists(ctx context.Context, blobPath string) (b
 	}
 	return false, nil
 }
+
+// GenerateSASURL returns a read-only SAS URL for blobPath that expires after ttl.
+// Signing needs the account key, from the connection string or AZURE_STORAGE_KEY, so
+// it fails under managed identity.
+func (a *AzureClientAdapter) GenerateSASURL(ctx context.Context, blobPath string, ttl time.Duration) (string, error) {
+	var keyErr error
+	url, err := a.guard.execute(ctx, "GetSASURL", blobPath, func() (interface{}, error) {
+		blob := a.service.ServiceClient().NewContainerClient(a.container).NewBlobClient(blobPath)
+		url, err := blob.GetSASURL(sas.BlobPermissions{Read: true}, time.Now().UTC().Add(ttl), nil)
+		// A missing key is configuration, not an outage; don't let it trip the breaker
+		if errors.Is(err, bloberror.MissingSharedKeyCredential) {
+			keyErr = err
+			return "", nil
+		}
+		return url, err
+	})
+	if err == nil {
+		err = keyErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("sign blob %s: %w", blobPath, err)
+	}
+	return url.(string), nil
+}
+
+// DownloadBlob reads a blob of at most maxBytes into memory
+func (a *AzureClientAdapter) DownloadBlob(ctx context.Context, blobPath string, maxBytes int64) ([]byte, error) {
+	c, cancel := context.WithTimeout(ctx, a.guard.attemptTimeout)
+	defer cancel()
+	data, err := a.guard.execute(ctx, "DownloadBlob", blobPath, func() (interface{}, error) {
+		resp, err := a.service.DownloadStream(c, a.container, blobPath, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("download blob %s: %w", blobPath, err)
+	}
+	body := data.([]byte)
+	if int64(len(body)) > maxBytes {
+		return nil, fmt.Errorf("blob %s is larger than %d bytes", blobPath, maxBytes)
+	}
+	return body, nil
+}
+
+// UploadBlob writes data to a block blob with the given content type and returns
+// the blob's URL
+func (a *AzureClientAdapter) UploadBlob(ctx context.Context, blobPath string, data []byte, contentType string) (string, error) {
+	err := a.guard.retry(ctx, "UploadBuffer", blobPath, func(c context.Context) error {
+		_, err := a.service.UploadBuffer(c, a.container, blobPath, data, &azblob.UploadBufferOptions{
+			HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+		})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("upload blob %s: %w", blobPath, err)
+	}
+	return a.service.ServiceClient().NewContainerClient(a.container).NewBlobClient(blobPath).URL(), nil
+}
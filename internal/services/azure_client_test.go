@@ -0,0 +1,150 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// blobServer answers the Blob service calls prefix deletion makes for the videos
+// container of account devstoreaccount1: flat listings of pageSize blobs, with the
+// last name listed as the next marker, and deletes. Blobs in gone answer a delete
+// with 404 BlobNotFound, as one deleted by someone else between listing and delete
+// would. The marker "expired" is rejected.
+type blobServer struct {
+	mu       sync.Mutex
+	blobs    map[string]bool
+	gone     map[string]bool
+	pageSize int
+	deletes  map[string]int
+	markers  []string
+}
+
+func (s *blobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	const base = "/devstoreaccount1/videos"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == base && r.URL.Query().Get("comp") == "list":
+		q := r.URL.Query()
+		marker := q.Get("marker")
+		s.markers = append(s.markers, marker)
+		if marker == "expired" {
+			w.Header().Set("x-ms-error-code", "InvalidQueryParameterValue")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var names []string
+		for name := range s.blobs {
+			if strings.HasPrefix(name, q.Get("prefix")) && name > marker {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		next := ""
+		if len(names) > s.pageSize {
+			names = names[:s.pageSize]
+			next = names[len(names)-1]
+		}
+		var body strings.Builder
+		body.WriteString(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults ContainerName="videos"><Blobs>`)
+		for _, name := range names {
+			fmt.Fprintf(&body, "<Blob><Name>%s</Name><Properties></Properties></Blob>", name)
+		}
+		fmt.Fprintf(&body, "</Blobs><NextMarker>%s</NextMarker></EnumerationResults>", next)
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(body.String()))
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, base+"/"):
+		name := strings.TrimPrefix(r.URL.Path, base+"/")
+		s.deletes[name]++
+		if s.gone[name] {
+			w.Header().Set("x-ms-error-code", "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(s.blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func azureAdapter(t *testing.T, blobs ...string) (*services.AzureClientAdapter, *blobServer) {
+	t.Helper()
+	backend := &blobServer{blobs: map[string]bool{}, gone: map[string]bool{}, pageSize: 2, deletes: map[string]int{}}
+	for _, name := range blobs {
+		backend.blobs[name] = true
+	}
+	srv := httptest.NewServer(backend)
+	t.Cleanup(srv.Close)
+	// The well-known development storage key; the fake server doesn't check signatures
+	conn := "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;" +
+		"AccountKey=Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==;" +
+		"BlobEndpoint=" + srv.URL + "/devstoreaccount1;"
+	adapter, err := services.NewAzureClientAdapter(
+		config.Azure{ConnectionString: conn, Container: "videos"},
+		config.Breaker{Reset: time.Minute, ConsecutiveFailures: 2, Retries: 2, AttemptTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return adapter, backend
+}
+
+func TestAzureDeleteBlobPage(t *testing.T) {
+	adapter, backend := azureAdapter(t, "hls/a/1.ts", "hls/a/2.ts", "hls/a/3.ts", "hls/b/1.ts")
+	backend.gone["hls/a/2.ts"] = true
+	ctx := context.Background()
+
+	next, deleted, err := adapter.DeleteBlobPage(ctx, "hls/a/", "")
+	if err != nil || next != "hls/a/2.ts" || deleted != 2 {
+		t.Fatalf("first page = %q, %d, %v; want marker hls/a/2.ts and 2 deleted", next, deleted, err)
+	}
+	// A blob already gone counts as deleted, without retries or tripping the breaker
+	if backend.deletes["hls/a/2.ts"] != 1 {
+		t.Errorf("missing blob deleted %d times, want once", backend.deletes["hls/a/2.ts"])
+	}
+	next, deleted, err = adapter.DeleteBlobPage(ctx, "hls/a/", next)
+	if err != nil || next != "" || deleted != 1 {
+		t.Fatalf("last page = %q, %d, %v; want no marker and 1 deleted", next, deleted, err)
+	}
+	if got := backend.markers; len(got) != 2 || got[1] != "hls/a/2.ts" {
+		t.Errorf("listed with markers %q, want the second page from the first's marker", got)
+	}
+	if !backend.blobs["hls/b/1.ts"] || len(backend.blobs) != 2 {
+		t.Errorf("blobs left = %v, want hls/b/1.ts and the one already gone", backend.blobs)
+	}
+}
+
+func TestAzureDeleteBlobPageStaleMarker(t *testing.T) {
+	adapter, _ := azureAdapter(t, "hls/a/1.ts")
+	if _, _, err := adapter.DeleteBlobPage(context.Background(), "hls/a/", "expired"); !errors.Is(err, services.ErrStaleMarker) {
+		t.Errorf("error = %v, want ErrStaleMarker", err)
+	}
+}
+
+func TestAzureDeleteBlobsWithPrefix(t *testing.T) {
+	var blobs []string
+	for i := 0; i < 7; i++ {
+		blobs = append(blobs, fmt.Sprintf("hls/a/%d.ts", i))
+	}
+	adapter, backend := azureAdapter(t, blobs...)
+	backend.gone["hls/a/4.ts"] = true
+
+	deleted, err := adapter.DeleteBlobsWithPrefix(context.Background(), "hls/a/")
+	if err != nil || deleted != 7 {
+		t.Errorf("DeleteBlobsWithPrefix = %d, %v; want 7", deleted, err)
+	}
+	if len(backend.markers) != 4 {
+		t.Errorf("listed %d pages, want 4", len(backend.markers))
+	}
+}
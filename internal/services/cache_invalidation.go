@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// MessagePublisher sends a message body to the broker under a routing key
+type MessagePublisher interface {
+	Publish(ctx context.Context, routingKey string, body []byte) error
+}
+
+// Cache invalidation reasons, most significant first. A coalesced message carries
+// the most significant reason seen in its window.
+const (
+	InvalidateDeleted    = "deleted"
+	InvalidateVisibility = "visibility"
+	InvalidateCreated    = "created"
+	InvalidateStatus     = "status"
+	InvalidateMetadata   = "metadata"
+)
+
+var invalidationRank = map[string]int{
+	InvalidateDeleted:    5,
+	InvalidateVisibility: 4,
+	InvalidateCreated:    3,
+	InvalidateStatus:     2,
+	InvalidateMetadata:   1,
+}
+
+// invalidationReason maps a change kind to the reason downstream caches see
+func invalidationReason(kind VideoChangeKind) string {
+	switch kind {
+	case VideoDeleted:
+		return InvalidateDeleted
+	case VideoVisibilityChanged, VideoModerated:
+		return InvalidateVisibility
+	case VideoCreated, VideoRestored:
+		return InvalidateCreated
+	case VideoStatusChanged:
+		return InvalidateStatus
+	default:
+		return InvalidateMetadata
+	}
+}
+
+// CacheInvalidation is the video.cache.invalidate message. Status, Visibility,
+// IsPrivate and ChangedAt describe the latest change in the window.
+type CacheInvalidation struct {
+	VideoID    uint               `json:"videoId"`
+	UploadID   string             `json:"uploadId"`
+	Reason     string             `json:"reason"`
+	Status     models.VideoStatus `json:"status,omitempty"`
+	Visibility models.Visibility  `json:"visibility,omitempty"`
+	IsPrivate  bool               `json:"isPrivate"`
+	ChangedAt  time.Time          `json:"changedAt"`
+	// Coalesced is how many changes this message stands for
+	Coalesced  int       `json:"coalesced"`
+	ProducedAt time.Time `json:"producedAt"`
+}
+
+// cacheInvalidatePublishTimeout bounds one publish from the debounce timer
+const cacheInvalidatePublishTimeout = 5 * time.Second
+
+// CacheInvalidator turns video changes into video.cache.invalidate messages. The
+// first change to a video opens a window; later changes in the window are folded
+// into the same message, which is published when the window closes. The window is
+// not extended by further changes, so a video under constant edits still gets one
+// message per window.
+type CacheInvalidator struct {
+	publisher  MessagePublisher
+	logger     *zap.SugaredLogger
+	routingKey string
+	window     time.Duration
+
+	mu      sync.Mutex
+	pending map[uint]*pendingInvalidation
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+type pendingInvalidation struct {
+	msg   CacheInvalidation
+	timer *time.Timer
+}
+
+// NewCacheInvalidator creates an invalidator publishing under routingKey with the
+// given debounce window
+func NewCacheInvalidator(publisher MessagePublisher, logger *zap.SugaredLogger, routingKey string, window time.Duration) *CacheInvalidator {
+	return &CacheInvalidator{
+		publisher:  publisher,
+		logger:     logger,
+		routingKey: routingKey,
+		window:     window,
+		pending:    map[uint]*pendingInvalidation{},
+	}
+}
+
+// Observe is a VideoChangeHook; subscribe it to the video change hub
+func (i *CacheInvalidator) Observe(_ context.Context, change VideoChange) {
+	reason := invalidationReason(change.Kind)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.stopped {
+		return
+	}
+	if p, ok := i.pending[change.VideoID]; ok {
+		if invalidationRank[reason] > invalidationRank[p.msg.Reason] {
+			p.msg.Reason = reason
+		}
+		p.msg.Status, p.msg.Visibility, p.msg.IsPrivate, p.msg.ChangedAt = change.Status, change.Visibility, change.IsPrivate, change.At
+		p.msg.Coalesced++
+		metrics.CacheInvalidationsCoalescedTotal.Inc()
+		return
+	}
+	p := &pendingInvalidation{msg: CacheInvalidation{
+		VideoID:    change.VideoID,
+		UploadID:   change.UploadID,
+		Reason:     reason,
+		Status:     change.Status,
+		Visibility: change.Visibility,
+		IsPrivate:  change.IsPrivate,
+		ChangedAt:  change.At,
+		Coalesced:  1,
+	}}
+	i.pending[change.VideoID] = p
+	i.wg.Add(1)
+	p.timer = time.AfterFunc(i.window, func() {
+		defer i.wg.Done()
+		i.flush(change.VideoID)
+	})
+}
+
+// flush publishes and forgets the pending message for a video
+func (i *CacheInvalidator) flush(videoID uint) {
+	i.mu.Lock()
+	p, ok := i.pending[videoID]
+	delete(i.pending, videoID)
+	i.mu.Unlock()
+	if ok {
+		i.publish(p.msg)
+	}
+}
+
+func (i *CacheInvalidator) publish(msg CacheInvalidation) {
+	msg.ProducedAt = time.Now().UTC()
+	body, err := json.Marshal(msg)
+	if err != nil {
+		metrics.CacheInvalidationsTotal.WithLabelValues(msg.Reason, "error").Inc()
+		i.logger.Errorw("Failed to encode cache invalidation", "error", err, "videoID", msg.VideoID)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cacheInvalidatePublishTimeout)
+	defer cancel()
+	if err := i.publisher.Publish(ctx, i.routingKey, body); err != nil {
+		metrics.CacheInvalidationsTotal.WithLabelValues(msg.Reason, "error").Inc()
+		i.logger.Warnw("Failed to publish cache invalidation", "error", err, "videoID", msg.VideoID, "reason", msg.Reason)
+		return
+	}
+	metrics.CacheInvalidationsTotal.WithLabelValues(msg.Reason, "published").Inc()
+}
+
+// Stop publishes every pending message immediately and ignores later changes
+func (i *CacheInvalidator) Stop() {
+	i.mu.Lock()
+	i.stopped = true
+	var due []CacheInvalidation
+	for id, p := range i.pending {
+		// A timer that already fired publishes its own message
+		if p.timer.Stop() {
+			i.wg.Done()
+			due = append(due, p.msg)
+			delete(i.pending, id)
+		}
+	}
+	i.mu.Unlock()
+
+	for _, msg := range due {
+		i.publish(msg)
+	}
+	i.wg.Wait()
+}
@@ -0,0 +1,199 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+const invalidateKey = "video.cache.invalidate"
+
+// fakePublisher is a MessagePublisher that keeps what it was sent
+type fakePublisher struct {
+	mu   sync.Mutex
+	keys []string
+	msgs []services.CacheInvalidation
+	err  error
+}
+
+func (p *fakePublisher) Publish(_ context.Context, routingKey string, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	var msg services.CacheInvalidation
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return err
+	}
+	p.keys = append(p.keys, routingKey)
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *fakePublisher) sent() []services.CacheInvalidation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]services.CacheInvalidation(nil), p.msgs...)
+}
+
+func change(kind services.VideoChangeKind, id uint, status models.VideoStatus, at time.Time) services.VideoChange {
+	return services.VideoChange{Kind: kind, VideoID: id, UploadID: "up-1", Status: status, Visibility: models.VisibilityPublic, At: at}
+}
+
+func TestCacheInvalidatorCoalescesBurst(t *testing.T) {
+	pub := &fakePublisher{}
+	inv := services.NewCacheInvalidator(pub, nopLogger(), invalidateKey, 50*time.Millisecond)
+	t.Cleanup(inv.Stop)
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	inv.Observe(ctx, change(services.VideoUpdated, 1, models.StatusProcessing, base))
+	inv.Observe(ctx, change(services.VideoStatusChanged, 1, models.StatusReady, base.Add(time.Second)))
+	inv.Observe(ctx, change(services.VideoUpdated, 1, models.StatusReady, base.Add(2*time.Second)))
+	inv.Observe(ctx, change(services.VideoUpdated, 1, models.StatusReady, base.Add(3*time.Second)))
+	last := change(services.VideoVisibilityChanged, 1, models.StatusReady, base.Add(4*time.Second))
+	last.Visibility, last.IsPrivate = models.VisibilityPrivate, true
+	inv.Observe(ctx, last)
+
+	waitFor(t, "the window to close", func() bool { return len(pub.sent()) > 0 })
+	time.Sleep(100 * time.Millisecond)
+	msgs := pub.sent()
+	if len(msgs) != 1 {
+		t.Fatalf("%d messages for a burst of five, want 1", len(msgs))
+	}
+	msg := msgs[0]
+	if pub.keys[0] != invalidateKey {
+		t.Errorf("routing key %q", pub.keys[0])
+	}
+	// The message describes the latest state and the most significant reason
+	if msg.VideoID != 1 || msg.UploadID != "up-1" || msg.Coalesced != 5 || msg.Reason != services.InvalidateVisibility {
+		t.Errorf("message = %+v, want 5 coalesced with reason visibility", msg)
+	}
+	if msg.Status != models.StatusReady || msg.Visibility != models.VisibilityPrivate || !msg.IsPrivate || !msg.ChangedAt.Equal(last.At) {
+		t.Errorf("message = %+v, want the state of the last change", msg)
+	}
+	if msg.ProducedAt.IsZero() {
+		t.Error("producedAt not set")
+	}
+}
+
+func TestCacheInvalidatorReasons(t *testing.T) {
+	tests := []struct {
+		kinds []services.VideoChangeKind
+		want  string
+	}{
+		{[]services.VideoChangeKind{services.VideoUpdated}, services.InvalidateMetadata},
+		{[]services.VideoChangeKind{services.VideoStatusChanged}, services.InvalidateStatus},
+		{[]services.VideoChangeKind{services.VideoCreated}, services.InvalidateCreated},
+		{[]services.VideoChangeKind{services.VideoRestored}, services.InvalidateCreated},
+		{[]services.VideoChangeKind{services.VideoModerated}, services.InvalidateVisibility},
+		{[]services.VideoChangeKind{services.VideoDeleted}, services.InvalidateDeleted},
+		{[]services.VideoChangeKind{services.VideoDeleted, services.VideoUpdated}, services.InvalidateDeleted},
+		{[]services.VideoChangeKind{services.VideoCreated, services.VideoStatusChanged, services.VideoUpdated}, services.InvalidateCreated},
+	}
+	for _, tc := range tests {
+		pub := &fakePublisher{}
+		inv := services.NewCacheInvalidator(pub, nopLogger(), invalidateKey, time.Hour)
+		for _, kind := range tc.kinds {
+			inv.Observe(context.Background(), change(kind, 1, models.StatusReady, time.Now()))
+		}
+		// Stop publishes what is pending without waiting for the window
+		inv.Stop()
+		if msgs := pub.sent(); len(msgs) != 1 || msgs[0].Reason != tc.want {
+			t.Errorf("%v: sent %+v, want one with reason %s", tc.kinds, msgs, tc.want)
+		}
+	}
+}
+
+func TestCacheInvalidatorPerVideo(t *testing.T) {
+	pub := &fakePublisher{}
+	inv := services.NewCacheInvalidator(pub, nopLogger(), invalidateKey, time.Hour)
+	for _, id := range []uint{1, 2, 1, 3, 2} {
+		inv.Observe(context.Background(), change(services.VideoUpdated, id, models.StatusReady, time.Now()))
+	}
+	inv.Stop()
+	coalesced := map[uint]int{}
+	for _, msg := range pub.sent() {
+		coalesced[msg.VideoID] = msg.Coalesced
+	}
+	if len(pub.sent()) != 3 || coalesced[1] != 2 || coalesced[2] != 2 || coalesced[3] != 1 {
+		t.Errorf("sent %+v, want one message per video", pub.sent())
+	}
+
+	// Changes after Stop are dropped
+	inv.Observe(context.Background(), change(services.VideoUpdated, 4, models.StatusReady, time.Now()))
+	inv.Stop()
+	if len(pub.sent()) != 3 {
+		t.Errorf("change after Stop was published")
+	}
+}
+
+func TestCacheInvalidatorWindowNotExtended(t *testing.T) {
+	pub := &fakePublisher{}
+	inv := services.NewCacheInvalidator(pub, nopLogger(), invalidateKey, 30*time.Millisecond)
+	t.Cleanup(inv.Stop)
+	// A video edited continuously for several windows still gets a message per window
+	deadline := time.Now().Add(200 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		inv.Observe(context.Background(), change(services.VideoUpdated, 1, models.StatusReady, time.Now()))
+		time.Sleep(2 * time.Millisecond)
+	}
+	if n := len(pub.sent()); n < 2 {
+		t.Errorf("%d messages during continuous edits, want one per window", n)
+	}
+}
+
+func TestCacheInvalidatorPublishError(t *testing.T) {
+	pub := &fakePublisher{err: errors.New("broker down")}
+	inv := services.NewCacheInvalidator(pub, nopLogger(), invalidateKey, time.Hour)
+	failed := metrics.CacheInvalidationsTotal.WithLabelValues(services.InvalidateDeleted, "error")
+	before := testutil.ToFloat64(failed)
+
+	inv.Observe(context.Background(), change(services.VideoDeleted, 1, models.StatusReady, time.Now()))
+	inv.Stop()
+	if got := testutil.ToFloat64(failed) - before; got != 1 {
+		t.Errorf("error counter +%v, want +1", got)
+	}
+}
+
+func TestCacheInvalidatorFromVideoService(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	pub := &fakePublisher{}
+	inv := services.NewCacheInvalidator(pub, nopLogger(), invalidateKey, time.Hour)
+	videos.Changes().Subscribe("cache_invalidation", inv.Observe)
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{Title: "start", Status: models.StatusReady})
+
+	// Five edits in quick succession
+	titles := []string{"one", "two", "three", "four", "five"}
+	for i := range titles {
+		if _, err := videos.UpdateVideoForUser(ctx, video.ID, "owner", 0, &models.VideoUpdateRequest{Title: &titles[i]}); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+	inv.Stop()
+	msgs := pub.sent()
+	if len(msgs) != 1 || msgs[0].VideoID != video.ID || msgs[0].Coalesced != 5 || msgs[0].Reason != services.InvalidateMetadata {
+		t.Fatalf("sent %+v, want one invalidation standing for five updates", msgs)
+	}
+	// The message references the latest state: a consumer refetching sees the last edit
+	got, err := videos.GetVideo(ctx, msgs[0].VideoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := titles[len(titles)-1]; got.Title != want {
+		t.Errorf("video title %q, want %q", got.Title, want)
+	}
+}
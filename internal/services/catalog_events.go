@@ -0,0 +1,64 @@
+package services
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// Outbound catalog event types, also used as their routing keys
+const (
+	CatalogVideoUpdated = "catalog.video.updated"
+	CatalogVideoDeleted = "catalog.video.deleted"
+)
+
+// CatalogVideoEvent is the body of catalog.video.updated and catalog.video.deleted.
+// Video is the record after the change, or as it was when deleted.
+type CatalogVideoEvent struct {
+	Type string `json:"type"`
+	// Change is the change kind: created, updated, visibility, status, moderation,
+	// deleted or restored
+	Change     VideoChangeKind `json:"change"`
+	VideoID    uint            `json:"videoId"`
+	UploadID   string          `json:"uploadId"`
+	Video      *models.Video   `json:"video"`
+	ChangedAt  time.Time       `json:"changedAt"`
+	ProducedAt time.Time       `json:"producedAt"`
+}
+
+// catalogEvent describes a change to video. The share token is left out: it is a
+// credential, and consumers don't need it.
+func catalogEvent(kind VideoChangeKind, video *models.Video) CatalogVideoEvent {
+	now := time.Now().UTC()
+	snapshot := *video
+	snapshot.ShareToken = ""
+	event := CatalogVideoEvent{
+		Type:       CatalogVideoUpdated,
+		Change:     kind,
+		VideoID:    video.ID,
+		UploadID:   video.UploadID,
+		Video:      &snapshot,
+		ChangedAt:  now,
+		ProducedAt: now,
+	}
+	if kind == VideoDeleted {
+		event.Type = CatalogVideoDeleted
+	}
+	return event
+}
+
+// SetOutbox makes video writes enqueue catalog.video.updated and
+// catalog.video.deleted in their own transactions, for other services
+// (recommendations, the search indexer). Without one, no catalog events are sent.
+func (s *VideoService) SetOutbox(o *Outbox) {
+	s.outbox = o
+}
+
+// enqueueCatalogEvent adds the catalog event for a change to video to the outbox
+// inside tx
+func enqueueCatalogEvent(tx *gorm.DB, outbox *Outbox, kind VideoChangeKind, video *models.Video) error {
+	event := catalogEvent(kind, video)
+	return outbox.Enqueue(tx, event.Type, event)
+}
@@ -0,0 +1,123 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// TestCatalogEvents changes a video through each writer and checks the catalog
+// event it enqueues, then that the dispatcher sends them in order
+func TestCatalogEvents(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	outbox := services.NewOutbox(db, nopLogger(), services.OutboxConfig{MaxAttempts: 3})
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	videos.SetOutbox(outbox)
+
+	var video *models.Video
+	title, unlisted := "renamed", models.VisibilityUnlisted
+	steps := []struct {
+		name       string
+		run        func() error
+		routingKey string
+		change     services.VideoChangeKind
+	}{
+		{"create", func() (err error) {
+			video, err = videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-1", Title: "t"})
+			return err
+		}, services.CatalogVideoUpdated, services.VideoCreated},
+		{"update", func() error {
+			_, err := videos.UpdateVideoForUser(ctx, video.ID, "owner", 0, &models.VideoUpdateRequest{Title: &title})
+			return err
+		}, services.CatalogVideoUpdated, services.VideoUpdated},
+		{"unlist", func() error {
+			_, err := videos.UpdateVideoForUser(ctx, video.ID, "owner", 0, &models.VideoUpdateRequest{Visibility: &unlisted})
+			return err
+		}, services.CatalogVideoUpdated, services.VideoVisibilityChanged},
+		{"status", func() error {
+			_, err := videos.SetVideoStatus(ctx, video.ID, models.StatusProcessing, "admin", "")
+			return err
+		}, services.CatalogVideoUpdated, services.VideoStatusChanged},
+		{"delete", func() error {
+			_, err := videos.DeleteVideoForUser(ctx, video.ID, "owner")
+			return err
+		}, services.CatalogVideoDeleted, services.VideoDeleted},
+		{"restore", func() error {
+			_, err := videos.RestoreVideo(ctx, video.ID, "owner")
+			return err
+		}, services.CatalogVideoUpdated, services.VideoRestored},
+	}
+	seen := 0
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		msgs := outboxMessages(t, db)[seen:]
+		seen += len(msgs)
+		if len(msgs) != 1 {
+			t.Errorf("%s: %d events enqueued, want 1", step.name, len(msgs))
+			continue
+		}
+		var event services.CatalogVideoEvent
+		if err := json.Unmarshal([]byte(msgs[0].Body), &event); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if msgs[0].RoutingKey != step.routingKey || event.Type != step.routingKey || event.Change != step.change ||
+			event.VideoID != video.ID || event.UploadID != "up-1" || event.Video == nil || event.Video.ID != video.ID ||
+			event.ChangedAt.IsZero() || event.ProducedAt.IsZero() {
+			t.Errorf("%s: %s %s, want %s with change %s", step.name, msgs[0].RoutingKey, msgs[0].Body, step.routingKey, step.change)
+		}
+		if strings.Contains(msgs[0].Body, "share_token") {
+			t.Errorf("%s: event carries the share token: %s", step.name, msgs[0].Body)
+		}
+	}
+
+	// The share token was set; it just isn't sent
+	var row models.Video
+	db.First(&row, video.ID)
+	if row.ShareToken == "" {
+		t.Fatal("unlisted video has no share token")
+	}
+
+	pub := &flakyPublisher{}
+	if sent, err := outbox.Dispatch(ctx, pub); err != nil || sent != len(steps) {
+		t.Fatalf("Dispatch: sent %d, err %v; want %d", sent, err, len(steps))
+	}
+	for i, step := range steps {
+		if !strings.HasPrefix(pub.sent[i], step.routingKey+" ") || !strings.Contains(pub.sent[i], `"change":"`+string(step.change)+`"`) {
+			t.Errorf("message %d = %s, want %s %s", i, pub.sent[i], step.routingKey, step.change)
+		}
+	}
+}
+
+// TestCatalogEventsOnlyForCommittedChanges enqueues nothing for a refused change
+func TestCatalogEventsOnlyForCommittedChanges(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	outbox := services.NewOutbox(db, nopLogger(), services.OutboxConfig{MaxAttempts: 3})
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	videos.SetOutbox(outbox)
+	video := createVideo(t, db, models.Video{Title: "t", Status: models.StatusReady})
+
+	title := "stolen"
+	if _, err := videos.UpdateVideoForUser(ctx, video.ID, "mallory", 0, &models.VideoUpdateRequest{Title: &title}); err == nil {
+		t.Fatal("update by another user succeeded")
+	}
+	var invalid *services.InvalidTransitionError
+	if _, err := videos.SetVideoStatus(ctx, video.ID, models.StatusUploaded, "admin", ""); !errors.As(err, &invalid) {
+		t.Fatalf("ready -> uploaded: %v, want an invalid transition", err)
+	}
+	if _, err := videos.DeleteVideoForUser(ctx, video.ID, "mallory"); err == nil {
+		t.Fatal("delete by another user succeeded")
+	}
+	if msgs := outboxMessages(t, db); len(msgs) != 0 {
+		t.Errorf("refused changes enqueued %+v", msgs)
+	}
+}
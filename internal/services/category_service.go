@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// InvalidCategoryError is returned when a video is given a category that doesn't
+// resolve to an active managed one. Allowed lists the active slugs.
+type InvalidCategoryError struct {
+	Category string
+	Allowed  []string
+}
+
+func (e *InvalidCategoryError) Error() string {
+	return fmt.Sprintf("category %q is not an active category", e.Category)
+}
+
+// Is implements errors.Is
+func (e *InvalidCategoryError) Is(target error) bool { return target == ErrInvalidCategory }
+
+// CategoryService manages the categories videos may be filed under. The table is
+// small and written rarely, so lookups go straight to the database and every
+// replica sees a change at once.
+type CategoryService struct {
+	db     *gorm.DB
+	logger *zap.SugaredLogger
+}
+
+// NewCategoryService creates a category service
+func NewCategoryService(db *gorm.DB, logger *zap.SugaredLogger) *CategoryService {
+	return &CategoryService{db: db, logger: logger}
+}
+
+// List returns the categories ordered by display name; inactive ones only when asked
+func (s *CategoryService) List(ctx context.Context, includeInactive bool) ([]models.Category, error) {
+	query := s.db.WithContext(ctx).Order("display_name, slug")
+	if !includeInactive {
+		query = query.Where("active")
+	}
+	out := []models.Category{}
+	if err := query.Find(&out).Error; err != nil {
+		return nil, fmt.Errorf("list categories: %w", err)
+	}
+	return out, nil
+}
+
+// Resolve maps what a client or producer sent to an active category's slug. The
+// value matches a slug once slugified, or a display name ignoring case, so "Music"
+// and "music" both give "music". An empty value resolves to "" (no category).
+func (s *CategoryService) Resolve(ctx context.Context, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	var slugs []string
+	err := s.db.WithContext(ctx).Model(&models.Category{}).
+		Where("active AND (slug = ? OR lower(display_name) = lower(?))", models.CategorySlug(value), value).
+		Order("slug").Limit(1).Pluck("slug", &slugs).Error
+	if err != nil {
+		return "", fmt.Errorf("resolve category: %w", err)
+	}
+	if len(slugs) == 0 {
+		allowed, err := s.activeSlugs(ctx)
+		if err != nil {
+			return "", err
+		}
+		return "", &InvalidCategoryError{Category: value, Allowed: allowed}
+	}
+	return slugs[0], nil
+}
+
+func (s *CategoryService) activeSlugs(ctx context.Context) ([]string, error) {
+	slugs := []string{}
+	if err := s.db.WithContext(ctx).Model(&models.Category{}).Where("active").Order("slug").Pluck("slug", &slugs).Error; err != nil {
+		return nil, fmt.Errorf("list active categories: %w", err)
+	}
+	return slugs, nil
+}
+
+// Create adds a category. The slug must already be in slug form rather than being
+// derived, so admins see exactly what videos will store.
+func (s *CategoryService) Create(ctx context.Context, req *models.CategoryCreateRequest) (*models.Category, error) {
+	category := &models.Category{Slug: req.Slug, DisplayName: strings.TrimSpace(req.DisplayName), Active: true}
+	if req.Active != nil {
+		category.Active = *req.Active
+	}
+	fields := map[string]string{}
+	if !models.ValidCategorySlug(category.Slug) {
+		fields["slug"] = fmt.Sprintf("must be lowercase a-z and 0-9 words joined by single dashes, at most %d characters", models.MaxCategorySlugLen)
+	}
+	if problem := categoryNameProblem(category.DisplayName); problem != "" {
+		fields["display_name"] = problem
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Err: ErrInvalidCategory, Fields: fields}
+	}
+	if err := s.db.WithContext(ctx).Create(category).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("category %s: %w", category.Slug, ErrCategoryExists)
+		}
+		return nil, fmt.Errorf("create category: %w", err)
+	}
+	return category, nil
+}
+
+// Update renames or (de)activates a category. Deactivating one leaves it on the
+// videos that already have it.
+func (s *CategoryService) Update(ctx context.Context, slug string, req *models.CategoryUpdateRequest) (*models.Category, error) {
+	var name string
+	if req.DisplayName != nil {
+		name = strings.TrimSpace(*req.DisplayName)
+		if problem := categoryNameProblem(name); problem != "" {
+			return nil, &ValidationError{Err: ErrInvalidCategory, Fields: map[string]string{"display_name": problem}}
+		}
+	}
+
+	var category models.Category
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&category, "slug = ?", slug).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("category %s: %w", slug, ErrCategoryNotFound)
+			}
+			return err
+		}
+		if req.DisplayName != nil {
+			category.DisplayName = name
+		}
+		if req.Active != nil {
+			category.Active = *req.Active
+		}
+		return tx.Save(&category).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrCategoryNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("update category: %w", err)
+	}
+	return &category, nil
+}
+
+// Delete removes a category no live video is filed under. One still in use must be
+// deactivated instead, so existing videos keep a category that names something.
+func (s *CategoryService) Delete(ctx context.Context, slug string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var inUse int64
+		if err := tx.Model(&models.Video{}).Where("category = ?", slug).Count(&inUse).Error; err != nil {
+			return fmt.Errorf("count videos in category: %w", err)
+		}
+		if inUse > 0 {
+			return fmt.Errorf("category %s is on %d videos: %w", slug, inUse, ErrCategoryInUse)
+		}
+		res := tx.Delete(&models.Category{}, "slug = ?", slug)
+		if res.Error != nil {
+			return fmt.Errorf("delete category: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("category %s: %w", slug, ErrCategoryNotFound)
+		}
+		return nil
+	})
+}
+
+func categoryNameProblem(name string) string {
+	switch {
+	case name == "":
+		return "required"
+	case len([]rune(name)) > models.MaxCategoryNameLen:
+		return fmt.Sprintf("at most %d characters", models.MaxCategoryNameLen)
+	}
+	return ""
+}
+
+// SetCategories makes videos take their category from svc: requests must name an
+// active category, and events naming an unknown one are filed without a category.
+// Without it, categories are stored as given.
+func (s *VideoService) SetCategories(svc *CategoryService) { s.categories = svc }
+
+// requestCategory resolves a category from an API request
+func (s *VideoService) requestCategory(ctx context.Context, value string) (string, error) {
+	if s.categories == nil {
+		return value, nil
+	}
+	return s.categories.Resolve(ctx, value)
+}
+
+// eventCategory resolves a category from a broker event. The producer can't be
+// told about an unknown category, so it is logged and dropped rather than failing
+// the message; a database error still fails it, to be retried.
+func (s *VideoService) eventCategory(ctx context.Context, uploadID, value string) (string, error) {
+	category, err := s.requestCategory(ctx, value)
+	var invalid *InvalidCategoryError
+	if errors.As(err, &invalid) {
+		s.log(ctx).Warnw("Dropped unknown category from event", "uploadID", uploadID, "category", value)
+		return "", nil
+	}
+	return category, err
+}
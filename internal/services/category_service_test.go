@@ -0,0 +1,186 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// seedCategories adds music and gaming, active, and retired, inactive
+func seedCategories(t *testing.T, db *gorm.DB) *services.CategoryService {
+	t.Helper()
+	categories := services.NewCategoryService(db, nopLogger())
+	inactive := false
+	for _, req := range []models.CategoryCreateRequest{
+		{Slug: "music", DisplayName: "Music"},
+		{Slug: "gaming", DisplayName: "Gaming"},
+		{Slug: "retired", DisplayName: "Retired", Active: &inactive},
+	} {
+		if _, err := categories.Create(context.Background(), &req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return categories
+}
+
+func TestCategoryList(t *testing.T) {
+	db := dbtest.Open(t)
+	categories := seedCategories(t, db)
+	slugs := func(includeInactive bool) []string {
+		t.Helper()
+		list, err := categories.List(context.Background(), includeInactive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, c := range list {
+			out = append(out, c.Slug)
+		}
+		return out
+	}
+	if got := slugs(false); !reflect.DeepEqual(got, []string{"gaming", "music"}) {
+		t.Errorf("active categories %v, want gaming, music by name", got)
+	}
+	if got := slugs(true); !reflect.DeepEqual(got, []string{"gaming", "music", "retired"}) {
+		t.Errorf("all categories %v", got)
+	}
+}
+
+func TestCategoryResolve(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	categories := seedCategories(t, db)
+	if _, err := categories.Create(ctx, &models.CategoryCreateRequest{Slug: "howto-style", DisplayName: "Howto & Style"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for value, want := range map[string]string{
+		"":              "",
+		"music":         "music",
+		"Music":         "music",
+		" MUSIC ":       "music",
+		"Müsic":         "music",
+		"howto & style": "howto-style",
+		"Howto-Style":   "howto-style",
+	} {
+		if got, err := categories.Resolve(ctx, value); err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"M", "cooking", "retired"} {
+		_, err := categories.Resolve(ctx, value)
+		var invalid *services.InvalidCategoryError
+		if !errors.As(err, &invalid) || !errors.Is(err, services.ErrInvalidCategory) {
+			t.Errorf("Resolve(%q): %v, want an InvalidCategoryError", value, err)
+			continue
+		}
+		if want := []string{"gaming", "howto-style", "music"}; !reflect.DeepEqual(invalid.Allowed, want) {
+			t.Errorf("Resolve(%q) allowed %v, want the active slugs %v", value, invalid.Allowed, want)
+		}
+	}
+}
+
+func TestCategoryWrites(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	categories := seedCategories(t, db)
+	name := func(s string) *string { return &s }
+	active := func(b bool) *bool { return &b }
+
+	creates := []struct {
+		name string
+		req  models.CategoryCreateRequest
+		want error
+		bad  string
+	}{
+		{"slug not in slug form", models.CategoryCreateRequest{Slug: "Cooking", DisplayName: "Cooking"}, services.ErrInvalidCategory, "slug"},
+		{"double dash", models.CategoryCreateRequest{Slug: "home--garden", DisplayName: "Home"}, services.ErrInvalidCategory, "slug"},
+		{"blank name", models.CategoryCreateRequest{Slug: "cooking", DisplayName: "  "}, services.ErrInvalidCategory, "display_name"},
+		{"taken slug", models.CategoryCreateRequest{Slug: "music", DisplayName: "More music"}, services.ErrCategoryExists, ""},
+	}
+	for _, tt := range creates {
+		_, err := categories.Create(ctx, &tt.req)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("create, %s: %v, want %v", tt.name, err, tt.want)
+			continue
+		}
+		var invalid *services.ValidationError
+		if tt.bad != "" && (!errors.As(err, &invalid) || invalid.Fields[tt.bad] == "") {
+			t.Errorf("create, %s: %v, want a message for %s", tt.name, err, tt.bad)
+		}
+	}
+	created, err := categories.Create(ctx, &models.CategoryCreateRequest{Slug: "cooking", DisplayName: " Cooking "})
+	if err != nil || created.DisplayName != "Cooking" || !created.Active {
+		t.Fatalf("create = %+v, %v; want an active, trimmed category", created, err)
+	}
+
+	// Renaming and retiring leave the slug alone
+	updated, err := categories.Update(ctx, "cooking", &models.CategoryUpdateRequest{DisplayName: name("Food & Cooking"), Active: active(false)})
+	if err != nil || updated.Slug != "cooking" || updated.DisplayName != "Food & Cooking" || updated.Active {
+		t.Fatalf("update = %+v, %v", updated, err)
+	}
+	if _, err := categories.Resolve(ctx, "cooking"); !errors.Is(err, services.ErrInvalidCategory) {
+		t.Errorf("resolve a retired category: %v, want ErrInvalidCategory", err)
+	}
+	if _, err := categories.Update(ctx, "cooking", &models.CategoryUpdateRequest{DisplayName: name("")}); !errors.Is(err, services.ErrInvalidCategory) {
+		t.Errorf("rename to blank: %v, want ErrInvalidCategory", err)
+	}
+	if _, err := categories.Update(ctx, "baking", &models.CategoryUpdateRequest{Active: active(true)}); !errors.Is(err, services.ErrCategoryNotFound) {
+		t.Errorf("update a missing category: %v, want ErrCategoryNotFound", err)
+	}
+
+	// A category on a live video is retired rather than deleted
+	createVideo(t, db, models.Video{Title: "t", Category: "music"})
+	if err := categories.Delete(ctx, "music"); !errors.Is(err, services.ErrCategoryInUse) {
+		t.Errorf("delete a category in use: %v, want ErrCategoryInUse", err)
+	}
+	if err := categories.Delete(ctx, "cooking"); err != nil {
+		t.Errorf("delete an unused category: %v", err)
+	}
+	if err := categories.Delete(ctx, "cooking"); !errors.Is(err, services.ErrCategoryNotFound) {
+		t.Errorf("delete it again: %v, want ErrCategoryNotFound", err)
+	}
+}
+
+// TestVideoCategories checks requests must name an active category while events
+// naming an unknown one are filed without one
+func TestVideoCategories(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	videos.SetCategories(seedCategories(t, db))
+
+	created, err := videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-1", Title: "t", Category: "Müsic"})
+	if err != nil || created.Category != "music" {
+		t.Fatalf("create with Müsic = %+v, %v; want the music slug", created, err)
+	}
+	if _, err := videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-2", Title: "u", Category: "retired"}); !errors.Is(err, services.ErrInvalidCategory) {
+		t.Errorf("create with a retired category: %v, want ErrInvalidCategory", err)
+	}
+	polka := "polka"
+	if _, err := videos.UpdateVideo(ctx, created.ID, &models.VideoUpdateRequest{Category: &polka}); !errors.Is(err, services.ErrInvalidCategory) {
+		t.Errorf("update to an unknown category: %v, want ErrInvalidCategory", err)
+	}
+	if updated, err := videos.UpdateVideo(ctx, created.ID, &models.VideoUpdateRequest{Category: new(string)}); err != nil || updated.Category != "" {
+		t.Errorf("clear the category = %+v, %v", updated, err)
+	}
+
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-3", UserID: "owner", Title: "v", Category: "Polka"}); err != nil {
+		t.Fatalf("event with an unknown category: %v, want it accepted", err)
+	}
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-4", UserID: "owner", Title: "w", Category: "GAMING"}); err != nil {
+		t.Fatal(err)
+	}
+	var got []models.Video
+	db.Where("upload_id IN ?", []string{"up-3", "up-4"}).Order("upload_id").Find(&got)
+	if len(got) != 2 || got[0].Category != "" || got[1].Category != "gaming" {
+		t.Errorf("event categories %+v, want none and gaming", got)
+	}
+}
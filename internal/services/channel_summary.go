@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// channelSummaryTopCategories is how many categories a summary lists
+const channelSummaryTopCategories = 5
+
+// ChannelSummary aggregates one user's videos for their profile page
+type ChannelSummary struct {
+	UserID     string `json:"user_id"`
+	VideoCount int64  `json:"video_count"`
+	TotalViews int64  `json:"total_views"`
+	// TotalDuration is in seconds
+	TotalDuration float64 `json:"total_duration"`
+	// LatestUploadAt is when the newest video was created; nil without videos
+	LatestUploadAt *time.Time      `json:"latest_upload_at"`
+	TopCategories  []CategoryCount `json:"top_categories"`
+}
+
+// CategoryCount is a category and how many of the user's videos are filed under it
+type CategoryCount struct {
+	Slug        string `json:"slug"`
+	DisplayName string `json:"display_name,omitempty"`
+	Count       int64  `json:"count"`
+}
+
+// summaryCache keeps public channel summaries for a short TTL. Each replica has
+// its own; a change to one of the user's videos drops their entry on the replica
+// that made it, and the others catch up within the TTL.
+type summaryCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	entries map[string]summaryEntry
+}
+
+type summaryEntry struct {
+	summary ChannelSummary
+	expires time.Time
+}
+
+func newSummaryCache(ttl time.Duration, maxSize int) *summaryCache {
+	return &summaryCache{ttl: ttl, maxSize: maxSize, entries: map[string]summaryEntry{}}
+}
+
+func (c *summaryCache) get(userID string) (ChannelSummary, bool) {
+	if c.ttl <= 0 {
+		return ChannelSummary{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[userID]
+	if !ok || time.Now().After(e.expires) {
+		return ChannelSummary{}, false
+	}
+	return e.summary, true
+}
+
+func (c *summaryCache) put(userID string, summary ChannelSummary) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.maxSize {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		// Still full of live entries: start over rather than track recency
+		if len(c.entries) >= c.maxSize {
+			c.entries = map[string]summaryEntry{}
+		}
+	}
+	c.entries[userID] = summaryEntry{summary: summary, expires: time.Now().Add(c.ttl)}
+}
+
+func (c *summaryCache) invalidate(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+}
+
+// ChannelSummary aggregates userID's videos. The public summary counts only public
+// videos and may be up to CHANNEL_SUMMARY_TTL stale; the owner's, with
+// includePrivate, counts every video they have and is always fresh.
+func (s *VideoService) ChannelSummary(ctx context.Context, userID string, includePrivate bool) (*ChannelSummary, error) {
+	if !includePrivate {
+		if cached, ok := s.summaries.get(userID); ok {
+			return &cached, nil
+		}
+	}
+
+	scope := func() *gorm.DB {
+		query := s.db.WithContext(ctx).Model(&models.Video{}).Where("videos.user_id = ?", userID)
+		if !includePrivate {
+			query = query.Where("videos.visibility = ? AND videos.moderation_status <> ?",
+				models.VisibilityPublic, models.ModerationStatusTakenDown)
+		}
+		return query
+	}
+	var totals struct {
+		VideoCount    int64
+		TotalViews    int64
+		TotalDuration float64
+	}
+	err := scope().Select(`COUNT(*) AS video_count, COALESCE(SUM(view_count), 0) AS total_views,
+		COALESCE(SUM(duration), 0) AS total_duration`).Scan(&totals).Error
+	if err != nil {
+		s.log(ctx).Errorw("Failed to summarize channel", "error", err, "userID", userID)
+		return nil, fmt.Errorf("failed to summarize channel: %w", err)
+	}
+	// Read as a column rather than MAX(created_at), whose result some drivers hand
+	// back untyped
+	var latest []time.Time
+	err = scope().Order("videos.created_at DESC").Limit(1).Pluck("videos.created_at", &latest).Error
+	if err != nil {
+		s.log(ctx).Errorw("Failed to find latest upload", "error", err, "userID", userID)
+		return nil, fmt.Errorf("failed to summarize channel: %w", err)
+	}
+	categories := []CategoryCount{}
+	err = scope().Select("videos.category AS slug, MAX(categories.display_name) AS display_name, COUNT(*) AS count").
+		Joins("LEFT JOIN categories ON categories.slug = videos.category").
+		Where("videos.category <> ''").
+		Group("videos.category").Order("count DESC, slug").Limit(channelSummaryTopCategories).
+		Scan(&categories).Error
+	if err != nil {
+		s.log(ctx).Errorw("Failed to count channel categories", "error", err, "userID", userID)
+		return nil, fmt.Errorf("failed to summarize channel: %w", err)
+	}
+
+	summary := ChannelSummary{
+		UserID:        userID,
+		VideoCount:    totals.VideoCount,
+		TotalViews:    totals.TotalViews,
+		TotalDuration: totals.TotalDuration,
+		TopCategories: categories,
+	}
+	if len(latest) > 0 {
+		summary.LatestUploadAt = &latest[0]
+	}
+	if !includePrivate {
+		s.summaries.put(userID, summary)
+	}
+	return &summary, nil
+}
@@ -0,0 +1,109 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestChannelSummaryOwnerAndPublic(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	db.Save(&models.Category{Slug: "music", DisplayName: "Music", Active: true})
+	db.Save(&models.Category{Slug: "gaming", DisplayName: "Gaming", Active: true})
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, v := range []models.Video{
+		{Title: "a", Visibility: models.VisibilityPublic, Category: "music", ViewCount: 10, Duration: 60},
+		{Title: "b", Visibility: models.VisibilityPublic, Category: "music", ViewCount: 5, Duration: 30.5},
+		{Title: "c", Visibility: models.VisibilityPublic, Category: "gaming", ViewCount: 1, Duration: 10},
+		{Title: "d", Visibility: models.VisibilityPrivate, Category: "gaming", ViewCount: 100, Duration: 600},
+		{Title: "e", Visibility: models.VisibilityUnlisted, Category: "gaming", ViewCount: 50, Duration: 300},
+		{Title: "f", Visibility: models.VisibilityPublic, ModerationStatus: models.ModerationStatusTakenDown, ViewCount: 7, Duration: 1},
+		{Title: "other", UserID: "someone-else", Visibility: models.VisibilityPublic, Category: "music", ViewCount: 1000},
+	} {
+		v.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		createVideo(t, db, v)
+	}
+
+	tests := []struct {
+		name           string
+		includePrivate bool
+		count, views   int64
+		duration       float64
+		latest         time.Time
+		categories     []services.CategoryCount
+	}{
+		{"public", false, 3, 16, 100.5, base.Add(2 * time.Hour), []services.CategoryCount{
+			{Slug: "music", DisplayName: "Music", Count: 2}, {Slug: "gaming", DisplayName: "Gaming", Count: 1}}},
+		{"owner", true, 6, 173, 1001.5, base.Add(5 * time.Hour), []services.CategoryCount{
+			{Slug: "gaming", DisplayName: "Gaming", Count: 3}, {Slug: "music", DisplayName: "Music", Count: 2}}},
+	}
+	for _, tt := range tests {
+		got, err := videos.ChannelSummary(ctx, "owner", tt.includePrivate)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got.UserID != "owner" || got.VideoCount != tt.count || got.TotalViews != tt.views || got.TotalDuration != tt.duration {
+			t.Errorf("%s: %d videos, %d views, %vs; want %d, %d, %vs", tt.name, got.VideoCount, got.TotalViews, got.TotalDuration, tt.count, tt.views, tt.duration)
+		}
+		if got.LatestUploadAt == nil || !got.LatestUploadAt.Equal(tt.latest) {
+			t.Errorf("%s: latest upload %v, want %v", tt.name, got.LatestUploadAt, tt.latest)
+		}
+		if len(got.TopCategories) != len(tt.categories) {
+			t.Errorf("%s: categories %+v, want %+v", tt.name, got.TopCategories, tt.categories)
+			continue
+		}
+		for i := range tt.categories {
+			if got.TopCategories[i] != tt.categories[i] {
+				t.Errorf("%s: categories %+v, want %+v", tt.name, got.TopCategories, tt.categories)
+				break
+			}
+		}
+	}
+
+	empty, err := videos.ChannelSummary(ctx, "nobody", false)
+	if err != nil || empty.VideoCount != 0 || empty.LatestUploadAt != nil || empty.TopCategories == nil || len(empty.TopCategories) != 0 {
+		t.Errorf("empty channel = %+v, %v", empty, err)
+	}
+}
+
+func TestChannelSummaryCache(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{Title: "a", Visibility: models.VisibilityPublic, ViewCount: 1})
+	summary := func(includePrivate bool) int64 {
+		t.Helper()
+		got, err := videos.ChannelSummary(ctx, "owner", includePrivate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.TotalViews
+	}
+	summary(false)
+
+	// A write the service didn't see leaves the public summary cached; the
+	// owner's is always fresh
+	db.Model(&models.Video{}).Where("id = ?", video.ID).Update("view_count", 5)
+	if got := summary(false); got != 1 {
+		t.Errorf("public views %d, want the cached 1", got)
+	}
+	if got := summary(true); got != 5 {
+		t.Errorf("owner views %d, want 5", got)
+	}
+
+	// A change through the service drops the owner's entry
+	private := models.VisibilityPrivate
+	if _, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Visibility: &private}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := videos.ChannelSummary(ctx, "owner", false)
+	if got.VideoCount != 0 || got.TotalViews != 0 {
+		t.Errorf("public summary %+v after the video went private", got)
+	}
+}
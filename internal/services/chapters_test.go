@@ -0,0 +1,100 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestUpdateChapters(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	video := createVideo(t, db, models.Video{Title: "t", Duration: 120})
+	stored := func() []models.Chapter {
+		t.Helper()
+		got, err := videos.GetVideo(ctx, video.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.Chapters
+	}
+	if chapters := stored(); chapters != nil {
+		t.Fatalf("new video has chapters %+v, want none set", chapters)
+	}
+
+	set := []models.Chapter{{Title: " Intro ", StartSeconds: 0}, {Title: "Main", StartSeconds: 30}}
+	if _, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Chapters: &set}); err != nil {
+		t.Fatal(err)
+	}
+	if chapters := stored(); len(chapters) != 2 || chapters[0].Title != "Intro" || chapters[1].StartSeconds != 30 {
+		t.Errorf("stored chapters %+v", chapters)
+	}
+
+	// Past the known duration: refused per field, and nothing changes
+	bad := []models.Chapter{{Title: "Intro", StartSeconds: 0}, {Title: "Credits", StartSeconds: 120}}
+	_, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Chapters: &bad})
+	var invalid *services.ValidationError
+	if !errors.As(err, &invalid) || !errors.Is(err, services.ErrInvalidChapters) || invalid.Fields["chapters[1].start_seconds"] == "" {
+		t.Fatalf("chapter past the end: %v, want a ValidationError on chapters[1].start_seconds", err)
+	}
+	if chapters := stored(); len(chapters) != 2 || chapters[1].Title != "Main" {
+		t.Errorf("refused update changed chapters to %+v", chapters)
+	}
+
+	// Removing them stores an empty list, not "not set"
+	if _, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Chapters: &[]models.Chapter{}}); err != nil {
+		t.Fatal(err)
+	}
+	if chapters := stored(); chapters == nil || len(chapters) != 0 {
+		t.Errorf("removed chapters stored as %#v, want an empty list", chapters)
+	}
+}
+
+func TestTranscodedEventChapters(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	event := func(uploadID string) *models.TranscodedEvent {
+		e := transcodedEvent(uploadID)
+		e.Metadata = &models.VideoMetadata{Duration: 90, Chapters: []models.ChapterInfo{
+			{Title: "Main", StartSeconds: 20}, {Title: "Intro", StartSeconds: 0}, {Title: "Too late", StartSeconds: 95},
+		}}
+		return e
+	}
+	chapters := func(id uint) []models.Chapter {
+		t.Helper()
+		video, err := videos.GetVideo(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return video.Chapters
+	}
+
+	// Detected chapters fill a video that has none, sorted and without bad entries
+	detected := createVideo(t, db, models.Video{UploadID: "up-1", Title: "detected", Status: models.StatusProcessing})
+	if err := videos.HandleTranscodedEvent(ctx, event("up-1")); err != nil {
+		t.Fatal(err)
+	}
+	if got := chapters(detected.ID); len(got) != 2 || got[0].Title != "Intro" || got[1].Title != "Main" {
+		t.Errorf("detected chapters %+v, want Intro then Main", got)
+	}
+
+	// They never replace the creator's chapters, nor a removal
+	for name, own := range map[string][]models.Chapter{"set": {{Title: "Mine", StartSeconds: 5}}, "removed": {}} {
+		video := createVideo(t, db, models.Video{Title: name, Status: models.StatusProcessing})
+		if _, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Chapters: &own}); err != nil {
+			t.Fatal(err)
+		}
+		if err := videos.HandleTranscodedEvent(ctx, event(video.UploadID)); err != nil {
+			t.Fatal(err)
+		}
+		if got := chapters(video.ID); len(got) != len(own) {
+			t.Errorf("%s chapters %+v replaced by %+v", name, own, got)
+		}
+	}
+}
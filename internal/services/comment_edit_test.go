@@ -0,0 +1,71 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestUpdateComment(t *testing.T) {
+	db := dbtest.Open(t)
+	comments := services.NewCommentService(db, config.Comments{MaxDepth: 2, EditWindow: 10 * time.Minute}, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{Title: "a", UserID: "owner"})
+	fresh := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "alice", Content: "teh"})
+	old := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "alice", CreatedAt: time.Now().Add(-11 * time.Minute)})
+
+	if comments.EditWindow() != 10*time.Minute {
+		t.Errorf("EditWindow() = %s, want the configured 10m", comments.EditWindow())
+	}
+	edited, err := comments.UpdateComment(ctx, fresh.ID, "alice", "the")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Content != "the" || edited.EditedAt == nil || time.Since(*edited.EditedAt) > time.Minute {
+		t.Errorf("edited comment = %+v", edited)
+	}
+	stored, _ := comments.GetComment(ctx, fresh.ID)
+	if stored.Content != "the" || stored.EditedAt == nil {
+		t.Errorf("stored comment = %+v", stored)
+	}
+
+	// Video owners may delete others' comments but not edit them
+	if _, err := comments.UpdateComment(ctx, fresh.ID, "owner", "rewritten"); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("edit by the video owner: %v, want ErrForbidden", err)
+	}
+	if _, err := comments.UpdateComment(ctx, old.ID, "alice", "too late"); !errors.Is(err, services.ErrEditWindowClosed) {
+		t.Errorf("edit after the window: %v, want ErrEditWindowClosed", err)
+	}
+	var invalid *services.ValidationError
+	if _, err := comments.UpdateComment(ctx, fresh.ID, "alice", strings.Repeat("x", models.MaxCommentLen+1)); !errors.As(err, &invalid) {
+		t.Errorf("oversized edit: %v, want a ValidationError", err)
+	}
+	if _, err := comments.UpdateComment(ctx, 999, "alice", "x"); !errors.Is(err, services.ErrCommentNotFound) {
+		t.Errorf("edit of an unknown comment: %v, want ErrCommentNotFound", err)
+	}
+	if stored, _ := comments.GetComment(ctx, fresh.ID); stored.Content != "the" {
+		t.Errorf("rejected edits changed the content to %q", stored.Content)
+	}
+}
+
+func TestUpdateCommentTombstone(t *testing.T) {
+	db := dbtest.Open(t)
+	comments := services.NewCommentService(db, commentSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{Title: "a", CommentsEnabled: true})
+	root := reply(t, comments, video.ID, "alice", nil)
+	reply(t, comments, video.ID, "bob", root)
+	if err := comments.DeleteComment(ctx, root.ID, "alice", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comments.UpdateComment(ctx, root.ID, "alice", "back"); !errors.Is(err, services.ErrCommentNotFound) {
+		t.Errorf("edit of a tombstone: %v, want ErrCommentNotFound", err)
+	}
+}
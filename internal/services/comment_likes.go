@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// CommentLikes is a comment's like count after a like or unlike, along with
+// whether the caller now likes it
+type CommentLikes struct {
+	CommentID uint  `json:"comment_id"`
+	LikeCount int64 `json:"like_count"`
+	Liked     bool  `json:"liked"`
+}
+
+// LikeComment records userID's like of a visible comment. Liking again changes
+// nothing. The row and the comment's like_count change in one transaction, and
+// the count moves only when a row was actually inserted, so retries and races
+// can't inflate it.
+func (s *CommentService) LikeComment(ctx context.Context, commentID uint, userID string) (*CommentLikes, error) {
+	likes := &CommentLikes{CommentID: commentID, Liked: true}
+	outcome := "unchanged"
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockLikeableComment(tx, commentID); err != nil {
+			return err
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.CommentReaction{CommentID: commentID, UserID: userID})
+		if res.Error != nil {
+			return fmt.Errorf("insert comment like: %w", res.Error)
+		}
+		if res.RowsAffected == 1 {
+			outcome = "liked"
+			if err := adjustCommentLikes(tx, commentID, 1); err != nil {
+				return err
+			}
+		}
+		return loadCommentLikes(tx, likes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.CommentLikesTotal.WithLabelValues(outcome).Inc()
+	return likes, nil
+}
+
+// UnlikeComment removes userID's like of a comment. Unliking a comment that was
+// never liked succeeds and changes nothing.
+func (s *CommentService) UnlikeComment(ctx context.Context, commentID uint, userID string) (*CommentLikes, error) {
+	likes := &CommentLikes{CommentID: commentID}
+	outcome := "unchanged"
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockLikeableComment(tx, commentID); err != nil {
+			return err
+		}
+		res := tx.Where("comment_id = ? AND user_id = ?", commentID, userID).Delete(&models.CommentReaction{})
+		if res.Error != nil {
+			return fmt.Errorf("delete comment like: %w", res.Error)
+		}
+		if res.RowsAffected == 1 {
+			outcome = "unliked"
+			if err := adjustCommentLikes(tx, commentID, -1); err != nil {
+				return err
+			}
+		}
+		return loadCommentLikes(tx, likes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.CommentLikesTotal.WithLabelValues(outcome).Inc()
+	return likes, nil
+}
+
+// UserCommentLikes returns which of the given comments userID likes, with one
+// query; comments without a like are absent from the map
+func (s *CommentService) UserCommentLikes(ctx context.Context, userID string, commentIDs []uint) (map[uint]bool, error) {
+	out := make(map[uint]bool, len(commentIDs))
+	if userID == "" || len(commentIDs) == 0 {
+		return out, nil
+	}
+	var liked []uint
+	if err := s.reader(ctx).Model(&models.CommentReaction{}).
+		Where("user_id = ? AND comment_id IN ?", userID, commentIDs).Pluck("comment_id", &liked).Error; err != nil {
+		return nil, fmt.Errorf("load comment likes: %w", err)
+	}
+	for _, id := range liked {
+		out[id] = true
+	}
+	return out, nil
+}
+
+// lockLikeableComment row-locks a visible comment, so a like and a concurrent
+// delete of the comment are ordered
+func lockLikeableComment(tx *gorm.DB, commentID uint) error {
+	var comment models.Comment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+		Where("id = ? AND status = ?", commentID, models.CommentVisible).First(&comment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("comment %d: %w", commentID, ErrCommentNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("lock comment: %w", err)
+	}
+	return nil
+}
+
+// adjustCommentLikes moves a comment's like_count by delta, never below zero
+func adjustCommentLikes(tx *gorm.DB, commentID uint, delta int) error {
+	err := tx.Model(&models.Comment{}).Where("id = ?", commentID).
+		UpdateColumn("like_count", gorm.Expr("GREATEST(like_count + ?, 0)", delta)).Error
+	if err != nil {
+		return fmt.Errorf("update comment like count: %w", err)
+	}
+	return nil
+}
+
+func loadCommentLikes(tx *gorm.DB, likes *CommentLikes) error {
+	var comment models.Comment
+	if err := tx.Select("id", "like_count").First(&comment, likes.CommentID).Error; err != nil {
+		return fmt.Errorf("load comment like count: %w", err)
+	}
+	likes.LikeCount = comment.LikeCount
+	return nil
+}
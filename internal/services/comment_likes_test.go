@@ -0,0 +1,141 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestLikeComment(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	comments := services.NewCommentService(db, commentSettings, nopLogger())
+	video := createVideo(t, db, models.Video{Title: "t"})
+	comment := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "author"})
+
+	steps := []struct {
+		name  string
+		like  bool
+		user  string
+		count int64
+		liked bool
+	}{
+		{"unlike, never liked", false, "u-1", 0, false},
+		{"like", true, "u-1", 1, true},
+		{"like twice", true, "u-1", 1, true},
+		{"another user", true, "u-2", 2, true},
+		{"unlike", false, "u-1", 1, false},
+		{"unlike twice", false, "u-1", 1, false},
+		{"like again", true, "u-1", 2, true},
+	}
+	for _, step := range steps {
+		var (
+			likes *services.CommentLikes
+			err   error
+		)
+		if step.like {
+			likes, err = comments.LikeComment(ctx, comment.ID, step.user)
+		} else {
+			likes, err = comments.UnlikeComment(ctx, comment.ID, step.user)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if likes.CommentID != comment.ID || likes.LikeCount != step.count || likes.Liked != step.liked {
+			t.Errorf("%s: %+v, want %d likes, liked %v", step.name, likes, step.count, step.liked)
+		}
+	}
+	var stored models.Comment
+	db.First(&stored, comment.ID)
+	var rows int64
+	db.Model(&models.CommentReaction{}).Where("comment_id = ?", comment.ID).Count(&rows)
+	if stored.LikeCount != 2 || rows != 2 {
+		t.Errorf("like_count %d with %d reaction rows, want 2 and 2", stored.LikeCount, rows)
+	}
+}
+
+func TestLikeHiddenComment(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	comments := services.NewCommentService(db, commentSettings, nopLogger())
+	video := createVideo(t, db, models.Video{Title: "t"})
+
+	for _, status := range []string{models.CommentPending, models.CommentDeleted} {
+		hidden := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "author", Status: status})
+		if _, err := comments.LikeComment(ctx, hidden.ID, "u-1"); !errors.Is(err, services.ErrCommentNotFound) {
+			t.Errorf("like %s comment: %v, want ErrCommentNotFound", status, err)
+		}
+		if _, err := comments.UnlikeComment(ctx, hidden.ID, "u-1"); !errors.Is(err, services.ErrCommentNotFound) {
+			t.Errorf("unlike %s comment: %v, want ErrCommentNotFound", status, err)
+		}
+	}
+	if _, err := comments.LikeComment(ctx, 999, "u-1"); !errors.Is(err, services.ErrCommentNotFound) {
+		t.Errorf("like missing comment: %v, want ErrCommentNotFound", err)
+	}
+	var rows int64
+	db.Model(&models.CommentReaction{}).Count(&rows)
+	if rows != 0 {
+		t.Errorf("%d reaction rows for hidden comments", rows)
+	}
+}
+
+func TestCommentLikesOrderTopAndFlagTheCaller(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	comments := services.NewCommentService(db, commentSettings, nopLogger())
+	video := createVideo(t, db, models.Video{Title: "t"})
+	base := time.Now().UTC().Add(-time.Hour)
+	a := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", Content: "a", CreatedAt: base})
+	b := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", Content: "b", CreatedAt: base.Add(time.Minute)})
+	c := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", Content: "c", CreatedAt: base.Add(2 * time.Minute)})
+	createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", Content: "d", CreatedAt: base.Add(3 * time.Minute)})
+
+	// a and c tie on two likes, so the newer c ranks first; b has one
+	for id, users := range map[uint][]string{a.ID: {"u-1", "u-2"}, b.ID: {"u-2", "u-3", "u-3"}, c.ID: {"u-1", "u-3"}} {
+		for _, user := range users {
+			if _, err := comments.LikeComment(ctx, id, user); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if _, err := comments.UnlikeComment(ctx, b.ID, "u-3"); err != nil {
+		t.Fatal(err)
+	}
+	list, _, err := comments.ListComments(ctx, video.ID, services.CommentSortTop, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ""
+	for _, comment := range list {
+		got += comment.Content
+	}
+	if got != "cabd" {
+		t.Errorf("top = %s, want cabd", got)
+	}
+
+	ids := []uint{a.ID, b.ID, c.ID}
+	for user, want := range map[string][]bool{
+		"u-1": {true, false, true},
+		"u-2": {true, true, false},
+		"u-3": {false, false, true},
+		"u-4": {false, false, false},
+	} {
+		liked, err := comments.UserCommentLikes(ctx, user, ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, id := range ids {
+			if liked[id] != want[i] {
+				t.Errorf("%s likes comment %d: %v, want %v", user, id, liked[id], want[i])
+			}
+		}
+	}
+	if liked, err := comments.UserCommentLikes(ctx, "", ids); err != nil || len(liked) != 0 {
+		t.Errorf("anonymous likes = %v, %v; want none", liked, err)
+	}
+}
@@ -1,67 +1,477 @@
 package services
 
 import (
+    "context"
+    "errors"
     "fmt"
+    "time"
+    "unicode/utf8"
 
     "go.uber.org/zap"
     "gorm.io/gorm"
+    "gorm.io/gorm/clause"
 
+    "github.com/streamhive/video-catalog-api/internal/config"
+    "github.com/streamhive/video-catalog-api/internal/cursor"
+    "github.com/streamhive/video-catalog-api/internal/db"
+    "github.com/streamhive/video-catalog-api/internal/logging"
     "github.com/streamhive/video-catalog-api/internal/models"
 )
 
 type CommentService struct {
-    db     *gorm.DB
-    logger *zap.SugaredLogger
+    db         *gorm.DB
+    replica    *gorm.DB
+    logger     *zap.SugaredLogger
+    moderation *ModerationService
+    notifier   *NotificationService
+    // maxDepth is how deep replies may nest; 1 allows replies to top-level comments only
+    maxDepth   int
+    // editWindow is how long after posting the author may edit a comment
+    editWindow time.Duration
 }
 
-func NewCommentService(db *gorm.DB, logger *zap.SugaredLogger) *CommentService {
-    return &CommentService{db: db, logger: logger}
+// threadStatuses are the comment states shown in threads: tombstones stay so their
+// replies keep their context
+var threadStatuses = []string{models.CommentVisible, models.CommentDeleted}
+
+func NewCommentService(db *gorm.DB, cfg config.Comments, logger *zap.SugaredLogger) *CommentService {
+    return &CommentService{db: db, logger: logger, maxDepth: cfg.MaxDepth, editWindow: cfg.EditWindow}
+}
+
+// MaxDepth is how deep replies may nest
+func (s *CommentService) MaxDepth() int { return s.maxDepth }
+
+// EditWindow is how long after posting the author may edit a comment
+func (s *CommentService) EditWindow() time.Duration { return s.editWindow }
+
+// SetModeration attaches the post-write moderation hook for comment content
+func (s *CommentService) SetModeration(m *ModerationService) { s.moderation = m }
+
+// SetNotifications attaches the post-commit owner notification hook
+func (s *CommentService) SetNotifications(n *NotificationService) { s.notifier = n }
+
+// SetReplica routes comment list reads to a read replica unless the context asks
+// for the primary (see db.WithPrimary)
+func (s *CommentService) SetReplica(replica *gorm.DB) { s.replica = replica }
+
+// log is the service's logger tagged with ctx's request ID
+func (s *CommentService) log(ctx context.Context) *zap.SugaredLogger {
+    return logging.For(ctx, s.logger)
+}
+
+func (s *CommentService) reader(ctx context.Context) *gorm.DB {
+    return db.Reader(ctx, s.db, s.replica)
+}
+
+// validateCommentContent enforces the comment length limit here as well as in
+// request binding, so no caller can store an oversized comment
+func validateCommentContent(content string) error {
+    if utf8.RuneCountInString(content) > models.MaxCommentLen {
+        return &ValidationError{Err: ErrInvalidComment, Fields: map[string]string{
+            "content": fmt.Sprintf("must be at most %d characters", models.MaxCommentLen)}}
+    }
+    return nil
 }
 
-func (s *CommentService) AddComment(videoID uint, userID, username, content string) (*models.Comment, error) {
+// AddComment posts a comment on a video, or a reply when parentID is set. The parent
+// must be a visible comment on the same video, and the reply no deeper than maxDepth.
+func (s *CommentService) AddComment(ctx context.Context, videoID uint, userID, username, content string, parentID *uint) (*models.Comment, error) {
+    if err := validateCommentContent(content); err != nil {
+        return nil, err
+    }
     // Ensure video exists and visibility allows commenting (basic existence check here)
     var v models.Video
-    if err := s.db.First(&v, videoID).Error; err != nil {
+    if err := s.db.WithContext(ctx).First(&v, videoID).Error; err != nil {
         if err == gorm.ErrRecordNotFound {
-            return nil, fmt.Errorf("video not found")
+            return nil, fmt.Errorf("video %d: %w", videoID, ErrVideoNotFound)
         }
         return nil, fmt.Errorf("lookup video: %w", err)
     }
-    c := &models.Comment{VideoID: videoID, UserID: userID, Username: username, Content: content}
-    if err := s.db.Create(c).Error; err != nil {
-        s.logger.Errorw("create comment", "err", err)
+    if !v.CommentsEnabled {
+        return nil, fmt.Errorf("video %d: %w", videoID, ErrCommentsDisabled)
+    }
+    c := &models.Comment{VideoID: videoID, UserID: userID, Username: username, Content: content, Status: models.CommentVisible}
+    // Insert and bump the denormalized counter atomically so a crash can't split them
+    err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if parentID != nil {
+            // The share lock keeps the parent from being deleted under the reply
+            var parent models.Comment
+            if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+                Select("id", "video_id", "depth", "status").First(&parent, *parentID).Error; err != nil {
+                if err == gorm.ErrRecordNotFound {
+                    return fmt.Errorf("parent comment %d: %w", *parentID, ErrInvalidParent)
+                }
+                return err
+            }
+            if parent.VideoID != videoID || parent.Status != models.CommentVisible {
+                return fmt.Errorf("parent comment %d: %w", *parentID, ErrInvalidParent)
+            }
+            if parent.Depth+1 > s.maxDepth {
+                return fmt.Errorf("reply to comment %d at depth %d: %w", *parentID, parent.Depth+1, ErrReplyTooDeep)
+            }
+            c.ParentID, c.Depth = &parent.ID, parent.Depth+1
+        }
+        if err := tx.Create(c).Error; err != nil {
+            return err
+        }
+        return tx.Model(&models.Video{}).Where("id = ?", videoID).
+            UpdateColumn("comment_count", gorm.Expr("comment_count + 1")).Error
+    })
+    if errors.Is(err, ErrInvalidParent) || errors.Is(err, ErrReplyTooDeep) {
+        return nil, err
+    }
+    if err != nil {
+        s.log(ctx).Errorw("create comment", "err", err, "videoID", videoID, logging.CommentText(content))
         return nil, fmt.Errorf("failed to create comment: %w", err)
     }
+    s.moderation.SubmitComment(c.ID, c.Content)
+    // The comment is already committed; a failed notification must not fail it
+    if err := s.notifier.NotifyComment(context.WithoutCancel(ctx), c); err != nil {
+        s.log(ctx).Warnw("Failed to notify video owner", "error", err, "commentID", c.ID)
+    }
     return c, nil
 }
 
-func (s *CommentService) ListComments(videoID uint, page, perPage int) ([]models.Comment, int64, error) {
+// CommentSort is the order of a video's top-level comments. The pinned comment
+// always comes first.
+type CommentSort string
+
+const (
+    CommentSortNewest CommentSort = "newest"
+    CommentSortOldest CommentSort = "oldest"
+    // CommentSortTop ranks by likes, newest first among equals
+    CommentSortTop CommentSort = "top"
+)
+
+// CommentSorts lists the accepted comment orders
+var CommentSorts = []CommentSort{CommentSortNewest, CommentSortOldest, CommentSortTop}
+
+// commentOrders maps each sort to its ORDER BY clause; nothing from the request
+// reaches it. id breaks ties so pages stay stable.
+var commentOrders = map[CommentSort]string{
+    CommentSortNewest: "pinned DESC, created_at DESC, id DESC",
+    CommentSortOldest: "pinned DESC, created_at ASC, id ASC",
+    CommentSortTop:    "pinned DESC, like_count DESC, created_at DESC, id DESC",
+}
+
+// ParseCommentSort validates a ?sort= value; "" means newest
+func ParseCommentSort(raw string) (CommentSort, error) {
+    if raw == "" {
+        return CommentSortNewest, nil
+    }
+    sort := CommentSort(raw)
+    if _, ok := commentOrders[sort]; !ok {
+        return "", fmt.Errorf("%w %q: use one of newest, oldest, top", ErrInvalidSort, raw)
+    }
+    return sort, nil
+}
+
+// ListComments returns a page of the video's top-level comments in the given order,
+// each with its reply count
+func (s *CommentService) ListComments(ctx context.Context, videoID uint, sort CommentSort, page, perPage int) ([]models.Comment, int64, error) {
+    order, ok := commentOrders[sort]
+    if !ok {
+        return nil, 0, fmt.Errorf("%w %q", ErrInvalidSort, sort)
+    }
     // Pagination with newest first
     if page < 1 { page = 1 }
     if perPage < 1 || perPage > 100 { perPage = 20 }
 
     var total int64
-    if err := s.db.Model(&models.Comment{}).Where("video_id = ?", videoID).Count(&total).Error; err != nil {
+    if err := s.topLevel(ctx, videoID).Model(&models.Comment{}).Count(&total).Error; err != nil {
         return nil, 0, fmt.Errorf("count comments: %w", err)
     }
 
     var out []models.Comment
-    if err := s.db.Where("video_id = ?", videoID).
-        Order("created_at DESC").
+    if err := s.topLevel(ctx, videoID).
+        Order(order).
         Limit(perPage).
         Offset((page-1)*perPage).
         Find(&out).Error; err != nil {
         return nil, 0, fmt.Errorf("list comments: %w", err)
     }
+    if err := s.fillReplyCounts(ctx, out); err != nil {
+        return nil, 0, err
+    }
+    return out, total, nil
+}
+
+// ListCommentsAfter returns up to limit top-level comments older than after (newest
+// first, keyset on created_at/id), and whether more remain. The pinned comment
+// belongs to the first page, so it is never returned here.
+func (s *CommentService) ListCommentsAfter(ctx context.Context, videoID uint, after *cursor.TimeID, limit int) ([]models.Comment, bool, error) {
+    q := s.topLevel(ctx, videoID).Where("NOT pinned")
+    if after != nil {
+        q = q.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+    }
+    var out []models.Comment
+    if err := q.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&out).Error; err != nil {
+        return nil, false, fmt.Errorf("list comments: %w", err)
+    }
+    more := len(out) > limit
+    if more {
+        out = out[:limit]
+    }
+    if err := s.fillReplyCounts(ctx, out); err != nil {
+        return nil, false, err
+    }
+    return out, more, nil
+}
+
+// topLevel selects the video's top-level comments as threads show them
+func (s *CommentService) topLevel(ctx context.Context, videoID uint) *gorm.DB {
+    return s.reader(ctx).Where("video_id = ? AND parent_id IS NULL AND status IN ?", videoID, threadStatuses)
+}
+
+// ListReplies returns a page of a comment's direct replies, oldest first so the
+// conversation reads in order, each with its own reply count
+func (s *CommentService) ListReplies(ctx context.Context, parentID uint, page, perPage int) ([]models.Comment, int64, error) {
+    if page < 1 { page = 1 }
+    if perPage < 1 || perPage > 100 { perPage = 20 }
+
+    replies := func() *gorm.DB {
+        return s.reader(ctx).Where("parent_id = ? AND status IN ?", parentID, threadStatuses)
+    }
+    var total int64
+    if err := replies().Model(&models.Comment{}).Count(&total).Error; err != nil {
+        return nil, 0, fmt.Errorf("count replies: %w", err)
+    }
+    var out []models.Comment
+    if err := replies().
+        Order("created_at ASC, id ASC").
+        Limit(perPage).
+        Offset((page-1)*perPage).
+        Find(&out).Error; err != nil {
+        return nil, 0, fmt.Errorf("list replies: %w", err)
+    }
+    if err := s.fillReplyCounts(ctx, out); err != nil {
+        return nil, 0, err
+    }
     return out, total, nil
 }
 
-func (s *CommentService) DeleteComment(commentID uint, requesterID string, isOwnerOrAuthor bool) error {
+// ReplyCount returns how many replies are shown under a comment
+func (s *CommentService) ReplyCount(ctx context.Context, parentID uint) (int64, error) {
+    var count int64
+    if err := s.reader(ctx).Model(&models.Comment{}).Where("parent_id = ? AND status IN ?", parentID, threadStatuses).Count(&count).Error; err != nil {
+        return 0, fmt.Errorf("count replies: %w", err)
+    }
+    return count, nil
+}
+
+// fillReplyCounts sets ReplyCount on each comment with one grouped query
+func (s *CommentService) fillReplyCounts(ctx context.Context, comments []models.Comment) error {
+    if len(comments) == 0 {
+        return nil
+    }
+    ids := make([]uint, len(comments))
+    for i := range comments {
+        ids[i] = comments[i].ID
+    }
+    var rows []struct {
+        ParentID uint
+        Replies  int64
+    }
+    if err := s.reader(ctx).Model(&models.Comment{}).
+        Select("parent_id, COUNT(*) AS replies").
+        Where("parent_id IN ? AND status IN ?", ids, threadStatuses).
+        Group("parent_id").
+        Scan(&rows).Error; err != nil {
+        return fmt.Errorf("count replies: %w", err)
+    }
+    counts := make(map[uint]int64, len(rows))
+    for _, r := range rows {
+        counts[r.ParentID] = r.Replies
+    }
+    for i := range comments {
+        comments[i].ReplyCount = counts[comments[i].ID]
+    }
+    return nil
+}
+
+// VisibleCount returns the video's denormalized visible comment count
+func (s *CommentService) VisibleCount(ctx context.Context, videoID uint) (int64, error) {
+    var count int64
+    if err := s.reader(ctx).Model(&models.Video{}).Select("comment_count").Where("id = ?", videoID).Scan(&count).Error; err != nil {
+        return 0, fmt.Errorf("count comments: %w", err)
+    }
+    return count, nil
+}
+
+// UpdateComment replaces a comment's content and stamps edited_at. Only the author
+// may edit, and only within editWindow of posting; tombstones can't be edited.
+func (s *CommentService) UpdateComment(ctx context.Context, commentID uint, userID, content string) (*models.Comment, error) {
+    if err := validateCommentContent(content); err != nil {
+        return nil, err
+    }
+    var c models.Comment
+    err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, commentID).Error; err != nil {
+            if err == gorm.ErrRecordNotFound {
+                return fmt.Errorf("comment %d: %w", commentID, ErrCommentNotFound)
+            }
+            return err
+        }
+        if c.Status == models.CommentDeleted {
+            return fmt.Errorf("comment %d is deleted: %w", commentID, ErrCommentNotFound)
+        }
+        if c.UserID != userID {
+            return fmt.Errorf("edit comment %d: %w", commentID, ErrForbidden)
+        }
+        now := time.Now().UTC()
+        if now.Sub(c.CreatedAt) > s.editWindow {
+            return fmt.Errorf("edit comment %d posted at %s: %w", commentID, c.CreatedAt.Format(time.RFC3339), ErrEditWindowClosed)
+        }
+        c.Content, c.EditedAt = content, &now
+        return tx.Model(&c).Updates(map[string]interface{}{"content": content, "edited_at": now}).Error
+    })
+    if err != nil {
+        if errors.Is(err, ErrCommentNotFound) || errors.Is(err, ErrForbidden) || errors.Is(err, ErrEditWindowClosed) {
+            return nil, err
+        }
+        s.log(ctx).Errorw("update comment", "err", err, "commentID", commentID, logging.CommentText(content))
+        return nil, fmt.Errorf("failed to update comment: %w", err)
+    }
+    // The edited text is moderated like new text
+    s.moderation.SubmitComment(c.ID, c.Content)
+    return &c, nil
+}
+
+// SetPinned pins or unpins a top-level comment. Only the video's owner may, and a
+// video has at most one pinned comment: pinning one unpins the previous.
+func (s *CommentService) SetPinned(ctx context.Context, commentID uint, userID string, pinned bool) (*models.Comment, error) {
+    var c models.Comment
+    err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, commentID).Error; err != nil {
+            if err == gorm.ErrRecordNotFound {
+                return fmt.Errorf("comment %d: %w", commentID, ErrCommentNotFound)
+            }
+            return err
+        }
+        if c.Status == models.CommentDeleted {
+            return fmt.Errorf("comment %d is deleted: %w", commentID, ErrCommentNotFound)
+        }
+        var owner string
+        if err := tx.Model(&models.Video{}).Select("user_id").Where("id = ?", c.VideoID).Scan(&owner).Error; err != nil {
+            return err
+        }
+        if owner != userID {
+            return fmt.Errorf("pin comment %d: %w", commentID, ErrForbidden)
+        }
+        if !pinned {
+            c.Pinned = false
+            return tx.Model(&c).UpdateColumn("pinned", false).Error
+        }
+        if c.ParentID != nil || c.Status != models.CommentVisible {
+            return fmt.Errorf("pin comment %d: %w", commentID, ErrNotPinnable)
+        }
+        if err := tx.Model(&models.Comment{}).Where("video_id = ? AND pinned AND id <> ?", c.VideoID, c.ID).
+            UpdateColumn("pinned", false).Error; err != nil {
+            return err
+        }
+        c.Pinned = true
+        return tx.Model(&c).UpdateColumn("pinned", true).Error
+    })
+    if err != nil {
+        if errors.Is(err, ErrCommentNotFound) || errors.Is(err, ErrForbidden) || errors.Is(err, ErrNotPinnable) {
+            return nil, err
+        }
+        return nil, fmt.Errorf("failed to pin comment: %w", err)
+    }
+    return &c, nil
+}
+
+// GetComment loads a single comment, whatever its moderation status
+func (s *CommentService) GetComment(ctx context.Context, commentID uint) (*models.Comment, error) {
+    var c models.Comment
+    if err := s.db.WithContext(ctx).First(&c, commentID).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, fmt.Errorf("comment %d: %w", commentID, ErrCommentNotFound)
+        }
+        return nil, fmt.Errorf("get comment: %w", err)
+    }
+    return &c, nil
+}
+
+// DeleteComment deletes a comment. One with replies is tombstoned instead, keeping
+// its place in the thread as "[deleted]"; a tombstone left without replies goes too.
+func (s *CommentService) DeleteComment(ctx context.Context, commentID uint, requesterID string, isOwnerOrAuthor bool) error {
     if !isOwnerOrAuthor {
-        return fmt.Errorf("forbidden")
+        return fmt.Errorf("delete comment %d: %w", commentID, ErrForbidden)
     }
-    if err := s.db.Delete(&models.Comment{}, commentID).Error; err != nil {
+    err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        var c models.Comment
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+            Select("id", "video_id", "parent_id", "status").First(&c, commentID).Error; err != nil {
+            if err == gorm.ErrRecordNotFound {
+                return fmt.Errorf("comment %d: %w", commentID, ErrCommentNotFound)
+            }
+            return err
+        }
+        if c.Status == models.CommentDeleted {
+            return fmt.Errorf("comment %d already deleted: %w", commentID, ErrCommentNotFound)
+        }
+        var replies int64
+        if err := tx.Model(&models.Comment{}).Where("parent_id = ?", commentID).Count(&replies).Error; err != nil {
+            return err
+        }
+        if replies > 0 {
+            if err := tx.Model(&models.Comment{}).Where("id = ?", commentID).UpdateColumns(map[string]interface{}{
+                "status":   models.CommentDeleted,
+                "content":  models.CommentTombstone,
+                "username": "",
+                "pinned":   false,
+            }).Error; err != nil {
+                return err
+            }
+        } else {
+            res := tx.Delete(&models.Comment{}, commentID)
+            if res.Error != nil {
+                return res.Error
+            }
+            if err := pruneTombstones(tx, c.ParentID); err != nil {
+                return err
+            }
+        }
+        // Only a visible comment was counted; pending ones were uncounted when hidden
+        if c.Status != models.CommentVisible {
+            return nil
+        }
+        return tx.Model(&models.Video{}).Where("id = ?", c.VideoID).
+            UpdateColumn("comment_count", gorm.Expr("GREATEST(comment_count - 1, 0)")).Error
+    })
+    if err != nil {
         return fmt.Errorf("delete comment: %w", err)
     }
     return nil
 }
+
+// pruneTombstones deletes tombstoned ancestors, starting at parentID, that have no
+// replies left. Tombstones are never counted, so comment_count is unaffected.
+func pruneTombstones(tx *gorm.DB, parentID *uint) error {
+    for parentID != nil {
+        var parent models.Comment
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+            Select("id", "parent_id", "status").First(&parent, *parentID).Error; err != nil {
+            if err == gorm.ErrRecordNotFound {
+                return nil
+            }
+            return err
+        }
+        if parent.Status != models.CommentDeleted {
+            return nil
+        }
+        var replies int64
+        if err := tx.Model(&models.Comment{}).Where("parent_id = ?", parent.ID).Count(&replies).Error; err != nil {
+            return err
+        }
+        if replies > 0 {
+            return nil
+        }
+        if err := tx.Delete(&models.Comment{}, parent.ID).Error; err != nil {
+            return err
+        }
+        parentID = parent.ParentID
+    }
+    return nil
+}
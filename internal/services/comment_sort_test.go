@@ -0,0 +1,153 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestParseCommentSort(t *testing.T) {
+	for raw, want := range map[string]services.CommentSort{
+		"":       services.CommentSortNewest,
+		"newest": services.CommentSortNewest,
+		"oldest": services.CommentSortOldest,
+		"top":    services.CommentSortTop,
+	} {
+		if got, err := services.ParseCommentSort(raw); err != nil || got != want {
+			t.Errorf("ParseCommentSort(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"likes", "NEWEST", "created_at; DROP TABLE comments"} {
+		if _, err := services.ParseCommentSort(raw); !errors.Is(err, services.ErrInvalidSort) {
+			t.Errorf("ParseCommentSort(%q): %v, want ErrInvalidSort", raw, err)
+		}
+	}
+}
+
+func TestListCommentsSorted(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	comments := services.NewCommentService(db, commentSettings, nopLogger())
+	video := createVideo(t, db, models.Video{Title: "t"})
+	base := time.Now().UTC().Add(-time.Hour)
+	// a oldest, d newest; b and c tie on likes, so the newer of them ranks first
+	a := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", Content: "a", CreatedAt: base, LikeCount: 1})
+	b := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", Content: "b", CreatedAt: base.Add(time.Minute), LikeCount: 5})
+	createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", Content: "c", CreatedAt: base.Add(2 * time.Minute), LikeCount: 5})
+	createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", Content: "d", CreatedAt: base.Add(3 * time.Minute)})
+	// Replies and hidden comments are never listed
+	createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", Content: "reply", ParentID: &a.ID, Depth: 1, LikeCount: 9})
+	createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", Content: "pending", Status: models.CommentPending, LikeCount: 9})
+
+	order := func(sort services.CommentSort, page, perPage int) string {
+		t.Helper()
+		list, total, err := comments.ListComments(ctx, video.ID, sort, page, perPage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 4 {
+			t.Errorf("%s: total %d, want 4", sort, total)
+		}
+		got := ""
+		for _, c := range list {
+			got += c.Content
+		}
+		return got
+	}
+	tests := []struct {
+		sort services.CommentSort
+		want string
+	}{
+		{services.CommentSortNewest, "dcba"},
+		{services.CommentSortOldest, "abcd"},
+		{services.CommentSortTop, "cbad"},
+	}
+	for _, tt := range tests {
+		if got := order(tt.sort, 1, 10); got != tt.want {
+			t.Errorf("%s: %s, want %s", tt.sort, got, tt.want)
+		}
+		// Pages split the same order
+		if got := order(tt.sort, 1, 3) + order(tt.sort, 2, 3); got != tt.want {
+			t.Errorf("%s in pages of 3: %s, want %s", tt.sort, got, tt.want)
+		}
+	}
+	if _, _, err := comments.ListComments(ctx, video.ID, "likes", 1, 10); !errors.Is(err, services.ErrInvalidSort) {
+		t.Errorf("unknown sort: %v, want ErrInvalidSort", err)
+	}
+
+	// The pinned comment leads every order
+	if _, err := comments.SetPinned(ctx, b.ID, "owner", true); err != nil {
+		t.Fatal(err)
+	}
+	for sort, want := range map[services.CommentSort]string{
+		services.CommentSortNewest: "bdca",
+		services.CommentSortOldest: "bacd",
+		services.CommentSortTop:    "bcad",
+	} {
+		if got := order(sort, 1, 10); got != want {
+			t.Errorf("%s with b pinned: %s, want %s", sort, got, want)
+		}
+	}
+}
+
+func TestSetPinned(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	comments := services.NewCommentService(db, commentSettings, nopLogger())
+	video := createVideo(t, db, models.Video{Title: "t"})
+	first := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u"})
+	second := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u"})
+	reply := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", ParentID: &first.ID, Depth: 1})
+	pending := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", Status: models.CommentPending})
+	deleted := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "u", Status: models.CommentDeleted})
+
+	pinned := func() []uint {
+		var ids []uint
+		db.Model(&models.Comment{}).Where("pinned").Order("id").Pluck("id", &ids)
+		return ids
+	}
+	if _, err := comments.SetPinned(ctx, first.ID, "owner", true); err != nil {
+		t.Fatal(err)
+	}
+	// Pinning another unpins the first: one pinned comment per video
+	got, err := comments.SetPinned(ctx, second.ID, "owner", true)
+	if err != nil || !got.Pinned {
+		t.Fatalf("pin second = %+v, %v", got, err)
+	}
+	if ids := pinned(); len(ids) != 1 || ids[0] != second.ID {
+		t.Errorf("pinned %v, want only the second comment", ids)
+	}
+
+	errs := []struct {
+		name string
+		id   uint
+		user string
+		want error
+	}{
+		{"not the video owner", first.ID, "u", services.ErrForbidden},
+		{"a reply", reply.ID, "owner", services.ErrNotPinnable},
+		{"pending", pending.ID, "owner", services.ErrNotPinnable},
+		{"deleted", deleted.ID, "owner", services.ErrCommentNotFound},
+		{"missing", 999, "owner", services.ErrCommentNotFound},
+	}
+	for _, tt := range errs {
+		if _, err := comments.SetPinned(ctx, tt.id, tt.user, true); !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if ids := pinned(); len(ids) != 1 || ids[0] != second.ID {
+		t.Errorf("pinned %v after refused pins, want only the second comment", ids)
+	}
+
+	if got, err := comments.SetPinned(ctx, second.ID, "owner", false); err != nil || got.Pinned {
+		t.Fatalf("unpin = %+v, %v", got, err)
+	}
+	if ids := pinned(); len(ids) != 0 {
+		t.Errorf("pinned %v after unpinning", ids)
+	}
+}
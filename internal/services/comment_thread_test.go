@@ -0,0 +1,173 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// reply posts content on videoID as user, under parent when it is set
+func reply(t *testing.T, comments *services.CommentService, videoID uint, user string, parent *models.Comment) *models.Comment {
+	t.Helper()
+	var parentID *uint
+	if parent != nil {
+		parentID = &parent.ID
+	}
+	c, err := comments.AddComment(context.Background(), videoID, user, user, "hi from "+user, parentID)
+	if err != nil {
+		t.Fatalf("comment by %s: %v", user, err)
+	}
+	return c
+}
+
+func commentCount(t *testing.T, db *gorm.DB, videoID uint) int64 {
+	t.Helper()
+	var v models.Video
+	if err := db.First(&v, videoID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return v.CommentCount
+}
+
+func TestCommentReplies(t *testing.T) {
+	db := dbtest.Open(t)
+	comments := services.NewCommentService(db, config.Comments{MaxDepth: 2}, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{Title: "a", CommentsEnabled: true})
+	other := createVideo(t, db, models.Video{Title: "b", CommentsEnabled: true})
+
+	root := reply(t, comments, video.ID, "alice", nil)
+	second := reply(t, comments, video.ID, "bob", nil)
+	first := reply(t, comments, video.ID, "bob", root)
+	for _, user := range []string{"carol", "dave"} {
+		reply(t, comments, video.ID, user, root)
+	}
+	nested := reply(t, comments, video.ID, "alice", first)
+	if first.ParentID == nil || *first.ParentID != root.ID || first.Depth != 1 || nested.Depth != 2 {
+		t.Errorf("reply depths: %d and %d, parent %v", first.Depth, nested.Depth, first.ParentID)
+	}
+
+	foreign := reply(t, comments, other.ID, "erin", nil)
+	pending := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "frank", Status: models.CommentPending})
+	missing := uint(999)
+	for name, parentID := range map[string]*uint{"on another video": &foreign.ID, "held for moderation": &pending.ID, "missing": &missing} {
+		if _, err := comments.AddComment(ctx, video.ID, "bob", "bob", "hi", parentID); !errors.Is(err, services.ErrInvalidParent) {
+			t.Errorf("reply to a parent %s: %v, want ErrInvalidParent", name, err)
+		}
+	}
+	if _, err := comments.AddComment(ctx, video.ID, "bob", "bob", "hi", &nested.ID); !errors.Is(err, services.ErrReplyTooDeep) {
+		t.Errorf("reply at depth 3: %v, want ErrReplyTooDeep", err)
+	}
+
+	// Top-level comments only, each with its direct replies counted
+	top, total, err := comments.ListComments(ctx, video.ID, services.CommentSortOldest, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(top) != 2 || top[0].ID != root.ID || top[1].ID != second.ID || top[0].ReplyCount != 3 || top[1].ReplyCount != 0 {
+		t.Errorf("top level = %+v (total %d), want alice's with 3 replies then bob's", top, total)
+	}
+
+	// Replies page oldest first
+	page1, total, err := comments.ListReplies(ctx, root.ID, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page2, _, err := comments.ListReplies(ctx, root.ID, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(page1) != 2 || len(page2) != 1 || page1[0].ID != first.ID || page1[0].ReplyCount != 1 || page2[0].UserID != "dave" {
+		t.Errorf("replies: total %d, pages %+v and %+v", total, page1, page2)
+	}
+	// Replies count toward the video's comments
+	if n := commentCount(t, db, video.ID); n != 6 {
+		t.Errorf("comment_count = %d, want 6", n)
+	}
+}
+
+func TestCommentMaxDepthOne(t *testing.T) {
+	db := dbtest.Open(t)
+	comments := services.NewCommentService(db, config.Comments{MaxDepth: 1}, nopLogger())
+	video := createVideo(t, db, models.Video{Title: "a", CommentsEnabled: true})
+	root := reply(t, comments, video.ID, "alice", nil)
+	first := reply(t, comments, video.ID, "bob", root)
+	if _, err := comments.AddComment(context.Background(), video.ID, "carol", "carol", "hi", &first.ID); !errors.Is(err, services.ErrReplyTooDeep) {
+		t.Errorf("reply to a reply: %v, want ErrReplyTooDeep", err)
+	}
+}
+
+func TestDeleteCommentTombstones(t *testing.T) {
+	db := dbtest.Open(t)
+	comments := services.NewCommentService(db, commentSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{Title: "a", CommentsEnabled: true})
+	root := reply(t, comments, video.ID, "alice", nil)
+	first := reply(t, comments, video.ID, "bob", root)
+	nested := reply(t, comments, video.ID, "carol", first)
+	leaf := reply(t, comments, video.ID, "dave", root)
+
+	// A comment without replies just goes
+	if err := comments.DeleteComment(ctx, leaf.ID, "dave", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comments.GetComment(ctx, leaf.ID); !errors.Is(err, services.ErrCommentNotFound) {
+		t.Errorf("deleted leaf: %v, want ErrCommentNotFound", err)
+	}
+
+	// One with replies stays in the thread as a tombstone
+	if err := comments.DeleteComment(ctx, root.ID, "alice", true); err != nil {
+		t.Fatal(err)
+	}
+	got, err := comments.GetComment(ctx, root.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.CommentDeleted || got.Content != models.CommentTombstone || got.Username != "" {
+		t.Errorf("tombstone = %+v", got)
+	}
+	top, total, _ := comments.ListComments(ctx, video.ID, services.CommentSortNewest, 1, 10)
+	if total != 1 || len(top) != 1 || top[0].Content != models.CommentTombstone || top[0].ReplyCount != 1 {
+		t.Errorf("thread after deleting its root = %+v", top)
+	}
+	if replies, _, _ := comments.ListReplies(ctx, root.ID, 1, 10); len(replies) != 1 || replies[0].ID != first.ID {
+		t.Errorf("replies under the tombstone = %+v", replies)
+	}
+	if err := comments.DeleteComment(ctx, root.ID, "alice", true); !errors.Is(err, services.ErrCommentNotFound) {
+		t.Errorf("deleting a tombstone: %v, want ErrCommentNotFound", err)
+	}
+	// Four posted, two deleted; the second delete of the root wasn't counted again
+	if n := commentCount(t, db, video.ID); n != 2 {
+		t.Errorf("comment_count = %d, want 2", n)
+	}
+
+	// Deleting the last reply removes the tombstones it leaves without replies
+	if err := comments.DeleteComment(ctx, first.ID, "bob", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := comments.DeleteComment(ctx, nested.ID, "carol", true); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uint{root.ID, first.ID, nested.ID} {
+		if _, err := comments.GetComment(ctx, id); !errors.Is(err, services.ErrCommentNotFound) {
+			t.Errorf("comment %d after its thread emptied: %v, want ErrCommentNotFound", id, err)
+		}
+	}
+	if _, total, _ := comments.ListComments(ctx, video.ID, services.CommentSortNewest, 1, 10); total != 0 {
+		t.Errorf("%d top-level comments left", total)
+	}
+	if n := commentCount(t, db, video.ID); n != 0 {
+		t.Errorf("comment_count = %d, want 0", n)
+	}
+
+	if err := comments.DeleteComment(ctx, 999, "alice", false); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("delete without permission: %v, want ErrForbidden", err)
+	}
+}
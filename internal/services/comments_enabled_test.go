@@ -0,0 +1,82 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestCommentsEnabledByDefault(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+
+	created, err := videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-api", Title: "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created.CommentsEnabled {
+		t.Error("CreateVideo: comments off")
+	}
+	// Each event can be the one that creates the row
+	for _, kind := range []string{services.EventKindUploaded, services.EventKindTranscoded, services.EventKindTranscodeFailed} {
+		uploadID := "up-" + kind
+		if err := applyEvent(ctx, videos, kind, uploadID); err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		var video models.Video
+		db.Where("upload_id = ?", uploadID).First(&video)
+		if !video.CommentsEnabled {
+			t.Errorf("video created by a %s event has comments off", kind)
+		}
+	}
+}
+
+func TestCommentsDisabled(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	comments := services.NewCommentService(db, commentSettings, nopLogger())
+	ctx := context.Background()
+	video, err := videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-1", Title: "t", Visibility: models.VisibilityPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := comments.AddComment(ctx, video.ID, "alice", "alice", "first", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toggle := func(on bool) {
+		t.Helper()
+		updated, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{CommentsEnabled: &on})
+		if err != nil || updated.CommentsEnabled != on {
+			t.Fatalf("turn comments %v: %+v, %v", on, updated, err)
+		}
+	}
+
+	toggle(false)
+	if _, err := comments.AddComment(ctx, video.ID, "bob", "bob", "second", nil); !errors.Is(err, services.ErrCommentsDisabled) {
+		t.Errorf("comment with comments off: %v, want ErrCommentsDisabled", err)
+	}
+	if _, err := comments.AddComment(ctx, video.ID, "bob", "bob", "reply", &first.ID); !errors.Is(err, services.ErrCommentsDisabled) {
+		t.Errorf("reply with comments off: %v, want ErrCommentsDisabled", err)
+	}
+	// An unrelated edit leaves the flag alone
+	title := "renamed"
+	if updated, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Title: &title}); err != nil || updated.CommentsEnabled {
+		t.Errorf("title edit: comments_enabled %v, %v", updated.CommentsEnabled, err)
+	}
+	// The thread stays readable
+	thread, total, err := comments.ListComments(ctx, video.ID, services.CommentSortNewest, 1, 20)
+	if err != nil || total != 1 || len(thread) != 1 || thread[0].ID != first.ID {
+		t.Errorf("thread with comments off = %v (%d), %v", thread, total, err)
+	}
+
+	toggle(true)
+	if _, err := comments.AddComment(ctx, video.ID, "bob", "bob", "second", nil); err != nil {
+		t.Errorf("comment after turning comments back on: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/logging"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// TestServicesRedactUserContent checks that the service log lines that carry user
+// content go through the redact policy
+func TestServicesRedactUserContent(t *testing.T) {
+	if err := logging.SetContentPolicy(logging.PolicyRedact); err != nil {
+		t.Fatal(err)
+	}
+	db := dbtest.Open(t)
+	core, logs := observer.New(zap.DebugLevel)
+	log := zap.New(core).Sugar()
+	ctx := context.Background()
+
+	const title = "Secret birthday surprise for Dana"
+	videos := services.NewVideoService(db, nil, videoSettings, log)
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-1", UserID: "alice", Title: title}); err != nil {
+		t.Fatalf("HandleUploadedEvent: %v", err)
+	}
+
+	// Without a comments table the insert fails and the error line includes the body
+	const body = "my address is 12 Rua Augusta"
+	video := createVideo(t, db, models.Video{UploadID: "up-2", UserID: "alice", Title: "t", CommentsEnabled: true})
+	db.Migrator().DropTable(&models.Comment{})
+	if _, err := services.NewCommentService(db, commentSettings, log).AddComment(ctx, video.ID, "bob", "bob", body, nil); err == nil {
+		t.Fatal("AddComment succeeded without a comments table")
+	}
+
+	if logs.FilterMessage("Catalog seeded from upload event").Len() != 1 || logs.FilterMessage("create comment").Len() != 1 {
+		t.Fatalf("expected log lines missing: %v", logs.All())
+	}
+	for _, entry := range logs.All() {
+		line := fmt.Sprint(entry.Message, entry.ContextMap())
+		for _, raw := range []string{title, body} {
+			if strings.Contains(line, raw) {
+				t.Errorf("%q logged in the clear: %s", raw, line)
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// A caller that has gone away cancels the queries run for it
+func TestServicesStopForCancelledCaller(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	comments := services.NewCommentService(db, commentSettings, nopLogger())
+	video := createVideo(t, db, models.Video{UploadID: "up-1", UserID: "owner", Title: "t", CommentsEnabled: true})
+	comment := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "alice", Content: "hi"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	title := "renamed"
+	for name, call := range map[string]func() error{
+		"GetVideo":           func() error { _, err := videos.GetVideo(ctx, video.ID); return err },
+		"GetVideoByUploadID": func() error { _, err := videos.GetVideoByUploadID(ctx, "up-1"); return err },
+		"ListVideos": func() error {
+			_, err := videos.ListVideos(ctx, "", 1, 20, false, services.VideoSort{}, services.VideoFilters{})
+			return err
+		},
+		"UpdateVideo": func() error {
+			_, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Title: &title})
+			return err
+		},
+		"DeleteVideo": func() error { _, err := videos.DeleteVideo(ctx, video.ID); return err },
+		"AddComment":  func() error { _, err := comments.AddComment(ctx, video.ID, "bob", "bob", "hello", nil); return err },
+		"ListComments": func() error {
+			_, _, err := comments.ListComments(ctx, video.ID, services.CommentSortNewest, 1, 20)
+			return err
+		},
+		"GetComment":    func() error { _, err := comments.GetComment(ctx, comment.ID); return err },
+		"DeleteComment": func() error { return comments.DeleteComment(ctx, comment.ID, "alice", true) },
+	} {
+		if err := call(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: %v, want context.Canceled", name, err)
+		}
+	}
+
+	// Nothing was written
+	stored, err := videos.GetVideo(context.Background(), video.ID)
+	if err != nil || stored.Title != "t" {
+		t.Errorf("video after cancelled writes = %+v, %v", stored, err)
+	}
+	if got, err := comments.GetComment(context.Background(), comment.ID); err != nil || got.Content != "hi" {
+		t.Errorf("comment after cancelled delete = %+v, %v", got, err)
+	}
+}
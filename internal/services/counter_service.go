@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// counterColumns lists the denormalized counter columns on videos. Full-row saves
+// must omit them so a stale in-memory copy never overwrites concurrent increments.
+var counterColumns = []string{"comment_count", "view_count", "like_count", "dislike_count", "counters_dirty"}
+
+// counterSpec describes how to recompute one denormalized counter from its source table
+type counterSpec struct {
+	name   string
+	column string
+	// source is a correlated subquery producing the true value for videos.id
+	source string
+}
+
+var videoCounters = []counterSpec{
+	{
+		name:   "comments",
+		column: "comment_count",
+		source: "SELECT COUNT(*) FROM comments c WHERE c.video_id = videos.id AND c.deleted_at IS NULL AND c.status = 'visible'",
+	},
+	{
+		name:   "likes",
+		column: "like_count",
+		source: "SELECT COUNT(*) FROM video_reactions r WHERE r.video_id = videos.id AND r.value = 1",
+	},
+	{
+		name:   "dislikes",
+		column: "dislike_count",
+		source: "SELECT COUNT(*) FROM video_reactions r WHERE r.video_id = videos.id AND r.value = -1",
+	},
+}
+
+// commentLikes names the like counts on a video's comments in repair results and
+// metrics; commentLikesSource produces the true like_count for comments.id
+const (
+	commentLikes       = "comment_likes"
+	commentLikesSource = "SELECT COUNT(*) FROM comment_reactions r WHERE r.comment_id = comments.id"
+)
+
+// CounterRepairResult describes the drift found and fixed for a single video. The
+// comment_likes drift is the total by which its comments' like counts were off.
+type CounterRepairResult struct {
+	VideoID uint             `json:"video_id"`
+	Before  map[string]int64 `json:"before"`
+	After   map[string]int64 `json:"after"`
+	Drift   map[string]int64 `json:"drift"`
+}
+
+// CounterRepairStats summarizes a sampled repair run
+type CounterRepairStats struct {
+	Checked  int              `json:"checked"`
+	Repaired int              `json:"repaired"`
+	Drift    map[string]int64 `json:"drift"`
+}
+
+// CounterService recomputes denormalized video counters and corrects drift
+type CounterService struct {
+	db     *gorm.DB
+	logger *zap.SugaredLogger
+}
+
+// NewCounterService creates a new counter service
+func NewCounterService(db *gorm.DB, logger *zap.SugaredLogger) *CounterService {
+	return &CounterService{db: db, logger: logger}
+}
+
+// MarkCountersDirty flags videos whose counters may have drifted (e.g. after bulk operations)
+// so the next repair run recomputes them regardless of sampling.
+func MarkCountersDirty(tx *gorm.DB, videoIDs ...uint) error {
+	if len(videoIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&models.Video{}).Where("id IN ?", videoIDs).UpdateColumn("counters_dirty", true).Error
+}
+
+// RecountVideo recomputes every counter of a single video and fixes any drift
+func (s *CounterService) RecountVideo(ctx context.Context, videoID uint) (*CounterRepairResult, error) {
+	var video models.Video
+	if err := s.db.WithContext(ctx).Select("id").First(&video, videoID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("video %d: %w", videoID, ErrVideoNotFound)
+		}
+		return nil, fmt.Errorf("failed to get video: %w", err)
+	}
+
+	results, err := s.repair(ctx, []uint{videoID}, "manual")
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// RepairSample recomputes counters for all dirty videos plus a random sample of
+// sampleSize videos, keeping each run cheap enough to schedule hourly.
+func (s *CounterService) RepairSample(ctx context.Context, sampleSize int) (*CounterRepairStats, error) {
+	start := time.Now()
+	defer func() { metrics.CounterRepairRunDuration.Observe(time.Since(start).Seconds()) }()
+
+	var dirty []uint
+	if err := s.db.WithContext(ctx).Model(&models.Video{}).
+		Where("counters_dirty = ?", true).
+		Limit(sampleSize).
+		Pluck("id", &dirty).Error; err != nil {
+		return nil, fmt.Errorf("load dirty videos: %w", err)
+	}
+
+	sample, err := sampleVideoIDs(ctx, s.db, sampleSize)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &CounterRepairStats{Drift: map[string]int64{}}
+	for _, batch := range []struct {
+		ids     []uint
+		trigger string
+	}{{dirty, "dirty"}, {sample, "sample"}} {
+		if len(batch.ids) == 0 {
+			continue
+		}
+		results, err := s.repair(ctx, batch.ids, batch.trigger)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range results {
+			stats.Checked++
+			if len(r.Drift) > 0 {
+				stats.Repaired++
+			}
+			for name, d := range r.Drift {
+				stats.Drift[name] += abs64(d)
+			}
+		}
+	}
+
+	for _, c := range videoCounters {
+		metrics.CounterDriftLastRun.WithLabelValues(c.name).Set(float64(stats.Drift[c.name]))
+	}
+	metrics.CounterDriftLastRun.WithLabelValues(commentLikes).Set(float64(stats.Drift[commentLikes]))
+	s.logger.Infow("Counter repair run completed",
+		"checked", stats.Checked,
+		"repaired", stats.Repaired,
+		"drift", stats.Drift,
+		"duration", time.Since(start))
+	return stats, nil
+}
+
+// sampleVideoIDs picks up to n video IDs as a contiguous primary-key range starting
+// at a random point, wrapping around to the start of the table if needed. Unlike
+// ORDER BY random() this stays an index range scan on large tables.
+func sampleVideoIDs(ctx context.Context, db *gorm.DB, n int) ([]uint, error) {
+	var maxID uint
+	if err := db.WithContext(ctx).Model(&models.Video{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+		return nil, fmt.Errorf("load max video id: %w", err)
+	}
+	if maxID == 0 || n <= 0 {
+		return nil, nil
+	}
+	from := uint(rand.Int63n(int64(maxID))) + 1
+
+	var ids []uint
+	if err := db.WithContext(ctx).Model(&models.Video{}).
+		Where("id >= ?", from).Order("id").Limit(n).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("sample videos: %w", err)
+	}
+	if len(ids) < n {
+		var wrapped []uint
+		if err := db.WithContext(ctx).Model(&models.Video{}).
+			Where("id < ?", from).Order("id").Limit(n-len(ids)).Pluck("id", &wrapped).Error; err != nil {
+			return nil, fmt.Errorf("sample videos: %w", err)
+		}
+		ids = append(ids, wrapped...)
+	}
+	return ids, nil
+}
+
+// repair compares stored counters with their source of truth and rewrites any that
+// drifted. The rewrite re-evaluates the source subquery inside the UPDATE so writes
+// that land between the comparison and the fix are not lost.
+func (s *CounterService) repair(ctx context.Context, videoIDs []uint, trigger string) ([]*CounterRepairResult, error) {
+	results := make(map[uint]*CounterRepairResult, len(videoIDs))
+	ordered := make([]*CounterRepairResult, 0, len(videoIDs))
+	for _, id := range videoIDs {
+		r := &CounterRepairResult{VideoID: id, Before: map[string]int64{}, After: map[string]int64{}, Drift: map[string]int64{}}
+		results[id] = r
+		ordered = append(ordered, r)
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, c := range videoCounters {
+			var rows []struct {
+				ID     uint
+				Stored int64
+				Actual int64
+			}
+			if err := tx.Model(&models.Video{}).
+				Select(fmt.Sprintf("id, %s AS stored, (%s) AS actual", c.column, c.source)).
+				Where("id IN ?", videoIDs).
+				Scan(&rows).Error; err != nil {
+				return fmt.Errorf("recompute %s: %w", c.name, err)
+			}
+
+			var drifted []uint
+			for _, row := range rows {
+				r := results[row.ID]
+				r.Before[c.name] = row.Stored
+				r.After[c.name] = row.Actual
+				if row.Stored != row.Actual {
+					r.Drift[c.name] = row.Actual - row.Stored
+					drifted = append(drifted, row.ID)
+					metrics.CounterDriftTotal.WithLabelValues(c.name).Add(float64(abs64(row.Actual - row.Stored)))
+				}
+			}
+			if len(drifted) == 0 {
+				continue
+			}
+			if err := tx.Model(&models.Video{}).Where("id IN ?", drifted).
+				UpdateColumn(c.column, gorm.Expr("("+c.source+")")).Error; err != nil {
+				return fmt.Errorf("fix %s: %w", c.name, err)
+			}
+			metrics.CounterRepairsTotal.WithLabelValues(c.name, trigger).Add(float64(len(drifted)))
+			s.logger.Warnw("Corrected counter drift", "counter", c.name, "videos", len(drifted), "trigger", trigger)
+		}
+		if err := s.repairCommentLikes(tx, videoIDs, results, trigger); err != nil {
+			return err
+		}
+		return tx.Model(&models.Video{}).Where("id IN ?", videoIDs).UpdateColumn("counters_dirty", false).Error
+	})
+	if err != nil {
+		s.logger.Errorw("Counter repair failed", "error", err, "trigger", trigger)
+		return nil, err
+	}
+	return ordered, nil
+}
+
+// repairCommentLikes rewrites the like counts of the videos' comments that drifted
+// from comment_reactions, adding the drift to each video's result
+func (s *CounterService) repairCommentLikes(tx *gorm.DB, videoIDs []uint, results map[uint]*CounterRepairResult, trigger string) error {
+	var rows []struct {
+		ID      uint
+		VideoID uint
+		Stored  int64
+		Actual  int64
+	}
+	if err := tx.Model(&models.Comment{}).
+		Select("id, video_id, like_count AS stored, ("+commentLikesSource+") AS actual").
+		Where("video_id IN ? AND like_count <> ("+commentLikesSource+")", videoIDs).
+		Scan(&rows).Error; err != nil {
+		return fmt.Errorf("recompute %s: %w", commentLikes, err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	drifted := make([]uint, 0, len(rows))
+	videos := map[uint]bool{}
+	for _, row := range rows {
+		drift := abs64(row.Actual - row.Stored)
+		results[row.VideoID].Drift[commentLikes] += drift
+		drifted = append(drifted, row.ID)
+		videos[row.VideoID] = true
+		metrics.CounterDriftTotal.WithLabelValues(commentLikes).Add(float64(drift))
+	}
+	if err := tx.Model(&models.Comment{}).Where("id IN ?", drifted).
+		UpdateColumn("like_count", gorm.Expr("("+commentLikesSource+")")).Error; err != nil {
+		return fmt.Errorf("fix %s: %w", commentLikes, err)
+	}
+	metrics.CounterRepairsTotal.WithLabelValues(commentLikes, trigger).Add(float64(len(videos)))
+	s.logger.Warnw("Corrected counter drift", "counter", commentLikes, "comments", len(drifted), "videos", len(videos), "trigger", trigger)
+	return nil
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
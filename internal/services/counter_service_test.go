@@ -0,0 +1,148 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func createVideo(t *testing.T, db *gorm.DB, v models.Video) *models.Video {
+	t.Helper()
+	if v.UploadID == "" {
+		v.UploadID = "upload-" + v.Title
+	}
+	if v.UserID == "" {
+		v.UserID = "owner"
+	}
+	if err := db.Create(&v).Error; err != nil {
+		t.Fatalf("create video: %v", err)
+	}
+	return &v
+}
+
+func createComment(t *testing.T, db *gorm.DB, c models.Comment) *models.Comment {
+	t.Helper()
+	if c.Status == "" {
+		c.Status = models.CommentVisible
+	}
+	if c.Content == "" {
+		c.Content = "nice"
+	}
+	if err := db.Create(&c).Error; err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	return &c
+}
+
+func create(t *testing.T, db *gorm.DB, rows ...interface{}) {
+	t.Helper()
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("create %T: %v", row, err)
+		}
+	}
+}
+
+func TestRecountVideoFixesDrift(t *testing.T) {
+	db := dbtest.Open(t)
+	video := createVideo(t, db, models.Video{Title: "drifted", CommentCount: 7, LikeCount: 0, DislikeCount: 3})
+	comment := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "alice", LikeCount: 5})
+	createComment(t, db, models.Comment{VideoID: video.ID, UserID: "bob", Status: models.CommentPending})
+	create(t, db,
+		&models.VideoReaction{VideoID: video.ID, UserID: "alice", Value: models.ReactionLike},
+		&models.VideoReaction{VideoID: video.ID, UserID: "bob", Value: models.ReactionLike},
+		&models.CommentReaction{CommentID: comment.ID, UserID: "bob"},
+	)
+
+	result, err := services.NewCounterService(db, nopLogger()).RecountVideo(context.Background(), video.ID)
+	if err != nil {
+		t.Fatalf("RecountVideo: %v", err)
+	}
+	want := map[string]int64{"comments": -6, "likes": 2, "dislikes": -3, "comment_likes": 4}
+	for name, drift := range want {
+		if result.Drift[name] != drift {
+			t.Errorf("drift[%s] = %d, want %d", name, result.Drift[name], drift)
+		}
+	}
+
+	var got models.Video
+	db.First(&got, video.ID)
+	if got.CommentCount != 1 || got.LikeCount != 2 || got.DislikeCount != 0 {
+		t.Errorf("counters = %d comments, %d likes, %d dislikes; want 1, 2, 0", got.CommentCount, got.LikeCount, got.DislikeCount)
+	}
+	var gotComment models.Comment
+	db.First(&gotComment, comment.ID)
+	if gotComment.LikeCount != 1 {
+		t.Errorf("comment like_count = %d, want 1", gotComment.LikeCount)
+	}
+}
+
+func TestRecountVideoNotFound(t *testing.T) {
+	db := dbtest.Open(t)
+	_, err := services.NewCounterService(db, nopLogger()).RecountVideo(context.Background(), 42)
+	if !errors.Is(err, services.ErrVideoNotFound) {
+		t.Fatalf("err = %v, want ErrVideoNotFound", err)
+	}
+}
+
+func TestRepairSampleRepairsDirtyVideos(t *testing.T) {
+	db := dbtest.Open(t)
+	dirty := createVideo(t, db, models.Video{Title: "dirty", CommentCount: 4})
+	clean := createVideo(t, db, models.Video{Title: "clean"})
+	if err := services.MarkCountersDirty(db, dirty.ID); err != nil {
+		t.Fatalf("MarkCountersDirty: %v", err)
+	}
+
+	// The dirty video is repaired whichever video the sample of one lands on
+	stats, err := services.NewCounterService(db, nopLogger()).RepairSample(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("RepairSample: %v", err)
+	}
+	if stats.Repaired != 1 || stats.Drift["comments"] != 4 {
+		t.Errorf("stats = %+v, want one repaired video with 4 comments of drift", stats)
+	}
+
+	var got []models.Video
+	db.Order("id").Find(&got)
+	for _, v := range got {
+		if v.CountersDirty {
+			t.Errorf("video %d still dirty", v.ID)
+		}
+	}
+	if got[0].ID != dirty.ID || got[0].CommentCount != 0 || got[1].ID != clean.ID {
+		t.Errorf("videos after repair = %+v", got)
+	}
+}
+
+func TestAnonymizeCommentsMarksCountersDirty(t *testing.T) {
+	db := dbtest.Open(t)
+	first := createVideo(t, db, models.Video{Title: "first"})
+	second := createVideo(t, db, models.Video{Title: "second"})
+	untouched := createVideo(t, db, models.Video{Title: "untouched"})
+	createComment(t, db, models.Comment{VideoID: first.ID, UserID: "leaving"})
+	createComment(t, db, models.Comment{VideoID: second.ID, UserID: "leaving"})
+	createComment(t, db, models.Comment{VideoID: untouched.ID, UserID: "staying"})
+
+	deletion := services.NewContentDeletionService(db, nopLogger(), nil, 10)
+	n, err := deletion.AnonymizeComments(context.Background(), "leaving")
+	if err != nil || n != 2 {
+		t.Fatalf("AnonymizeComments = %d, %v; want 2 comments", n, err)
+	}
+
+	var dirty []uint
+	db.Model(&models.Video{}).Where("counters_dirty = ?", true).Order("id").Pluck("id", &dirty)
+	if len(dirty) != 2 || dirty[0] != first.ID || dirty[1] != second.ID {
+		t.Errorf("dirty videos = %v, want [%d %d]", dirty, first.ID, second.ID)
+	}
+	var left int64
+	db.Model(&models.Comment{}).Where("user_id = ?", "leaving").Count(&left)
+	if left != 0 {
+		t.Errorf("%d comments still carry the user", left)
+	}
+}
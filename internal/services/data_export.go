@@ -0,0 +1,481 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/streaming"
+)
+
+// Data export triggers
+const (
+	DataExportTriggerAPI   = "api"
+	DataExportTriggerEvent = "event"
+)
+
+// notHeldCategories are personal data categories this service does not store yet;
+// they are listed in the manifest so an empty section isn't mistaken for an omission
+var notHeldCategories = []string{"watch_history", "subscriptions"}
+
+// ExportSection streams one table of a user's personal data into an export
+type ExportSection interface {
+	Name() string
+	Count(ctx context.Context, db *gorm.DB, userID string) (int64, error)
+	Export(ctx context.Context, db *gorm.DB, userID string, emit func(record interface{}) error) (int64, error)
+}
+
+// tableSection exports rows of T whose column matches the user, walking the table
+// in primary-key order with a cursor
+type tableSection[T any] struct {
+	name     string
+	column   string
+	unscoped bool
+}
+
+func (t tableSection[T]) Name() string { return t.name }
+
+func (t tableSection[T]) query(ctx context.Context, db *gorm.DB, userID string) *gorm.DB {
+	q := db.WithContext(ctx).Model(new(T)).Where(t.column+" = ?", userID)
+	if t.unscoped {
+		q = q.Unscoped()
+	}
+	return q
+}
+
+func (t tableSection[T]) Count(ctx context.Context, db *gorm.DB, userID string) (int64, error) {
+	var n int64
+	err := t.query(ctx, db, userID).Count(&n).Error
+	return n, err
+}
+
+func (t tableSection[T]) Export(ctx context.Context, db *gorm.DB, userID string, emit func(record interface{}) error) (int64, error) {
+	var n int64
+	err := streaming.Each(ctx, t.query(ctx, db, userID).Order("id"), func(row *T) error {
+		n++
+		return emit(row)
+	})
+	return n, err
+}
+
+// ExportManifest describes the contents of an export archive
+type ExportManifest struct {
+	UserID      string           `json:"user_id"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Sections    map[string]int64 `json:"sections"`
+	NotHeld     []string         `json:"not_held"`
+	Rows        int64            `json:"rows"`
+}
+
+// DataExportService assembles a user's personal data into a ZIP of NDJSON files,
+// one per table plus a manifest. Small exports stream straight to the caller;
+// larger ones run as background jobs that notify the user when ready.
+type DataExportService struct {
+	db          *gorm.DB
+	logger      *zap.SugaredLogger
+	sections    []ExportSection
+	dir         string
+	ttl         time.Duration
+	syncMaxRows int64
+	ctx         context.Context
+}
+
+// NewDataExportService creates an export service writing job archives to dir.
+// Exports estimated above syncMaxRows rows are run as jobs; archives expire after ttl.
+func NewDataExportService(db *gorm.DB, logger *zap.SugaredLogger, dir string, ttl time.Duration, syncMaxRows int64) *DataExportService {
+	return &DataExportService{
+		db:     db,
+		logger: logger,
+		sections: []ExportSection{
+			// Soft-deleted rows are still held, so they are personal data too
+			tableSection[models.Video]{name: "videos", column: "user_id", unscoped: true},
+			tableSection[models.Comment]{name: "comments", column: "user_id", unscoped: true},
+			tableSection[models.Notification]{name: "notifications", column: "user_id"},
+			tableSection[models.VideoAccessLog]{name: "access_log", column: "viewer_id"},
+			tableSection[models.VideoView]{name: "views", column: "viewer"},
+			tableSection[models.VideoReaction]{name: "reactions", column: "user_id"},
+			tableSection[models.CommentReaction]{name: "comment_likes", column: "user_id"},
+			tableSection[models.WatchProgress]{name: "watch_progress", column: "user_id"},
+			tableSection[models.VideoReport]{name: "reports", column: "reporter_id"},
+			tableSection[models.VideoDeletion]{name: "video_deletions", column: "user_id"},
+		},
+		dir:         dir,
+		ttl:         ttl,
+		syncMaxRows: syncMaxRows,
+		ctx:         context.Background(),
+	}
+}
+
+// Start sets the context export jobs run under, fails jobs left running by a
+// previous process and picks up any still pending
+func (s *DataExportService) Start(ctx context.Context) error {
+	s.ctx = ctx
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return fmt.Errorf("create export dir: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(&models.DataExport{}).
+		Where("status = ? AND updated_at < ?", models.DataExportRunning, time.Now().UTC().Add(-time.Hour)).
+		Updates(map[string]interface{}{"status": models.DataExportFailed, "error": "interrupted"}).Error; err != nil {
+		return fmt.Errorf("fail interrupted exports: %w", err)
+	}
+	var pending []models.DataExport
+	if err := s.db.WithContext(ctx).Where("status = ?", models.DataExportPending).Find(&pending).Error; err != nil {
+		return fmt.Errorf("load pending exports: %w", err)
+	}
+	for i := range pending {
+		go s.run(pending[i])
+	}
+	return nil
+}
+
+// ShouldRunAsJob reports whether a user's export is too large to stream synchronously
+func (s *DataExportService) ShouldRunAsJob(ctx context.Context, userID string) (bool, error) {
+	var total int64
+	for _, section := range s.sections {
+		n, err := section.Count(ctx, s.db, userID)
+		if err != nil {
+			return false, fmt.Errorf("count %s: %w", section.Name(), err)
+		}
+		total += n
+		if total > s.syncMaxRows {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Assemble writes the full export archive for a user to w. Both the streaming
+// endpoint and background jobs use it.
+func (s *DataExportService) Assemble(ctx context.Context, userID string, w io.Writer) (*ExportManifest, error) {
+	zw := zip.NewWriter(w)
+	manifest := &ExportManifest{
+		UserID:      userID,
+		GeneratedAt: time.Now().UTC(),
+		Sections:    make(map[string]int64, len(s.sections)),
+		NotHeld:     notHeldCategories,
+	}
+	for _, section := range s.sections {
+		f, err := zw.Create(section.Name() + ".ndjson")
+		if err != nil {
+			return nil, fmt.Errorf("create %s entry: %w", section.Name(), err)
+		}
+		n, err := section.Export(ctx, s.db, userID, json.NewEncoder(f).Encode)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", section.Name(), err)
+		}
+		manifest.Sections[section.Name()] = n
+		manifest.Rows += n
+	}
+
+	f, err := zw.Create("manifest.json")
+	if err != nil {
+		return nil, fmt.Errorf("create manifest entry: %w", err)
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return nil, fmt.Errorf("write manifest: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("finish archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// Export formats for WriteDocument
+const (
+	ExportFormatJSON   = "json"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// exportFlushEvery is how many records WriteDocument buffers before flushing
+const exportFlushEvery = 500
+
+// WriteDocument writes a user's export to w as a single document instead of an
+// archive. With ExportFormatJSON it is one object,
+//
+//	{"user_id":"...","generated_at":"...","videos":[...],"comments":[...],...,"manifest":{...}}
+//
+// and with ExportFormatNDJSON one {"section":"...","record":{...}} line per row,
+// ending with a "manifest" line. Records are encoded one at a time as the cursor
+// reaches them, so memory stays flat however many rows the user has.
+func (s *DataExportService) WriteDocument(ctx context.Context, userID, format string, w io.Writer) (*ExportManifest, error) {
+	bw := bufio.NewWriter(w)
+	flusher, _ := w.(interface{ Flush() })
+	pending := 0
+	flush := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		pending = 0
+		return nil
+	}
+	enc := json.NewEncoder(bw)
+	manifest := &ExportManifest{
+		UserID:      userID,
+		GeneratedAt: time.Now().UTC(),
+		Sections:    make(map[string]int64, len(s.sections)),
+		NotHeld:     notHeldCategories,
+	}
+
+	ndjson := format == ExportFormatNDJSON
+	if !ndjson {
+		header, err := json.Marshal(map[string]interface{}{"user_id": userID, "generated_at": manifest.GeneratedAt})
+		if err != nil {
+			return nil, fmt.Errorf("encode header: %w", err)
+		}
+		// Reopen the header object so the sections follow as further keys
+		bw.Write(header[:len(header)-1])
+	}
+	for _, section := range s.sections {
+		name := section.Name()
+		if !ndjson {
+			fmt.Fprintf(bw, ",%q:[", name)
+		}
+		first := true
+		n, err := section.Export(ctx, s.db, userID, func(record interface{}) error {
+			var err error
+			if ndjson {
+				err = enc.Encode(exportLine{Section: name, Record: record})
+			} else {
+				if !first {
+					bw.WriteByte(',')
+				}
+				err = enc.Encode(record)
+			}
+			if err != nil {
+				return err
+			}
+			first = false
+			if pending++; pending >= exportFlushEvery {
+				return flush()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", name, err)
+		}
+		if !ndjson {
+			bw.WriteByte(']')
+		}
+		manifest.Sections[name] = n
+		manifest.Rows += n
+	}
+
+	var err error
+	if ndjson {
+		err = enc.Encode(exportLine{Section: "manifest", Record: manifest})
+	} else {
+		bw.WriteString(`,"manifest":`)
+		if err = enc.Encode(manifest); err == nil {
+			_, err = bw.WriteString("}\n")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("write manifest: %w", err)
+	}
+	if err := flush(); err != nil {
+		return nil, fmt.Errorf("flush export: %w", err)
+	}
+	return manifest, nil
+}
+
+// exportLine is one row of an NDJSON export document
+type exportLine struct {
+	Section string      `json:"section"`
+	Record  interface{} `json:"record"`
+}
+
+// StartExport queues an export job for a user. If one is already pending or
+// running it is returned instead and created is false.
+func (s *DataExportService) StartExport(ctx context.Context, userID, trigger string) (export *models.DataExport, created bool, err error) {
+	var existing models.DataExport
+	err = s.db.WithContext(ctx).
+		Where("user_id = ? AND status IN ?", userID, []string{models.DataExportPending, models.DataExportRunning}).
+		Order("id DESC").First(&existing).Error
+	if err == nil {
+		return &existing, false, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, false, fmt.Errorf("check running exports: %w", err)
+	}
+
+	export = &models.DataExport{UserID: userID, Status: models.DataExportPending, Trigger: trigger}
+	if err := s.db.WithContext(ctx).Create(export).Error; err != nil {
+		return nil, false, fmt.Errorf("create export job: %w", err)
+	}
+	go s.run(*export)
+	return export, true, nil
+}
+
+func (s *DataExportService) run(export models.DataExport) {
+	ctx := s.ctx
+	res := s.db.WithContext(ctx).Model(&models.DataExport{}).
+		Where("id = ? AND status = ?", export.ID, models.DataExportPending).
+		UpdateColumn("status", models.DataExportRunning)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return // claimed elsewhere
+	}
+
+	start := time.Now()
+	path := filepath.Join(s.dir, fmt.Sprintf("export-%d.zip", export.ID))
+	manifest, size, err := s.writeArchive(ctx, export.UserID, path)
+	metrics.DataExportDuration.WithLabelValues("job").Observe(time.Since(start).Seconds())
+	if err != nil {
+		os.Remove(path)
+		metrics.DataExportsTotal.WithLabelValues("job", "failed").Inc()
+		s.logger.Errorw("Data export failed", "error", err, "exportID", export.ID, "userID", export.UserID)
+		if err := s.db.Model(&models.DataExport{}).Where("id = ?", export.ID).
+			Updates(map[string]interface{}{"status": models.DataExportFailed, "error": err.Error()}).Error; err != nil {
+			s.logger.Errorw("Failed to record export failure", "error", err, "exportID", export.ID)
+		}
+		return
+	}
+
+	now := time.Now().UTC()
+	expires := now.Add(s.ttl)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DataExport{}).Where("id = ?", export.ID).Updates(map[string]interface{}{
+			"status":       models.DataExportReady,
+			"file_path":    path,
+			"size_bytes":   size,
+			"rows":         manifest.Rows,
+			"completed_at": now,
+			"expires_at":   expires,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.Notification{
+			UserID:  export.UserID,
+			Type:    models.NotificationDataExportReady,
+			Count:   1,
+			Message: fmt.Sprintf("Your data export is ready to download until %s", expires.Format(time.RFC1123)),
+		}).Error
+	})
+	if err != nil {
+		os.Remove(path)
+		metrics.DataExportsTotal.WithLabelValues("job", "failed").Inc()
+		s.logger.Errorw("Failed to mark data export ready", "error", err, "exportID", export.ID)
+		return
+	}
+	metrics.DataExportsTotal.WithLabelValues("job", "ready").Inc()
+	s.logger.Infow("Data export ready", "exportID", export.ID, "userID", export.UserID, "rows", manifest.Rows, "bytes", size)
+}
+
+// writeArchive assembles into a temp file and renames it into place once complete
+func (s *DataExportService) writeArchive(ctx context.Context, userID, path string) (*ExportManifest, int64, error) {
+	tmp := path + ".partial"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return nil, 0, fmt.Errorf("create archive: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	bw := bufio.NewWriter(f)
+	manifest, err := s.Assemble(ctx, userID, bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return nil, 0, fmt.Errorf("stat archive: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, 0, fmt.Errorf("move archive into place: %w", err)
+	}
+	return manifest, info.Size(), nil
+}
+
+// GetExport returns one of the user's export jobs
+func (s *DataExportService) GetExport(ctx context.Context, userID string, id uint) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&export).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("export %d: %w", id, ErrExportNotFound)
+		}
+		return nil, fmt.Errorf("get export: %w", err)
+	}
+	return &export, nil
+}
+
+// partialMaxAge is how old a half-written archive must be before PruneExpired
+// treats it as left behind by a replica that died mid-export
+const partialMaxAge = 24 * time.Hour
+
+// PruneExpired deletes archives past their expiry and marks their jobs expired. It
+// also removes half-written archives left by replicas that stopped mid-export.
+func (s *DataExportService) PruneExpired(ctx context.Context) (int, error) {
+	s.removeStalePartials()
+
+	var expired []models.DataExport
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND expires_at < ?", models.DataExportReady, time.Now().UTC()).
+		Find(&expired).Error; err != nil {
+		return 0, fmt.Errorf("load expired exports: %w", err)
+	}
+	for _, export := range expired {
+		if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+			s.logger.Warnw("Failed to remove expired export", "error", err, "exportID", export.ID)
+			continue
+		}
+		if err := s.db.WithContext(ctx).Model(&models.DataExport{}).Where("id = ?", export.ID).
+			Updates(map[string]interface{}{"status": models.DataExportExpired, "file_path": ""}).Error; err != nil {
+			return 0, fmt.Errorf("mark export %d expired: %w", export.ID, err)
+		}
+	}
+	return len(expired), nil
+}
+
+// removeStalePartials deletes .partial archives older than partialMaxAge
+func (s *DataExportService) removeStalePartials() {
+	partials, err := filepath.Glob(filepath.Join(s.dir, "*.partial"))
+	if err != nil {
+		return
+	}
+	for _, path := range partials {
+		info, err := os.Stat(path)
+		if err != nil || time.Since(info.ModTime()) < partialMaxAge {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			s.logger.Warnw("Failed to remove abandoned export", "error", err, "path", path)
+			continue
+		}
+		s.logger.Infow("Removed abandoned export", "path", path)
+	}
+}
+
+// OpenArchive opens a ready export's archive. ErrExportExpired means the file is
+// gone although the job says otherwise, e.g. removed by hand or on a volume this
+// replica doesn't share.
+func (s *DataExportService) OpenArchive(export *models.DataExport) (*os.File, error) {
+	f, err := os.Open(export.FilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.logger.Errorw("Export archive missing", "exportID", export.ID, "path", export.FilePath)
+			return nil, fmt.Errorf("export %d archive: %w", export.ID, ErrExportExpired)
+		}
+		return nil, fmt.Errorf("open export %d archive: %w", export.ID, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,170 @@
+package services_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// seedExportUser gives alice two videos (one deleted), comments, a reaction and a
+// view, next to bob's data that must stay out of her export
+func seedExportUser(t *testing.T, db *gorm.DB, comments int) {
+	t.Helper()
+	video := createVideo(t, db, models.Video{Title: "mine", UserID: "alice", Description: "with \"quotes\" and\nnewlines"})
+	deleted := createVideo(t, db, models.Video{Title: "gone", UserID: "alice"})
+	db.Delete(deleted)
+	other := createVideo(t, db, models.Video{Title: "theirs", UserID: "bob"})
+	rows := make([]models.Comment, comments)
+	for i := range rows {
+		rows[i] = models.Comment{VideoID: other.ID, UserID: "alice", Content: fmt.Sprintf("comment %d", i), Status: models.CommentVisible}
+	}
+	if err := db.CreateInBatches(rows, 200).Error; err != nil {
+		t.Fatal(err)
+	}
+	createComment(t, db, models.Comment{VideoID: video.ID, UserID: "bob"})
+	db.Create(&models.VideoReaction{VideoID: other.ID, UserID: "alice", Value: 1})
+	db.Create(&models.VideoView{VideoID: other.ID, Viewer: "alice", ViewDate: time.Now().UTC().Truncate(24 * time.Hour), Views: 2, LastCountedAt: time.Now().UTC()})
+}
+
+func TestWriteDocumentJSONRoundTrip(t *testing.T) {
+	db := dbtest.Open(t)
+	seedExportUser(t, db, 3)
+	exports := services.NewDataExportService(db, nopLogger(), t.TempDir(), time.Hour, 0)
+
+	var buf bytes.Buffer
+	manifest, err := exports.WriteDocument(context.Background(), "alice", services.ExportFormatJSON, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		UserID        string                  `json:"user_id"`
+		GeneratedAt   time.Time               `json:"generated_at"`
+		Videos        []models.Video          `json:"videos"`
+		Comments      []models.Comment        `json:"comments"`
+		Reactions     []models.VideoReaction  `json:"reactions"`
+		Views         []models.VideoView      `json:"views"`
+		Notifications []json.RawMessage       `json:"notifications"`
+		Manifest      services.ExportManifest `json:"manifest"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("export doesn't parse: %v\n%s", err, buf.String())
+	}
+	if doc.UserID != "alice" || doc.GeneratedAt.IsZero() {
+		t.Errorf("header = %q at %v", doc.UserID, doc.GeneratedAt)
+	}
+	if len(doc.Videos) != 2 || doc.Videos[0].Description != "with \"quotes\" and\nnewlines" || doc.Videos[1].Title != "gone" {
+		t.Errorf("videos = %+v, want both of alice's, deleted included", doc.Videos)
+	}
+	if len(doc.Comments) != 3 || doc.Comments[2].Content != "comment 2" {
+		t.Errorf("comments = %+v", doc.Comments)
+	}
+	if len(doc.Reactions) != 1 || len(doc.Views) != 1 || doc.Views[0].Views != 2 {
+		t.Errorf("reactions = %+v, views = %+v", doc.Reactions, doc.Views)
+	}
+	// Empty sections are empty arrays, not missing
+	if doc.Notifications == nil || len(doc.Notifications) != 0 {
+		t.Errorf("notifications = %v", doc.Notifications)
+	}
+	if doc.Manifest.Rows != 7 || doc.Manifest.Rows != manifest.Rows || doc.Manifest.Sections["comments"] != 3 {
+		t.Errorf("manifest = %+v, returned %+v", doc.Manifest, manifest)
+	}
+	var keys map[string]json.RawMessage
+	json.Unmarshal(buf.Bytes(), &keys)
+	for section := range manifest.Sections {
+		if _, ok := keys[section]; !ok {
+			t.Errorf("section %s missing from the document", section)
+		}
+	}
+}
+
+func TestWriteDocumentNDJSON(t *testing.T) {
+	db := dbtest.Open(t)
+	seedExportUser(t, db, 3)
+	exports := services.NewDataExportService(db, nopLogger(), t.TempDir(), time.Hour, 0)
+
+	var buf bytes.Buffer
+	if _, err := exports.WriteDocument(context.Background(), "alice", services.ExportFormatNDJSON, &buf); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int64{}
+	var last struct {
+		Section string                  `json:"section"`
+		Record  services.ExportManifest `json:"record"`
+	}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line struct {
+			Section string          `json:"section"`
+			Record  json.RawMessage `json:"record"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		counts[line.Section]++
+		json.Unmarshal(scanner.Bytes(), &last)
+	}
+	if last.Section != "manifest" || counts["manifest"] != 1 {
+		t.Fatalf("last line is %q, want the one manifest", last.Section)
+	}
+	for section, n := range last.Record.Sections {
+		if counts[section] != n {
+			t.Errorf("%s: %d lines, manifest says %d", section, counts[section], n)
+		}
+	}
+	if counts["videos"] != 2 || counts["comments"] != 3 || counts["reactions"] != 1 || counts["views"] != 1 {
+		t.Errorf("lines per section = %v", counts)
+	}
+}
+
+// flushRecorder is a response writer noting how much had been written at each flush
+type flushRecorder struct {
+	bytes.Buffer
+	flushedAt []int
+	failAfter int
+}
+
+func (w *flushRecorder) Write(p []byte) (int, error) {
+	if w.failAfter > 0 && w.Len()+len(p) > w.failAfter {
+		return 0, errors.New("connection reset")
+	}
+	return w.Buffer.Write(p)
+}
+
+func (w *flushRecorder) Flush() { w.flushedAt = append(w.flushedAt, w.Len()) }
+
+func TestWriteDocumentStreams(t *testing.T) {
+	db := dbtest.Open(t)
+	seedExportUser(t, db, 1200)
+	exports := services.NewDataExportService(db, nopLogger(), t.TempDir(), time.Hour, 0)
+	ctx := context.Background()
+
+	w := &flushRecorder{}
+	if _, err := exports.WriteDocument(ctx, "alice", services.ExportFormatJSON, w); err != nil {
+		t.Fatal(err)
+	}
+	// Rows reach the client as they are read, not all at the end
+	if len(w.flushedAt) < 3 || w.flushedAt[0] >= w.Len()/2 {
+		t.Errorf("flushed at %v of %d bytes, want several flushes along the way", w.flushedAt, w.Len())
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(w.Bytes(), &doc); err != nil {
+		t.Fatalf("export doesn't parse: %v", err)
+	}
+
+	// A client gone mid-stream ends the export with an error
+	broken := &flushRecorder{failAfter: w.Len() / 2}
+	if _, err := exports.WriteDocument(ctx, "alice", services.ExportFormatJSON, broken); err == nil {
+		t.Error("export succeeded despite the failed writes")
+	}
+}
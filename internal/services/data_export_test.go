@@ -0,0 +1,131 @@
+package services_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestDataExportJobLifecycle(t *testing.T) {
+	db := dbtest.Open(t)
+	dir := t.TempDir()
+	exports := services.NewDataExportService(db, nopLogger(), dir, time.Hour, 0)
+	ctx := context.Background()
+	if err := exports.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	video := createVideo(t, db, models.Video{Title: "mine", UserID: "alice"})
+	createComment(t, db, models.Comment{VideoID: video.ID, UserID: "alice"})
+	createComment(t, db, models.Comment{VideoID: video.ID, UserID: "bob"})
+
+	if asJob, err := exports.ShouldRunAsJob(ctx, "alice"); err != nil || !asJob {
+		t.Fatalf("ShouldRunAsJob = %v, %v; want a job above 0 rows", asJob, err)
+	}
+	job, created, err := exports.StartExport(ctx, "alice", services.DataExportTriggerAPI)
+	if err != nil || !created {
+		t.Fatalf("StartExport = %v, %v", created, err)
+	}
+	var ready *models.DataExport
+	waitFor(t, "the export to be ready", func() bool {
+		ready, err = exports.GetExport(ctx, "alice", job.ID)
+		return err == nil && ready.Status == models.DataExportReady
+	})
+	if filepath.Dir(ready.FilePath) != dir || ready.Rows != 2 || ready.ExpiresAt == nil {
+		t.Errorf("ready export = %+v", ready)
+	}
+	var notes int64
+	db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", "alice", models.NotificationDataExportReady).Count(&notes)
+	if notes != 1 {
+		t.Errorf("%d ready notifications, want 1", notes)
+	}
+
+	f, err := exports.OpenArchive(ready)
+	if err != nil {
+		t.Fatalf("OpenArchive: %v", err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	manifest := readManifest(t, data)
+	if manifest.Sections["videos"] != 1 || manifest.Sections["comments"] != 1 {
+		t.Errorf("manifest sections = %v", manifest.Sections)
+	}
+
+	// Past its expiry the prune job removes the archive
+	db.Model(&models.DataExport{}).Where("id = ?", job.ID).Update("expires_at", time.Now().UTC().Add(-time.Minute))
+	if n, err := exports.PruneExpired(ctx); err != nil || n != 1 {
+		t.Fatalf("PruneExpired = %d, %v", n, err)
+	}
+	if _, err := os.Stat(ready.FilePath); !os.IsNotExist(err) {
+		t.Errorf("expired archive still on disk: %v", err)
+	}
+	pruned, _ := exports.GetExport(ctx, "alice", job.ID)
+	if pruned.Status != models.DataExportExpired {
+		t.Errorf("status after prune = %s", pruned.Status)
+	}
+}
+
+func TestDataExportPrunesAbandonedPartials(t *testing.T) {
+	dir := t.TempDir()
+	exports := services.NewDataExportService(dbtest.Open(t), nopLogger(), dir, time.Hour, 0)
+	stale := filepath.Join(dir, "export-1.zip.partial")
+	fresh := filepath.Join(dir, "export-2.zip.partial")
+	for _, p := range []string{stale, fresh} {
+		if err := os.WriteFile(p, []byte("PK"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-25 * time.Hour)
+	os.Chtimes(stale, old, old)
+
+	if _, err := exports.PruneExpired(context.Background()); err != nil {
+		t.Fatalf("PruneExpired: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("abandoned partial archive kept")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Error("partial archive of a running export removed")
+	}
+}
+
+func TestDataExportMissingArchive(t *testing.T) {
+	exports := services.NewDataExportService(dbtest.Open(t), nopLogger(), t.TempDir(), time.Hour, 0)
+	export := &models.DataExport{ID: 3, Status: models.DataExportReady, FilePath: filepath.Join(t.TempDir(), "export-3.zip")}
+	if _, err := exports.OpenArchive(export); !errors.Is(err, services.ErrExportExpired) {
+		t.Fatalf("err = %v, want ErrExportExpired", err)
+	}
+}
+
+func readManifest(t *testing.T, archive []byte) services.ExportManifest {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	var manifest services.ExportManifest
+	for _, f := range zr.File {
+		if f.Name != "manifest.json" {
+			continue
+		}
+		rc, _ := f.Open()
+		err := json.NewDecoder(rc).Decode(&manifest)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("decode manifest: %v", err)
+		}
+		return manifest
+	}
+	t.Fatal("archive has no manifest.json")
+	return manifest
+}
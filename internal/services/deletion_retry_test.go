@@ -0,0 +1,106 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestRetryDeletionRunsOnlyFailedItems(t *testing.T) {
+	db := dbtest.Open(t)
+	storage := newFakeStorage("raw/owner/up-1.mp4", "thumbnails/owner/up-1.jpg",
+		"hls/owner/up-1/master.m3u8", "hls/owner/up-1/720p/seg-0.ts")
+	storage.failOnce = map[string]bool{"raw/owner/up-1.mp4": true}
+	videos := services.NewVideoService(db, storageLoader(storage), videoSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{UploadID: "up-1", UserID: "owner", Title: "t", RawVideoPath: "raw/owner/up-1.mp4",
+		HLSMasterURL: "https://acct.blob.core.windows.net/videos/hls/owner/up-1/master.m3u8"})
+	job, err := videos.DeleteVideoForUser(ctx, video.ID, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expire(t, db, video.ID)
+
+	worker := services.NewDeletionWorker(videos, nopLogger(), 1, 10*time.Millisecond, time.Minute)
+	runCtx, stop := context.WithCancel(ctx)
+	worker.Run(runCtx)
+	defer func() { stop(); worker.Wait() }()
+
+	waitFor(t, "the first attempt to fail", func() bool {
+		job, err := videos.GetDeletion(ctx, job.ID)
+		return err == nil && job.Status == models.DeletionFailed
+	})
+	failed, err := videos.GetDeletion(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range failed.Items {
+		switch {
+		case item.Path == "raw/owner/up-1.mp4":
+			if item.Status != models.DeletionItemFailed || item.Attempts != 1 || item.Error == "" {
+				t.Errorf("failing item = %+v, want failed after 1 attempt with its error", item)
+			}
+		case item.Status != models.DeletionItemDeleted || item.Attempts != 1:
+			t.Errorf("item %s = %s after %d attempts, want deleted after 1", item.Path, item.Status, item.Attempts)
+		}
+	}
+	if storage.has("thumbnails/owner/up-1.jpg") || storage.has("hls/owner/up-1/master.m3u8") {
+		t.Error("paths that didn't fail were kept")
+	}
+	var rows int64
+	db.Unscoped().Model(&models.Video{}).Where("id = ?", video.ID).Count(&rows)
+	if rows != 1 {
+		t.Fatal("video row removed while a storage path is left")
+	}
+	if _, err := videos.RetryDeletion(ctx, 999); !errors.Is(err, services.ErrDeletionNotFound) {
+		t.Errorf("retry of an unknown job: %v, want ErrDeletionNotFound", err)
+	}
+
+	retried, err := videos.RetryDeletion(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried.Status != models.DeletionPending {
+		t.Errorf("retried job is %s, want pending", retried.Status)
+	}
+	waitFor(t, "the retry to complete", func() bool {
+		job, err := videos.GetDeletion(ctx, job.ID)
+		return err == nil && job.Status == models.DeletionCompleted
+	})
+
+	done, err := videos.GetDeletion(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range done.Items {
+		want := 1
+		if item.Path == "raw/owner/up-1.mp4" {
+			want = 2
+		}
+		if item.Status != models.DeletionItemDeleted || item.Attempts != want || item.Error != "" {
+			t.Errorf("item %s = %s after %d attempts (%q), want deleted after %d", item.Path, item.Status, item.Attempts, item.Error, want)
+		}
+	}
+	storage.mu.Lock()
+	rawDeletes, thumbnailDeletes := storage.deletes["raw/owner/up-1.mp4"], storage.deletes["thumbnails/owner/up-1.jpg"]
+	storage.mu.Unlock()
+	if rawDeletes != 2 || thumbnailDeletes != 1 {
+		t.Errorf("DeleteBlob calls: raw %d, thumbnail %d; want 2 and 1", rawDeletes, thumbnailDeletes)
+	}
+	if storage.has("raw/owner/up-1.mp4") {
+		t.Error("raw file survived the retry")
+	}
+	db.Unscoped().Model(&models.Video{}).Where("id = ?", video.ID).Count(&rows)
+	if rows != 0 {
+		t.Error("video row kept after every path was deleted")
+	}
+
+	if _, err := videos.RetryDeletion(ctx, job.ID); !errors.Is(err, services.ErrDeletionNotFailed) {
+		t.Errorf("retry of a completed job: %v, want ErrDeletionNotFailed", err)
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import "errors"
+
+// Sentinel errors returned (usually wrapped) by the services; match them with errors.Is
+var (
+	ErrVideoNotFound            = errors.New("video not found")
+	ErrCommentNotFound          = errors.New("comment not found")
+	ErrNotificationNotFound     = errors.New("notification not found")
+	ErrExportNotFound           = errors.New("export not found")
+	ErrQuarantinedEventNotFound = errors.New("quarantined event not found")
+	ErrInboundEventNotFound     = errors.New("inbound event not found")
+	ErrDeletionNotFound         = errors.New("deletion job not found")
+	// ErrExportExpired means a data export's archive is no longer available
+	ErrExportExpired = errors.New("export expired")
+	// ErrInvalidAnonymousSession covers forged, malformed and expired anonymous session tokens
+	ErrInvalidAnonymousSession = errors.New("invalid anonymous session")
+	// ErrAnonymousQuota means an anonymous identity already holds the maximum number of rows
+	ErrAnonymousQuota = errors.New("anonymous data limit reached")
+	// ErrAnonymousSessionMerged means the session was already merged into another account
+	ErrAnonymousSessionMerged = errors.New("anonymous session already merged into another account")
+	// ErrInvalidSort means a list was asked for an order it doesn't support
+	ErrInvalidSort = errors.New("invalid sort")
+	// ErrInvalidStatus means a list was filtered by an unknown video status
+	ErrInvalidStatus = errors.New("invalid status")
+	// ErrInvalidTransition means a video's status can't move to the requested one;
+	// see InvalidTransitionError
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrStaleMarker means a saved blob listing marker is no longer accepted; restart the listing
+	ErrStaleMarker = errors.New("stale listing marker")
+	// ErrForbidden means the caller is not allowed to act on the resource
+	ErrForbidden = errors.New("forbidden")
+	// ErrVideoPurged means a deleted video's restore window has passed; it is gone
+	// or about to be
+	ErrVideoPurged = errors.New("video permanently deleted")
+	// ErrVideoNotDeleted means a restore was asked for a video that isn't deleted
+	ErrVideoNotDeleted = errors.New("video is not deleted")
+	// ErrInvalidVisibility means a video was given an unknown visibility
+	ErrInvalidVisibility = errors.New("invalid visibility")
+	// ErrNoPlayback means the video has no master playlist yet
+	ErrNoPlayback = errors.New("video has no playback URL")
+	// ErrPlaybackUnavailable means a private video's playback URL can't be signed
+	// because the storage backend doesn't support it or isn't configured
+	ErrPlaybackUnavailable = errors.New("signed playback URLs unavailable")
+	// ErrInvalidParent means a reply's parent comment is missing, deleted or on
+	// another video
+	ErrInvalidParent = errors.New("invalid parent comment")
+	// ErrReplyTooDeep means a reply would nest deeper than the configured maximum
+	ErrReplyTooDeep = errors.New("reply nested too deeply")
+	// ErrCommentsDisabled means the video's owner turned comments off
+	ErrCommentsDisabled = errors.New("comments are disabled for this video")
+	// ErrEditWindowClosed means a comment is too old for its author to edit
+	ErrEditWindowClosed = errors.New("comment edit window has closed")
+	// ErrNotPinnable means a comment that isn't a visible top-level comment was pinned
+	ErrNotPinnable = errors.New("only visible top-level comments can be pinned")
+	// ErrInvalidChapters means a creator's chapters failed validation; see ValidationError
+	ErrInvalidChapters = errors.New("invalid chapters")
+	// ErrInvalidTags means request tags would be dropped by normalization; see ValidationError
+	ErrInvalidTags = errors.New("invalid tags")
+	// ErrInvalidMetadata means a video update broke the metadata limits; see ValidationError
+	ErrInvalidMetadata = errors.New("invalid video metadata")
+	// ErrInvalidComment means comment content was too long; see ValidationError
+	ErrInvalidComment = errors.New("invalid comment")
+	// ErrInvalidCategory means a video was given a category that isn't an active
+	// managed one; see InvalidCategoryError
+	ErrInvalidCategory = errors.New("invalid category")
+	// ErrCategoryNotFound means no managed category has the slug
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrCategoryExists means a category was created with a slug already taken
+	ErrCategoryExists = errors.New("category already exists")
+	// ErrCategoryInUse means a category still on videos was deleted; deactivate it instead
+	ErrCategoryInUse = errors.New("category is in use")
+	// ErrVersionConflict means a video changed since the version an update was based on
+	ErrVersionConflict = errors.New("video was modified concurrently")
+	// ErrIdempotencyKeyReused means an Idempotency-Key was sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused for a different request")
+	// ErrReportNotFound means no report has the ID
+	ErrReportNotFound = errors.New("report not found")
+	// ErrReportExists means the user already has an open report on the video
+	ErrReportExists = errors.New("video already reported")
+	// ErrReportResolved means a report that was already reviewed or dismissed was resolved again
+	ErrReportResolved = errors.New("report already resolved")
+	// ErrModerationFlagNotFound means no moderation flag has the ID
+	ErrModerationFlagNotFound = errors.New("moderation flag not found")
+	// ErrModerationFlagResolved means a flag an admin already approved or upheld was resolved again
+	ErrModerationFlagResolved = errors.New("moderation flag already resolved")
+	// ErrThumbnailNotFound means the video has no thumbnail candidate with the ID
+	ErrThumbnailNotFound = errors.New("thumbnail not found")
+	// ErrInvalidThumbnail means an uploaded thumbnail isn't an accepted image; see ValidationError
+	ErrInvalidThumbnail = errors.New("invalid thumbnail")
+	// ErrStorageUnavailable means an operation needs blob storage and none is configured
+	ErrStorageUnavailable = errors.New("storage not configured")
+	// ErrProgressNotFound means the viewer has no saved position in the video
+	ErrProgressNotFound = errors.New("watch progress not found")
+	// ErrEventNotReprocessable means an archived event can't be run again: its body
+	// was truncated, or it isn't a video event
+	ErrEventNotReprocessable = errors.New("event can't be reprocessed")
+	// ErrIdempotencyKeyInFlight means the original request for an Idempotency-Key is still running
+	ErrIdempotencyKeyInFlight = errors.New("request with this idempotency key is in progress")
+)
+
+// ValidationError carries a message per offending request field alongside the
+// sentinel it wraps
+type ValidationError struct {
+	Err    error
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+
+// Unwrap lets errors.Is match the sentinel
+func (e *ValidationError) Unwrap() error { return e.Err }
@@ -0,0 +1,58 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestLookupsWrapSentinels(t *testing.T) {
+	ctx := context.Background()
+	// lookups returns each lookup against a fresh database, and a func that breaks it
+	lookups := func(t *testing.T) (map[string]func() error, func() error) {
+		db := dbtest.Open(t)
+		videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+		comments := services.NewCommentService(db, commentSettings, nopLogger())
+		deletes := services.NewVideoDeleteService(db, nopLogger(), nil)
+		return map[string]func() error{
+			"GetVideo": func() error { _, err := videos.GetVideo(ctx, 9); return err },
+			"GetVideoByUploadID": func() error {
+				_, err := videos.GetVideoByUploadID(ctx, "up-none")
+				return err
+			},
+			"DeleteVideoCompletely": func() error {
+				_, err := deletes.DeleteVideoCompletely(ctx, 9, 1, "system")
+				return err
+			},
+			"GetComment": func() error { _, err := comments.GetComment(ctx, 9); return err },
+		}, func() error { return db.Migrator().DropTable(&models.Comment{}, &models.Video{}) }
+	}
+	want := map[string]error{
+		"GetVideo":              services.ErrVideoNotFound,
+		"GetVideoByUploadID":    services.ErrVideoNotFound,
+		"DeleteVideoCompletely": services.ErrVideoNotFound,
+		"GetComment":            services.ErrCommentNotFound,
+	}
+
+	missing, _ := lookups(t)
+	for name, sentinel := range want {
+		if err := missing[name](); !errors.Is(err, sentinel) {
+			t.Errorf("%s of a missing row: err = %v, want %v", name, err, sentinel)
+		}
+	}
+
+	// Any other database failure must not look like a missing row
+	broken, breakDB := lookups(t)
+	if err := breakDB(); err != nil {
+		t.Fatal(err)
+	}
+	for name, sentinel := range want {
+		if err := broken[name](); err == nil || errors.Is(err, sentinel) {
+			t.Errorf("%s on a broken database: err = %v, want a non-%v error", name, err, sentinel)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// Event kinds that can be quarantined and replayed
+const (
+	EventKindUploaded        = "uploaded"
+	EventKindTranscoded      = "transcoded"
+	EventKindTranscodeFailed = "transcode_failed"
+)
+
+// EventQuarantineService stores events whose handler failed, with their original
+// envelope, and replays them through the video service in original order.
+type EventQuarantineService struct {
+	db     *gorm.DB
+	logger *zap.SugaredLogger
+	videos *VideoService
+}
+
+// NewEventQuarantineService creates a quarantine service dispatching replays to videos
+func NewEventQuarantineService(db *gorm.DB, logger *zap.SugaredLogger, videos *VideoService) *EventQuarantineService {
+	return &EventQuarantineService{db: db, logger: logger, videos: videos}
+}
+
+// Quarantine records a failed event. The metadata is stored as received so a
+// replay can hand the handler the same correlation ID, retry count and timestamps.
+func (s *EventQuarantineService) Quarantine(ctx context.Context, kind string, md events.Metadata, body []byte, handleErr error) (*models.QuarantinedEvent, error) {
+	var probe struct {
+		UploadID   string     `json:"uploadId"`
+		OccurredAt *time.Time `json:"occurredAt"`
+		FailedAt   *time.Time `json:"failedAt"`
+	}
+	_ = json.Unmarshal(body, &probe) // best effort: the body may be what failed to parse
+
+	q := &models.QuarantinedEvent{
+		Kind:       kind,
+		RoutingKey: md.RoutingKey,
+		UploadID:   probe.UploadID,
+		Body:       string(body),
+		Metadata:   metadataToMap(md),
+		Error:      handleErr.Error(),
+		Status:     models.QuarantineHeld,
+	}
+	if probe.OccurredAt == nil {
+		probe.OccurredAt = probe.FailedAt
+	}
+	if t := events.EventTime(probe.OccurredAt, md); !t.IsZero() {
+		q.EventTime = &t
+	}
+	if err := s.db.WithContext(ctx).Create(q).Error; err != nil {
+		return nil, fmt.Errorf("quarantine event: %w", err)
+	}
+	metrics.EventsQuarantinedTotal.WithLabelValues(kind).Inc()
+	s.logger.Warnw("Event quarantined", "id", q.ID, "kind", kind, "uploadID", q.UploadID,
+		"correlationID", md.CorrelationID, "error", handleErr)
+	return q, nil
+}
+
+// Query selects quarantined events, optionally filtered by upload ID and status
+func (s *EventQuarantineService) Query(ctx context.Context, uploadID, status string) *gorm.DB {
+	query := s.db.WithContext(ctx).Model(&models.QuarantinedEvent{})
+	if uploadID != "" {
+		query = query.Where("upload_id = ?", uploadID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	return query
+}
+
+// List returns a page of quarantined events, optionally filtered by upload ID and status
+func (s *EventQuarantineService) List(ctx context.Context, uploadID, status string, page, perPage int) ([]models.QuarantinedEvent, int64, error) {
+	query := s.Query(ctx, uploadID, status)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count quarantined events: %w", err)
+	}
+	var out []models.QuarantinedEvent
+	if err := query.Order("id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&out).Error; err != nil {
+		return nil, 0, fmt.Errorf("list quarantined events: %w", err)
+	}
+	return out, total, nil
+}
+
+// Replay re-applies one quarantined event. Unless force is set, a replay that would
+// move the video's state backwards is refused with *events.ReplayRejectedError, and
+// one the status state machine doesn't allow with an InvalidTransitionError.
+func (s *EventQuarantineService) Replay(ctx context.Context, id uint, force bool) (*models.QuarantinedEvent, error) {
+	var q models.QuarantinedEvent
+	if err := s.db.WithContext(ctx).First(&q, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("quarantined event %d: %w", id, ErrQuarantinedEventNotFound)
+		}
+		return nil, fmt.Errorf("get quarantined event: %w", err)
+	}
+	return &q, s.replay(ctx, &q, force)
+}
+
+// ReplayUpload replays every held or previously rejected event for an upload in the
+// order the producer emitted them, stopping at the first failure so later events
+// are never applied ahead of earlier ones.
+func (s *EventQuarantineService) ReplayUpload(ctx context.Context, uploadID string, force bool) ([]models.QuarantinedEvent, error) {
+	var pending []models.QuarantinedEvent
+	if err := s.db.WithContext(ctx).
+		Where("upload_id = ? AND status IN ?", uploadID, []string{models.QuarantineHeld, models.QuarantineRejected}).
+		Order("event_time ASC NULLS LAST, id ASC").
+		Find(&pending).Error; err != nil {
+		return nil, fmt.Errorf("load quarantined events: %w", err)
+	}
+	for i := range pending {
+		if err := s.replay(ctx, &pending[i], force); err != nil {
+			return pending[:i+1], err
+		}
+	}
+	return pending, nil
+}
+
+func (s *EventQuarantineService) replay(ctx context.Context, q *models.QuarantinedEvent, force bool) error {
+	if q.Status == models.QuarantineReplayed && !force {
+		return fmt.Errorf("event %d was already replayed", q.ID)
+	}
+
+	md := replayMetadata(q.Metadata, force)
+	err := dispatchVideoEvent(events.WithMetadata(ctx, md), s.videos, q.Kind, []byte(q.Body))
+
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"replay_count": gorm.Expr("replay_count + 1"),
+		"replayed_at":  now,
+		"metadata":     metadataToMap(md),
+	}
+	outcome := "ok"
+	var rejected *events.ReplayRejectedError
+	switch {
+	case err == nil:
+		updates["status"] = models.QuarantineReplayed
+		updates["replay_error"] = ""
+	case errors.As(err, &rejected), errors.Is(err, ErrInvalidTransition):
+		outcome = "rejected"
+		updates["status"] = models.QuarantineRejected
+		updates["replay_error"] = err.Error()
+	default:
+		outcome = "error"
+		updates["replay_error"] = err.Error()
+	}
+	if uerr := s.db.WithContext(ctx).Model(q).Updates(updates).Error; uerr != nil {
+		s.logger.Errorw("Failed to record replay outcome", "error", uerr, "id", q.ID)
+	}
+	metrics.EventReplaysTotal.WithLabelValues(q.Kind, outcome).Inc()
+	s.logger.Infow("Quarantined event replayed", "id", q.ID, "kind", q.Kind, "uploadID", q.UploadID,
+		"correlationID", md.CorrelationID, "retryCount", md.RetryCount, "force", force, "outcome", outcome, "error", err)
+	return err
+}
+
+// replayMetadata is the envelope a stored event is re-applied with: the original
+// one, marked as a replay with its retry count bumped
+func replayMetadata(stored map[string]interface{}, force bool) events.Metadata {
+	md := metadataFromMap(stored)
+	md.Replay = true
+	md.Force = force
+	md.RetryCount++
+	if md.Headers == nil {
+		md.Headers = map[string]interface{}{}
+	}
+	md.Headers[events.HeaderRetryCount] = md.RetryCount
+	md.Headers[events.HeaderReplayed] = true
+	return md
+}
+
+// dispatchVideoEvent hands an event body to the video service handler for its kind
+func dispatchVideoEvent(ctx context.Context, videos *VideoService, kind string, body []byte) error {
+	switch kind {
+	case EventKindUploaded:
+		var event models.UploadedEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return fmt.Errorf("unmarshal uploaded: %w", err)
+		}
+		return videos.HandleUploadedEvent(ctx, &event)
+	case EventKindTranscoded:
+		var event models.TranscodedEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return fmt.Errorf("unmarshal transcoded: %w", err)
+		}
+		return videos.HandleTranscodedEvent(ctx, &event)
+	case EventKindTranscodeFailed:
+		var event models.TranscodeFailedEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return fmt.Errorf("unmarshal transcode failed: %w", err)
+		}
+		return videos.HandleTranscodeFailedEvent(ctx, &event)
+	default:
+		return fmt.Errorf("unknown event kind %q", kind)
+	}
+}
+
+func metadataToMap(md events.Metadata) map[string]interface{} {
+	raw, err := json.Marshal(md)
+	if err != nil {
+		return map[string]interface{}{"routing_key": md.RoutingKey, "correlation_id": md.CorrelationID}
+	}
+	out := map[string]interface{}{}
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
+
+func metadataFromMap(m map[string]interface{}) events.Metadata {
+	var md events.Metadata
+	raw, err := json.Marshal(m)
+	if err == nil {
+		_ = json.Unmarshal(raw, &md)
+	}
+	return md
+}
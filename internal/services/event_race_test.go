@@ -0,0 +1,113 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// uploadEvents returns the uploaded and transcoded events of one upload
+func uploadEvents(uploadID string) (*models.UploadedEvent, *models.TranscodedEvent) {
+	return &models.UploadedEvent{
+		UploadID:     uploadID,
+		UserID:       "owner",
+		Title:        "Holiday",
+		Description:  "beach",
+		OriginalName: "holiday.mp4",
+		RawVideoPath: "raw/" + uploadID + ".mp4",
+	}, &models.TranscodedEvent{
+		UploadID: uploadID,
+		UserID:   "owner",
+		Ready:    true,
+		HLS:      models.HLSInfo{MasterURL: "https://cdn.example/" + uploadID + "/master.m3u8"},
+	}
+}
+
+// checkMerged checks the upload has one row carrying the fields of both events
+func checkMerged(t *testing.T, db *gorm.DB, uploaded *models.UploadedEvent, transcoded *models.TranscodedEvent) {
+	t.Helper()
+	var rows []models.Video
+	if err := db.Unscoped().Where("upload_id = ?", uploaded.UploadID).Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("%s: %d rows, want 1", uploaded.UploadID, len(rows))
+	}
+	v := rows[0]
+	if v.Title != uploaded.Title || v.Description != uploaded.Description || v.OriginalFilename != uploaded.OriginalName || v.RawVideoPath != uploaded.RawVideoPath {
+		t.Errorf("%s: upload fields lost: title %q, description %q, file %q, raw %q", uploaded.UploadID, v.Title, v.Description, v.OriginalFilename, v.RawVideoPath)
+	}
+	if v.Status != models.StatusReady || v.HLSMasterURL != transcoded.HLS.MasterURL {
+		t.Errorf("%s: transcode fields lost: status %s, master %q", uploaded.UploadID, v.Status, v.HLSMasterURL)
+	}
+}
+
+func TestUploadedAndTranscodedEitherOrder(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+
+	uploaded, transcoded := uploadEvents("uploaded-first")
+	if err := videos.HandleUploadedEvent(ctx, uploaded); err != nil {
+		t.Fatal(err)
+	}
+	if err := videos.HandleTranscodedEvent(ctx, transcoded); err != nil {
+		t.Fatal(err)
+	}
+	checkMerged(t, db, uploaded, transcoded)
+
+	uploaded, transcoded = uploadEvents("transcoded-first")
+	if err := videos.HandleTranscodedEvent(ctx, transcoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := videos.HandleUploadedEvent(ctx, uploaded); err != nil {
+		t.Fatal(err)
+	}
+	checkMerged(t, db, uploaded, transcoded)
+}
+
+// TestUploadedAndTranscodedRace delivers both events for one upload at the same
+// time, as the two queue consumers can, and checks they end up in one row with
+// the fields of both
+func TestUploadedAndTranscodedRace(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		uploadID := fmt.Sprintf("race-%d", i)
+		uploaded, transcoded := uploadEvents(uploadID)
+
+		start := make(chan struct{})
+		errs := make(chan error, 2)
+		var wg sync.WaitGroup
+		for _, handle := range []func() error{
+			func() error { return videos.HandleUploadedEvent(ctx, uploaded) },
+			func() error { return videos.HandleTranscodedEvent(ctx, transcoded) },
+		} {
+			wg.Add(1)
+			go func(handle func() error) {
+				defer wg.Done()
+				<-start
+				errs <- handle()
+			}(handle)
+		}
+		close(start)
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("%s: %v", uploadID, err)
+			}
+		}
+
+		checkMerged(t, db, uploaded, transcoded)
+	}
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// AnonymizeComments exposes one anonymization batch to the external tests
+func (s *ContentDeletionService) AnonymizeComments(ctx context.Context, userID string) (int64, error) {
+	return s.anonymizeComments(ctx, userID)
+}
+
+// SetLoader replaces the Postgres tag aggregate, which SQLite can't run, and the
+// service's clock
+func (s *TagService) SetLoader(load func(ctx context.Context) ([]TagCount, error), now func() time.Time) {
+	s.load, s.now = load, now
+}
+
+// SetClock replaces the store's clock
+func (s *IdempotencyStore) SetClock(now func() time.Time) { s.now = now }
+
+// SaveWithoutRowLock drops the progress upsert's row lock and xmax check so SQLite
+// runs the rest of it; every write then counts as a first insert for milestones
+func (s *WatchProgressService) SaveWithoutRowLock() {
+	s.saveSQL = strings.NewReplacer(" FOR UPDATE", "", "CASE WHEN xmax = 0 THEN 0 ELSE -1 END", "0").Replace(saveProgressSQL)
+}
@@ -0,0 +1,268 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/cache"
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// fakeStorage is an in-memory StorageClient. err, when set, fails every call;
+// existsErr fails only BlobExists for the paths it lists, and failOnce fails the
+// first DeleteBlob of each path it lists.
+type fakeStorage struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	err       error
+	existsErr map[string]error
+	failOnce  map[string]bool
+	// deletes counts DeleteBlob calls by path
+	deletes map[string]int
+	// block makes BlobExists wait for the context, as a hung backend would
+	block bool
+	// downloads counts DownloadBlob calls
+	downloads int
+}
+
+var (
+	_ services.StorageClient  = (*fakeStorage)(nil)
+	_ services.BlobDownloader = (*fakeStorage)(nil)
+)
+
+func newFakeStorage(paths ...string) *fakeStorage {
+	s := &fakeStorage{blobs: map[string][]byte{}, deletes: map[string]int{}}
+	for _, p := range paths {
+		s.blobs[p] = nil
+	}
+	return s
+}
+
+func (s *fakeStorage) DeleteBlob(_ context.Context, blobPath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deletes[blobPath]++
+	if s.err != nil {
+		return s.err
+	}
+	if s.failOnce[blobPath] {
+		delete(s.failOnce, blobPath)
+		return fmt.Errorf("delete %s: storage timeout", blobPath)
+	}
+	delete(s.blobs, blobPath)
+	return nil
+}
+
+func (s *fakeStorage) DeleteBlobsWithPrefix(_ context.Context, prefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	n := 0
+	for p := range s.blobs {
+		if strings.HasPrefix(p, prefix) {
+			delete(s.blobs, p)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *fakeStorage) DeleteBlobPage(ctx context.Context, prefix, _ string) (string, int, error) {
+	n, err := s.DeleteBlobsWithPrefix(ctx, prefix)
+	return "", n, err
+}
+
+func (s *fakeStorage) BlobExists(ctx context.Context, blobPath string) (bool, error) {
+	if s.block {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	if err := s.existsErr[blobPath]; err != nil {
+		return false, err
+	}
+	_, ok := s.blobs[blobPath]
+	return ok, nil
+}
+
+func (s *fakeStorage) UploadBlob(_ context.Context, blobPath string, data []byte, _ string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return "", s.err
+	}
+	s.blobs[blobPath] = data
+	return "https://blobs.example/" + blobPath, nil
+}
+
+// DownloadBlob makes fakeStorage a BlobDownloader too, as the Azure adapter is
+func (s *fakeStorage) DownloadBlob(_ context.Context, blobPath string, maxBytes int64) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.downloads++
+	if s.err != nil {
+		return nil, s.err
+	}
+	data, ok := s.blobs[blobPath]
+	if !ok {
+		return nil, fmt.Errorf("blob %s not found", blobPath)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("blob %s is larger than %d bytes", blobPath, maxBytes)
+	}
+	return data, nil
+}
+
+func (s *fakeStorage) downloadCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.downloads
+}
+
+func (s *fakeStorage) has(blobPath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.blobs[blobPath]
+	return ok
+}
+
+// storageLoader wraps client in a loader that always returns it
+func storageLoader(client services.StorageClient) *services.StorageLoader {
+	return services.NewStorageLoader(func() (services.StorageClient, error) { return client, nil }, 0, nopLogger())
+}
+
+func nopLogger() *zap.SugaredLogger { return zap.NewNop().Sugar() }
+
+// videoSettings are the video service defaults Load would give
+var videoSettings = config.Videos{
+	UploadMissTTL:     2 * time.Second,
+	DeleteGrace:       7 * 24 * time.Hour,
+	PlaybackURLTTL:    time.Hour,
+	ChannelSummaryTTL: 30 * time.Second,
+}
+
+// commentSettings are the comment service defaults Load would give
+var commentSettings = config.Comments{MaxDepth: 2, EditWindow: 15 * time.Minute}
+
+// fakeProvider is a ModerationProvider returning score for every text, or err.
+// With release set, each call waits for it to be closed or for the context.
+type fakeProvider struct {
+	score   float64
+	err     error
+	release chan struct{}
+	calls   chan string
+}
+
+var _ services.ModerationProvider = (*fakeProvider)(nil)
+
+func newFakeProvider(score float64, err error) *fakeProvider {
+	return &fakeProvider{score: score, err: err, calls: make(chan string, 16)}
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) ScoreText(ctx context.Context, kind, text string) (*services.ModerationResult, error) {
+	if p.release != nil {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	defer func() { p.calls <- text }()
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &services.ModerationResult{Categories: map[string]float64{"toxicity": p.score}}, nil
+}
+
+// fakeCache is an in-memory cache.Cache that ignores expiry. err, when set, fails
+// every call.
+type fakeCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+	err     error
+}
+
+var _ cache.Cache = (*fakeCache)(nil)
+
+func newFakeCache() *fakeCache { return &fakeCache{entries: map[string][]byte{}} }
+
+func (c *fakeCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, false, c.err
+	}
+	v, ok := c.entries[key]
+	return v, ok, nil
+}
+
+func (c *fakeCache) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.entries[key] = value
+	return nil
+}
+
+func (c *fakeCache) Delete(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+func (c *fakeCache) Incr(_ context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return 0, c.err
+	}
+	var n int64
+	fmt.Sscan(string(c.entries[key]), &n)
+	n++
+	c.entries[key] = []byte(fmt.Sprint(n))
+	return n, nil
+}
+
+func (c *fakeCache) Close() error { return nil }
+
+// len is the number of entries held
+func (c *fakeCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// idempotencyLockTimeout is how long a request may hold its key before a retry may
+// take it over; it only matters when a replica dies mid-request
+const idempotencyLockTimeout = time.Minute
+
+// idempotencyPruneBatch bounds how many rows one prune statement deletes
+const idempotencyPruneBatch = 5000
+
+// IdempotencyStore keeps the responses to writes sent with an Idempotency-Key, so
+// a retried request is answered from the first one instead of being applied twice.
+// A request claims its key with Begin before it runs and stores its response with
+// Complete; a request that fails releases the key so it can be retried.
+type IdempotencyStore struct {
+	db     *gorm.DB
+	logger *zap.SugaredLogger
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewIdempotencyStore creates a store that replays responses for ttl after the
+// original request
+func NewIdempotencyStore(db *gorm.DB, logger *zap.SugaredLogger, ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{db: db, logger: logger, ttl: ttl, now: time.Now}
+}
+
+// Begin claims key for userID's request to endpoint. When claimed is true the
+// caller runs the request and must then Complete or Release the returned record;
+// otherwise the record holds the stored response to replay. A key reused for a
+// different request fails with ErrIdempotencyKeyReused, and one whose original
+// request hasn't finished with ErrIdempotencyKeyInFlight. Expired keys, and keys
+// held past idempotencyLockTimeout, are taken over as if new.
+func (s *IdempotencyStore) Begin(ctx context.Context, userID, endpoint, key, requestHash string) (record *models.IdempotencyKey, claimed bool, err error) {
+	// Postgres keeps microseconds; Complete and Release match on created_at
+	now := s.now().UTC().Truncate(time.Microsecond)
+	record = &models.IdempotencyKey{UserID: userID, Endpoint: endpoint, Key: key, RequestHash: requestHash, CreatedAt: now}
+	res := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if res.Error != nil {
+		return nil, false, fmt.Errorf("claim idempotency key: %w", res.Error)
+	}
+	if res.RowsAffected > 0 {
+		return record, true, nil
+	}
+
+	var existing models.IdempotencyKey
+	err = s.db.WithContext(ctx).Where("user_id = ? AND endpoint = ? AND key = ?", userID, endpoint, key).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Released or pruned between the insert and the read
+		return nil, false, ErrIdempotencyKeyInFlight
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("load idempotency key: %w", err)
+	}
+
+	age := now.Sub(existing.CreatedAt)
+	if age >= s.ttl || (!existing.Completed() && age >= idempotencyLockTimeout) {
+		res := s.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+			Where("user_id = ? AND endpoint = ? AND key = ? AND created_at = ?", userID, endpoint, key, existing.CreatedAt).
+			Updates(map[string]interface{}{
+				"request_hash":  requestHash,
+				"status":        0,
+				"content_type":  "",
+				"body":          nil,
+				"response_hash": "",
+				"created_at":    now,
+			})
+		if res.Error != nil {
+			return nil, false, fmt.Errorf("take over idempotency key: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return nil, false, ErrIdempotencyKeyInFlight
+		}
+		return record, true, nil
+	}
+	if existing.RequestHash != requestHash {
+		return nil, false, ErrIdempotencyKeyReused
+	}
+	if !existing.Completed() {
+		return nil, false, ErrIdempotencyKeyInFlight
+	}
+	return &existing, false, nil
+}
+
+// Complete stores the response to a claimed request for replay
+func (s *IdempotencyStore) Complete(ctx context.Context, record *models.IdempotencyKey, status int, contentType string, body []byte) error {
+	sum := sha256.Sum256(body)
+	res := s.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("user_id = ? AND endpoint = ? AND key = ? AND created_at = ?", record.UserID, record.Endpoint, record.Key, record.CreatedAt).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"content_type":  contentType,
+			"body":          body,
+			"response_hash": hex.EncodeToString(sum[:]),
+		})
+	if res.Error != nil {
+		return fmt.Errorf("store idempotent response: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		s.logger.Warnw("Idempotency key was taken over before its request finished",
+			"userID", record.UserID, "endpoint", record.Endpoint, "key", record.Key)
+	}
+	return nil
+}
+
+// Release gives up a claimed key without storing a response, so the request can be retried
+func (s *IdempotencyStore) Release(ctx context.Context, record *models.IdempotencyKey) error {
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND endpoint = ? AND key = ? AND created_at = ? AND status = 0",
+			record.UserID, record.Endpoint, record.Key, record.CreatedAt).
+		Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Prune deletes keys older than the replay window
+func (s *IdempotencyStore) Prune(ctx context.Context) (int64, error) {
+	cutoff := s.now().UTC().Add(-s.ttl)
+	var pruned int64
+	for {
+		res := s.db.WithContext(ctx).Exec(`DELETE FROM idempotency_keys WHERE (user_id, endpoint, key) IN
+			(SELECT user_id, endpoint, key FROM idempotency_keys WHERE created_at < ? LIMIT ?)`,
+			cutoff, idempotencyPruneBatch)
+		if res.Error != nil {
+			return pruned, fmt.Errorf("prune idempotency keys: %w", res.Error)
+		}
+		pruned += res.RowsAffected
+		if res.RowsAffected < idempotencyPruneBatch {
+			break
+		}
+	}
+	if pruned > 0 {
+		s.logger.Infow("Pruned idempotency keys", "rows", pruned, "ttl", s.ttl)
+	}
+	return pruned, nil
+}
@@ -0,0 +1,128 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+const idemEndpoint = "POST /api/v1/videos"
+
+func TestIdempotencyStore(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := services.NewIdempotencyStore(db, nopLogger(), 24*time.Hour)
+	store.SetClock(clock.Now)
+
+	record, claimed, err := store.Begin(ctx, "alice", idemEndpoint, "k1", "hash-a")
+	if err != nil || !claimed {
+		t.Fatalf("first Begin = %v, %v; want the key claimed", claimed, err)
+	}
+	// While the first request runs, a retry waits and a different request is refused
+	if _, _, err := store.Begin(ctx, "alice", idemEndpoint, "k1", "hash-a"); !errors.Is(err, services.ErrIdempotencyKeyInFlight) {
+		t.Errorf("retry in flight: %v, want ErrIdempotencyKeyInFlight", err)
+	}
+	if _, _, err := store.Begin(ctx, "alice", idemEndpoint, "k1", "hash-b"); !errors.Is(err, services.ErrIdempotencyKeyReused) {
+		t.Errorf("other request in flight: %v, want ErrIdempotencyKeyReused", err)
+	}
+	// Keys are scoped to the caller and the endpoint
+	for _, scope := range []struct{ user, endpoint string }{{"bob", idemEndpoint}, {"alice", "POST /api/v1/videos/:id/comments"}} {
+		if _, claimed, err := store.Begin(ctx, scope.user, scope.endpoint, "k1", "hash-b"); err != nil || !claimed {
+			t.Errorf("%s on %s: %v, %v; want its own key", scope.user, scope.endpoint, claimed, err)
+		}
+	}
+
+	if err := store.Complete(ctx, record, 201, "application/json", []byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	replay, claimed, err := store.Begin(ctx, "alice", idemEndpoint, "k1", "hash-a")
+	if err != nil || claimed || replay.Status != 201 || replay.ContentType != "application/json" || string(replay.Body) != `{"id":1}` {
+		t.Fatalf("retry after completion = %+v, %v, %v; want the stored response", replay, claimed, err)
+	}
+	if _, _, err := store.Begin(ctx, "alice", idemEndpoint, "k1", "hash-b"); !errors.Is(err, services.ErrIdempotencyKeyReused) {
+		t.Errorf("other request after completion: %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	// Past the TTL the key is new again, whatever the request
+	clock.now = clock.now.Add(24 * time.Hour)
+	if _, claimed, err := store.Begin(ctx, "alice", idemEndpoint, "k1", "hash-b"); err != nil || !claimed {
+		t.Errorf("expired key: %v, %v; want it claimed", claimed, err)
+	}
+}
+
+func TestIdempotencyRelease(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := services.NewIdempotencyStore(db, nopLogger(), 24*time.Hour)
+	store.SetClock(clock.Now)
+
+	record, _, err := store.Begin(ctx, "alice", idemEndpoint, "k1", "hash-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Release(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	// A released key can be used again, even for a different request
+	record, claimed, err := store.Begin(ctx, "alice", idemEndpoint, "k1", "hash-b")
+	if err != nil || !claimed {
+		t.Fatalf("after release: %v, %v; want the key claimed", claimed, err)
+	}
+
+	// A request whose replica died holds the key for a minute at most
+	clock.now = clock.now.Add(59 * time.Second)
+	if _, _, err := store.Begin(ctx, "alice", idemEndpoint, "k1", "hash-b"); !errors.Is(err, services.ErrIdempotencyKeyInFlight) {
+		t.Errorf("retry within the lock timeout: %v, want ErrIdempotencyKeyInFlight", err)
+	}
+	clock.now = clock.now.Add(time.Second)
+	taken, claimed, err := store.Begin(ctx, "alice", idemEndpoint, "k1", "hash-b")
+	if err != nil || !claimed {
+		t.Fatalf("retry after the lock timeout: %v, %v; want the key taken over", claimed, err)
+	}
+	// The original request finishing late stores nothing over the new claim
+	if err := store.Complete(ctx, record, 201, "application/json", []byte("late")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Begin(ctx, "alice", idemEndpoint, "k1", "hash-b"); !errors.Is(err, services.ErrIdempotencyKeyInFlight) {
+		t.Errorf("after a late completion: %v, want the takeover still in flight", err)
+	}
+	if err := store.Complete(ctx, taken, 200, "application/json", []byte("ok")); err != nil {
+		t.Fatal(err)
+	}
+	// Release never drops a stored response
+	if err := store.Release(ctx, taken); err != nil {
+		t.Fatal(err)
+	}
+	if replay, claimed, err := store.Begin(ctx, "alice", idemEndpoint, "k1", "hash-b"); err != nil || claimed || string(replay.Body) != "ok" {
+		t.Errorf("after releasing a completed key: %+v, %v, %v; want the response replayed", replay, claimed, err)
+	}
+}
+
+func TestIdempotencyPrune(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := services.NewIdempotencyStore(db, nopLogger(), time.Hour)
+	store.SetClock(clock.Now)
+
+	store.Begin(ctx, "alice", idemEndpoint, "old", "h")
+	clock.now = clock.now.Add(30 * time.Minute)
+	store.Begin(ctx, "alice", idemEndpoint, "new", "h")
+	clock.now = clock.now.Add(31 * time.Minute)
+
+	if pruned, err := store.Prune(ctx); err != nil || pruned != 1 {
+		t.Fatalf("Prune = %d, %v; want the key past the TTL", pruned, err)
+	}
+	var keys []string
+	db.Model(&models.IdempotencyKey{}).Pluck("key", &keys)
+	if len(keys) != 1 || keys[0] != "new" {
+		t.Errorf("keys after pruning %v, want the one within the TTL", keys)
+	}
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// InboundEventArchive keeps every consumed broker message with its envelope and
+// outcome. Like the access log, writes go through a buffered channel and are
+// flushed in batches, so acking never waits on the table.
+type InboundEventArchive struct {
+	db            *gorm.DB
+	logger        *zap.SugaredLogger
+	videos        *VideoService
+	entries       chan models.InboundEvent
+	batchSize     int
+	flushInterval time.Duration
+	maxBody       int
+	done          chan struct{}
+}
+
+// NewInboundEventArchive creates an archive with a buffer of bufferSize events that
+// stores at most maxBody bytes of each payload, reprocessing through videos
+func NewInboundEventArchive(db *gorm.DB, logger *zap.SugaredLogger, videos *VideoService, bufferSize, batchSize int, flushInterval time.Duration, maxBody int64) *InboundEventArchive {
+	return &InboundEventArchive{
+		db:            db,
+		logger:        logger,
+		videos:        videos,
+		entries:       make(chan models.InboundEvent, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		maxBody:       int(maxBody),
+		done:          make(chan struct{}),
+	}
+}
+
+// Record queues a handled event with its outcome: status is one of the Inbound*
+// states, handleErr the handler's error and quarantined the quarantine entry it
+// went to, if any. When the buffer is full the event is dropped rather than
+// holding up the consumer.
+func (s *InboundEventArchive) Record(kind string, md events.Metadata, body []byte, status string, handleErr error, quarantined *uint) {
+	if s == nil {
+		return
+	}
+	var probe struct {
+		UploadID string `json:"uploadId"`
+	}
+	_ = json.Unmarshal(body, &probe) // best effort: the body may be what failed to parse
+
+	entry := models.InboundEvent{
+		Kind:               kind,
+		RoutingKey:         md.RoutingKey,
+		UploadID:           probe.UploadID,
+		BodySize:           len(body),
+		Metadata:           metadataToMap(md),
+		Status:             status,
+		QuarantinedEventID: quarantined,
+		ReceivedAt:         md.ReceivedAt.UTC(),
+	}
+	if entry.ReceivedAt.IsZero() {
+		entry.ReceivedAt = time.Now().UTC()
+	}
+	if s.maxBody > 0 && len(body) > s.maxBody {
+		body, entry.Truncated = truncateUTF8(body, s.maxBody), true
+	}
+	entry.Body = textBody(body)
+	if handleErr != nil {
+		entry.Error = handleErr.Error()
+	}
+	select {
+	case s.entries <- entry:
+	default:
+		metrics.InboundEventsTotal.WithLabelValues("dropped").Inc()
+	}
+}
+
+// Run flushes buffered events until ctx is cancelled, then drains what's left
+func (s *InboundEventArchive) Run(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.InboundEvent, 0, s.batchSize)
+	for {
+		select {
+		case entry := <-s.entries:
+			batch = append(batch, entry)
+			if len(batch) >= s.batchSize {
+				batch = s.flush(batch)
+			}
+		case <-ticker.C:
+			batch = s.flush(batch)
+		case <-ctx.Done():
+			for {
+				select {
+				case entry := <-s.entries:
+					batch = append(batch, entry)
+				default:
+					s.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// Wait blocks until Run has drained the buffer after cancellation
+func (s *InboundEventArchive) Wait() { <-s.done }
+
+func (s *InboundEventArchive) flush(batch []models.InboundEvent) []models.InboundEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := s.db.CreateInBatches(batch, s.batchSize).Error; err == nil {
+		metrics.InboundEventsTotal.WithLabelValues("written").Add(float64(len(batch)))
+		return batch[:0]
+	}
+	// One row the database rejects fails the whole insert, so retry one at a time
+	// and lose only that row
+	for i := range batch {
+		batch[i].ID = 0 // may be set by the rolled-back insert
+		if err := s.db.Create(&batch[i]).Error; err != nil {
+			metrics.InboundEventsTotal.WithLabelValues("failed").Inc()
+			s.logger.Errorw("Failed to write inbound event", "error", err, "kind", batch[i].Kind, "uploadID", batch[i].UploadID)
+			continue
+		}
+		metrics.InboundEventsTotal.WithLabelValues("written").Inc()
+	}
+	return batch[:0]
+}
+
+// truncateUTF8 cuts body, which is longer than limit, to at most limit bytes
+// without splitting a rune. Bytes that aren't UTF-8 are cut at limit; textBody
+// replaces them.
+func truncateUTF8(body []byte, limit int) []byte {
+	for cut := limit; cut > 0 && cut > limit-utf8.UTFMax; cut-- {
+		if utf8.RuneStart(body[cut]) {
+			return body[:cut]
+		}
+	}
+	return body[:limit]
+}
+
+// textBody makes body storable in a Postgres text column, which rejects invalid
+// UTF-8 and NUL: each is replaced with U+FFFD. Reprocessing decodes the same
+// events, since encoding/json reads invalid UTF-8 as U+FFFD and JSON can't
+// contain a raw NUL.
+func textBody(body []byte) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(string(body), "\uFFFD"), "\x00", "\uFFFD")
+}
+
+// List returns a page of archived events newest first, without their bodies,
+// optionally filtered by upload ID, status and kind
+func (s *InboundEventArchive) List(ctx context.Context, uploadID, status, kind string, page, perPage int) ([]models.InboundEvent, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.InboundEvent{})
+	if uploadID != "" {
+		query = query.Where("upload_id = ?", uploadID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count inbound events: %w", err)
+	}
+	out := []models.InboundEvent{}
+	if err := query.Omit("body").Order("received_at DESC, id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&out).Error; err != nil {
+		return nil, 0, fmt.Errorf("list inbound events: %w", err)
+	}
+	return out, total, nil
+}
+
+// Get returns one archived event with its body
+func (s *InboundEventArchive) Get(ctx context.Context, id uint) (*models.InboundEvent, error) {
+	var event models.InboundEvent
+	if err := s.db.WithContext(ctx).First(&event, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("inbound event %d: %w", id, ErrInboundEventNotFound)
+		}
+		return nil, fmt.Errorf("get inbound event: %w", err)
+	}
+	return &event, nil
+}
+
+// Reprocess runs an archived video event through its handler again, as a replay:
+// duplicate detection is bypassed and, unless force is set, the replay ordering
+// guard refuses to move the video backwards. Handlers upsert by upload ID, so
+// reprocessing an event that was applied already changes nothing. The event is
+// returned with the outcome recorded, even when the handler fails.
+func (s *InboundEventArchive) Reprocess(ctx context.Context, id uint, force bool) (*models.InboundEvent, error) {
+	event, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if event.Truncated {
+		return nil, fmt.Errorf("inbound event %d body was cut to the archive's size cap: %w", id, ErrEventNotReprocessable)
+	}
+	switch event.Kind {
+	case EventKindUploaded, EventKindTranscoded, EventKindTranscodeFailed:
+	default:
+		return nil, fmt.Errorf("inbound event %d is a %s event: %w", id, event.Kind, ErrEventNotReprocessable)
+	}
+
+	md := replayMetadata(event.Metadata, force)
+	err = dispatchVideoEvent(events.WithMetadata(ctx, md), s.videos, event.Kind, []byte(event.Body))
+
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"reprocess_count": gorm.Expr("reprocess_count + 1"),
+		"reprocessed_at":  now,
+	}
+	outcome := "ok"
+	var rejected *events.ReplayRejectedError
+	switch {
+	case err == nil:
+		updates["status"] = models.InboundReprocessed
+		updates["reprocess_error"] = ""
+	case errors.As(err, &rejected), errors.Is(err, ErrInvalidTransition):
+		outcome = "rejected"
+		updates["reprocess_error"] = err.Error()
+	default:
+		outcome = "error"
+		updates["reprocess_error"] = err.Error()
+	}
+	if uerr := s.db.WithContext(ctx).Model(event).Updates(updates).Error; uerr != nil {
+		s.logger.Errorw("Failed to record reprocess outcome", "error", uerr, "id", id)
+	}
+	event.ReprocessCount++
+	event.ReprocessedAt = &now
+	metrics.EventReprocessesTotal.WithLabelValues(event.Kind, outcome).Inc()
+	s.logger.Infow("Inbound event reprocessed", "id", id, "kind", event.Kind, "uploadID", event.UploadID,
+		"correlationID", md.CorrelationID, "force", force, "outcome", outcome, "error", err)
+	return event, err
+}
+
+// Prune deletes events received before retention
+func (s *InboundEventArchive) Prune(ctx context.Context, retention time.Duration) (int64, error) {
+	res := s.db.WithContext(ctx).Where("received_at < ?", time.Now().UTC().Add(-retention)).Delete(&models.InboundEvent{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("prune inbound events: %w", res.Error)
+	}
+	if res.RowsAffected > 0 {
+		s.logger.Infow("Pruned inbound events", "deleted", res.RowsAffected, "retention", retention)
+	}
+	return res.RowsAffected, nil
+}
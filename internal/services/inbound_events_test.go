@@ -0,0 +1,266 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// newArchive returns an archive over db storing at most maxBody bytes of each
+// payload, and a func that flushes what was recorded
+func newArchive(t *testing.T, db *gorm.DB, maxBody int64) (*services.InboundEventArchive, *services.VideoService, func()) {
+	t.Helper()
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	archive := services.NewInboundEventArchive(db, nopLogger(), videos, 64, 16, time.Hour, maxBody)
+	flush := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		go archive.Run(ctx)
+		cancel()
+		archive.Wait()
+	}
+	return archive, videos, flush
+}
+
+func archived(t *testing.T, db *gorm.DB) []models.InboundEvent {
+	t.Helper()
+	var out []models.InboundEvent
+	if err := db.Order("id").Find(&out).Error; err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestInboundEventArchiveRecord(t *testing.T) {
+	db := dbtest.Open(t)
+	archive, _, flush := newArchive(t, db, 20)
+	md := events.Metadata{RoutingKey: "video.uploaded", MessageID: "msg-1", ReceivedAt: time.Now().Add(-time.Minute)}
+
+	archive.Record(services.EventKindUploaded, md, []byte(`{"uploadId":"up-1"}`), models.InboundProcessed, nil, nil)
+	// Cut at 20 bytes would split the tenth é
+	archive.Record(services.EventKindUploaded, md, []byte("a"+strings.Repeat("é", 10)), models.InboundFailed, errors.New("unmarshal uploaded: invalid character"), nil)
+	// Postgres text rejects invalid UTF-8 and NUL
+	archive.Record(services.EventKindTranscoded, md, []byte{'{', 0xff, 0x00, '}'}, models.InboundFailed, errors.New("unmarshal transcoded"), nil)
+	flush()
+
+	got := archived(t, db)
+	if len(got) != 3 {
+		t.Fatalf("%d events archived, want 3", len(got))
+	}
+	if e := got[0]; e.UploadID != "up-1" || e.Body != `{"uploadId":"up-1"}` || e.Truncated || e.BodySize != 19 || e.Metadata["message_id"] != "msg-1" {
+		t.Errorf("processed event = %+v", e)
+	}
+	if e := got[1]; e.Body != "a"+strings.Repeat("é", 9) || !e.Truncated || e.BodySize != 21 || e.Error != "unmarshal uploaded: invalid character" {
+		t.Errorf("oversized event: body %q, truncated %v, size %d, error %q", e.Body, e.Truncated, e.BodySize, e.Error)
+	}
+	if e := got[2]; e.Body != "{\uFFFD\uFFFD}" || e.Truncated || e.BodySize != 4 {
+		t.Errorf("malformed event: body %q, truncated %v, size %d", e.Body, e.Truncated, e.BodySize)
+	}
+	for _, e := range got {
+		if !utf8.ValidString(e.Body) || strings.ContainsRune(e.Body, 0) {
+			t.Errorf("event %d body %q can't be stored as Postgres text", e.ID, e.Body)
+		}
+	}
+}
+
+func TestInboundEventArchiveFlushKeepsTheRestOfABatch(t *testing.T) {
+	db := dbtest.Open(t)
+	// Stand in for Postgres refusing one row of the batch
+	err := db.Callback().Create().Before("gorm:create").Register("test:reject_row", func(tx *gorm.DB) {
+		switch dest := tx.Statement.Dest.(type) {
+		case []models.InboundEvent:
+			for _, e := range dest {
+				if e.UploadID == "bad" {
+					tx.AddError(errors.New("invalid byte sequence for encoding \"UTF8\""))
+				}
+			}
+		case *models.InboundEvent:
+			if dest.UploadID == "bad" {
+				tx.AddError(errors.New("invalid byte sequence for encoding \"UTF8\""))
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, _, flush := newArchive(t, db, 0)
+	written := testutil.ToFloat64(metrics.InboundEventsTotal.WithLabelValues("written"))
+	failed := testutil.ToFloat64(metrics.InboundEventsTotal.WithLabelValues("failed"))
+
+	for _, uploadID := range []string{"up-1", "bad", "up-2"} {
+		archive.Record(services.EventKindUploaded, events.Metadata{}, []byte(`{"uploadId":"`+uploadID+`"}`), models.InboundProcessed, nil, nil)
+	}
+	flush()
+
+	got := archived(t, db)
+	if len(got) != 2 || got[0].UploadID != "up-1" || got[1].UploadID != "up-2" {
+		t.Errorf("archived %+v, want up-1 and up-2", got)
+	}
+	if n := testutil.ToFloat64(metrics.InboundEventsTotal.WithLabelValues("written")) - written; n != 2 {
+		t.Errorf("written = %v, want 2", n)
+	}
+	if n := testutil.ToFloat64(metrics.InboundEventsTotal.WithLabelValues("failed")) - failed; n != 1 {
+		t.Errorf("failed = %v, want 1", n)
+	}
+}
+
+func TestInboundEventArchiveList(t *testing.T) {
+	db := dbtest.Open(t)
+	archive, _, flush := newArchive(t, db, 0)
+	now := time.Now()
+	for i, e := range []struct {
+		kind, uploadID, status string
+	}{
+		{services.EventKindUploaded, "up-1", models.InboundProcessed},
+		{services.EventKindTranscoded, "up-1", models.InboundFailed},
+		{services.EventKindUploaded, "up-2", models.InboundFailed},
+		{services.EventKindTranscoded, "up-2", models.InboundProcessed},
+	} {
+		md := events.Metadata{ReceivedAt: now.Add(time.Duration(i) * time.Second)}
+		archive.Record(e.kind, md, []byte(`{"uploadId":"`+e.uploadID+`"}`), e.status, nil, nil)
+	}
+	flush()
+
+	tests := []struct {
+		name                   string
+		uploadID, status, kind string
+		want                   []string
+	}{
+		{"all, newest first", "", "", "", []string{"up-2/transcoded", "up-2/uploaded", "up-1/transcoded", "up-1/uploaded"}},
+		{"failed", "", models.InboundFailed, "", []string{"up-2/uploaded", "up-1/transcoded"}},
+		{"upload", "up-1", "", "", []string{"up-1/transcoded", "up-1/uploaded"}},
+		{"upload and status", "up-2", models.InboundFailed, "", []string{"up-2/uploaded"}},
+		{"kind", "", "", services.EventKindTranscoded, []string{"up-2/transcoded", "up-1/transcoded"}},
+		{"no match", "up-3", "", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, total, err := archive.List(context.Background(), tt.uploadID, tt.status, tt.kind, 1, 10)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var got []string
+			for _, e := range list {
+				got = append(got, e.UploadID+"/"+e.Kind)
+				if e.Body != "" {
+					t.Errorf("event %d listed with its body", e.ID)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") || total != int64(len(tt.want)) {
+				t.Errorf("List = %v (total %d), want %v", got, total, tt.want)
+			}
+		})
+	}
+
+	page, total, err := archive.List(context.Background(), "", "", "", 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || total != 4 || page[0].UploadID != "up-1" || page[0].Kind != services.EventKindUploaded {
+		t.Errorf("page 2 = %+v (total %d), want the oldest event", page, total)
+	}
+}
+
+func TestInboundEventReprocessIsIdempotent(t *testing.T) {
+	db := dbtest.Open(t)
+	archive, videos, flush := newArchive(t, db, 0)
+	// Replays are ordered by event time, so the event is newer than the row it creates
+	md := events.Metadata{RoutingKey: "video.uploaded", MessageID: "msg-1", Timestamp: time.Now().Add(time.Minute)}
+	body := []byte(`{"uploadId":"up-1","userId":"owner","title":"Holiday"}`)
+	if err := videos.HandleUploadedEvent(events.WithMetadata(context.Background(), md), &models.UploadedEvent{UploadID: "up-1", UserID: "owner", Title: "Holiday"}); err != nil {
+		t.Fatalf("HandleUploadedEvent: %v", err)
+	}
+	archive.Record(services.EventKindUploaded, md, body, models.InboundProcessed, nil, nil)
+	flush()
+	id := archived(t, db)[0].ID
+	before, err := videos.GetVideoByUploadID(context.Background(), "up-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		event, err := archive.Reprocess(context.Background(), id, false)
+		if err != nil {
+			t.Fatalf("Reprocess #%d: %v", i, err)
+		}
+		if event.ReprocessCount != i {
+			t.Errorf("Reprocess #%d: count %d", i, event.ReprocessCount)
+		}
+	}
+	after, err := videos.GetVideoByUploadID(context.Background(), "up-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.ID != before.ID || after.Version != before.Version || after.Title != "Holiday" {
+		t.Errorf("reprocessing changed the video: %+v, was %+v", after, before)
+	}
+	var count int64
+	db.Model(&models.Video{}).Where("upload_id = ?", "up-1").Count(&count)
+	if count != 1 {
+		t.Errorf("%d videos for the upload, want 1", count)
+	}
+	stored, err := archive.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.InboundReprocessed || stored.ReprocessCount != 2 || stored.ReprocessError != "" || stored.ReprocessedAt == nil {
+		t.Errorf("stored outcome = status %s, count %d, error %q", stored.Status, stored.ReprocessCount, stored.ReprocessError)
+	}
+}
+
+func TestInboundEventReprocessRefusesTruncatedEvents(t *testing.T) {
+	db := dbtest.Open(t)
+	archive, _, flush := newArchive(t, db, 16)
+	archive.Record(services.EventKindUploaded, events.Metadata{}, []byte(`{"uploadId":"up-1","userId":"owner","title":"Holiday"}`), models.InboundFailed, nil, nil)
+	flush()
+	stored := archived(t, db)[0]
+
+	if _, err := archive.Reprocess(context.Background(), stored.ID, true); !errors.Is(err, services.ErrEventNotReprocessable) {
+		t.Errorf("Reprocess = %v, want ErrEventNotReprocessable", err)
+	}
+	var count int64
+	db.Model(&models.Video{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d videos created from a truncated body", count)
+	}
+	if again := archived(t, db)[0]; again.ReprocessCount != 0 || again.Status != models.InboundFailed {
+		t.Errorf("refused reprocess recorded: count %d, status %s", again.ReprocessCount, again.Status)
+	}
+	if _, err := archive.Reprocess(context.Background(), stored.ID+1, false); !errors.Is(err, services.ErrInboundEventNotFound) {
+		t.Errorf("Reprocess of a missing event = %v, want ErrInboundEventNotFound", err)
+	}
+}
+
+func TestInboundEventArchivePrune(t *testing.T) {
+	db := dbtest.Open(t)
+	archive, _, flush := newArchive(t, db, 0)
+	now := time.Now()
+	for _, age := range []time.Duration{15 * 24 * time.Hour, 30 * 24 * time.Hour, 13 * 24 * time.Hour, time.Minute} {
+		archive.Record(services.EventKindUploaded, events.Metadata{ReceivedAt: now.Add(-age)}, []byte(`{}`), models.InboundProcessed, nil, nil)
+	}
+	flush()
+
+	pruned, err := archive.Prune(context.Background(), 14*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if pruned != 2 {
+		t.Errorf("pruned %d events, want 2", pruned)
+	}
+	for _, e := range archived(t, db) {
+		if e.ReceivedAt.Before(now.Add(-14 * 24 * time.Hour)) {
+			t.Errorf("event received %s outlived the retention", e.ReceivedAt)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// storageDeletions reads catalog_storage_deletions_total and the blobs deleted so far
+func storageDeletions() (pagesOK, pagesFailed, blobsOK, blobs float64) {
+	return testutil.ToFloat64(metrics.StorageDeletionsTotal.WithLabelValues("page", "ok")),
+		testutil.ToFloat64(metrics.StorageDeletionsTotal.WithLabelValues("page", "error")),
+		testutil.ToFloat64(metrics.StorageDeletionsTotal.WithLabelValues("blob", "ok")),
+		testutil.ToFloat64(metrics.StorageBlobsDeletedTotal)
+}
+
+func TestStorageDeletionMetrics(t *testing.T) {
+	db := dbtest.Open(t)
+	video, storage := hlsVideo(t, db, 7)
+	storage.blobs["thumbnails/owner/up-1.jpg"] = nil
+	deletes := services.NewVideoDeleteService(db, nopLogger(), storage)
+	ctx := context.Background()
+
+	// The second page fails after deleting one of its two blobs
+	storage.failCall = 2
+	pagesOK, pagesFailed, blobsOK, blobs := storageDeletions()
+	if _, err := deletes.DeleteVideoCompletely(ctx, video.ID, 1, "owner"); err == nil {
+		t.Fatal("cleanup succeeded despite the failed page")
+	}
+	gotPagesOK, gotPagesFailed, gotBlobsOK, gotBlobs := storageDeletions()
+	// Every page listed counts, including the empty ones of other prefixes
+	if calls := float64(len(storage.calls)); gotPagesOK-pagesOK != calls-1 || gotPagesFailed-pagesFailed != 1 {
+		t.Errorf("pages: %v ok and %v failed, want %v and 1", gotPagesOK-pagesOK, gotPagesFailed-pagesFailed, calls-1)
+	}
+	// The thumbnail goes too, as files are deleted after every prefix was tried
+	if gotBlobsOK-blobsOK != 1 || gotBlobs-blobs != 7 {
+		t.Errorf("first attempt: %v blob deletes, %v blobs deleted; want 1 and 5+1+1", gotBlobsOK-blobsOK, gotBlobs-blobs)
+	}
+
+	storage.failCall = 0
+	if _, err := deletes.DeleteVideoCompletely(ctx, video.ID, 1, "owner"); err != nil {
+		t.Fatal(err)
+	}
+	if pagesOK, _, _, blobs := storageDeletions(); pagesOK-gotPagesOK != 1 || blobs-gotBlobs != 1 {
+		t.Errorf("retry: %v pages, %v blobs deleted; want the one blob left", pagesOK-gotPagesOK, blobs-gotBlobs)
+	}
+}
+
+func TestRunStatusMetrics(t *testing.T) {
+	db := dbtest.Open(t)
+	for i, status := range []models.VideoStatus{models.StatusReady, models.StatusReady, models.StatusProcessing} {
+		createVideo(t, db, models.Video{UploadID: "up-" + string(rune('a'+i)), Title: "t", Status: status})
+	}
+	deleted := createVideo(t, db, models.Video{UploadID: "up-deleted", Title: "t", Status: models.StatusReady})
+	db.Delete(deleted)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		videos.RunStatusMetrics(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	defer func() { stop(); <-done }()
+
+	gauge := func(status models.VideoStatus) float64 {
+		return testutil.ToFloat64(metrics.Videos.WithLabelValues(string(status)))
+	}
+	waitFor(t, "the gauge to be set", func() bool { return gauge(models.StatusReady) == 2 })
+	if gauge(models.StatusProcessing) != 1 || gauge(models.StatusFailed) != 0 || gauge(models.StatusUploaded) != 0 {
+		t.Errorf("catalog_videos = processing %v, failed %v, uploaded %v", gauge(models.StatusProcessing),
+			gauge(models.StatusFailed), gauge(models.StatusUploaded))
+	}
+
+	// The next refresh picks up a status change, and the emptied status drops to 0
+	db.Model(&models.Video{}).Where("status = ?", models.StatusProcessing).Update("status", models.StatusFailed)
+	waitFor(t, "the next refresh", func() bool { return gauge(models.StatusFailed) == 1 })
+	if gauge(models.StatusProcessing) != 0 {
+		t.Errorf("processing = %v after its only video failed, want 0", gauge(models.StatusProcessing))
+	}
+}
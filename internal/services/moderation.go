@@ -0,0 +1,397 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// Content kinds submitted for moderation
+const (
+	ModerationKindTitle       = "title"
+	ModerationKindDescription = "description"
+	ModerationKindComment     = "comment"
+)
+
+// ModerationResult holds per-category scores in [0,1] returned by a provider
+type ModerationResult struct {
+	Categories map[string]float64 `json:"categories"`
+}
+
+// MaxScore returns the highest category score
+func (r *ModerationResult) MaxScore() float64 {
+	max := 0.0
+	for _, score := range r.Categories {
+		if score > max {
+			max = score
+		}
+	}
+	return max
+}
+
+// ModerationProvider scores user-generated text
+type ModerationProvider interface {
+	Name() string
+	ScoreText(ctx context.Context, kind, text string) (*ModerationResult, error)
+}
+
+// NoopModerationProvider scores everything as clean; used when no provider is configured
+type NoopModerationProvider struct{}
+
+// Name implements ModerationProvider
+func (NoopModerationProvider) Name() string { return "noop" }
+
+// ScoreText implements ModerationProvider
+func (NoopModerationProvider) ScoreText(context.Context, string, string) (*ModerationResult, error) {
+	return &ModerationResult{Categories: map[string]float64{}}, nil
+}
+
+// HTTPModerationProvider calls an external moderation service over HTTP.
+// It POSTs {"kind": ..., "text": ...} and expects {"categories": {"name": score}}.
+type HTTPModerationProvider struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPModerationProvider creates an HTTP provider with a per-request timeout
+func NewHTTPModerationProvider(url string, timeout time.Duration) *HTTPModerationProvider {
+	return &HTTPModerationProvider{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Name implements ModerationProvider
+func (p *HTTPModerationProvider) Name() string { return "http" }
+
+// ScoreText implements ModerationProvider
+func (p *HTTPModerationProvider) ScoreText(ctx context.Context, kind, text string) (*ModerationResult, error) {
+	body, err := json.Marshal(map[string]string{"kind": kind, "text": text})
+	if err != nil {
+		return nil, fmt.Errorf("encode moderation request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build moderation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("moderation request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation provider returned %d", resp.StatusCode)
+	}
+
+	var result ModerationResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode moderation response: %w", err)
+	}
+	return &result, nil
+}
+
+// NewModerationProvider builds the provider cfg selects; Load has checked an http
+// provider has a URL
+func NewModerationProvider(cfg config.Moderation) (ModerationProvider, error) {
+	switch cfg.Provider {
+	case "", config.ModerationProviderNone:
+		return NoopModerationProvider{}, nil
+	case config.ModerationProviderHTTP:
+		return NewHTTPModerationProvider(cfg.URL, cfg.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown moderation provider %q", cfg.Provider)
+	}
+}
+
+// ModerationService runs user content through a provider after it has been written
+// and records the outcome. It never blocks or fails the originating write.
+type ModerationService struct {
+	db            *gorm.DB
+	logger        *zap.SugaredLogger
+	provider      ModerationProvider
+	flagThreshold float64
+	highThreshold float64
+	inflight      chan struct{}
+}
+
+// NewModerationService creates a moderation service. Scores at or above flagThreshold
+// are recorded; at or above highThreshold comments are hidden and videos queued for review.
+func NewModerationService(db *gorm.DB, logger *zap.SugaredLogger, provider ModerationProvider, flagThreshold, highThreshold float64, maxInflight int) *ModerationService {
+	if maxInflight < 1 {
+		maxInflight = 1
+	}
+	return &ModerationService{
+		db:            db,
+		logger:        logger,
+		provider:      provider,
+		flagThreshold: flagThreshold,
+		highThreshold: highThreshold,
+		inflight:      make(chan struct{}, maxInflight),
+	}
+}
+
+// SubmitVideo asynchronously moderates a video's title and/or description
+func (s *ModerationService) SubmitVideo(videoID uint, fields map[string]string) {
+	for kind, text := range fields {
+		s.submit(models.ModerationTargetVideo, videoID, kind, text)
+	}
+}
+
+// SubmitComment asynchronously moderates a comment's content
+func (s *ModerationService) SubmitComment(commentID uint, content string) {
+	s.submit(models.ModerationTargetComment, commentID, ModerationKindComment, content)
+}
+
+func (s *ModerationService) submit(targetType string, targetID uint, kind, text string) {
+	if s == nil || text == "" {
+		return
+	}
+	if _, ok := s.provider.(NoopModerationProvider); ok {
+		return
+	}
+	select {
+	case s.inflight <- struct{}{}:
+	default:
+		// Saturated: shed rather than queue unbounded work behind a slow provider
+		metrics.ModerationRequestsTotal.WithLabelValues(kind, "dropped").Inc()
+		s.logger.Warnw("Moderation queue full, skipping", "targetType", targetType, "targetID", targetID, "kind", kind)
+		return
+	}
+	go func() {
+		defer func() { <-s.inflight }()
+		defer func() {
+			if r := recover(); r != nil {
+				s.logger.Errorw("Moderation hook panicked", "panic", r, "targetType", targetType, "targetID", targetID)
+			}
+		}()
+		s.moderate(context.Background(), targetType, targetID, kind, text)
+	}()
+}
+
+func (s *ModerationService) moderate(ctx context.Context, targetType string, targetID uint, kind, text string) {
+	start := time.Now()
+	result, err := s.provider.ScoreText(ctx, kind, text)
+	metrics.ModerationDuration.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.ModerationRequestsTotal.WithLabelValues(kind, "error").Inc()
+		s.logger.Warnw("Moderation provider failed", "error", err, "targetType", targetType, "targetID", targetID, "kind", kind)
+		return
+	}
+	metrics.ModerationRequestsTotal.WithLabelValues(kind, "ok").Inc()
+
+	score := result.MaxScore()
+	if score < s.flagThreshold {
+		return
+	}
+
+	flag := &models.ModerationFlag{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Field:      kind,
+		Categories: result.Categories,
+		MaxScore:   score,
+		Severity:   "medium",
+		Status:     models.ModerationFlagged,
+		Provider:   s.provider.Name(),
+	}
+	high := score >= s.highThreshold
+	if high {
+		flag.Severity = "high"
+		if targetType == models.ModerationTargetComment {
+			flag.Status = models.ModerationAutoHidden
+		} else {
+			// Videos are never taken down automatically; an admin decides
+			flag.Status = models.ModerationNeedsReview
+		}
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(flag).Error; err != nil {
+			return err
+		}
+		if high && targetType == models.ModerationTargetComment {
+			return hideComment(tx, targetID)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Errorw("Failed to record moderation flag", "error", err, "targetType", targetType, "targetID", targetID)
+		return
+	}
+	metrics.ModerationFlagsTotal.WithLabelValues(targetType, flag.Status).Inc()
+	s.logger.Infow("Content flagged by moderation", "targetType", targetType, "targetID", targetID, "kind", kind, "score", score, "status", flag.Status)
+}
+
+// hideComment moves a visible comment to pending and keeps comment_count in step
+func hideComment(tx *gorm.DB, commentID uint) error {
+	var c models.Comment
+	if err := tx.Select("id", "video_id").First(&c, commentID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil // deleted in the meantime
+		}
+		return err
+	}
+	res := tx.Model(&models.Comment{}).
+		Where("id = ? AND status = ?", commentID, models.CommentVisible).
+		UpdateColumn("status", models.CommentPending)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return res.Error
+	}
+	return tx.Model(&models.Video{}).Where("id = ?", c.VideoID).
+		UpdateColumn("comment_count", gorm.Expr("GREATEST(comment_count - 1, 0)")).Error
+}
+
+// FlagsQuery selects moderation flags, optionally filtered by status and target type
+func (s *ModerationService) FlagsQuery(ctx context.Context, status, targetType string) *gorm.DB {
+	query := s.db.WithContext(ctx).Model(&models.ModerationFlag{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	return query
+}
+
+// ListFlags returns a page of moderation flags, optionally filtered by status and target type
+func (s *ModerationService) ListFlags(ctx context.Context, status, targetType string, page, perPage int) ([]models.ModerationFlag, int64, error) {
+	query := s.FlagsQuery(ctx, status, targetType)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count moderation flags: %w", err)
+	}
+	var flags []models.ModerationFlag
+	if err := query.Order("max_score DESC, id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&flags).Error; err != nil {
+		return nil, 0, fmt.Errorf("list moderation flags: %w", err)
+	}
+	return flags, total, nil
+}
+
+// openFlagStatuses are the flag statuses still waiting for an admin
+var openFlagStatuses = []string{models.ModerationFlagged, models.ModerationNeedsReview, models.ModerationAutoHidden}
+
+// ModerationResolution is the outcome of an admin resolving a moderation flag
+type ModerationResolution struct {
+	Flag *models.ModerationFlag `json:"flag"`
+	// AlsoResolved counts the target's other open flags closed with it
+	AlsoResolved int64 `json:"also_resolved"`
+	// Restored says a comment the provider hid is visible again
+	Restored bool `json:"restored"`
+}
+
+// GetFlag returns a single moderation flag
+func (s *ModerationService) GetFlag(ctx context.Context, id uint) (*models.ModerationFlag, error) {
+	var flag models.ModerationFlag
+	if err := s.db.WithContext(ctx).First(&flag, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("moderation flag %d: %w", id, ErrModerationFlagNotFound)
+		}
+		return nil, fmt.Errorf("get moderation flag: %w", err)
+	}
+	return &flag, nil
+}
+
+// TargetOwner returns the user who wrote the flagged video or comment, deleted or
+// not; empty when the content is gone
+func (s *ModerationService) TargetOwner(ctx context.Context, flag *models.ModerationFlag) (string, error) {
+	model := interface{}(&models.Video{})
+	if flag.TargetType == models.ModerationTargetComment {
+		model = &models.Comment{}
+	}
+	var owners []string
+	if err := s.db.WithContext(ctx).Unscoped().Model(model).Where("id = ?", flag.TargetID).
+		Limit(1).Pluck("user_id", &owners).Error; err != nil {
+		return "", fmt.Errorf("load flagged %s: %w", flag.TargetType, err)
+	}
+	if len(owners) == 0 {
+		return "", nil
+	}
+	return owners[0], nil
+}
+
+// ResolveFlag closes an open flag with adminID's decision, and the target's other
+// open flags with it since the decision covers the content as a whole. Approving a
+// comment the provider hid moves it back to visible.
+func (s *ModerationService) ResolveFlag(ctx context.Context, id uint, decision, adminID string) (*ModerationResolution, error) {
+	var status string
+	switch decision {
+	case models.ModerationDecisionApprove:
+		status = models.ModerationApproved
+	case models.ModerationDecisionUphold:
+		status = models.ModerationUpheld
+	default:
+		return nil, fmt.Errorf("resolve moderation flag %d with %q: %w", id, decision, ErrInvalidStatus)
+	}
+	result := &ModerationResolution{Flag: &models.ModerationFlag{}}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		flag := result.Flag
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(flag, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("moderation flag %d: %w", id, ErrModerationFlagNotFound)
+			}
+			return err
+		}
+		if flag.ResolvedAt != nil {
+			return fmt.Errorf("moderation flag %d is %s: %w", id, flag.Status, ErrModerationFlagResolved)
+		}
+
+		var hidden int64
+		if err := tx.Model(&models.ModerationFlag{}).
+			Where("target_type = ? AND target_id = ? AND status = ?", flag.TargetType, flag.TargetID, models.ModerationAutoHidden).
+			Count(&hidden).Error; err != nil {
+			return fmt.Errorf("count hiding flags: %w", err)
+		}
+		now := time.Now().UTC()
+		res := tx.Model(&models.ModerationFlag{}).
+			Where("target_type = ? AND target_id = ? AND status IN ?", flag.TargetType, flag.TargetID, openFlagStatuses).
+			Updates(map[string]interface{}{"status": status, "resolved_by": adminID, "resolved_at": now})
+		if res.Error != nil {
+			return fmt.Errorf("resolve moderation flags: %w", res.Error)
+		}
+		result.AlsoResolved = res.RowsAffected - 1
+		flag.Status, flag.ResolvedBy, flag.ResolvedAt = status, adminID, &now
+
+		if status != models.ModerationApproved || hidden == 0 {
+			return nil
+		}
+		restored, err := showComment(tx, flag.TargetID)
+		result.Restored = restored
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// showComment moves a comment hidden by moderation back to visible and keeps
+// comment_count in step; the reverse of hideComment
+func showComment(tx *gorm.DB, commentID uint) (bool, error) {
+	var c models.Comment
+	if err := tx.Select("id", "video_id").First(&c, commentID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil // deleted in the meantime
+		}
+		return false, err
+	}
+	res := tx.Model(&models.Comment{}).
+		Where("id = ? AND status = ?", commentID, models.CommentPending).
+		UpdateColumn("status", models.CommentVisible)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+	return true, tx.Model(&models.Video{}).Where("id = ?", c.VideoID).
+		UpdateColumn("comment_count", gorm.Expr("comment_count + 1")).Error
+}
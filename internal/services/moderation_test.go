@@ -0,0 +1,214 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// moderatedComments wires comments to a moderation service using provider
+func moderatedComments(t *testing.T, provider services.ModerationProvider) (*gorm.DB, *services.CommentService, *models.Video) {
+	t.Helper()
+	db := dbtest.Open(t)
+	comments := services.NewCommentService(db, commentSettings, nopLogger())
+	comments.SetModeration(services.NewModerationService(db, nopLogger(), provider, 0.5, 0.9, 4))
+	return db, comments, createVideo(t, db, models.Video{Title: "moderated", CommentsEnabled: true})
+}
+
+func TestModerationProviderFailureNeverFailsWrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider *fakeProvider
+	}{
+		{"provider error", newFakeProvider(0, errors.New("provider returned 500"))},
+		{"provider timeout", newFakeProvider(0, context.DeadlineExceeded)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, comments, video := moderatedComments(t, tt.provider)
+			comment, err := comments.AddComment(context.Background(), video.ID, "alice", "Alice", "first!", nil)
+			if err != nil {
+				t.Fatalf("AddComment: %v", err)
+			}
+			select {
+			case <-tt.provider.calls:
+			case <-time.After(time.Second):
+				t.Fatal("provider never called")
+			}
+
+			var got models.Comment
+			db.First(&got, comment.ID)
+			if got.Status != models.CommentVisible {
+				t.Errorf("comment status = %s, want visible", got.Status)
+			}
+			var flags int64
+			db.Model(&models.ModerationFlag{}).Count(&flags)
+			if flags != 0 {
+				t.Errorf("%d flags recorded for a failed score", flags)
+			}
+		})
+	}
+}
+
+func TestModerationHungProviderDoesntBlockWrite(t *testing.T) {
+	provider := newFakeProvider(0.95, nil)
+	provider.release = make(chan struct{})
+	_, comments, video := moderatedComments(t, provider)
+	defer close(provider.release)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := comments.AddComment(context.Background(), video.ID, "alice", "Alice", "still pending", nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("AddComment: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("AddComment waited for the moderation provider")
+	}
+}
+
+func TestNewModerationProvider(t *testing.T) {
+	if p, err := services.NewModerationProvider(config.Moderation{Provider: config.ModerationProviderNone}); err != nil {
+		t.Errorf("none: %v", err)
+	} else if _, ok := p.(services.NoopModerationProvider); !ok {
+		t.Errorf("none: %T", p)
+	}
+	p, err := services.NewModerationProvider(config.Moderation{Provider: config.ModerationProviderHTTP, URL: "http://moderation.internal/", Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("http: %v", err)
+	}
+	if _, ok := p.(*services.HTTPModerationProvider); !ok {
+		t.Errorf("http: %T", p)
+	}
+	if _, err := services.NewModerationProvider(config.Moderation{Provider: "openai"}); err == nil {
+		t.Error("unknown provider accepted")
+	}
+}
+
+func TestHTTPModerationProviderTimeout(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	provider := services.NewHTTPModerationProvider(server.URL, 50*time.Millisecond)
+	start := time.Now()
+	if _, err := provider.ScoreText(context.Background(), services.ModerationKindComment, "hello"); err == nil {
+		t.Fatal("slow provider didn't fail")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ScoreText took %v despite a 50ms timeout", elapsed)
+	}
+}
+
+func TestHTTPModerationProviderStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	if _, err := services.NewHTTPModerationProvider(server.URL, time.Second).ScoreText(context.Background(), "comment", "x"); err == nil {
+		t.Fatal("502 from the provider wasn't an error")
+	}
+}
+
+// hiddenComment posts a comment the provider scores high and waits for it to be hidden
+func hiddenComment(t *testing.T) (*gorm.DB, *services.ModerationService, *models.Video, *models.Comment) {
+	t.Helper()
+	db := dbtest.Open(t)
+	moderation := services.NewModerationService(db, nopLogger(), newFakeProvider(0.95, nil), 0.5, 0.9, 4)
+	comments := services.NewCommentService(db, commentSettings, nopLogger())
+	comments.SetModeration(moderation)
+	video := createVideo(t, db, models.Video{Title: "moderated", CommentsEnabled: true})
+	comment, err := comments.AddComment(context.Background(), video.ID, "alice", "Alice", "something awful", nil)
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	waitFor(t, "the comment to be hidden", func() bool {
+		var got models.Comment
+		return db.First(&got, comment.ID).Error == nil && got.Status == models.CommentPending
+	})
+	return db, moderation, video, comment
+}
+
+func TestResolveFlagApproveRestoresComment(t *testing.T) {
+	db, moderation, video, comment := hiddenComment(t)
+	var v models.Video
+	db.First(&v, video.ID)
+	if v.CommentCount != 0 {
+		t.Fatalf("comment_count = %d while hidden, want 0", v.CommentCount)
+	}
+	var flag models.ModerationFlag
+	db.First(&flag)
+	create(t, db, &models.ModerationFlag{TargetType: models.ModerationTargetComment, TargetID: comment.ID,
+		Field: "comment", Severity: "medium", Status: models.ModerationFlagged})
+
+	ctx := context.Background()
+	res, err := moderation.ResolveFlag(ctx, flag.ID, models.ModerationDecisionApprove, "admin-1")
+	if err != nil {
+		t.Fatalf("ResolveFlag: %v", err)
+	}
+	if !res.Restored || res.AlsoResolved != 1 || res.Flag.Status != models.ModerationApproved || res.Flag.ResolvedBy != "admin-1" {
+		t.Errorf("resolution = %+v, flag %+v", res, res.Flag)
+	}
+	var got models.Comment
+	db.First(&got, comment.ID)
+	db.First(&v, video.ID)
+	if got.Status != models.CommentVisible || v.CommentCount != 1 {
+		t.Errorf("after approval: comment %s, comment_count %d; want visible, 1", got.Status, v.CommentCount)
+	}
+	var open int64
+	db.Model(&models.ModerationFlag{}).Where("resolved_at IS NULL").Count(&open)
+	if open != 0 {
+		t.Errorf("%d flags still open", open)
+	}
+
+	if _, err := moderation.ResolveFlag(ctx, flag.ID, models.ModerationDecisionUphold, "admin-2"); !errors.Is(err, services.ErrModerationFlagResolved) {
+		t.Errorf("second resolve: err = %v, want ErrModerationFlagResolved", err)
+	}
+}
+
+func TestResolveFlagUpholdKeepsCommentHidden(t *testing.T) {
+	db, moderation, _, comment := hiddenComment(t)
+	var flag models.ModerationFlag
+	db.First(&flag)
+	res, err := moderation.ResolveFlag(context.Background(), flag.ID, models.ModerationDecisionUphold, "admin-1")
+	if err != nil {
+		t.Fatalf("ResolveFlag: %v", err)
+	}
+	var got models.Comment
+	db.First(&got, comment.ID)
+	if res.Restored || res.Flag.Status != models.ModerationUpheld || got.Status != models.CommentPending {
+		t.Errorf("resolution = %+v, comment %s; want upheld and still pending", res, got.Status)
+	}
+}
+
+func TestResolveFlagErrors(t *testing.T) {
+	db := dbtest.Open(t)
+	moderation := services.NewModerationService(db, nopLogger(), services.NoopModerationProvider{}, 0.5, 0.9, 1)
+	ctx := context.Background()
+	if _, err := moderation.ResolveFlag(ctx, 7, models.ModerationDecisionApprove, "admin"); !errors.Is(err, services.ErrModerationFlagNotFound) {
+		t.Errorf("unknown flag: err = %v", err)
+	}
+	if _, err := moderation.ResolveFlag(ctx, 7, "delete", "admin"); !errors.Is(err, services.ErrInvalidStatus) {
+		t.Errorf("unknown decision: err = %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// NotificationService generates in-app notifications for video owners. Comment
+// notifications are individual rows until an owner+video pair exceeds the collapse
+// threshold within the window; after that a single rolling batch row absorbs
+// further comments in place.
+type NotificationService struct {
+	db                *gorm.DB
+	logger            *zap.SugaredLogger
+	collapseThreshold int
+	collapseWindow    time.Duration
+}
+
+// NewNotificationService creates a notification service. More than collapseThreshold
+// comment notifications for the same owner and video within collapseWindow are
+// collapsed into one "X new comments" notification.
+func NewNotificationService(db *gorm.DB, logger *zap.SugaredLogger, collapseThreshold int, collapseWindow time.Duration) *NotificationService {
+	return &NotificationService{
+		db:                db,
+		logger:            logger,
+		collapseThreshold: collapseThreshold,
+		collapseWindow:    collapseWindow,
+	}
+}
+
+// NotifyComment notifies the video owner about a new comment. It runs after the
+// comment has committed; self-comments and muted videos generate nothing.
+func (s *NotificationService) NotifyComment(ctx context.Context, comment *models.Comment) error {
+	if s == nil {
+		return nil
+	}
+	outcome := "created"
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var video models.Video
+		if err := tx.Select("id", "user_id", "title", "notifications_muted").First(&video, comment.VideoID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				outcome = "skipped"
+				return nil
+			}
+			return err
+		}
+		if video.UserID == comment.UserID {
+			outcome = "skipped"
+			return nil
+		}
+		if video.NotificationsMuted {
+			outcome = "muted"
+			return nil
+		}
+
+		// Serialize per owner+video so concurrent comments can't both decide to
+		// open a batch, or both insert individual rows past the threshold
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))",
+			fmt.Sprintf("notify:%s:%d", video.UserID, video.ID)).Error; err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		windowEnds := now.Add(s.collapseWindow)
+
+		var batch models.Notification
+		err := tx.Where("user_id = ? AND video_id = ? AND type = ? AND window_ends_at > ?",
+			video.UserID, video.ID, models.NotificationCommentBatch, now).
+			Order("id DESC").First(&batch).Error
+		switch {
+		case err == nil:
+			outcome = "collapsed"
+			// A read batch starts counting afresh: "X new comments" means new since
+			// the owner last looked, and the row becomes unread again
+			count := batch.Count + 1
+			if batch.ReadAt != nil {
+				count = 1
+			}
+			return tx.Model(&batch).Updates(map[string]interface{}{
+				"count":          count,
+				"message":        commentBatchMessage(count, video.Title),
+				"actor_id":       comment.UserID,
+				"comment_id":     comment.ID,
+				"read_at":        nil,
+				"window_ends_at": windowEnds,
+			}).Error
+		case err != gorm.ErrRecordNotFound:
+			return err
+		}
+
+		var recent int64
+		if err := tx.Model(&models.Notification{}).
+			Where("user_id = ? AND video_id = ? AND type = ? AND created_at > ?",
+				video.UserID, video.ID, models.NotificationComment, now.Add(-s.collapseWindow)).
+			Count(&recent).Error; err != nil {
+			return err
+		}
+
+		commentID := comment.ID
+		n := &models.Notification{
+			UserID:    video.UserID,
+			VideoID:   &video.ID,
+			Type:      models.NotificationComment,
+			ActorID:   comment.UserID,
+			CommentID: &commentID,
+			Count:     1,
+			Message:   commentMessage(comment, video.Title),
+		}
+		if int(recent) >= s.collapseThreshold {
+			outcome = "collapsed"
+			n.Type = models.NotificationCommentBatch
+			n.Message = commentBatchMessage(1, video.Title)
+			n.WindowEndsAt = &windowEnds
+		}
+		return tx.Create(n).Error
+	})
+	if err != nil {
+		metrics.NotificationsTotal.WithLabelValues(models.NotificationComment, "failed").Inc()
+		return fmt.Errorf("notify comment %d: %w", comment.ID, err)
+	}
+	metrics.NotificationsTotal.WithLabelValues(models.NotificationComment, outcome).Inc()
+	return nil
+}
+
+func commentMessage(comment *models.Comment, title string) string {
+	who := comment.Username
+	if who == "" {
+		who = "Someone"
+	}
+	return fmt.Sprintf("%s commented on %q", who, title)
+}
+
+func commentBatchMessage(count int, title string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 new comment on %q", title)
+	}
+	return fmt.Sprintf("%d new comments on %q", count, title)
+}
+
+// SetMuted turns comment notifications for a video off or on
+func (s *NotificationService) SetMuted(ctx context.Context, videoID uint, muted bool) error {
+	if err := s.db.WithContext(ctx).Model(&models.Video{}).Where("id = ?", videoID).
+		UpdateColumn("notifications_muted", muted).Error; err != nil {
+		return fmt.Errorf("set notifications muted: %w", err)
+	}
+	return nil
+}
+
+// List returns a user's notifications, most recently updated first, so a batch
+// that was just bumped surfaces at the top
+func (s *NotificationService) List(ctx context.Context, userID string, unreadOnly bool, page, perPage int) ([]models.Notification, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count notifications: %w", err)
+	}
+	var out []models.Notification
+	if err := query.Order("updated_at DESC, id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&out).Error; err != nil {
+		return nil, 0, fmt.Errorf("list notifications: %w", err)
+	}
+	return out, total, nil
+}
+
+// MarkRead marks one of the user's notifications as read
+func (s *NotificationService) MarkRead(ctx context.Context, userID string, id uint) error {
+	res := s.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		UpdateColumn("read_at", gorm.Expr("COALESCE(read_at, NOW())"))
+	if res.Error != nil {
+		return fmt.Errorf("mark notification read: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("notification %d: %w", id, ErrNotificationNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func notificationOutcomes(outcome string) float64 {
+	return testutil.ToFloat64(metrics.NotificationsTotal.WithLabelValues(models.NotificationComment, outcome))
+}
+
+// TestNotifyCommentCollapses comments past the threshold: the first comments are
+// individual notifications, then one batch row counts the rest, restarting its
+// count once the owner has read it
+func TestNotifyCommentCollapses(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	notifications := services.NewNotificationService(db, nopLogger(), 2, time.Hour)
+	video := createVideo(t, db, models.Video{Title: "Holiday", UserID: "owner"})
+	comment := func(n int) {
+		t.Helper()
+		c := &models.Comment{VideoID: video.ID, UserID: fmt.Sprintf("fan-%d", n), Username: fmt.Sprintf("Fan %d", n), Content: "nice"}
+		if err := db.Create(c).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := notifications.NotifyComment(ctx, c); err != nil {
+			t.Fatalf("NotifyComment: %v", err)
+		}
+	}
+	list := func() []models.Notification {
+		t.Helper()
+		out, _, err := notifications.List(ctx, "owner", false, 1, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	collapsed := notificationOutcomes("collapsed")
+	for n := 1; n <= 4; n++ {
+		comment(n)
+	}
+	got := list()
+	if len(got) != 3 {
+		t.Fatalf("%d notifications after 4 comments, want 2 individual and a batch", len(got))
+	}
+	batch := got[0]
+	if batch.Type != models.NotificationCommentBatch || batch.Count != 2 || batch.Message != `2 new comments on "Holiday"` || batch.ActorID != "fan-4" {
+		t.Errorf("batch = %+v", batch)
+	}
+	if got[1].Type != models.NotificationComment || got[1].Message != `Fan 2 commented on "Holiday"` {
+		t.Errorf("individual = %+v", got[1])
+	}
+	if n := notificationOutcomes("collapsed") - collapsed; n != 2 {
+		t.Errorf("collapsed +%v, want +2", n)
+	}
+
+	// Reading the batch resets what counts as new
+	if err := notifications.MarkRead(ctx, "owner", batch.ID); err != nil {
+		t.Fatal(err)
+	}
+	comment(5)
+	got = list()
+	if len(got) != 3 || got[0].ID != batch.ID || got[0].Count != 1 || got[0].ReadAt != nil || got[0].Message != `1 new comment on "Holiday"` {
+		t.Errorf("after reading and another comment: %+v", got[0])
+	}
+}
+
+func TestNotifyCommentSkipsAndMutes(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	notifications := services.NewNotificationService(db, nopLogger(), 10, time.Hour)
+	video := createVideo(t, db, models.Video{Title: "t", UserID: "owner"})
+	notify := func(userID string) {
+		t.Helper()
+		c := &models.Comment{VideoID: video.ID, UserID: userID, Content: "hi"}
+		db.Create(c)
+		if err := notifications.NotifyComment(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func() int64 {
+		var n int64
+		db.Model(&models.Notification{}).Count(&n)
+		return n
+	}
+
+	notify("owner")
+	if count() != 0 {
+		t.Error("the owner was notified of their own comment")
+	}
+	muted := notificationOutcomes("muted")
+	if err := notifications.SetMuted(ctx, video.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	notify("fan")
+	if count() != 0 || notificationOutcomes("muted")-muted != 1 {
+		t.Errorf("muted video: %d notifications, muted +%v", count(), notificationOutcomes("muted")-muted)
+	}
+	notifications.SetMuted(ctx, video.ID, false)
+	notify("fan")
+	if count() != 1 {
+		t.Errorf("%d notifications after unmuting, want 1", count())
+	}
+	// Without a service nothing is sent and nothing fails
+	var none *services.NotificationService
+	if err := none.NotifyComment(ctx, &models.Comment{VideoID: video.ID, UserID: "fan"}); err != nil {
+		t.Errorf("nil service: %v", err)
+	}
+}
+
+func TestNotificationMarkRead(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	notifications := services.NewNotificationService(db, nopLogger(), 10, time.Hour)
+	n := models.Notification{UserID: "owner", Type: models.NotificationComment, Message: "m"}
+	db.Create(&n)
+
+	if err := notifications.MarkRead(ctx, "someone-else", n.ID); !errors.Is(err, services.ErrNotificationNotFound) {
+		t.Errorf("another user's notification: %v, want ErrNotificationNotFound", err)
+	}
+	if err := notifications.MarkRead(ctx, "owner", n.ID); err != nil {
+		t.Fatal(err)
+	}
+	unread, total, err := notifications.List(ctx, "owner", true, 1, 20)
+	if err != nil || len(unread) != 0 || total != 0 {
+		t.Errorf("unread after marking read: %v, %d, %v", unread, total, err)
+	}
+	// Marking it again keeps the first read time
+	var first models.Notification
+	db.First(&first, n.ID)
+	time.Sleep(5 * time.Millisecond)
+	notifications.MarkRead(ctx, "owner", n.ID)
+	var again models.Notification
+	db.First(&again, n.ID)
+	if first.ReadAt == nil || again.ReadAt == nil || !again.ReadAt.Equal(*first.ReadAt) {
+		t.Errorf("read at %v, then %v", first.ReadAt, again.ReadAt)
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// OutboxConfig tunes the outbox dispatcher
+type OutboxConfig struct {
+	// PollInterval is how often pending messages are looked for when nothing
+	// nudges the dispatcher sooner
+	PollInterval time.Duration
+	// BatchSize bounds the messages published per poll
+	BatchSize int
+	// MaxAttempts is stored with each message; after that many failed publishes
+	// it is no longer retried
+	MaxAttempts int
+	// BackoffMin and BackoffMax bound the delay before a failed message is retried.
+	// The delay doubles with each attempt.
+	BackoffMin time.Duration
+	BackoffMax time.Duration
+	// PublishTimeout bounds one publish, including the broker's confirm
+	PublishTimeout time.Duration
+}
+
+// Outbox makes broker messages as durable as the database writes they describe.
+// Writers call Enqueue inside their transaction, so a message exists if and only
+// if its change committed; Run publishes pending messages with confirms and marks
+// them sent. Delivery is at least once: a crash between the broker's confirm and
+// the sent mark republishes the message, so consumers must tolerate duplicates.
+// Messages go out in insert order, except that a failed one is retried after a
+// backoff while later ones carry on.
+type Outbox struct {
+	db     *gorm.DB
+	logger *zap.SugaredLogger
+	cfg    OutboxConfig
+	wake   chan struct{}
+	done   chan struct{}
+}
+
+// NewOutbox creates an outbox
+func NewOutbox(db *gorm.DB, logger *zap.SugaredLogger, cfg OutboxConfig) *Outbox {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.BackoffMax < cfg.BackoffMin {
+		cfg.BackoffMax = cfg.BackoffMin
+	}
+	return &Outbox{db: db, logger: logger, cfg: cfg, wake: make(chan struct{}, 1), done: make(chan struct{})}
+}
+
+// Enqueue adds a message to the outbox inside tx, the transaction making the change
+// it announces. payload is encoded as JSON. A nil outbox enqueues nothing.
+func (o *Outbox) Enqueue(tx *gorm.DB, routingKey string, payload interface{}) error {
+	if o == nil {
+		return nil
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode outbox message: %w", err)
+	}
+	msg := models.OutboxMessage{
+		RoutingKey:    routingKey,
+		Body:          string(body),
+		MaxAttempts:   o.cfg.MaxAttempts,
+		NextAttemptAt: time.Now().UTC(),
+	}
+	if err := tx.Create(&msg).Error; err != nil {
+		return fmt.Errorf("enqueue outbox message: %w", err)
+	}
+	return nil
+}
+
+// Notify asks the dispatcher to poll now instead of waiting for the next tick; call
+// it after committing a transaction that enqueued something
+func (o *Outbox) Notify() {
+	if o == nil {
+		return
+	}
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Observe is a VideoChangeHook that nudges the dispatcher after each committed
+// video change
+func (o *Outbox) Observe(_ context.Context, _ VideoChange) { o.Notify() }
+
+// Run publishes pending messages through publisher until ctx is cancelled. Every
+// replica may run it; each batch locks its rows with SKIP LOCKED, so replicas
+// never publish the same message concurrently.
+func (o *Outbox) Run(ctx context.Context, publisher MessagePublisher) {
+	defer close(o.done)
+	ticker := time.NewTicker(o.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			sent, err := o.Dispatch(ctx, publisher)
+			if err != nil && ctx.Err() == nil {
+				o.logger.Errorw("Outbox dispatch failed", "error", err)
+			}
+			// A full batch means more may be waiting; keep going without a tick
+			if err != nil || sent < o.cfg.BatchSize {
+				break
+			}
+		}
+		o.updateBacklog(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Wait blocks until Run has returned after cancellation
+func (o *Outbox) Wait() { <-o.done }
+
+// Dispatch publishes one batch of due messages and returns how many it published.
+// The batch stops at the first failure, which usually means the broker is down;
+// the failed message is rescheduled with backoff and the rest wait for the next poll.
+func (o *Outbox) Dispatch(ctx context.Context, publisher MessagePublisher) (int, error) {
+	sent := 0
+	// The marks are committed even if ctx ends mid-batch, so what was published
+	// isn't published again
+	err := o.db.WithContext(context.WithoutCancel(ctx)).Transaction(func(tx *gorm.DB) error {
+		var batch []models.OutboxMessage
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at IS NULL AND attempts < max_attempts AND next_attempt_at <= ?", time.Now().UTC()).
+			Order("id").Limit(o.cfg.BatchSize).Find(&batch).Error; err != nil {
+			return fmt.Errorf("load outbox batch: %w", err)
+		}
+		for i := range batch {
+			msg := &batch[i]
+			if err := o.publish(ctx, publisher, msg); err != nil {
+				if ctx.Err() != nil {
+					// Shutting down: leave the message as it was for the next run
+					return nil
+				}
+				return o.reschedule(tx, msg, err)
+			}
+			now := time.Now().UTC()
+			if err := tx.Model(msg).Updates(map[string]interface{}{
+				"sent_at":  now,
+				"attempts": msg.Attempts + 1,
+			}).Error; err != nil {
+				return fmt.Errorf("mark outbox message %d sent: %w", msg.ID, err)
+			}
+			metrics.OutboxMessagesTotal.WithLabelValues(msg.RoutingKey, "published").Inc()
+			sent++
+		}
+		return nil
+	})
+	return sent, err
+}
+
+func (o *Outbox) publish(ctx context.Context, publisher MessagePublisher, msg *models.OutboxMessage) error {
+	if o.cfg.PublishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.cfg.PublishTimeout)
+		defer cancel()
+	}
+	return publisher.Publish(ctx, msg.RoutingKey, []byte(msg.Body))
+}
+
+// reschedule records a failed publish, giving up once the message's attempts are spent
+func (o *Outbox) reschedule(tx *gorm.DB, msg *models.OutboxMessage, cause error) error {
+	attempts := msg.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"last_error":      cause.Error(),
+		"next_attempt_at": time.Now().UTC().Add(o.backoff(attempts)),
+	}
+	if err := tx.Model(msg).Updates(updates).Error; err != nil {
+		return fmt.Errorf("reschedule outbox message %d: %w", msg.ID, err)
+	}
+	if attempts >= msg.MaxAttempts {
+		metrics.OutboxMessagesTotal.WithLabelValues(msg.RoutingKey, "exhausted").Inc()
+		o.logger.Errorw("Outbox message failed its last attempt; giving up",
+			"error", cause, "id", msg.ID, "routingKey", msg.RoutingKey, "attempts", attempts)
+		return nil
+	}
+	metrics.OutboxMessagesTotal.WithLabelValues(msg.RoutingKey, "retried").Inc()
+	o.logger.Warnw("Outbox publish failed; will retry",
+		"error", cause, "id", msg.ID, "routingKey", msg.RoutingKey, "attempts", attempts)
+	return nil
+}
+
+// backoff is the delay before retrying a message that has failed attempts times
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.cfg.BackoffMin
+	for i := 1; i < attempts && delay < o.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > o.cfg.BackoffMax {
+		delay = o.cfg.BackoffMax
+	}
+	return delay
+}
+
+// updateBacklog refreshes the backlog gauge: messages still to be published,
+// including those waiting out a backoff
+func (o *Outbox) updateBacklog(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
+	var pending int64
+	if err := o.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("sent_at IS NULL AND attempts < max_attempts").Count(&pending).Error; err != nil {
+		o.logger.Warnw("Failed to count outbox backlog", "error", err)
+		return
+	}
+	metrics.OutboxBacklog.Set(float64(pending))
+}
+
+// PruneSent deletes messages published more than retention ago. Messages that
+// exhausted their attempts are kept.
+func (o *Outbox) PruneSent(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoff := time.Now().UTC().Add(-retention)
+	res := o.db.WithContext(ctx).Where("sent_at < ?", cutoff).Delete(&models.OutboxMessage{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("prune outbox: %w", res.Error)
+	}
+	if res.RowsAffected > 0 {
+		o.logger.Infow("Pruned sent outbox messages", "rows", res.RowsAffected, "retention", retention)
+	}
+	return res.RowsAffected, nil
+}
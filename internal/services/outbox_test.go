@@ -0,0 +1,252 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// flakyPublisher fails its first failures publishes, then records what it is sent
+type flakyPublisher struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	sent     []string
+}
+
+func (p *flakyPublisher) Publish(_ context.Context, routingKey string, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.calls <= p.failures {
+		return errors.New("broker unreachable")
+	}
+	p.sent = append(p.sent, routingKey+" "+string(body))
+	return nil
+}
+
+func (p *flakyPublisher) sentCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.sent)
+}
+
+func outboxMessages(t *testing.T, db *gorm.DB) []models.OutboxMessage {
+	t.Helper()
+	var msgs []models.OutboxMessage
+	if err := db.Order("id").Find(&msgs).Error; err != nil {
+		t.Fatal(err)
+	}
+	return msgs
+}
+
+// makeDue moves every pending message's retry time to now
+func makeDue(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	if err := db.Model(&models.OutboxMessage{}).Where("sent_at IS NULL").
+		Update("next_attempt_at", time.Now().UTC().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutboxEnqueueFollowsTransaction(t *testing.T) {
+	db := dbtest.Open(t)
+	outbox := services.NewOutbox(db, nopLogger(), services.OutboxConfig{MaxAttempts: 3})
+
+	rollback := errors.New("rollback")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := outbox.Enqueue(tx, "ghost", map[string]string{"a": "b"}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatal(err)
+	}
+	if msgs := outboxMessages(t, db); len(msgs) != 0 {
+		t.Fatalf("a rolled back change left %+v", msgs)
+	}
+
+	// A committed video change enqueues its catalog event
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	videos.SetOutbox(outbox)
+	video := createVideo(t, db, models.Video{UploadID: "up-1", UserID: "owner", Title: "before"})
+	title := "after"
+	if _, err := videos.UpdateVideoForUser(context.Background(), video.ID, "owner", 0, &models.VideoUpdateRequest{Title: &title}); err != nil {
+		t.Fatal(err)
+	}
+	msgs := outboxMessages(t, db)
+	if len(msgs) != 1 || msgs[0].RoutingKey != services.CatalogVideoUpdated || msgs[0].MaxAttempts != 3 || msgs[0].SentAt != nil {
+		t.Fatalf("outbox after an update = %+v", msgs)
+	}
+}
+
+func TestOutboxRetriesFailedPublish(t *testing.T) {
+	db := dbtest.Open(t)
+	outbox := services.NewOutbox(db, nopLogger(), services.OutboxConfig{MaxAttempts: 5, BackoffMin: time.Hour, BackoffMax: 3 * time.Hour})
+	ctx := context.Background()
+	if err := outbox.Enqueue(db, "first", "first"); err != nil {
+		t.Fatal(err)
+	}
+	retried := testutil.ToFloat64(metrics.OutboxMessagesTotal.WithLabelValues("first", "retried"))
+	published := testutil.ToFloat64(metrics.OutboxMessagesTotal.WithLabelValues("first", "published"))
+
+	pub := &flakyPublisher{failures: 3}
+	// Each failure pushes the retry further out, doubling up to the cap
+	for attempt, want := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour} {
+		start := time.Now().UTC()
+		if sent, err := outbox.Dispatch(ctx, pub); sent != 0 || err != nil {
+			t.Fatalf("attempt %d: sent %d, err %v", attempt+1, sent, err)
+		}
+		first := outboxMessages(t, db)[0]
+		delay := first.NextAttemptAt.Sub(start)
+		if first.Attempts != attempt+1 || first.LastError == "" || delay < want || delay > want+time.Minute {
+			t.Errorf("after failure %d: attempts %d, error %q, retry in %v; want retry in %v",
+				attempt+1, first.Attempts, first.LastError, delay, want)
+		}
+		// Not due yet: nothing is tried
+		if sent, _ := outbox.Dispatch(ctx, pub); sent != 0 || pub.calls != attempt+1 {
+			t.Fatalf("message retried before its backoff: %d calls", pub.calls)
+		}
+		makeDue(t, db)
+	}
+
+	if sent, err := outbox.Dispatch(ctx, pub); sent != 1 || err != nil {
+		t.Fatalf("after recovery: sent %d, err %v", sent, err)
+	}
+	if msg := outboxMessages(t, db)[0]; msg.SentAt == nil || msg.Attempts != 4 || len(pub.sent) != 1 || pub.sent[0] != `first "first"` {
+		t.Errorf("message after recovery = %+v, published %q", msg, pub.sent)
+	}
+	if got := testutil.ToFloat64(metrics.OutboxMessagesTotal.WithLabelValues("first", "retried")) - retried; got != 3 {
+		t.Errorf("retries counted %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(metrics.OutboxMessagesTotal.WithLabelValues("first", "published")) - published; got != 1 {
+		t.Errorf("publishes counted %v, want 1", got)
+	}
+	// Sent messages aren't published again
+	if sent, _ := outbox.Dispatch(ctx, pub); sent != 0 {
+		t.Errorf("republished %d sent messages", sent)
+	}
+}
+
+func TestOutboxFailureDoesNotHoldBackLaterMessages(t *testing.T) {
+	db := dbtest.Open(t)
+	outbox := services.NewOutbox(db, nopLogger(), services.OutboxConfig{MaxAttempts: 3, BackoffMin: time.Hour})
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		if err := outbox.Enqueue(db, key, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pub := &flakyPublisher{failures: 1}
+
+	// The batch stops at the failure; the rest wait for the next poll, then go out
+	// in order while the failed one backs off
+	if sent, err := outbox.Dispatch(ctx, pub); sent != 0 || err != nil || pub.calls != 1 {
+		t.Fatalf("first poll: sent %d, err %v, %d calls", sent, err, pub.calls)
+	}
+	if sent, err := outbox.Dispatch(ctx, pub); sent != 2 || err != nil {
+		t.Fatalf("second poll: sent %d, err %v", sent, err)
+	}
+	makeDue(t, db)
+	if sent, err := outbox.Dispatch(ctx, pub); sent != 1 || err != nil {
+		t.Fatalf("after the backoff: sent %d, err %v", sent, err)
+	}
+	if len(pub.sent) != 3 || pub.sent[0] != `b "b"` || pub.sent[1] != `c "c"` || pub.sent[2] != `a "a"` {
+		t.Errorf("published %q", pub.sent)
+	}
+}
+
+func TestOutboxGivesUpAfterMaxAttempts(t *testing.T) {
+	db := dbtest.Open(t)
+	outbox := services.NewOutbox(db, nopLogger(), services.OutboxConfig{MaxAttempts: 2})
+	ctx := context.Background()
+	if err := outbox.Enqueue(db, "doomed", "x"); err != nil {
+		t.Fatal(err)
+	}
+	exhausted := testutil.ToFloat64(metrics.OutboxMessagesTotal.WithLabelValues("doomed", "exhausted"))
+
+	pub := &flakyPublisher{failures: 100}
+	for i := 0; i < 4; i++ {
+		outbox.Dispatch(ctx, pub)
+		makeDue(t, db)
+	}
+	if pub.calls != 2 {
+		t.Errorf("published %d times, want max attempts (2)", pub.calls)
+	}
+	msg := outboxMessages(t, db)[0]
+	if msg.SentAt != nil || msg.Attempts != 2 || msg.LastError == "" {
+		t.Errorf("exhausted message = %+v, want it kept unsent with its error", msg)
+	}
+	if got := testutil.ToFloat64(metrics.OutboxMessagesTotal.WithLabelValues("doomed", "exhausted")) - exhausted; got != 1 {
+		t.Errorf("exhaustion counted %v, want 1", got)
+	}
+}
+
+func TestOutboxRun(t *testing.T) {
+	db := dbtest.Open(t)
+	// The poll interval is far off; only Notify gets the message out promptly
+	outbox := services.NewOutbox(db, nopLogger(), services.OutboxConfig{PollInterval: time.Hour, MaxAttempts: 3, BackoffMin: time.Hour})
+	pub := &flakyPublisher{}
+	ctx, cancel := context.WithCancel(context.Background())
+	go outbox.Run(ctx, pub)
+
+	for i := 0; i < 3; i++ {
+		if err := outbox.Enqueue(db, "k", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outbox.Notify()
+	waitFor(t, "the notified dispatch", func() bool { return pub.sentCount() == 3 })
+	waitFor(t, "the backlog gauge", func() bool { return testutil.ToFloat64(metrics.OutboxBacklog) == 0 })
+
+	// A failed message counts in the backlog while it backs off
+	pub.mu.Lock()
+	pub.failures = pub.calls + 1
+	pub.mu.Unlock()
+	if err := outbox.Enqueue(db, "k", "late"); err != nil {
+		t.Fatal(err)
+	}
+	outbox.Notify()
+	waitFor(t, "the backlog gauge", func() bool { return testutil.ToFloat64(metrics.OutboxBacklog) == 1 })
+
+	cancel()
+	done := make(chan struct{})
+	go func() { outbox.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't stop after cancellation")
+	}
+}
+
+func TestOutboxPruneSent(t *testing.T) {
+	db := dbtest.Open(t)
+	outbox := services.NewOutbox(db, nopLogger(), services.OutboxConfig{MaxAttempts: 1})
+	ctx := context.Background()
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	recent := time.Now().UTC()
+	for _, msg := range []models.OutboxMessage{
+		{RoutingKey: "old", Body: "{}", MaxAttempts: 1, SentAt: &old},
+		{RoutingKey: "recent", Body: "{}", MaxAttempts: 1, SentAt: &recent},
+		{RoutingKey: "exhausted", Body: "{}", MaxAttempts: 1, Attempts: 1},
+	} {
+		create(t, db, &msg)
+	}
+	if n, err := outbox.PruneSent(ctx, 24*time.Hour); n != 1 || err != nil {
+		t.Fatalf("pruned %d, err %v; want the old sent message only", n, err)
+	}
+	if msgs := outboxMessages(t, db); len(msgs) != 2 || msgs[0].RoutingKey != "recent" || msgs[1].RoutingKey != "exhausted" {
+		t.Errorf("left %+v", msgs)
+	}
+}
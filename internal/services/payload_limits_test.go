@@ -0,0 +1,61 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestUpdateVideoMetadataLimits(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{Title: "before", Visibility: models.VisibilityPublic})
+
+	atLimit := strings.Repeat("x", models.MaxTitleLen)
+	if got, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Title: &atLimit}); err != nil || got.Title != atLimit {
+		t.Fatalf("title at the limit: %v", err)
+	}
+
+	over := strings.Repeat("x", models.MaxDescriptionLen+1)
+	title := "after"
+	_, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Title: &title, Description: &over})
+	var invalid *services.ValidationError
+	if !errors.As(err, &invalid) || !errors.Is(err, services.ErrInvalidMetadata) || invalid.Fields["description"] == "" {
+		t.Fatalf("description over the limit: %v, want a ValidationError for description", err)
+	}
+	// The update is refused whole, so the valid title isn't applied either
+	var stored models.Video
+	db.First(&stored, video.ID)
+	if stored.Title != atLimit || stored.Description != "" {
+		t.Errorf("stored %q / %d-character description after a refused update", stored.Title, len(stored.Description))
+	}
+}
+
+func TestCommentContentLimit(t *testing.T) {
+	db := dbtest.Open(t)
+	comments := services.NewCommentService(db, commentSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{Title: "a", CommentsEnabled: true})
+
+	// Counted in characters, so a comment of multibyte runes at the limit is fine
+	atLimit := strings.Repeat("ü", models.MaxCommentLen)
+	if _, err := comments.AddComment(ctx, video.ID, "alice", "alice", atLimit, nil); err != nil {
+		t.Fatalf("comment at the limit: %v", err)
+	}
+	_, err := comments.AddComment(ctx, video.ID, "alice", "alice", atLimit+"ü", nil)
+	var invalid *services.ValidationError
+	if !errors.As(err, &invalid) || !errors.Is(err, services.ErrInvalidComment) || invalid.Fields["content"] != "must be at most 2000 characters" {
+		t.Fatalf("comment one over: %v, want a ValidationError for content", err)
+	}
+	var count int64
+	db.Model(&models.Comment{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d comments stored, want only the one at the limit", count)
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// BlobURLSigner issues time-limited read URLs for blobs in a private container
+type BlobURLSigner interface {
+	GenerateSASURL(ctx context.Context, blobPath string, ttl time.Duration) (string, error)
+}
+
+// Playback is where a player fetches a video's master playlist
+type Playback struct {
+	URL string `json:"url"`
+	// Signed is true for a private video's time-limited URL
+	Signed    bool       `json:"signed"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// PlaybackURL returns the video's master playlist URL. A public video's stored URL
+// is returned unchanged; a private video gets a read URL signed for the configured
+// TTL, since its container isn't public. Callers check the requester may watch it.
+func (s *VideoService) PlaybackURL(ctx context.Context, video *models.Video) (*Playback, error) {
+	if video.HLSMasterURL == "" {
+		return nil, fmt.Errorf("video %d: %w", video.ID, ErrNoPlayback)
+	}
+	if video.IsPublic() {
+		return &Playback{URL: video.HLSMasterURL}, nil
+	}
+	signer, ok := s.Storage().(BlobURLSigner)
+	if !ok || signer == nil {
+		return nil, fmt.Errorf("video %d: %w", video.ID, ErrPlaybackUnavailable)
+	}
+	blobPath := path.Join(extractHLSPrefix(video.HLSMasterURL, video.UserID, video.UploadID), "master.m3u8")
+	expiresAt := time.Now().UTC().Add(s.playbackTTL)
+	url, err := signer.GenerateSASURL(ctx, blobPath, s.playbackTTL)
+	if err != nil {
+		s.logger.Errorw("Failed to sign playback URL", "error", err, "videoID", video.ID)
+		return nil, fmt.Errorf("sign playback URL: %w", err)
+	}
+	return &Playback{URL: url, Signed: true, ExpiresAt: &expiresAt}, nil
+}
@@ -0,0 +1,75 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+const masterURL = "https://acct.blob.core.windows.net/videos/hls/owner/up-1/master.m3u8"
+
+func TestPlaybackURLSignsPrivateVideos(t *testing.T) {
+	adapter, _ := azureAdapter(t)
+	settings := videoSettings
+	settings.PlaybackURLTTL = 15 * time.Minute
+	videos := services.NewVideoService(dbtest.Open(t), storageLoader(adapter), settings, nopLogger())
+	video := &models.Video{UploadID: "up-1", UserID: "owner", Visibility: models.VisibilityPrivate, HLSMasterURL: masterURL}
+
+	playback, err := videos.PlaybackURL(context.Background(), video)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !playback.Signed || playback.ExpiresAt == nil {
+		t.Fatalf("playback = %+v, want a signed URL with its expiry", playback)
+	}
+	if ttl := time.Until(*playback.ExpiresAt); ttl < 14*time.Minute || ttl > 15*time.Minute {
+		t.Errorf("URL expires in %s, want the configured 15m", ttl)
+	}
+	signed, err := url.Parse(playback.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(signed.Path, "/videos/hls/owner/up-1/master.m3u8") {
+		t.Errorf("signed path = %s, want the video's master playlist", signed.Path)
+	}
+	q := signed.Query()
+	if q.Get("sig") == "" || q.Get("sp") != "r" {
+		t.Errorf("query = %v, want a read-only signature", q)
+	}
+	expiry, err := time.Parse(time.RFC3339, q.Get("se"))
+	if err != nil || expiry.Sub(*playback.ExpiresAt).Abs() > time.Minute {
+		t.Errorf("se = %q, want about %s", q.Get("se"), playback.ExpiresAt)
+	}
+}
+
+func TestPlaybackURLPublicAndUnavailable(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	adapter, _ := azureAdapter(t)
+	videos := services.NewVideoService(db, storageLoader(adapter), videoSettings, nopLogger())
+
+	public := &models.Video{UploadID: "up-1", UserID: "owner", Visibility: models.VisibilityPublic, HLSMasterURL: masterURL}
+	if playback, err := videos.PlaybackURL(ctx, public); err != nil || playback.URL != masterURL || playback.Signed || playback.ExpiresAt != nil {
+		t.Errorf("public video: %+v, %v; want its stored URL unchanged", playback, err)
+	}
+	notReady := &models.Video{UploadID: "up-2", UserID: "owner", Visibility: models.VisibilityPrivate}
+	if _, err := videos.PlaybackURL(ctx, notReady); !errors.Is(err, services.ErrNoPlayback) {
+		t.Errorf("video without HLS: %v, want ErrNoPlayback", err)
+	}
+
+	// Neither a storage client that can't sign nor none at all can serve a private video
+	private := &models.Video{UploadID: "up-1", UserID: "owner", Visibility: models.VisibilityUnlisted, HLSMasterURL: masterURL}
+	for name, loader := range map[string]*services.StorageLoader{"not a signer": storageLoader(newFakeStorage()), "no storage": nil} {
+		videos := services.NewVideoService(db, loader, videoSettings, nopLogger())
+		if _, err := videos.PlaybackURL(ctx, private); !errors.Is(err, services.ErrPlaybackUnavailable) {
+			t.Errorf("%s: %v, want ErrPlaybackUnavailable", name, err)
+		}
+	}
+}
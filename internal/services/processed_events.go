@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// processedEventKey identifies an event across redeliveries: the producer's message
+// ID when there is one, else the upload ID and a hash of the decoded event
+func processedEventKey(kind, uploadID string, md events.Metadata, event interface{}) (string, error) {
+	if md.MessageID != "" {
+		return kind + ":msg:" + md.MessageID, nil
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("hash event: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	return kind + ":" + uploadID + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+// markProcessed records a broker event inside the transaction that applies it and
+// reports whether it had already been processed. The insert takes the key's lock, so
+// a concurrent duplicate waits for this transaction and then sees the row. Events
+// that didn't come from the broker are not tracked; replays from quarantine are
+// always applied and refresh the row.
+func (s *VideoService) markProcessed(ctx context.Context, tx *gorm.DB, kind, uploadID string, event interface{}) (bool, error) {
+	md, ok := events.FromContext(ctx)
+	if !ok {
+		return false, nil
+	}
+	key, err := processedEventKey(kind, uploadID, md, event)
+	if err != nil {
+		return false, err
+	}
+	row := &models.ProcessedEvent{
+		Key:         key,
+		Kind:        kind,
+		UploadID:    uploadID,
+		MessageID:   md.MessageID,
+		DeliveryTag: md.DeliveryTag,
+		ProcessedAt: time.Now().UTC(),
+	}
+	onConflict := clause.OnConflict{DoNothing: true}
+	if md.Replay {
+		onConflict = clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"processed_at"}),
+		}
+	}
+	res := tx.Clauses(onConflict).Create(row)
+	if res.Error != nil {
+		return false, fmt.Errorf("record processed event: %w", res.Error)
+	}
+	if res.RowsAffected > 0 || md.Replay {
+		return false, nil
+	}
+	metrics.EventsIgnoredTotal.WithLabelValues(kind, "duplicate").Inc()
+	s.logger.Infow("Skipping already processed event", "kind", kind, "uploadID", uploadID,
+		"messageID", md.MessageID, "redelivered", md.Redelivered)
+	return true, nil
+}
+
+// processedEventsPruneBatch bounds how many rows one prune statement deletes
+const processedEventsPruneBatch = 5000
+
+// PruneProcessedEvents forgets processed events older than retention. A redelivery
+// arriving after that is applied again, so retention must outlast any broker
+// redelivery delay.
+func (s *VideoService) PruneProcessedEvents(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoff := time.Now().UTC().Add(-retention)
+	var pruned int64
+	for {
+		res := s.db.WithContext(ctx).Exec(
+			"DELETE FROM processed_events WHERE key IN (SELECT key FROM processed_events WHERE processed_at < ? LIMIT ?)",
+			cutoff, processedEventsPruneBatch)
+		if res.Error != nil {
+			return pruned, fmt.Errorf("prune processed events: %w", res.Error)
+		}
+		pruned += res.RowsAffected
+		if res.RowsAffected < processedEventsPruneBatch {
+			break
+		}
+	}
+	if pruned > 0 {
+		s.logger.Infow("Pruned processed events", "rows", pruned, "retention", retention)
+	}
+	return pruned, nil
+}
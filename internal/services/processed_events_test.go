@@ -0,0 +1,176 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestRedeliveredUploadedEventIsSkipped(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	delivery := events.WithMetadata(context.Background(), events.Metadata{RoutingKey: "video.uploaded", MessageID: "msg-1", DeliveryTag: 1})
+	event := &models.UploadedEvent{UploadID: "up-1", UserID: "owner", Title: "Holiday", Description: "beach"}
+
+	if err := videos.HandleUploadedEvent(delivery, event); err != nil {
+		t.Fatalf("HandleUploadedEvent: %v", err)
+	}
+	video, err := videos.GetVideoByUploadID(context.Background(), "up-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The owner renames the video to the default title and clears the description,
+	// the fields an upload event backfills
+	title, description := "Untitled Video", ""
+	if _, err := videos.UpdateVideoForUser(context.Background(), video.ID, "owner", 0,
+		&models.VideoUpdateRequest{Title: &title, Description: &description}); err != nil {
+		t.Fatalf("UpdateVideoForUser: %v", err)
+	}
+
+	ignored := metrics.EventsIgnoredTotal.WithLabelValues(services.EventKindUploaded, "duplicate")
+	before := testutil.ToFloat64(ignored)
+	redelivery := events.WithMetadata(context.Background(), events.Metadata{RoutingKey: "video.uploaded", MessageID: "msg-1", DeliveryTag: 2, Redelivered: true})
+	if err := videos.HandleUploadedEvent(redelivery, event); err != nil {
+		t.Fatalf("redelivered HandleUploadedEvent: %v", err)
+	}
+	video, err = videos.GetVideo(context.Background(), video.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if video.Title != title || video.Description != description {
+		t.Errorf("redelivery overwrote the edit: title %q, description %q", video.Title, video.Description)
+	}
+	if got := testutil.ToFloat64(ignored) - before; got != 1 {
+		t.Errorf("duplicates counted = %v, want 1", got)
+	}
+	var rows []models.ProcessedEvent
+	db.Find(&rows)
+	if len(rows) != 1 || rows[0].Key != services.EventKindUploaded+":msg:msg-1" || rows[0].DeliveryTag != 1 {
+		t.Errorf("processed events = %+v, want the first delivery only", rows)
+	}
+}
+
+func TestTranscodedEventWithoutMessageIDIsDeduplicatedByPayload(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	delivery := events.WithMetadata(context.Background(), events.Metadata{RoutingKey: "video.transcoded"})
+	event := &models.TranscodedEvent{UploadID: "up-1", UserID: "owner", Ready: true,
+		HLS: models.HLSInfo{MasterURL: "https://cdn.example/up-1/master.m3u8"}}
+
+	if err := videos.HandleTranscodedEvent(delivery, event); err != nil {
+		t.Fatalf("HandleTranscodedEvent: %v", err)
+	}
+	video, err := videos.GetVideoByUploadID(context.Background(), "up-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every applied transcoded event bumps the version, so an unchanged one shows
+	// the duplicate was skipped
+	same := *event
+	if err := videos.HandleTranscodedEvent(delivery, &same); err != nil {
+		t.Fatalf("duplicate HandleTranscodedEvent: %v", err)
+	}
+	after, err := videos.GetVideo(context.Background(), video.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Version != video.Version {
+		t.Errorf("duplicate payload applied: version %d, want %d", after.Version, video.Version)
+	}
+
+	// Another payload for the same upload is a different event
+	retried := *event
+	retried.HLS.MasterURL = "https://cdn.example/up-1/v2/master.m3u8"
+	if err := videos.HandleTranscodedEvent(delivery, &retried); err != nil {
+		t.Fatalf("retried HandleTranscodedEvent: %v", err)
+	}
+	if video, err = videos.GetVideo(context.Background(), video.ID); err != nil {
+		t.Fatal(err)
+	}
+	if video.Version != after.Version+1 || video.HLSMasterURL != retried.HLS.MasterURL {
+		t.Errorf("new payload not applied: version %d, master %q", video.Version, video.HLSMasterURL)
+	}
+	var count int64
+	db.Model(&models.ProcessedEvent{}).Where("upload_id = ? AND message_id = ''", "up-1").Count(&count)
+	if count != 2 {
+		t.Errorf("%d processed events keyed by payload, want 2", count)
+	}
+}
+
+func TestReplayBypassesDedup(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	md := events.Metadata{RoutingKey: "video.uploaded", MessageID: "msg-1"}
+	event := &models.UploadedEvent{UploadID: "up-1", UserID: "owner", Title: "Holiday"}
+
+	if err := videos.HandleUploadedEvent(events.WithMetadata(context.Background(), md), event); err != nil {
+		t.Fatalf("HandleUploadedEvent: %v", err)
+	}
+	video, err := videos.GetVideoByUploadID(context.Background(), "up-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	title := "Untitled Video"
+	if _, err := videos.UpdateVideo(context.Background(), video.ID, &models.VideoUpdateRequest{Title: &title}); err != nil {
+		t.Fatalf("UpdateVideo: %v", err)
+	}
+	var first models.ProcessedEvent
+	if err := db.First(&first).Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&models.ProcessedEvent{}).Where("key = ?", first.Key).Update("processed_at", time.Now().UTC().Add(-time.Hour))
+
+	// Replays are ordered by event time, so this one is newer than the edit
+	md.Replay, md.Timestamp = true, time.Now().UTC().Add(time.Minute)
+	if err := videos.HandleUploadedEvent(events.WithMetadata(context.Background(), md), event); err != nil {
+		t.Fatalf("replayed HandleUploadedEvent: %v", err)
+	}
+	if video, err = videos.GetVideo(context.Background(), video.ID); err != nil {
+		t.Fatal(err)
+	}
+	if video.Title != "Holiday" {
+		t.Errorf("replay was skipped: title %q", video.Title)
+	}
+	var rows []models.ProcessedEvent
+	db.Find(&rows)
+	if len(rows) != 1 || !rows[0].ProcessedAt.After(time.Now().UTC().Add(-time.Minute)) {
+		t.Errorf("processed events = %+v, want the one row refreshed", rows)
+	}
+}
+
+func TestPruneProcessedEvents(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	now := time.Now().UTC()
+	for key, age := range map[string]time.Duration{
+		"uploaded:msg:old":    8 * 24 * time.Hour,
+		"uploaded:msg:older":  30 * 24 * time.Hour,
+		"uploaded:msg:recent": 6 * 24 * time.Hour,
+		"uploaded:msg:new":    time.Minute,
+	} {
+		if err := db.Create(&models.ProcessedEvent{Key: key, Kind: services.EventKindUploaded, ProcessedAt: now.Add(-age)}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruned, err := videos.PruneProcessedEvents(context.Background(), 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("PruneProcessedEvents: %v", err)
+	}
+	if pruned != 2 {
+		t.Errorf("pruned %d rows, want 2", pruned)
+	}
+	var keys []string
+	db.Model(&models.ProcessedEvent{}).Order("key").Pluck("key", &keys)
+	if len(keys) != 2 || keys[0] != "uploaded:msg:new" || keys[1] != "uploaded:msg:recent" {
+		t.Errorf("kept %v, want the rows inside the retention", keys)
+	}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// ActorSystem is the actor recorded for changes made by the service itself, such
+// as applying broker events
+const ActorSystem = "system"
+
+// publicEventLockKey is the transaction-scoped advisory lock serializing appends to
+// the public event log
+const publicEventLockKey = 7423002
+
+// appendPublicEvent writes an entry to the public event log inside tx, the same
+// transaction as the change it records. Appends hold an advisory lock until commit,
+// so sequence numbers become visible in order: a reader that has seen seq N never
+// later finds a smaller one appear. Call it last in the transaction to keep the
+// lock short.
+func appendPublicEvent(tx *gorm.DB, event *models.PublicEvent) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", publicEventLockKey).Error; err != nil {
+		return fmt.Errorf("lock public event log: %w", err)
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+	if err := tx.Create(event).Error; err != nil {
+		return fmt.Errorf("append public event: %w", err)
+	}
+	metrics.PublicEventsTotal.WithLabelValues(event.Type).Inc()
+	return nil
+}
+
+// newPublicEvent describes a public event about video
+func newPublicEvent(eventType string, video *models.Video, actorID string) *models.PublicEvent {
+	return &models.PublicEvent{
+		Type:     eventType,
+		VideoID:  video.ID,
+		UploadID: video.UploadID,
+		OwnerID:  video.UserID,
+		ActorID:  actorID,
+	}
+}
+
+// isPublished reports whether anyone can watch the video
+func isPublished(video *models.Video) bool {
+	return video.Status == models.StatusReady && video.IsListed()
+}
+
+// appendPublishedEvent logs video.published when a change makes the video
+// watchable by everyone, by finishing transcoding, by becoming public or by being
+// reinstated after a takedown
+func appendPublishedEvent(tx *gorm.DB, before models.Video, after *models.Video, actorID string) error {
+	if isPublished(after) && !isPublished(&before) {
+		return appendPublicEvent(tx, newPublicEvent(models.PublicEventPublished, after, actorID))
+	}
+	return nil
+}
+
+// PublicEventLog reads the public event log
+type PublicEventLog struct {
+	db     *gorm.DB
+	logger *zap.SugaredLogger
+}
+
+// NewPublicEventLog creates a reader for the public event log
+func NewPublicEventLog(db *gorm.DB, logger *zap.SugaredLogger) *PublicEventLog {
+	return &PublicEventLog{db: db, logger: logger}
+}
+
+// List returns up to limit entries after afterSeq in sequence order, optionally of
+// one type, and whether more remain
+func (l *PublicEventLog) List(ctx context.Context, afterSeq int64, eventType string, limit int) ([]models.PublicEvent, bool, error) {
+	query := l.db.WithContext(ctx).Where("seq > ?", afterSeq)
+	if eventType != "" {
+		query = query.Where("type = ?", eventType)
+	}
+	var entries []models.PublicEvent
+	if err := query.Order("seq").Limit(limit + 1).Find(&entries).Error; err != nil {
+		return nil, false, fmt.Errorf("list public events: %w", err)
+	}
+	if len(entries) > limit {
+		return entries[:limit], true, nil
+	}
+	return entries, false, nil
+}
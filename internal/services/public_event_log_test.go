@@ -0,0 +1,226 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// publicEvents returns the whole log in sequence order
+func publicEvents(t *testing.T, db *gorm.DB) []models.PublicEvent {
+	t.Helper()
+	var entries []models.PublicEvent
+	if err := db.Order("seq").Find(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestPublicEventLogLifecycle(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	reports := services.NewReportService(db, videos, nopLogger())
+	ctx := context.Background()
+
+	if err := videos.HandleTranscodedEvent(ctx, &models.TranscodedEvent{UploadID: "up-1", UserID: "owner", Ready: true,
+		HLS: models.HLSInfo{MasterURL: "https://cdn.example/up-1/master.m3u8"}}); err != nil {
+		t.Fatal(err)
+	}
+	video, err := videos.GetVideoByUploadID(ctx, "up-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, public := models.VisibilityPrivate, models.VisibilityPublic
+	steps := []func() error{
+		// Hiding it is not a public event; making it public again is
+		func() error {
+			_, err := videos.UpdateVideoForUser(ctx, video.ID, "owner", 0, &models.VideoUpdateRequest{Visibility: &private})
+			return err
+		},
+		func() error {
+			_, err := videos.UpdateVideoForUser(ctx, video.ID, "owner", 0, &models.VideoUpdateRequest{Visibility: &public})
+			return err
+		},
+		func() error {
+			report, err := reports.Report(ctx, video.ID, "reporter", models.ReportSpam, "")
+			if err != nil {
+				return err
+			}
+			takenDown := models.ModerationStatusTakenDown
+			_, err = reports.Resolve(ctx, report.ID, models.ReportReviewed, &takenDown, "admin", "")
+			return err
+		},
+		func() error {
+			_, err := videos.DeleteVideoForUser(ctx, video.ID, "owner")
+			return err
+		},
+		func() error {
+			_, err := videos.RestoreVideo(ctx, video.ID, "owner")
+			return err
+		},
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+
+	want := []struct{ typ, actor string }{
+		{models.PublicEventPublished, services.ActorSystem},
+		{models.PublicEventPublished, "owner"},
+		{models.PublicEventTakenDown, "admin"},
+		{models.PublicEventDeleted, "owner"},
+		{models.PublicEventRestored, "owner"},
+	}
+	entries := publicEvents(t, db)
+	if len(entries) != len(want) {
+		t.Fatalf("log has %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, e := range entries {
+		if e.Type != want[i].typ || e.ActorID != want[i].actor || e.VideoID != video.ID || e.UploadID != "up-1" || e.OwnerID != "owner" || e.OccurredAt.IsZero() {
+			t.Errorf("entry %d = %+v, want %s by %q", i, e, want[i].typ, want[i].actor)
+		}
+		if i > 0 && e.Seq <= entries[i-1].Seq {
+			t.Errorf("seq %d follows %d", e.Seq, entries[i-1].Seq)
+		}
+	}
+	if d := entries[2].Details; d["report_id"] == nil || d["reason"] != string(models.ReportSpam) {
+		t.Errorf("takedown details = %v, want the report ID and reason", d)
+	}
+}
+
+// TestPublicEventLogSameTransaction checks a change whose log entry can't be
+// written doesn't happen either
+func TestPublicEventLogSameTransaction(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{Title: "kept", Status: models.StatusReady})
+	if err := db.Migrator().DropTable(&models.PublicEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := videos.DeleteVideoForUser(ctx, video.ID, "owner"); err == nil {
+		t.Fatal("delete succeeded without its log entry")
+	}
+	if _, err := videos.GetVideo(ctx, video.ID); err != nil {
+		t.Errorf("video gone after the failed delete: %v", err)
+	}
+}
+
+func TestPublicEventLogConcurrentAppends(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	log := services.NewPublicEventLog(db, nopLogger())
+	ctx := context.Background()
+	const writers, perWriter = 8, 5
+	ids := make([][]uint, writers)
+	for w := range ids {
+		for i := 0; i < perWriter; i++ {
+			ids[w] = append(ids[w], createVideo(t, db, models.Video{Title: fmt.Sprintf("w%d-%d", w, i), Status: models.StatusReady}).ID)
+		}
+	}
+
+	// A replaying reader pages with after_seq while the writers append
+	done := make(chan struct{})
+	read := make(chan []models.PublicEvent)
+	go func() {
+		var seen []models.PublicEvent
+		var after int64
+		for {
+			select {
+			case <-done:
+				rest, _, err := log.List(ctx, after, "", 1000)
+				if err != nil {
+					t.Error(err)
+				}
+				read <- append(seen, rest...)
+				return
+			default:
+			}
+			page, _, err := log.List(ctx, after, "", 3)
+			if err != nil {
+				t.Error(err)
+				read <- seen
+				return
+			}
+			if len(page) > 0 {
+				seen = append(seen, page...)
+				after = page[len(page)-1].Seq
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := range ids {
+		wg.Add(1)
+		go func(ids []uint) {
+			defer wg.Done()
+			for _, id := range ids {
+				if _, err := videos.DeleteVideoForUser(ctx, id, "owner"); err != nil {
+					t.Errorf("delete %d: %v", id, err)
+				}
+			}
+		}(ids[w])
+	}
+	wg.Wait()
+	close(done)
+	seen := <-read
+
+	entries := publicEvents(t, db)
+	if len(entries) != writers*perWriter {
+		t.Fatalf("%d entries, want %d", len(entries), writers*perWriter)
+	}
+	videosLogged := map[uint]bool{}
+	for i, e := range entries {
+		if i > 0 && e.Seq <= entries[i-1].Seq {
+			t.Fatalf("seq %d follows %d", e.Seq, entries[i-1].Seq)
+		}
+		videosLogged[e.VideoID] = true
+	}
+	if len(videosLogged) != writers*perWriter {
+		t.Errorf("%d videos logged, want each once", len(videosLogged))
+	}
+	// The reader missed nothing and never saw the sequence go backwards
+	if len(seen) != len(entries) {
+		t.Fatalf("reader saw %d entries of %d", len(seen), len(entries))
+	}
+	for i := range seen {
+		if seen[i].Seq != entries[i].Seq {
+			t.Fatalf("reader's entry %d has seq %d, the log %d", i, seen[i].Seq, entries[i].Seq)
+		}
+	}
+}
+
+func TestPublicEventLogList(t *testing.T) {
+	db := dbtest.Open(t)
+	log := services.NewPublicEventLog(db, nopLogger())
+	ctx := context.Background()
+	types := []string{models.PublicEventPublished, models.PublicEventDeleted, models.PublicEventPublished, models.PublicEventRestored, models.PublicEventPublished}
+	for i, typ := range types {
+		create(t, db, &models.PublicEvent{Type: typ, VideoID: uint(i + 1), OccurredAt: db.NowFunc()})
+	}
+
+	page, more, err := log.List(ctx, 0, "", 2)
+	if err != nil || len(page) != 2 || !more || page[0].Seq != 1 || page[1].Seq != 2 {
+		t.Fatalf("first page = %+v, more %v, err %v", page, more, err)
+	}
+	page, more, err = log.List(ctx, 2, "", 10)
+	if err != nil || len(page) != 3 || more || page[0].Seq != 3 {
+		t.Fatalf("after seq 2 = %+v, more %v, err %v", page, more, err)
+	}
+	page, _, err = log.List(ctx, 1, models.PublicEventPublished, 10)
+	if err != nil || len(page) != 2 || page[0].Seq != 3 || page[1].Seq != 5 {
+		t.Errorf("published after 1 = %+v, err %v", page, err)
+	}
+	if page, more, _ := log.List(ctx, 5, "", 10); len(page) != 0 || more {
+		t.Errorf("after the end = %+v, more %v", page, more)
+	}
+}
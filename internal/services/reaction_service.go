@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// ReactionCounts are a video's like and dislike counts after a reaction change,
+// along with the caller's reaction (0 for none)
+type ReactionCounts struct {
+	VideoID      uint  `json:"video_id"`
+	LikeCount    int64 `json:"like_count"`
+	DislikeCount int64 `json:"dislike_count"`
+	MyReaction   int   `json:"my_reaction"`
+}
+
+// ReactionService records likes and dislikes, keeping the denormalized counters on
+// videos in step with video_reactions in the same transaction
+type ReactionService struct {
+	db     *gorm.DB
+	logger *zap.SugaredLogger
+}
+
+// NewReactionService creates a reaction service
+func NewReactionService(db *gorm.DB, logger *zap.SugaredLogger) *ReactionService {
+	return &ReactionService{db: db, logger: logger}
+}
+
+// React sets userID's reaction to videoID to value (models.ReactionLike or
+// models.ReactionDislike). Repeating the current reaction changes nothing; switching
+// moves one count from the old counter to the new one.
+func (s *ReactionService) React(ctx context.Context, videoID uint, userID string, value int) (*ReactionCounts, error) {
+	if value != models.ReactionLike && value != models.ReactionDislike {
+		return nil, fmt.Errorf("invalid reaction value %d", value)
+	}
+	counts := &ReactionCounts{VideoID: videoID, MyReaction: value}
+	outcome := "added"
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		previous, err := lockReaction(tx, videoID, userID)
+		if err != nil {
+			return err
+		}
+		if previous == nil {
+			// Insert-or-lock: a concurrent first reaction by the same user wins the
+			// insert, and this transaction then locks and updates its row
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.VideoReaction{VideoID: videoID, UserID: userID, Value: value})
+			if res.Error != nil {
+				return fmt.Errorf("insert reaction: %w", res.Error)
+			}
+			if res.RowsAffected == 0 {
+				if previous, err = lockReaction(tx, videoID, userID); err != nil {
+					return err
+				}
+			}
+		}
+		switch {
+		case previous == nil:
+			err = adjustReactionCounts(tx, videoID, map[int]int{value: 1})
+		case previous.Value == value:
+			outcome = "unchanged"
+		default:
+			outcome = "switched"
+			// Update writes the new value into previous, so keep the old one first
+			was := previous.Value
+			if err = tx.Model(previous).Update("value", value).Error; err != nil {
+				return fmt.Errorf("update reaction: %w", err)
+			}
+			err = adjustReactionCounts(tx, videoID, map[int]int{was: -1, value: 1})
+		}
+		if err != nil {
+			return err
+		}
+		return loadReactionCounts(tx, counts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.VideoReactionsTotal.WithLabelValues(reactionName(value), outcome).Inc()
+	return counts, nil
+}
+
+// ClearReaction removes userID's reaction to videoID, if any
+func (s *ReactionService) ClearReaction(ctx context.Context, videoID uint, userID string) (*ReactionCounts, error) {
+	counts := &ReactionCounts{VideoID: videoID}
+	var cleared *models.VideoReaction
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		previous, err := lockReaction(tx, videoID, userID)
+		if err != nil {
+			return err
+		}
+		if previous != nil {
+			if err := tx.Delete(previous).Error; err != nil {
+				return fmt.Errorf("delete reaction: %w", err)
+			}
+			if err := adjustReactionCounts(tx, videoID, map[int]int{previous.Value: -1}); err != nil {
+				return err
+			}
+		}
+		cleared = previous
+		return loadReactionCounts(tx, counts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cleared != nil {
+		metrics.VideoReactionsTotal.WithLabelValues(reactionName(cleared.Value), "cleared").Inc()
+	}
+	return counts, nil
+}
+
+// UserReactions returns userID's reactions to the given videos; videos without one
+// are absent from the map
+func (s *ReactionService) UserReactions(ctx context.Context, userID string, videoIDs []uint) (map[uint]int, error) {
+	out := make(map[uint]int, len(videoIDs))
+	if userID == "" || len(videoIDs) == 0 {
+		return out, nil
+	}
+	var rows []models.VideoReaction
+	if err := s.db.WithContext(ctx).Select("video_id", "value").
+		Where("user_id = ? AND video_id IN ?", userID, videoIDs).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("load reactions: %w", err)
+	}
+	for _, r := range rows {
+		out[r.VideoID] = r.Value
+	}
+	return out, nil
+}
+
+// lockReaction loads and row-locks a user's reaction to a video; nil if there is none
+func lockReaction(tx *gorm.DB, videoID uint, userID string) (*models.VideoReaction, error) {
+	var reaction models.VideoReaction
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("video_id = ? AND user_id = ?", videoID, userID).First(&reaction).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock reaction: %w", err)
+	}
+	return &reaction, nil
+}
+
+// adjustReactionCounts applies deltas keyed by reaction value to the video's counters
+func adjustReactionCounts(tx *gorm.DB, videoID uint, deltas map[int]int) error {
+	updates := map[string]interface{}{}
+	for value, delta := range deltas {
+		column := reactionColumn(value)
+		updates[column] = gorm.Expr(column+" + ?", delta)
+	}
+	res := tx.Model(&models.Video{}).Where("id = ?", videoID).UpdateColumns(updates)
+	if res.Error != nil {
+		return fmt.Errorf("update reaction counts: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("video %d: %w", videoID, ErrVideoNotFound)
+	}
+	return nil
+}
+
+func loadReactionCounts(tx *gorm.DB, counts *ReactionCounts) error {
+	var video models.Video
+	if err := tx.Select("id", "like_count", "dislike_count").First(&video, counts.VideoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("video %d: %w", counts.VideoID, ErrVideoNotFound)
+		}
+		return fmt.Errorf("load reaction counts: %w", err)
+	}
+	counts.LikeCount, counts.DislikeCount = video.LikeCount, video.DislikeCount
+	return nil
+}
+
+func reactionColumn(value int) string {
+	if value == models.ReactionLike {
+		return "like_count"
+	}
+	return "dislike_count"
+}
+
+func reactionName(value int) string {
+	if value == models.ReactionLike {
+		return "like"
+	}
+	return "dislike"
+}
@@ -0,0 +1,107 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestReactions(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	reactions := services.NewReactionService(db, nopLogger())
+	video := createVideo(t, db, models.Video{Title: "t"})
+
+	steps := []struct {
+		name            string
+		user            string
+		value           int // 0 clears
+		likes, dislikes int64
+		mine            int
+	}{
+		{"like", "u-1", models.ReactionLike, 1, 0, models.ReactionLike},
+		{"like again", "u-1", models.ReactionLike, 1, 0, models.ReactionLike},
+		{"another user dislikes", "u-2", models.ReactionDislike, 1, 1, models.ReactionDislike},
+		{"switch to dislike", "u-1", models.ReactionDislike, 0, 2, models.ReactionDislike},
+		{"clear", "u-1", 0, 0, 1, 0},
+		{"clear again", "u-1", 0, 0, 1, 0},
+		{"like after clearing", "u-1", models.ReactionLike, 1, 1, models.ReactionLike},
+	}
+	for _, step := range steps {
+		var counts *services.ReactionCounts
+		var err error
+		if step.value == 0 {
+			counts, err = reactions.ClearReaction(ctx, video.ID, step.user)
+		} else {
+			counts, err = reactions.React(ctx, video.ID, step.user, step.value)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if counts.LikeCount != step.likes || counts.DislikeCount != step.dislikes || counts.MyReaction != step.mine {
+			t.Errorf("%s: %+v, want %d likes, %d dislikes, mine %d", step.name, counts, step.likes, step.dislikes, step.mine)
+		}
+		// The denormalized counters always match the reaction rows
+		var likes, dislikes int64
+		db.Model(&models.VideoReaction{}).Where("video_id = ? AND value = ?", video.ID, models.ReactionLike).Count(&likes)
+		db.Model(&models.VideoReaction{}).Where("video_id = ? AND value = ?", video.ID, models.ReactionDislike).Count(&dislikes)
+		if likes != counts.LikeCount || dislikes != counts.DislikeCount {
+			t.Errorf("%s: rows hold %d likes and %d dislikes, counters %d and %d", step.name, likes, dislikes, counts.LikeCount, counts.DislikeCount)
+		}
+	}
+
+	mine, err := reactions.UserReactions(ctx, "u-2", []uint{video.ID, video.ID + 1})
+	if err != nil || len(mine) != 1 || mine[video.ID] != models.ReactionDislike {
+		t.Errorf("UserReactions = %v, %v; want only the dislike", mine, err)
+	}
+}
+
+func TestReactErrors(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	reactions := services.NewReactionService(db, nopLogger())
+	video := createVideo(t, db, models.Video{Title: "t"})
+
+	if _, err := reactions.React(ctx, video.ID, "u-1", 7); err == nil {
+		t.Error("reaction value 7 accepted")
+	}
+	if _, err := reactions.React(ctx, video.ID+1, "u-1", models.ReactionLike); !errors.Is(err, services.ErrVideoNotFound) {
+		t.Errorf("missing video: %v, want ErrVideoNotFound", err)
+	}
+	var rows int64
+	db.Model(&models.VideoReaction{}).Count(&rows)
+	if rows != 0 {
+		t.Errorf("%d reaction rows after failed reactions", rows)
+	}
+}
+
+// TestConcurrentReactions has one user like a video from many requests at once:
+// exactly one like is counted
+func TestConcurrentReactions(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	reactions := services.NewReactionService(db, nopLogger())
+	video := createVideo(t, db, models.Video{Title: "t"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := reactions.React(ctx, video.ID, "u-1", models.ReactionLike); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	var row models.Video
+	db.First(&row, video.ID)
+	if row.LikeCount != 1 {
+		t.Errorf("like_count %d after concurrent likes by one user, want 1", row.LikeCount)
+	}
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// videoSaveOmit is left out of full-row video saves: the counters (see
+// counterColumns) and associations such as renditions, which are written
+// explicitly so a preloaded copy is never upserted back
+var videoSaveOmit = append(append([]string{}, counterColumns...), clause.Associations)
+
+// replaceRenditions swaps the stored renditions of video for video.Renditions
+func replaceRenditions(tx *gorm.DB, video *models.Video) error {
+	if err := tx.Where("video_id = ?", video.ID).Delete(&models.VideoRendition{}).Error; err != nil {
+		return fmt.Errorf("delete renditions: %w", err)
+	}
+	if len(video.Renditions) == 0 {
+		return nil
+	}
+	for i := range video.Renditions {
+		video.Renditions[i].ID = 0
+		video.Renditions[i].VideoID = video.ID
+	}
+	if err := tx.Create(&video.Renditions).Error; err != nil {
+		return fmt.Errorf("create renditions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func renditionEvent(uploadID string, names ...string) *models.TranscodedEvent {
+	event := transcodedEvent(uploadID)
+	for i, name := range names {
+		event.HLS.Renditions = append(event.HLS.Renditions, models.RenditionInfo{
+			Name: name, Bandwidth: int64(1000 * (i + 1)), PlaylistURL: "https://cdn.example/" + uploadID + "/" + name + ".m3u8",
+		})
+	}
+	return event
+}
+
+// renditionNames lists the names of a video's renditions as GetVideo returns them
+func renditionNames(t *testing.T, videos *services.VideoService, id uint) string {
+	t.Helper()
+	video, err := videos.GetVideo(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range video.Renditions {
+		names = append(names, r.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestTranscodedEventRenditions(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{UploadID: "up-1", Title: "t", Status: models.StatusProcessing, Visibility: models.VisibilityPublic})
+
+	if err := videos.HandleTranscodedEvent(ctx, renditionEvent("up-1", "480p", "720p", "1080p")); err != nil {
+		t.Fatal(err)
+	}
+	// Highest bandwidth first
+	if got := renditionNames(t, videos, video.ID); got != "1080p,720p,480p" {
+		t.Errorf("renditions = %s", got)
+	}
+
+	// A redelivery without renditions keeps them; one with renditions replaces them
+	if err := videos.HandleTranscodedEvent(ctx, renditionEvent("up-1")); err != nil {
+		t.Fatal(err)
+	}
+	if got := renditionNames(t, videos, video.ID); got != "1080p,720p,480p" {
+		t.Errorf("renditions after an event without any = %s", got)
+	}
+	if err := videos.HandleTranscodedEvent(ctx, renditionEvent("up-1", "360p", "720p")); err != nil {
+		t.Fatal(err)
+	}
+	if got := renditionNames(t, videos, video.ID); got != "720p,360p" {
+		t.Errorf("renditions after a new set = %s", got)
+	}
+	var rows int64
+	db.Model(&models.VideoRendition{}).Count(&rows)
+	if rows != 2 {
+		t.Errorf("%d rendition rows, want the old set replaced", rows)
+	}
+
+	// Saving a loaded video doesn't write its renditions back
+	title := "renamed"
+	if _, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Title: &title}); err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&models.VideoRendition{}).Count(&rows)
+	if rows != 2 {
+		t.Errorf("%d rendition rows after an update, want 2", rows)
+	}
+
+	page, err := videos.ListVideos(ctx, "", 1, 20, false, services.VideoSort{}, services.VideoFilters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Videos) != 1 || page.Videos[0].Renditions != nil {
+		t.Errorf("listing = %+v, want the video without renditions", page.Videos)
+	}
+}
+
+func TestRenditionsDeletedWithVideo(t *testing.T) {
+	db := dbtest.Open(t)
+	// SQLite only enforces foreign keys when asked, per connection
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
+		t.Fatal(err)
+	}
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{UploadID: "up-1", Title: "t", Status: models.StatusProcessing})
+	other := createVideo(t, db, models.Video{UploadID: "up-2", Title: "t", Status: models.StatusProcessing})
+	for _, uploadID := range []string{"up-1", "up-2"} {
+		if err := videos.HandleTranscodedEvent(ctx, renditionEvent(uploadID, "480p", "720p")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deletes := services.NewVideoDeleteService(db, nopLogger(), newFakeStorage())
+	if _, err := deletes.DeleteVideoCompletely(ctx, video.ID, 0, "owner"); err != nil {
+		t.Fatal(err)
+	}
+	var left []models.VideoRendition
+	db.Find(&left)
+	if len(left) != 2 || left[0].VideoID != other.ID || left[1].VideoID != other.ID {
+		t.Errorf("renditions left = %+v, want only the other video's", left)
+	}
+}
@@ -0,0 +1,41 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// TestEventLogsCarryCorrelationID checks a consumed event's service log lines are
+// tagged with the message's correlation ID
+func TestEventLogsCarryCorrelationID(t *testing.T) {
+	db := dbtest.Open(t)
+	core, logs := observer.New(zap.InfoLevel)
+	videos := services.NewVideoService(db, nil, videoSettings, zap.New(core).Sugar())
+
+	ctx := events.WithMetadata(context.Background(), events.Metadata{RoutingKey: "video.uploaded", CorrelationID: "corr-7", MessageID: "msg-7"})
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-1", UserID: "alice", Title: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	seeded := logs.FilterMessage("Catalog seeded from upload event").All()
+	if len(seeded) != 1 || seeded[0].ContextMap()["requestID"] != "corr-7" {
+		t.Errorf("seeded line %v, want it tagged with the correlation ID", seeded)
+	}
+
+	// Lines for work outside any request or message carry no ID
+	if err := videos.HandleUploadedEvent(context.Background(), &models.UploadedEvent{UploadID: "up-2", UserID: "alice", Title: "u"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range logs.FilterMessage("Catalog seeded from upload event").All()[1:] {
+		if _, ok := entry.ContextMap()["requestID"]; ok {
+			t.Errorf("untagged event logged with requestID: %v", entry.ContextMap())
+		}
+	}
+}
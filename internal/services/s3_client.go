@@ -0,0 +1,225 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+)
+
+// S3ClientAdapter is the StorageClient for S3 and S3-compatible stores such as MinIO
+type S3ClientAdapter struct {
+	service *s3.Client
+	bucket  string
+	guard   *storageGuard
+	// region, endpoint and pathStyle build object URLs
+	region    string
+	endpoint  string
+	pathStyle bool
+}
+
+// NewS3ClientAdapter creates an S3 client for cfg's bucket, calling through the
+// breaker and retry policy breaker describes. cfg.Endpoint points it at an
+// S3-compatible store instead of AWS.
+func NewS3ClientAdapter(cfg config.S3, breaker config.Breaker) (*S3ClientAdapter, error) {
+	bucket := cfg.Bucket
+	if bucket == "" {
+		return nil, fmt.Errorf("missing S3_BUCKET")
+	}
+	accessKey := cfg.AccessKeyID
+	secretKey := cfg.SecretAccessKey
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("missing S3 credentials - need S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY")
+	}
+	region := cfg.Region
+	endpoint := cfg.Endpoint
+	pathStyle := cfg.ForcePathStyle
+
+	creds := aws.Credentials{AccessKeyID: accessKey, SecretAccessKey: secretKey, Source: "environment"}
+	svc := s3.New(s3.Options{
+		Region: region,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return creds, nil
+		}),
+		UsePathStyle: pathStyle,
+		// S3-compatible stores don't all accept the newer default checksums
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+	}, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+
+	return &S3ClientAdapter{
+		service:   svc,
+		bucket:    bucket,
+		guard:     newStorageGuard("s3-client", breaker),
+		region:    region,
+		endpoint:  endpoint,
+		pathStyle: pathStyle,
+	}, nil
+}
+
+// DeleteBlob deletes a single object. S3 reports success for a missing key.
+func (a *S3ClientAdapter) DeleteBlob(ctx context.Context, blobPath string) error {
+	return a.guard.retry(ctx, "DeleteObject", blobPath, func(c context.Context) error {
+		_, err := a.service.DeleteObject(c, &s3.DeleteObjectInput{Bucket: &a.bucket, Key: &blobPath})
+		return err
+	})
+}
+
+// DeleteBlobsWithPrefix deletes all objects with the given prefix and returns how
+// many it deleted, including those deleted before a failure
+func (a *S3ClientAdapter) DeleteBlobsWithPrefix(ctx context.Context, prefix string) (int, error) {
+	return deleteAllPages(ctx, prefix, a.DeleteBlobPage)
+}
+
+// DeleteBlobPage lists one page of objects under prefix with ListObjectsV2, starting
+// at marker (a continuation token, "" for the first page), and deletes them with one
+// DeleteObjects call. It returns the next page's token, "" once the listing is
+// exhausted, and how many objects it deleted. A token the store no longer accepts
+// fails with ErrStaleMarker.
+func (a *S3ClientAdapter) DeleteBlobPage(ctx context.Context, prefix, marker string) (string, int, error) {
+	input := &s3.ListObjectsV2Input{Bucket: &a.bucket, Prefix: &prefix, MaxKeys: aws.Int32(prefixDeletePageSize)}
+	if marker != "" {
+		input.ContinuationToken = &marker
+	}
+	pageAny, err := a.guard.execute(ctx, "ListObjectsV2", prefix, func() (interface{}, error) { return a.service.ListObjectsV2(ctx, input) })
+	if err != nil {
+		var apiErr smithy.APIError
+		if marker != "" && errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidArgument" {
+			return "", 0, fmt.Errorf("list objects with prefix %s: %w", prefix, ErrStaleMarker)
+		}
+		return "", 0, fmt.Errorf("failed to list objects with prefix %s: %w", prefix, err)
+	}
+	page := pageAny.(*s3.ListObjectsV2Output)
+
+	objects := make([]types.ObjectIdentifier, 0, len(page.Contents))
+	for _, obj := range page.Contents {
+		if obj.Key != nil {
+			objects = append(objects, types.ObjectIdentifier{Key: obj.Key})
+		}
+	}
+	deleted := 0
+	if len(objects) > 0 {
+		var failed []types.Error
+		err := a.guard.retry(ctx, "DeleteObjects", prefix, func(c context.Context) error {
+			out, err := a.service.DeleteObjects(c, &s3.DeleteObjectsInput{
+				Bucket: &a.bucket,
+				Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+			})
+			if err != nil {
+				return err
+			}
+			failed = out.Errors
+			return nil
+		})
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to delete objects with prefix %s: %w", prefix, err)
+		}
+		deleted = len(objects) - len(failed)
+		if len(failed) > 0 {
+			return "", deleted, fmt.Errorf("failed to delete object %s: %s", aws.ToString(failed[0].Key), aws.ToString(failed[0].Message))
+		}
+	}
+	if !aws.ToBool(page.IsTruncated) || page.NextContinuationToken == nil {
+		return "", deleted, nil
+	}
+	return *page.NextContinuationToken, deleted, nil
+}
+
+// BlobExists checks if an object exists with HeadObject
+func (a *S3ClientAdapter) BlobExists(ctx context.Context, blobPath string) (bool, error) {
+	exists, err := a.guard.execute(ctx, "HeadObject", blobPath, func() (interface{}, error) {
+		_, err := a.service.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &a.bucket, Key: &blobPath})
+		// A missing object is an answer, not a failure of the store
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check object existence: %w", err)
+	}
+	return exists.(bool), nil
+}
+
+// GenerateSASURL returns a presigned GET URL for blobPath that expires after ttl;
+// S3 caps it at seven days
+func (a *S3ClientAdapter) GenerateSASURL(ctx context.Context, blobPath string, ttl time.Duration) (string, error) {
+	presigner := s3.NewPresignClient(a.service)
+	req, err := a.guard.execute(ctx, "PresignGetObject", blobPath, func() (interface{}, error) {
+		return presigner.PresignGetObject(ctx, &s3.GetObjectInput{Bucket: &a.bucket, Key: &blobPath}, s3.WithPresignExpires(ttl))
+	})
+	if err != nil {
+		return "", fmt.Errorf("sign object %s: %w", blobPath, err)
+	}
+	return req.(*v4.PresignedHTTPRequest).URL, nil
+}
+
+// DownloadBlob reads an object of at most maxBytes into memory
+func (a *S3ClientAdapter) DownloadBlob(ctx context.Context, blobPath string, maxBytes int64) ([]byte, error) {
+	c, cancel := context.WithTimeout(ctx, a.guard.attemptTimeout)
+	defer cancel()
+	data, err := a.guard.execute(ctx, "GetObject", blobPath, func() (interface{}, error) {
+		resp, err := a.service.GetObject(c, &s3.GetObjectInput{Bucket: &a.bucket, Key: &blobPath})
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("download blob %s: %w", blobPath, err)
+	}
+	body := data.([]byte)
+	if int64(len(body)) > maxBytes {
+		return nil, fmt.Errorf("blob %s is larger than %d bytes", blobPath, maxBytes)
+	}
+	return body, nil
+}
+
+// UploadBlob puts an object with the given content type and returns its URL
+func (a *S3ClientAdapter) UploadBlob(ctx context.Context, blobPath string, data []byte, contentType string) (string, error) {
+	err := a.guard.retry(ctx, "PutObject", blobPath, func(c context.Context) error {
+		_, err := a.service.PutObject(c, &s3.PutObjectInput{
+			Bucket:      &a.bucket,
+			Key:         &blobPath,
+			Body:        bytes.NewReader(data),
+			ContentType: &contentType,
+		})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("upload object %s: %w", blobPath, err)
+	}
+	return a.objectURL(blobPath), nil
+}
+
+// objectURL is the URL of key: virtual-hosted style on AWS, and on custom endpoints
+// unless path-style addressing is forced
+func (a *S3ClientAdapter) objectURL(key string) string {
+	path := (&url.URL{Path: "/" + key}).EscapedPath()
+	if a.endpoint == "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", a.bucket, a.region, path)
+	}
+	base, err := url.Parse(strings.TrimRight(a.endpoint, "/"))
+	if err != nil || a.pathStyle {
+		return strings.TrimRight(a.endpoint, "/") + "/" + a.bucket + path
+	}
+	base.Host = a.bucket + "." + base.Host
+	return base.String() + path
+}
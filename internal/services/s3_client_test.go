@@ -0,0 +1,321 @@
+package services_test
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// objectServer answers the S3 calls the adapter makes for the videos bucket,
+// path-style: ListObjectsV2 in pages of pageSize keys, with the last key listed as
+// the continuation token, batch and single deletes, HEAD, GET and PUT. Keys in
+// locked fail a batch delete with AccessDenied, and the token "expired" is
+// rejected. While denied is set every call fails with 403, which the SDK doesn't
+// retry.
+type objectServer struct {
+	mu           sync.Mutex
+	objects      map[string][]byte
+	types        map[string]string
+	locked       map[string]bool
+	pageSize     int
+	tokens       []string
+	denied       bool
+	calls        int
+	batchDeletes int
+}
+
+func (s *objectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	const base = "/videos"
+	if s.denied {
+		s3Error(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+	q := r.URL.Query()
+	key := strings.TrimPrefix(r.URL.Path, base+"/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == base && q.Get("list-type") == "2":
+		token := q.Get("continuation-token")
+		s.tokens = append(s.tokens, token)
+		if token == "expired" {
+			s3Error(w, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+		var keys []string
+		for k := range s.objects {
+			if strings.HasPrefix(k, q.Get("prefix")) && k > token {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		truncated := len(keys) > s.pageSize
+		if truncated {
+			keys = keys[:s.pageSize]
+		}
+		var body strings.Builder
+		body.WriteString(`<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>videos</Name>`)
+		for _, k := range keys {
+			fmt.Fprintf(&body, "<Contents><Key>%s</Key><Size>0</Size></Contents>", k)
+		}
+		fmt.Fprintf(&body, "<KeyCount>%d</KeyCount><IsTruncated>%t</IsTruncated>", len(keys), truncated)
+		if truncated {
+			fmt.Fprintf(&body, "<NextContinuationToken>%s</NextContinuationToken>", keys[len(keys)-1])
+		}
+		body.WriteString("</ListBucketResult>")
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(body.String()))
+	case r.Method == http.MethodPost && r.URL.Path == base && q.Has("delete"):
+		s.batchDeletes++
+		var req struct {
+			Objects []struct {
+				Key string `xml:"Key"`
+			} `xml:"Object"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+			s3Error(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		var body strings.Builder
+		body.WriteString(`<?xml version="1.0" encoding="UTF-8"?><DeleteResult>`)
+		for _, obj := range req.Objects {
+			if s.locked[obj.Key] {
+				fmt.Fprintf(&body, "<Error><Key>%s</Key><Code>AccessDenied</Code><Message>object is locked</Message></Error>", obj.Key)
+				continue
+			}
+			delete(s.objects, obj.Key)
+		}
+		body.WriteString("</DeleteResult>")
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(body.String()))
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, base+"/"):
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, base+"/"):
+		if _, ok := s.objects[key]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, base+"/"):
+		data, ok := s.objects[key]
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Type", s.types[key])
+		w.Write(data)
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, base+"/"):
+		data, _ := io.ReadAll(r.Body)
+		s.objects[key] = data
+		s.types[key] = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+func s3Adapter(t *testing.T, keys ...string) (*services.S3ClientAdapter, *objectServer) {
+	t.Helper()
+	backend := &objectServer{objects: map[string][]byte{}, types: map[string]string{}, locked: map[string]bool{}, pageSize: 2}
+	for _, k := range keys {
+		backend.objects[k] = nil
+	}
+	srv := httptest.NewServer(backend)
+	t.Cleanup(srv.Close)
+	adapter, err := services.NewS3ClientAdapter(
+		config.S3{Bucket: "videos", AccessKeyID: "minio", SecretAccessKey: "minio123", Region: "us-east-1", Endpoint: srv.URL, ForcePathStyle: true},
+		config.Breaker{Reset: time.Minute, ConsecutiveFailures: 2, AttemptTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return adapter, backend
+}
+
+func TestS3DeleteBlobPage(t *testing.T) {
+	adapter, backend := s3Adapter(t, "hls/a/1.ts", "hls/a/2.ts", "hls/a/3.ts", "hls/b/1.ts")
+	ctx := context.Background()
+
+	next, deleted, err := adapter.DeleteBlobPage(ctx, "hls/a/", "")
+	if err != nil || next != "hls/a/2.ts" || deleted != 2 {
+		t.Fatalf("first page = %q, %d, %v; want token hls/a/2.ts and 2 deleted", next, deleted, err)
+	}
+	next, deleted, err = adapter.DeleteBlobPage(ctx, "hls/a/", next)
+	if err != nil || next != "" || deleted != 1 {
+		t.Fatalf("last page = %q, %d, %v; want no token and 1 deleted", next, deleted, err)
+	}
+	if got := backend.tokens; len(got) != 2 || got[1] != "hls/a/2.ts" {
+		t.Errorf("listed with tokens %q, want the second page from the first's token", got)
+	}
+	if _, ok := backend.objects["hls/b/1.ts"]; !ok || len(backend.objects) != 1 {
+		t.Errorf("objects left = %v, want only hls/b/1.ts", backend.objects)
+	}
+	// One DeleteObjects call a page
+	if backend.batchDeletes != 2 {
+		t.Errorf("%d DeleteObjects calls, want 2", backend.batchDeletes)
+	}
+	// An empty prefix lists once and deletes nothing
+	if next, deleted, err := adapter.DeleteBlobPage(ctx, "hls/none/", ""); err != nil || next != "" || deleted != 0 || backend.batchDeletes != 2 {
+		t.Errorf("empty prefix = %q, %d, %v after %d DeleteObjects calls", next, deleted, err, backend.batchDeletes)
+	}
+}
+
+func TestS3DeleteBlobPagePartialFailure(t *testing.T) {
+	adapter, backend := s3Adapter(t, "hls/a/1.ts", "hls/a/2.ts")
+	backend.locked["hls/a/2.ts"] = true
+	_, deleted, err := adapter.DeleteBlobPage(context.Background(), "hls/a/", "")
+	if err == nil || !strings.Contains(err.Error(), "hls/a/2.ts") || deleted != 1 {
+		t.Errorf("page with a locked object = %d, %v; want 1 deleted and an error naming it", deleted, err)
+	}
+}
+
+func TestS3DeleteBlobPageStaleMarker(t *testing.T) {
+	adapter, _ := s3Adapter(t, "hls/a/1.ts")
+	if _, _, err := adapter.DeleteBlobPage(context.Background(), "hls/a/", "expired"); !errors.Is(err, services.ErrStaleMarker) {
+		t.Errorf("error = %v, want ErrStaleMarker", err)
+	}
+}
+
+func TestS3DeleteBlobsWithPrefix(t *testing.T) {
+	var keys []string
+	for i := 0; i < 7; i++ {
+		keys = append(keys, fmt.Sprintf("hls/a/%d.ts", i))
+	}
+	adapter, backend := s3Adapter(t, keys...)
+	deleted, err := adapter.DeleteBlobsWithPrefix(context.Background(), "hls/a/")
+	if err != nil || deleted != 7 || len(backend.objects) != 0 {
+		t.Errorf("DeleteBlobsWithPrefix = %d, %v with %d left; want 7 and none", deleted, err, len(backend.objects))
+	}
+	if len(backend.tokens) != 4 {
+		t.Errorf("listed %d pages, want 4", len(backend.tokens))
+	}
+}
+
+func TestS3Objects(t *testing.T) {
+	adapter, backend := s3Adapter(t, "raw/owner/up-1.mp4")
+	ctx := context.Background()
+
+	for key, want := range map[string]bool{"raw/owner/up-1.mp4": true, "raw/owner/up-2.mp4": false} {
+		if exists, err := adapter.BlobExists(ctx, key); err != nil || exists != want {
+			t.Errorf("BlobExists(%s) = %v, %v; want %v", key, exists, err, want)
+		}
+	}
+	if err := adapter.DeleteBlob(ctx, "raw/owner/up-1.mp4"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := backend.objects["raw/owner/up-1.mp4"]; ok {
+		t.Error("object survived DeleteBlob")
+	}
+	// Deleting a missing object succeeds, as on S3
+	if err := adapter.DeleteBlob(ctx, "raw/owner/up-1.mp4"); err != nil {
+		t.Errorf("delete of a missing object: %v", err)
+	}
+
+	location, err := adapter.UploadBlob(ctx, "thumbnails/owner/up 1.jpg", []byte("jpeg"), "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(location, "/videos/thumbnails/owner/up%201.jpg") {
+		t.Errorf("uploaded to %s, want a path-style object URL", location)
+	}
+	if backend.types["thumbnails/owner/up 1.jpg"] != "image/jpeg" {
+		t.Errorf("content type %q, want image/jpeg", backend.types["thumbnails/owner/up 1.jpg"])
+	}
+	data, err := adapter.DownloadBlob(ctx, "thumbnails/owner/up 1.jpg", 4)
+	if err != nil || string(data) != "jpeg" {
+		t.Errorf("DownloadBlob = %q, %v", data, err)
+	}
+	if _, err := adapter.DownloadBlob(ctx, "thumbnails/owner/up 1.jpg", 3); err == nil {
+		t.Error("downloaded an object over the size limit")
+	}
+}
+
+// TestS3BreakerOpens stops calling the store once it has failed the configured
+// number of times in a row
+func TestS3BreakerOpens(t *testing.T) {
+	adapter, backend := s3Adapter(t, "raw/owner/up-1.mp4")
+	backend.denied = true
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := adapter.BlobExists(ctx, "raw/owner/up-1.mp4"); err == nil {
+			t.Fatal("BlobExists succeeded against a failing store")
+		}
+	}
+	calls := backend.calls
+	if _, err := adapter.BlobExists(ctx, "raw/owner/up-1.mp4"); !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Errorf("third call: %v, want the breaker open", err)
+	}
+	if backend.calls != calls {
+		t.Error("the store was called with the breaker open")
+	}
+}
+
+func TestS3SignedURL(t *testing.T) {
+	adapter, err := services.NewS3ClientAdapter(
+		config.S3{Bucket: "videos", AccessKeyID: "key", SecretAccessKey: "secret", Region: "eu-west-1"},
+		config.Breaker{Reset: time.Minute, ConsecutiveFailures: 2, AttemptTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := adapter.GenerateSASURL(context.Background(), "hls/owner/up-1/master.m3u8", 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "videos.s3.eu-west-1.amazonaws.com" || u.Path != "/hls/owner/up-1/master.m3u8" ||
+		u.Query().Get("X-Amz-Expires") != "900" || u.Query().Get("X-Amz-Signature") == "" {
+		t.Errorf("signed URL %s, want a virtual-hosted URL valid for 900s", signed)
+	}
+}
+
+func TestNewStorageClient(t *testing.T) {
+	breaker := config.Breaker{Reset: time.Minute, ConsecutiveFailures: 2, AttemptTimeout: time.Second}
+	tests := []struct {
+		name    string
+		cfg     config.Storage
+		wantErr string
+	}{
+		{"unknown backend", config.Storage{Backend: "gcs"}, "unknown storage backend"},
+		{"s3 without a bucket", config.Storage{Backend: config.StorageBackendS3, S3: config.S3{AccessKeyID: "k", SecretAccessKey: "s"}}, "S3_BUCKET"},
+		{"s3 without credentials", config.Storage{Backend: config.StorageBackendS3, S3: config.S3{Bucket: "videos"}}, "S3_ACCESS_KEY_ID"},
+		{"s3", config.Storage{Backend: config.StorageBackendS3, S3: config.S3{Bucket: "videos", AccessKeyID: "k", SecretAccessKey: "s", Region: "us-east-1"}}, ""},
+	}
+	for _, tt := range tests {
+		tt.cfg.Breaker = breaker
+		client, err := services.NewStorageClient(tt.cfg)
+		if tt.wantErr == "" {
+			if _, ok := client.(*services.S3ClientAdapter); err != nil || !ok {
+				t.Errorf("%s: %T, %v; want an S3 client", tt.name, client, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: %v, want an error mentioning %s", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/search"
+)
+
+// searchSortFields maps list sort columns to the index fields they sort on
+var searchSortFields = map[string]string{
+	"created_at": search.SortCreatedAt,
+	"title":      search.SortTitle,
+	"duration":   search.SortDuration,
+	"file_size":  search.SortFileSize,
+	"view_count": search.SortViewCount,
+}
+
+// SearchIndex keeps an OpenSearch index of public videos in step with the catalog
+// and answers searches from it. Changes are queued and written in bulk batches by
+// Run, so requests never wait on the cluster; a change that can't be queued or
+// written is only logged and counted, and the next reindex repairs it. Counters
+// change without a video change, so the indexed view and like counts are as of
+// the video's last edit or the last reindex.
+type SearchIndex struct {
+	db            *gorm.DB
+	client        *search.Client
+	logger        *zap.SugaredLogger
+	ops           chan search.Op
+	batchSize     int
+	flushInterval time.Duration
+	done          chan struct{}
+
+	mu         sync.Mutex
+	reindexing bool
+}
+
+// NewSearchIndex creates an index writer queueing up to bufferSize changes
+func NewSearchIndex(db *gorm.DB, client *search.Client, logger *zap.SugaredLogger, bufferSize, batchSize int, flushInterval time.Duration) *SearchIndex {
+	return &SearchIndex{
+		db:            db,
+		client:        client,
+		logger:        logger,
+		ops:           make(chan search.Op, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+	}
+}
+
+// SetSearchIndex answers SearchVideos from idx and keeps it up to date with every
+// reported video change. Without one, search runs in the database.
+func (s *VideoService) SetSearchIndex(idx *SearchIndex) {
+	s.search = idx
+	if idx != nil {
+		s.changes.Subscribe("search_index", idx.Observe)
+	}
+}
+
+// Observe queues the index write for a change: listed videos are indexed, and
+// anything else, including taken-down videos, is removed from the index. It
+// never blocks; when the queue is full the change is dropped.
+func (s *SearchIndex) Observe(_ context.Context, change VideoChange) {
+	op := search.Op{ID: change.VideoID, Delete: true}
+	if change.Kind != VideoDeleted && change.Video != nil && change.Video.IsListed() {
+		doc := search.DocumentFor(change.Video)
+		op = search.Op{ID: change.VideoID, Doc: &doc}
+	}
+	select {
+	case s.ops <- op:
+	default:
+		metrics.SearchIndexOpsTotal.WithLabelValues("dropped").Inc()
+		s.logger.Warnw("Search index queue full; dropped change", "videoID", change.VideoID, "kind", change.Kind)
+	}
+}
+
+// Run writes queued changes until ctx is cancelled, then drains what's left
+func (s *SearchIndex) Run(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]search.Op, 0, s.batchSize)
+	for {
+		select {
+		case op := <-s.ops:
+			batch = append(batch, op)
+			if len(batch) >= s.batchSize {
+				batch = s.flush(batch)
+			}
+		case <-ticker.C:
+			batch = s.flush(batch)
+		case <-ctx.Done():
+			for {
+				select {
+				case op := <-s.ops:
+					batch = append(batch, op)
+				default:
+					s.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// Wait blocks until Run has drained the queue after cancellation
+func (s *SearchIndex) Wait() { <-s.done }
+
+func (s *SearchIndex) flush(batch []search.Op) []search.Op {
+	if len(batch) == 0 {
+		return batch
+	}
+	// Detached so the final drain still gets written during shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.client.Bulk(ctx, batch); err != nil {
+		metrics.SearchIndexOpsTotal.WithLabelValues("failed").Add(float64(len(batch)))
+		s.logger.Errorw("Failed to write search index batch", "error", err, "ops", len(batch))
+	} else {
+		metrics.SearchIndexOpsTotal.WithLabelValues("written").Add(float64(len(batch)))
+	}
+	return batch[:0]
+}
+
+// StartReindex runs Reindex in the background; it returns false if one is already running
+func (s *SearchIndex) StartReindex(ctx context.Context, batchSize int) bool {
+	s.mu.Lock()
+	if s.reindexing {
+		s.mu.Unlock()
+		return false
+	}
+	s.reindexing = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			s.reindexing = false
+			s.mu.Unlock()
+		}()
+		if _, err := s.Reindex(ctx, batchSize); err != nil {
+			s.logger.Errorw("Search reindex failed", "error", err)
+		}
+	}()
+	return true
+}
+
+// Reindex writes every listed video into the index, batchSize at a time in id
+// order, and returns how many were written. It creates the index if needed.
+// Videos unlisted since they were indexed are removed by their own change, not by
+// a reindex.
+func (s *SearchIndex) Reindex(ctx context.Context, batchSize int) (int64, error) {
+	if err := s.client.EnsureIndex(ctx); err != nil {
+		return 0, fmt.Errorf("ensure search index: %w", err)
+	}
+	var written int64
+	var batchErr error
+	var videos []models.Video
+	res := listed(s.db.WithContext(ctx)).
+		FindInBatches(&videos, batchSize, func(tx *gorm.DB, batch int) error {
+			ops := make([]search.Op, len(videos))
+			for i := range videos {
+				doc := search.DocumentFor(&videos[i])
+				ops[i] = search.Op{ID: videos[i].ID, Doc: &doc}
+			}
+			if err := s.client.Bulk(ctx, ops); err != nil {
+				batchErr = err
+				return err
+			}
+			written += int64(len(ops))
+			metrics.SearchIndexOpsTotal.WithLabelValues("reindexed").Add(float64(len(ops)))
+			s.logger.Infow("Search reindex progress", "batch", batch, "written", written)
+			return nil
+		})
+	if batchErr != nil {
+		return written, fmt.Errorf("reindex after %d videos: %w", written, batchErr)
+	}
+	if res.Error != nil {
+		return written, fmt.Errorf("read videos for reindex: %w", res.Error)
+	}
+	s.logger.Infow("Search reindex finished", "written", written)
+	return written, nil
+}
+
+// Search finds one page of public videos for a SearchVideos request
+func (s *SearchIndex) Search(ctx context.Context, text string, page, perPage int, sort VideoSort, filters VideoFilters) (*search.Result, error) {
+	q := search.Query{
+		Text:           text,
+		Category:       filters.Category,
+		Status:         string(filters.Status),
+		Tags:           filters.Tags,
+		MinDuration:    filters.MinDuration,
+		MaxDuration:    filters.MaxDuration,
+		UploadedAfter:  filters.UploadedAfter,
+		UploadedBefore: filters.UploadedBefore,
+		From:           (page - 1) * perPage,
+		Size:           perPage,
+	}
+	// An explicit sort wins; otherwise matches are ranked by relevance
+	if sort.Key != "" || sort.Order != "" {
+		q.SortField = searchSortFields[videoSortColumns[sort.Key]]
+		if sort.Key == "" {
+			q.SortField = search.SortCreatedAt
+		}
+		q.SortAsc = sort.Order == "asc"
+	}
+	return s.client.Search(ctx, q)
+}
+
+// searchIndexed finds a search's page of video IDs in the index, reporting ok
+// false when there is no index or it failed, so the caller searches the database
+func (s *VideoService) searchIndexed(ctx context.Context, query string, page, perPage int, sort VideoSort, filters VideoFilters) (*search.Result, bool) {
+	if s.search == nil {
+		return nil, false
+	}
+	result, err := s.search.Search(ctx, query, page, perPage, sort, filters)
+	if err != nil {
+		reason := "error"
+		if errors.Is(err, search.ErrUnavailable) {
+			reason = "unavailable"
+		}
+		metrics.SearchFallbacksTotal.WithLabelValues(reason).Inc()
+		s.log(ctx).Warnw("Search index failed; searching the database", "error", err)
+		return nil, false
+	}
+	return result, true
+}
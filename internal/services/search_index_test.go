@@ -0,0 +1,345 @@
+package services_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/search"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// fakeCluster is an in-memory OpenSearch behind an http.RoundTripper: bulk writes
+// land in docs, and searches answer hits, or every document by id when hits is nil
+type fakeCluster struct {
+	mu       sync.Mutex
+	docs     map[string]search.Document
+	bulks    int
+	searches []string
+	hits     []uint
+	total    int64
+	// down fails every request as an unreachable cluster would
+	down bool
+	// status, when set, answers searches with it
+	status int
+	// hold, when set, blocks bulk writes until it is closed
+	hold chan struct{}
+}
+
+func newFakeCluster() *fakeCluster { return &fakeCluster{docs: map[string]search.Document{}} }
+
+func (f *fakeCluster) client() *search.Client {
+	return search.NewClient(search.Config{URL: "http://search:9200", Index: "videos", Timeout: time.Second}, f)
+}
+
+func (f *fakeCluster) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	down, hold := f.down, f.hold
+	f.mu.Unlock()
+	if down {
+		return nil, errors.New("dial tcp: connection refused")
+	}
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	switch {
+	case req.Method == http.MethodHead:
+		return answer(http.StatusOK, "")
+	case req.URL.Path == "/_bulk":
+		if hold != nil {
+			<-hold
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.bulks++
+		scanner := bufio.NewScanner(strings.NewReader(string(body)))
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			var action map[string]struct {
+				ID string `json:"_id"`
+			}
+			json.Unmarshal(scanner.Bytes(), &action)
+			if a, ok := action["delete"]; ok {
+				delete(f.docs, a.ID)
+				continue
+			}
+			scanner.Scan()
+			var doc search.Document
+			json.Unmarshal(scanner.Bytes(), &doc)
+			f.docs[action["index"].ID] = doc
+		}
+		return answer(http.StatusOK, `{"errors":false,"items":[]}`)
+	case strings.HasSuffix(req.URL.Path, "/_search"):
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.searches = append(f.searches, string(body))
+		if f.status != 0 {
+			return answer(f.status, `{"error":{"type":"search_phase_execution_exception"}}`)
+		}
+		ids, total := f.hits, f.total
+		if ids == nil {
+			ids, total = f.indexed(), int64(len(f.docs))
+		}
+		hits := make([]string, len(ids))
+		for i, id := range ids {
+			hits[i] = fmt.Sprintf(`{"_id":"%d"}`, id)
+		}
+		return answer(http.StatusOK, fmt.Sprintf(`{"hits":{"total":{"value":%d},"hits":[%s]}}`, total, strings.Join(hits, ",")))
+	}
+	return answer(http.StatusNotFound, "")
+}
+
+// indexed lists the indexed video IDs in order; f.mu must be held
+func (f *fakeCluster) indexed() []uint {
+	ids := make([]uint, 0, len(f.docs))
+	for id := range f.docs {
+		n, _ := strconv.ParseUint(id, 10, 32)
+		ids = append(ids, uint(n))
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (f *fakeCluster) indexedIDs() []uint {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.indexed()
+}
+
+func (f *fakeCluster) set(change func(f *fakeCluster)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change(f)
+}
+
+func answer(status int, body string) (*http.Response, error) {
+	return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+// indexedVideos is a VideoService searching through cluster, with the index
+// writer running until the test ends
+func indexedVideos(t *testing.T, cluster *fakeCluster) (*gorm.DB, *services.VideoService) {
+	t.Helper()
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	idx := services.NewSearchIndex(db, cluster.client(), nopLogger(), 100, 50, 5*time.Millisecond)
+	videos.SetSearchIndex(idx)
+	ctx, cancel := context.WithCancel(context.Background())
+	go idx.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		idx.Wait()
+	})
+	return db, videos
+}
+
+func sameIDs(a, b []uint) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func TestSearchIndexFollowsChanges(t *testing.T) {
+	cluster := newFakeCluster()
+	_, videos := indexedVideos(t, cluster)
+	ctx := context.Background()
+
+	public, err := videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-1", Title: "holiday", Tags: []string{"beach"}, Visibility: models.VisibilityPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-2", Title: "diary", Visibility: models.VisibilityPrivate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the public video to be indexed", func() bool { return sameIDs(cluster.indexedIDs(), []uint{public.ID}) })
+
+	title := "summer holiday"
+	if _, err := videos.UpdateVideo(ctx, public.ID, &models.VideoUpdateRequest{Title: &title}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the edit to be indexed", func() bool {
+		cluster.mu.Lock()
+		defer cluster.mu.Unlock()
+		doc := cluster.docs[strconv.FormatUint(uint64(public.ID), 10)]
+		return doc.Title == title && len(doc.Tags) == 1 && doc.Tags[0] == "beach"
+	})
+
+	// Going public adds a video; going private or being deleted removes it
+	visible := models.VisibilityPublic
+	if _, err := videos.UpdateVideo(ctx, private.ID, &models.VideoUpdateRequest{Visibility: &visible}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the newly public video to be indexed", func() bool { return sameIDs(cluster.indexedIDs(), []uint{public.ID, private.ID}) })
+	hidden := models.VisibilityUnlisted
+	if _, err := videos.UpdateVideo(ctx, private.ID, &models.VideoUpdateRequest{Visibility: &hidden}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := videos.DeleteVideo(ctx, public.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "hidden and deleted videos to leave the index", func() bool { return len(cluster.indexedIDs()) == 0 })
+}
+
+func TestSearchIndexQueueFull(t *testing.T) {
+	cluster := newFakeCluster()
+	db := dbtest.Open(t)
+	// Not running, so the one-slot queue stays full
+	idx := services.NewSearchIndex(db, cluster.client(), nopLogger(), 1, 10, time.Hour)
+	dropped := testutil.ToFloat64(metrics.SearchIndexOpsTotal.WithLabelValues("dropped"))
+	for id := uint(1); id <= 3; id++ {
+		idx.Observe(context.Background(), services.VideoChange{Kind: services.VideoDeleted, VideoID: id})
+	}
+	if got := testutil.ToFloat64(metrics.SearchIndexOpsTotal.WithLabelValues("dropped")) - dropped; got != 2 {
+		t.Errorf("%v changes dropped, want 2", got)
+	}
+
+	// What was queued is still written on shutdown
+	cluster.set(func(f *fakeCluster) { f.docs["1"] = search.Document{ID: 1} })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	idx.Run(ctx)
+	if len(cluster.indexedIDs()) != 0 {
+		t.Error("the queued delete wasn't written during the drain")
+	}
+}
+
+func TestSearchVideosFromIndex(t *testing.T) {
+	cluster := newFakeCluster()
+	db, videos := indexedVideos(t, cluster)
+	ctx := context.Background()
+	a := createVideo(t, db, models.Video{Title: "a", Visibility: models.VisibilityPublic})
+	b := createVideo(t, db, models.Video{Title: "b", Visibility: models.VisibilityPublic})
+	// Made private after the index last saw it
+	stale := createVideo(t, db, models.Video{Title: "stale", Visibility: models.VisibilityPrivate})
+	cluster.set(func(f *fakeCluster) { f.hits, f.total = []uint{b.ID, stale.ID, a.ID}, 57 })
+
+	page, err := videos.SearchVideos(ctx, "hollyday", 2, 3, services.VideoSort{}, services.VideoFilters{Category: "travel"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uint
+	for _, v := range page.Videos {
+		got = append(got, v.ID)
+	}
+	if !sameIDs(got, []uint{b.ID, a.ID}) || page.Total != 57 || page.TotalPages != 19 || page.Page != 2 {
+		t.Errorf("search = %v, total %d, %d pages; want the index's order without the private video", got, page.Total, page.TotalPages)
+	}
+	cluster.mu.Lock()
+	sent := cluster.searches[0]
+	cluster.mu.Unlock()
+	for _, want := range []string{`"query":"hollyday"`, `"fuzziness":"AUTO"`, `"from":3`, `"size":3`, `{"term":{"category":"travel"}}`} {
+		if !strings.Contains(sent, want) {
+			t.Errorf("search body %s lacks %s", sent, want)
+		}
+	}
+
+	cards, err := videos.SearchVideoCards(ctx, "hollyday", 1, 3, services.VideoSort{}, services.VideoFilters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	for _, c := range cards.Videos {
+		got = append(got, c.ID)
+	}
+	if !sameIDs(got, []uint{b.ID, a.ID}) || cards.Total != 57 {
+		t.Errorf("card search = %v, total %d", got, cards.Total)
+	}
+}
+
+func TestSearchVideosFallsBack(t *testing.T) {
+	cluster := newFakeCluster()
+	db, videos := indexedVideos(t, cluster)
+	ctx := context.Background()
+	public := createVideo(t, db, models.Video{Title: "a", Visibility: models.VisibilityPublic})
+	createVideo(t, db, models.Video{Title: "b", Visibility: models.VisibilityPrivate})
+
+	tests := []struct {
+		reason  string
+		breakIt func(f *fakeCluster)
+	}{
+		{"unavailable", func(f *fakeCluster) { f.down = true }},
+		{"unavailable", func(f *fakeCluster) { f.down, f.status = false, http.StatusServiceUnavailable }},
+		{"error", func(f *fakeCluster) { f.status = http.StatusBadRequest }},
+	}
+	for _, tt := range tests {
+		cluster.set(tt.breakIt)
+		before := testutil.ToFloat64(metrics.SearchFallbacksTotal.WithLabelValues(tt.reason))
+		page, err := videos.SearchVideos(ctx, "", 1, 20, services.VideoSort{}, services.VideoFilters{})
+		if err != nil || len(page.Videos) != 1 || page.Videos[0].ID != public.ID || page.Total != 1 {
+			t.Errorf("%s: search = %+v, %v; want the database's answer", tt.reason, page, err)
+		}
+		if _, err := videos.SearchVideoCards(ctx, "", 1, 20, services.VideoSort{}, services.VideoFilters{}); err != nil {
+			t.Errorf("%s: card search: %v", tt.reason, err)
+		}
+		if got := testutil.ToFloat64(metrics.SearchFallbacksTotal.WithLabelValues(tt.reason)) - before; got != 2 {
+			t.Errorf("%s: %v fallbacks counted, want 2", tt.reason, got)
+		}
+	}
+
+	// Without an index, search never leaves the database
+	plain := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	if page, err := plain.SearchVideos(ctx, "", 1, 20, services.VideoSort{}, services.VideoFilters{}); err != nil || page.Total != 1 {
+		t.Errorf("search without an index = %+v, %v", page, err)
+	}
+}
+
+func TestReindex(t *testing.T) {
+	cluster := newFakeCluster()
+	db := dbtest.Open(t)
+	idx := services.NewSearchIndex(db, cluster.client(), nopLogger(), 10, 10, time.Hour)
+	ctx := context.Background()
+	var want []uint
+	for i := 0; i < 5; i++ {
+		want = append(want, createVideo(t, db, models.Video{Title: fmt.Sprintf("v%d", i), Visibility: models.VisibilityPublic}).ID)
+	}
+	createVideo(t, db, models.Video{Title: "private", Visibility: models.VisibilityPrivate})
+	createVideo(t, db, models.Video{Title: "taken down", Visibility: models.VisibilityPublic, ModerationStatus: models.ModerationStatusTakenDown})
+
+	written, err := idx.Reindex(ctx, 2)
+	if err != nil || written != 5 {
+		t.Fatalf("Reindex = %d, %v; want the 5 listed videos", written, err)
+	}
+	if got := cluster.indexedIDs(); !sameIDs(got, want) || cluster.bulks != 3 {
+		t.Errorf("indexed %v in %d bulks, want %v in 3", got, cluster.bulks, want)
+	}
+
+	cluster.set(func(f *fakeCluster) { f.down = true })
+	if written, err := idx.Reindex(ctx, 2); err == nil || written != 0 {
+		t.Errorf("Reindex against a down cluster = %d, %v", written, err)
+	}
+}
+
+func TestStartReindexOneAtATime(t *testing.T) {
+	cluster := newFakeCluster()
+	db := dbtest.Open(t)
+	idx := services.NewSearchIndex(db, cluster.client(), nopLogger(), 10, 10, time.Hour)
+	createVideo(t, db, models.Video{Title: "a", Visibility: models.VisibilityPublic})
+	release := make(chan struct{})
+	cluster.set(func(f *fakeCluster) { f.hold = release })
+
+	if !idx.StartReindex(context.Background(), 10) {
+		t.Fatal("the first reindex didn't start")
+	}
+	if idx.StartReindex(context.Background(), 10) {
+		t.Error("a second reindex started while the first was running")
+	}
+	close(release)
+	waitFor(t, "the reindex to finish", func() bool { return len(cluster.indexedIDs()) == 1 })
+	waitFor(t, "another reindex to be allowed", func() bool { return idx.StartReindex(context.Background(), 10) })
+}
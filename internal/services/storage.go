@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/tracing"
+)
+
+// StorageClient is the blob storage the catalog cleans up, inspects and stores
+// uploaded thumbnails in: Azure Blob Storage or an S3-compatible store such as
+// MinIO. Paths are blob names (object keys) relative to the configured container
+// or bucket.
+type StorageClient interface {
+	DeleteBlob(ctx context.Context, blobPath string) error
+	DeleteBlobsWithPrefix(ctx context.Context, prefix string) (int, error)
+	DeleteBlobPage(ctx context.Context, prefix, marker string) (next string, deleted int, err error)
+	BlobExists(ctx context.Context, blobPath string) (bool, error)
+	// UploadBlob writes data to blobPath, replacing any blob there, and returns its URL
+	UploadBlob(ctx context.Context, blobPath string, data []byte, contentType string) (string, error)
+}
+
+// NewStorageClient creates the storage client cfg.Backend names
+func NewStorageClient(cfg config.Storage) (StorageClient, error) {
+	switch cfg.Backend {
+	case config.StorageBackendAzure:
+		client, err := NewAzureClientAdapter(cfg.Azure, cfg.Breaker)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case config.StorageBackendS3:
+		client, err := NewS3ClientAdapter(cfg.S3, cfg.Breaker)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (want %s or %s)", cfg.Backend, config.StorageBackendAzure, config.StorageBackendS3)
+	}
+}
+
+// storageGuard is the circuit breaker and retry policy every storage backend calls
+// through, so a struggling store fails fast instead of stalling deletes. Each call
+// is traced as one span named after the client and operation.
+type storageGuard struct {
+	name    string
+	breaker *gobreaker.CircuitBreaker
+	// attemptTimeout and retries bound each retried call
+	attemptTimeout time.Duration
+	retries        int
+}
+
+// newStorageGuard builds the breaker and retry policy cfg describes
+func newStorageGuard(name string, cfg config.Breaker) *storageGuard {
+	cbFailures := uint32(cfg.ConsecutiveFailures)
+	breaker := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        name,
+		Timeout:     cfg.Reset,
+		ReadyToTrip: func(c gobreaker.Counts) bool { return c.ConsecutiveFailures >= cbFailures },
+	})
+
+	return &storageGuard{
+		name:           name,
+		breaker:        breaker,
+		attemptTimeout: cfg.AttemptTimeout,
+		retries:        cfg.Retries,
+	}
+}
+
+// trace starts the span for op on blobPath (a blob name or prefix)
+func (g *storageGuard) trace(ctx context.Context, op, blobPath string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, g.name+" "+op, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("storage.operation", op), attribute.String("storage.path", blobPath)))
+}
+
+// execute runs fn through the circuit breaker once
+func (g *storageGuard) execute(ctx context.Context, op, blobPath string, fn func() (interface{}, error)) (interface{}, error) {
+	_, span := g.trace(ctx, op, blobPath)
+	result, err := g.breaker.Execute(fn)
+	tracing.End(span, err)
+	return result, err
+}
+
+// retry runs fn through the circuit breaker, each attempt bounded by the attempt
+// timeout, retrying failures with backoff
+func (g *storageGuard) retry(ctx context.Context, op, blobPath string, fn func(ctx context.Context) error) (err error) {
+	ctx, span := g.trace(ctx, op, blobPath)
+	attempts := 0
+	defer func() {
+		span.SetAttributes(attribute.Int("storage.attempts", attempts))
+		tracing.End(span, err)
+	}()
+	var last error
+	backoff := 200 * time.Millisecond
+	for i := 0; i <= g.retries; i++ {
+		attempts++
+		c, cancel := context.WithTimeout(ctx, g.attemptTimeout)
+		_, err := g.breaker.Execute(func() (interface{}, error) { return nil, fn(c) })
+		cancel()
+		if err == nil {
+			return nil
+		}
+		last = err
+		if i < g.retries {
+			time.Sleep(backoff)
+			if backoff < 1500*time.Millisecond {
+				backoff *= 2
+			}
+		}
+	}
+	return last
+}
+
+// deleteAllPages deletes every blob under prefix one page at a time through
+// deletePage, returning how many it deleted, including those deleted before a failure
+func deleteAllPages(ctx context.Context, prefix string, deletePage func(ctx context.Context, prefix, marker string) (string, int, error)) (int, error) {
+	marker := ""
+	total := 0
+	for {
+		next, deleted, err := deletePage(ctx, prefix, marker)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		if next == "" {
+			return total, nil
+		}
+		marker = next
+	}
+}
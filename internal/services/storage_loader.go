@@ -0,0 +1,78 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+)
+
+// StorageFactory builds a storage client. It may fail at first, e.g. while the
+// secrets volume isn't mounted yet, and succeed when called again later.
+type StorageFactory func() (StorageClient, error)
+
+// StorageLoader builds the storage client on first use and keeps it. A failed
+// attempt is retried on a later call, at most once per retry interval, so a client
+// that couldn't be built at startup is picked up without a restart. A nil loader
+// has no storage.
+type StorageLoader struct {
+	newClient StorageFactory
+	retry     time.Duration
+	logger    *zap.SugaredLogger
+
+	mu     sync.Mutex
+	client StorageClient
+	err    error
+	// next is when a failed attempt may be repeated
+	next time.Time
+}
+
+// NewStorageLoader creates a loader that calls newClient until it succeeds, waiting
+// at least retry between failed attempts
+func NewStorageLoader(newClient StorageFactory, retry time.Duration, logger *zap.SugaredLogger) *StorageLoader {
+	return &StorageLoader{newClient: newClient, retry: retry, logger: logger}
+}
+
+// Client returns the storage client, building it if it doesn't exist yet. While it
+// can't be built, it returns nil and the last attempt's error.
+func (l *StorageLoader) Client() (StorageClient, error) {
+	if l == nil {
+		return nil, ErrStorageUnavailable
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.client != nil {
+		return l.client, nil
+	}
+	if now := time.Now(); l.err == nil || !now.Before(l.next) {
+		l.attempt(now)
+	}
+	return l.client, l.err
+}
+
+// attempt calls the factory once; l.mu is held
+func (l *StorageLoader) attempt(now time.Time) {
+	retried := l.err != nil
+	client, err := l.newClient()
+	if err == nil && client == nil {
+		err = ErrStorageUnavailable
+	}
+	if err != nil {
+		l.err, l.next = fmt.Errorf("storage client: %w", err), now.Add(l.retry)
+		metrics.StorageClientInitsTotal.WithLabelValues("error").Inc()
+		if !retried {
+			l.logger.Warnw("Storage client not available; deletes are database-only until it is", "error", err, "retry", l.retry.String())
+		}
+		return
+	}
+	l.client, l.err = client, nil
+	metrics.StorageClientInitsTotal.WithLabelValues("ok").Inc()
+	fields := []interface{}{"client", fmt.Sprintf("%T", client), "retried", retried}
+	if azure, ok := client.(*AzureClientAdapter); ok {
+		fields = append(fields, "authMode", azure.AuthMode())
+	}
+	l.logger.Infow("Storage client initialized", fields...)
+}
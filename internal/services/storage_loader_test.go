@@ -0,0 +1,144 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// flakyFactory fails its first failures calls, as while the secrets volume isn't
+// mounted yet, then returns client
+type flakyFactory struct {
+	mu       sync.Mutex
+	client   services.StorageClient
+	failures int
+	calls    int
+}
+
+func (f *flakyFactory) build() (services.StorageClient, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, errors.New("read /mnt/secrets-store/storage-account-key: no such file or directory")
+	}
+	return f.client, nil
+}
+
+func (f *flakyFactory) called() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func storageInits(outcome string) float64 {
+	return testutil.ToFloat64(metrics.StorageClientInitsTotal.WithLabelValues(outcome))
+}
+
+func TestStorageLoaderRetries(t *testing.T) {
+	storage := newFakeStorage()
+	factory := &flakyFactory{client: storage, failures: 2}
+	loader := services.NewStorageLoader(factory.build, 20*time.Millisecond, nopLogger())
+	failed, ok := storageInits("error"), storageInits("ok")
+
+	if client, err := loader.Client(); client != nil || err == nil {
+		t.Fatalf("first attempt = %v, %v; want the factory's error", client, err)
+	}
+	// Within the retry interval the failure is remembered, not retried
+	if _, err := loader.Client(); err == nil || factory.called() != 1 {
+		t.Fatalf("second call: %v after %d attempts, want the same error without another", err, factory.called())
+	}
+	time.Sleep(25 * time.Millisecond)
+	if _, err := loader.Client(); err == nil || factory.called() != 2 {
+		t.Fatalf("after the interval: %v after %d attempts, want a second failed attempt", err, factory.called())
+	}
+	time.Sleep(25 * time.Millisecond)
+	client, err := loader.Client()
+	if err != nil || client != storage {
+		t.Fatalf("third attempt = %v, %v; want the client", client, err)
+	}
+	// Once built it is kept
+	loader.Client()
+	if factory.called() != 3 {
+		t.Errorf("factory called %d times, want 3", factory.called())
+	}
+	if got := storageInits("error") - failed; got != 2 {
+		t.Errorf("failed inits +%v, want +2", got)
+	}
+	if got := storageInits("ok") - ok; got != 1 {
+		t.Errorf("successful inits +%v, want +1", got)
+	}
+}
+
+func TestStorageLoaderUnavailable(t *testing.T) {
+	var none *services.StorageLoader
+	if _, err := none.Client(); !errors.Is(err, services.ErrStorageUnavailable) {
+		t.Errorf("nil loader: %v, want ErrStorageUnavailable", err)
+	}
+	// A factory with nothing to build, such as no backend configured
+	empty := services.NewStorageLoader(func() (services.StorageClient, error) { return nil, nil }, time.Hour, nopLogger())
+	if _, err := empty.Client(); !errors.Is(err, services.ErrStorageUnavailable) {
+		t.Errorf("nil client: %v, want ErrStorageUnavailable", err)
+	}
+}
+
+// TestDeleteAfterStorageComesUp deletes one video while the storage client can't be
+// built, then another once it can: the first is purged from the database only, the
+// second's blobs are deleted too
+func TestDeleteAfterStorageComesUp(t *testing.T) {
+	db := dbtest.Open(t)
+	storage := newFakeStorage("raw/owner/up-1.mp4", "raw/owner/up-2.mp4")
+	factory := &flakyFactory{client: storage, failures: 1}
+	videos := services.NewVideoService(db, services.NewStorageLoader(factory.build, 0, nopLogger()), videoSettings, nopLogger())
+	ctx := context.Background()
+	first := createVideo(t, db, models.Video{UploadID: "up-1", Title: "t", RawVideoPath: "raw/owner/up-1.mp4"})
+	second := createVideo(t, db, models.Video{UploadID: "up-2", Title: "t", RawVideoPath: "raw/owner/up-2.mp4"})
+
+	worker := services.NewDeletionWorker(videos, nopLogger(), 1, 10*time.Millisecond, time.Minute)
+	runCtx, stop := context.WithCancel(ctx)
+	worker.Run(runCtx)
+	defer func() { stop(); worker.Wait() }()
+	purge := func(video *models.Video) {
+		t.Helper()
+		job, err := videos.DeleteVideoForUser(ctx, video.ID, "owner")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expire(t, db, video.ID)
+		waitFor(t, "the purge to complete", func() bool {
+			job, err := videos.GetDeletion(ctx, job.ID)
+			return err == nil && job.Status == models.DeletionCompleted
+		})
+	}
+
+	databaseOnly := testutil.ToFloat64(metrics.DatabaseOnlyPurgesTotal)
+	purge(first)
+	if !storage.has("raw/owner/up-1.mp4") {
+		t.Error("a blob was deleted without a storage client")
+	}
+	if got := testutil.ToFloat64(metrics.DatabaseOnlyPurgesTotal) - databaseOnly; got != 1 {
+		t.Errorf("database-only purges +%v, want +1", got)
+	}
+
+	purge(second)
+	if storage.has("raw/owner/up-2.mp4") {
+		t.Error("the second video's blob was kept once storage was available")
+	}
+	if got := testutil.ToFloat64(metrics.DatabaseOnlyPurgesTotal) - databaseOnly; got != 1 {
+		t.Errorf("database-only purges +%v after storage came up, want still +1", got)
+	}
+	var rows int64
+	db.Unscoped().Model(&models.Video{}).Where("id IN ?", []uint{first.ID, second.ID}).Count(&rows)
+	if rows != 0 {
+		t.Errorf("%d video rows left, want both purged", rows)
+	}
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// BundleSection is one independently collected part of a support bundle. A failing
+// section is reported in place and never prevents the others from being collected.
+type BundleSection interface {
+	Name() string
+	Collect(ctx context.Context, video *models.Video) (interface{}, error)
+}
+
+// BundleSectionResult is the outcome of collecting a single section
+type BundleSectionResult struct {
+	Status     string      `json:"status"`
+	Data       interface{} `json:"data,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	DurationMs int64       `json:"duration_ms"`
+}
+
+// SupportBundle is the document returned to support for a single video
+type SupportBundle struct {
+	VideoID     uint                           `json:"video_id"`
+	UploadID    string                         `json:"upload_id"`
+	GeneratedAt time.Time                      `json:"generated_at"`
+	Sections    map[string]BundleSectionResult `json:"sections"`
+}
+
+// SupportBundleService assembles support bundles from registered sections
+type SupportBundleService struct {
+	db       *gorm.DB
+	logger   *zap.SugaredLogger
+	sections []BundleSection
+}
+
+// NewSupportBundleService creates a bundle service with the given sections
+func NewSupportBundleService(db *gorm.DB, logger *zap.SugaredLogger, sections ...BundleSection) *SupportBundleService {
+	return &SupportBundleService{db: db, logger: logger, sections: sections}
+}
+
+// Build collects every section for a video. Soft-deleted videos are included since
+// tickets are often about videos that have just been removed.
+func (s *SupportBundleService) Build(ctx context.Context, videoID uint) (*SupportBundle, error) {
+	var video models.Video
+	if err := s.db.WithContext(ctx).Unscoped().First(&video, videoID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("video %d: %w", videoID, ErrVideoNotFound)
+		}
+		return nil, fmt.Errorf("failed to get video: %w", err)
+	}
+
+	bundle := &SupportBundle{
+		VideoID:     video.ID,
+		UploadID:    video.UploadID,
+		GeneratedAt: time.Now().UTC(),
+		Sections:    make(map[string]BundleSectionResult, len(s.sections)),
+	}
+	for _, section := range s.sections {
+		bundle.Sections[section.Name()] = s.collect(ctx, section, &video)
+	}
+	return bundle, nil
+}
+
+func (s *SupportBundleService) collect(ctx context.Context, section BundleSection, video *models.Video) (result BundleSectionResult) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Errorw("Support bundle section panicked", "section", section.Name(), "videoID", video.ID, "panic", r)
+			result = BundleSectionResult{Status: "error", Error: fmt.Sprintf("panic: %v", r)}
+		}
+		result.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	data, err := section.Collect(ctx, video)
+	if err != nil {
+		s.logger.Warnw("Support bundle section failed", "section", section.Name(), "videoID", video.ID, "error", err)
+		return BundleSectionResult{Status: "error", Data: data, Error: err.Error()}
+	}
+	return BundleSectionResult{Status: "ok", Data: data}
+}
+
+// VideoRecordSection returns the full video row
+type VideoRecordSection struct{}
+
+// Name implements BundleSection
+func (VideoRecordSection) Name() string { return "video" }
+
+// Collect implements BundleSection
+func (VideoRecordSection) Collect(_ context.Context, video *models.Video) (interface{}, error) {
+	return video, nil
+}
+
+// BlobCheck is the result of one storage existence check
+type BlobCheck struct {
+	Kind   string `json:"kind"`
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+	Error  string `json:"error,omitempty"`
+}
+
+// StorageSection checks that the raw upload, HLS master playlist and thumbnail exist.
+// Each check runs live against storage with its own timeout.
+type StorageSection struct {
+	Storage      *StorageLoader
+	CheckTimeout time.Duration
+}
+
+// Name implements BundleSection
+func (StorageSection) Name() string { return "storage" }
+
+// Collect implements BundleSection
+func (s StorageSection) Collect(ctx context.Context, video *models.Video) (interface{}, error) {
+	storage, err := s.Storage.Client()
+	if err != nil {
+		return nil, fmt.Errorf("storage client not available: %w", err)
+	}
+
+	var targets []BlobCheck
+	if video.RawVideoPath != "" {
+		targets = append(targets, BlobCheck{Kind: "raw", Path: video.RawVideoPath})
+	}
+	if video.HLSMasterURL != "" {
+		prefix := extractHLSPrefix(video.HLSMasterURL, video.UserID, video.UploadID)
+		targets = append(targets, BlobCheck{Kind: "hls_master", Path: path.Join(prefix, "master.m3u8")})
+	}
+	targets = append(targets, BlobCheck{Kind: "thumbnail", Path: thumbnailBlobPath(video)})
+
+	failed := 0
+	for i := range targets {
+		checkCtx, cancel := context.WithTimeout(ctx, s.CheckTimeout)
+		exists, err := storage.BlobExists(checkCtx, targets[i].Path)
+		cancel()
+		if err != nil {
+			targets[i].Error = err.Error()
+			failed++
+			continue
+		}
+		targets[i].Exists = exists
+	}
+	if failed == len(targets) {
+		return targets, fmt.Errorf("all %d storage checks failed", failed)
+	}
+	return targets, nil
+}
+
+// QuarantineSection lists quarantined events for the video's upload ID
+type QuarantineSection struct {
+	DB *gorm.DB
+}
+
+// Name implements BundleSection
+func (QuarantineSection) Name() string { return "quarantined_events" }
+
+// Collect implements BundleSection
+func (s QuarantineSection) Collect(ctx context.Context, video *models.Video) (interface{}, error) {
+	var out []models.QuarantinedEvent
+	if err := s.DB.WithContext(ctx).Where("upload_id = ?", video.UploadID).
+		Order("id DESC").Limit(50).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StatusHistorySection lists the video's status changes, oldest first
+type StatusHistorySection struct {
+	DB *gorm.DB
+}
+
+// Name implements BundleSection
+func (StatusHistorySection) Name() string { return "status_history" }
+
+// Collect implements BundleSection
+func (s StatusHistorySection) Collect(ctx context.Context, video *models.Video) (interface{}, error) {
+	var out []models.VideoStatusChange
+	if err := s.DB.WithContext(ctx).Where("video_id = ?", video.ID).
+		Order("created_at, id").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CommentSummary is the comments section: how many comments the video has in each
+// state, soft-deleted ones included, and the newest of them
+type CommentSummary struct {
+	ByStatus map[string]int64 `json:"by_status"`
+	Deleted  int64            `json:"deleted"`
+	Recent   []models.Comment `json:"recent"`
+}
+
+// CommentsSection summarizes the video's comments
+type CommentsSection struct {
+	DB *gorm.DB
+}
+
+// Name implements BundleSection
+func (CommentsSection) Name() string { return "comments" }
+
+// Collect implements BundleSection
+func (s CommentsSection) Collect(ctx context.Context, video *models.Video) (interface{}, error) {
+	var counts []struct {
+		Status string
+		N      int64
+	}
+	if err := s.DB.WithContext(ctx).Model(&models.Comment{}).Select("status, COUNT(*) AS n").
+		Where("video_id = ?", video.ID).Group("status").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	out := CommentSummary{ByStatus: make(map[string]int64, len(counts))}
+	for _, c := range counts {
+		out.ByStatus[c.Status] = c.N
+	}
+	if err := s.DB.WithContext(ctx).Unscoped().Model(&models.Comment{}).
+		Where("video_id = ? AND deleted_at IS NOT NULL", video.ID).Count(&out.Deleted).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.WithContext(ctx).Unscoped().Where("video_id = ?", video.ID).
+		Order("id DESC").Limit(20).Find(&out.Recent).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AccessLogSection lists the newest accesses to the video
+type AccessLogSection struct {
+	DB *gorm.DB
+}
+
+// Name implements BundleSection
+func (AccessLogSection) Name() string { return "access_log" }
+
+// Collect implements BundleSection
+func (s AccessLogSection) Collect(ctx context.Context, video *models.Video) (interface{}, error) {
+	var out []models.VideoAccessLog
+	if err := s.DB.WithContext(ctx).Where("video_id = ?", video.ID).
+		Order("accessed_at DESC, id DESC").Limit(50).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// InboundEventsSection lists the archived broker events for the video's upload ID
+type InboundEventsSection struct {
+	DB *gorm.DB
+}
+
+// Name implements BundleSection
+func (InboundEventsSection) Name() string { return "events" }
+
+// Collect implements BundleSection
+func (s InboundEventsSection) Collect(ctx context.Context, video *models.Video) (interface{}, error) {
+	var out []models.InboundEvent
+	if err := s.DB.WithContext(ctx).Where("upload_id = ?", video.UploadID).
+		Order("id DESC").Limit(50).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ModerationSection lists the moderation flags raised on the video and its comments
+type ModerationSection struct {
+	DB *gorm.DB
+}
+
+// Name implements BundleSection
+func (ModerationSection) Name() string { return "moderation" }
+
+// Collect implements BundleSection
+func (s ModerationSection) Collect(ctx context.Context, video *models.Video) (interface{}, error) {
+	comments := s.DB.Unscoped().Model(&models.Comment{}).Select("id").Where("video_id = ?", video.ID)
+	var out []models.ModerationFlag
+	if err := s.DB.WithContext(ctx).
+		Where("(target_type = ? AND target_id = ?) OR (target_type = ? AND target_id IN (?))",
+			models.ModerationTargetVideo, video.ID, models.ModerationTargetComment, comments).
+		Order("id DESC").Limit(50).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeletionsSection lists the video's deletion jobs with what each left behind
+type DeletionsSection struct {
+	DB *gorm.DB
+}
+
+// Name implements BundleSection
+func (DeletionsSection) Name() string { return "deletions" }
+
+// Collect implements BundleSection
+func (s DeletionsSection) Collect(ctx context.Context, video *models.Video) (interface{}, error) {
+	var out []models.VideoDeletion
+	if err := s.DB.WithContext(ctx).Where("video_id = ?", video.ID).
+		Order("id DESC").Limit(20).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuditSection lists the newest admin actions on the video
+type AuditSection struct {
+	DB *gorm.DB
+}
+
+// Name implements BundleSection
+func (AuditSection) Name() string { return "audit" }
+
+// Collect implements BundleSection
+func (s AuditSection) Collect(ctx context.Context, video *models.Video) (interface{}, error) {
+	var out []models.AuditLog
+	if err := s.DB.WithContext(ctx).Where("target = ?", fmt.Sprintf("video:%d", video.ID)).
+		Order("id DESC").Limit(50).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
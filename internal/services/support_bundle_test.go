@@ -0,0 +1,192 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// fakeSection returns data, or fails with err, or panics
+type fakeSection struct {
+	name  string
+	data  interface{}
+	err   error
+	panic bool
+}
+
+func (f fakeSection) Name() string { return f.name }
+
+func (f fakeSection) Collect(context.Context, *models.Video) (interface{}, error) {
+	if f.panic {
+		panic("section exploded")
+	}
+	return f.data, f.err
+}
+
+func TestSupportBundleFailingSectionsDontFailBundle(t *testing.T) {
+	db := dbtest.Open(t)
+	video := createVideo(t, db, models.Video{Title: "ticket"})
+	bundles := services.NewSupportBundleService(db, nopLogger(),
+		fakeSection{name: "healthy", data: "fine"},
+		fakeSection{name: "broken", data: "partial", err: errors.New("dependency down")},
+		fakeSection{name: "panicking", panic: true},
+		services.VideoRecordSection{},
+	)
+
+	bundle, err := bundles.Build(context.Background(), video.ID)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if got := bundle.Sections["healthy"]; got.Status != "ok" || got.Data != "fine" {
+		t.Errorf("healthy = %+v", got)
+	}
+	if got := bundle.Sections["broken"]; got.Status != "error" || got.Error != "dependency down" || got.Data != "partial" {
+		t.Errorf("broken = %+v", got)
+	}
+	if got := bundle.Sections["panicking"]; got.Status != "error" || got.Error != "panic: section exploded" {
+		t.Errorf("panicking = %+v", got)
+	}
+	if got := bundle.Sections["video"]; got.Status != "ok" {
+		t.Errorf("video = %+v", got)
+	}
+}
+
+func TestSupportBundleUnknownVideo(t *testing.T) {
+	bundles := services.NewSupportBundleService(dbtest.Open(t), nopLogger())
+	if _, err := bundles.Build(context.Background(), 99); !errors.Is(err, services.ErrVideoNotFound) {
+		t.Fatalf("err = %v, want ErrVideoNotFound", err)
+	}
+}
+
+func TestSupportBundleIncludesDeletedVideo(t *testing.T) {
+	db := dbtest.Open(t)
+	video := createVideo(t, db, models.Video{Title: "gone"})
+	db.Delete(video)
+	bundle, err := services.NewSupportBundleService(db, nopLogger()).Build(context.Background(), video.ID)
+	if err != nil || bundle.UploadID != video.UploadID {
+		t.Fatalf("Build = %+v, %v", bundle, err)
+	}
+}
+
+func TestStorageSection(t *testing.T) {
+	video := &models.Video{UserID: "owner", UploadID: "up", RawVideoPath: "raw/owner/up.mp4"}
+	tests := []struct {
+		name    string
+		storage *fakeStorage
+		loader  *services.StorageLoader
+		wantErr bool
+		exists  map[string]bool
+		errored map[string]bool
+	}{
+		{
+			name:    "raw present, thumbnail missing",
+			storage: newFakeStorage("raw/owner/up.mp4"),
+			exists:  map[string]bool{"raw": true, "thumbnail": false},
+		},
+		{
+			name: "one check failing",
+			storage: &fakeStorage{blobs: map[string][]byte{"thumbnails/owner/up.jpg": nil},
+				existsErr: map[string]error{"raw/owner/up.mp4": errors.New("503")}},
+			exists:  map[string]bool{"thumbnail": true},
+			errored: map[string]bool{"raw": true},
+		},
+		{
+			name:    "every check failing",
+			storage: &fakeStorage{err: errors.New("unreachable")},
+			wantErr: true,
+			errored: map[string]bool{"raw": true, "thumbnail": true},
+		},
+		{
+			name:    "hung backend hits the per-check timeout",
+			storage: &fakeStorage{block: true},
+			wantErr: true,
+			errored: map[string]bool{"raw": true, "thumbnail": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			section := services.StorageSection{Storage: storageLoader(tt.storage), CheckTimeout: 20 * time.Millisecond}
+			data, err := section.Collect(context.Background(), video)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			checks := data.([]services.BlobCheck)
+			if len(checks) != 2 {
+				t.Fatalf("checks = %+v, want raw and thumbnail", checks)
+			}
+			for _, c := range checks {
+				if c.Exists != tt.exists[c.Kind] || (c.Error != "") != tt.errored[c.Kind] {
+					t.Errorf("%s check = %+v", c.Kind, c)
+				}
+			}
+		})
+	}
+}
+
+func TestStorageSectionWithoutClient(t *testing.T) {
+	section := services.StorageSection{CheckTimeout: time.Second}
+	if _, err := section.Collect(context.Background(), &models.Video{}); !errors.Is(err, services.ErrStorageUnavailable) {
+		t.Fatalf("err = %v, want ErrStorageUnavailable", err)
+	}
+}
+
+func TestDatabaseSections(t *testing.T) {
+	db := dbtest.Open(t)
+	video := createVideo(t, db, models.Video{Title: "ticket"})
+	other := createVideo(t, db, models.Video{Title: "other"})
+	visible := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "alice"})
+	createComment(t, db, models.Comment{VideoID: video.ID, UserID: "bob", Status: models.CommentPending})
+	deleted := createComment(t, db, models.Comment{VideoID: video.ID, UserID: "carol"})
+	db.Delete(deleted)
+	foreign := createComment(t, db, models.Comment{VideoID: other.ID, UserID: "alice"})
+	now := time.Now().UTC()
+	create(t, db,
+		&models.VideoAccessLog{VideoID: video.ID, ViewerID: "alice", ViewerType: models.ViewerUser, Endpoint: "get", AccessedAt: now},
+		&models.VideoAccessLog{VideoID: other.ID, ViewerID: "alice", ViewerType: models.ViewerUser, Endpoint: "get", AccessedAt: now},
+		&models.InboundEvent{Kind: "uploaded", UploadID: video.UploadID, Body: "{}", Status: models.InboundProcessed, ReceivedAt: now},
+		&models.InboundEvent{Kind: "uploaded", UploadID: other.UploadID, Body: "{}", Status: models.InboundProcessed, ReceivedAt: now},
+		&models.ModerationFlag{TargetType: models.ModerationTargetVideo, TargetID: video.ID, Field: "title", Severity: "high", Status: models.ModerationNeedsReview},
+		&models.ModerationFlag{TargetType: models.ModerationTargetComment, TargetID: visible.ID, Field: "content", Severity: "low", Status: models.ModerationFlagged},
+		&models.ModerationFlag{TargetType: models.ModerationTargetComment, TargetID: foreign.ID, Field: "content", Severity: "low", Status: models.ModerationFlagged},
+		&models.VideoDeletion{VideoID: video.ID, UserID: "owner", Status: "failed", RunAfter: now},
+		&models.AuditLog{Action: models.AuditActionSetStatus, ActorID: "admin", Target: fmt.Sprintf("video:%d", video.ID)},
+		&models.AuditLog{Action: models.AuditActionSetStatus, ActorID: "admin", Target: fmt.Sprintf("video:%d", other.ID)},
+	)
+
+	ctx := context.Background()
+	collect := func(section services.BundleSection) interface{} {
+		t.Helper()
+		data, err := section.Collect(ctx, video)
+		if err != nil {
+			t.Fatalf("%s: %v", section.Name(), err)
+		}
+		return data
+	}
+
+	comments := collect(services.CommentsSection{DB: db}).(services.CommentSummary)
+	if comments.ByStatus[models.CommentVisible] != 1 || comments.ByStatus[models.CommentPending] != 1 ||
+		comments.Deleted != 1 || len(comments.Recent) != 3 {
+		t.Errorf("comments = %+v", comments)
+	}
+	if got := collect(services.AccessLogSection{DB: db}).([]models.VideoAccessLog); len(got) != 1 {
+		t.Errorf("access log = %+v", got)
+	}
+	if got := collect(services.InboundEventsSection{DB: db}).([]models.InboundEvent); len(got) != 1 || got[0].UploadID != video.UploadID {
+		t.Errorf("events = %+v", got)
+	}
+	if got := collect(services.ModerationSection{DB: db}).([]models.ModerationFlag); len(got) != 2 {
+		t.Errorf("moderation flags = %+v, want the video's and its comment's", got)
+	}
+	if got := collect(services.DeletionsSection{DB: db}).([]models.VideoDeletion); len(got) != 1 {
+		t.Errorf("deletions = %+v", got)
+	}
+	if got := collect(services.AuditSection{DB: db}).([]models.AuditLog); len(got) != 1 || got[0].Target != fmt.Sprintf("video:%d", video.ID) {
+		t.Errorf("audit = %+v", got)
+	}
+}
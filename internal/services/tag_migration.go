@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+const tagsMigrationName = "tags_array_backfill"
+
+// TagMismatch is a row whose legacy and typed tag columns disagree
+type TagMismatch struct {
+	VideoID uint     `json:"video_id"`
+	Legacy  []string `json:"legacy"`
+	Typed   []string `json:"typed"`
+}
+
+// TagVerifyReport is the result of sampling rows for legacy/typed consistency
+type TagVerifyReport struct {
+	Mode        string                      `json:"mode"`
+	Sampled     int                         `json:"sampled"`
+	Missing     int64                       `json:"missing_typed"`
+	Mismatches  []TagMismatch               `json:"mismatches"`
+	Checkpoint  *models.MigrationCheckpoint `json:"checkpoint,omitempty"`
+	BackfillRun bool                        `json:"backfill_running"`
+}
+
+// TagMigrationService backfills the typed tags_array column from the legacy tags
+// column in checkpointed batches and verifies the two agree.
+type TagMigrationService struct {
+	db      *gorm.DB
+	logger  *zap.SugaredLogger
+	mu      sync.Mutex
+	running bool
+}
+
+// NewTagMigrationService creates a tag migration service
+func NewTagMigrationService(db *gorm.DB, logger *zap.SugaredLogger) *TagMigrationService {
+	return &TagMigrationService{db: db, logger: logger}
+}
+
+// StartBackfill runs Backfill in the background; it returns false if one is already running
+func (s *TagMigrationService) StartBackfill(ctx context.Context, batchSize int) bool {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return false
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			s.running = false
+			s.mu.Unlock()
+		}()
+		if err := s.Backfill(ctx, batchSize); err != nil {
+			s.logger.Errorw("Tags backfill failed", "error", err)
+		}
+	}()
+	return true
+}
+
+func (s *TagMigrationService) isRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// Backfill copies legacy tags into tags_array for rows that don't have it yet,
+// resuming from the stored checkpoint. Each batch and its checkpoint commit together.
+func (s *TagMigrationService) Backfill(ctx context.Context, batchSize int) error {
+	cp := models.MigrationCheckpoint{Name: tagsMigrationName}
+	if err := s.db.WithContext(ctx).FirstOrCreate(&cp, models.MigrationCheckpoint{Name: tagsMigrationName}).Error; err != nil {
+		return fmt.Errorf("load checkpoint: %w", err)
+	}
+	s.logger.Infow("Tags backfill starting", "fromID", cp.LastID, "migrated", cp.Migrated)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var rows []struct {
+			ID   uint
+			Tags string
+		}
+		// Unscoped: soft-deleted rows can be restored later and must be migrated too
+		if err := s.db.WithContext(ctx).Unscoped().Model(&models.Video{}).
+			Select("id, tags").
+			Where("id > ? AND tags_array IS NULL", cp.LastID).
+			Order("id").Limit(batchSize).
+			Scan(&rows).Error; err != nil {
+			return fmt.Errorf("load batch after %d: %w", cp.LastID, err)
+		}
+
+		if len(rows) == 0 {
+			cp.Done = true
+			if err := s.db.WithContext(ctx).Save(&cp).Error; err != nil {
+				return fmt.Errorf("save checkpoint: %w", err)
+			}
+			s.logger.Infow("Tags backfill complete", "migrated", cp.Migrated)
+			return nil
+		}
+
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for _, row := range rows {
+				// Guard on IS NULL so a concurrent dual-mode write is never overwritten
+				if err := tx.Unscoped().Model(&models.Video{}).
+					Where("id = ? AND tags_array IS NULL", row.ID).
+					UpdateColumn("tags_array", models.TagArray(models.ParseLegacyTags(row.Tags))).Error; err != nil {
+					return fmt.Errorf("migrate video %d: %w", row.ID, err)
+				}
+			}
+			cp.LastID = rows[len(rows)-1].ID
+			cp.Migrated += int64(len(rows))
+			return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&cp).Error
+		})
+		if err != nil {
+			return err
+		}
+		metrics.MigrationRowsTotal.WithLabelValues(tagsMigrationName).Add(float64(len(rows)))
+		metrics.MigrationCheckpointID.WithLabelValues(tagsMigrationName).Set(float64(cp.LastID))
+	}
+}
+
+// Verify samples up to sampleSize rows that have both columns populated and reports
+// any whose decoded tags differ, plus how many rows still lack the typed column.
+func (s *TagMigrationService) Verify(ctx context.Context, sampleSize int) (*TagVerifyReport, error) {
+	report := &TagVerifyReport{Mode: models.TagStorageMode(), Mismatches: []TagMismatch{}, BackfillRun: s.isRunning()}
+
+	if err := s.db.WithContext(ctx).Unscoped().Model(&models.Video{}).
+		Where("tags_array IS NULL").Count(&report.Missing).Error; err != nil {
+		return nil, fmt.Errorf("count unmigrated rows: %w", err)
+	}
+
+	ids, err := sampleVideoIDs(ctx, s.db, sampleSize)
+	if err != nil {
+		return nil, err
+	}
+	// The type tag lets gorm scan the typed column outside the Video model
+	var rows []struct {
+		ID        uint
+		Tags      string
+		TagsArray models.TagArray `gorm:"type:text[]"`
+	}
+	if len(ids) > 0 {
+		if err := s.db.WithContext(ctx).Model(&models.Video{}).
+			Select("id, tags, tags_array").
+			Where("id IN ? AND tags_array IS NOT NULL", ids).
+			Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("sample rows: %w", err)
+		}
+	}
+	report.Sampled = len(rows)
+	for _, row := range rows {
+		legacy := models.ParseLegacyTags(row.Tags)
+		typed := []string(row.TagsArray)
+		if len(legacy) == 0 && len(typed) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(legacy, typed) {
+			report.Mismatches = append(report.Mismatches, TagMismatch{VideoID: row.ID, Legacy: legacy, Typed: typed})
+		}
+	}
+
+	var cp models.MigrationCheckpoint
+	if err := s.db.WithContext(ctx).First(&cp, "name = ?", tagsMigrationName).Error; err == nil {
+		report.Checkpoint = &cp
+	}
+	return report, nil
+}
@@ -0,0 +1,87 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// TestTagsNormalizedOnEveryWrite sends the same messy tags through each write
+// path and checks each stores the same list
+func TestTagsNormalizedOnEveryWrite(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+	messy := []string{" Music", "LIVE ", "music", "", "live", "Jazz"}
+	want := []string{"music", "live", "jazz"}
+
+	created, err := videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-create", Title: "t", Tags: messy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := createVideo(t, db, models.Video{UploadID: "up-update", Title: "t"})
+	if _, err := videos.UpdateVideo(ctx, updated.ID, &models.VideoUpdateRequest{Tags: messy}); err != nil {
+		t.Fatal(err)
+	}
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-uploaded", UserID: "owner", Title: "t", Tags: messy}); err != nil {
+		t.Fatal(err)
+	}
+	transcoded := transcodedEvent("up-transcoded")
+	transcoded.Tags = messy
+	if err := videos.HandleTranscodedEvent(ctx, transcoded); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, uploadID := range []string{created.UploadID, updated.UploadID, "up-uploaded", "up-transcoded"} {
+		var video models.Video
+		if err := db.Where("upload_id = ?", uploadID).First(&video).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual([]string(video.TagsList), want) {
+			t.Errorf("%s: tags %q, want %q", uploadID, video.TagsList, want)
+		}
+	}
+}
+
+func TestInvalidTags(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+	bad := []string{"ok", "a,b", "{braced}"}
+	wantFields := map[string]string{"tags[1]": "must not contain commas or braces", "tags[2]": "must not contain commas or braces"}
+
+	// The API paths reject the request, naming each offending tag
+	_, err := videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-1", Title: "t", Tags: bad})
+	var invalid *services.ValidationError
+	if !errors.As(err, &invalid) || !errors.Is(err, services.ErrInvalidTags) || !reflect.DeepEqual(invalid.Fields, wantFields) {
+		t.Fatalf("CreateVideo: %v, want ErrInvalidTags with %v", err, wantFields)
+	}
+	var count int64
+	db.Model(&models.Video{}).Count(&count)
+	if count != 0 {
+		t.Error("a video was created despite its invalid tags")
+	}
+	video := createVideo(t, db, models.Video{UploadID: "up-1", Title: "t", TagsList: []string{"keep"}})
+	if _, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Tags: bad}); !errors.As(err, &invalid) {
+		t.Fatalf("UpdateVideo: %v, want a ValidationError", err)
+	}
+	db.First(video, video.ID)
+	if !reflect.DeepEqual([]string(video.TagsList), []string{"keep"}) {
+		t.Errorf("tags %q after a rejected update", video.TagsList)
+	}
+
+	// Events can't be refused, so they keep what's valid
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-2", UserID: "owner", Title: "t", Tags: bad}); err != nil {
+		t.Fatal(err)
+	}
+	var fromEvent models.Video
+	db.Where("upload_id = ?", "up-2").First(&fromEvent)
+	if !reflect.DeepEqual([]string(fromEvent.TagsList), []string{"ok"}) {
+		t.Errorf("event tags %q, want the valid ones", fromEvent.TagsList)
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// TagCount is a tag and how many public, ready videos carry it. Tags differing
+// only by case are counted together under their most common spelling.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// TagService serves popular tags and prefix suggestions from an in-memory
+// snapshot of the most used tags, recomputed at most once per interval
+type TagService struct {
+	db       *gorm.DB
+	logger   *zap.SugaredLogger
+	interval time.Duration
+	// max bounds the snapshot; suggestions only come from the max most used tags
+	max int
+	// load computes the snapshot; count, except in tests that can't run the
+	// Postgres aggregate
+	load func(ctx context.Context) ([]TagCount, error)
+	now  func() time.Time
+
+	mu        sync.Mutex
+	tags      []TagCount
+	refreshed time.Time
+}
+
+// NewTagService creates a tag service keeping up to max tags, refreshed every interval
+func NewTagService(db *gorm.DB, logger *zap.SugaredLogger, interval time.Duration, max int) *TagService {
+	s := &TagService{db: db, logger: logger, interval: interval, max: max, now: time.Now}
+	s.load = s.count
+	return s
+}
+
+// Popular returns up to limit tags from offset on, most used first, and how many
+// tags the snapshot holds
+func (s *TagService) Popular(ctx context.Context, offset, limit int) ([]TagCount, int, error) {
+	tags, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if offset >= len(tags) {
+		return []TagCount{}, len(tags), nil
+	}
+	end := offset + limit
+	if end > len(tags) {
+		end = len(tags)
+	}
+	return tags[offset:end], len(tags), nil
+}
+
+// Suggest returns up to limit tags starting with prefix, ignoring case, most used first
+func (s *TagService) Suggest(ctx context.Context, prefix string, limit int) ([]TagCount, error) {
+	tags, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	out := []TagCount{}
+	for _, t := range tags {
+		if len(out) == limit {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(t.Tag), prefix) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// snapshot returns the cached tag counts, recomputing them once they are older
+// than the interval. A failed refresh keeps serving the previous snapshot if there
+// is one.
+func (s *TagService) snapshot(ctx context.Context) ([]TagCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.tags != nil && now.Sub(s.refreshed) < s.interval {
+		return s.tags, nil
+	}
+	tags, err := s.load(ctx)
+	if err != nil {
+		if s.tags != nil {
+			s.logger.Warnw("Failed to refresh popular tags; serving the previous snapshot", "error", err, "age", now.Sub(s.refreshed))
+			return s.tags, nil
+		}
+		return nil, err
+	}
+	s.tags, s.refreshed = tags, now
+	return tags, nil
+}
+
+// count aggregates the tags of listed, ready videos, grouping them case-insensitively
+func (s *TagService) count(ctx context.Context) ([]TagCount, error) {
+	tags := []TagCount{}
+	err := s.db.WithContext(ctx).Raw(`SELECT mode() WITHIN GROUP (ORDER BY t.tag) AS tag, COUNT(DISTINCT v.id) AS count
+		FROM videos v CROSS JOIN LATERAL unnest(`+models.TagsReadExpr()+`) AS t(tag)
+		WHERE v.deleted_at IS NULL AND v.visibility = ? AND v.moderation_status <> ? AND v.status = ?
+			AND btrim(t.tag) <> ''
+		GROUP BY lower(t.tag)
+		ORDER BY count DESC, tag
+		LIMIT ?`, models.VisibilityPublic, models.ModerationStatusTakenDown, models.StatusReady, s.max).Scan(&tags).Error
+	if err != nil {
+		return nil, fmt.Errorf("count tags: %w", err)
+	}
+	return tags, nil
+}
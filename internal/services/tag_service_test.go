@@ -0,0 +1,163 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// tagCounts stands in for the Postgres aggregate: it returns tags, or fails with
+// err, and counts its calls
+type tagCounts struct {
+	tags  []services.TagCount
+	err   error
+	calls int
+}
+
+func (l *tagCounts) load(context.Context) ([]services.TagCount, error) {
+	l.calls++
+	if l.err != nil {
+		return nil, l.err
+	}
+	return l.tags, nil
+}
+
+// fakeClock is a clock that only moves when told to
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func tagService(t *testing.T, counts *tagCounts, clock *fakeClock) *services.TagService {
+	t.Helper()
+	tags := services.NewTagService(dbtest.Open(t), nopLogger(), time.Minute, 100)
+	tags.SetLoader(counts.load, clock.Now)
+	return tags
+}
+
+func tagNames(tags []services.TagCount) string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Tag
+	}
+	return strings.Join(names, ",")
+}
+
+// caseTags are the snapshot the aggregate produces when "Go", "GO" and "go" were
+// grouped under their most common spelling
+var caseTags = []services.TagCount{{Tag: "golang", Count: 9}, {Tag: "Go", Count: 7}, {Tag: "gaming", Count: 4}, {Tag: "GOPHERS", Count: 2}, {Tag: "travel", Count: 1}}
+
+func TestSuggestTagsIgnoresCase(t *testing.T) {
+	tags := tagService(t, &tagCounts{tags: caseTags}, &fakeClock{})
+	ctx := context.Background()
+	tests := []struct {
+		prefix string
+		limit  int
+		want   string
+	}{
+		{"go", 10, "golang,Go,GOPHERS"},
+		{"GO", 10, "golang,Go,GOPHERS"},
+		{"Gop", 10, "GOPHERS"},
+		{"g", 2, "golang,Go"},
+		{"  Tr ", 10, "travel"},
+		{"x", 10, ""},
+	}
+	for _, tt := range tests {
+		got, err := tags.Suggest(ctx, tt.prefix, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == nil || tagNames(got) != tt.want {
+			t.Errorf("Suggest(%q, %d) = %v, want %s", tt.prefix, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestPopularTagsPages(t *testing.T) {
+	tags := tagService(t, &tagCounts{tags: caseTags}, &fakeClock{})
+	ctx := context.Background()
+	tests := []struct {
+		offset, limit int
+		want          string
+	}{
+		{0, 2, "golang,Go"},
+		{2, 2, "gaming,GOPHERS"},
+		{4, 2, "travel"},
+		{5, 2, ""},
+		{50, 2, ""},
+	}
+	for _, tt := range tests {
+		got, total, err := tags.Popular(ctx, tt.offset, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == nil || tagNames(got) != tt.want || total != len(caseTags) {
+			t.Errorf("Popular(%d, %d) = %v of %d, want %s of %d", tt.offset, tt.limit, got, total, tt.want, len(caseTags))
+		}
+	}
+}
+
+func TestTagSnapshotRefresh(t *testing.T) {
+	counts := &tagCounts{err: errors.New("database down")}
+	clock := &fakeClock{now: time.Now()}
+	tags := tagService(t, counts, clock)
+	ctx := context.Background()
+	popular := func() string {
+		t.Helper()
+		got, _, err := tags.Popular(ctx, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tagNames(got)
+	}
+
+	// With nothing to fall back on, the failure is the caller's
+	if _, _, err := tags.Popular(ctx, 0, 10); err == nil {
+		t.Fatal("Popular succeeded without a snapshot")
+	}
+	counts.err, counts.tags = nil, []services.TagCount{{Tag: "cats", Count: 2}}
+	if got := popular(); got != "cats" {
+		t.Fatalf("popular = %s", got)
+	}
+
+	// Within the interval the snapshot is served as is
+	counts.tags = []services.TagCount{{Tag: "dogs", Count: 3}}
+	clock.now = clock.now.Add(59 * time.Second)
+	tags.Suggest(ctx, "d", 10)
+	if got := popular(); got != "cats" || counts.calls != 2 {
+		t.Errorf("popular = %s after %d loads, want the cached cats from 2", got, counts.calls)
+	}
+	clock.now = clock.now.Add(time.Second)
+	if got := popular(); got != "dogs" || counts.calls != 3 {
+		t.Errorf("popular = %s after %d loads, want dogs from a third", got, counts.calls)
+	}
+
+	// A failed refresh keeps the last snapshot, and is retried on the next read
+	counts.err = errors.New("database down")
+	clock.now = clock.now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if got := popular(); got != "dogs" {
+			t.Errorf("popular = %s during an outage, want the previous dogs", got)
+		}
+	}
+	if counts.calls != 5 {
+		t.Errorf("%d loads, want each read during the outage to retry", counts.calls)
+	}
+}
+
+func TestSuggestTagsLimit(t *testing.T) {
+	counts := &tagCounts{}
+	for i := 0; i < 30; i++ {
+		counts.tags = append(counts.tags, services.TagCount{Tag: fmt.Sprintf("tag%02d", i), Count: int64(30 - i)})
+	}
+	tags := tagService(t, counts, &fakeClock{})
+	got, err := tags.Suggest(context.Background(), "TAG", 10)
+	if err != nil || len(got) != 10 || got[0].Tag != "tag00" {
+		t.Errorf("Suggest = %v, %v; want the 10 most used", got, err)
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png" // decode PNG thumbnails
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // decode WebP thumbnails
+	"golang.org/x/sync/singleflight"
+
+	"github.com/streamhive/video-catalog-api/internal/cache"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// ErrThumbnailWidth is returned for a width outside the allowed list
+var ErrThumbnailWidth = errors.New("thumbnail width not allowed")
+
+// ErrNoThumbnail means the video has no stored thumbnail
+var ErrNoThumbnail = errors.New("video has no thumbnail")
+
+// maxThumbnailSource bounds how much of an original thumbnail is read
+const maxThumbnailSource = 10 << 20
+
+// maxThumbnailPixels bounds decoded size, so a small file can't expand into a huge bitmap
+const maxThumbnailPixels = 8000 * 8000
+
+// thumbnailJPEGQuality is used when re-encoding resized thumbnails
+const thumbnailJPEGQuality = 82
+
+// BlobDownloader reads a blob from storage
+type BlobDownloader interface {
+	DownloadBlob(ctx context.Context, blobPath string, maxBytes int64) ([]byte, error)
+}
+
+// Thumbnail is a resized thumbnail ready to serve
+type Thumbnail struct {
+	Data        []byte
+	ContentType string
+	// ETag changes whenever the video, width or stored thumbnail does
+	ETag string
+}
+
+// ThumbnailService serves thumbnails resized to a fixed set of widths. Originals come
+// from blob storage when configured, else from the thumbnail URL (CDN origin).
+// Results are cached in memory by (video, width, updated_at) and resizing runs on a
+// bounded pool.
+type ThumbnailService struct {
+	logger  *zap.SugaredLogger
+	blobs   BlobDownloader
+	client  *http.Client
+	widths  map[int]bool
+	cache   *cache.BytesLRU
+	workers chan struct{}
+	flight  singleflight.Group
+}
+
+// NewThumbnailService creates a thumbnail service. blobs may be nil. At most workers
+// resizes run at once, and cacheBytes bounds the memory cache.
+func NewThumbnailService(logger *zap.SugaredLogger, blobs BlobDownloader, widths []int, workers int, cacheBytes int64, fetchTimeout time.Duration) *ThumbnailService {
+	if workers < 1 {
+		workers = 1
+	}
+	allowed := make(map[int]bool, len(widths))
+	for _, w := range widths {
+		allowed[w] = true
+	}
+	return &ThumbnailService{
+		logger:  logger,
+		blobs:   blobs,
+		client:  &http.Client{Timeout: fetchTimeout},
+		widths:  allowed,
+		cache:   cache.NewBytesLRU(cacheBytes),
+		workers: make(chan struct{}, workers),
+	}
+}
+
+// Widths returns the allowed widths, narrowest first
+func (s *ThumbnailService) Widths() []int {
+	out := make([]int, 0, len(s.widths))
+	for w := range s.widths {
+		out = append(out, w)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Resized returns video's thumbnail scaled to width. Thumbnails narrower than
+// width are returned re-encoded at their own size; they are never upscaled.
+func (s *ThumbnailService) Resized(ctx context.Context, video *models.Video, width int) (*Thumbnail, error) {
+	if !s.widths[width] {
+		return nil, fmt.Errorf("%w: %d", ErrThumbnailWidth, width)
+	}
+	if video.ThumbnailURL == "" {
+		return nil, ErrNoThumbnail
+	}
+	key := fmt.Sprintf("%d:%d:%d", video.ID, width, video.UpdatedAt.UnixNano())
+	thumb := &Thumbnail{ContentType: "image/jpeg", ETag: `"` + key + `"`}
+	if data, ok := s.cache.Get(key); ok {
+		metrics.ThumbnailRequestsTotal.WithLabelValues("hit").Inc()
+		thumb.Data = data
+		return thumb, nil
+	}
+
+	data, err, _ := s.flight.Do(key, func() (interface{}, error) {
+		original, err := s.fetch(ctx, video)
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case s.workers <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		defer func() { <-s.workers }()
+		start := time.Now()
+		resized, err := resizeJPEG(original, width)
+		metrics.ThumbnailResizeDuration.Observe(time.Since(start).Seconds())
+		if err != nil {
+			return nil, err
+		}
+		s.cache.Add(key, resized)
+		metrics.ThumbnailCacheBytes.Set(float64(s.cache.Size()))
+		return resized, nil
+	})
+	if err != nil {
+		metrics.ThumbnailRequestsTotal.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	metrics.ThumbnailRequestsTotal.WithLabelValues("resized").Inc()
+	thumb.Data = data.([]byte)
+	return thumb, nil
+}
+
+// fetch reads the original thumbnail from storage, falling back to its URL. A
+// picked thumbnail is read from its own blob, if it has one in storage.
+func (s *ThumbnailService) fetch(ctx context.Context, video *models.Video) ([]byte, error) {
+	path := thumbnailBlobPath(video)
+	if video.ThumbnailID != nil {
+		path = video.ThumbnailPath
+	}
+	if s.blobs != nil && path != "" {
+		data, err := s.blobs.DownloadBlob(ctx, path, maxThumbnailSource)
+		if err == nil {
+			return data, nil
+		}
+		s.logger.Warnw("Thumbnail blob download failed; trying URL", "error", err, "videoID", video.ID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, video.ThumbnailURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build thumbnail request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch thumbnail: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch thumbnail: origin returned %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxThumbnailSource+1))
+	if err != nil {
+		return nil, fmt.Errorf("read thumbnail: %w", err)
+	}
+	if len(data) > maxThumbnailSource {
+		return nil, fmt.Errorf("thumbnail is larger than %d bytes", maxThumbnailSource)
+	}
+	return data, nil
+}
+
+// resizeJPEG decodes a JPEG, PNG or WebP image, scales it to width keeping the
+// aspect ratio, and encodes it as JPEG
+func resizeJPEG(original []byte, width int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(original))
+	if err != nil {
+		return nil, fmt.Errorf("decode thumbnail: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxThumbnailPixels {
+		return nil, fmt.Errorf("thumbnail is %dx%d, too large to resize", cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(original))
+	if err != nil {
+		return nil, fmt.Errorf("decode thumbnail: %w", err)
+	}
+	bounds := src.Bounds()
+	if bounds.Dx() > width {
+		height := bounds.Dy() * width / bounds.Dx()
+		if height < 1 {
+			height = 1
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+		src = dst
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: thumbnailJPEGQuality}); err != nil {
+		return nil, fmt.Errorf("encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,306 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// testThumbnail draws a w×h image with four solid quadrants and a diagonal
+// gradient band, so scaling errors show up as misplaced or smeared edges
+func testThumbnail(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	quadrants := [4]color.RGBA{{220, 30, 30, 255}, {30, 200, 40, 255}, {30, 60, 220, 255}, {240, 240, 240, 255}}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			q := 0
+			if x >= w/2 {
+				q++
+			}
+			if y >= h/2 {
+				q += 2
+			}
+			c := quadrants[q]
+			if d := x*h/w - y; d >= -h/20 && d <= h/20 {
+				v := uint8(255 * x / w)
+				c = color.RGBA{v, v, v, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// checkGolden compares a resized JPEG with testdata/thumbnails/name.png. JPEG is
+// lossy and encoders differ slightly between Go releases, so pixels may drift a
+// little; misplaced edges or a wrong size fail. Run with -update to rewrite it.
+func checkGolden(t *testing.T, name string, data []byte) {
+	t.Helper()
+	got, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("resized thumbnail is not a JPEG: %v", err)
+	}
+	path := filepath.Join("testdata", "thumbnails", name+".png")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, encodePNG(t, got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("golden file: %v (run go test -update to create it)", err)
+	}
+	defer f.Close()
+	want, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("size %v, golden %v", got.Bounds(), want.Bounds())
+	}
+	var total, worst int
+	b := got.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			gr, gg, gb, _ := got.At(x, y).RGBA()
+			wr, wg, wb, _ := want.At(x, y).RGBA()
+			for _, d := range []int{absDiff(gr, wr), absDiff(gg, wg), absDiff(gb, wb)} {
+				total += d
+				if d > worst {
+					worst = d
+				}
+			}
+		}
+	}
+	if mean := float64(total) / float64(3*b.Dx()*b.Dy()); mean > 2 || worst > 48 {
+		t.Errorf("differs from %s: mean channel error %.2f, worst %d", path, mean, worst)
+	}
+}
+
+// absDiff compares 16-bit channel values on an 8-bit scale
+func absDiff(a, b uint32) int {
+	d := int(a>>8) - int(b>>8)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// pngHeader returns the start of a PNG claiming to be w×h: enough for
+// image.DecodeConfig, with none of the pixel data
+func pngHeader(w, h uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], w)
+	binary.BigEndian.PutUint32(ihdr[8:], h)
+	ihdr[12], ihdr[13] = 8, 0 // 8-bit grayscale
+	out := []byte("\x89PNG\r\n\x1a\n")
+	out = binary.BigEndian.AppendUint32(out, 13)
+	out = append(out, ihdr...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(ihdr))
+}
+
+func thumbnailVideo(id uint) *models.Video {
+	return &models.Video{ID: id, UserID: "owner", UploadID: "up-1", ThumbnailURL: "https://cdn.example/thumbs/up-1.jpg", UpdatedAt: time.Unix(1700000000, 0)}
+}
+
+func TestThumbnailResizeGolden(t *testing.T) {
+	storage := newFakeStorage()
+	video := thumbnailVideo(1)
+	storage.blobs["thumbnails/owner/up-1.jpg"] = encodePNG(t, testThumbnail(1280, 720))
+	thumbs := services.NewThumbnailService(nopLogger(), storage, []int{160, 320, 2560}, 2, 1<<20, time.Second)
+
+	for _, width := range []int{160, 320} {
+		thumb, err := thumbs.Resized(context.Background(), video, width)
+		if err != nil {
+			t.Fatalf("Resized(%d): %v", width, err)
+		}
+		if thumb.ContentType != "image/jpeg" {
+			t.Errorf("content type %q", thumb.ContentType)
+		}
+		checkGolden(t, "quadrants-"+strconv.Itoa(width), thumb.Data)
+	}
+
+	// Thumbnails are never upscaled
+	thumb, err := thumbs.Resized(context.Background(), video, 2560)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb.Data))
+	if err != nil || cfg.Width != 1280 || cfg.Height != 720 {
+		t.Errorf("2560 wide request gave %dx%d (%v), want the original 1280x720", cfg.Width, cfg.Height, err)
+	}
+}
+
+func TestThumbnailRejects(t *testing.T) {
+	storage := newFakeStorage()
+	thumbs := services.NewThumbnailService(nopLogger(), storage, []int{320}, 1, 1<<20, time.Second)
+	ctx := context.Background()
+
+	if _, err := thumbs.Resized(ctx, thumbnailVideo(1), 321); !errors.Is(err, services.ErrThumbnailWidth) {
+		t.Errorf("width outside the list: err = %v", err)
+	}
+	if _, err := thumbs.Resized(ctx, &models.Video{ID: 2}, 320); !errors.Is(err, services.ErrNoThumbnail) {
+		t.Errorf("video without thumbnail: err = %v", err)
+	}
+	if got := thumbs.Widths(); len(got) != 1 || got[0] != 320 {
+		t.Errorf("Widths = %v", got)
+	}
+
+	// A small file that decodes to a huge bitmap is refused before decoding
+	huge := thumbnailVideo(3)
+	storage.blobs["thumbnails/owner/up-1.jpg"] = pngHeader(9000, 9000)
+	if _, err := thumbs.Resized(ctx, huge, 320); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("9000x9000 thumbnail: err = %v, want it refused as too large", err)
+	}
+	garbage := thumbnailVideo(4)
+	storage.blobs["thumbnails/owner/up-1.jpg"] = []byte("not an image")
+	if _, err := thumbs.Resized(ctx, garbage, 320); err == nil {
+		t.Error("undecodable thumbnail was resized")
+	}
+}
+
+func TestThumbnailCache(t *testing.T) {
+	storage := newFakeStorage()
+	storage.blobs["thumbnails/owner/up-1.jpg"] = encodePNG(t, testThumbnail(640, 360))
+	thumbs := services.NewThumbnailService(nopLogger(), storage, []int{320}, 1, 1<<20, time.Second)
+	ctx := context.Background()
+	video := thumbnailVideo(1)
+
+	first, err := thumbs.Resized(ctx, video, 320)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := thumbs.Resized(ctx, video, 320)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storage.downloadCount() != 1 || !bytes.Equal(first.Data, second.Data) || first.ETag != second.ETag {
+		t.Errorf("%d downloads for two requests, want the second served from cache", storage.downloadCount())
+	}
+
+	// An edited video is a new cache key and a new ETag
+	edited := *video
+	edited.UpdatedAt = video.UpdatedAt.Add(time.Second)
+	third, err := thumbs.Resized(ctx, &edited, 320)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storage.downloadCount() != 2 || third.ETag == first.ETag {
+		t.Errorf("edited video: %d downloads, ETag %s (was %s)", storage.downloadCount(), third.ETag, first.ETag)
+	}
+}
+
+func TestThumbnailConcurrentMisses(t *testing.T) {
+	storage := newFakeStorage()
+	storage.blobs["thumbnails/owner/up-1.jpg"] = encodePNG(t, testThumbnail(640, 360))
+	thumbs := services.NewThumbnailService(nopLogger(), storage, []int{320}, 1, 1<<20, time.Second)
+	video := thumbnailVideo(1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := thumbs.Resized(context.Background(), video, 320); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	// Concurrent misses for one key share a download; a straggler may find the cache
+	if n := storage.downloadCount(); n != 1 {
+		t.Errorf("%d downloads for one thumbnail", n)
+	}
+}
+
+func TestThumbnailSources(t *testing.T) {
+	var originHits atomic.Int32
+	original := encodePNG(t, testThumbnail(640, 360))
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		originHits.Add(1)
+		if r.URL.Path != "/thumbs/up-1.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(original)
+	}))
+	defer origin.Close()
+	ctx := context.Background()
+
+	t.Run("URL when the blob is missing", func(t *testing.T) {
+		storage := newFakeStorage()
+		thumbs := services.NewThumbnailService(nopLogger(), storage, []int{320}, 1, 1<<20, time.Second)
+		video := thumbnailVideo(1)
+		video.ThumbnailURL = origin.URL + "/thumbs/up-1.jpg"
+		before := originHits.Load()
+		if _, err := thumbs.Resized(ctx, video, 320); err != nil {
+			t.Fatal(err)
+		}
+		if storage.downloadCount() != 1 || originHits.Load() != before+1 {
+			t.Errorf("blob tried %d times, origin %d times; want one each", storage.downloadCount(), originHits.Load()-before)
+		}
+	})
+	t.Run("URL without storage", func(t *testing.T) {
+		thumbs := services.NewThumbnailService(nopLogger(), nil, []int{320}, 1, 1<<20, time.Second)
+		video := thumbnailVideo(1)
+		video.ThumbnailURL = origin.URL + "/thumbs/up-1.jpg"
+		if _, err := thumbs.Resized(ctx, video, 320); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("origin error", func(t *testing.T) {
+		thumbs := services.NewThumbnailService(nopLogger(), nil, []int{320}, 1, 1<<20, time.Second)
+		video := thumbnailVideo(1)
+		video.ThumbnailURL = origin.URL + "/thumbs/missing.jpg"
+		if _, err := thumbs.Resized(ctx, video, 320); err == nil {
+			t.Error("404 from the origin was resized")
+		}
+	})
+	t.Run("picked thumbnail reads its own blob", func(t *testing.T) {
+		storage := newFakeStorage()
+		storage.blobs["thumbnails/owner/up-1/custom.png"] = original
+		thumbs := services.NewThumbnailService(nopLogger(), storage, []int{320}, 1, 1<<20, time.Second)
+		video := thumbnailVideo(1)
+		picked := uint(7)
+		video.ThumbnailID, video.ThumbnailPath = &picked, "thumbnails/owner/up-1/custom.png"
+		video.ThumbnailURL = origin.URL + "/thumbs/missing.jpg"
+		if _, err := thumbs.Resized(ctx, video, 320); err != nil {
+			t.Fatalf("picked thumbnail: %v", err)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+	"github.com/streamhive/video-catalog-api/internal/spantest"
+	"github.com/streamhive/video-catalog-api/internal/tracing"
+)
+
+// attr returns the span's value for key, or an empty value
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestHandleEventSpans(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	spans := spantest.Record(t)
+
+	// The consumer's span for the delivery
+	ctx, delivery := tracing.Start(context.Background(), "video.uploaded process")
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-1", UserID: "alice", Title: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	// A failed event marks its span as failed
+	db.Migrator().DropTable(&models.Video{})
+	if err := videos.HandleTranscodedEvent(ctx, &models.TranscodedEvent{UploadID: "up-1", UserID: "alice"}); err == nil {
+		t.Fatal("HandleTranscodedEvent succeeded without a videos table")
+	}
+	delivery.End()
+
+	for name, wantErr := range map[string]bool{
+		"VideoService.HandleUploadedEvent":   false,
+		"VideoService.HandleTranscodedEvent": true,
+	} {
+		got := spantest.Named(spans, name)
+		if len(got) != 1 {
+			t.Errorf("%d %s spans, want 1", len(got), name)
+			continue
+		}
+		span := got[0]
+		if span.Parent().SpanID() != delivery.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of the delivery span", name)
+		}
+		if v := attr(span, "catalog.upload_id").AsString(); v == "" {
+			t.Errorf("%s has no catalog.upload_id", name)
+		}
+		if failed := span.Status().Code == codes.Error; failed != wantErr {
+			t.Errorf("%s status %v, want error %v", name, span.Status(), wantErr)
+		}
+	}
+}
+
+func TestStorageSpans(t *testing.T) {
+	adapter, backend := azureAdapter(t, "raw/owner/up-1.mp4")
+	spans := spantest.Record(t)
+	ctx, parent := tracing.Start(context.Background(), "purge")
+	if err := adapter.DeleteBlob(ctx, "raw/owner/up-1.mp4"); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+	if backend.blobs["raw/owner/up-1.mp4"] {
+		t.Fatal("blob not deleted")
+	}
+
+	got := spantest.Named(spans, "azure-client DeleteBlob")
+	if len(got) != 1 {
+		t.Fatalf("%d DeleteBlob spans, want 1", len(got))
+	}
+	span := got[0]
+	if span.SpanKind() != trace.SpanKindClient || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("DeleteBlob span kind %s, parent %s; want a client span under the caller's", span.SpanKind(), span.Parent().SpanID())
+	}
+	if attr(span, "storage.path").AsString() != "raw/owner/up-1.mp4" || attr(span, "storage.attempts").AsInt64() != 1 {
+		t.Errorf("DeleteBlob span attributes = %v", span.Attributes())
+	}
+}
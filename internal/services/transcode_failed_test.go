@@ -0,0 +1,156 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestTranscodeFailedBeforeUpload(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+
+	// The failure overtakes the upload event: a placeholder is created already failed
+	if err := videos.HandleTranscodeFailedEvent(ctx, &models.TranscodeFailedEvent{UploadID: "up-1", UserID: "owner", ErrorMessage: "  unsupported codec  "}); err != nil {
+		t.Fatalf("HandleTranscodeFailedEvent: %v", err)
+	}
+	video, err := videos.GetVideoByUploadID(ctx, "up-1")
+	if err != nil {
+		t.Fatalf("placeholder: %v", err)
+	}
+	if video.Status != models.StatusFailed || video.FailureReason != "unsupported codec" || video.UserID != "owner" || video.Title != "Untitled Video" {
+		t.Errorf("placeholder = status %s, reason %q, user %q, title %q", video.Status, video.FailureReason, video.UserID, video.Title)
+	}
+
+	// The late upload event fills in the metadata but leaves the failure alone
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-1", UserID: "owner", Title: "Holiday", Description: "beach"}); err != nil {
+		t.Fatalf("HandleUploadedEvent: %v", err)
+	}
+	video, err = videos.GetVideo(ctx, video.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if video.Status != models.StatusFailed || video.FailureReason != "unsupported codec" {
+		t.Errorf("after upload event: status %s, reason %q; want failed with the reason kept", video.Status, video.FailureReason)
+	}
+	if video.Title != "Holiday" || video.Description != "beach" {
+		t.Errorf("upload metadata not applied: title %q, description %q", video.Title, video.Description)
+	}
+	var count int64
+	db.Model(&models.Video{}).Where("upload_id = ?", "up-1").Count(&count)
+	if count != 1 {
+		t.Errorf("%d rows for the upload, want 1", count)
+	}
+}
+
+func TestTranscodeFailedAfterUpload(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-1", UserID: "owner", Title: "Holiday"}); err != nil {
+		t.Fatalf("HandleUploadedEvent: %v", err)
+	}
+	if err := videos.HandleTranscodeFailedEvent(ctx, &models.TranscodeFailedEvent{UploadID: "up-1", UserID: "owner", ErrorMessage: "ffmpeg exited with 1"}); err != nil {
+		t.Fatalf("HandleTranscodeFailedEvent: %v", err)
+	}
+	video, err := videos.GetVideoByUploadID(ctx, "up-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if video.Status != models.StatusFailed || video.FailureReason != "ffmpeg exited with 1" || video.Title != "Holiday" {
+		t.Errorf("video = status %s, reason %q, title %q", video.Status, video.FailureReason, video.Title)
+	}
+
+	// A late success doesn't resurrect a failed video on its own
+	transcoded := &models.TranscodedEvent{UploadID: "up-1", UserID: "owner", Ready: true,
+		HLS: models.HLSInfo{MasterURL: "https://cdn.example/up-1/master.m3u8"}}
+	if err := videos.HandleTranscodedEvent(ctx, transcoded); err == nil {
+		t.Error("transcoded event moved a failed video to ready")
+	}
+
+	// Once an admin requeues it, the retried transcode clears the failure
+	if _, err := videos.SetVideoStatus(ctx, video.ID, models.StatusProcessing, "admin", "retry"); err != nil {
+		t.Fatalf("SetVideoStatus: %v", err)
+	}
+	if err := videos.HandleTranscodedEvent(ctx, transcoded); err != nil {
+		t.Fatalf("HandleTranscodedEvent: %v", err)
+	}
+	video, err = videos.GetVideo(ctx, video.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if video.Status != models.StatusReady || video.FailureReason != "" {
+		t.Errorf("after retry: status %s, reason %q; want ready with no reason", video.Status, video.FailureReason)
+	}
+
+	// A stale failure for a video that is already ready is ignored
+	if err := videos.HandleTranscodeFailedEvent(ctx, &models.TranscodeFailedEvent{UploadID: "up-1", UserID: "owner", ErrorMessage: "late"}); err != nil {
+		t.Fatalf("HandleTranscodeFailedEvent: %v", err)
+	}
+	video, err = videos.GetVideo(ctx, video.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if video.Status != models.StatusReady || video.FailureReason != "" {
+		t.Errorf("late failure applied: status %s, reason %q", video.Status, video.FailureReason)
+	}
+}
+
+func TestTranscodeFailedReason(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+
+	tests := []struct {
+		name, message, want string
+	}{
+		{"empty", "", "transcode failed"},
+		{"blank", " \n\t", "transcode failed"},
+		{"long", strings.Repeat("é", 2500), strings.Repeat("é", 2000)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			uploadID := "up-" + tc.name
+			if err := videos.HandleTranscodeFailedEvent(ctx, &models.TranscodeFailedEvent{UploadID: uploadID, UserID: "owner", ErrorMessage: tc.message}); err != nil {
+				t.Fatalf("HandleTranscodeFailedEvent: %v", err)
+			}
+			video, err := videos.GetVideoByUploadID(ctx, uploadID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if video.FailureReason != tc.want {
+				t.Errorf("reason has %d runes, want %d", len([]rune(video.FailureReason)), len([]rune(tc.want)))
+			}
+		})
+	}
+
+	for _, event := range []*models.TranscodeFailedEvent{{UserID: "owner"}, {UploadID: "up-x"}} {
+		if err := videos.HandleTranscodeFailedEvent(ctx, event); err == nil {
+			t.Errorf("event %+v accepted without upload or user ID", event)
+		}
+	}
+}
+
+func TestFailureReasonJSON(t *testing.T) {
+	failed, err := json.Marshal(models.Video{Status: models.StatusFailed, FailureReason: "unsupported codec"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(failed), `"failure_reason":"unsupported codec"`) {
+		t.Errorf("failed video JSON lacks failure_reason: %s", failed)
+	}
+	ready, err := json.Marshal(models.Video{Status: models.StatusReady})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(ready), "failure_reason") {
+		t.Errorf("ready video JSON has failure_reason: %s", ready)
+	}
+}
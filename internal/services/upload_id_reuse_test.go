@@ -0,0 +1,175 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// deletedVideo creates a video for uploadID, soft-deletes it through the service
+// and returns the deleted row
+func deletedVideo(t *testing.T, db *gorm.DB, videos *services.VideoService, uploadID string) *models.Video {
+	t.Helper()
+	video := createVideo(t, db, models.Video{Title: uploadID, UploadID: uploadID, Status: models.StatusReady})
+	if _, err := videos.DeleteVideoForUser(context.Background(), video.ID, "owner"); err != nil {
+		t.Fatalf("delete video: %v", err)
+	}
+	var deleted models.Video
+	if err := db.Unscoped().First(&deleted, video.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &deleted
+}
+
+func TestUploadIDUniqueAmongLiveVideos(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	deletedVideo(t, db, videos, "up-1")
+
+	// A deleted video no longer holds its upload ID
+	video, err := videos.CreateVideo(context.Background(), "owner", &models.VideoCreateRequest{UploadID: "up-1", Title: "again"})
+	if err != nil {
+		t.Fatalf("create over a deleted upload: %v", err)
+	}
+	// Two live videos still can't share one
+	_, err = videos.CreateVideo(context.Background(), "owner", &models.VideoCreateRequest{UploadID: "up-1", Title: "third"})
+	var dup *services.DuplicateUploadError
+	if !errors.As(err, &dup) || dup.VideoID != video.ID {
+		t.Errorf("second live video: err = %v, want a duplicate naming video %d", err, video.ID)
+	}
+	if err := db.Create(&models.Video{UploadID: "up-1", UserID: "owner", Title: "raw"}).Error; !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("insert past the service: err = %v, want the index to refuse it", err)
+	}
+}
+
+func TestRestoreWithUploadIDTaken(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// holder is the state of the other video with the same upload ID, if any
+		holder  string
+		wantErr error
+	}{
+		{"no other video", "", nil},
+		{"live video holds the upload ID", "live", services.ErrDuplicateUploadID},
+		{"other video is deleted too", "deleted", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db := dbtest.Open(t)
+			videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+			old := deletedVideo(t, db, videos, "up-1")
+			var holder *models.Video
+			switch tc.holder {
+			case "live":
+				holder = createVideo(t, db, models.Video{Title: "new", UploadID: "up-1"})
+			case "deleted":
+				holder = deletedVideo(t, db, videos, "up-1")
+			}
+
+			restored, err := videos.RestoreVideo(ctx, old.ID, "owner")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("RestoreVideo: err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				var dup *services.DuplicateUploadError
+				if !errors.As(err, &dup) || dup.VideoID != holder.ID {
+					t.Errorf("err = %v, want it to name video %d", err, holder.ID)
+				}
+				if _, err := videos.GetVideo(ctx, old.ID); !errors.Is(err, services.ErrVideoNotFound) {
+					t.Errorf("refused restore left the video visible: %v", err)
+				}
+				return
+			}
+			if restored.ID != old.ID || restored.DeletedAt.Valid {
+				t.Errorf("restored = %+v", restored)
+			}
+		})
+	}
+}
+
+func TestEventsForDeletedUploadsAreIgnored(t *testing.T) {
+	ctx := context.Background()
+	handlers := []struct {
+		kind   string
+		handle func(*services.VideoService, string) error
+	}{
+		{services.EventKindUploaded, func(v *services.VideoService, uploadID string) error {
+			return v.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: uploadID, UserID: "owner", Title: "event"})
+		}},
+		{services.EventKindTranscoded, func(v *services.VideoService, uploadID string) error {
+			return v.HandleTranscodedEvent(ctx, &models.TranscodedEvent{UploadID: uploadID, UserID: "owner", Title: "event", Ready: true,
+				HLS: models.HLSInfo{MasterURL: "https://cdn.example/" + uploadID + "/master.m3u8"}})
+		}},
+		{services.EventKindTranscodeFailed, func(v *services.VideoService, uploadID string) error {
+			return v.HandleTranscodeFailedEvent(ctx, &models.TranscodeFailedEvent{UploadID: uploadID, UserID: "owner", ErrorMessage: "codec"})
+		}},
+	}
+	for _, h := range handlers {
+		t.Run(h.kind+"/only a deleted video", func(t *testing.T) {
+			db := dbtest.Open(t)
+			videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+			old := deletedVideo(t, db, videos, "up-1")
+			ignored := metrics.EventsIgnoredTotal.WithLabelValues(h.kind, "soft_deleted")
+			before := testutil.ToFloat64(ignored)
+
+			if err := h.handle(videos, "up-1"); err != nil {
+				t.Fatalf("handle: %v", err)
+			}
+			if got := testutil.ToFloat64(ignored) - before; got != 1 {
+				t.Errorf("ignored counter +%v, want +1", got)
+			}
+			var rows []models.Video
+			db.Unscoped().Where("upload_id = ?", "up-1").Find(&rows)
+			if len(rows) != 1 || rows[0].ID != old.ID || !rows[0].DeletedAt.Valid || rows[0].Status != models.StatusReady {
+				t.Errorf("rows = %+v, want only the deleted video, untouched", rows)
+			}
+		})
+		t.Run(h.kind+"/deleted and live videos", func(t *testing.T) {
+			db := dbtest.Open(t)
+			videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+			old := deletedVideo(t, db, videos, "up-1")
+			live := createVideo(t, db, models.Video{Title: "Untitled Video", UploadID: "up-1", Status: models.StatusProcessing})
+			ignored := metrics.EventsIgnoredTotal.WithLabelValues(h.kind, "soft_deleted")
+			before := testutil.ToFloat64(ignored)
+
+			if err := h.handle(videos, "up-1"); err != nil {
+				t.Fatalf("handle: %v", err)
+			}
+			if got := testutil.ToFloat64(ignored) - before; got != 0 {
+				t.Errorf("ignored counter +%v for an event with a live video", got)
+			}
+			got, err := videos.GetVideo(ctx, live.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Version == live.Version {
+				t.Errorf("the live video wasn't updated by the event")
+			}
+			var deleted models.Video
+			db.Unscoped().First(&deleted, old.ID)
+			if !deleted.DeletedAt.Valid || deleted.Version != old.Version {
+				t.Errorf("deleted video changed: %+v", deleted)
+			}
+		})
+	}
+
+	t.Run("unknown upload", func(t *testing.T) {
+		db := dbtest.Open(t)
+		videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+		if err := handlers[0].handle(videos, "up-new"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := videos.GetVideoByUploadID(ctx, "up-new"); err != nil {
+			t.Errorf("a new upload wasn't created: %v", err)
+		}
+	})
+}
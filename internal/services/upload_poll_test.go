@@ -0,0 +1,52 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestUploadPollSeesVideoRightAfterEvent(t *testing.T) {
+	ctx := context.Background()
+	videos := services.NewVideoService(dbtest.Open(t), nil, videoSettings, nopLogger())
+
+	for i := 0; i < 2; i++ {
+		if _, err := videos.GetVideoByUploadID(ctx, "upload-1"); !errors.Is(err, services.ErrVideoNotFound) {
+			t.Fatalf("poll %d before the event: err = %v, want ErrVideoNotFound", i, err)
+		}
+	}
+
+	event := &models.UploadedEvent{UploadID: "upload-1", UserID: "owner", Title: "Holiday"}
+	if err := videos.HandleUploadedEvent(ctx, event); err != nil {
+		t.Fatalf("HandleUploadedEvent: %v", err)
+	}
+
+	// The miss cached by the polls above must not outlive the created row
+	video, err := videos.GetVideoByUploadID(ctx, "upload-1")
+	if err != nil {
+		t.Fatalf("poll after the event: %v", err)
+	}
+	if video.Title != "Holiday" || video.Status != models.StatusProcessing {
+		t.Errorf("video = %q %s, want Holiday processing", video.Title, video.Status)
+	}
+}
+
+func TestUploadPollAfterTranscodedPlaceholder(t *testing.T) {
+	ctx := context.Background()
+	videos := services.NewVideoService(dbtest.Open(t), nil, videoSettings, nopLogger())
+
+	if _, err := videos.GetVideoByUploadID(ctx, "upload-2"); !errors.Is(err, services.ErrVideoNotFound) {
+		t.Fatalf("poll before the event: err = %v, want ErrVideoNotFound", err)
+	}
+	event := &models.TranscodedEvent{UploadID: "upload-2", UserID: "owner", HLS: models.HLSInfo{MasterURL: "https://cdn.example/upload-2/master.m3u8"}, Ready: true}
+	if err := videos.HandleTranscodedEvent(ctx, event); err != nil {
+		t.Fatalf("HandleTranscodedEvent: %v", err)
+	}
+	if _, err := videos.GetVideoByUploadID(ctx, "upload-2"); err != nil {
+		t.Fatalf("poll after the transcoded event: %v", err)
+	}
+}
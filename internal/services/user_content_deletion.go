@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// ErrContentDeletionNotFound means no content deletion job has the ID for the user
+var ErrContentDeletionNotFound = errors.New("content deletion job not found")
+
+// contentDeletionStale is how long a running job may go without progress before a
+// restarted replica takes it to have died with its process
+const contentDeletionStale = 15 * time.Minute
+
+// ContentDeletionService purges a deleted account's catalog footprint. Videos go
+// through the video deletion jobs with no restore window; comments are anonymized
+// in place, so the threads they sit in survive. Reactions, views and the public
+// event log are left alone.
+type ContentDeletionService struct {
+	db     *gorm.DB
+	logger *zap.SugaredLogger
+	videos *VideoService
+	// batch bounds how many videos or comments one step handles
+	batch int
+	ctx   context.Context
+}
+
+// NewContentDeletionService creates the service; batch bounds each step's rows
+func NewContentDeletionService(db *gorm.DB, logger *zap.SugaredLogger, videos *VideoService, batch int) *ContentDeletionService {
+	if batch < 1 {
+		batch = 100
+	}
+	return &ContentDeletionService{db: db, logger: logger, videos: videos, batch: batch, ctx: context.Background()}
+}
+
+// Start sets the context jobs run under and resumes jobs that are pending, or that
+// were left running by a process that died
+func (s *ContentDeletionService) Start(ctx context.Context) error {
+	s.ctx = ctx
+	if err := s.db.WithContext(ctx).Model(&models.UserContentDeletion{}).
+		Where("status = ? AND updated_at < ?", models.DeletionRunning, time.Now().UTC().Add(-contentDeletionStale)).
+		UpdateColumn("status", models.DeletionPending).Error; err != nil {
+		return fmt.Errorf("requeue interrupted content deletions: %w", err)
+	}
+	var pending []models.UserContentDeletion
+	if err := s.db.WithContext(ctx).Where("status = ?", models.DeletionPending).Find(&pending).Error; err != nil {
+		return fmt.Errorf("load pending content deletions: %w", err)
+	}
+	for _, job := range pending {
+		go s.run(job.ID)
+	}
+	return nil
+}
+
+// DeleteUserContent queues the purge of userID's content. If a job for them is
+// already pending or running it is returned instead and created is false.
+func (s *ContentDeletionService) DeleteUserContent(ctx context.Context, userID, actorID, trigger string) (job *models.UserContentDeletion, created bool, err error) {
+	var existing models.UserContentDeletion
+	err = s.db.WithContext(ctx).
+		Where("user_id = ? AND status IN ?", userID, []string{models.DeletionPending, models.DeletionRunning}).
+		Order("id DESC").First(&existing).Error
+	if err == nil {
+		return &existing, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("check running content deletions: %w", err)
+	}
+
+	job = &models.UserContentDeletion{UserID: userID, ActorID: actorID, Trigger: trigger, Status: models.DeletionPending}
+	if err := s.db.WithContext(ctx).Model(&models.Video{}).Where("user_id = ?", userID).Count(&job.VideosTotal).Error; err != nil {
+		return nil, false, fmt.Errorf("count videos: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Unscoped().Model(&models.Comment{}).Where("user_id = ?", userID).Count(&job.CommentsTotal).Error; err != nil {
+		return nil, false, fmt.Errorf("count comments: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, false, fmt.Errorf("create content deletion job: %w", err)
+	}
+	go s.run(job.ID)
+	return job, true, nil
+}
+
+// GetDeletion returns one of userID's content deletion jobs with the status of
+// their video purges
+func (s *ContentDeletionService) GetDeletion(ctx context.Context, userID string, id uint) (*models.UserContentDeletion, error) {
+	var job models.UserContentDeletion
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("content deletion %d: %w", id, ErrContentDeletionNotFound)
+		}
+		return nil, fmt.Errorf("get content deletion job: %w", err)
+	}
+	var counts []struct {
+		Status string
+		Count  int64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.VideoDeletion{}).Select("status, COUNT(*) AS count").
+		Where("user_id = ? AND status <> ?", userID, models.DeletionCancelled).
+		Group("status").Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("count video purges: %w", err)
+	}
+	job.VideoPurges = make(map[string]int64, len(counts))
+	for _, c := range counts {
+		job.VideoPurges[c.Status] = c.Count
+	}
+	return &job, nil
+}
+
+// RetryDeletion requeues a failed job; it resumes where it stopped
+func (s *ContentDeletionService) RetryDeletion(ctx context.Context, userID string, id uint) (*models.UserContentDeletion, error) {
+	res := s.db.WithContext(ctx).Model(&models.UserContentDeletion{}).
+		Where("id = ? AND user_id = ? AND status = ?", id, userID, models.DeletionFailed).
+		Updates(map[string]interface{}{"status": models.DeletionPending, "finished_at": nil})
+	if res.Error != nil {
+		return nil, fmt.Errorf("retry content deletion job: %w", res.Error)
+	}
+	job, err := s.GetDeletion(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("content deletion %d is %s: %w", id, job.Status, ErrDeletionNotFailed)
+	}
+	go s.run(id)
+	return job, nil
+}
+
+// run claims a pending job and works through its steps
+func (s *ContentDeletionService) run(id uint) {
+	ctx := s.ctx
+	now := time.Now().UTC()
+	res := s.db.WithContext(ctx).Model(&models.UserContentDeletion{}).
+		Where("id = ? AND status = ?", id, models.DeletionPending).
+		Updates(map[string]interface{}{
+			"status":     models.DeletionRunning,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"started_at": now,
+			"error":      "",
+		})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return // claimed elsewhere
+	}
+	var job models.UserContentDeletion
+	if err := s.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		s.logger.Errorw("Failed to load content deletion job", "error", err, "deletionID", id)
+		return
+	}
+
+	err := s.purge(ctx, &job)
+	finished := time.Now().UTC()
+	updates := map[string]interface{}{"status": models.DeletionCompleted, "finished_at": finished}
+	if err != nil {
+		updates = map[string]interface{}{"status": models.DeletionFailed, "error": err.Error(), "finished_at": finished}
+		s.logger.Errorw("User content deletion failed", "error", err, "deletionID", id, "userID", job.UserID)
+	}
+	// Record the outcome even when shutdown interrupted the job
+	if err := s.db.WithContext(context.WithoutCancel(ctx)).Model(&job).Updates(updates).Error; err != nil {
+		s.logger.Errorw("Failed to record content deletion outcome", "error", err, "deletionID", id)
+		return
+	}
+	if err == nil {
+		s.logger.Infow("User content deleted", "deletionID", id, "userID", job.UserID,
+			"videos", job.VideosDeleted, "comments", job.CommentsAnonymized)
+	}
+}
+
+// purge runs each step until it finds nothing left, saving progress per batch
+func (s *ContentDeletionService) purge(ctx context.Context, job *models.UserContentDeletion) error {
+	actorID := job.ActorID
+	if actorID == "" {
+		actorID = ActorSystem
+	}
+	for {
+		n, err := s.videos.deleteUserVideos(ctx, job.UserID, actorID, s.batch)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		if err := s.progress(ctx, job, "videos_deleted", n); err != nil {
+			return err
+		}
+	}
+	// Videos deleted earlier, by the user or before a failed attempt, skip their
+	// restore window too
+	if err := s.videos.expediteUserPurges(ctx, job.UserID); err != nil {
+		return err
+	}
+	for {
+		n, err := s.anonymizeComments(ctx, job.UserID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		if err := s.progress(ctx, job, "comments_anonymized", n); err != nil {
+			return err
+		}
+	}
+	return s.anonymizeNotifications(ctx, job.UserID)
+}
+
+// progress adds n to a counter column, which also marks the job as alive
+func (s *ContentDeletionService) progress(ctx context.Context, job *models.UserContentDeletion, column string, n int64) error {
+	if err := s.db.WithContext(ctx).Model(job).UpdateColumns(map[string]interface{}{
+		column:       gorm.Expr(column+" + ?", n),
+		"updated_at": time.Now().UTC(),
+	}).Error; err != nil {
+		return fmt.Errorf("record progress: %w", err)
+	}
+	switch column {
+	case "videos_deleted":
+		job.VideosDeleted += n
+	case "comments_anonymized":
+		job.CommentsAnonymized += n
+	}
+	return nil
+}
+
+// anonymizeComments rewrites one batch of userID's comments, soft-deleted ones
+// included, as written by nobody. The batch spans videos and bypasses the
+// per-comment counter upkeep, so their counters are flagged for the repair job in
+// the same transaction.
+func (s *ContentDeletionService) anonymizeComments(ctx context.Context, userID string) (int64, error) {
+	var anonymized int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var batch []models.Comment
+		if err := tx.Unscoped().Select("id", "video_id").Where("user_id = ?", userID).
+			Order("id").Limit(s.batch).Find(&batch).Error; err != nil {
+			return fmt.Errorf("list comments: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(batch))
+		videos := map[uint]bool{}
+		var videoIDs []uint
+		for i, c := range batch {
+			ids[i] = c.ID
+			if !videos[c.VideoID] {
+				videos[c.VideoID] = true
+				videoIDs = append(videoIDs, c.VideoID)
+			}
+		}
+		res := tx.Exec("UPDATE comments SET user_id = ?, username = '', content = ?, updated_at = ? WHERE id IN ?",
+			models.DeletedUserID, models.DeletedContent, time.Now().UTC(), ids)
+		if res.Error != nil {
+			return fmt.Errorf("anonymize comments: %w", res.Error)
+		}
+		anonymized = res.RowsAffected
+		return MarkCountersDirty(tx, videoIDs...)
+	})
+	return anonymized, err
+}
+
+// anonymizeNotifications deletes the user's own notifications and removes their
+// name from the ones their comments sent to others
+func (s *ContentDeletionService) anonymizeNotifications(ctx context.Context, userID string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Notification{}).Error; err != nil {
+			return fmt.Errorf("delete notifications: %w", err)
+		}
+		// commentMessage starts with the commenter's name
+		if err := tx.Exec(`UPDATE notifications SET actor_id = ?,
+			message = CASE WHEN type = ? AND strpos(message, ' commented on ') > 0
+				THEN 'Someone' || substr(message, strpos(message, ' commented on ')) ELSE message END
+			WHERE actor_id = ?`, models.DeletedUserID, models.NotificationComment, userID).Error; err != nil {
+			return fmt.Errorf("anonymize notifications: %w", err)
+		}
+		return nil
+	})
+}
+
+// deleteUserVideos deletes up to limit of userID's live videos with no restore
+// window, queueing their purge, and returns how many it deleted
+func (s *VideoService) deleteUserVideos(ctx context.Context, userID, actorID string, limit int) (int64, error) {
+	var ids []uint
+	if err := s.db.WithContext(ctx).Model(&models.Video{}).Where("user_id = ?", userID).
+		Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("list user videos: %w", err)
+	}
+	var deleted int64
+	for _, id := range ids {
+		if _, err := s.deleteVideo(ctx, id, actorID, 0); err != nil {
+			// Deleted by someone else since the listing
+			if errors.Is(err, ErrVideoNotFound) {
+				continue
+			}
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// expediteUserPurges ends the restore window of userID's already deleted videos,
+// so their pending purges run on the next worker poll and the purge job queues
+// any that have none
+func (s *VideoService) expediteUserPurges(ctx context.Context, userID string) error {
+	now := time.Now().UTC()
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Video{}).
+			Where("user_id = ? AND deleted_at IS NOT NULL AND (purge_after IS NULL OR purge_after > ?)", userID, now).
+			UpdateColumn("purge_after", now).Error; err != nil {
+			return fmt.Errorf("expedite video purges: %w", err)
+		}
+		if err := tx.Model(&models.VideoDeletion{}).
+			Where("user_id = ? AND status = ? AND run_after > ?", userID, models.DeletionPending, now).
+			UpdateColumn("run_after", now).Error; err != nil {
+			return fmt.Errorf("expedite video deletion jobs: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,206 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// waitForContentDeletion waits for job id to leave pending and running
+func waitForContentDeletion(t *testing.T, db *gorm.DB, id uint) models.UserContentDeletion {
+	t.Helper()
+	var job models.UserContentDeletion
+	waitFor(t, "the content deletion to finish", func() bool {
+		db.First(&job, id)
+		return job.Status == models.DeletionCompleted || job.Status == models.DeletionFailed
+	})
+	return job
+}
+
+func TestDeleteUserContent(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	deletions := services.NewContentDeletionService(db, nopLogger(), videos, 1)
+
+	// gone has two live videos, one deleted in its restore window, and comments
+	// on someone else's video, one of them already deleted
+	live1 := createVideo(t, db, models.Video{UserID: "gone", Title: "live 1"})
+	live2 := createVideo(t, db, models.Video{UserID: "gone", Title: "live 2"})
+	restorable := createVideo(t, db, models.Video{UserID: "gone", Title: "restorable"})
+	if _, err := videos.DeleteVideoForUser(ctx, restorable.ID, "gone"); err != nil {
+		t.Fatal(err)
+	}
+	other := createVideo(t, db, models.Video{UserID: "owner", Title: "other"})
+	kept := createComment(t, db, models.Comment{VideoID: other.ID, UserID: "owner", Username: "Owner", Content: "mine"})
+	c1 := createComment(t, db, models.Comment{VideoID: other.ID, UserID: "gone", Username: "Gone", Content: "first"})
+	c2 := createComment(t, db, models.Comment{VideoID: other.ID, UserID: "gone", Username: "Gone", Content: "reply", ParentID: &kept.ID, Depth: 1})
+	c3 := createComment(t, db, models.Comment{VideoID: other.ID, UserID: "gone", Username: "Gone", Content: "removed"})
+	db.Delete(&c3)
+	db.Create(&[]models.Notification{
+		{UserID: "gone", Type: models.NotificationComment, Message: "x"},
+		{UserID: "owner", Type: models.NotificationComment, ActorID: "gone", Message: `Gone commented on "other"`},
+		{UserID: "owner", Type: models.NotificationComment, ActorID: "fan", Message: `Fan commented on "other"`},
+	})
+
+	job, created, err := deletions.DeleteUserContent(ctx, "gone", "admin-1", models.ContentDeletionTriggerAPI)
+	if err != nil || !created {
+		t.Fatalf("DeleteUserContent = %+v, %v, %v", job, created, err)
+	}
+	if job.VideosTotal != 2 || job.CommentsTotal != 3 {
+		t.Errorf("job totals %d videos, %d comments; want 2 live videos and all 3 comments", job.VideosTotal, job.CommentsTotal)
+	}
+	done := waitForContentDeletion(t, db, job.ID)
+	if done.Status != models.DeletionCompleted || done.VideosDeleted != 2 || done.CommentsAnonymized != 3 || done.Attempts != 1 {
+		t.Fatalf("finished job = %+v", done)
+	}
+
+	// Every video is deleted with no restore window and queued for purging now
+	now := time.Now().UTC().Add(time.Second)
+	for _, id := range []uint{live1.ID, live2.ID, restorable.ID} {
+		var v models.Video
+		db.Unscoped().First(&v, id)
+		if !v.DeletedAt.Valid || v.PurgeAfter == nil || v.PurgeAfter.After(now) {
+			t.Errorf("video %s: deleted %v, purge after %v; want deleted and due now", v.Title, v.DeletedAt.Valid, v.PurgeAfter)
+		}
+		var purge models.VideoDeletion
+		if err := db.Where("video_id = ?", id).First(&purge).Error; err != nil || purge.Status != models.DeletionPending || purge.RunAfter.After(now) {
+			t.Errorf("video %s: purge job %+v, %v; want one pending and due", v.Title, purge, err)
+		}
+	}
+
+	// Comments are rewritten in place, so the thread survives
+	for _, id := range []uint{c1.ID, c2.ID, c3.ID} {
+		var c models.Comment
+		db.Unscoped().First(&c, id)
+		if c.UserID != models.DeletedUserID || c.Username != "" || c.Content != models.DeletedContent {
+			t.Errorf("comment %d = %s/%q/%q, want anonymized", id, c.UserID, c.Username, c.Content)
+		}
+	}
+	var reply models.Comment
+	db.First(&reply, c2.ID)
+	if reply.ParentID == nil || *reply.ParentID != kept.ID {
+		t.Errorf("reply lost its parent: %+v", reply.ParentID)
+	}
+	var mine models.Comment
+	db.First(&mine, kept.ID)
+	if mine.Content != "mine" || mine.UserID != "owner" {
+		t.Errorf("another user's comment changed: %+v", mine)
+	}
+	var dirty models.Video
+	db.First(&dirty, other.ID)
+	if !dirty.CountersDirty {
+		t.Error("commented video's counters not flagged for repair")
+	}
+
+	// Their own notifications go; the ones they caused lose their name
+	var notes []models.Notification
+	db.Order("id").Find(&notes)
+	if len(notes) != 2 || notes[0].ActorID != models.DeletedUserID || notes[0].Message != `Someone commented on "other"` ||
+		notes[1].ActorID != "fan" || notes[1].Message != `Fan commented on "other"` {
+		t.Errorf("notifications = %+v", notes)
+	}
+
+	got, err := deletions.GetDeletion(ctx, "gone", job.ID)
+	if err != nil || got.VideoPurges[models.DeletionPending] != 3 {
+		t.Errorf("GetDeletion = %+v, %v; want 3 pending video purges", got, err)
+	}
+	if _, err := deletions.GetDeletion(ctx, "owner", job.ID); !errors.Is(err, services.ErrContentDeletionNotFound) {
+		t.Errorf("another user's job: %v, want ErrContentDeletionNotFound", err)
+	}
+	if _, err := deletions.RetryDeletion(ctx, "gone", job.ID); !errors.Is(err, services.ErrDeletionNotFailed) {
+		t.Errorf("retry a completed job: %v, want ErrDeletionNotFailed", err)
+	}
+}
+
+// TestDeleteUserContentReturnsActiveJob checks a second request while a job is
+// pending or running gets that job back
+func TestDeleteUserContentReturnsActiveJob(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	deletions := services.NewContentDeletionService(db, nopLogger(), services.NewVideoService(db, nil, videoSettings, nopLogger()), 1)
+	running := models.UserContentDeletion{UserID: "gone", Trigger: models.ContentDeletionTriggerAPI, Status: models.DeletionRunning}
+	db.Create(&running)
+
+	job, created, err := deletions.DeleteUserContent(ctx, "gone", services.ActorSystem, models.ContentDeletionTriggerEvent)
+	if err != nil || created || job.ID != running.ID {
+		t.Errorf("DeleteUserContent = %+v, created %v, %v; want the running job", job, created, err)
+	}
+	var count int64
+	db.Model(&models.UserContentDeletion{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d jobs, want 1", count)
+	}
+}
+
+// TestDeleteUserContentResumes fails a job after its comments and retries it:
+// the retry finishes the remaining step without counting anything twice
+func TestDeleteUserContentResumes(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	deletions := services.NewContentDeletionService(db, nopLogger(), services.NewVideoService(db, nil, videoSettings, nopLogger()), 1)
+	createVideo(t, db, models.Video{UserID: "gone", Title: "v"})
+	other := createVideo(t, db, models.Video{UserID: "owner", Title: "other"})
+	createComment(t, db, models.Comment{VideoID: other.ID, UserID: "gone", Content: "a"})
+	createComment(t, db, models.Comment{VideoID: other.ID, UserID: "gone", Content: "b"})
+	db.Create(&models.Notification{UserID: "gone", Type: models.NotificationComment, Message: "x"})
+	db.Migrator().DropTable(&models.Notification{})
+
+	job, _, err := deletions.DeleteUserContent(ctx, "gone", "admin-1", models.ContentDeletionTriggerAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := waitForContentDeletion(t, db, job.ID)
+	if failed.Status != models.DeletionFailed || failed.Error == "" || failed.VideosDeleted != 1 || failed.CommentsAnonymized != 2 {
+		t.Fatalf("failed job = %+v, want it stopped at the notifications with the rest done", failed)
+	}
+
+	if err := db.AutoMigrate(&models.Notification{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := deletions.RetryDeletion(ctx, "gone", job.ID); err != nil {
+		t.Fatal(err)
+	}
+	done := waitForContentDeletion(t, db, job.ID)
+	if done.Status != models.DeletionCompleted || done.Error != "" || done.Attempts != 2 || done.VideosDeleted != 1 || done.CommentsAnonymized != 2 {
+		t.Errorf("retried job = %+v, want completed on attempt 2 with unchanged counts", done)
+	}
+}
+
+// TestContentDeletionStartResumes checks Start picks up pending jobs and ones a
+// dead process left running, but not ones still making progress
+func TestContentDeletionStartResumes(t *testing.T) {
+	db := dbtest.Open(t)
+	deletions := services.NewContentDeletionService(db, nopLogger(), services.NewVideoService(db, nil, videoSettings, nopLogger()), 10)
+	for _, user := range []string{"pending", "stalled", "alive"} {
+		createVideo(t, db, models.Video{UserID: user, Title: user})
+	}
+	pending := models.UserContentDeletion{UserID: "pending", Trigger: models.ContentDeletionTriggerEvent, Status: models.DeletionPending}
+	stalled := models.UserContentDeletion{UserID: "stalled", Trigger: models.ContentDeletionTriggerEvent, Status: models.DeletionRunning}
+	alive := models.UserContentDeletion{UserID: "alive", Trigger: models.ContentDeletionTriggerEvent, Status: models.DeletionRunning}
+	for _, job := range []*models.UserContentDeletion{&pending, &stalled, &alive} {
+		db.Create(job)
+	}
+	db.Model(&stalled).UpdateColumn("updated_at", time.Now().UTC().Add(-time.Hour))
+
+	if err := deletions.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range []models.UserContentDeletion{pending, stalled} {
+		if done := waitForContentDeletion(t, db, job.ID); done.Status != models.DeletionCompleted || done.VideosDeleted != 1 {
+			t.Errorf("%s job = %+v, want it resumed and completed", job.UserID, done)
+		}
+	}
+	var still models.UserContentDeletion
+	db.First(&still, alive.ID)
+	if still.Status != models.DeletionRunning || still.Attempts != 0 {
+		t.Errorf("job still making progress = %+v, want it left to its process", still)
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// Which soft-deleted videos an admin listing returns
+const (
+	DeletedExclude = "exclude"
+	DeletedInclude = "include"
+	DeletedOnly    = "only"
+)
+
+// ErrInvalidDeletedFilter is returned for a deleted filter other than the constants above
+var ErrInvalidDeletedFilter = errors.New("invalid deleted filter")
+
+// ListAllVideos lists videos for moderation: private ones always, soft-deleted ones
+// as deleted says, optionally narrowed to one owner. Ordering and filters match
+// ListVideos.
+func (s *VideoService) ListAllVideos(ctx context.Context, userID, deleted string, page, perPage int, sort VideoSort, filters VideoFilters) (*models.VideoListResponse, error) {
+	order, err := videoOrder(sort)
+	if err != nil {
+		return nil, err
+	}
+	query := s.db.WithContext(ctx).Unscoped().Model(&models.Video{})
+	switch deleted {
+	case "", DeletedExclude:
+		query = query.Where("deleted_at IS NULL")
+	case DeletedInclude:
+	case DeletedOnly:
+		query = query.Where("deleted_at IS NOT NULL")
+	default:
+		return nil, fmt.Errorf("%w %q: use %s, %s or %s", ErrInvalidDeletedFilter, deleted, DeletedExclude, DeletedInclude, DeletedOnly)
+	}
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if query, err = filters.apply(query); err != nil {
+		return nil, err
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("count videos: %w", err)
+	}
+	var videos []models.Video
+	if err := query.Offset((page - 1) * perPage).Limit(perPage).Order(order).Find(&videos).Error; err != nil {
+		return nil, fmt.Errorf("list videos: %w", err)
+	}
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+	return &models.VideoListResponse{Videos: videos, Total: total, Page: page, PerPage: perPage, TotalPages: totalPages}, nil
+}
+
+// DeleteVideoAsAdmin deletes any video regardless of owner, recording adminID in the
+// public event log. Callers must have checked the admin role and audited the action.
+// The purge is queued to run at once: the owner must not be able to undo a
+// moderation delete.
+func (s *VideoService) DeleteVideoAsAdmin(ctx context.Context, id uint, adminID string) (*models.VideoDeletion, error) {
+	return s.deleteVideo(ctx, id, adminID, 0)
+}
+
+// SetVideoStatus moves a video to status. It skips the ordering checks applied to
+// broker events but not the status state machine: a transition it doesn't allow
+// fails with an InvalidTransitionError. Moving off failed clears the failure
+// reason. The change is recorded in the status history with actorID and reason.
+// If it makes the video watchable, actorID is also recorded in the public event log.
+func (s *VideoService) SetVideoStatus(ctx context.Context, id uint, status models.VideoStatus, actorID, reason string) (*models.Video, error) {
+	if !status.Valid() {
+		return nil, fmt.Errorf("%w %q", ErrInvalidStatus, status)
+	}
+	var video models.Video
+	var before models.Video
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&video, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("video %d: %w", id, ErrVideoNotFound)
+			}
+			return err
+		}
+		if err := checkTransition(ctx, &video, status); err != nil {
+			return err
+		}
+		before = video
+		updates := map[string]interface{}{"status": status, "version": video.Version + 1}
+		if status != models.StatusFailed {
+			updates["failure_reason"] = ""
+		}
+		if err := tx.Model(&video).Updates(updates).Error; err != nil {
+			return err
+		}
+		video.Status = status
+		video.Version++
+		if status != models.StatusFailed {
+			video.FailureReason = ""
+		}
+		if before.Status != video.Status {
+			if err := recordStatusChange(tx, before.Status, &video, models.StatusSourceAPI, actorID, reason); err != nil {
+				return err
+			}
+			if err := enqueueCatalogEvent(tx, s.outbox, VideoStatusChanged, &video); err != nil {
+				return err
+			}
+		}
+		return appendPublishedEvent(tx, before, &video, actorID)
+	})
+	if err != nil {
+		if errors.Is(err, ErrVideoNotFound) || errors.Is(err, ErrInvalidTransition) {
+			return nil, err
+		}
+		s.logger.Errorw("Failed to set video status", "error", err, "videoID", id, "status", status)
+		return nil, fmt.Errorf("set video status: %w", err)
+	}
+	if before.Status != video.Status {
+		s.changes.Emit(ctx, VideoStatusChanged, &video)
+	}
+	return &video, nil
+}
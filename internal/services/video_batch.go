@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// GetVideosByIDs loads the videos with the given IDs in one query and returns them
+// in the order asked for. Missing and soft-deleted IDs are left out, as are repeats
+// after the first. Renditions aren't loaded, as in listings.
+func (s *VideoService) GetVideosByIDs(ctx context.Context, ids []uint) ([]models.Video, error) {
+	if len(ids) == 0 {
+		return []models.Video{}, nil
+	}
+	var found []models.Video
+	if err := s.db.WithContext(ctx).Where("id IN ?", ids).Find(&found).Error; err != nil {
+		s.log(ctx).Errorw("Failed to batch get videos", "error", err, "count", len(ids))
+		return nil, fmt.Errorf("failed to get videos: %w", err)
+	}
+	byID := make(map[uint]models.Video, len(found))
+	for _, v := range found {
+		byID[v.ID] = v
+	}
+	return inRequestOrder(ids, byID), nil
+}
+
+// GetVideosByUploadIDs is GetVideosByIDs keyed by upload ID
+func (s *VideoService) GetVideosByUploadIDs(ctx context.Context, uploadIDs []string) ([]models.Video, error) {
+	if len(uploadIDs) == 0 {
+		return []models.Video{}, nil
+	}
+	var found []models.Video
+	if err := s.db.WithContext(ctx).Where("upload_id IN ?", uploadIDs).Find(&found).Error; err != nil {
+		s.log(ctx).Errorw("Failed to batch get videos by upload ID", "error", err, "count", len(uploadIDs))
+		return nil, fmt.Errorf("failed to get videos: %w", err)
+	}
+	byUploadID := make(map[string]models.Video, len(found))
+	for _, v := range found {
+		byUploadID[v.UploadID] = v
+	}
+	return inRequestOrder(uploadIDs, byUploadID), nil
+}
+
+// inRequestOrder picks the found videos out in the order of keys, once each
+func inRequestOrder[K comparable](keys []K, found map[K]models.Video) []models.Video {
+	out := make([]models.Video, 0, len(found))
+	for _, key := range keys {
+		if v, ok := found[key]; ok {
+			out = append(out, v)
+			delete(found, key)
+		}
+	}
+	return out
+}
@@ -0,0 +1,39 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestGetVideosInRequestOrder(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	a := createVideo(t, db, models.Video{Title: "a"})
+	b := createVideo(t, db, models.Video{Title: "b"})
+	c := createVideo(t, db, models.Video{Title: "c"})
+	db.Delete(&c)
+
+	titles := func(list []models.Video) string {
+		got := ""
+		for _, v := range list {
+			got += v.Title
+		}
+		return got
+	}
+	byID, err := videos.GetVideosByIDs(ctx, []uint{b.ID, 999, c.ID, a.ID, b.ID})
+	if err != nil || titles(byID) != "ba" {
+		t.Errorf("by id = %s, %v; want b then a, deleted and missing left out", titles(byID), err)
+	}
+	byUploadID, err := videos.GetVideosByUploadIDs(ctx, []string{a.UploadID, "nope", b.UploadID, c.UploadID})
+	if err != nil || titles(byUploadID) != "ab" {
+		t.Errorf("by upload id = %s, %v; want a then b", titles(byUploadID), err)
+	}
+	if none, err := videos.GetVideosByIDs(ctx, nil); err != nil || none == nil || len(none) != 0 {
+		t.Errorf("no ids = %#v, %v; want an empty list", none, err)
+	}
+}
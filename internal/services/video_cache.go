@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/cache"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+const (
+	videoCacheKeyPrefix = "catalog:video:"
+	listCacheKeyPrefix  = "catalog:videos:"
+	// listGenerationKey namespaces list entries; bumping it orphans every cached
+	// list at once, since they can't be enumerated to delete
+	listGenerationKey = listCacheKeyPrefix + "gen"
+)
+
+// VideoCache keeps single videos and public listing pages in a shared cache for
+// ttl. Cache errors are logged and treated as misses, so an unavailable cache only
+// costs database reads. A nil *VideoCache caches nothing.
+//
+// Entries are dropped when a video changes, but a read racing the change can
+// store the old row after the drop; such an entry lives at most ttl.
+type VideoCache struct {
+	cache  cache.Cache
+	ttl    time.Duration
+	logger *zap.SugaredLogger
+}
+
+// NewVideoCache caches entries in c for ttl
+func NewVideoCache(c cache.Cache, ttl time.Duration, logger *zap.SugaredLogger) *VideoCache {
+	return &VideoCache{cache: c, ttl: ttl, logger: logger}
+}
+
+// SetCache serves GetVideoCached and public listings from c, and drops their
+// entries on every reported video change. Without one, every read hits the database.
+func (s *VideoService) SetCache(c *VideoCache) {
+	s.cache = c
+	if c != nil {
+		s.changes.Subscribe("video_cache", c.observe)
+	}
+}
+
+// GetVideoCached is GetVideo for read-only responses: the video may come from the
+// cache and be up to the cache TTL stale. Writers must use GetVideo.
+func (s *VideoService) GetVideoCached(ctx context.Context, id uint) (*models.Video, error) {
+	key := videoCacheKey(id)
+	var video models.Video
+	if s.cache.get(ctx, "video", key, &video) {
+		return &video, nil
+	}
+	v, err := s.GetVideo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.set(ctx, key, v)
+	return v, nil
+}
+
+func videoCacheKey(id uint) string {
+	return videoCacheKeyPrefix + strconv.FormatUint(uint64(id), 10)
+}
+
+// listKey names a public listing page under the current list generation. It
+// returns "" when the generation can't be read, which disables caching for the call.
+func (c *VideoCache) listKey(ctx context.Context, page, perPage int, sort VideoSort, filters VideoFilters) string {
+	if c == nil {
+		return ""
+	}
+	gen, _, err := c.cache.Get(ctx, listGenerationKey)
+	if err != nil {
+		c.failed("list", err)
+		return ""
+	}
+	query, err := json.Marshal(struct {
+		Page    int
+		PerPage int
+		Sort    VideoSort
+		Filters VideoFilters
+	}{page, perPage, sort, filters})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(query)
+	// A missing generation reads as empty, which is as good a namespace as any
+	return listCacheKeyPrefix + string(gen) + ":" + hex.EncodeToString(sum[:12])
+}
+
+// get decodes the entry under key into dst and reports whether it was found
+func (c *VideoCache) get(ctx context.Context, kind, key string, dst any) bool {
+	if c == nil || key == "" {
+		return false
+	}
+	data, ok, err := c.cache.Get(ctx, key)
+	if err != nil {
+		c.failed(kind, err)
+		return false
+	}
+	if ok {
+		if err := json.Unmarshal(data, dst); err == nil {
+			metrics.CacheRequestsTotal.WithLabelValues(kind, "hit").Inc()
+			return true
+		}
+		c.failed(kind, fmt.Errorf("decode %s: %w", key, err))
+		return false
+	}
+	metrics.CacheRequestsTotal.WithLabelValues(kind, "miss").Inc()
+	return false
+}
+
+// set stores value under key for the TTL
+func (c *VideoCache) set(ctx context.Context, key string, value any) {
+	if c == nil || key == "" {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		c.logger.Warnw("Failed to encode cache entry", "error", err, "key", key)
+		return
+	}
+	if err := c.cache.Set(ctx, key, data, c.ttl); err != nil {
+		c.logger.Warnw("Failed to write cache entry", "error", err, "key", key)
+	}
+}
+
+// InvalidateVideo drops the cached copy of one video, leaving listings alone
+func (c *VideoCache) InvalidateVideo(ctx context.Context, id uint) {
+	if c == nil {
+		return
+	}
+	if err := c.cache.Delete(ctx, videoCacheKey(id)); err != nil {
+		c.logger.Warnw("Failed to invalidate cached video", "error", err, "videoID", id)
+	}
+}
+
+// observe is a VideoChangeHook: any change can alter the video's response and
+// which listings it appears in, so both are dropped
+func (c *VideoCache) observe(ctx context.Context, change VideoChange) {
+	c.InvalidateVideo(ctx, change.VideoID)
+	if _, err := c.cache.Incr(ctx, listGenerationKey); err != nil {
+		c.logger.Warnw("Failed to invalidate cached listings", "error", err, "videoID", change.VideoID)
+	}
+}
+
+func (c *VideoCache) failed(kind string, err error) {
+	metrics.CacheRequestsTotal.WithLabelValues(kind, "error").Inc()
+	c.logger.Warnw("Cache read failed; using the database", "error", err, "cache", kind)
+}
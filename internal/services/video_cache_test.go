@@ -0,0 +1,186 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// cacheResults reads catalog_cache_requests_total for one cache
+func cacheResults(kind string) (hits, misses, errs float64) {
+	return testutil.ToFloat64(metrics.CacheRequestsTotal.WithLabelValues(kind, "hit")),
+		testutil.ToFloat64(metrics.CacheRequestsTotal.WithLabelValues(kind, "miss")),
+		testutil.ToFloat64(metrics.CacheRequestsTotal.WithLabelValues(kind, "error"))
+}
+
+func cachedVideos(t *testing.T, c *fakeCache) (*services.VideoService, *services.ViewService) {
+	t.Helper()
+	db := dbtest.Open(t)
+	videoCache := services.NewVideoCache(c, time.Minute, nopLogger())
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	videos.SetCache(videoCache)
+	views := services.NewViewService(db, nopLogger(), time.Hour)
+	views.SetCache(videoCache)
+	return videos, views
+}
+
+func TestGetVideoCached(t *testing.T) {
+	c := newFakeCache()
+	videos, views := cachedVideos(t, c)
+	ctx := context.Background()
+	video, err := videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-1", Title: "first", Visibility: models.VisibilityPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.entries = map[string][]byte{}
+
+	hits, misses, _ := cacheResults("video")
+	for i := 0; i < 3; i++ {
+		got, err := videos.GetVideoCached(ctx, video.ID)
+		if err != nil || got.Title != "first" {
+			t.Fatalf("read %d: %+v, %v", i, got, err)
+		}
+	}
+	gotHits, gotMisses, _ := cacheResults("video")
+	if gotHits-hits != 2 || gotMisses-misses != 1 {
+		t.Errorf("%v hits and %v misses, want the first read to miss and the rest to hit", gotHits-hits, gotMisses-misses)
+	}
+
+	// An update drops the entry, so the next read sees it
+	title := "second"
+	if _, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Title: &title}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := videos.GetVideoCached(ctx, video.ID); got.Title != "second" {
+		t.Errorf("title %q after the update, want second", got.Title)
+	}
+
+	// So does a counted view, but not a deduped one
+	if counted, err := views.RecordView(ctx, video.ID, "viewer"); err != nil || !counted {
+		t.Fatalf("RecordView: %v, %v", counted, err)
+	}
+	if got, _ := videos.GetVideoCached(ctx, video.ID); got.ViewCount != 1 {
+		t.Errorf("view_count %d after a counted view, want 1", got.ViewCount)
+	}
+	_, misses, _ = cacheResults("video")
+	if counted, _ := views.RecordView(ctx, video.ID, "viewer"); counted {
+		t.Fatal("a repeat view within the interval was counted")
+	}
+	videos.GetVideoCached(ctx, video.ID)
+	if _, gotMisses, _ := cacheResults("video"); gotMisses != misses {
+		t.Error("a deduped view dropped the cached video")
+	}
+
+	if _, err := videos.GetVideoCached(ctx, video.ID+100); !errors.Is(err, services.ErrVideoNotFound) {
+		t.Errorf("missing video: %v, want ErrVideoNotFound", err)
+	}
+}
+
+func TestPublicListingCached(t *testing.T) {
+	c := newFakeCache()
+	videos, _ := cachedVideos(t, c)
+	ctx := context.Background()
+	list := func() []models.Video {
+		t.Helper()
+		page, err := videos.ListVideos(ctx, "", 1, 20, false, services.VideoSort{}, services.VideoFilters{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return page.Videos
+	}
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-1", UserID: "owner", Title: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := list(); len(got) != 1 || got[0].Status == models.StatusReady {
+		t.Fatalf("listed %+v before transcoding", got)
+	}
+
+	hits, _, _ := cacheResults("list")
+	list()
+	if gotHits, _, _ := cacheResults("list"); gotHits-hits != 1 {
+		t.Errorf("%v list hits on the second read, want 1", gotHits-hits)
+	}
+
+	// Going ready bumps the generation, so the listing is read afresh
+	if err := videos.HandleTranscodedEvent(ctx, &models.TranscodedEvent{UploadID: "up-1", UserID: "owner", Ready: true,
+		HLS: models.HLSInfo{MasterURL: "https://cdn.example/up-1/master.m3u8"}}); err != nil {
+		t.Fatal(err)
+	}
+	got := list()
+	if len(got) != 1 || got[0].Status != models.StatusReady {
+		t.Fatalf("listed %+v after transcoding, want the ready video", got)
+	}
+	if _, err := videos.DeleteVideo(ctx, got[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := list(); len(got) != 0 {
+		t.Errorf("listed %d videos after the delete, want 0", len(got))
+	}
+
+	// Listings with private videos are never cached
+	entries := c.len()
+	if _, err := videos.ListVideos(ctx, "owner", 1, 20, true, services.VideoSort{}, services.VideoFilters{}); err != nil {
+		t.Fatal(err)
+	}
+	if c.len() != entries {
+		t.Error("a listing including private videos was cached")
+	}
+}
+
+func TestVideoCacheUnavailable(t *testing.T) {
+	c := newFakeCache()
+	c.err = errors.New("connection refused")
+	videos, views := cachedVideos(t, c)
+	ctx := context.Background()
+	video, err := videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-1", Title: "t", Visibility: models.VisibilityPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, videoErrs := cacheResults("video")
+	_, _, listErrs := cacheResults("list")
+	if got, err := videos.GetVideoCached(ctx, video.ID); err != nil || got.ID != video.ID {
+		t.Errorf("GetVideoCached = %+v, %v; want the video from the database", got, err)
+	}
+	if _, err := videos.ListVideos(ctx, "", 1, 20, false, services.VideoSort{}, services.VideoFilters{}); err != nil {
+		t.Errorf("ListVideos: %v", err)
+	}
+	if _, err := views.RecordView(ctx, video.ID, "viewer"); err != nil {
+		t.Errorf("RecordView: %v", err)
+	}
+	_, _, gotVideoErrs := cacheResults("video")
+	_, _, gotListErrs := cacheResults("list")
+	if gotVideoErrs-videoErrs != 1 || gotListErrs-listErrs != 1 {
+		t.Errorf("%v video and %v list errors, want one each", gotVideoErrs-videoErrs, gotListErrs-listErrs)
+	}
+}
+
+func TestWithoutVideoCache(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	videos.SetCache(nil)
+	views := services.NewViewService(db, nopLogger(), time.Hour)
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Status: models.StatusReady, Visibility: models.VisibilityPublic})
+
+	if _, err := views.RecordView(ctx, video.ID, "viewer"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := videos.GetVideoCached(ctx, video.ID)
+	if err != nil || got.ViewCount != 1 {
+		t.Errorf("GetVideoCached = %+v, %v", got, err)
+	}
+	// Every read goes to the database
+	db.Model(&models.Video{}).Where("id = ?", video.ID).Update("title", "direct")
+	if got, _ := videos.GetVideoCached(ctx, video.ID); got.Title != "direct" {
+		t.Errorf("title %q, want the database's", got.Title)
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// VideoChangeKind says what kind of mutation a video went through. When one write
+// changes several things, the most significant kind is reported.
+type VideoChangeKind string
+
+const (
+	VideoCreated VideoChangeKind = "created"
+	// VideoUpdated covers metadata edits that leave visibility and status alone
+	VideoUpdated VideoChangeKind = "updated"
+	// VideoVisibilityChanged means the visibility changed; search must reflect it immediately
+	VideoVisibilityChanged VideoChangeKind = "visibility"
+	VideoStatusChanged     VideoChangeKind = "status"
+	VideoDeleted           VideoChangeKind = "deleted"
+	// VideoRestored means a soft-deleted video was brought back by its owner
+	VideoRestored VideoChangeKind = "restored"
+	// VideoModerated means an admin changed the moderation status; like a visibility
+	// change, it can hide the video from everyone but its owner
+	VideoModerated VideoChangeKind = "moderation"
+)
+
+// VideoChange describes a committed mutation of one video. For deletions only the
+// identifiers are guaranteed to be set.
+type VideoChange struct {
+	Kind       VideoChangeKind
+	VideoID    uint
+	UploadID   string
+	UserID     string
+	Visibility models.Visibility
+	// IsPrivate is true for any visibility but public
+	IsPrivate bool
+	Status    models.VideoStatus
+	At        time.Time
+	// Video is a copy of the record after the change; for a deletion, the record
+	// as it was when deleted, when the writer had it loaded
+	Video *models.Video
+}
+
+// VideoChangeHook reacts to a video change; it runs synchronously after the write
+// commits, so it must be quick and must not fail the write
+type VideoChangeHook func(ctx context.Context, change VideoChange)
+
+// VideoChanges is the single fan-out point every video mutation path reports to.
+// Derived state (caches, search, change feeds, outbound events) subscribes here
+// instead of being updated piecemeal by each writer. Counter columns, the tags
+// backfill and notification muting aren't catalog-visible and are not reported.
+type VideoChanges struct {
+	logger *zap.SugaredLogger
+	mu     sync.RWMutex
+	names  []string
+	hooks  []VideoChangeHook
+}
+
+// NewVideoChanges creates an empty change hub
+func NewVideoChanges(logger *zap.SugaredLogger) *VideoChanges {
+	return &VideoChanges{logger: logger}
+}
+
+// Subscribe registers a hook under a name used in logs
+func (c *VideoChanges) Subscribe(name string, hook VideoChangeHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.names = append(c.names, name)
+	c.hooks = append(c.hooks, hook)
+}
+
+// Emit reports a change of video to every hook. A panicking hook is logged and
+// skipped so the others still run.
+func (c *VideoChanges) Emit(ctx context.Context, kind VideoChangeKind, video *models.Video) {
+	if c == nil {
+		return
+	}
+	snapshot := *video
+	change := VideoChange{
+		Kind:       kind,
+		VideoID:    video.ID,
+		UploadID:   video.UploadID,
+		UserID:     video.UserID,
+		Visibility: video.Visibility,
+		IsPrivate:  video.IsPrivate(),
+		Status:     video.Status,
+		At:         time.Now().UTC(),
+		Video:      &snapshot,
+	}
+	metrics.VideoChangesTotal.WithLabelValues(string(kind)).Inc()
+
+	c.mu.RLock()
+	names, hooks := c.names, c.hooks
+	c.mu.RUnlock()
+	for i, hook := range hooks {
+		c.run(ctx, names[i], hook, change)
+	}
+}
+
+func (c *VideoChanges) run(ctx context.Context, name string, hook VideoChangeHook, change VideoChange) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Errorw("Video change hook panicked", "hook", name, "videoID", change.VideoID, "kind", change.Kind, "panic", r)
+		}
+	}()
+	hook(ctx, change)
+}
+
+// changeKind picks the most significant kind for an update, given the visibility
+// and status the video had before it
+func changeKind(before models.Video, after *models.Video) VideoChangeKind {
+	switch {
+	case before.Visibility != after.Visibility:
+		return VideoVisibilityChanged
+	case before.Status != after.Status:
+		return VideoStatusChanged
+	default:
+		return VideoUpdated
+	}
+}
@@ -0,0 +1,126 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"sync"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// changeRecorder is a change hook that keeps what it saw
+type changeRecorder struct {
+	mu      sync.Mutex
+	changes []services.VideoChange
+}
+
+func (r *changeRecorder) hook(_ context.Context, change services.VideoChange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, change)
+}
+
+// take returns and forgets the recorded changes
+func (r *changeRecorder) take() []services.VideoChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changes := r.changes
+	r.changes = nil
+	return changes
+}
+
+func pngImage(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestEveryMutationPathReportsChanges mutates a video through each writer and
+// checks the change hook saw it, with the right kind
+func TestEveryMutationPathReportsChanges(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	reports := services.NewReportService(db, videos, nopLogger())
+	rec := &changeRecorder{}
+	videos.Changes().Subscribe("test", rec.hook)
+	// A panicking subscriber must not keep the others from running
+	videos.Changes().Subscribe("broken", func(context.Context, services.VideoChange) { panic("boom") })
+	ctx := context.Background()
+
+	var created *models.Video
+	title, public := "renamed", models.VisibilityPublic
+	steps := []struct {
+		path string
+		run  func() error
+		want services.VideoChangeKind
+	}{
+		{"CreateVideo", func() (err error) {
+			created, err = videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-1", Title: "t", Visibility: models.VisibilityPrivate})
+			return err
+		}, services.VideoCreated},
+		{"UpdateVideo metadata", func() error {
+			_, err := videos.UpdateVideoForUser(ctx, created.ID, "owner", 0, &models.VideoUpdateRequest{Title: &title})
+			return err
+		}, services.VideoUpdated},
+		{"UpdateVideo visibility", func() error {
+			_, err := videos.UpdateVideo(ctx, created.ID, &models.VideoUpdateRequest{Visibility: &public})
+			return err
+		}, services.VideoVisibilityChanged},
+		{"UploadThumbnail", func() error {
+			_, _, err := videos.UploadThumbnail(ctx, created.ID, "owner", pngImage(t, 320, 180))
+			return err
+		}, services.VideoUpdated},
+		{"SetVideoStatus (admin)", func() error {
+			_, err := videos.SetVideoStatus(ctx, created.ID, models.StatusProcessing, "admin", "retry")
+			return err
+		}, services.VideoStatusChanged},
+		{"ReportService.Resolve (takedown)", func() error {
+			report, err := reports.Report(ctx, created.ID, "reporter", models.ReportSpam, "")
+			if err != nil {
+				return err
+			}
+			takenDown := models.ModerationStatusTakenDown
+			_, err = reports.Resolve(ctx, report.ID, models.ReportReviewed, &takenDown, "admin", "")
+			return err
+		}, services.VideoModerated},
+		{"DeleteVideo", func() error {
+			_, err := videos.DeleteVideoForUser(ctx, created.ID, "owner")
+			return err
+		}, services.VideoDeleted},
+		{"RestoreVideo", func() error {
+			_, err := videos.RestoreVideo(ctx, created.ID, "owner")
+			return err
+		}, services.VideoRestored},
+		{"HandleUploadedEvent", func() error {
+			return videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-2", UserID: "owner", Title: "t"})
+		}, services.VideoCreated},
+		{"HandleTranscodedEvent", func() error {
+			return videos.HandleTranscodedEvent(ctx, &models.TranscodedEvent{UploadID: "up-2", UserID: "owner", Ready: true,
+				HLS: models.HLSInfo{MasterURL: "https://cdn.example/up-2/master.m3u8"}})
+		}, services.VideoStatusChanged},
+		{"HandleTranscodeFailedEvent", func() error {
+			return videos.HandleTranscodeFailedEvent(ctx, &models.TranscodeFailedEvent{UploadID: "up-3", UserID: "owner", ErrorMessage: "codec"})
+		}, services.VideoCreated},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.path, err)
+		}
+		changes := rec.take()
+		if len(changes) != 1 {
+			t.Errorf("%s: %d changes reported, want 1", step.path, len(changes))
+			continue
+		}
+		if changes[0].Kind != step.want || changes[0].VideoID == 0 || changes[0].Video == nil {
+			t.Errorf("%s: change = %+v, want kind %s", step.path, changes[0], step.want)
+		}
+	}
+}
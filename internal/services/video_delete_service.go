@@ -2,56 +2,72 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/streamhive/video-catalog-api/internal/logging"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
 	"github.com/streamhive/video-catalog-api/internal/models"
 )
 
 // VideoDeleteService handles video deletion including storage cleanup
 type VideoDeleteService struct {
-	db     *gorm.DB
-	logger *zap.SugaredLogger
-	azure  AzureStorageClient
-}
-
-// AzureStorageClient interface for Azure operations needed for deletion
-type AzureStorageClient interface {
-	DeleteBlob(ctx context.Context, blobPath string) error
-	DeleteBlobsWithPrefix(ctx context.Context, prefix string) error
-	BlobExists(ctx context.Context, blobPath string) (bool, error)
+	db      *gorm.DB
+	logger  *zap.SugaredLogger
+	storage StorageClient
+	outbox  *Outbox
 }
 
 // NewVideoDeleteService creates a new video delete service
-func NewVideoDeleteService(db *gorm.DB, logger *zap.SugaredLogger, azure AzureStorageClient) *VideoDeleteService {
+func NewVideoDeleteService(db *gorm.DB, logger *zap.SugaredLogger, storage StorageClient) *VideoDeleteService {
 	return &VideoDeleteService{
-		db:     db,
-		logger: logger,
-		azure:  azure,
+		db:      db,
+		logger:  logger,
+		storage: storage,
 	}
 }
 
-// DeleteVideoCompletely removes a video and all associated files from database and storage
-func (s *VideoDeleteService) DeleteVideoCompletely(ctx context.Context, videoID uint) error {
+// DeletionProgress reports how far a storage cleanup got
+type DeletionProgress struct {
+	FilesDeleted int
+	// BlobsDeleted counts blobs under the video's prefixes, including those deleted
+	// by earlier attempts
+	BlobsDeleted int64
+	// RemainingPrefixes are the prefixes not fully deleted when cleanup stopped
+	RemainingPrefixes []string
+}
+
+// DeleteVideoCompletely removes a video and all associated files from database and
+// storage, as deletion job deletionID. Each storage path's outcome is kept in
+// deletion_items; paths an earlier attempt deleted are skipped, and the row is only
+// removed once every path is gone, so a failure never leaves orphaned blobs behind
+// a deleted row. It purges soft-deleted videos too; removing a live one is recorded
+// in the public event log under actorID, while a soft-deleted one was recorded
+// when it was deleted.
+func (s *VideoDeleteService) DeleteVideoCompletely(ctx context.Context, videoID, deletionID uint, actorID string) (DeletionProgress, error) {
+	var progress DeletionProgress
 	// First get the video to extract all file paths
 	var video models.Video
-	if err := s.db.First(&video, videoID).Error; err != nil {
+	if err := s.db.Unscoped().First(&video, videoID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return fmt.Errorf("video not found")
+			return progress, fmt.Errorf("video %d: %w", videoID, ErrVideoNotFound)
 		}
 		s.logger.Errorw("Failed to get video for deletion", "error", err, "videoID", videoID)
-		return fmt.Errorf("failed to get video: %w", err)
+		return progress, fmt.Errorf("failed to get video: %w", err)
 	}
 
 	s.logger.Infow("Starting complete video deletion",
 		"videoID", videoID,
 		"uploadID", video.UploadID,
 		"userID", video.UserID,
-		"title", video.Title)
+		logging.Title(video.Title))
 
 	// Collect all storage paths to delete
 	var pathsToDelete []string
@@ -65,66 +81,234 @@ func (s *VideoDeleteService) DeleteVideoCompletely(ctx context.Context, videoID
 
 	// 2. HLS files (all renditions, segments, and master playlist)
 	if video.HLSMasterURL != "" {
-		hlsPrefix := s.extractHLSPrefix(video.HLSMasterURL, video.UserID, video.UploadID)
+		hlsPrefix := extractHLSPrefix(video.HLSMasterURL, video.UserID, video.UploadID)
 		if hlsPrefix != "" {
 			prefixesToDelete = append(prefixesToDelete, hlsPrefix)
 			s.logger.Infow("Will delete HLS files", "prefix", hlsPrefix)
 		}
 	}
 
-	// 3. Thumbnail
-	thumbnailPath := fmt.Sprintf("thumbnails/%s/%s.jpg", video.UserID, video.UploadID)
+	// 3. Thumbnail, plus uploaded thumbnails and candidates, including any the
+	// transcoder stored outside the video's thumbnail prefix
+	thumbnailPath := thumbnailBlobPath(&video)
 	pathsToDelete = append(pathsToDelete, thumbnailPath)
 	s.logger.Infow("Will delete thumbnail", "path", thumbnailPath)
+	thumbnailPrefix := thumbnailBlobPrefix(&video)
+	prefixesToDelete = append(prefixesToDelete, thumbnailPrefix)
+	var candidatePaths []string
+	if err := s.db.WithContext(ctx).Model(&models.VideoThumbnail{}).Where("video_id = ? AND blob_path <> ''", videoID).
+		Distinct().Pluck("blob_path", &candidatePaths).Error; err != nil {
+		return progress, fmt.Errorf("list thumbnail candidates: %w", err)
+	}
+	outside := 0
+	for _, path := range candidatePaths {
+		if path != thumbnailPath && !strings.HasPrefix(path, thumbnailPrefix) {
+			pathsToDelete = append(pathsToDelete, path)
+			outside++
+		}
+	}
+	s.logger.Infow("Will delete thumbnail candidates", "prefix", thumbnailPrefix, "otherPaths", outside)
+
+	// 4. Preview sprites and WebVTT track; always cleared in case an event's
+	// previews were written to storage but rejected as incomplete
+	previewPrefix := previewBlobPrefix(&video)
+	prefixesToDelete = append(prefixesToDelete, previewPrefix)
+	s.logger.Infow("Will delete previews", "prefix", previewPrefix)
 
-	// 4. Any other potential files (future-proofing)
+	// 5. Any other potential files (future-proofing)
 	otherPrefix := fmt.Sprintf("videos/%s/%s", video.UserID, video.UploadID)
 	prefixesToDelete = append(prefixesToDelete, otherPrefix)
 
 	// Delete from storage first (easier to retry if DB deletion fails)
-	deletedFiles := 0
-	deletedPrefixes := 0
-
-	// Delete individual files
-	for _, path := range pathsToDelete {
-		if err := s.deleteFileIfExists(ctx, path); err != nil {
-			s.logger.Warnw("Failed to delete file (continuing)", "error", err, "path", path)
-		} else {
-			deletedFiles++
-		}
+	items, err := s.loadItems(ctx, deletionID, videoID, pathsToDelete, prefixesToDelete)
+	if err != nil {
+		return progress, err
 	}
-
-	// Delete by prefix (for HLS folders)
-	for _, prefix := range prefixesToDelete {
-		if err := s.azure.DeleteBlobsWithPrefix(ctx, prefix); err != nil {
-			s.logger.Warnw("Failed to delete files with prefix (continuing)", "error", err, "prefix", prefix)
-		} else {
-			deletedPrefixes++
+	var failed []string
+	var firstErr error
+	for i := range items {
+		item := &items[i]
+		if item.Status != models.DeletionItemDeleted {
+			if ctx.Err() != nil {
+				failed = append(failed, item.Path)
+				continue
+			}
+			if err := s.deleteItem(ctx, item, &progress); err != nil {
+				failed = append(failed, item.Path)
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+		if item.Kind == models.DeletionItemPrefix {
+			progress.BlobsDeleted += item.BlobsDeleted
+			if item.Status != models.DeletionItemDeleted {
+				progress.RemainingPrefixes = append(progress.RemainingPrefixes, item.Path)
+			}
 		}
 	}
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	if len(failed) > 0 {
+		// The video is kept so the job can be retried; only these paths run again
+		return progress, fmt.Errorf("%d of %d storage paths not deleted (%s): %w",
+			len(failed), len(items), strings.Join(failed, ", "), firstErr)
+	}
 
 	s.logger.Infow("Storage cleanup completed",
-		"deletedFiles", deletedFiles,
-		"deletedPrefixes", deletedPrefixes,
+		"deletedFiles", progress.FilesDeleted,
+		"paths", len(items),
+		"deletedBlobs", progress.BlobsDeleted,
 		"videoID", videoID)
 
 	// Now delete from database (hard delete, not soft delete)
-	if err := s.db.Unscoped().Delete(&video).Error; err != nil {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&video).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("video_id = ?", videoID).Delete(&models.BlobCleanupCheckpoint{}).Error; err != nil {
+			return err
+		}
+		if video.DeletedAt.Valid {
+			return nil
+		}
+		return recordRemoval(tx, s.outbox, &video, actorID)
+	})
+	if err != nil {
 		s.logger.Errorw("Failed to delete video from database", "error", err, "videoID", videoID)
-		return fmt.Errorf("failed to delete video from database: %w", err)
+		return progress, fmt.Errorf("failed to delete video from database: %w", err)
 	}
 
 	s.logger.Infow("Video completely deleted",
 		"videoID", videoID,
 		"uploadID", video.UploadID,
-		"title", video.Title)
+		logging.Title(video.Title))
+
+	return progress, nil
+}
+
+// loadItems returns deletion job deletionID's items, creating any that an earlier
+// attempt didn't know about, files first
+func (s *VideoDeleteService) loadItems(ctx context.Context, deletionID, videoID uint, paths, prefixes []string) ([]models.DeletionItem, error) {
+	items := make([]models.DeletionItem, 0, len(paths)+len(prefixes))
+	for _, path := range paths {
+		items = append(items, models.DeletionItem{DeletionID: deletionID, VideoID: videoID, Kind: models.DeletionItemFile, Path: path, Status: models.DeletionItemPending})
+	}
+	for _, prefix := range prefixes {
+		items = append(items, models.DeletionItem{DeletionID: deletionID, VideoID: videoID, Kind: models.DeletionItemPrefix, Path: prefix, Status: models.DeletionItemPending})
+	}
+	db := s.db.WithContext(ctx)
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&items).Error; err != nil {
+		return nil, fmt.Errorf("record deletion items: %w", err)
+	}
+	items = items[:0]
+	if err := db.Where("deletion_id = ?", deletionID).Order("id").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("load deletion items: %w", err)
+	}
+	return items, nil
+}
+
+// deleteItem deletes one storage path and saves its outcome
+func (s *VideoDeleteService) deleteItem(ctx context.Context, item *models.DeletionItem, progress *DeletionProgress) error {
+	var err error
+	switch item.Kind {
+	case models.DeletionItemFile:
+		if err = s.deleteFileIfExists(ctx, item.Path); err == nil {
+			progress.FilesDeleted++
+		}
+	case models.DeletionItemPrefix:
+		// A failure keeps the prefix's checkpoint, so the retry resumes instead of
+		// starting over
+		item.BlobsDeleted, err = s.deletePrefix(ctx, item.VideoID, item.Path)
+	}
+	item.Attempts++
+	if err != nil {
+		s.logger.Errorw("Failed to delete storage path", "error", err, "kind", item.Kind, "path", item.Path, "videoID", item.VideoID)
+		item.Status = models.DeletionItemFailed
+		item.Error = err.Error()
+	} else {
+		item.Status = models.DeletionItemDeleted
+		item.Error = ""
+	}
+	// Saved even when ctx is done, so the next attempt knows what is left
+	if saveErr := s.db.WithContext(context.WithoutCancel(ctx)).Save(item).Error; saveErr != nil && err == nil {
+		return fmt.Errorf("save deletion item %s: %w", item.Path, saveErr)
+	}
+	return err
+}
+
+// recordRemoval queues catalog.video.deleted and logs video.deleted for video
+// inside tx, the transaction taking it out of the catalog
+func recordRemoval(tx *gorm.DB, outbox *Outbox, video *models.Video, actorID string) error {
+	if err := enqueueCatalogEvent(tx, outbox, VideoDeleted, video); err != nil {
+		return err
+	}
+	return appendPublicEvent(tx, newPublicEvent(models.PublicEventDeleted, video, actorID))
+}
+
+// deletePrefix deletes every blob under prefix a page at a time, saving the listing
+// marker after each page. It resumes from a saved checkpoint and skips a prefix
+// that an earlier attempt finished. It returns the blobs deleted under prefix so
+// far, by this attempt and earlier ones.
+func (s *VideoDeleteService) deletePrefix(ctx context.Context, videoID uint, prefix string) (int64, error) {
+	checkpoint := models.BlobCleanupCheckpoint{VideoID: videoID, Prefix: prefix}
+	if err := s.db.WithContext(ctx).Where("video_id = ? AND prefix = ?", videoID, prefix).
+		FirstOrCreate(&checkpoint).Error; err != nil {
+		return 0, fmt.Errorf("load cleanup checkpoint: %w", err)
+	}
+	if checkpoint.CompletedAt != nil {
+		return checkpoint.BlobsDeleted, nil
+	}
+	if checkpoint.PagesDone > 0 {
+		s.logger.Infow("Resuming prefix deletion from checkpoint", "videoID", videoID, "prefix", prefix,
+			"pagesDone", checkpoint.PagesDone, "blobsDeleted", checkpoint.BlobsDeleted)
+	}
+
+	for {
+		next, deleted, err := s.storage.DeleteBlobPage(ctx, prefix, checkpoint.Marker)
+		metrics.StorageBlobsDeletedTotal.Add(float64(deleted))
+		metrics.StorageDeletionsTotal.WithLabelValues("page", storageOutcome(err)).Inc()
+		if errors.Is(err, ErrStaleMarker) {
+			// Restarting re-lists only what is left; deleted blobs are no longer listed
+			s.logger.Warnw("Cleanup checkpoint marker expired; restarting listing", "videoID", videoID, "prefix", prefix)
+			checkpoint.Marker = ""
+			continue
+		}
+		checkpoint.BlobsDeleted += int64(deleted)
+		if err != nil {
+			checkpoint.LastError = err.Error()
+			s.saveCheckpoint(ctx, &checkpoint)
+			return checkpoint.BlobsDeleted, err
+		}
+		checkpoint.PagesDone++
+		checkpoint.Marker = next
+		checkpoint.LastError = ""
+		if next == "" {
+			now := time.Now().UTC()
+			checkpoint.CompletedAt = &now
+		}
+		if err := s.saveCheckpoint(ctx, &checkpoint); err != nil {
+			return checkpoint.BlobsDeleted, err
+		}
+		if next == "" {
+			return checkpoint.BlobsDeleted, nil
+		}
+	}
+}
 
+func (s *VideoDeleteService) saveCheckpoint(ctx context.Context, checkpoint *models.BlobCleanupCheckpoint) error {
+	// Saved even when ctx is done, so a cancelled cleanup still records its progress
+	if err := s.db.WithContext(context.WithoutCancel(ctx)).Save(checkpoint).Error; err != nil {
+		s.logger.Warnw("Failed to save cleanup checkpoint", "error", err, "videoID", checkpoint.VideoID, "prefix", checkpoint.Prefix)
+		return fmt.Errorf("save cleanup checkpoint: %w", err)
+	}
 	return nil
 }
 
 // deleteFileIfExists deletes a file if it exists, ignoring not-found errors
 func (s *VideoDeleteService) deleteFileIfExists(ctx context.Context, path string) error {
-	exists, err := s.azure.BlobExists(ctx, path)
+	exists, err := s.storage.BlobExists(ctx, path)
 	if err != nil {
 		return fmt.Errorf("failed to check if file exists: %w", err)
 	}
@@ -134,16 +318,45 @@ func (s *VideoDeleteService) deleteFileIfExists(ctx context.Context, path string
 		return nil
 	}
 
-	if err := s.azure.DeleteBlob(ctx, path); err != nil {
+	err = s.storage.DeleteBlob(ctx, path)
+	metrics.StorageDeletionsTotal.WithLabelValues("blob", storageOutcome(err)).Inc()
+	if err != nil {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
+	metrics.StorageBlobsDeletedTotal.Inc()
 
 	s.logger.Debugw("File deleted", "path", path)
 	return nil
 }
 
+// storageOutcome labels a storage delete call for catalog_storage_deletions_total
+func storageOutcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// thumbnailBlobPath is where the transcoder stores a video's thumbnail
+func thumbnailBlobPath(video *models.Video) string {
+	return fmt.Sprintf("thumbnails/%s/%s.jpg", video.UserID, video.UploadID)
+}
+
+// thumbnailBlobPrefix is where a video's other thumbnails are stored: custom
+// uploads, and transcoder candidates. The trailing slash keeps it from matching
+// thumbnailBlobPath or another upload's files.
+func thumbnailBlobPrefix(video *models.Video) string {
+	return fmt.Sprintf("thumbnails/%s/%s/", video.UserID, video.UploadID)
+}
+
+// previewBlobPrefix is where the transcoder stores a video's preview sprites and VTT
+// track. The trailing slash keeps up-1's cleanup out of up-10's previews.
+func previewBlobPrefix(video *models.Video) string {
+	return fmt.Sprintf("previews/%s/%s/", video.UserID, video.UploadID)
+}
+
 // extractHLSPrefix extracts the HLS storage prefix from the master URL
-func (s *VideoDeleteService) extractHLSPrefix(masterURL, userID, uploadID string) string {
+func extractHLSPrefix(masterURL, userID, uploadID string) string {
 	// Expected format: https://{account}.blob.core.windows.net/{container}/hls/{userID}/{uploadID}/master.m3u8
 	// We want to extract: hls/{userID}/{uploadID}
 
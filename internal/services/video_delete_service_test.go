@@ -0,0 +1,207 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+const hlsPrefix = "hls/owner/up-1"
+
+// pagedStorage is a fakeStorage whose DeleteBlobPage lists pageSize blobs at a
+// time in name order, with the last name listed as the marker, as Azure pages.
+// failCall makes that DeleteBlobPage call (counting from 1) fail halfway through
+// its page; staleMarker rejects the next non-empty marker.
+type pagedStorage struct {
+	*fakeStorage
+	pageSize    int
+	failCall    int
+	staleMarker bool
+	// calls records each DeleteBlobPage call as prefix@marker
+	calls []string
+}
+
+func (s *pagedStorage) DeleteBlobPage(_ context.Context, prefix, marker string) (string, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, prefix+"@"+marker)
+	if marker != "" && s.staleMarker {
+		s.staleMarker = false
+		return "", 0, services.ErrStaleMarker
+	}
+	var names []string
+	for name := range s.blobs {
+		if strings.HasPrefix(name, prefix) && name > marker {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	page := names
+	if len(page) > s.pageSize {
+		page = page[:s.pageSize]
+	}
+	for i, name := range page {
+		if len(s.calls) == s.failCall && i == len(page)/2 {
+			return "", i, errors.New("storage unavailable")
+		}
+		delete(s.blobs, name)
+	}
+	if len(names) > len(page) {
+		return page[len(page)-1], len(page), nil
+	}
+	return "", len(page), nil
+}
+
+// callsFor returns the markers DeleteBlobPage was called with for prefix since call from
+func (s *pagedStorage) callsFor(prefix string, from int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var markers []string
+	for _, call := range s.calls[from:] {
+		if p, marker, _ := strings.Cut(call, "@"); p == prefix {
+			markers = append(markers, marker)
+		}
+	}
+	return markers
+}
+
+// hlsVideo creates a video with segments HLS segments in a paged store of five
+// blobs a page
+func hlsVideo(t *testing.T, db *gorm.DB, segments int) (*models.Video, *pagedStorage) {
+	t.Helper()
+	storage := &pagedStorage{fakeStorage: newFakeStorage(), pageSize: 5}
+	for i := 0; i < segments; i++ {
+		storage.blobs[fmt.Sprintf("%s/seg-%02d.ts", hlsPrefix, i)] = nil
+	}
+	video := createVideo(t, db, models.Video{UploadID: "up-1", UserID: "owner", Title: "hls",
+		HLSMasterURL: "https://acct.blob.core.windows.net/videos/" + hlsPrefix + "/master.m3u8"})
+	return video, storage
+}
+
+func checkpointFor(t *testing.T, db *gorm.DB, videoID uint, prefix string) models.BlobCleanupCheckpoint {
+	t.Helper()
+	var checkpoint models.BlobCleanupCheckpoint
+	if err := db.Where("video_id = ? AND prefix = ?", videoID, prefix).First(&checkpoint).Error; err != nil {
+		t.Fatal(err)
+	}
+	return checkpoint
+}
+
+func TestDeleteVideoCompletelyResumesFromCheckpoint(t *testing.T) {
+	db := dbtest.Open(t)
+	video, storage := hlsVideo(t, db, 23)
+	deletes := services.NewVideoDeleteService(db, nopLogger(), storage)
+	ctx := context.Background()
+
+	// The third page fails after deleting two of its five blobs
+	storage.failCall = 3
+	progress, err := deletes.DeleteVideoCompletely(ctx, video.ID, 1, "owner")
+	if err == nil {
+		t.Fatal("cleanup succeeded despite the failed page")
+	}
+	if progress.BlobsDeleted != 12 || len(progress.RemainingPrefixes) != 1 || progress.RemainingPrefixes[0] != hlsPrefix {
+		t.Errorf("progress = %+v, want 12 blobs deleted and %s remaining", progress, hlsPrefix)
+	}
+	if _, err := services.NewVideoService(db, nil, videoSettings, nopLogger()).GetVideo(ctx, video.ID); err != nil {
+		t.Errorf("video removed before its storage was: %v", err)
+	}
+	checkpoint := checkpointFor(t, db, video.ID, hlsPrefix)
+	if checkpoint.PagesDone != 2 || checkpoint.BlobsDeleted != 12 || checkpoint.Marker != hlsPrefix+"/seg-09.ts" ||
+		checkpoint.LastError == "" || checkpoint.CompletedAt != nil {
+		t.Errorf("checkpoint after the failure = %+v", checkpoint)
+	}
+
+	// The retry picks up at the saved marker and touches no other prefix
+	storage.failCall = 0
+	retryFrom := len(storage.calls)
+	progress, err = deletes.DeleteVideoCompletely(ctx, video.ID, 1, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if markers := storage.callsFor(hlsPrefix, retryFrom); len(markers) != 3 || markers[0] != hlsPrefix+"/seg-09.ts" {
+		t.Errorf("retry listed from %q, want three pages from the checkpoint", markers)
+	}
+	if len(storage.calls)-retryFrom != 3 {
+		t.Errorf("retry made %v, want only the HLS pages", storage.calls[retryFrom:])
+	}
+	if progress.BlobsDeleted != 23 || len(progress.RemainingPrefixes) != 0 {
+		t.Errorf("progress = %+v, want all 23 blobs deleted", progress)
+	}
+	if len(storage.blobs) != 0 {
+		t.Errorf("blobs left: %v", storage.blobs)
+	}
+	var row models.Video
+	if err := db.Unscoped().First(&row, video.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("video row still there: %v", err)
+	}
+	var left int64
+	db.Model(&models.BlobCleanupCheckpoint{}).Where("video_id = ?", video.ID).Count(&left)
+	if left != 0 {
+		t.Errorf("%d checkpoints left after the video was deleted", left)
+	}
+}
+
+func TestDeleteVideoCompletelyStaleMarker(t *testing.T) {
+	db := dbtest.Open(t)
+	video, storage := hlsVideo(t, db, 12)
+	deletes := services.NewVideoDeleteService(db, nopLogger(), storage)
+	ctx := context.Background()
+
+	storage.failCall = 2
+	if _, err := deletes.DeleteVideoCompletely(ctx, video.ID, 1, "owner"); err == nil {
+		t.Fatal("cleanup succeeded despite the failed page")
+	}
+
+	// The saved marker expired; the retry lists again from the start, which only
+	// finds what wasn't deleted
+	storage.failCall, storage.staleMarker = 0, true
+	retryFrom := len(storage.calls)
+	progress, err := deletes.DeleteVideoCompletely(ctx, video.ID, 1, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if markers := storage.callsFor(hlsPrefix, retryFrom); len(markers) < 2 || markers[0] == "" || markers[1] != "" {
+		t.Errorf("retry markers = %q, want the stale one then a fresh listing", markers)
+	}
+	if progress.BlobsDeleted != 12 || len(storage.blobs) != 0 {
+		t.Errorf("progress = %+v with %d blobs left", progress, len(storage.blobs))
+	}
+}
+
+func TestDeleteVideoCompletelySkipsFinishedPrefix(t *testing.T) {
+	db := dbtest.Open(t)
+	video, storage := hlsVideo(t, db, 7)
+	deletes := services.NewVideoDeleteService(db, nopLogger(), storage)
+	ctx := context.Background()
+
+	// A file that can't be checked fails the job after every prefix is done
+	storage.existsErr = map[string]error{"thumbnails/owner/up-1.jpg": errors.New("timeout")}
+	if _, err := deletes.DeleteVideoCompletely(ctx, video.ID, 1, "owner"); err == nil {
+		t.Fatal("cleanup succeeded despite the failed file")
+	}
+	if checkpoint := checkpointFor(t, db, video.ID, hlsPrefix); checkpoint.CompletedAt == nil || checkpoint.BlobsDeleted != 7 {
+		t.Errorf("checkpoint = %+v, want the prefix finished", checkpoint)
+	}
+
+	storage.existsErr = nil
+	retryFrom := len(storage.calls)
+	progress, err := deletes.DeleteVideoCompletely(ctx, video.ID, 1, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storage.calls) != retryFrom {
+		t.Errorf("retry listed finished prefixes again: %v", storage.calls[retryFrom:])
+	}
+	if progress.BlobsDeleted != 7 {
+		t.Errorf("progress = %+v, want the 7 blobs deleted earlier counted", progress)
+	}
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// ErrDeletionNotFailed is returned when retrying a job that hasn't failed
+var ErrDeletionNotFailed = errors.New("deletion job has not failed")
+
+// queueDeletion creates the job purging video once runAfter has passed, inside tx,
+// the transaction soft-deleting it
+func queueDeletion(tx *gorm.DB, video *models.Video, actorID string, runAfter time.Time) (*models.VideoDeletion, error) {
+	job := &models.VideoDeletion{
+		VideoID:  video.ID,
+		UploadID: video.UploadID,
+		UserID:   video.UserID,
+		ActorID:  actorID,
+		Status:   models.DeletionPending,
+		RunAfter: runAfter,
+	}
+	if err := tx.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("queue deletion job: %w", err)
+	}
+	return job, nil
+}
+
+// GetDeletion returns a deletion job with its storage paths. While it runs, or
+// after it failed, its blob count is read from the cleanup checkpoints, so it shows
+// progress as it happens.
+func (s *VideoService) GetDeletion(ctx context.Context, id uint) (*models.VideoDeletion, error) {
+	var job models.VideoDeletion
+	if err := s.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("deletion %d: %w", id, ErrDeletionNotFound)
+		}
+		return nil, fmt.Errorf("get deletion job: %w", err)
+	}
+	if job.Status == models.DeletionRunning || job.Status == models.DeletionFailed {
+		var blobs int64
+		if err := s.db.WithContext(ctx).Model(&models.BlobCleanupCheckpoint{}).
+			Where("video_id = ?", job.VideoID).Select("COALESCE(SUM(blobs_deleted), 0)").Scan(&blobs).Error; err != nil {
+			return nil, fmt.Errorf("read cleanup progress: %w", err)
+		}
+		job.BlobsDeleted = blobs
+	}
+	if err := s.db.WithContext(ctx).Where("deletion_id = ?", job.ID).Order("id").Find(&job.Items).Error; err != nil {
+		return nil, fmt.Errorf("load deletion items: %w", err)
+	}
+	return &job, nil
+}
+
+// RetryDeletion puts a failed job back in the queue, for the next worker poll. The
+// storage paths its earlier attempts deleted are skipped, so only the failed ones
+// run again, and prefixes resume from their cleanup checkpoints.
+func (s *VideoService) RetryDeletion(ctx context.Context, id uint) (*models.VideoDeletion, error) {
+	res := s.db.WithContext(ctx).Model(&models.VideoDeletion{}).
+		Where("id = ? AND status = ?", id, models.DeletionFailed).
+		Updates(map[string]interface{}{
+			"status":      models.DeletionPending,
+			"run_after":   time.Now().UTC(),
+			"finished_at": nil,
+		})
+	if res.Error != nil {
+		return nil, fmt.Errorf("retry deletion job: %w", res.Error)
+	}
+	job, err := s.GetDeletion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("deletion %d is %s: %w", id, job.Status, ErrDeletionNotFailed)
+	}
+	return job, nil
+}
+
+// LatestDeletion returns the most recent deletion job for a video, or
+// ErrDeletionNotFound when it has none
+func (s *VideoService) LatestDeletion(ctx context.Context, videoID uint) (*models.VideoDeletion, error) {
+	var job models.VideoDeletion
+	if err := s.db.WithContext(ctx).Where("video_id = ?", videoID).Order("id DESC").First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("deletion for video %d: %w", videoID, ErrDeletionNotFound)
+		}
+		return nil, fmt.Errorf("get deletion job: %w", err)
+	}
+	return &job, nil
+}
+
+// purgeVideo removes job's video, files and row, for good, whether or not it was
+// soft-deleted first
+func (s *VideoService) purgeVideo(ctx context.Context, job *models.VideoDeletion) (DeletionProgress, error) {
+	id, actorID := job.VideoID, job.ActorID
+	deletes, storageErr := s.deletes()
+	if deletes != nil {
+		progress, err := deletes.DeleteVideoCompletely(ctx, id, job.ID, actorID)
+		if err != nil {
+			s.logger.Errorw("Failed to delete video completely", "error", err, "videoID", id)
+		}
+		return progress, err
+	}
+
+	s.logger.Warnw("Storage client not available - purging video from database only", "videoID", id, "reason", storageErr)
+	metrics.DatabaseOnlyPurgesTotal.Inc()
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var video models.Video
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&video, id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("video %d: %w", id, ErrVideoNotFound)
+			}
+			return err
+		}
+		if err := tx.Unscoped().Delete(&video).Error; err != nil {
+			return err
+		}
+		if video.DeletedAt.Valid {
+			return nil
+		}
+		return recordRemoval(tx, s.outbox, &video, actorID)
+	})
+	if err != nil && !errors.Is(err, ErrVideoNotFound) {
+		s.logger.Errorw("Failed to purge video from database", "error", err, "videoID", id)
+		return DeletionProgress{}, fmt.Errorf("failed to purge video: %w", err)
+	}
+	return DeletionProgress{}, err
+}
+
+// QueueExpiredDeletions queues deletion jobs for up to limit soft-deleted videos
+// that are past their restore window but have no job: videos deleted before
+// deletion jobs existed, which also have no purge_after. It returns how many it
+// queued.
+func (s *VideoService) QueueExpiredDeletions(ctx context.Context, limit int) (int, error) {
+	var videos []models.Video
+	if err := s.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND (purge_after IS NULL OR purge_after <= ?)", time.Now().UTC()).
+		Where("NOT EXISTS (SELECT 1 FROM video_deletions d WHERE d.video_id = videos.id AND d.status <> ?)", models.DeletionCancelled).
+		Order("id").Limit(limit).Find(&videos).Error; err != nil {
+		return 0, fmt.Errorf("list expired videos: %w", err)
+	}
+	for i := range videos {
+		if _, err := queueDeletion(s.db.WithContext(ctx), &videos[i], ActorSystem, time.Now().UTC()); err != nil {
+			return i, err
+		}
+	}
+	if len(videos) > 0 {
+		s.logger.Infow("Queued deletion jobs for expired videos", "videos", len(videos))
+	}
+	return len(videos), nil
+}
+
+// DeletionWorker runs video deletion jobs in the background. Each replica may run
+// one; jobs are claimed with SKIP LOCKED, and a job left running longer than the
+// job timeout (its worker died) is claimed again.
+type DeletionWorker struct {
+	videos   *VideoService
+	logger   *zap.SugaredLogger
+	workers  int
+	interval time.Duration
+	timeout  time.Duration
+	wake     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewDeletionWorker creates a worker running up to workers jobs at once, polling
+// every interval. timeout bounds one attempt at a job.
+func NewDeletionWorker(videos *VideoService, logger *zap.SugaredLogger, workers int, interval, timeout time.Duration) *DeletionWorker {
+	if workers < 1 {
+		workers = 1
+	}
+	return &DeletionWorker{
+		videos:   videos,
+		logger:   logger,
+		workers:  workers,
+		interval: interval,
+		timeout:  timeout,
+		wake:     make(chan struct{}, workers),
+	}
+}
+
+// Observe is a VideoChangeHook that wakes the worker when a deletion is queued
+func (w *DeletionWorker) Observe(_ context.Context, change VideoChange) {
+	if change.Kind != VideoDeleted {
+		return
+	}
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run starts the workers; they stop when ctx is cancelled
+func (w *DeletionWorker) Run(ctx context.Context) {
+	for i := 0; i < w.workers; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.loop(ctx)
+		}()
+	}
+}
+
+// Wait blocks until the workers have stopped. A job interrupted by shutdown goes
+// back to pending and resumes from its checkpoints.
+func (w *DeletionWorker) Wait() { w.wg.Wait() }
+
+func (w *DeletionWorker) loop(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil {
+			job, err := w.claim(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					w.logger.Errorw("Failed to claim deletion job", "error", err)
+				}
+				break
+			}
+			if job == nil {
+				break
+			}
+			w.process(ctx, job)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim marks the next due job running and returns it; nil when none is due
+func (w *DeletionWorker) claim(ctx context.Context) (*models.VideoDeletion, error) {
+	var job models.VideoDeletion
+	now := time.Now().UTC()
+	err := w.videos.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_after <= ?) OR (status = ? AND started_at < ?)",
+				models.DeletionPending, now, models.DeletionRunning, now.Add(-w.timeout)).
+			Order("run_after, id").First(&job).Error
+		if err != nil {
+			return err
+		}
+		job.Status = models.DeletionRunning
+		job.Attempts++
+		job.StartedAt = &now
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":     job.Status,
+			"attempts":   job.Attempts,
+			"started_at": now,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// process runs one attempt at job and records how it ended
+func (w *DeletionWorker) process(ctx context.Context, job *models.VideoDeletion) {
+	runCtx, cancel := context.WithTimeout(ctx, w.timeout)
+	progress, err := w.videos.purgeVideo(runCtx, job)
+	cancel()
+
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"files_deleted":      job.FilesDeleted + progress.FilesDeleted,
+		"blobs_deleted":      progress.BlobsDeleted,
+		"remaining_prefixes": progress.RemainingPrefixes,
+	}
+	outcome := models.DeletionCompleted
+	switch {
+	case err == nil || errors.Is(err, ErrVideoNotFound):
+		// Not found: an earlier attempt removed the row but didn't record it
+		updates["status"] = models.DeletionCompleted
+		updates["error"] = ""
+		updates["finished_at"] = now
+	case ctx.Err() != nil:
+		// Shutting down: the next worker resumes from the checkpoints
+		outcome = "interrupted"
+		updates["status"] = models.DeletionPending
+	default:
+		outcome = models.DeletionFailed
+		updates["status"] = models.DeletionFailed
+		updates["error"] = err.Error()
+		updates["finished_at"] = now
+	}
+	// Recorded even when ctx is done, so an interrupted job doesn't sit in running
+	if dbErr := w.videos.db.WithContext(context.WithoutCancel(ctx)).Model(job).Updates(updates).Error; dbErr != nil {
+		w.logger.Errorw("Failed to record deletion job outcome", "error", dbErr, "deletionID", job.ID, "outcome", outcome)
+	}
+	metrics.VideoDeletionsTotal.WithLabelValues(outcome).Inc()
+	if outcome == models.DeletionFailed {
+		w.logger.Errorw("Video deletion job failed", "error", err, "deletionID", job.ID, "videoID", job.VideoID,
+			"remainingPrefixes", progress.RemainingPrefixes)
+		return
+	}
+	w.logger.Infow("Video deletion job finished", "deletionID", job.ID, "videoID", job.VideoID, "outcome", outcome,
+		"blobsDeleted", progress.BlobsDeleted)
+}
@@ -0,0 +1,173 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// hangingStorage is a fakeStorage whose deletes wait for their context, reporting
+// on started when the first one begins
+type hangingStorage struct {
+	*fakeStorage
+	started chan struct{}
+}
+
+func (s *hangingStorage) DeleteBlob(ctx context.Context, _ string) error {
+	select {
+	case s.started <- struct{}{}:
+	default:
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func runWorker(t *testing.T, worker *services.DeletionWorker) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	worker.Run(ctx)
+	stop = func() { cancel(); worker.Wait() }
+	t.Cleanup(stop)
+	return stop
+}
+
+// TestAdminDeleteWakesWorker purges an admin-deleted video straight away, without
+// waiting for the worker's next poll
+func TestAdminDeleteWakesWorker(t *testing.T) {
+	db := dbtest.Open(t)
+	storage := newFakeStorage("raw/owner/up-1.mp4")
+	videos := services.NewVideoService(db, storageLoader(storage), videoSettings, nopLogger())
+	ctx := context.Background()
+	worker := services.NewDeletionWorker(videos, nopLogger(), 1, time.Hour, time.Minute)
+	videos.Changes().Subscribe("deletions", worker.Observe)
+	runWorker(t, worker)
+	video := createVideo(t, db, models.Video{Title: "t", RawVideoPath: "raw/owner/up-1.mp4"})
+
+	job, err := videos.DeleteVideoAsAdmin(ctx, video.ID, "admin-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != models.DeletionPending || job.ActorID != "admin-1" || job.UserID != "owner" || time.Until(job.RunAfter) > 0 {
+		t.Errorf("queued job = %+v, want a due pending job for the owner's video", job)
+	}
+	waitFor(t, "the admin deletion to complete", func() bool {
+		job, err := videos.GetDeletion(ctx, job.ID)
+		return err == nil && job.Status == models.DeletionCompleted
+	})
+	done, _ := videos.GetDeletion(ctx, job.ID)
+	if done.Attempts != 1 || done.FinishedAt == nil || done.Error != "" {
+		t.Errorf("completed job = %+v", done)
+	}
+	if storage.has("raw/owner/up-1.mp4") {
+		t.Error("raw file survived the purge")
+	}
+}
+
+// TestDeletionWorkerReclaimsStaleJob claims a job whose worker died mid-run once
+// the job timeout has passed, and leaves one still within it alone
+func TestDeletionWorkerReclaimsStaleJob(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+	stale := createVideo(t, db, models.Video{Title: "stale"})
+	busy := createVideo(t, db, models.Video{Title: "busy"})
+	long := time.Now().UTC().Add(-10 * time.Minute)
+	recent := time.Now().UTC().Add(-10 * time.Second)
+	staleJob := models.VideoDeletion{VideoID: stale.ID, UserID: "owner", ActorID: "owner", Status: models.DeletionRunning,
+		RunAfter: long, StartedAt: &long, Attempts: 1}
+	busyJob := models.VideoDeletion{VideoID: busy.ID, UserID: "owner", ActorID: "owner", Status: models.DeletionRunning,
+		RunAfter: long, StartedAt: &recent, Attempts: 1}
+	db.Create(&staleJob)
+	db.Create(&busyJob)
+
+	runWorker(t, services.NewDeletionWorker(videos, nopLogger(), 1, 10*time.Millisecond, time.Minute))
+	waitFor(t, "the stale job to be reclaimed", func() bool {
+		job, err := videos.GetDeletion(ctx, staleJob.ID)
+		return err == nil && job.Status == models.DeletionCompleted
+	})
+	if job, _ := videos.GetDeletion(ctx, staleJob.ID); job.Attempts != 2 {
+		t.Errorf("reclaimed job made %d attempts, want 2", job.Attempts)
+	}
+	if job, _ := videos.GetDeletion(ctx, busyJob.ID); job.Status != models.DeletionRunning || job.Attempts != 1 {
+		t.Errorf("job within its timeout = %s after %d attempts, want left running", job.Status, job.Attempts)
+	}
+}
+
+// TestDeletionWorkerShutdown puts a job interrupted by shutdown back to pending
+func TestDeletionWorkerShutdown(t *testing.T) {
+	db := dbtest.Open(t)
+	storage := &hangingStorage{fakeStorage: newFakeStorage("raw/owner/up-1.mp4"), started: make(chan struct{}, 1)}
+	videos := services.NewVideoService(db, storageLoader(storage), videoSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{Title: "t", RawVideoPath: "raw/owner/up-1.mp4"})
+	job, err := videos.DeleteVideoAsAdmin(ctx, video.ID, "admin-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := runWorker(t, services.NewDeletionWorker(videos, nopLogger(), 1, 10*time.Millisecond, time.Minute))
+	select {
+	case <-storage.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the worker never started the job")
+	}
+	stop()
+
+	got, err := videos.GetDeletion(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.DeletionPending || got.FinishedAt != nil || got.Error != "" {
+		t.Errorf("interrupted job = %+v, want pending to resume", got)
+	}
+	var rows int64
+	db.Unscoped().Model(&models.Video{}).Where("id = ?", video.ID).Count(&rows)
+	if rows != 1 {
+		t.Error("video row removed by an interrupted job")
+	}
+}
+
+// TestDeletionWithoutStorage purges the row alone when there is no storage
+// client, and counts it
+func TestDeletionWithoutStorage(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{Title: "t", RawVideoPath: "raw/owner/up-1.mp4"})
+	gone := createVideo(t, db, models.Video{Title: "gone"})
+	before := testutil.ToFloat64(metrics.DatabaseOnlyPurgesTotal)
+
+	job, err := videos.DeleteVideoAsAdmin(ctx, video.ID, "admin-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Removed by an earlier attempt that didn't get to record it
+	goneJob, err := videos.DeleteVideoAsAdmin(ctx, gone.ID, "admin-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Unscoped().Delete(&models.Video{}, gone.ID)
+
+	runWorker(t, services.NewDeletionWorker(videos, nopLogger(), 1, 10*time.Millisecond, time.Minute))
+	for _, id := range []uint{job.ID, goneJob.ID} {
+		waitFor(t, "the database-only purge", func() bool {
+			job, err := videos.GetDeletion(ctx, id)
+			return err == nil && job.Status == models.DeletionCompleted
+		})
+	}
+	var rows int64
+	db.Unscoped().Model(&models.Video{}).Where("id = ?", video.ID).Count(&rows)
+	if rows != 0 {
+		t.Error("video row kept by a database-only purge")
+	}
+	if got := testutil.ToFloat64(metrics.DatabaseOnlyPurgesTotal) - before; got != 2 {
+		t.Errorf("database-only purges counted %v, want 2", got)
+	}
+}
@@ -0,0 +1,182 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+var filterCategories = []string{"music", "gaming", ""}
+
+// seedFilterVideos creates 30 videos by owner cycling through the categories,
+// alternately ready and processing, with every fifth private
+func seedFilterVideos(t *testing.T, db *gorm.DB) []models.Video {
+	t.Helper()
+	videos := make([]models.Video, 30)
+	for i := range videos {
+		videos[i] = models.Video{
+			UploadID: fmt.Sprintf("filter-%02d", i),
+			UserID:   "owner",
+			Title:    fmt.Sprintf("video %d", i),
+			Category: filterCategories[i%3],
+			Status:   models.StatusReady,
+		}
+		if i%2 == 1 {
+			videos[i].Status = models.StatusProcessing
+		}
+		if i%5 == 0 {
+			videos[i].Visibility = models.VisibilityPrivate
+		}
+	}
+	if err := db.Create(&videos).Error; err != nil {
+		t.Fatal(err)
+	}
+	return videos
+}
+
+// The tag filter uses Postgres array containment, which SQLite can't run; it is
+// left to the Postgres-backed environments.
+func TestListVideosFilters(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	seeded := seedFilterVideos(t, db)
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		filters        services.VideoFilters
+		includePrivate bool
+	}{
+		{"none", services.VideoFilters{}, false},
+		{"category", services.VideoFilters{Category: "music"}, false},
+		{"status", services.VideoFilters{Status: models.StatusProcessing}, false},
+		{"category and status", services.VideoFilters{Category: "gaming", Status: models.StatusReady}, false},
+		{"owner sees private", services.VideoFilters{Category: "gaming", Status: models.StatusReady}, true},
+		{"unknown category", services.VideoFilters{Category: "cooking"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := map[uint]bool{}
+			for _, v := range seeded {
+				if (tt.includePrivate || v.Visibility != models.VisibilityPrivate) &&
+					(tt.filters.Category == "" || v.Category == tt.filters.Category) &&
+					(tt.filters.Status == "" || v.Status == tt.filters.Status) {
+					want[v.ID] = true
+				}
+			}
+
+			// A page smaller than the match count still reports the filtered total
+			list, err := videos.ListVideos(ctx, "owner", 1, 4, tt.includePrivate, services.VideoSort{}, tt.filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if list.Total != int64(len(want)) {
+				t.Errorf("total = %d, want %d", list.Total, len(want))
+			}
+			all, err := videos.ListVideos(ctx, "owner", 1, 100, tt.includePrivate, services.VideoSort{}, tt.filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			after, _, err := videos.ListVideosAfter(ctx, "owner", tt.includePrivate, tt.filters, nil, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, got := range map[string][]models.Video{"ListVideos": all.Videos, "ListVideosAfter": after} {
+				if len(got) != len(want) {
+					t.Errorf("%s returned %d videos, want %d", name, len(got), len(want))
+				}
+				for _, v := range got {
+					if !want[v.ID] {
+						t.Errorf("%s returned %q (category %q, %s)", name, v.Title, v.Category, v.Status)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestListVideosInvalidStatus(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	filters := services.VideoFilters{Status: "published"}
+
+	if _, err := videos.ListVideos(ctx, "", 1, 20, false, services.VideoSort{}, filters); !errors.Is(err, services.ErrInvalidStatus) {
+		t.Errorf("ListVideos error = %v, want ErrInvalidStatus", err)
+	}
+	if _, _, err := videos.ListVideosAfter(ctx, "", false, filters, nil, 20); !errors.Is(err, services.ErrInvalidStatus) {
+		t.Errorf("ListVideosAfter error = %v, want ErrInvalidStatus", err)
+	}
+}
+
+// TestSearchVideosFilters searches with an empty query, so only the filters narrow
+// the public videos; duration and upload date bounds are inclusive
+func TestSearchVideosFilters(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	seeded := []models.Video{
+		{Title: "short music", Category: "music", Duration: 30, CreatedAt: day(1)},
+		{Title: "long music", Category: "music", Duration: 600, CreatedAt: day(10)},
+		{Title: "gaming", Category: "gaming", Duration: 120, CreatedAt: day(20)},
+		{Title: "private", Category: "music", Duration: 120, CreatedAt: day(10), Visibility: models.VisibilityPrivate},
+	}
+	for i := range seeded {
+		seeded[i].UploadID, seeded[i].UserID, seeded[i].Status = fmt.Sprintf("search-%d", i), "owner", models.StatusReady
+	}
+	if err := db.Create(&seeded).Error; err != nil {
+		t.Fatal(err)
+	}
+	seconds := func(s float64) *float64 { return &s }
+	at := func(d int) *time.Time { t := day(d); return &t }
+
+	tests := []struct {
+		name    string
+		filters services.VideoFilters
+		want    string
+	}{
+		{"none", services.VideoFilters{}, "short music,long music,gaming"},
+		{"category", services.VideoFilters{Category: "music"}, "short music,long music"},
+		{"min duration, inclusive", services.VideoFilters{MinDuration: seconds(120)}, "long music,gaming"},
+		{"max duration, inclusive", services.VideoFilters{MaxDuration: seconds(120)}, "short music,gaming"},
+		{"duration range", services.VideoFilters{MinDuration: seconds(31), MaxDuration: seconds(599)}, "gaming"},
+		{"uploaded after, inclusive", services.VideoFilters{UploadedAfter: at(10)}, "long music,gaming"},
+		{"uploaded before, inclusive", services.VideoFilters{UploadedBefore: at(10)}, "short music,long music"},
+		{"everything", services.VideoFilters{Category: "music", MinDuration: seconds(60), UploadedAfter: at(2), UploadedBefore: at(15)}, "long music"},
+		{"nothing matches", services.VideoFilters{Category: "gaming", MaxDuration: seconds(60)}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sort := services.VideoSort{Key: services.SortCreatedAt, Order: "asc"}
+			result, err := videos.SearchVideos(ctx, "", 1, 2, sort, tt.filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.Split(tt.want, ",")
+			if tt.want == "" {
+				want = nil
+			}
+			if result.Total != int64(len(want)) {
+				t.Errorf("total %d, want %d", result.Total, len(want))
+			}
+			all, _ := videos.SearchVideos(ctx, "", 1, 20, sort, tt.filters)
+			var got []string
+			for _, v := range all.Videos {
+				got = append(got, v.Title)
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("found %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/cursor"
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// seedVideos creates n videos for owner whose created_at repeats in runs of seven,
+// so pages keep ending in the middle of a tie
+func seedVideos(t *testing.T, db *gorm.DB, owner string, n int) {
+	t.Helper()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	videos := make([]models.Video, n)
+	for i := range videos {
+		videos[i] = models.Video{
+			UploadID:  fmt.Sprintf("%s-%03d", owner, i),
+			UserID:    owner,
+			Title:     fmt.Sprintf("video %d", i),
+			Status:    models.StatusReady,
+			CreatedAt: base.Add(time.Duration(i/7) * time.Minute),
+		}
+		if i%10 == 0 {
+			videos[i].Visibility = models.VisibilityPrivate
+		}
+	}
+	if err := db.CreateInBatches(videos, 100).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// walkAfter pages through ListVideosAfter and returns every video in order
+func walkAfter(t *testing.T, videos *services.VideoService, userID string, includePrivate bool, limit int, between func()) []models.Video {
+	t.Helper()
+	var all []models.Video
+	var after *cursor.TimeID
+	for pages := 0; ; pages++ {
+		if pages > 1000 {
+			t.Fatal("paging doesn't end")
+		}
+		page, more, err := videos.ListVideosAfter(context.Background(), userID, includePrivate, services.VideoFilters{}, after, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) > limit || (more && len(page) != limit) {
+			t.Fatalf("page of %d with more=%v at limit %d", len(page), more, limit)
+		}
+		all = append(all, page...)
+		if !more {
+			return all
+		}
+		last := page[len(page)-1]
+		after = &cursor.TimeID{CreatedAt: last.CreatedAt, ID: last.ID}
+		if between != nil {
+			between()
+		}
+	}
+}
+
+// checkWalk checks videos are exactly want distinct rows in created_at, id
+// descending order
+func checkWalk(t *testing.T, videos []models.Video, want int) {
+	t.Helper()
+	seen := map[uint]bool{}
+	for i, v := range videos {
+		if seen[v.ID] {
+			t.Fatalf("video %d returned twice", v.ID)
+		}
+		seen[v.ID] = true
+		if i == 0 {
+			continue
+		}
+		prev := videos[i-1]
+		if v.CreatedAt.After(prev.CreatedAt) || (v.CreatedAt.Equal(prev.CreatedAt) && v.ID > prev.ID) {
+			t.Fatalf("video %d (%s) follows video %d (%s): out of order", v.ID, v.CreatedAt, prev.ID, prev.CreatedAt)
+		}
+	}
+	if len(videos) != want {
+		t.Errorf("walked %d videos, want %d", len(videos), want)
+	}
+}
+
+func TestListVideosAfterWalk(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	seedVideos(t, db, "owner", 250)
+
+	for _, limit := range []int{1, 7, 20, 100, 250, 500} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			// One in ten is private: 225 public, all 250 for the owner
+			checkWalk(t, walkAfter(t, videos, "", false, limit, nil), 225)
+			checkWalk(t, walkAfter(t, videos, "owner", true, limit, nil), 250)
+		})
+	}
+}
+
+func TestListVideosAfterStableUnderInserts(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	seedVideos(t, db, "owner", 250)
+
+	// New uploads land while a client pages; they sort ahead of its position, so
+	// nothing shifts and nothing repeats
+	n := 0
+	walked := walkAfter(t, videos, "owner", true, 20, func() {
+		n++
+		createVideo(t, db, models.Video{Title: fmt.Sprintf("new-%d", n), UserID: "owner"})
+	})
+	checkWalk(t, walked, 250)
+	for _, v := range walked {
+		if strings.HasPrefix(v.Title, "new-") {
+			t.Errorf("video %q added mid-walk was returned", v.Title)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// TestTranscodedEventPreviews stores previews from a transcoded event and checks a
+// later event without them, or with an incomplete grid, leaves them in place
+func TestTranscodedEventPreviews(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	createVideo(t, db, models.Video{UploadID: "up-1", UserID: "owner", Title: "t"})
+	transcoded := func(previews *models.PreviewInfo) *models.Video {
+		t.Helper()
+		if err := videos.HandleTranscodedEvent(ctx, &models.TranscodedEvent{UploadID: "up-1", UserID: "owner", Ready: true, Previews: previews}); err != nil {
+			t.Fatal(err)
+		}
+		video, err := videos.GetVideoByUploadID(ctx, "up-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return video
+	}
+
+	video := transcoded(&models.PreviewInfo{SpriteURL: "https://cdn/s.jpg", TileWidth: 160, TileHeight: 90, Columns: 10, IntervalSeconds: 2, Count: 40,
+		VTTURL: "https://cdn/p.vtt"})
+	if p := video.Previews; p == nil || p.SpriteURL != "https://cdn/s.jpg" || p.Count != 40 || p.VTTURL != "https://cdn/p.vtt" {
+		t.Fatalf("previews = %+v", video.Previews)
+	}
+	if video = transcoded(nil); video.Previews == nil || video.Previews.Count != 40 {
+		t.Errorf("an event without previews changed them to %+v", video.Previews)
+	}
+	if video = transcoded(&models.PreviewInfo{SpriteURL: "https://cdn/other.jpg"}); video.Previews == nil || video.Previews.SpriteURL != "https://cdn/s.jpg" {
+		t.Errorf("an incomplete grid replaced the previews with %+v", video.Previews)
+	}
+
+	disabled := true
+	video, err := videos.UpdateVideoForUser(ctx, video.ID, "owner", 0, &models.VideoUpdateRequest{PreviewsDisabled: &disabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !video.PreviewsDisabled || video.Previews == nil {
+		t.Errorf("after disabling: disabled %v, previews %+v; want them kept but hidden", video.PreviewsDisabled, video.Previews)
+	}
+}
+
+func TestDeleteVideoCompletelyRemovesPreviews(t *testing.T) {
+	db := dbtest.Open(t)
+	video, storage := hlsVideo(t, db, 2)
+	for _, name := range []string{"previews/owner/up-1/sprite-0.jpg", "previews/owner/up-1/sprite-1.jpg", "previews/owner/up-1/thumbs.vtt"} {
+		storage.blobs[name] = nil
+	}
+	storage.blobs["previews/owner/up-10/sprite-0.jpg"] = nil
+
+	progress, err := services.NewVideoDeleteService(db, nopLogger(), storage).DeleteVideoCompletely(context.Background(), video.ID, 1, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.BlobsDeleted != 5 {
+		t.Errorf("deleted %d blobs, want the 2 segments and 3 previews", progress.BlobsDeleted)
+	}
+	if len(storage.blobs) != 1 || !storage.has("previews/owner/up-10/sprite-0.jpg") {
+		t.Errorf("blobs left: %v, want only another upload's preview", storage.blobs)
+	}
+}
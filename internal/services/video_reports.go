@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// ReportService files users' abuse reports about videos and lets admins work
+// through them. Resolving a report can change the video's moderation status.
+type ReportService struct {
+	db     *gorm.DB
+	videos *VideoService
+	logger *zap.SugaredLogger
+}
+
+// NewReportService creates a report service; moderation decisions are reported
+// through videos' change hub and outbox
+func NewReportService(db *gorm.DB, videos *VideoService, logger *zap.SugaredLogger) *ReportService {
+	return &ReportService{db: db, videos: videos, logger: logger}
+}
+
+// Report files reporterID's report about videoID. A user with an open report on
+// the video gets ErrReportExists.
+func (s *ReportService) Report(ctx context.Context, videoID uint, reporterID string, reason models.ReportReason, details string) (*models.VideoReport, error) {
+	report := &models.VideoReport{
+		VideoID:    videoID,
+		ReporterID: reporterID,
+		Reason:     reason,
+		Details:    details,
+		Status:     models.ReportOpen,
+	}
+	if err := s.db.WithContext(ctx).Create(report).Error; err != nil {
+		// The partial unique index allows one open report per user and video
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("video %d: %w", videoID, ErrReportExists)
+		}
+		return nil, fmt.Errorf("create report: %w", err)
+	}
+	metrics.VideoReportsTotal.WithLabelValues(string(reason)).Inc()
+	return report, nil
+}
+
+// GetReport returns the report with id
+func (s *ReportService) GetReport(ctx context.Context, id uint) (*models.VideoReport, error) {
+	var report models.VideoReport
+	if err := s.db.WithContext(ctx).First(&report, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("report %d: %w", id, ErrReportNotFound)
+		}
+		return nil, fmt.Errorf("get report: %w", err)
+	}
+	return &report, nil
+}
+
+// ListReports lists reports newest first, narrowed to status and videoID when set
+func (s *ReportService) ListReports(ctx context.Context, status models.ReportStatus, videoID uint, page, perPage int) ([]models.VideoReport, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.VideoReport{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if videoID != 0 {
+		query = query.Where("video_id = ?", videoID)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count reports: %w", err)
+	}
+	reports := []models.VideoReport{}
+	if err := query.Order("id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&reports).Error; err != nil {
+		return nil, 0, fmt.Errorf("list reports: %w", err)
+	}
+	return reports, total, nil
+}
+
+// ReportedVideos lists the videos with open reports, most open reports first,
+// with their report counts
+func (s *ReportService) ReportedVideos(ctx context.Context, page, perPage int) ([]models.ReportedVideo, int64, error) {
+	var total int64
+	err := s.db.WithContext(ctx).Raw(`SELECT COUNT(DISTINCT r.video_id) FROM video_reports r
+		JOIN videos v ON v.id = r.video_id AND v.deleted_at IS NULL
+		WHERE r.status = ?`, models.ReportOpen).Scan(&total).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("count reported videos: %w", err)
+	}
+	videos := []models.ReportedVideo{}
+	err = s.db.WithContext(ctx).Raw(`SELECT r.video_id, v.title, v.user_id, v.moderation_status,
+			COUNT(*) FILTER (WHERE r.status = ?) AS open_reports, COUNT(*) AS total_reports,
+			MAX(r.created_at) AS last_reported_at
+		FROM video_reports r
+		JOIN videos v ON v.id = r.video_id AND v.deleted_at IS NULL
+		GROUP BY r.video_id, v.title, v.user_id, v.moderation_status
+		HAVING COUNT(*) FILTER (WHERE r.status = ?) > 0
+		ORDER BY open_reports DESC, last_reported_at DESC, r.video_id
+		LIMIT ? OFFSET ?`, models.ReportOpen, models.ReportOpen, perPage, (page-1)*perPage).Scan(&videos).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("list reported videos: %w", err)
+	}
+	return videos, total, nil
+}
+
+// ReportResolution is the outcome of resolving a report
+type ReportResolution struct {
+	Report *models.VideoReport `json:"report"`
+	// Video is set when the resolution changed the video's moderation status
+	Video *models.Video `json:"video,omitempty"`
+	// AlsoResolved counts the video's other open reports closed with this one
+	AlsoResolved int64 `json:"also_resolved"`
+}
+
+// Resolve closes an open report as reviewed or dismissed by adminID. With a
+// moderation status, the video is moved to it and the video's other open reports
+// are closed the same way, since the decision covers them too. Taking a video down
+// logs video.taken_down; reinstating a listed, ready video logs video.published.
+func (s *ReportService) Resolve(ctx context.Context, id uint, status models.ReportStatus, moderation *models.ModerationStatus, adminID, note string) (*ReportResolution, error) {
+	if status != models.ReportReviewed && status != models.ReportDismissed {
+		return nil, fmt.Errorf("resolve report %d as %q: %w", id, status, ErrInvalidStatus)
+	}
+	if moderation != nil && !moderation.Valid() {
+		return nil, fmt.Errorf("moderation status %q: %w", *moderation, ErrInvalidStatus)
+	}
+	result := &ReportResolution{Report: &models.VideoReport{}}
+	var video models.Video
+	changed := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		report := result.Report
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(report, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("report %d: %w", id, ErrReportNotFound)
+			}
+			return err
+		}
+		if report.Status != models.ReportOpen {
+			return fmt.Errorf("report %d is %s: %w", id, report.Status, ErrReportResolved)
+		}
+		now := time.Now().UTC()
+		resolution := map[string]interface{}{"status": status, "resolved_by": adminID, "resolution_note": note, "resolved_at": now}
+		if err := tx.Model(report).Updates(resolution).Error; err != nil {
+			return fmt.Errorf("resolve report: %w", err)
+		}
+		report.Status, report.ResolvedBy, report.ResolutionNote, report.ResolvedAt = status, adminID, note, &now
+		if moderation == nil {
+			return nil
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&video, report.VideoID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("video %d: %w", report.VideoID, ErrVideoNotFound)
+			}
+			return err
+		}
+		others := tx.Model(&models.VideoReport{}).Where("video_id = ? AND status = ?", report.VideoID, models.ReportOpen).
+			Updates(resolution)
+		if others.Error != nil {
+			return fmt.Errorf("resolve other reports: %w", others.Error)
+		}
+		result.AlsoResolved = others.RowsAffected
+		if video.ModerationStatus == *moderation {
+			return nil
+		}
+
+		before := video
+		video.ModerationStatus = *moderation
+		video.Version++
+		if err := tx.Model(&video).Updates(map[string]interface{}{"moderation_status": video.ModerationStatus, "version": video.Version}).Error; err != nil {
+			return fmt.Errorf("set moderation status: %w", err)
+		}
+		changed = true
+		if err := enqueueCatalogEvent(tx, s.videos.outbox, VideoModerated, &video); err != nil {
+			return err
+		}
+		if video.IsTakenDown() && !before.IsTakenDown() {
+			event := newPublicEvent(models.PublicEventTakenDown, &video, adminID)
+			event.Details = map[string]interface{}{"report_id": report.ID, "reason": report.Reason}
+			return appendPublicEvent(tx, event)
+		}
+		return appendPublishedEvent(tx, before, &video, adminID)
+	})
+	if err != nil {
+		if errors.Is(err, ErrReportNotFound) || errors.Is(err, ErrReportResolved) || errors.Is(err, ErrVideoNotFound) {
+			return nil, err
+		}
+		s.logger.Errorw("Failed to resolve report", "error", err, "reportID", id)
+		return nil, fmt.Errorf("resolve report: %w", err)
+	}
+	metrics.VideoReportsResolvedTotal.WithLabelValues(string(status)).Add(float64(1 + result.AlsoResolved))
+	if changed {
+		result.Video = &video
+		s.videos.changes.Emit(ctx, VideoModerated, &video)
+		s.logger.Infow("Video moderation status changed", "videoID", video.ID, "moderationStatus", video.ModerationStatus, "reportID", id, "admin", adminID)
+	}
+	return result, nil
+}
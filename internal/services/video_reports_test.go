@@ -0,0 +1,114 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestOneOpenReportPerUserAndVideo(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	reports := services.NewReportService(db, videos, nopLogger())
+	video := createVideo(t, db, models.Video{Title: "t"})
+	other := createVideo(t, db, models.Video{Title: "other"})
+
+	first, err := reports.Report(ctx, video.ID, "u-1", models.ReportSpam, "ads")
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if first.Status != models.ReportOpen || first.ReporterID != "u-1" || first.Reason != models.ReportSpam {
+		t.Errorf("report = %+v", first)
+	}
+	if _, err := reports.Report(ctx, video.ID, "u-1", models.ReportHate, ""); !errors.Is(err, services.ErrReportExists) {
+		t.Errorf("second open report: %v, want ErrReportExists", err)
+	}
+	if _, err := reports.Report(ctx, video.ID, "u-2", models.ReportSpam, ""); err != nil {
+		t.Errorf("another user: %v", err)
+	}
+	if _, err := reports.Report(ctx, other.ID, "u-1", models.ReportSpam, ""); err != nil {
+		t.Errorf("another video: %v", err)
+	}
+
+	// Once resolved, the user may report the video again
+	if _, err := reports.Resolve(ctx, first.ID, models.ReportDismissed, nil, "admin", ""); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if _, err := reports.Report(ctx, video.ID, "u-1", models.ReportHate, ""); err != nil {
+		t.Errorf("report after resolution: %v", err)
+	}
+}
+
+func TestResolveReport(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	reports := services.NewReportService(db, videos, nopLogger())
+	video := createVideo(t, db, models.Video{Title: "t", Status: models.StatusReady})
+	report := func(user string) *models.VideoReport {
+		t.Helper()
+		r, err := reports.Report(ctx, video.ID, user, models.ReportViolence, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	dismissed, reviewed, other := report("u-1"), report("u-2"), report("u-3")
+
+	if _, err := reports.Resolve(ctx, dismissed.ID, models.ReportOpen, nil, "admin", ""); !errors.Is(err, services.ErrInvalidStatus) {
+		t.Errorf("resolve as open: %v, want ErrInvalidStatus", err)
+	}
+	hidden := models.ModerationStatus("hidden")
+	if _, err := reports.Resolve(ctx, dismissed.ID, models.ReportReviewed, &hidden, "admin", ""); !errors.Is(err, services.ErrInvalidStatus) {
+		t.Errorf("unknown moderation status: %v, want ErrInvalidStatus", err)
+	}
+	if _, err := reports.Resolve(ctx, 999, models.ReportReviewed, nil, "admin", ""); !errors.Is(err, services.ErrReportNotFound) {
+		t.Errorf("missing report: %v, want ErrReportNotFound", err)
+	}
+
+	// Without a moderation status only the one report closes
+	result, err := reports.Resolve(ctx, dismissed.ID, models.ReportDismissed, nil, "admin", "fine")
+	if err != nil {
+		t.Fatalf("dismiss: %v", err)
+	}
+	if result.Report.Status != models.ReportDismissed || result.Report.ResolvedBy != "admin" || result.Report.ResolvedAt == nil ||
+		result.Video != nil || result.AlsoResolved != 0 {
+		t.Errorf("dismissal = %+v", result)
+	}
+	if _, err := reports.Resolve(ctx, dismissed.ID, models.ReportReviewed, nil, "admin", ""); !errors.Is(err, services.ErrReportResolved) {
+		t.Errorf("resolve twice: %v, want ErrReportResolved", err)
+	}
+
+	takenDown := models.ModerationStatusTakenDown
+	result, err = reports.Resolve(ctx, reviewed.ID, models.ReportReviewed, &takenDown, "admin", "gore")
+	if err != nil {
+		t.Fatalf("take down: %v", err)
+	}
+	if result.Video == nil || result.Video.ModerationStatus != takenDown || result.AlsoResolved != 1 {
+		t.Errorf("takedown = %+v", result)
+	}
+	stored, err := videos.GetVideo(ctx, video.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ModerationStatus != takenDown || stored.Version != video.Version+1 {
+		t.Errorf("video moderation %s, version %d; want taken_down, %d", stored.ModerationStatus, stored.Version, video.Version+1)
+	}
+	closed, err := reports.GetReport(ctx, other.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.Status != models.ReportReviewed || closed.ResolutionNote != "gore" {
+		t.Errorf("other open report = %+v, want reviewed with the decision", closed)
+	}
+	var events []models.PublicEvent
+	db.Where("video_id = ?", video.ID).Find(&events)
+	if len(events) != 1 || events[0].Type != models.PublicEventTakenDown || events[0].ActorID != "admin" {
+		t.Errorf("public events = %+v, want one takedown", events)
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// RestoreVideo brings back userID's soft-deleted video while its restore window is
+// open. It returns ErrVideoPurged once the window has passed or the video is gone,
+// ErrVideoNotDeleted for a live video, and a DuplicateUploadError if another video
+// has taken its upload ID meanwhile.
+func (s *VideoService) RestoreVideo(ctx context.Context, id uint, userID string) (*models.Video, error) {
+	var video models.Video
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&video, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.missingVideo(tx, id)
+		}
+		if err != nil {
+			return err
+		}
+		if video.UserID != userID {
+			return fmt.Errorf("restore video %d: %w", id, ErrForbidden)
+		}
+		if !video.DeletedAt.Valid {
+			return fmt.Errorf("restore video %d: %w", id, ErrVideoNotDeleted)
+		}
+		if video.PurgeAfter == nil || !time.Now().Before(*video.PurgeAfter) {
+			return fmt.Errorf("restore video %d: %w", id, ErrVideoPurged)
+		}
+		var holder models.Video
+		err = tx.Select("id", "user_id").Where("upload_id = ?", video.UploadID).First(&holder).Error
+		if err == nil {
+			return &DuplicateUploadError{UploadID: video.UploadID, VideoID: holder.ID, UserID: holder.UserID}
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("check upload_id: %w", err)
+		}
+
+		if err := tx.Unscoped().Model(&video).UpdateColumns(map[string]interface{}{
+			"deleted_at":  nil,
+			"purge_after": nil,
+			"version":     video.Version + 1,
+		}).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return fmt.Errorf("%w: %s", ErrDuplicateUploadID, video.UploadID)
+			}
+			return err
+		}
+		video.DeletedAt = gorm.DeletedAt{}
+		video.PurgeAfter = nil
+		video.Version++
+		if err := tx.Model(&models.VideoDeletion{}).
+			Where("video_id = ? AND status = ?", id, models.DeletionPending).
+			Updates(map[string]interface{}{"status": models.DeletionCancelled, "finished_at": time.Now().UTC()}).Error; err != nil {
+			return fmt.Errorf("cancel deletion job: %w", err)
+		}
+		if err := enqueueCatalogEvent(tx, s.outbox, VideoRestored, &video); err != nil {
+			return err
+		}
+		return appendPublicEvent(tx, newPublicEvent(models.PublicEventRestored, &video, userID))
+	})
+	if err != nil {
+		if errors.Is(err, ErrVideoNotFound) || errors.Is(err, ErrVideoPurged) || errors.Is(err, ErrForbidden) ||
+			errors.Is(err, ErrVideoNotDeleted) || errors.Is(err, ErrDuplicateUploadID) {
+			return nil, err
+		}
+		s.logger.Errorw("Failed to restore video", "error", err, "videoID", id)
+		return nil, fmt.Errorf("restore video: %w", err)
+	}
+	s.changes.Emit(ctx, VideoRestored, &video)
+	s.logger.Infow("Video restored", "videoID", id, "userID", userID)
+	return &video, nil
+}
+
+// missingVideo explains why no row exists for id: the public event log remembers
+// deletions after the row is purged, which tells a purged video from one that
+// never existed
+func (s *VideoService) missingVideo(tx *gorm.DB, id uint) error {
+	var deletions int64
+	if err := tx.Model(&models.PublicEvent{}).
+		Where("video_id = ? AND type = ?", id, models.PublicEventDeleted).Count(&deletions).Error; err != nil {
+		return fmt.Errorf("query public event log: %w", err)
+	}
+	if deletions > 0 {
+		return fmt.Errorf("video %d: %w", id, ErrVideoPurged)
+	}
+	return fmt.Errorf("video %d: %w", id, ErrVideoNotFound)
+}
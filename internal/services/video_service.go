@@ -2,96 +2,332 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/streamhive/video-catalog-api/internal/cache"
+	"github.com/streamhive/video-catalog-api/internal/config"
+	"github.com/streamhive/video-catalog-api/internal/cursor"
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/logging"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
 	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/tracing"
 )
 
 // VideoService handles video-related business logic
 type VideoService struct {
-	db            *gorm.DB
-	logger        *zap.SugaredLogger
-	deleteService *VideoDeleteService
+	db     *gorm.DB
+	logger *zap.SugaredLogger
+	// storage builds the blob storage client on first use; nil runs database-only
+	storage *StorageLoader
+	// uploadMisses short-circuits polling for upload IDs whose events haven't landed yet
+	uploadMisses *cache.NegativeCache
+	moderation   *ModerationService
+	changes      *VideoChanges
+	// outbox receives catalog events in the same transaction as each write; nil
+	// when they are disabled
+	outbox *Outbox
+	// deleteGrace is how long a deleted video can be restored before it is purged
+	deleteGrace time.Duration
+	// playbackTTL is how long a private video's signed playback URL is valid
+	playbackTTL time.Duration
+	// cache serves read-only lookups and public listings; nil when disabled
+	cache *VideoCache
+	// categories validates and normalizes categories; nil stores them as given
+	categories *CategoryService
+	// summaries briefly keeps public channel summaries
+	summaries *summaryCache
+	// search answers SearchVideos when set; the database is the fallback
+	search *SearchIndex
 }
 
-// NewVideoService creates a new video service
-func NewVideoService(db *gorm.DB, logger *zap.SugaredLogger) *VideoService {
-	// Initialize Azure client for deletion operations
-	azureClient, err := NewAzureClientAdapterFromEnv()
+// NewVideoService creates a new video service. While storage has no client, and
+// always when it is nil, deletes only touch the database.
+func NewVideoService(db *gorm.DB, storage *StorageLoader, cfg config.Videos, logger *zap.SugaredLogger) *VideoService {
+	svc := &VideoService{db: db, logger: logger, storage: storage, uploadMisses: cache.NewNegativeCache(cfg.UploadMissTTL, 10000), changes: NewVideoChanges(logger),
+		deleteGrace: cfg.DeleteGrace,
+		playbackTTL: cfg.PlaybackURLTTL,
+		summaries:   newSummaryCache(cfg.ChannelSummaryTTL, 10000)}
+	// Row is committed: drop any cached miss so pollers see it immediately
+	svc.changes.Subscribe("upload_miss_cache", func(_ context.Context, change VideoChange) {
+		if change.Kind == VideoCreated || change.Kind == VideoRestored {
+			svc.uploadMisses.Invalidate(change.UploadID)
+		}
+	})
+	svc.changes.Subscribe("channel_summary_cache", func(_ context.Context, change VideoChange) {
+		svc.summaries.invalidate(change.UserID)
+	})
+	return svc
+}
+
+// UploadMissTTL is how long an unknown upload ID is remembered as missing; clients
+// polling for it are told to retry after this interval.
+func (s *VideoService) UploadMissTTL() time.Duration { return s.uploadMisses.TTL() }
+
+// Changes returns the hub every video mutation is reported to; subscribe derived
+// state (search, feeds, outbound events) here
+func (s *VideoService) Changes() *VideoChanges { return s.changes }
+
+// log is the service's logger tagged with ctx's request ID
+func (s *VideoService) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.For(ctx, s.logger)
+}
+
+// SetModeration attaches the post-write moderation hook for titles and descriptions
+func (s *VideoService) SetModeration(m *ModerationService) { s.moderation = m }
+
+// Storage returns the blob storage client, or nil while running database-only
+func (s *VideoService) Storage() StorageClient {
+	client, _ := s.storage.Client()
+	return client
+}
+
+// deletes returns the storage cleanup for purges, or nil and the reason while there
+// is no storage client
+func (s *VideoService) deletes() (*VideoDeleteService, error) {
+	client, err := s.storage.Client()
 	if err != nil {
-		logger.Warnw("Failed to initialize Azure client for video deletion", "error", err)
-		// Continue without deletion service - deletion will be database-only
-		return &VideoService{db: db, logger: logger, deleteService: nil}
+		return nil, err
 	}
-
-	deleteService := NewVideoDeleteService(db, logger, azureClient)
-	return &VideoService{db: db, logger: logger, deleteService: deleteService}
+	d := NewVideoDeleteService(s.db, s.logger, client)
+	d.outbox = s.outbox
+	return d, nil
 }
 
 // DB exposes the underlying gorm.DB for internal read-only operations in handlers
 func (s *VideoService) DB() *gorm.DB { return s.db }
 
 // CreateVideo creates a new video record (manual creation path)
-func (s *VideoService) CreateVideo(userID string, req *models.VideoCreateRequest) (*models.Video, error) {
+func (s *VideoService) CreateVideo(ctx context.Context, userID string, req *models.VideoCreateRequest) (*models.Video, error) {
 	if req.UploadID == "" {
 		return nil, fmt.Errorf("upload_id required")
 	}
+	tags, err := requestTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	category, err := s.requestCategory(ctx, req.Category)
+	if err != nil {
+		return nil, err
+	}
 
 	video := &models.Video{
-		UploadID:    req.UploadID,
-		UserID:      userID,
-		Title:       req.Title,
-		Description: req.Description,
-		TagsList:    req.Tags,
-		IsPrivate:   req.IsPrivate,
-		Category:    req.Category,
-		Status:      models.StatusUploaded,
-	}
-
-	if err := s.db.Create(video).Error; err != nil {
-		s.logger.Errorw("Failed to create video", "error", err, "userID", userID, "uploadID", req.UploadID)
+		UploadID:        req.UploadID,
+		UserID:          userID,
+		Title:           req.Title,
+		Description:     req.Description,
+		TagsList:        tags,
+		Category:        category,
+		Status:          models.StatusUploaded,
+		CommentsEnabled: true,
+	}
+	visibility, ok := requestedVisibility(&req.Visibility, &req.IsPrivate)
+	if !ok || !visibility.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidVisibility, visibility)
+	}
+	if err := setVisibility(video, visibility); err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(video).Error; err != nil {
+			return err
+		}
+		if err := recordStatusChange(tx, "", video, models.StatusSourceAPI, userID, ""); err != nil {
+			return err
+		}
+		return enqueueCatalogEvent(tx, s.outbox, VideoCreated, video)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, s.duplicateUpload(ctx, req.UploadID)
+		}
+		s.log(ctx).Errorw("Failed to create video", "error", err, "userID", userID, "uploadID", req.UploadID)
 		return nil, fmt.Errorf("failed to create video: %w", err)
 	}
 
-	s.logger.Infow("Video created", "videoID", video.ID, "userID", userID, "uploadID", req.UploadID)
+	// Committed: subscribers run even if the caller has since gone away
+	s.changes.Emit(context.WithoutCancel(ctx), VideoCreated, video)
+	s.moderation.SubmitVideo(video.ID, map[string]string{
+		ModerationKindTitle:       video.Title,
+		ModerationKindDescription: video.Description,
+	})
+	s.log(ctx).Infow("Video created", "videoID", video.ID, "userID", userID, "uploadID", req.UploadID)
 	return video, nil
 }
 
-// GetVideo retrieves a video by ID
-func (s *VideoService) GetVideo(id uint) (*models.Video, error) {
+// ErrDuplicateUploadID is returned when a video already exists for an upload ID
+var ErrDuplicateUploadID = errors.New("duplicate upload_id")
+
+// DuplicateUploadError identifies the video that already holds an upload ID. It
+// matches ErrDuplicateUploadID with errors.Is.
+type DuplicateUploadError struct {
+	UploadID string
+	VideoID  uint
+	UserID   string
+}
+
+func (e *DuplicateUploadError) Error() string {
+	return fmt.Sprintf("video %d already exists for upload_id %s", e.VideoID, e.UploadID)
+}
+
+// Is implements errors.Is
+func (e *DuplicateUploadError) Is(target error) bool { return target == ErrDuplicateUploadID }
+
+// duplicateUpload looks up the row that caused a unique violation on upload_id.
+// The index covers live rows only, so soft-deleted videos are not considered.
+func (s *VideoService) duplicateUpload(ctx context.Context, uploadID string) error {
+	var existing models.Video
+	if err := s.db.WithContext(ctx).Select("id", "user_id").Where("upload_id = ?", uploadID).First(&existing).Error; err != nil {
+		return fmt.Errorf("%w: %s", ErrDuplicateUploadID, uploadID)
+	}
+	return &DuplicateUploadError{UploadID: uploadID, VideoID: existing.ID, UserID: existing.UserID}
+}
+
+// requestTags normalizes tags from an API request, rejecting the request if
+// normalization would drop any for a reason other than being empty or repeated
+func requestTags(in []string) ([]string, error) {
+	tags, problems := models.NormalizeTags(in)
+	if problems != nil {
+		return nil, &ValidationError{Err: ErrInvalidTags, Fields: problems}
+	}
+	return tags, nil
+}
+
+// eventTags normalizes tags from a broker event; the producer can't be told, so
+// dropped tags are only logged
+func (s *VideoService) eventTags(ctx context.Context, uploadID string, in []string) []string {
+	tags, problems := models.NormalizeTags(in)
+	if problems != nil {
+		s.log(ctx).Warnw("Dropped invalid tags from event", "uploadID", uploadID, "problems", problems)
+	}
+	return tags
+}
+
+// GetVideo retrieves a video by ID, with its renditions
+func (s *VideoService) GetVideo(ctx context.Context, id uint) (*models.Video, error) {
 	var video models.Video
-	if err := s.db.First(&video, id).Error; err != nil {
+	err := s.db.WithContext(ctx).Preload("Renditions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("bandwidth DESC, id")
+	}).First(&video, id).Error
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("video not found")
+			return nil, fmt.Errorf("video %d: %w", id, ErrVideoNotFound)
 		}
-		s.logger.Errorw("Failed to get video", "error", err, "videoID", id)
+		s.log(ctx).Errorw("Failed to get video", "error", err, "videoID", id)
 		return nil, fmt.Errorf("failed to get video: %w", err)
 	}
 	return &video, nil
 }
 
-// GetVideoByUploadID retrieves a video by upload ID
-func (s *VideoService) GetVideoByUploadID(uploadID string) (*models.Video, error) {
+// GetVideoByUploadID retrieves a video by upload ID. Recent misses are served from
+// the negative cache without touching the database.
+func (s *VideoService) GetVideoByUploadID(ctx context.Context, uploadID string) (*models.Video, error) {
+	if s.uploadMisses.IsMiss(uploadID) {
+		return nil, fmt.Errorf("upload %s: %w", uploadID, ErrVideoNotFound)
+	}
+	gen := s.uploadMisses.Generation()
+	video, err := s.findByUploadID(ctx, uploadID)
+	if err != nil {
+		if errors.Is(err, ErrVideoNotFound) {
+			s.uploadMisses.RecordMiss(uploadID, gen)
+		}
+		return nil, err
+	}
+	return video, nil
+}
+
+// findByUploadID looks up a video by upload ID directly in the database
+func (s *VideoService) findByUploadID(ctx context.Context, uploadID string) (*models.Video, error) {
 	var video models.Video
-	if err := s.db.Where("upload_id = ?", uploadID).First(&video).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("upload_id = ?", uploadID).First(&video).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("video not found")
+			return nil, fmt.Errorf("upload %s: %w", uploadID, ErrVideoNotFound)
 		}
-		s.logger.Errorw("Failed to get video by upload ID", "error", err, "uploadID", uploadID)
+		s.log(ctx).Errorw("Failed to get video by upload ID", "error", err, "uploadID", uploadID)
 		return nil, fmt.Errorf("failed to get video: %w", err)
 	}
 	return &video, nil
 }
 
-// UpdateVideo updates a video record
-func (s *VideoService) UpdateVideo(id uint, req *models.VideoUpdateRequest) (*models.Video, error) {
-	video, err := s.GetVideo(id)
+// deletedUpload reports whether uploadID belongs only to a soft-deleted video. Call
+// it after a live lookup missed: events for a deleted upload are dropped instead of
+// resurrecting the video as a placeholder.
+func (s *VideoService) deletedUpload(tx *gorm.DB, kind, uploadID string) (bool, error) {
+	var deleted models.Video
+	err := tx.Unscoped().Select("id").
+		Where("upload_id = ? AND deleted_at IS NOT NULL", uploadID).First(&deleted).Error
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	}
 	if err != nil {
-		return nil, err
+		return false, fmt.Errorf("query deleted video: %w", err)
+	}
+	metrics.EventsIgnoredTotal.WithLabelValues(kind, "soft_deleted").Inc()
+	s.logger.Warnw("Ignoring event for a deleted video", "kind", kind, "uploadID", uploadID, "videoID", deleted.ID)
+	return true, nil
+}
+
+// UpdateVideo updates a video record
+func (s *VideoService) UpdateVideo(ctx context.Context, id uint, req *models.VideoUpdateRequest) (*models.Video, error) {
+	return s.updateVideo(ctx, id, "", 0, req)
+}
+
+// UpdateVideoForUser updates a video on behalf of userID, returning ErrForbidden
+// unless they own it. Internal callers that act on the system's behalf use UpdateVideo.
+// A non-zero ifVersion makes the update conditional: if the video's version is no
+// longer ifVersion it fails with ErrVersionConflict and nothing is written.
+func (s *VideoService) UpdateVideoForUser(ctx context.Context, id uint, userID string, ifVersion int64, req *models.VideoUpdateRequest) (*models.Video, error) {
+	return s.updateVideo(ctx, id, userID, ifVersion, req)
+}
+
+// updateConflictRetries is how often an unconditional update is reapplied to a
+// fresh copy after losing a race, keeping its last-write-wins behaviour
+const updateConflictRetries = 3
+
+// updateVideo applies req for userID, or for the system when userID is empty
+func (s *VideoService) updateVideo(ctx context.Context, id uint, userID string, ifVersion int64, req *models.VideoUpdateRequest) (*models.Video, error) {
+	if fields := models.ValidateVideoUpdate(req); fields != nil {
+		return nil, &ValidationError{Err: ErrInvalidMetadata, Fields: fields}
+	}
+	actorID := userID
+	if actorID == "" {
+		actorID = ActorSystem
 	}
+	for attempt := 1; ; attempt++ {
+		video, err := s.GetVideo(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if userID != "" && video.UserID != userID {
+			return nil, fmt.Errorf("update video %d: %w", id, ErrForbidden)
+		}
+		if ifVersion != 0 && video.Version != ifVersion {
+			return nil, fmt.Errorf("update video %d at version %d: %w", id, ifVersion, ErrVersionConflict)
+		}
+		updated, err := s.applyUpdate(ctx, video, req, actorID)
+		if errors.Is(err, ErrVersionConflict) && ifVersion == 0 && attempt < updateConflictRetries {
+			continue
+		}
+		return updated, err
+	}
+}
+
+// applyUpdate saves the requested changes as a compare-and-swap on the version
+// video was read at, failing with ErrVersionConflict if it has moved on; actorID is
+// recorded in the public event log if the update publishes the video
+func (s *VideoService) applyUpdate(ctx context.Context, video *models.Video, req *models.VideoUpdateRequest, actorID string) (*models.Video, error) {
+	id := video.ID
+	before := *video
 
 	// Update fields if provided
 	if req.Title != nil {
@@ -101,103 +337,519 @@ func (s *VideoService) UpdateVideo(id uint, req *models.VideoUpdateRequest) (*mo
 		video.Description = *req.Description
 	}
 	if req.Tags != nil {
-		video.TagsList = req.Tags
+		tags, err := requestTags(req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		video.TagsList = tags
+	}
+	if visibility, ok := requestedVisibility(req.Visibility, req.IsPrivate); ok {
+		if !visibility.Valid() {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidVisibility, visibility)
+		}
+		if err := setVisibility(video, visibility); err != nil {
+			return nil, err
+		}
 	}
-	if req.IsPrivate != nil {
-		video.IsPrivate = *req.IsPrivate
+	// Resending the current category is fine even if it has since been deactivated
+	if req.Category != nil && *req.Category != video.Category {
+		category, err := s.requestCategory(ctx, *req.Category)
+		if err != nil {
+			return nil, err
+		}
+		video.Category = category
+	}
+	if req.PreviewsDisabled != nil {
+		video.PreviewsDisabled = *req.PreviewsDisabled
+	}
+	if req.CommentsEnabled != nil {
+		video.CommentsEnabled = *req.CommentsEnabled
 	}
-	if req.Category != nil {
-		video.Category = *req.Category
+	if req.Chapters != nil {
+		if fields := models.ValidateChapters(*req.Chapters, video.Duration); fields != nil {
+			return nil, &ValidationError{Err: ErrInvalidChapters, Fields: fields}
+		}
+		// Never nil, so an empty list is stored as [] rather than "not set"
+		chapters := make([]models.Chapter, len(*req.Chapters))
+		for i, ch := range *req.Chapters {
+			chapters[i] = models.Chapter{Title: strings.TrimSpace(ch.Title), StartSeconds: ch.StartSeconds}
+		}
+		video.Chapters = chapters
 	}
 
-	if err := s.db.Save(video).Error; err != nil {
-		s.logger.Errorw("Failed to update video", "error", err, "videoID", id)
+	video.Version++
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Select("*") keeps Save from turning a missed version into an upsert
+		res := tx.Select("*").Omit(videoSaveOmit...).Where("version = ?", before.Version).Save(video)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("update video %d at version %d: %w", id, before.Version, ErrVersionConflict)
+		}
+		if err := enqueueCatalogEvent(tx, s.outbox, changeKind(before, video), video); err != nil {
+			return err
+		}
+		return appendPublishedEvent(tx, before, video, actorID)
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		return nil, err
+	}
+	if err != nil {
+		s.log(ctx).Errorw("Failed to update video", "error", err, "videoID", id)
 		return nil, fmt.Errorf("failed to update video: %w", err)
 	}
+	s.changes.Emit(context.WithoutCancel(ctx), changeKind(before, video), video)
+
+	changed := map[string]string{}
+	if req.Title != nil {
+		changed[ModerationKindTitle] = video.Title
+	}
+	if req.Description != nil {
+		changed[ModerationKindDescription] = video.Description
+	}
+	s.moderation.SubmitVideo(video.ID, changed)
 
-	s.logger.Infow("Video updated", "videoID", id)
+	s.log(ctx).Infow("Video updated", "videoID", id)
 	return video, nil
 }
 
-// DeleteVideo completely removes a video and all associated files
-func (s *VideoService) DeleteVideo(id uint) error {
-	// Use the delete service if available for complete cleanup
-	if s.deleteService != nil {
-		ctx := context.Background()
-		if err := s.deleteService.DeleteVideoCompletely(ctx, id); err != nil {
-			s.logger.Errorw("Failed to delete video completely", "error", err, "videoID", id)
+// DeleteVideo deletes a video on the system's behalf; see deleteVideo
+func (s *VideoService) DeleteVideo(ctx context.Context, id uint) (*models.VideoDeletion, error) {
+	return s.deleteVideo(ctx, id, ActorSystem, s.deleteGrace)
+}
+
+// deleteVideo soft-deletes a video, recording actorID in the public event log, and
+// queues the job that purges its files and row once grace has passed. Until then
+// the owner can restore it.
+func (s *VideoService) deleteVideo(ctx context.Context, id uint, actorID string, grace time.Duration) (*models.VideoDeletion, error) {
+	var video models.Video
+	var job *models.VideoDeletion
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&video, id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("video %d: %w", id, ErrVideoNotFound)
+			}
 			return err
 		}
-		return nil
+		now := time.Now().UTC()
+		purgeAfter := now.Add(grace)
+		if err := tx.Model(&video).UpdateColumns(map[string]interface{}{
+			"deleted_at":  now,
+			"purge_after": purgeAfter,
+			"version":     video.Version + 1,
+		}).Error; err != nil {
+			return err
+		}
+		video.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		video.PurgeAfter = &purgeAfter
+		video.Version++
+		var err error
+		if job, err = queueDeletion(tx, &video, actorID, purgeAfter); err != nil {
+			return err
+		}
+		return recordRemoval(tx, s.outbox, &video, actorID)
+	})
+	if err != nil {
+		if errors.Is(err, ErrVideoNotFound) {
+			return nil, err
+		}
+		s.log(ctx).Errorw("Failed to delete video", "error", err, "videoID", id)
+		return nil, fmt.Errorf("failed to delete video: %w", err)
 	}
+	s.changes.Emit(context.WithoutCancel(ctx), VideoDeleted, &video)
+	s.log(ctx).Infow("Video deleted; purge queued", "videoID", id, "deletionID", job.ID, "purgeAfter", job.RunAfter)
+	return job, nil
+}
 
-	// Fallback to database-only deletion if Azure client unavailable
-	s.logger.Warnw("Azure client not available - performing database-only deletion", "videoID", id)
-	if err := s.db.Delete(&models.Video{}, id).Error; err != nil {
-		s.logger.Errorw("Failed to delete video from database", "error", err, "videoID", id)
-		return fmt.Errorf("failed to delete video: %w", err)
+// DeleteVideoForUser deletes a video on behalf of userID, returning ErrForbidden
+// unless they own it
+func (s *VideoService) DeleteVideoForUser(ctx context.Context, id uint, userID string) (*models.VideoDeletion, error) {
+	video, err := s.GetVideo(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	s.logger.Infow("Video deleted from database only", "videoID", id)
-	return nil
+	if video.UserID != userID {
+		return nil, fmt.Errorf("delete video %d: %w", id, ErrForbidden)
+	}
+	return s.deleteVideo(ctx, id, userID, s.deleteGrace)
 }
 
-// ListVideos retrieves a paginated list of videos for a user
-func (s *VideoService) ListVideos(userID string, page, perPage int, includePrivate bool) (*models.VideoListResponse, error) {
+// Video list sort keys accepted by ListVideos and SearchVideos. recent and views
+// are older names for created_at and view_count.
+const (
+	SortCreatedAt = "created_at"
+	SortTitle     = "title"
+	SortDuration  = "duration"
+	SortFileSize  = "file_size"
+	SortViewCount = "view_count"
+	SortRecent    = "recent"
+	SortViews     = "views"
+)
+
+// videoSortColumns whitelists the columns a list may be ordered by; nothing else
+// from the request reaches the ORDER BY clause
+var videoSortColumns = map[string]string{
+	SortCreatedAt: "created_at",
+	SortTitle:     "title",
+	SortDuration:  "duration",
+	SortFileSize:  "file_size",
+	SortViewCount: "view_count",
+	SortRecent:    "created_at",
+	SortViews:     "view_count",
+}
+
+// VideoSort is a list order: Key is a sort key ("" for created_at), Order is asc
+// or desc ("" for desc)
+type VideoSort struct {
+	Key   string
+	Order string
+}
+
+// IsDefault reports whether s is the default created_at desc order
+func (s VideoSort) IsDefault() bool {
+	return (s.Key == "" || videoSortColumns[s.Key] == "created_at") && (s.Order == "" || s.Order == "desc")
+}
+
+// videoOrder maps a sort to its ORDER BY clause. id breaks ties in the same
+// direction, so rows with equal keys keep a stable order across pages.
+func videoOrder(sort VideoSort) (string, error) {
+	key := sort.Key
+	if key == "" {
+		key = SortCreatedAt
+	}
+	column, ok := videoSortColumns[key]
+	if !ok {
+		return "", fmt.Errorf("%w %q: use one of created_at, title, duration, file_size, view_count", ErrInvalidSort, sort.Key)
+	}
+	direction := "DESC"
+	switch sort.Order {
+	case "", "desc":
+	case "asc":
+		direction = "ASC"
+	default:
+		return "", fmt.Errorf("%w order %q: use asc or desc", ErrInvalidSort, sort.Order)
+	}
+	return column + " " + direction + ", id " + direction, nil
+}
+
+// VideoFilters narrow a video list or search; empty fields don't filter, and set
+// fields AND together
+type VideoFilters struct {
+	Category string
+	Status   models.VideoStatus
+	// Tags matches videos carrying every one of these exact tags
+	Tags []string
+	// MinDuration and MaxDuration bound the duration in seconds, inclusive
+	MinDuration *float64
+	MaxDuration *float64
+	// UploadedAfter and UploadedBefore bound created_at, inclusive
+	UploadedAfter  *time.Time
+	UploadedBefore *time.Time
+}
+
+// apply adds the filters to query, rejecting an unknown status
+func (f VideoFilters) apply(query *gorm.DB) (*gorm.DB, error) {
+	if f.Category != "" {
+		query = query.Where("category = ?", f.Category)
+	}
+	if f.Status != "" {
+		if !f.Status.Valid() {
+			return nil, fmt.Errorf("%w %q", ErrInvalidStatus, f.Status)
+		}
+		query = query.Where("status = ?", f.Status)
+	}
+	if len(f.Tags) > 0 {
+		query = query.Where(models.TagsReadExpr()+" @> ARRAY[?]::text[]", f.Tags)
+	}
+	if f.MinDuration != nil {
+		query = query.Where("duration >= ?", *f.MinDuration)
+	}
+	if f.MaxDuration != nil {
+		query = query.Where("duration <= ?", *f.MaxDuration)
+	}
+	if f.UploadedAfter != nil {
+		query = query.Where("created_at >= ?", *f.UploadedAfter)
+	}
+	if f.UploadedBefore != nil {
+		query = query.Where("created_at <= ?", *f.UploadedBefore)
+	}
+	return query, nil
+}
+
+// ListVideos retrieves a paginated list of videos for a user. Total counts only
+// videos matching filters.
+func (s *VideoService) ListVideos(ctx context.Context, userID string, page, perPage int, includePrivate bool, sort VideoSort, filters VideoFilters) (*models.VideoListResponse, error) {
+	order, err := videoOrder(sort)
+	if err != nil {
+		return nil, err
+	}
 	var videos []models.Video
 	var total int64
-	query := s.db.Model(&models.Video{})
-	if userID != "" {
-		query = query.Where("user_id = ?", userID)
+	query, err := s.listQuery(ctx, userID, includePrivate, filters)
+	if err != nil {
+		return nil, err
 	}
-	if !includePrivate {
-		query = query.Where("is_private = ?", false)
+	// Only the public listing is shared between callers, so only it is cached
+	var cacheKey string
+	if userID == "" && !includePrivate {
+		cacheKey = s.cache.listKey(ctx, page, perPage, sort, filters)
+		var cached models.VideoListResponse
+		if s.cache.get(ctx, "list", cacheKey, &cached) {
+			return &cached, nil
+		}
 	}
 	if err := query.Count(&total).Error; err != nil {
-		s.logger.Errorw("Failed to count videos", "error", err, "userID", userID)
+		s.log(ctx).Errorw("Failed to count videos", "error", err, "userID", userID)
 		return nil, fmt.Errorf("failed to count videos: %w", err)
 	}
 	offset := (page - 1) * perPage
-	if err := query.Offset(offset).Limit(perPage).Order("created_at DESC").Find(&videos).Error; err != nil {
-		s.logger.Errorw("Failed to list videos", "error", err, "userID", userID)
+	if err := query.Offset(offset).Limit(perPage).Order(order).Find(&videos).Error; err != nil {
+		s.log(ctx).Errorw("Failed to list videos", "error", err, "userID", userID)
 		return nil, fmt.Errorf("failed to list videos: %w", err)
 	}
 	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
-	return &models.VideoListResponse{Videos: videos, Total: total, Page: page, PerPage: perPage, TotalPages: totalPages}, nil
+	response := &models.VideoListResponse{Videos: videos, Total: total, Page: page, PerPage: perPage, TotalPages: totalPages}
+	s.cache.set(ctx, cacheKey, response)
+	return response, nil
+}
+
+// ListVideosAfter returns up to limit videos older than after, newest first (keyset
+// on created_at/id), and whether more remain. Filters match ListVideos; a nil after
+// starts from the newest.
+func (s *VideoService) ListVideosAfter(ctx context.Context, userID string, includePrivate bool, filters VideoFilters, after *cursor.TimeID, limit int) ([]models.Video, bool, error) {
+	query, err := s.listAfterQuery(ctx, userID, includePrivate, filters, after, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	var videos []models.Video
+	if err := query.Find(&videos).Error; err != nil {
+		s.log(ctx).Errorw("Failed to list videos", "error", err, "userID", userID)
+		return nil, false, fmt.Errorf("failed to list videos: %w", err)
+	}
+	if len(videos) > limit {
+		return videos[:limit], true, nil
+	}
+	return videos, false, nil
+}
+
+// ListVideoCards is ListVideos reading only the columns of a VideoCard. Cards
+// aren't cached; the projection is what keeps them cheap.
+func (s *VideoService) ListVideoCards(ctx context.Context, userID string, page, perPage int, includePrivate bool, sort VideoSort, filters VideoFilters) (*models.VideoCardListResponse, error) {
+	order, err := videoOrder(sort)
+	if err != nil {
+		return nil, err
+	}
+	query, err := s.listQuery(ctx, userID, includePrivate, filters)
+	if err != nil {
+		return nil, err
+	}
+	response, err := pageVideoCards(query, order, page, perPage)
+	if err != nil {
+		s.log(ctx).Errorw("Failed to list video cards", "error", err, "userID", userID)
+		return nil, fmt.Errorf("failed to list videos: %w", err)
+	}
+	return response, nil
+}
+
+// ListVideoCardsAfter is ListVideosAfter reading only the columns of a VideoCard
+func (s *VideoService) ListVideoCardsAfter(ctx context.Context, userID string, includePrivate bool, filters VideoFilters, after *cursor.TimeID, limit int) ([]models.VideoCard, bool, error) {
+	query, err := s.listAfterQuery(ctx, userID, includePrivate, filters, after, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	var cards []models.VideoCard
+	if err := query.Select(models.VideoCardColumns).Find(&cards).Error; err != nil {
+		s.log(ctx).Errorw("Failed to list video cards", "error", err, "userID", userID)
+		return nil, false, fmt.Errorf("failed to list videos: %w", err)
+	}
+	if len(cards) > limit {
+		return cards[:limit], true, nil
+	}
+	return cards, false, nil
+}
+
+// listQuery selects the videos the list endpoints show: userID's if set, listed
+// ones unless includePrivate, narrowed by filters
+func (s *VideoService) listQuery(ctx context.Context, userID string, includePrivate bool, filters VideoFilters) (*gorm.DB, error) {
+	query := s.db.WithContext(ctx).Model(&models.Video{})
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if !includePrivate {
+		query = listed(query)
+	}
+	return filters.apply(query)
+}
+
+// listed narrows query to the videos public listings and search may show: public
+// and not taken down, as Video.IsListed
+func listed(query *gorm.DB) *gorm.DB {
+	return query.Where("visibility = ? AND moderation_status <> ?", models.VisibilityPublic, models.ModerationStatusTakenDown)
+}
+
+// listAfterQuery is listQuery for the page of up to limit videos after after, plus
+// one more to tell whether another page follows
+func (s *VideoService) listAfterQuery(ctx context.Context, userID string, includePrivate bool, filters VideoFilters, after *cursor.TimeID, limit int) (*gorm.DB, error) {
+	query, err := s.listQuery(ctx, userID, includePrivate, filters)
+	if err != nil {
+		return nil, err
+	}
+	if after != nil {
+		query = query.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+	return query.Order("created_at DESC, id DESC").Limit(limit + 1), nil
 }
 
-// SearchVideos searches for videos by title, description, or tags
-func (s *VideoService) SearchVideos(query string, page, perPage int) (*models.VideoListResponse, error) {
+// pageVideoCards counts query and reads one page of it as cards
+func pageVideoCards(query *gorm.DB, order string, page, perPage int) (*models.VideoCardListResponse, error) {
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	cards := []models.VideoCard{}
+	if err := query.Select(models.VideoCardColumns).Offset((page - 1) * perPage).Limit(perPage).Order(order).Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+	return &models.VideoCardListResponse{Videos: cards, Total: total, Page: page, PerPage: perPage, TotalPages: totalPages}, nil
+}
+
+// SearchVideos searches public videos by title, description, or tags, narrowed by
+// filters. An empty query matches every public video, so filters can be used alone.
+func (s *VideoService) SearchVideos(ctx context.Context, query string, page, perPage int, sort VideoSort, filters VideoFilters) (*models.VideoListResponse, error) {
+	order, err := videoOrder(sort)
+	if err != nil {
+		return nil, err
+	}
 	var videos []models.Video
 	var total int64
-	searchQuery := s.db.Model(&models.Video{}).Where("is_private = ?", false)
-	if query != "" {
-		pattern := "%" + query + "%"
-		searchQuery = searchQuery.Where("title ILIKE ? OR description ILIKE ? OR ? = ANY(tags)", pattern, pattern, query)
+	searchQuery, err := s.searchQuery(ctx, query, filters)
+	if err != nil {
+		return nil, err
+	}
+	if result, ok := s.searchIndexed(ctx, query, page, perPage, sort, filters); ok {
+		found, err := s.GetVideosByIDs(ctx, result.IDs)
+		if err != nil {
+			return nil, err
+		}
+		// The index trails the database briefly; never show what isn't listed now
+		videos = make([]models.Video, 0, len(found))
+		for _, v := range found {
+			if v.IsListed() {
+				videos = append(videos, v)
+			}
+		}
+		totalPages := int((result.Total + int64(perPage) - 1) / int64(perPage))
+		return &models.VideoListResponse{Videos: videos, Total: result.Total, Page: page, PerPage: perPage, TotalPages: totalPages}, nil
 	}
 	if err := searchQuery.Count(&total).Error; err != nil {
-		s.logger.Errorw("Failed to count search results", "error", err, "query", query)
+		s.log(ctx).Errorw("Failed to count search results", "error", err, "query", query)
 		return nil, fmt.Errorf("failed to count search results: %w", err)
 	}
 	offset := (page - 1) * perPage
-	if err := searchQuery.Offset(offset).Limit(perPage).Order("created_at DESC").Find(&videos).Error; err != nil {
-		s.logger.Errorw("Failed to search videos", "error", err, "query", query)
+	if err := searchQuery.Offset(offset).Limit(perPage).Order(order).Find(&videos).Error; err != nil {
+		s.log(ctx).Errorw("Failed to search videos", "error", err, "query", query)
 		return nil, fmt.Errorf("failed to search videos: %w", err)
 	}
 	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
 	return &models.VideoListResponse{Videos: videos, Total: total, Page: page, PerPage: perPage, TotalPages: totalPages}, nil
 }
 
+// SearchVideoCards is SearchVideos reading only the columns of a VideoCard
+func (s *VideoService) SearchVideoCards(ctx context.Context, query string, page, perPage int, sort VideoSort, filters VideoFilters) (*models.VideoCardListResponse, error) {
+	order, err := videoOrder(sort)
+	if err != nil {
+		return nil, err
+	}
+	searchQuery, err := s.searchQuery(ctx, query, filters)
+	if err != nil {
+		return nil, err
+	}
+	if result, ok := s.searchIndexed(ctx, query, page, perPage, sort, filters); ok {
+		var found []models.VideoCard
+		err := listed(s.db.WithContext(ctx).Model(&models.Video{})).Select(models.VideoCardColumns).
+			Where("id IN ?", result.IDs).Find(&found).Error
+		if err != nil {
+			s.log(ctx).Errorw("Failed to load searched video cards", "error", err, "query", query)
+			return nil, fmt.Errorf("failed to search videos: %w", err)
+		}
+		byID := make(map[uint]models.VideoCard, len(found))
+		for _, card := range found {
+			byID[card.ID] = card
+		}
+		cards := make([]models.VideoCard, 0, len(found))
+		for _, id := range result.IDs {
+			if card, ok := byID[id]; ok {
+				cards = append(cards, card)
+			}
+		}
+		totalPages := int((result.Total + int64(perPage) - 1) / int64(perPage))
+		return &models.VideoCardListResponse{Videos: cards, Total: result.Total, Page: page, PerPage: perPage, TotalPages: totalPages}, nil
+	}
+	response, err := pageVideoCards(searchQuery, order, page, perPage)
+	if err != nil {
+		s.log(ctx).Errorw("Failed to search video cards", "error", err, "query", query)
+		return nil, fmt.Errorf("failed to search videos: %w", err)
+	}
+	return response, nil
+}
+
+// searchQuery selects the listed videos matching query in title, description or
+// tags, narrowed by filters
+func (s *VideoService) searchQuery(ctx context.Context, query string, filters VideoFilters) (*gorm.DB, error) {
+	searchQuery := listed(s.db.WithContext(ctx).Model(&models.Video{}))
+	if query != "" {
+		pattern := "%" + query + "%"
+		searchQuery = searchQuery.Where("title ILIKE ? OR description ILIKE ? OR ? = ANY("+models.TagsReadExpr()+")", pattern, pattern, query)
+	}
+	return filters.apply(searchQuery)
+}
+
 // HandleUploadedEvent seeds catalog from upload event
-func (s *VideoService) HandleUploadedEvent(event *models.UploadedEvent) error {
+func (s *VideoService) HandleUploadedEvent(ctx context.Context, event *models.UploadedEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "VideoService.HandleUploadedEvent", trace.WithAttributes(attribute.String("catalog.upload_id", event.UploadID)))
+	defer func() { tracing.End(span, err) }()
 	if event.UploadID == "" || event.UserID == "" {
 		return fmt.Errorf("invalid uploaded event")
 	}
 
-	var existing models.Video
-	err := s.db.Where("upload_id = ?", event.UploadID).First(&existing).Error
-	if err == nil {
+	category, err := s.eventCategory(ctx, event.UploadID, event.Category)
+	if err != nil {
+		return err
+	}
+	seed := &models.Video{
+		UploadID:         event.UploadID,
+		UserID:           event.UserID,
+		Username:         event.Username,
+		Title:            nonEmpty(event.Title, "Untitled Video"),
+		Description:      event.Description,
+		TagsList:         s.eventTags(ctx, event.UploadID, event.Tags),
+		Category:         category,
+		OriginalFilename: event.OriginalName,
+		RawVideoPath:     event.RawVideoPath,
+		Status:           models.StatusProcessing,
+		CommentsEnabled:  true,
+	}
+	if err := setVisibility(seed, models.VisibilityFromPrivate(event.IsPrivate)); err != nil {
+		return err
+	}
+	var patched bool
+	video, created, err := s.applyByUploadID(ctx, EventKindUploaded, event, seed, func(existing *models.Video, created bool) (bool, error) {
+		if created {
+			return false, nil
+		}
+		if err := guardReplay(ctx, existing, models.StatusProcessing, event.OccurredAt); err != nil {
+			return false, err
+		}
 		// Row already exists – possibly created from a prior transcoded event placeholder.
 		updated := false
+		// The upload event means the transcode was queued, so a video registered by
+		// hand moves on to processing. Placeholders already past it keep their status.
+		if existing.Status == models.StatusUploaded {
+			existing.Status = models.StatusProcessing
+			updated = true
+		}
 		// Only patch empty / default fields so we don't overwrite user edits.
 		if existing.Username == "" && event.Username != "" {
 			existing.Username = event.Username
@@ -211,12 +863,12 @@ func (s *VideoService) HandleUploadedEvent(event *models.UploadedEvent) error {
 			existing.Description = event.Description
 			updated = true
 		}
-		if len(existing.TagsList) == 0 && len(event.Tags) > 0 {
-			existing.TagsList = event.Tags
+		if len(existing.TagsList) == 0 && len(seed.TagsList) > 0 {
+			existing.TagsList = seed.TagsList
 			updated = true
 		}
-		if existing.Category == "" && event.Category != "" {
-			existing.Category = event.Category
+		if existing.Category == "" && seed.Category != "" {
+			existing.Category = seed.Category
 			updated = true
 		}
 		if existing.OriginalFilename == "" && event.OriginalName != "" {
@@ -227,124 +879,378 @@ func (s *VideoService) HandleUploadedEvent(event *models.UploadedEvent) error {
 			existing.RawVideoPath = event.RawVideoPath
 			updated = true
 		}
-		// Always trust privacy flag if row had default false and upload says true.
-		if !existing.IsPrivate && event.IsPrivate {
-			existing.IsPrivate = true
-			updated = true
-		}
-		if updated {
-			if err := s.db.Save(&existing).Error; err != nil {
-				return fmt.Errorf("patch existing video from upload event: %w", err)
+		// Always trust the privacy flag if it is stricter than the row's visibility
+		if existing.Visibility != models.VisibilityPrivate && event.IsPrivate {
+			if err := setVisibility(existing, models.VisibilityPrivate); err != nil {
+				return false, err
 			}
-			s.logger.Infow("Patched existing video with upload metadata", "uploadID", event.UploadID, "videoID", existing.ID)
+			updated = true
 		}
-		return nil
-	}
-	if err != nil && err != gorm.ErrRecordNotFound {
-		return fmt.Errorf("query existing: %w", err)
-	}
-
-	video := &models.Video{
-		UploadID:         event.UploadID,
-		UserID:           event.UserID,
-		Username:         event.Username,
-		Title:            nonEmpty(event.Title, "Untitled Video"),
-		Description:      event.Description,
-		TagsList:         event.Tags,
-		IsPrivate:        event.IsPrivate,
-		Category:         event.Category,
-		OriginalFilename: event.OriginalName,
-		RawVideoPath:     event.RawVideoPath,
-		Status:           models.StatusProcessing,
+		patched = updated
+		return updated, nil
+	})
+	if err != nil {
+		return err
 	}
-
-	if err := s.db.Create(video).Error; err != nil {
-		s.logger.Errorw("Failed to create video from uploaded event", "error", err, "uploadID", event.UploadID)
-		return fmt.Errorf("failed to create video: %w", err)
+	switch {
+	case created:
+		s.log(ctx).Infow("Catalog seeded from upload event", "uploadID", event.UploadID, "videoID", video.ID, logging.Title(video.Title))
+	case patched:
+		s.log(ctx).Infow("Patched existing video with upload metadata", "uploadID", event.UploadID, "videoID", video.ID)
 	}
-
-	s.logger.Infow("Catalog seeded from upload event", "uploadID", event.UploadID, "videoID", video.ID)
 	return nil
 }
 
 // HandleTranscodedEvent processes video.transcoded events
-func (s *VideoService) HandleTranscodedEvent(event *models.TranscodedEvent) error {
-	video, err := s.GetVideoByUploadID(event.UploadID)
+func (s *VideoService) HandleTranscodedEvent(ctx context.Context, event *models.TranscodedEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "VideoService.HandleTranscodedEvent", trace.WithAttributes(attribute.String("catalog.upload_id", event.UploadID)))
+	defer func() { tracing.End(span, err) }()
+	category, err := s.eventCategory(ctx, event.UploadID, event.Category)
 	if err != nil {
-		video = &models.Video{
-			UploadID: event.UploadID,
-			UserID:   event.UserID,
-			Title:    nonEmpty(event.Title, "Untitled Video"),
-			Status:   models.StatusProcessing,
+		return err
+	}
+	seed := &models.Video{
+		UploadID:        event.UploadID,
+		UserID:          event.UserID,
+		Title:           nonEmpty(event.Title, "Untitled Video"),
+		Status:          models.StatusProcessing,
+		CommentsEnabled: true,
+	}
+	if err := setVisibility(seed, models.VisibilityFromPrivate(event.IsPrivate)); err != nil {
+		return err
+	}
+	var updated bool
+	video, _, err := s.applyByUploadID(ctx, EventKindTranscoded, event, seed, func(video *models.Video, created bool) (bool, error) {
+		if !created {
+			if err := guardReplay(ctx, video, models.StatusReady, event.OccurredAt); err != nil {
+				return false, err
+			}
+			// A transcode implies processing started, even if the uploaded event
+			// saying so hasn't landed yet
+			if video.Status == models.StatusUploaded {
+				video.Status = models.StatusProcessing
+			}
+			// A late success must not revive a failed video; retries go through processing
+			if err := checkTransition(ctx, video, models.StatusReady); err != nil {
+				return false, err
+			}
+		}
+
+		// Backfill metadata if still empty / default
+		if video.Title == "Untitled Video" && event.Title != "" {
+			video.Title = event.Title
+			updated = true
+		}
+		if video.Description == "" && event.Description != "" {
+			video.Description = event.Description
+			updated = true
 		}
-		if err := s.db.Create(video).Error; err != nil {
-			s.logger.Errorw("Failed to create video from transcoded event", "error", err, "uploadID", event.UploadID)
-			return fmt.Errorf("failed to create video: %w", err)
+		if tags := s.eventTags(ctx, event.UploadID, event.Tags); len(video.TagsList) == 0 && len(tags) > 0 {
+			video.TagsList = tags
+			updated = true
+		}
+		if video.Category == "" && category != "" {
+			video.Category = category
+			updated = true
 		}
+		if video.OriginalFilename == "" && event.OriginalFilename != "" {
+			video.OriginalFilename = event.OriginalFilename
+			updated = true
+		}
+		if video.RawVideoPath == "" && event.RawVideoPath != "" {
+			video.RawVideoPath = event.RawVideoPath
+			updated = true
+		}
+		if video.Visibility != models.VisibilityPrivate && event.IsPrivate { // escalate privacy if needed
+			if err := setVisibility(video, models.VisibilityPrivate); err != nil {
+				return false, err
+			}
+			updated = true
+		}
+
+		video.HLSMasterURL = event.HLS.MasterURL
+		video.Status = models.StatusReady
+		video.FailureReason = ""
+
+		// Candidates are added for the owner to pick from. Until they pick one, the
+		// event's thumbnail, else its first candidate, is the video's.
+		thumbnails := models.ToVideoThumbnails(event.Thumbnails)
+		if len(thumbnails) > 0 {
+			video.Thumbnails = thumbnails
+			updated = true
+		}
+		if video.ThumbnailID == nil {
+			if event.ThumbnailURL != "" {
+				video.ThumbnailURL, video.ThumbnailPath = event.ThumbnailURL, ""
+				updated = true
+			} else if video.ThumbnailURL == "" && len(thumbnails) > 0 {
+				video.ThumbnailURL = thumbnails[0].URL
+				video.ThumbnailPath = thumbnails[0].BlobPath
+				updated = true
+			}
+		}
+
+		// Events without renditions leave any existing ones in place
+		if len(event.HLS.Renditions) > 0 {
+			if renditions := models.ToVideoRenditions(event.HLS.Renditions); len(renditions) > 0 {
+				video.Renditions = renditions
+				updated = true
+			} else {
+				s.log(ctx).Warnw("Ignoring renditions without name or playlist URL in transcoded event", "uploadID", event.UploadID)
+			}
+		}
+
+		// Events without previews leave any existing ones in place
+		if event.Previews != nil {
+			if previews := event.Previews.ToVideoPreviews(); previews != nil {
+				video.Previews = previews
+				updated = true
+			} else {
+				s.log(ctx).Warnw("Ignoring incomplete preview info in transcoded event", "uploadID", event.UploadID)
+			}
+		}
+
+		if event.Metadata != nil {
+			video.Duration = event.Metadata.Duration
+			video.FileSize = event.Metadata.FileSize
+			video.Width = event.Metadata.Width
+			video.Height = event.Metadata.Height
+			video.VideoCodec = event.Metadata.VideoCodec
+			video.VideoBitrate = event.Metadata.VideoBitrate
+			video.AudioCodec = event.Metadata.AudioCodec
+			video.AudioBitrate = event.Metadata.AudioBitrate
+			video.FrameRate = event.Metadata.FrameRate
+			updated = true
+			// Detected chapters never replace ones the creator set, or removed
+			if len(event.Metadata.Chapters) > 0 && video.Chapters == nil {
+				video.Chapters = models.ChaptersFromEvent(event.Metadata.Chapters, video.Duration)
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	if video == nil {
+		return nil
 	}
 
-	// Backfill metadata if still empty / default
-	updated := false
-	if video.Title == "Untitled Video" && event.Title != "" {
-		video.Title = event.Title
-		updated = true
+	if updated {
+		s.log(ctx).Infow("Video updated from transcoded event (metadata backfilled)", "uploadID", event.UploadID, "videoID", video.ID)
+	} else {
+		s.log(ctx).Infow("Video status updated from transcoded event", "uploadID", event.UploadID, "videoID", video.ID)
 	}
-	if video.Description == "" && event.Description != "" {
-		video.Description = event.Description
-		updated = true
+	return nil
+}
+
+// maxFailureReasonLen caps the stored transcoder error
+const maxFailureReasonLen = 2000
+
+// HandleTranscodeFailedEvent processes video.transcode.failed events: the video is
+// marked failed with the transcoder's error. A failure for a video that is already
+// ready (e.g. a late message from an earlier attempt) is ignored, since its
+// renditions are still playable. If the upload event hasn't arrived yet, a
+// placeholder row is created that the upload event later fills in.
+func (s *VideoService) HandleTranscodeFailedEvent(ctx context.Context, event *models.TranscodeFailedEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "VideoService.HandleTranscodeFailedEvent", trace.WithAttributes(attribute.String("catalog.upload_id", event.UploadID)))
+	defer func() { tracing.End(span, err) }()
+	if event.UploadID == "" || event.UserID == "" {
+		return fmt.Errorf("invalid transcode failed event")
 	}
-	if len(video.TagsList) == 0 && len(event.Tags) > 0 {
-		video.TagsList = event.Tags
-		updated = true
+	reason := nonEmpty(strings.TrimSpace(event.ErrorMessage), "transcode failed")
+	if r := []rune(reason); len(r) > maxFailureReasonLen {
+		reason = string(r[:maxFailureReasonLen])
 	}
-	if video.Category == "" && event.Category != "" {
-		video.Category = event.Category
-		updated = true
+
+	seed := &models.Video{
+		UploadID:        event.UploadID,
+		UserID:          event.UserID,
+		Title:           "Untitled Video",
+		Status:          models.StatusFailed,
+		FailureReason:   reason,
+		CommentsEnabled: true,
 	}
-	if video.OriginalFilename == "" && event.OriginalFilename != "" {
-		video.OriginalFilename = event.OriginalFilename
-		updated = true
+	video, created, err := s.applyByUploadID(ctx, EventKindTranscodeFailed, event, seed, func(video *models.Video, created bool) (bool, error) {
+		if created {
+			return false, nil
+		}
+		if err := guardReplay(ctx, video, models.StatusFailed, event.FailedAt); err != nil {
+			return false, err
+		}
+		if video.Status == models.StatusReady {
+			s.log(ctx).Warnw("Ignoring transcode failure for a ready video", "uploadID", event.UploadID, "videoID", video.ID)
+			return false, nil
+		}
+		if video.Status == models.StatusUploaded {
+			video.Status = models.StatusProcessing
+		}
+		if err := checkTransition(ctx, video, models.StatusFailed); err != nil {
+			return false, err
+		}
+		video.Status = models.StatusFailed
+		video.FailureReason = reason
+		return true, nil
+	})
+	if err != nil {
+		return err
 	}
-	if video.RawVideoPath == "" && event.RawVideoPath != "" {
-		video.RawVideoPath = event.RawVideoPath
-		updated = true
+	if video == nil || video.Status != models.StatusFailed {
+		return nil
 	}
-	if !video.IsPrivate && event.IsPrivate { // escalate privacy if needed
-		video.IsPrivate = true
-		updated = true
+	if created {
+		s.log(ctx).Warnw("Transcode failed before upload event; placeholder created", "uploadID", event.UploadID, "videoID", video.ID)
+	} else {
+		s.log(ctx).Warnw("Video transcode failed", "uploadID", event.UploadID, "videoID", video.ID)
 	}
+	return nil
+}
 
-	video.HLSMasterURL = event.HLS.MasterURL
-	video.Status = models.StatusReady
+// applyByUploadID applies one broker event to the live video for seed.UploadID in a
+// transaction that holds the row lock. The uploaded and transcoded queues are
+// consumed concurrently, so without the lock both handlers could miss the row and
+// race to insert it, or overwrite each other's fields. If no live row exists, seed
+// is inserted (ON CONFLICT DO NOTHING, so the loser of a race locks the winner's
+// row instead) and apply sees it with created set. apply edits the video in place
+// and reports whether it changed anything; renditions it sets replace the
+// stored ones, and thumbnails it sets are added to them. A nil video means the
+// event was ignored: it had already been processed (see markProcessed) or the
+// upload belongs to a deleted video.
+func (s *VideoService) applyByUploadID(ctx context.Context, kind string, event interface{}, seed *models.Video, apply func(video *models.Video, created bool) (bool, error)) (*models.Video, bool, error) {
+	var (
+		video   models.Video
+		created bool
+		changed bool
+		before  models.Video
+	)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if duplicate, err := s.markProcessed(ctx, tx, kind, seed.UploadID, event); err != nil || duplicate {
+			return err
+		}
+		err := lockByUploadID(tx, seed.UploadID, &video)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return fmt.Errorf("query existing: %w", err)
+		}
+		if err == gorm.ErrRecordNotFound {
+			deleted, err := s.deletedUpload(tx, kind, seed.UploadID)
+			if err != nil || deleted {
+				return err
+			}
+			res := tx.Clauses(clause.OnConflict{
+				Columns:     []clause.Column{{Name: "upload_id"}},
+				TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL"}}},
+				DoNothing:   true,
+			}).Create(seed)
+			if res.Error != nil {
+				return fmt.Errorf("failed to create video: %w", res.Error)
+			}
+			if res.RowsAffected == 1 {
+				video, created = *seed, true
+			} else if err := lockByUploadID(tx, seed.UploadID, &video); err != nil {
+				return fmt.Errorf("query concurrently created video: %w", err)
+			}
+		}
 
-	// Set thumbnail URL if provided
-	if event.ThumbnailURL != "" {
-		video.ThumbnailURL = event.ThumbnailURL
-		updated = true
+		before = video
+		if changed, err = apply(&video, created); err != nil || !(changed || created) {
+			return err
+		}
+		if changed {
+			// The row is locked, so this can't race an edit's compare-and-swap
+			video.Version++
+			if err := tx.Omit(videoSaveOmit...).Save(&video).Error; err != nil {
+				return fmt.Errorf("failed to update video: %w", err)
+			}
+			if video.Renditions != nil {
+				if err := replaceRenditions(tx, &video); err != nil {
+					return err
+				}
+			}
+			if video.Thumbnails != nil {
+				if err := addThumbnails(tx, &video); err != nil {
+					return err
+				}
+			}
+		}
+		from := before.Status
+		if created {
+			from = ""
+		}
+		if err := recordStatusChange(tx, from, &video, eventStatusSources[kind], "", ""); err != nil {
+			return err
+		}
+		kind := changeKind(before, &video)
+		if created {
+			kind = VideoCreated
+		}
+		if err := enqueueCatalogEvent(tx, s.outbox, kind, &video); err != nil {
+			return err
+		}
+		return appendPublishedEvent(tx, before, &video, ActorSystem)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if video.ID == 0 {
+		return nil, false, nil
+	}
+	switch {
+	case created:
+		s.changes.Emit(ctx, VideoCreated, &video)
+	case changed:
+		s.changes.Emit(ctx, changeKind(before, &video), &video)
 	}
+	return &video, created, nil
+}
+
+// lockByUploadID loads the live video for uploadID and locks its row until the
+// transaction ends
+func lockByUploadID(tx *gorm.DB, uploadID string, video *models.Video) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("upload_id = ?", uploadID).First(video).Error
+}
 
-	if event.Metadata != nil {
-		video.Duration = event.Metadata.Duration
-		video.FileSize = event.Metadata.FileSize
-		video.Width = event.Metadata.Width
-		video.Height = event.Metadata.Height
-		video.VideoCodec = event.Metadata.VideoCodec
-		video.VideoBitrate = event.Metadata.VideoBitrate
-		video.AudioCodec = event.Metadata.AudioCodec
-		video.AudioBitrate = event.Metadata.AudioBitrate
-		video.FrameRate = event.Metadata.FrameRate
-		updated = true
+// guardReplay refuses a replayed event that would move the video's state backwards,
+// unless the replay was forced. Live deliveries are never checked.
+func guardReplay(ctx context.Context, video *models.Video, target models.VideoStatus, occurredAt *time.Time) error {
+	md, ok := events.FromContext(ctx)
+	if !ok || !md.Replay || md.Force {
+		return nil
 	}
+	return events.CheckReplayOrder(video.Status, video.UpdatedAt, target, events.EventTime(occurredAt, md))
+}
 
-	if err := s.db.Save(video).Error; err != nil {
-		s.logger.Errorw("Failed to update video from transcoded event", "error", err, "uploadID", event.UploadID)
-		return fmt.Errorf("failed to update video: %w", err)
+// RunStatusMetrics refreshes the catalog_videos gauge every interval until ctx is
+// done. Every replica runs it, so whichever one is scraped reports current counts.
+func (s *VideoService) RunStatusMetrics(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.refreshStatusMetrics(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Warnw("Failed to refresh video status metrics", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
+}
 
-	if updated {
-		s.logger.Infow("Video updated from transcoded event (metadata backfilled)", "uploadID", event.UploadID, "videoID", video.ID)
-	} else {
-		s.logger.Infow("Video status updated from transcoded event", "uploadID", event.UploadID, "videoID", video.ID)
+func (s *VideoService) refreshStatusMetrics(ctx context.Context) error {
+	var rows []struct {
+		Status models.VideoStatus
+		Count  int64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Video{}).Select("status, COUNT(*) AS count").
+		Group("status").Scan(&rows).Error; err != nil {
+		return fmt.Errorf("count videos by status: %w", err)
+	}
+	counts := make(map[models.VideoStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	// Every known status is set, so one that empties drops to 0 rather than going stale
+	for _, status := range models.VideoStatuses {
+		metrics.Videos.WithLabelValues(string(status)).Set(float64(counts[status]))
 	}
 	return nil
 }
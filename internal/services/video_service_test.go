@@ -0,0 +1,66 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestVideoOwnerVariants(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{UploadID: "up", UserID: "owner", Title: "original"})
+	title := "renamed"
+
+	if _, err := videos.UpdateVideoForUser(ctx, video.ID, "mallory", 0, &models.VideoUpdateRequest{Title: &title}); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("UpdateVideoForUser by another user: err = %v, want ErrForbidden", err)
+	}
+	if _, err := videos.DeleteVideoForUser(ctx, video.ID, "mallory"); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("DeleteVideoForUser by another user: err = %v, want ErrForbidden", err)
+	}
+	if _, err := videos.DeleteVideoForUser(ctx, video.ID+1, "owner"); !errors.Is(err, services.ErrVideoNotFound) {
+		t.Errorf("DeleteVideoForUser of a missing video: err = %v, want ErrVideoNotFound", err)
+	}
+
+	// Event handlers act for the system and skip the owner check
+	updated, err := videos.UpdateVideo(ctx, video.ID, &models.VideoUpdateRequest{Title: &title})
+	if err != nil || updated.Title != title {
+		t.Fatalf("UpdateVideo = %+v, %v", updated, err)
+	}
+	if _, err := videos.DeleteVideo(ctx, video.ID); err != nil {
+		t.Fatalf("DeleteVideo: %v", err)
+	}
+	if _, err := videos.GetVideo(ctx, video.ID); !errors.Is(err, services.ErrVideoNotFound) {
+		t.Errorf("GetVideo after DeleteVideo: err = %v", err)
+	}
+}
+
+func TestCreateVideoDuplicateUploadID(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	req := &models.VideoCreateRequest{UploadID: "up-1", Title: "t"}
+	first, err := videos.CreateVideo(ctx, "owner", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = videos.CreateVideo(ctx, "owner", req)
+	var dup *services.DuplicateUploadError
+	if !errors.As(err, &dup) || !errors.Is(err, services.ErrDuplicateUploadID) {
+		t.Fatalf("err = %v, want a DuplicateUploadError", err)
+	}
+	if dup.VideoID != first.ID || dup.UserID != "owner" || dup.UploadID != "up-1" {
+		t.Errorf("duplicate = %+v", dup)
+	}
+	var n int64
+	db.Model(&models.Video{}).Count(&n)
+	if n != 1 {
+		t.Errorf("%d videos after the retry, want 1", n)
+	}
+}
@@ -0,0 +1,173 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// expire moves video's restore window and its deletion job into the past, as if
+// the grace period had run out
+func expire(t *testing.T, db *gorm.DB, videoID uint) {
+	t.Helper()
+	past := time.Now().UTC().Add(-time.Minute)
+	if err := db.Unscoped().Model(&models.Video{}).Where("id = ?", videoID).Update("purge_after", past).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.VideoDeletion{}).Where("video_id = ?", videoID).Update("run_after", past).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	db := dbtest.Open(t)
+	storage := newFakeStorage("raw/owner/up-1.mp4")
+	settings := videoSettings
+	settings.DeleteGrace = 48 * time.Hour
+	videos := services.NewVideoService(db, storageLoader(storage), settings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{UploadID: "up-1", UserID: "owner", Title: "t", RawVideoPath: "raw/owner/up-1.mp4"})
+
+	if _, err := videos.DeleteVideoForUser(ctx, video.ID, "mallory"); !errors.Is(err, services.ErrForbidden) {
+		t.Fatalf("delete by another user: %v, want ErrForbidden", err)
+	}
+	job, err := videos.DeleteVideoForUser(ctx, video.ID, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grace := time.Until(job.RunAfter); job.Status != models.DeletionPending || grace < 47*time.Hour || grace > 48*time.Hour {
+		t.Errorf("job = %+v, want pending for the 48h grace period", job)
+	}
+
+	// Gone from reads and lists, but its files stay until the purge
+	if _, err := videos.GetVideo(ctx, video.ID); !errors.Is(err, services.ErrVideoNotFound) {
+		t.Errorf("GetVideo after delete: %v, want ErrVideoNotFound", err)
+	}
+	if list, _ := videos.ListVideos(ctx, "owner", 1, 10, true, services.VideoSort{}, services.VideoFilters{}); list.Total != 0 {
+		t.Errorf("owner's list still has %d videos", list.Total)
+	}
+	if !storage.has("raw/owner/up-1.mp4") {
+		t.Error("files deleted before the grace period ended")
+	}
+
+	if _, err := videos.RestoreVideo(ctx, video.ID, "mallory"); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("restore by another user: %v, want ErrForbidden", err)
+	}
+	restored, err := videos.RestoreVideo(ctx, video.ID, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.DeletedAt.Valid || restored.PurgeAfter != nil {
+		t.Errorf("restored video = %+v", restored)
+	}
+	if _, err := videos.GetVideo(ctx, video.ID); err != nil {
+		t.Errorf("GetVideo after restore: %v", err)
+	}
+	if job, _ := videos.GetDeletion(ctx, job.ID); job.Status != models.DeletionCancelled {
+		t.Errorf("job after restore is %s, want cancelled", job.Status)
+	}
+	if _, err := videos.RestoreVideo(ctx, video.ID, "owner"); !errors.Is(err, services.ErrVideoNotDeleted) {
+		t.Errorf("restoring a live video: %v, want ErrVideoNotDeleted", err)
+	}
+}
+
+func TestRestoreAfterWindow(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{UploadID: "up-1", UserID: "owner", Title: "t"})
+	if _, err := videos.DeleteVideoForUser(ctx, video.ID, "owner"); err != nil {
+		t.Fatal(err)
+	}
+	expire(t, db, video.ID)
+
+	// Not purged yet, but the window has closed
+	if _, err := videos.RestoreVideo(ctx, video.ID, "owner"); !errors.Is(err, services.ErrVideoPurged) {
+		t.Errorf("restore after the window: %v, want ErrVideoPurged", err)
+	}
+	if _, err := videos.RestoreVideo(ctx, 999, "owner"); !errors.Is(err, services.ErrVideoNotFound) {
+		t.Errorf("restore of a video that never existed: %v, want ErrVideoNotFound", err)
+	}
+}
+
+func TestDeletionWorkerPurgesExpiredVideos(t *testing.T) {
+	db := dbtest.Open(t)
+	storage := newFakeStorage("raw/owner/up-1.mp4", "hls/owner/up-1/master.m3u8", "hls/owner/up-1/720p/seg-0.ts", "raw/owner/up-2.mp4")
+	videos := services.NewVideoService(db, storageLoader(storage), videoSettings, nopLogger())
+	ctx := context.Background()
+	expired := createVideo(t, db, models.Video{UploadID: "up-1", UserID: "owner", Title: "t", RawVideoPath: "raw/owner/up-1.mp4",
+		HLSMasterURL: "https://acct.blob.core.windows.net/videos/hls/owner/up-1/master.m3u8"})
+	waiting := createVideo(t, db, models.Video{UploadID: "up-2", UserID: "owner", Title: "t", RawVideoPath: "raw/owner/up-2.mp4"})
+	expiredJob, err := videos.DeleteVideoForUser(ctx, expired.ID, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitingJob, err := videos.DeleteVideoForUser(ctx, waiting.ID, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expire(t, db, expired.ID)
+
+	worker := services.NewDeletionWorker(videos, nopLogger(), 2, 10*time.Millisecond, time.Minute)
+	runCtx, stop := context.WithCancel(ctx)
+	worker.Run(runCtx)
+	defer func() { stop(); worker.Wait() }()
+
+	waitFor(t, "the expired video's purge", func() bool {
+		job, err := videos.GetDeletion(ctx, expiredJob.ID)
+		return err == nil && job.Status == models.DeletionCompleted
+	})
+	for _, path := range []string{"raw/owner/up-1.mp4", "hls/owner/up-1/master.m3u8", "hls/owner/up-1/720p/seg-0.ts"} {
+		if storage.has(path) {
+			t.Errorf("%s survived the purge", path)
+		}
+	}
+	var rows int64
+	db.Unscoped().Model(&models.Video{}).Where("id = ?", expired.ID).Count(&rows)
+	if rows != 0 {
+		t.Error("purged video's row is still there")
+	}
+	// After the purge, the video is gone for good rather than unknown
+	if _, err := videos.RestoreVideo(ctx, expired.ID, "owner"); !errors.Is(err, services.ErrVideoPurged) {
+		t.Errorf("restore after the purge: %v, want ErrVideoPurged", err)
+	}
+
+	// The video still in its window is left alone
+	if job, _ := videos.GetDeletion(ctx, waitingJob.ID); job.Status != models.DeletionPending || !storage.has("raw/owner/up-2.mp4") {
+		t.Errorf("video in its restore window: job %s, raw file kept %v", job.Status, storage.has("raw/owner/up-2.mp4"))
+	}
+}
+
+func TestQueueExpiredDeletions(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	// Deleted before deletion jobs existed: no purge_after and no job
+	legacy := createVideo(t, db, models.Video{UploadID: "up-1", UserID: "owner", Title: "t"})
+	if err := db.Delete(legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Deleted with a job, still in its window
+	current := createVideo(t, db, models.Video{UploadID: "up-2", UserID: "owner", Title: "t"})
+	if _, err := videos.DeleteVideoForUser(ctx, current.ID, "owner"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := videos.QueueExpiredDeletions(ctx, 10); n != 1 || err != nil {
+		t.Fatalf("queued %d, err %v; want the legacy video only", n, err)
+	}
+	job, err := videos.LatestDeletion(ctx, legacy.ID)
+	if err != nil || job.Status != models.DeletionPending || job.ActorID != services.ActorSystem || time.Until(job.RunAfter) > 0 {
+		t.Errorf("legacy video's job = %+v, err %v; want a due pending system job", job, err)
+	}
+	if n, _ := videos.QueueExpiredDeletions(ctx, 10); n != 0 {
+		t.Errorf("queued %d more on the second sweep, want none", n)
+	}
+}
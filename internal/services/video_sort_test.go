@@ -0,0 +1,127 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestListVideosSort(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Every key repeats, so only the id tiebreaker fixes the order
+	for i := 0; i < 12; i++ {
+		createVideo(t, db, models.Video{
+			UploadID:  fmt.Sprintf("sort-%02d", i),
+			UserID:    "owner",
+			Title:     fmt.Sprintf("title %d", i%3),
+			Duration:  float64(i % 4),
+			FileSize:  int64(i % 2),
+			ViewCount: int64(i % 5),
+			CreatedAt: base.Add(time.Duration(i%6) * time.Hour),
+		})
+	}
+	keyOf := map[string]func(v models.Video) string{
+		services.SortCreatedAt: func(v models.Video) string { return v.CreatedAt.UTC().Format(time.RFC3339) },
+		services.SortTitle:     func(v models.Video) string { return v.Title },
+		services.SortDuration:  func(v models.Video) string { return fmt.Sprintf("%08.2f", v.Duration) },
+		services.SortFileSize:  func(v models.Video) string { return fmt.Sprintf("%08d", v.FileSize) },
+		services.SortViewCount: func(v models.Video) string { return fmt.Sprintf("%08d", v.ViewCount) },
+	}
+
+	for key, keyFn := range keyOf {
+		for _, order := range []string{"asc", "desc"} {
+			t.Run(key+" "+order, func(t *testing.T) {
+				sort := services.VideoSort{Key: key, Order: order}
+				all, err := videos.ListVideos(ctx, "", 1, 100, false, sort, services.VideoFilters{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 1; i < len(all.Videos); i++ {
+					prev, cur := all.Videos[i-1], all.Videos[i]
+					pk, ck := keyFn(prev), keyFn(cur)
+					inOrder := pk < ck || (pk == ck && prev.ID < cur.ID)
+					if order == "desc" {
+						inOrder = pk > ck || (pk == ck && prev.ID > cur.ID)
+					}
+					if !inOrder {
+						t.Fatalf("video %d (%s) before %d (%s)", prev.ID, pk, cur.ID, ck)
+					}
+				}
+
+				// Pages of a few rows line up with the full list: no row is repeated or skipped
+				for page := 1; page <= 4; page++ {
+					got, err := videos.ListVideos(ctx, "", page, 3, false, sort, services.VideoFilters{})
+					if err != nil {
+						t.Fatal(err)
+					}
+					for i, v := range got.Videos {
+						if want := all.Videos[(page-1)*3+i]; v.ID != want.ID {
+							t.Fatalf("page %d row %d is video %d, want %d", page, i, v.ID, want.ID)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestVideoSortDefault(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		createVideo(t, db, models.Video{UploadID: fmt.Sprintf("d-%d", i), UserID: "owner", Title: "t", CreatedAt: base.Add(time.Duration(i/2) * time.Hour)})
+	}
+	list, err := videos.ListVideos(ctx, "", 1, 10, false, services.VideoSort{}, services.VideoFilters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uint
+	for _, v := range list.Videos {
+		got = append(got, v.ID)
+	}
+	if fmt.Sprint(got) != "[4 3 2 1]" {
+		t.Errorf("default order = %v, want newest first, higher id first on ties", got)
+	}
+	for _, sort := range []services.VideoSort{{}, {Key: "created_at"}, {Order: "desc"}, {Key: "recent", Order: "desc"}} {
+		if !sort.IsDefault() {
+			t.Errorf("%+v is not the default", sort)
+		}
+	}
+	if (services.VideoSort{Order: "asc"}).IsDefault() || (services.VideoSort{Key: "title"}).IsDefault() {
+		t.Error("a non-default order reported as default")
+	}
+}
+
+func TestVideoSortRejected(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	for _, sort := range []services.VideoSort{
+		{Key: "uploaded"},
+		{Key: "title; DROP TABLE videos"},
+		{Key: "id"},
+		{Key: "title", Order: "sideways"},
+		{Key: "title", Order: "ASC, id"},
+	} {
+		if _, err := videos.ListVideos(ctx, "", 1, 10, false, sort, services.VideoFilters{}); !errors.Is(err, services.ErrInvalidSort) {
+			t.Errorf("ListVideos with %+v: error %v, want ErrInvalidSort", sort, err)
+		}
+		if _, err := videos.SearchVideos(ctx, "", 1, 10, sort, services.VideoFilters{}); !errors.Is(err, services.ErrInvalidSort) {
+			t.Errorf("SearchVideos with %+v: error %v, want ErrInvalidSort", sort, err)
+		}
+	}
+	if !db.Migrator().HasTable(&models.Video{}) {
+		t.Error("videos table gone")
+	}
+}
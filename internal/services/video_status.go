@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// InvalidTransitionError is returned when a video's status can't move from From to
+// To (see models.VideoStatus.CanTransitionTo)
+type InvalidTransitionError struct {
+	VideoID uint
+	From    models.VideoStatus
+	To      models.VideoStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("video %d: status %s cannot move to %s", e.VideoID, e.From, e.To)
+}
+
+// Is implements errors.Is
+func (e *InvalidTransitionError) Is(target error) bool { return target == ErrInvalidTransition }
+
+// checkTransition refuses to move video to status to unless the transition is
+// allowed. A forced replay skips the check, as it skips guardReplay, so an operator
+// can still apply an event the state machine would reject.
+func checkTransition(ctx context.Context, video *models.Video, to models.VideoStatus) error {
+	if video.Status.CanTransitionTo(to) {
+		return nil
+	}
+	if md, ok := events.FromContext(ctx); ok && md.Replay && md.Force {
+		return nil
+	}
+	return &InvalidTransitionError{VideoID: video.ID, From: video.Status, To: to}
+}
+
+// eventStatusSources names the history source for each broker event kind
+var eventStatusSources = map[string]string{
+	EventKindUploaded:        models.StatusSourceUploadEvent,
+	EventKindTranscoded:      models.StatusSourceTranscodedEvent,
+	EventKindTranscodeFailed: models.StatusSourceFailedEvent,
+}
+
+// recordStatusChange appends to video's status history when its status differs
+// from from, which is empty for a video being created. It must run in the
+// transaction that changed the status. A move to failed records the failure
+// reason unless reason is given.
+func recordStatusChange(tx *gorm.DB, from models.VideoStatus, video *models.Video, source, actorID, reason string) error {
+	if from == video.Status {
+		return nil
+	}
+	if reason == "" && video.Status == models.StatusFailed {
+		reason = video.FailureReason
+	}
+	entry := &models.VideoStatusChange{VideoID: video.ID, From: from, To: video.Status, Source: source, ActorID: actorID, Reason: reason}
+	if err := tx.Create(entry).Error; err != nil {
+		return fmt.Errorf("record status change: %w", err)
+	}
+	return nil
+}
+
+// StatusHistory returns every recorded status change of a video, oldest first
+func (s *VideoService) StatusHistory(ctx context.Context, videoID uint) ([]models.VideoStatusChange, error) {
+	var history []models.VideoStatusChange
+	if err := s.db.WithContext(ctx).Where("video_id = ?", videoID).Order("created_at, id").Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("status history: %w", err)
+	}
+	return history, nil
+}
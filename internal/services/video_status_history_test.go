@@ -0,0 +1,122 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// historyLine renders a status change as from>to/source/actor/reason
+func historyLine(c models.VideoStatusChange) string {
+	return fmt.Sprintf("%s>%s/%s/%s/%s", c.From, c.To, c.Source, c.ActorID, c.Reason)
+}
+
+func historyOf(t *testing.T, videos *services.VideoService, videoID uint) string {
+	t.Helper()
+	history, err := videos.StatusHistory(context.Background(), videoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := make([]string, len(history))
+	for i, c := range history {
+		if c.VideoID != videoID || c.CreatedAt.IsZero() {
+			t.Errorf("entry %d = %+v", i, c)
+		}
+		lines[i] = historyLine(c)
+	}
+	return strings.Join(lines, " ")
+}
+
+func TestStatusHistoryLifecycle(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+
+	video, err := videos.CreateVideo(ctx, "owner", &models.VideoCreateRequest{UploadID: "up-1", Title: "t", Visibility: models.VisibilityPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"uploaded", func() error {
+			return videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-1", UserID: "owner", Title: "t"})
+		}},
+		// A redelivery changes nothing, so records nothing
+		{"uploaded again", func() error {
+			return videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-1", UserID: "owner", Title: "t"})
+		}},
+		{"transcode failed", func() error {
+			return videos.HandleTranscodeFailedEvent(ctx, &models.TranscodeFailedEvent{UploadID: "up-1", UserID: "owner", ErrorMessage: "codec"})
+		}},
+		// Refused by the state machine, so nothing is written
+		{"late success", func() error {
+			if err := videos.HandleTranscodedEvent(ctx, transcodedEvent("up-1")); !errors.Is(err, services.ErrInvalidTransition) {
+				return fmt.Errorf("got %v, want an invalid transition", err)
+			}
+			return nil
+		}},
+		{"admin retry", func() error {
+			_, err := videos.SetVideoStatus(ctx, video.ID, models.StatusProcessing, "admin", "retry")
+			return err
+		}},
+		{"transcoded", func() error { return videos.HandleTranscodedEvent(ctx, transcodedEvent("up-1")) }},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+	}
+
+	want := strings.Join([]string{
+		">uploaded/api/owner/",
+		"uploaded>processing/upload-event//",
+		"processing>failed/failed-event//codec",
+		"failed>processing/api/admin/retry",
+		"processing>ready/transcoded-event//",
+	}, " ")
+	if got := historyOf(t, videos, video.ID); got != want {
+		t.Errorf("history:\n got %s\nwant %s", got, want)
+	}
+}
+
+// TestStatusHistoryOutOfOrder starts the history at the placeholder a transcode
+// creates before its upload event arrives
+func TestStatusHistoryOutOfOrder(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+	if err := videos.HandleTranscodedEvent(ctx, transcodedEvent("up-1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-1", UserID: "owner", Title: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	var video models.Video
+	db.Where("upload_id = ?", "up-1").First(&video)
+	if got := historyOf(t, videos, video.ID); got != ">ready/transcoded-event//" {
+		t.Errorf("history = %s, want the placeholder created ready", got)
+	}
+}
+
+func TestStatusHistorySection(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{Title: "t", Status: models.StatusProcessing})
+	if _, err := videos.SetVideoStatus(ctx, video.ID, models.StatusFailed, "admin", "stuck"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := services.StatusHistorySection{DB: db}.Collect(ctx, video)
+	history, _ := data.([]models.VideoStatusChange)
+	if err != nil || len(history) != 1 || historyLine(history[0]) != "processing>failed/api/admin/stuck" {
+		t.Errorf("section = %+v, %v", data, err)
+	}
+}
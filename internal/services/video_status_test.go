@@ -0,0 +1,168 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/events"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestSetVideoStatusTransitions(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	ctx := context.Background()
+	for _, from := range models.VideoStatuses {
+		for _, to := range models.VideoStatuses {
+			video := createVideo(t, db, models.Video{Title: string(from) + "-" + string(to), Status: from})
+			_, err := videos.SetVideoStatus(ctx, video.ID, to, "admin", "")
+			var current models.Video
+			db.First(&current, video.ID)
+			if from.CanTransitionTo(to) {
+				if err != nil || current.Status != to {
+					t.Errorf("%s -> %s: %v, status %s", from, to, err, current.Status)
+				}
+				continue
+			}
+			var invalid *services.InvalidTransitionError
+			if !errors.As(err, &invalid) || !errors.Is(err, services.ErrInvalidTransition) {
+				t.Errorf("%s -> %s: %v, want an InvalidTransitionError", from, to, err)
+				continue
+			}
+			if invalid.VideoID != video.ID || invalid.From != from || invalid.To != to {
+				t.Errorf("%s -> %s: error %+v", from, to, invalid)
+			}
+			if current.Status != from {
+				t.Errorf("%s -> %s: status changed to %s despite the error", from, to, current.Status)
+			}
+		}
+	}
+}
+
+// TestEventStatusTransitions applies each broker event to a video in each status,
+// "" meaning the event arrives before any row exists
+func TestEventStatusTransitions(t *testing.T) {
+	const (
+		uploaded   = models.StatusUploaded
+		processing = models.StatusProcessing
+		ready      = models.StatusReady
+		failed     = models.StatusFailed
+	)
+	tests := []struct {
+		start   models.VideoStatus
+		event   string
+		want    models.VideoStatus
+		invalid bool
+	}{
+		{"", services.EventKindUploaded, processing, false},
+		{"", services.EventKindTranscoded, ready, false},
+		{"", services.EventKindTranscodeFailed, failed, false},
+		{uploaded, services.EventKindUploaded, processing, false},
+		{uploaded, services.EventKindTranscoded, ready, false},
+		{uploaded, services.EventKindTranscodeFailed, failed, false},
+		{processing, services.EventKindUploaded, processing, false},
+		{processing, services.EventKindTranscoded, ready, false},
+		{processing, services.EventKindTranscodeFailed, failed, false},
+		{ready, services.EventKindUploaded, ready, false},
+		{ready, services.EventKindTranscoded, ready, false},
+		// A late failure for a ready video is ignored
+		{ready, services.EventKindTranscodeFailed, ready, false},
+		{failed, services.EventKindUploaded, failed, false},
+		// A late success must not revive a failed video
+		{failed, services.EventKindTranscoded, failed, true},
+		{failed, services.EventKindTranscodeFailed, failed, false},
+	}
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+	for i, tt := range tests {
+		uploadID := fmt.Sprintf("up-%d", i)
+		if tt.start != "" {
+			createVideo(t, db, models.Video{UploadID: uploadID, Title: "t", Status: tt.start})
+		}
+		err := applyEvent(ctx, videos, tt.event, uploadID)
+		if tt.invalid != errors.Is(err, services.ErrInvalidTransition) || !tt.invalid && err != nil {
+			t.Errorf("%q + %s: %v, want invalid transition %v", tt.start, tt.event, err, tt.invalid)
+		}
+		var video models.Video
+		if err := db.Where("upload_id = ?", uploadID).First(&video).Error; err != nil {
+			t.Fatalf("%q + %s: %v", tt.start, tt.event, err)
+		}
+		if video.Status != tt.want {
+			t.Errorf("%q + %s: status %s, want %s", tt.start, tt.event, video.Status, tt.want)
+		}
+	}
+}
+
+func applyEvent(ctx context.Context, videos *services.VideoService, kind, uploadID string) error {
+	switch kind {
+	case services.EventKindUploaded:
+		return videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: uploadID, UserID: "owner", Title: "t"})
+	case services.EventKindTranscoded:
+		return videos.HandleTranscodedEvent(ctx, transcodedEvent(uploadID))
+	default:
+		return videos.HandleTranscodeFailedEvent(ctx, &models.TranscodeFailedEvent{UploadID: uploadID, UserID: "owner", ErrorMessage: "codec"})
+	}
+}
+
+func transcodedEvent(uploadID string) *models.TranscodedEvent {
+	return &models.TranscodedEvent{UploadID: uploadID, UserID: "owner", Ready: true,
+		HLS: models.HLSInfo{MasterURL: "https://cdn.example/" + uploadID + "/master.m3u8"}}
+}
+
+// TestOutOfOrderEvents delivers the transcode before the upload it follows
+func TestOutOfOrderEvents(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	ctx := context.Background()
+	if err := videos.HandleTranscodedEvent(ctx, transcodedEvent("up-1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := videos.HandleUploadedEvent(ctx, &models.UploadedEvent{UploadID: "up-1", UserID: "owner", Title: "Holiday"}); err != nil {
+		t.Fatal(err)
+	}
+	var video models.Video
+	db.Where("upload_id = ?", "up-1").First(&video)
+	if video.Status != models.StatusReady || video.Title != "Holiday" {
+		t.Errorf("video = %s %q, want ready with the upload's title", video.Status, video.Title)
+	}
+}
+
+func TestReplayRefusedTransition(t *testing.T) {
+	db := dbtest.Open(t)
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	quarantine := services.NewEventQuarantineService(db, nopLogger(), videos)
+	ctx := context.Background()
+	video := createVideo(t, db, models.Video{UploadID: "up-1", Title: "t", Status: models.StatusFailed})
+
+	// Newer than the failure, so only the state machine stands in its way
+	event := transcodedEvent("up-1")
+	occurred := time.Now().Add(time.Minute)
+	event.OccurredAt = &occurred
+	body, _ := json.Marshal(event)
+	handleErr := videos.HandleTranscodedEvent(ctx, event)
+	held, err := quarantine.Quarantine(ctx, services.EventKindTranscoded, events.Metadata{RoutingKey: "video.transcoded"}, body, handleErr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := quarantine.Replay(ctx, held.ID, false)
+	if !errors.Is(err, services.ErrInvalidTransition) || replayed.Status != models.QuarantineRejected {
+		t.Fatalf("replay: %v, quarantine status %s; want it rejected", err, replayed.Status)
+	}
+	// Forcing it applies the event anyway
+	if _, err := quarantine.Replay(ctx, held.ID, true); err != nil {
+		t.Fatalf("forced replay: %v", err)
+	}
+	var current models.Video
+	db.First(&current, video.ID)
+	if current.Status != models.StatusReady {
+		t.Errorf("status %s after a forced replay, want ready", current.Status)
+	}
+}
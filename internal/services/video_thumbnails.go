@@ -0,0 +1,191 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// Uploaded thumbnails must be JPEG or PNG within these dimensions
+const (
+	MinThumbnailUploadWidth  = 320
+	MinThumbnailUploadHeight = 180
+	MaxThumbnailUploadWidth  = 3840
+	MaxThumbnailUploadHeight = 2160
+)
+
+// thumbnailUploadTypes maps the accepted image formats to their content type and extension
+var thumbnailUploadTypes = map[string][2]string{
+	"jpeg": {"image/jpeg", "jpg"},
+	"png":  {"image/png", "png"},
+}
+
+// addThumbnails stores video.Thumbnails as candidates, skipping URLs the video
+// already has, so replayed events add nothing
+func addThumbnails(tx *gorm.DB, video *models.Video) error {
+	if len(video.Thumbnails) == 0 {
+		return nil
+	}
+	for i := range video.Thumbnails {
+		video.Thumbnails[i].ID = 0
+		video.Thumbnails[i].VideoID = video.ID
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&video.Thumbnails).Error; err != nil {
+		return fmt.Errorf("create thumbnails: %w", err)
+	}
+	return nil
+}
+
+// ListThumbnails returns video's thumbnail candidates, oldest first, marking the
+// one that is the video's thumbnail
+func (s *VideoService) ListThumbnails(ctx context.Context, video *models.Video) ([]models.VideoThumbnail, error) {
+	thumbnails := []models.VideoThumbnail{}
+	if err := s.db.WithContext(ctx).Where("video_id = ?", video.ID).Order("id").Find(&thumbnails).Error; err != nil {
+		return nil, fmt.Errorf("list thumbnails: %w", err)
+	}
+	for i := range thumbnails {
+		t := &thumbnails[i]
+		if video.ThumbnailID != nil {
+			t.Selected = t.ID == *video.ThumbnailID
+		} else {
+			t.Selected = t.URL == video.ThumbnailURL
+		}
+	}
+	return thumbnails, nil
+}
+
+// SelectThumbnail makes candidate thumbnailID the thumbnail of video id, for its
+// owner userID. Later transcoded events no longer change it.
+func (s *VideoService) SelectThumbnail(ctx context.Context, id uint, userID string, thumbnailID uint) (*models.Video, error) {
+	var video models.Video
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockOwnedVideo(tx, id, userID, &video); err != nil {
+			return err
+		}
+		var thumb models.VideoThumbnail
+		if err := tx.Where("id = ? AND video_id = ?", thumbnailID, id).First(&thumb).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("thumbnail %d of video %d: %w", thumbnailID, id, ErrThumbnailNotFound)
+			}
+			return err
+		}
+		return s.setThumbnail(tx, &video, &thumb)
+	})
+	if err != nil {
+		if errors.Is(err, ErrVideoNotFound) || errors.Is(err, ErrForbidden) || errors.Is(err, ErrThumbnailNotFound) {
+			return nil, err
+		}
+		s.log(ctx).Errorw("Failed to select thumbnail", "error", err, "videoID", id, "thumbnailID", thumbnailID)
+		return nil, fmt.Errorf("select thumbnail: %w", err)
+	}
+	s.changes.Emit(context.WithoutCancel(ctx), VideoUpdated, &video)
+	s.log(ctx).Infow("Thumbnail selected", "videoID", id, "thumbnailID", thumbnailID)
+	return &video, nil
+}
+
+// UploadThumbnail stores data, a JPEG or PNG image, as a candidate thumbnail of
+// video id and selects it, for its owner userID. The image is checked against the
+// upload dimension limits; callers enforce the size limit.
+func (s *VideoService) UploadThumbnail(ctx context.Context, id uint, userID string, data []byte) (*models.VideoThumbnail, *models.Video, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, &ValidationError{Err: ErrInvalidThumbnail, Fields: map[string]string{"file": "must be a JPEG or PNG image"}}
+	}
+	kind, ok := thumbnailUploadTypes[format]
+	if !ok {
+		return nil, nil, &ValidationError{Err: ErrInvalidThumbnail, Fields: map[string]string{"file": "must be a JPEG or PNG image"}}
+	}
+	if cfg.Width < MinThumbnailUploadWidth || cfg.Height < MinThumbnailUploadHeight ||
+		cfg.Width > MaxThumbnailUploadWidth || cfg.Height > MaxThumbnailUploadHeight {
+		return nil, nil, &ValidationError{Err: ErrInvalidThumbnail, Fields: map[string]string{"file": fmt.Sprintf(
+			"must be between %dx%d and %dx%d pixels, got %dx%d", MinThumbnailUploadWidth, MinThumbnailUploadHeight,
+			MaxThumbnailUploadWidth, MaxThumbnailUploadHeight, cfg.Width, cfg.Height)}}
+	}
+	storage := s.Storage()
+	if storage == nil {
+		return nil, nil, ErrStorageUnavailable
+	}
+	video, err := s.GetVideo(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if video.UserID != userID {
+		return nil, nil, fmt.Errorf("upload thumbnail of video %d: %w", id, ErrForbidden)
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, nil, fmt.Errorf("generate thumbnail name: %w", err)
+	}
+	blobPath := fmt.Sprintf("%scustom-%s.%s", thumbnailBlobPrefix(video), hex.EncodeToString(suffix), kind[1])
+	url, err := storage.UploadBlob(ctx, blobPath, data, kind[0])
+	if err != nil {
+		s.log(ctx).Errorw("Failed to upload thumbnail", "error", err, "videoID", id, "path", blobPath)
+		return nil, nil, fmt.Errorf("upload thumbnail: %w", err)
+	}
+
+	thumb := &models.VideoThumbnail{VideoID: id, Source: models.ThumbnailFromUpload, URL: url, BlobPath: blobPath,
+		Width: cfg.Width, Height: cfg.Height, Selected: true}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockOwnedVideo(tx, id, userID, video); err != nil {
+			return err
+		}
+		if err := tx.Create(thumb).Error; err != nil {
+			return fmt.Errorf("create thumbnail: %w", err)
+		}
+		return s.setThumbnail(tx, video, thumb)
+	})
+	if err != nil {
+		// Nothing refers to the blob yet, so don't leave it behind
+		if derr := storage.DeleteBlob(context.WithoutCancel(ctx), blobPath); derr != nil {
+			s.log(ctx).Warnw("Failed to remove unused thumbnail upload", "error", derr, "path", blobPath)
+		}
+		if errors.Is(err, ErrVideoNotFound) || errors.Is(err, ErrForbidden) {
+			return nil, nil, err
+		}
+		s.log(ctx).Errorw("Failed to save uploaded thumbnail", "error", err, "videoID", id)
+		return nil, nil, fmt.Errorf("save thumbnail: %w", err)
+	}
+	s.changes.Emit(context.WithoutCancel(ctx), VideoUpdated, video)
+	s.log(ctx).Infow("Thumbnail uploaded", "videoID", id, "thumbnailID", thumb.ID, "bytes", len(data))
+	return thumb, video, nil
+}
+
+// lockOwnedVideo row-locks video id into video, failing unless userID owns it
+func lockOwnedVideo(tx *gorm.DB, id uint, userID string, video *models.Video) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(video, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("video %d: %w", id, ErrVideoNotFound)
+		}
+		return err
+	}
+	if video.UserID != userID {
+		return fmt.Errorf("change thumbnail of video %d: %w", id, ErrForbidden)
+	}
+	return nil
+}
+
+// setThumbnail makes thumb video's thumbnail on the locked row. updated_at moves
+// with it, which also changes the resized thumbnails' cache key.
+func (s *VideoService) setThumbnail(tx *gorm.DB, video *models.Video, thumb *models.VideoThumbnail) error {
+	video.ThumbnailURL, video.ThumbnailID, video.ThumbnailPath = thumb.URL, &thumb.ID, thumb.BlobPath
+	video.Version++
+	if err := tx.Model(video).Updates(map[string]interface{}{
+		"thumbnail_url":  video.ThumbnailURL,
+		"thumbnail_id":   thumb.ID,
+		"thumbnail_path": video.ThumbnailPath,
+		"version":        video.Version,
+	}).Error; err != nil {
+		return fmt.Errorf("set thumbnail: %w", err)
+	}
+	return enqueueCatalogEvent(tx, s.outbox, VideoUpdated, video)
+}
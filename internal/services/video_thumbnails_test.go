@@ -0,0 +1,230 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+// grayImage encodes a blank w x h PNG, cheaper than an RGBA one at the upload limits
+func grayImage(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// transcodedWithCandidates is a transcoded event for upload up-1 offering urls as
+// thumbnail candidates
+func transcodedWithCandidates(urls ...string) *models.TranscodedEvent {
+	event := &models.TranscodedEvent{UploadID: "up-1", UserID: "owner", Ready: true,
+		HLS: models.HLSInfo{MasterURL: "https://cdn.example/up-1/master.m3u8"}}
+	for _, url := range urls {
+		event.Thumbnails = append(event.Thumbnails, models.ThumbnailInfo{URL: url, Path: strings.TrimPrefix(url, "https://cdn.example/")})
+	}
+	return event
+}
+
+func TestSelectThumbnail(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	if err := videos.HandleTranscodedEvent(ctx, transcodedWithCandidates("https://cdn.example/a.jpg", "https://cdn.example/b.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	video, err := videos.GetVideoByUploadID(ctx, "up-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := createVideo(t, db, models.Video{Title: "other"})
+	foreign := models.VideoThumbnail{VideoID: other.ID, Source: models.ThumbnailFromUpload, URL: "https://cdn.example/other.jpg"}
+	db.Create(&foreign)
+	candidates, err := videos.ListThumbnails(ctx, video)
+	if err != nil || len(candidates) != 2 || !candidates[0].Selected || candidates[1].Selected {
+		t.Fatalf("candidates = %+v, %v; want two with the first selected", candidates, err)
+	}
+
+	tests := []struct {
+		name        string
+		videoID     uint
+		user        string
+		thumbnailID uint
+		want        error
+	}{
+		{"missing candidate", video.ID, "owner", 999, services.ErrThumbnailNotFound},
+		{"another video's candidate", video.ID, "owner", foreign.ID, services.ErrThumbnailNotFound},
+		{"not the owner", video.ID, "mallory", candidates[1].ID, services.ErrForbidden},
+		{"missing video", 999, "owner", candidates[1].ID, services.ErrVideoNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := videos.SelectThumbnail(ctx, tt.videoID, tt.user, tt.thumbnailID); !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if unchanged, _ := videos.GetVideo(ctx, video.ID); unchanged.ThumbnailURL != candidates[0].URL || unchanged.Version != video.Version {
+		t.Errorf("refused selections changed the video: thumbnail %q, version %d", unchanged.ThumbnailURL, unchanged.Version)
+	}
+
+	selected, err := videos.SelectThumbnail(ctx, video.ID, "owner", candidates[1].ID)
+	if err != nil {
+		t.Fatalf("SelectThumbnail: %v", err)
+	}
+	if selected.ThumbnailURL != candidates[1].URL || selected.ThumbnailID == nil || *selected.ThumbnailID != candidates[1].ID ||
+		selected.Version != video.Version+1 {
+		t.Errorf("selected = thumbnail %q (%v), version %d", selected.ThumbnailURL, selected.ThumbnailID, selected.Version)
+	}
+
+	// A later transcode adds candidates but leaves the owner's pick alone
+	event := transcodedWithCandidates("https://cdn.example/c.jpg")
+	event.ThumbnailURL = "https://cdn.example/c.jpg"
+	if err := videos.HandleTranscodedEvent(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	after, err := videos.GetVideo(ctx, video.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.ThumbnailURL != candidates[1].URL {
+		t.Errorf("transcoded event replaced the selected thumbnail with %q", after.ThumbnailURL)
+	}
+}
+
+func TestTranscodedThumbnailCandidatesAreIdempotent(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	urls := func() []string {
+		t.Helper()
+		var out []string
+		db.Model(&models.VideoThumbnail{}).Order("id").Pluck("url", &out)
+		return out
+	}
+
+	event := transcodedWithCandidates("https://cdn.example/a.jpg", "https://cdn.example/b.jpg", "https://cdn.example/c.jpg")
+	for i := 0; i < 3; i++ {
+		if err := videos.HandleTranscodedEvent(ctx, event); err != nil {
+			t.Fatalf("delivery %d: %v", i+1, err)
+		}
+	}
+	if got := urls(); len(got) != 3 {
+		t.Errorf("candidates after redeliveries = %v, want 3", got)
+	}
+
+	// A retried transcode overlapping the first adds only what is new
+	if err := videos.HandleTranscodedEvent(ctx, transcodedWithCandidates("https://cdn.example/c.jpg", "https://cdn.example/d.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	got := urls()
+	if strings.Join(got, ",") != "https://cdn.example/a.jpg,https://cdn.example/b.jpg,https://cdn.example/c.jpg,https://cdn.example/d.jpg" {
+		t.Errorf("candidates = %v", got)
+	}
+}
+
+func TestUploadThumbnail(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	storage := newFakeStorage()
+	videos := services.NewVideoService(db, storageLoader(storage), videoSettings, nopLogger())
+	video := createVideo(t, db, models.Video{UploadID: "up-1", Title: "t"})
+
+	var animated bytes.Buffer
+	if err := gif.Encode(&animated, image.NewGray(image.Rect(0, 0, 640, 360)), nil); err != nil {
+		t.Fatal(err)
+	}
+	rejected := []struct {
+		name string
+		data []byte
+	}{
+		{"not an image", []byte("<html>")},
+		{"gif", animated.Bytes()},
+		{"too narrow", grayImage(t, services.MinThumbnailUploadWidth-1, services.MinThumbnailUploadHeight)},
+		{"too short", grayImage(t, services.MinThumbnailUploadWidth, services.MinThumbnailUploadHeight-1)},
+		{"too wide", grayImage(t, services.MaxThumbnailUploadWidth+1, services.MinThumbnailUploadHeight)},
+		{"too tall", grayImage(t, services.MinThumbnailUploadWidth, services.MaxThumbnailUploadHeight+1)},
+	}
+	for _, tt := range rejected {
+		var invalid *services.ValidationError
+		if _, _, err := videos.UploadThumbnail(ctx, video.ID, "owner", tt.data); !errors.As(err, &invalid) || !errors.Is(err, services.ErrInvalidThumbnail) {
+			t.Errorf("%s: %v, want an invalid thumbnail error", tt.name, err)
+		}
+	}
+	if _, _, err := videos.UploadThumbnail(ctx, video.ID, "mallory", grayImage(t, 640, 360)); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("not the owner: %v, want ErrForbidden", err)
+	}
+	if len(storage.blobs) != 0 {
+		t.Errorf("refused uploads stored %d blobs", len(storage.blobs))
+	}
+
+	for _, size := range [][2]int{
+		{services.MinThumbnailUploadWidth, services.MinThumbnailUploadHeight},
+		{services.MaxThumbnailUploadWidth, services.MaxThumbnailUploadHeight},
+	} {
+		thumb, updated, err := videos.UploadThumbnail(ctx, video.ID, "owner", grayImage(t, size[0], size[1]))
+		if err != nil {
+			t.Fatalf("%dx%d: %v", size[0], size[1], err)
+		}
+		if thumb.Width != size[0] || thumb.Height != size[1] || thumb.Source != models.ThumbnailFromUpload ||
+			!strings.HasPrefix(thumb.BlobPath, "thumbnails/owner/up-1/custom-") || !storage.has(thumb.BlobPath) {
+			t.Errorf("%dx%d: thumbnail = %+v", size[0], size[1], thumb)
+		}
+		if updated.ThumbnailID == nil || *updated.ThumbnailID != thumb.ID || updated.ThumbnailURL != thumb.URL {
+			t.Errorf("%dx%d: upload not selected: %+v", size[0], size[1], updated)
+		}
+	}
+
+	unavailable := services.NewVideoService(db, nil, videoSettings, nopLogger())
+	if _, _, err := unavailable.UploadThumbnail(ctx, video.ID, "owner", grayImage(t, 640, 360)); !errors.Is(err, services.ErrStorageUnavailable) {
+		t.Errorf("without storage: %v, want ErrStorageUnavailable", err)
+	}
+}
+
+func TestDeleteVideoRemovesThumbnailBlobs(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	// up-10 shares up-1's name prefix, and its blobs must survive
+	storage := newFakeStorage(
+		"thumbnails/owner/up-1.jpg",
+		"candidates/up-1/0.jpg",
+		"thumbnails/owner/up-10.jpg",
+		"thumbnails/owner/up-10/custom-a.png",
+	)
+	videos := services.NewVideoService(db, storageLoader(storage), videoSettings, nopLogger())
+	event := transcodedWithCandidates("https://cdn.example/candidates/up-1/0.jpg")
+	if err := videos.HandleTranscodedEvent(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	video, err := videos.GetVideoByUploadID(ctx, "up-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, _, err := videos.UploadThumbnail(ctx, video.ID, "owner", grayImage(t, 640, 360))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deletes := services.NewVideoDeleteService(db, nopLogger(), storage)
+	if _, err := deletes.DeleteVideoCompletely(ctx, video.ID, 1, "owner"); err != nil {
+		t.Fatalf("DeleteVideoCompletely: %v", err)
+	}
+	for _, path := range []string{"thumbnails/owner/up-1.jpg", "candidates/up-1/0.jpg", thumb.BlobPath} {
+		if storage.has(path) {
+			t.Errorf("%s survived the deletion", path)
+		}
+	}
+	for _, path := range []string{"thumbnails/owner/up-10.jpg", "thumbnails/owner/up-10/custom-a.png"} {
+		if !storage.has(path) {
+			t.Errorf("another upload's %s was deleted", path)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestVideoVersion(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	videos := services.NewVideoService(db, storageLoader(newFakeStorage()), videoSettings, nopLogger())
+	video := createVideo(t, db, models.Video{UploadID: "up-1", UserID: "owner", Title: "t", Status: models.StatusProcessing})
+	version := func() int64 {
+		t.Helper()
+		got, err := videos.GetVideo(ctx, video.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.Version
+	}
+	title := func(s string) *models.VideoUpdateRequest { return &models.VideoUpdateRequest{Title: &s} }
+	if v := version(); v != 1 {
+		t.Fatalf("new video at version %d, want 1", v)
+	}
+
+	updated, err := videos.UpdateVideoForUser(ctx, video.ID, "owner", 1, title("first tab"))
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("update at version 1 = %+v, %v; want version 2", updated, err)
+	}
+	// The second tab still holds version 1: refused, nothing written
+	if _, err := videos.UpdateVideoForUser(ctx, video.ID, "owner", 1, title("second tab")); !errors.Is(err, services.ErrVersionConflict) {
+		t.Errorf("update at a stale version: %v, want ErrVersionConflict", err)
+	}
+	if got, _ := videos.GetVideo(ctx, video.ID); got.Title != "first tab" || got.Version != 2 {
+		t.Errorf("after the refused update: %q at version %d", got.Title, got.Version)
+	}
+
+	// Event writes skip the check but still move the version on
+	if err := videos.HandleTranscodedEvent(ctx, transcodedEvent("up-1")); err != nil {
+		t.Fatal(err)
+	}
+	if v := version(); v != 3 {
+		t.Errorf("after the transcoded event at version %d, want 3", v)
+	}
+	if _, err := videos.UpdateVideoForUser(ctx, video.ID, "owner", 2, title("read before the event")); !errors.Is(err, services.ErrVersionConflict) {
+		t.Errorf("update at the version before the event: %v, want ErrVersionConflict", err)
+	}
+	// Unconditional updates always apply
+	if updated, err := videos.UpdateVideoForUser(ctx, video.ID, "owner", 0, title("last write")); err != nil || updated.Version != 4 {
+		t.Errorf("unconditional update = %+v, %v; want version 4", updated, err)
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// ErrVideoPublic is returned when rotating the share token of a public video,
+// which has none
+var ErrVideoPublic = errors.New("public videos have no share token")
+
+// newShareToken returns 32 random bytes, hex-encoded
+func newShareToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate share token: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// setVisibility changes video's visibility. A video that stops being public gets
+// a share token if it has none; one that becomes public drops its token, so
+// unlisting it again issues a new link.
+func setVisibility(video *models.Video, visibility models.Visibility) error {
+	video.Visibility = visibility
+	if video.IsPublic() {
+		video.ShareToken = ""
+		return nil
+	}
+	if video.ShareToken != "" {
+		return nil
+	}
+	token, err := newShareToken()
+	if err != nil {
+		return err
+	}
+	video.ShareToken = token
+	return nil
+}
+
+// requestedVisibility resolves a create or update request's visibility: an explicit
+// visibility wins over the legacy is_private flag. ok is false when neither is set.
+func requestedVisibility(visibility *models.Visibility, isPrivate *bool) (models.Visibility, bool) {
+	switch {
+	case visibility != nil && *visibility != "":
+		return *visibility, true
+	case isPrivate != nil:
+		return models.VisibilityFromPrivate(*isPrivate), true
+	default:
+		return "", false
+	}
+}
+
+// RotateShareToken replaces the share token of userID's unlisted or private video,
+// so links handed out with the old token stop working
+func (s *VideoService) RotateShareToken(ctx context.Context, id uint, userID string) (*models.Video, error) {
+	var video models.Video
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&video, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("video %d: %w", id, ErrVideoNotFound)
+			}
+			return err
+		}
+		if video.UserID != userID {
+			return fmt.Errorf("rotate share token of video %d: %w", id, ErrForbidden)
+		}
+		if video.IsPublic() {
+			return fmt.Errorf("video %d: %w", id, ErrVideoPublic)
+		}
+		token, err := newShareToken()
+		if err != nil {
+			return err
+		}
+		video.ShareToken = token
+		video.Version++
+		return tx.Model(&video).UpdateColumns(map[string]interface{}{"share_token": token, "version": video.Version}).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrVideoNotFound) || errors.Is(err, ErrForbidden) || errors.Is(err, ErrVideoPublic) {
+			return nil, err
+		}
+		s.logger.Errorw("Failed to rotate share token", "error", err, "videoID", id)
+		return nil, fmt.Errorf("rotate share token: %w", err)
+	}
+	s.logger.Infow("Share token rotated", "videoID", id, "userID", userID)
+	return &video, nil
+}
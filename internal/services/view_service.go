@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// ViewerIPPrefix marks a viewer identified only by remote address
+const ViewerIPPrefix = "ip:"
+
+// viewDateLayout formats the UTC day a view is deduped under
+const viewDateLayout = "2006-01-02"
+
+// ViewService counts video views, ignoring repeats by the same viewer within the
+// dedup interval
+type ViewService struct {
+	db        *gorm.DB
+	logger    *zap.SugaredLogger
+	interval  time.Duration
+	anonymous *AnonymousSessionService
+	// cache holds videos whose view_count a counted view makes stale
+	cache *VideoCache
+}
+
+// NewViewService creates a view counter. A viewer's views of a video closer together
+// than interval count once.
+func NewViewService(db *gorm.DB, logger *zap.SugaredLogger, interval time.Duration) *ViewService {
+	return &ViewService{db: db, logger: logger, interval: interval}
+}
+
+// SetAnonymousSessions lets anonymous sessions record views; their dedup rows take
+// part in the session's quota, merge and retention
+func (s *ViewService) SetAnonymousSessions(a *AnonymousSessionService) {
+	s.anonymous = a
+	a.RegisterData(AnonymousData{Name: "views", Table: "video_views", Column: "viewer", Merge: mergeViews})
+}
+
+// SetCache drops a video's cached copy whenever a view of it is counted. Cached
+// listings are left to expire, so their view counts lag by up to the cache TTL.
+func (s *ViewService) SetCache(c *VideoCache) { s.cache = c }
+
+// RecordView counts a view of videoID by viewer unless the viewer's last counted
+// view of it today is within the dedup interval. It reports whether the view was
+// counted. Callers must have checked the viewer may see the video.
+func (s *ViewService) RecordView(ctx context.Context, videoID uint, viewer string) (bool, error) {
+	if models.IsAnonymousIdentity(viewer) && s.anonymous != nil {
+		if err := s.anonymous.CheckQuota(ctx, viewer); err != nil {
+			return false, err
+		}
+	}
+	now := time.Now().UTC()
+	counted := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The conditional upsert is the dedup check: it only touches the row when
+		// the last counted view is older than the interval
+		res := tx.Exec(`INSERT INTO video_views (video_id, viewer, view_date, views, last_counted_at)
+			VALUES (?, ?, ?, 1, ?)
+			ON CONFLICT (video_id, viewer, view_date) DO UPDATE
+			SET views = video_views.views + 1, last_counted_at = EXCLUDED.last_counted_at
+			WHERE video_views.last_counted_at <= ?`,
+			videoID, viewer, now.Format(viewDateLayout), now, now.Add(-s.interval))
+		if res.Error != nil {
+			return fmt.Errorf("record view: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		res = tx.Model(&models.Video{}).Where("id = ?", videoID).
+			UpdateColumn("view_count", gorm.Expr("view_count + 1"))
+		if res.Error != nil {
+			return fmt.Errorf("increment view count: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("video %d: %w", videoID, ErrVideoNotFound)
+		}
+		counted = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if models.IsAnonymousIdentity(viewer) && s.anonymous != nil {
+		if err := s.anonymous.Touch(ctx, strings.TrimPrefix(viewer, models.AnonymousPrefix)); err != nil {
+			s.logger.Warnw("Failed to touch anonymous session", "error", err, "viewer", viewer)
+		}
+	}
+	outcome := "deduped"
+	if counted {
+		outcome = "counted"
+		s.cache.InvalidateVideo(ctx, videoID)
+	}
+	metrics.VideoViewsTotal.WithLabelValues(outcome).Inc()
+	return counted, nil
+}
+
+// mergeViews moves an anonymous session's view rows to a user, folding them into
+// the user's own row where both viewed the same video on the same day
+func mergeViews(tx *gorm.DB, from, to string) (int64, error) {
+	if err := tx.Exec(`UPDATE video_views AS u
+		SET views = u.views + a.views, last_counted_at = GREATEST(u.last_counted_at, a.last_counted_at)
+		FROM video_views a
+		WHERE a.viewer = ? AND u.viewer = ? AND u.video_id = a.video_id AND u.view_date = a.view_date`,
+		from, to).Error; err != nil {
+		return 0, err
+	}
+	folded := tx.Exec(`DELETE FROM video_views AS a
+		WHERE a.viewer = ? AND EXISTS (SELECT 1 FROM video_views u
+			WHERE u.viewer = ? AND u.video_id = a.video_id AND u.view_date = a.view_date)`, from, to)
+	if folded.Error != nil {
+		return 0, folded.Error
+	}
+	moved := tx.Exec("UPDATE video_views SET viewer = ? WHERE viewer = ?", to, from)
+	return folded.RowsAffected + moved.RowsAffected, moved.Error
+}
+
+// PruneDedupRows deletes dedup rows for days older than retention. Counts are kept;
+// only the record of who viewed goes.
+func (s *ViewService) PruneDedupRows(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoff := time.Now().UTC().Add(-retention).Format(viewDateLayout)
+	res := s.db.WithContext(ctx).Where("view_date < ?", cutoff).Delete(&models.VideoView{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("prune video views: %w", res.Error)
+	}
+	if res.RowsAffected > 0 {
+		s.logger.Infow("Pruned video view dedup rows", "rows", res.RowsAffected, "retention", retention)
+	}
+	return res.RowsAffected, nil
+}
@@ -0,0 +1,107 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func viewOutcomes(outcome string) float64 {
+	return testutil.ToFloat64(metrics.VideoViewsTotal.WithLabelValues(outcome))
+}
+
+func TestRecordViewDedups(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	views := services.NewViewService(db, nopLogger(), time.Hour)
+	video := createVideo(t, db, models.Video{Title: "t"})
+	record := func(viewer string) bool {
+		t.Helper()
+		counted, err := views.RecordView(ctx, video.ID, viewer)
+		if err != nil {
+			t.Fatalf("RecordView(%s): %v", viewer, err)
+		}
+		return counted
+	}
+
+	counted, deduped := viewOutcomes("counted"), viewOutcomes("deduped")
+	if !record("u-1") {
+		t.Error("first view not counted")
+	}
+	if record("u-1") {
+		t.Error("repeat within the interval counted")
+	}
+	if !record(services.ViewerIPPrefix + "10.0.0.1") {
+		t.Error("another viewer's view not counted")
+	}
+	var row models.Video
+	db.First(&row, video.ID)
+	if row.ViewCount != 2 {
+		t.Errorf("view_count %d, want 2", row.ViewCount)
+	}
+	if c, d := viewOutcomes("counted")-counted, viewOutcomes("deduped")-deduped; c != 2 || d != 1 {
+		t.Errorf("counted +%v, deduped +%v; want +2, +1", c, d)
+	}
+
+	// A view of a missing video leaves no dedup row behind
+	if _, err := views.RecordView(ctx, video.ID+100, "u-1"); !errors.Is(err, services.ErrVideoNotFound) {
+		t.Errorf("missing video: %v, want ErrVideoNotFound", err)
+	}
+	var rows int64
+	db.Model(&models.VideoView{}).Where("video_id = ?", video.ID+100).Count(&rows)
+	if rows != 0 {
+		t.Errorf("%d dedup rows for a missing video", rows)
+	}
+}
+
+// TestRecordViewAfterInterval counts the same viewer again once the interval has
+// passed, on the same dedup row
+func TestRecordViewAfterInterval(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	views := services.NewViewService(db, nopLogger(), 20*time.Millisecond)
+	video := createVideo(t, db, models.Video{Title: "t"})
+
+	views.RecordView(ctx, video.ID, "u-1")
+	time.Sleep(30 * time.Millisecond)
+	if counted, err := views.RecordView(ctx, video.ID, "u-1"); err != nil || !counted {
+		t.Fatalf("view after the interval = %v, %v; want counted", counted, err)
+	}
+	var dedup []models.VideoView
+	db.Where("video_id = ?", video.ID).Find(&dedup)
+	if len(dedup) != 1 || dedup[0].Views != 2 {
+		t.Errorf("dedup rows %+v, want one row with 2 views", dedup)
+	}
+}
+
+// TestPruneDedupRows drops the record of who viewed on old days but keeps the counts
+func TestPruneDedupRows(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	views := services.NewViewService(db, nopLogger(), time.Hour)
+	video := createVideo(t, db, models.Video{Title: "t", ViewCount: 3})
+	today := time.Now().UTC()
+	for i, day := range []time.Time{today.AddDate(0, 0, -10), today.AddDate(0, 0, -3), today} {
+		db.Create(&models.VideoView{VideoID: video.ID, Viewer: "u-" + string(rune('a'+i)), ViewDate: day, Views: 1, LastCountedAt: day})
+	}
+
+	pruned, err := views.PruneDedupRows(ctx, 7*24*time.Hour)
+	if err != nil || pruned != 1 {
+		t.Fatalf("PruneDedupRows = %d, %v; want 1", pruned, err)
+	}
+	var left int64
+	db.Model(&models.VideoView{}).Count(&left)
+	var row models.Video
+	db.First(&row, video.ID)
+	if left != 2 || row.ViewCount != 3 {
+		t.Errorf("%d dedup rows left, view_count %d; want 2 and 3", left, row.ViewCount)
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// watchMilestones are the completion percentages reported to recommendations, in
+// order. Bit i of a milestone mask records watchMilestones[i].
+var watchMilestones = []int{25, 50, 75, 95}
+
+// MilestoneMask returns the mask of milestones reached at position seconds into a
+// video of duration seconds. Callers OR it into the mask stored with the viewer's
+// progress; bits are never cleared, so seeking back and re-watching reports nothing.
+func MilestoneMask(position, duration float64) uint8 {
+	if duration <= 0 || position <= 0 {
+		return 0
+	}
+	var mask uint8
+	for i, pct := range watchMilestones {
+		if position*100 >= duration*float64(pct) {
+			mask |= 1 << i
+		}
+	}
+	return mask
+}
+
+// newMilestones lists the milestones set in reached but not in recorded
+func newMilestones(recorded, reached uint8) []int {
+	var out []int
+	for i, pct := range watchMilestones {
+		if reached&(1<<i) != 0 && recorded&(1<<i) == 0 {
+			out = append(out, pct)
+		}
+	}
+	return out
+}
+
+// WatchMilestone is the video.watch.milestone message
+type WatchMilestone struct {
+	// ViewerID is the user ID, or anon:<session> for a logged-out viewer
+	ViewerID   string    `json:"viewerId"`
+	Anonymous  bool      `json:"anonymous"`
+	VideoID    uint      `json:"videoId"`
+	Milestone  int       `json:"milestone"`
+	ReachedAt  time.Time `json:"reachedAt"`
+	ProducedAt time.Time `json:"producedAt"`
+}
+
+// watchMilestonePublishTimeout bounds the publishes for one progress report
+const watchMilestonePublishTimeout = 2 * time.Second
+
+// WatchMilestones publishes video.watch.milestone messages when watch progress
+// crosses a milestone. The progress write path keeps the milestones a viewer has
+// reached as a mask on their progress row, updated under the row's lock together
+// with the position, and passes the masks from before and after; so each
+// viewer/video/milestone is reported at most once however reports are retried or
+// duplicated. Publishing is best-effort: a failed publish is logged and not retried.
+type WatchMilestones struct {
+	publisher  MessagePublisher
+	logger     *zap.SugaredLogger
+	routingKey string
+	enabled    bool
+}
+
+// NewWatchMilestones creates a milestone reporter. When disabled, masks are still
+// kept but nothing is published, so enabling it later doesn't replay old milestones.
+func NewWatchMilestones(publisher MessagePublisher, logger *zap.SugaredLogger, routingKey string, enabled bool) *WatchMilestones {
+	return &WatchMilestones{publisher: publisher, logger: logger, routingKey: routingKey, enabled: enabled && publisher != nil}
+}
+
+// Report publishes each milestone in reached that recorded, the mask stored before
+// this update, didn't have. A nil reporter does nothing.
+func (w *WatchMilestones) Report(ctx context.Context, viewerID string, videoID uint, recorded, reached uint8, at time.Time) {
+	if w == nil || !w.enabled {
+		return
+	}
+	crossed := newMilestones(recorded, reached)
+	if len(crossed) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, watchMilestonePublishTimeout)
+	defer cancel()
+	for _, pct := range crossed {
+		label := strconv.Itoa(pct)
+		body, err := json.Marshal(WatchMilestone{
+			ViewerID:   viewerID,
+			Anonymous:  models.IsAnonymousIdentity(viewerID),
+			VideoID:    videoID,
+			Milestone:  pct,
+			ReachedAt:  at.UTC(),
+			ProducedAt: time.Now().UTC(),
+		})
+		if err == nil {
+			err = w.publisher.Publish(ctx, w.routingKey, body)
+		}
+		if err != nil {
+			metrics.WatchMilestonesTotal.WithLabelValues(label, "error").Inc()
+			w.logger.Warnw("Failed to publish watch milestone", "error", err, "videoID", videoID, "milestone", pct)
+			continue
+		}
+		metrics.WatchMilestonesTotal.WithLabelValues(label, "published").Inc()
+	}
+}
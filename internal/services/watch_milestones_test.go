@@ -0,0 +1,181 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+const milestoneKey = "video.watch.milestone"
+
+// milestonePublisher is a MessagePublisher that keeps the milestones it was sent
+type milestonePublisher struct {
+	mu   sync.Mutex
+	keys []string
+	msgs []services.WatchMilestone
+	err  error
+}
+
+func (p *milestonePublisher) Publish(_ context.Context, routingKey string, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	var msg services.WatchMilestone
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return err
+	}
+	p.keys = append(p.keys, routingKey)
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *milestonePublisher) milestones() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []int
+	for _, m := range p.msgs {
+		out = append(out, m.Milestone)
+	}
+	return out
+}
+
+// progressRow stands in for a viewer's watch_progress row: each save ORs the
+// milestones reached into the stored mask and reports against the mask from before,
+// as the progress upsert does
+type progressRow struct {
+	reporter *services.WatchMilestones
+	viewer   string
+	videoID  uint
+	duration float64
+	mask     uint8
+}
+
+func (r *progressRow) save(position float64, at time.Time) {
+	reached := r.mask | services.MilestoneMask(position, r.duration)
+	r.reporter.Report(context.Background(), r.viewer, r.videoID, r.mask, reached, at)
+	r.mask = reached
+}
+
+func TestMilestoneMask(t *testing.T) {
+	tests := []struct {
+		position, duration float64
+		want               uint8
+	}{
+		{0, 100, 0},
+		{-5, 100, 0},
+		{50, 0, 0},
+		{24.9, 100, 0},
+		{25, 100, 0b0001},
+		{60, 100, 0b0011},
+		{75, 100, 0b0111},
+		{94, 100, 0b0111},
+		{95, 100, 0b1111},
+		{100, 100, 0b1111},
+		{570, 600, 0b1111},
+	}
+	for _, tt := range tests {
+		if got := services.MilestoneMask(tt.position, tt.duration); got != tt.want {
+			t.Errorf("MilestoneMask(%v, %v) = %04b, want %04b", tt.position, tt.duration, got, tt.want)
+		}
+	}
+}
+
+func TestWatchMilestonesReport(t *testing.T) {
+	pub := &milestonePublisher{}
+	row := &progressRow{
+		reporter: services.NewWatchMilestones(pub, nopLogger(), milestoneKey, true),
+		viewer:   "anon:session-1",
+		videoID:  7,
+		duration: 200,
+	}
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	before := testutil.ToFloat64(metrics.WatchMilestonesTotal.WithLabelValues("50", "published"))
+
+	steps := []struct {
+		name     string
+		position float64
+		want     []int
+	}{
+		{"start", 10, nil},
+		{"jump past three", 160, []int{25, 50, 75}},
+		{"retried report", 160, nil},
+		{"seek back", 40, nil},
+		{"rewatch past 50", 110, nil},
+		{"finish", 195, []int{95}},
+		{"duplicate finish", 195, nil},
+	}
+	sent := 0
+	for _, step := range steps {
+		row.save(step.position, at)
+		got := pub.milestones()[sent:]
+		if fmt.Sprint(got) != fmt.Sprint(step.want) {
+			t.Errorf("%s: published %v, want %v", step.name, got, step.want)
+		}
+		sent += len(got)
+	}
+
+	for i, msg := range pub.msgs {
+		if pub.keys[i] != milestoneKey || msg.ViewerID != "anon:session-1" || !msg.Anonymous || msg.VideoID != 7 ||
+			!msg.ReachedAt.Equal(at) || msg.ReachedAt.Location() != time.UTC || msg.ProducedAt.IsZero() {
+			t.Errorf("message %d = %+v to %q", i, msg, pub.keys[i])
+		}
+	}
+	if got := testutil.ToFloat64(metrics.WatchMilestonesTotal.WithLabelValues("50", "published")) - before; got != 1 {
+		t.Errorf("published 50%% counted %v times, want 1", got)
+	}
+}
+
+func TestWatchMilestonesSignedInViewer(t *testing.T) {
+	pub := &milestonePublisher{}
+	services.NewWatchMilestones(pub, nopLogger(), milestoneKey, true).Report(context.Background(), "user-1", 3, 0, 0b0001, time.Now())
+	if len(pub.msgs) != 1 || pub.msgs[0].ViewerID != "user-1" || pub.msgs[0].Anonymous {
+		t.Errorf("published %+v", pub.msgs)
+	}
+}
+
+func TestWatchMilestonesDisabled(t *testing.T) {
+	pub := &milestonePublisher{}
+	row := &progressRow{reporter: services.NewWatchMilestones(pub, nopLogger(), milestoneKey, false), viewer: "user-1", videoID: 1, duration: 100}
+	row.save(96, time.Now())
+	if len(pub.msgs) != 0 {
+		t.Errorf("disabled reporter published %+v", pub.msgs)
+	}
+	// The mask is still kept, so turning emission on later doesn't replay old milestones
+	row.reporter = services.NewWatchMilestones(pub, nopLogger(), milestoneKey, true)
+	row.save(100, time.Now())
+	if len(pub.msgs) != 0 {
+		t.Errorf("enabling replayed %+v", pub.msgs)
+	}
+
+	// Neither a nil reporter nor one without a publisher does anything
+	var none *services.WatchMilestones
+	none.Report(context.Background(), "user-1", 1, 0, 0b1111, time.Now())
+	services.NewWatchMilestones(nil, nopLogger(), milestoneKey, true).Report(context.Background(), "user-1", 1, 0, 0b1111, time.Now())
+}
+
+func TestWatchMilestonesPublishError(t *testing.T) {
+	pub := &milestonePublisher{err: errors.New("broker down")}
+	before := map[string]float64{}
+	for _, pct := range []string{"25", "50"} {
+		before[pct] = testutil.ToFloat64(metrics.WatchMilestonesTotal.WithLabelValues(pct, "error"))
+	}
+
+	// A failed milestone is counted and doesn't stop the others
+	services.NewWatchMilestones(pub, nopLogger(), milestoneKey, true).Report(context.Background(), "user-1", 1, 0, 0b0011, time.Now())
+	for pct, n := range before {
+		if got := testutil.ToFloat64(metrics.WatchMilestonesTotal.WithLabelValues(pct, "error")) - n; got != 1 {
+			t.Errorf("%s%% errors counted %v times, want 1", pct, got)
+		}
+	}
+}
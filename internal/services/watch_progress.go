@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+)
+
+// continueWatchingMaxFraction is how far into a video a viewer may be for it to
+// still count as unfinished
+const continueWatchingMaxFraction = 0.9
+
+// saveProgressSQL upserts a viewer's position, returning the row's milestones
+// before and after the write, or no row when the write was skipped
+const saveProgressSQL = `WITH prev AS (
+		SELECT milestones FROM watch_progress WHERE user_id = ? AND video_id = ? FOR UPDATE
+	)
+	INSERT INTO watch_progress (user_id, video_id, position_seconds, duration_at_time, milestones, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (user_id, video_id) DO UPDATE
+	SET position_seconds = EXCLUDED.position_seconds, duration_at_time = EXCLUDED.duration_at_time,
+		milestones = watch_progress.milestones | EXCLUDED.milestones, updated_at = EXCLUDED.updated_at
+	WHERE abs(watch_progress.position_seconds - EXCLUDED.position_seconds) >= ?
+		OR watch_progress.milestones | EXCLUDED.milestones <> watch_progress.milestones
+	RETURNING milestones,
+		COALESCE((SELECT milestones FROM prev), CASE WHEN xmax = 0 THEN 0 ELSE -1 END) AS previous`
+
+// WatchProgressService keeps each viewer's resume position per video. Players
+// report often, so a position is only written when it moved by at least minStep
+// or crossed a watch milestone.
+type WatchProgressService struct {
+	db         *gorm.DB
+	logger     *zap.SugaredLogger
+	minStep    time.Duration
+	anonymous  *AnonymousSessionService
+	milestones *WatchMilestones
+	// saveSQL is saveProgressSQL, except in tests that can't lock rows or read xmax
+	saveSQL string
+}
+
+// NewWatchProgressService creates a progress store that skips writes moving the
+// position by less than minStep
+func NewWatchProgressService(db *gorm.DB, logger *zap.SugaredLogger, minStep time.Duration) *WatchProgressService {
+	return &WatchProgressService{db: db, logger: logger, minStep: minStep, saveSQL: saveProgressSQL}
+}
+
+// SetAnonymousSessions lets anonymous sessions save progress; their rows take part
+// in the session's quota, merge and retention
+func (s *WatchProgressService) SetAnonymousSessions(a *AnonymousSessionService) {
+	s.anonymous = a
+	a.RegisterData(AnonymousData{Name: "watch_progress", Table: "watch_progress", Column: "user_id", Merge: mergeWatchProgress})
+}
+
+// SetMilestones reports milestones crossed by saved positions
+func (s *WatchProgressService) SetMilestones(m *WatchMilestones) { s.milestones = m }
+
+// WatchProgressUpdate is the outcome of a progress report
+type WatchProgressUpdate struct {
+	VideoID         uint    `json:"video_id"`
+	PositionSeconds float64 `json:"position_seconds"`
+	DurationAtTime  float64 `json:"duration_at_time"`
+	// Saved is false when the position was too close to the stored one to write
+	Saved bool `json:"saved"`
+}
+
+// SaveProgress records viewer's position in video, clamped to [0, duration].
+// Callers must have checked the viewer may see the video.
+func (s *WatchProgressService) SaveProgress(ctx context.Context, video *models.Video, viewer string, position float64) (*WatchProgressUpdate, error) {
+	if models.IsAnonymousIdentity(viewer) && s.anonymous != nil {
+		if err := s.anonymous.CheckQuota(ctx, viewer); err != nil {
+			return nil, err
+		}
+	}
+	if position < 0 {
+		position = 0
+	}
+	if video.Duration > 0 && position > video.Duration {
+		position = video.Duration
+	}
+	update := &WatchProgressUpdate{VideoID: video.ID, PositionSeconds: position, DurationAtTime: video.Duration}
+	reached := MilestoneMask(position, video.Duration)
+	now := time.Now().UTC()
+
+	// One statement: the upsert skips small moves unless they reach a new milestone,
+	// and prev reads the stored mask under the row lock so each milestone is
+	// reported once. A row inserted concurrently by another report isn't visible to
+	// prev; previous is then unknown (-1) and nothing is reported for this write.
+	var rows []struct {
+		Milestones int16
+		Previous   int16
+	}
+	err := s.db.WithContext(ctx).Raw(s.saveSQL,
+		viewer, video.ID, viewer, video.ID, position, video.Duration, int16(reached), now, now, s.minStep.Seconds()).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("save watch progress: %w", err)
+	}
+	outcome := "skipped"
+	if len(rows) == 1 {
+		update.Saved = true
+		outcome = "saved"
+		if row := rows[0]; row.Previous >= 0 {
+			s.milestones.Report(ctx, viewer, video.ID, uint8(row.Previous), uint8(row.Milestones), now)
+		}
+	}
+	if models.IsAnonymousIdentity(viewer) && s.anonymous != nil {
+		if err := s.anonymous.Touch(ctx, strings.TrimPrefix(viewer, models.AnonymousPrefix)); err != nil {
+			s.logger.Warnw("Failed to touch anonymous session", "error", err, "viewer", viewer)
+		}
+	}
+	metrics.WatchProgressTotal.WithLabelValues(outcome).Inc()
+	return update, nil
+}
+
+// GetProgress returns viewer's saved position in videoID
+func (s *WatchProgressService) GetProgress(ctx context.Context, videoID uint, viewer string) (*models.WatchProgress, error) {
+	var progress models.WatchProgress
+	err := s.db.WithContext(ctx).Where("user_id = ? AND video_id = ?", viewer, videoID).First(&progress).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("video %d: %w", videoID, ErrProgressNotFound)
+		}
+		return nil, fmt.Errorf("get watch progress: %w", err)
+	}
+	return &progress, nil
+}
+
+// ContinueWatching lists userID's started, unfinished ready videos, most recently
+// watched first. Only listed videos and the user's own are included: access through
+// a share link can be revoked, so those videos aren't listed back.
+func (s *WatchProgressService) ContinueWatching(ctx context.Context, userID string, page, perPage int) ([]models.ContinueWatchingEntry, int64, error) {
+	const from = `FROM watch_progress p
+		JOIN videos v ON v.id = p.video_id AND v.deleted_at IS NULL
+		WHERE p.user_id = ? AND v.status = ? AND p.position_seconds > 0
+			AND p.position_seconds < ? * COALESCE(NULLIF(v.duration, 0), p.duration_at_time)
+			AND (v.user_id = ? OR (v.visibility = ? AND v.moderation_status <> ?))`
+	args := []interface{}{userID, models.StatusReady, continueWatchingMaxFraction, userID,
+		models.VisibilityPublic, models.ModerationStatusTakenDown}
+
+	var total int64
+	if err := s.db.WithContext(ctx).Raw("SELECT COUNT(*) "+from, args...).Scan(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count continue watching: %w", err)
+	}
+	entries := []models.ContinueWatchingEntry{}
+	err := s.db.WithContext(ctx).Raw(`SELECT v.id, v.title, v.thumbnail_url, v.duration, v.username, v.view_count,
+			v.like_count, v.created_at, p.position_seconds, p.updated_at AS progress_updated_at `+from+`
+		ORDER BY p.updated_at DESC, p.video_id DESC
+		LIMIT ? OFFSET ?`, append(args, perPage, (page-1)*perPage)...).Scan(&entries).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("list continue watching: %w", err)
+	}
+	return entries, total, nil
+}
+
+// mergeWatchProgress moves an anonymous session's progress to a user. Where both
+// watched the same video, the more recent position wins and the milestone masks
+// are combined.
+func mergeWatchProgress(tx *gorm.DB, from, to string) (int64, error) {
+	if err := tx.Exec(`UPDATE watch_progress AS u
+		SET position_seconds = CASE WHEN a.updated_at > u.updated_at THEN a.position_seconds ELSE u.position_seconds END,
+			duration_at_time = CASE WHEN a.updated_at > u.updated_at THEN a.duration_at_time ELSE u.duration_at_time END,
+			milestones = u.milestones | a.milestones, updated_at = GREATEST(u.updated_at, a.updated_at)
+		FROM watch_progress a
+		WHERE a.user_id = ? AND u.user_id = ? AND u.video_id = a.video_id`, from, to).Error; err != nil {
+		return 0, err
+	}
+	folded := tx.Exec(`DELETE FROM watch_progress AS a
+		WHERE a.user_id = ? AND EXISTS (SELECT 1 FROM watch_progress u WHERE u.user_id = ? AND u.video_id = a.video_id)`,
+		from, to)
+	if folded.Error != nil {
+		return 0, folded.Error
+	}
+	moved := tx.Exec("UPDATE watch_progress SET user_id = ? WHERE user_id = ?", to, from)
+	return folded.RowsAffected + moved.RowsAffected, moved.Error
+}
@@ -0,0 +1,162 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/services"
+)
+
+func TestSaveProgress(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	progress := services.NewWatchProgressService(db, nopLogger(), 5*time.Second)
+	progress.SaveWithoutRowLock()
+	video := createVideo(t, db, models.Video{Title: "t", Duration: 100})
+	skipped := metrics.WatchProgressTotal.WithLabelValues("skipped")
+	before := testutil.ToFloat64(skipped)
+
+	steps := []struct {
+		name     string
+		position float64
+		saved    bool
+		stored   float64
+	}{
+		{"first report", 30, true, 30},
+		{"small move forward", 34, false, 30},
+		{"small move back", 25.5, false, 30},
+		{"one step", 35, true, 35},
+		{"past the end", 150, true, 100},
+		{"before the start", -10, true, 0},
+		{"still before the start", -3, false, 0},
+	}
+	for _, step := range steps {
+		update, err := progress.SaveProgress(ctx, video, "viewer", step.position)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if update.Saved != step.saved || update.PositionSeconds != min(max(step.position, 0), 100) || update.DurationAtTime != 100 {
+			t.Errorf("%s: %+v, want saved %v", step.name, update, step.saved)
+		}
+		stored, err := progress.GetProgress(ctx, video.ID, "viewer")
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if stored.PositionSeconds != step.stored {
+			t.Errorf("%s: stored position %v, want %v", step.name, stored.PositionSeconds, step.stored)
+		}
+	}
+	if got := testutil.ToFloat64(skipped) - before; got != 3 {
+		t.Errorf("skipped writes counted = %v, want 3", got)
+	}
+
+	// Without a known duration there is no upper bound
+	live := createVideo(t, db, models.Video{Title: "live"})
+	if update, err := progress.SaveProgress(ctx, live, "viewer", 5000); err != nil || update.PositionSeconds != 5000 {
+		t.Errorf("no duration: %+v, %v; want 5000", update, err)
+	}
+}
+
+func TestSaveProgressCrossingMilestoneIsNotSkipped(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	progress := services.NewWatchProgressService(db, nopLogger(), 30*time.Second)
+	progress.SaveWithoutRowLock()
+	video := createVideo(t, db, models.Video{Title: "t", Duration: 100})
+
+	if _, err := progress.SaveProgress(ctx, video, "viewer", 20); err != nil {
+		t.Fatal(err)
+	}
+	// 26 is within the step of 20 but past the 25% milestone
+	update, err := progress.SaveProgress(ctx, video, "viewer", 26)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !update.Saved {
+		t.Errorf("move across a milestone was skipped")
+	}
+	stored, err := progress.GetProgress(ctx, video.ID, "viewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.PositionSeconds != 26 || stored.Milestones != services.MilestoneMask(26, 100) {
+		t.Errorf("stored %+v, want position 26 with the 25%% milestone", stored)
+	}
+}
+
+func TestContinueWatching(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	progress := services.NewWatchProgressService(db, nopLogger(), 5*time.Second)
+	now := time.Now().UTC()
+	watched := 0
+	watch := func(user string, video *models.Video, position, duration float64) {
+		t.Helper()
+		watched++
+		row := models.WatchProgress{UserID: user, VideoID: video.ID, PositionSeconds: position, DurationAtTime: duration,
+			UpdatedAt: now.Add(time.Duration(watched) * time.Minute)}
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	ready := func(title string, v models.Video) *models.Video {
+		v.Title, v.Status = title, models.StatusReady
+		if v.Duration == 0 {
+			v.Duration = 100
+		}
+		return createVideo(t, db, v)
+	}
+
+	started := ready("started", models.Video{})
+	watch("viewer", started, 10, 100)
+	watch("viewer", ready("almost done", models.Video{}), 89.9, 100)
+	watch("viewer", ready("done", models.Video{}), 90, 100)
+	watch("viewer", ready("not started", models.Video{}), 0, 100)
+	// The duration saved with the position stands in when the video has none
+	noDuration := createVideo(t, db, models.Video{Title: "no duration", Status: models.StatusReady})
+	watch("viewer", noDuration, 50, 100)
+	watch("viewer", createVideo(t, db, models.Video{Title: "no duration, done", Status: models.StatusReady}), 95, 100)
+	watch("viewer", createVideo(t, db, models.Video{Title: "processing", Status: models.StatusProcessing, Duration: 100}), 10, 100)
+	watch("viewer", ready("private", models.Video{Visibility: models.VisibilityPrivate}), 10, 100)
+	watch("viewer", ready("unlisted", models.Video{Visibility: models.VisibilityUnlisted}), 10, 100)
+	watch("viewer", ready("taken down", models.Video{ModerationStatus: models.ModerationStatusTakenDown}), 10, 100)
+	own := ready("own private", models.Video{UserID: "viewer", Visibility: models.VisibilityPrivate})
+	watch("viewer", own, 10, 100)
+	deleted := ready("deleted", models.Video{})
+	watch("viewer", deleted, 10, 100)
+	db.Delete(deleted)
+	watch("someone else", ready("someone else's", models.Video{}), 10, 100)
+
+	entries, total, err := progress.ContinueWatching(ctx, "viewer", 1, 10)
+	if err != nil {
+		t.Fatalf("ContinueWatching: %v", err)
+	}
+	var titles []string
+	for _, entry := range entries {
+		titles = append(titles, entry.Title)
+	}
+	want := []string{"own private", "no duration", "almost done", "started"}
+	if total != int64(len(want)) || len(titles) != len(want) {
+		t.Fatalf("continue watching = %v (total %d), want %v", titles, total, want)
+	}
+	for i := range want {
+		if titles[i] != want[i] {
+			t.Errorf("continue watching = %v, want %v most recent first", titles, want)
+			break
+		}
+	}
+	if entries[3].ID != started.ID || entries[3].PositionSeconds != 10 {
+		t.Errorf("started entry = %+v", entries[3])
+	}
+
+	page, total, err := progress.ContinueWatching(ctx, "viewer", 2, 3)
+	if err != nil || total != 4 || len(page) != 1 || page[0].ID != started.ID {
+		t.Errorf("second page = %+v (total %d), %v; want the oldest entry", page, total, err)
+	}
+}
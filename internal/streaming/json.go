@@ -0,0 +1,158 @@
+// Package streaming writes large query results to clients row by row, so memory
+// use stays flat regardless of how many rows a query returns.
+package streaming
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// Limits bounds a streamed response
+type Limits struct {
+	// MaxRows stops the stream after this many elements
+	MaxRows int
+	// MaxBytes stops the stream before an element would push the body past this size
+	MaxBytes int64
+	// FlushEvery flushes buffered output to the client every N elements
+	FlushEvery int
+}
+
+// DefaultLimits applies to streamed endpoints; main overrides it from the environment
+var DefaultLimits = Limits{MaxRows: 100000, MaxBytes: 64 << 20, FlushEvery: 500}
+
+// Result summarizes a finished stream
+type Result struct {
+	Rows      int   `json:"count"`
+	Bytes     int64 `json:"-"`
+	Truncated bool  `json:"truncated"`
+}
+
+// afterFinder matches models with an AfterFind hook; ScanRows doesn't run callbacks
+type afterFinder interface {
+	AfterFind(tx *gorm.DB) error
+}
+
+// Each walks a query with a database cursor, scanning one row at a time and
+// checking for cancellation between rows. fn must not retain the pointer.
+func Each[T any](ctx context.Context, query *gorm.DB, fn func(*T) error) error {
+	query = query.WithContext(ctx)
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var item T
+		if err := query.ScanRows(rows, &item); err != nil {
+			return err
+		}
+		if hook, ok := any(&item).(afterFinder); ok {
+			if err := hook.AfterFind(query); err != nil {
+				return err
+			}
+		}
+		if err := fn(&item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// errLimit stops Each once a limit is reached; it is not reported to callers
+var errLimit = errors.New("stream limit reached")
+
+// WriteArray streams the rows of query to w as
+//
+//	{"<key>":[...],"count":N,"truncated":false}
+//
+// Hitting a row or byte limit ends the array early with "truncated":true. A
+// database error mid-stream closes the document with an "error" field, since the
+// status code has already been sent.
+func WriteArray[T any](ctx context.Context, w io.Writer, key string, query *gorm.DB, limits Limits) (Result, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriterSize(cw, 32<<10)
+	flush := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	}
+
+	var res Result
+	keyJSON, _ := json.Marshal(key)
+	if _, err := fmt.Fprintf(bw, "{%s:[", keyJSON); err != nil {
+		return res, err
+	}
+
+	streamErr := Each(ctx, query, func(item *T) error {
+		if limits.MaxRows > 0 && res.Rows >= limits.MaxRows {
+			res.Truncated = true
+			return errLimit
+		}
+		b, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if limits.MaxBytes > 0 && cw.n+int64(bw.Buffered()+len(b)+1) > limits.MaxBytes {
+			res.Truncated = true
+			return errLimit
+		}
+		if res.Rows > 0 {
+			bw.WriteByte(',')
+		}
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+		res.Rows++
+		if limits.FlushEvery > 0 && res.Rows%limits.FlushEvery == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if streamErr == errLimit {
+		streamErr = nil
+	}
+	if ctx.Err() != nil {
+		// Client went away; nobody is left to read a closing marker
+		return res, ctx.Err()
+	}
+
+	tail := map[string]interface{}{"count": res.Rows, "truncated": res.Truncated || streamErr != nil}
+	if streamErr != nil {
+		tail["error"] = "stream aborted"
+	}
+	tailJSON, _ := json.Marshal(tail)
+	// Splice the tail object's fields after the array: ],"count":N,...}
+	bw.WriteString("],")
+	bw.Write(tailJSON[1:])
+	if err := flush(); err != nil && streamErr == nil {
+		streamErr = err
+	}
+	res.Bytes = cw.n
+	return res, streamErr
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
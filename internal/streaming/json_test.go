@@ -0,0 +1,178 @@
+package streaming_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/streamhive/video-catalog-api/internal/dbtest"
+	"github.com/streamhive/video-catalog-api/internal/models"
+	"github.com/streamhive/video-catalog-api/internal/streaming"
+)
+
+// seedFlags inserts n moderation flags, a wide enough row to make the body large
+func seedFlags(t *testing.T, db *gorm.DB, n int) {
+	t.Helper()
+	flags := make([]models.ModerationFlag, n)
+	for i := range flags {
+		flags[i] = models.ModerationFlag{TargetType: models.ModerationTargetVideo, TargetID: uint(i + 1), Field: "title",
+			Categories: map[string]float64{"spam": 0.1, "hate": 0.2}, Severity: "low", Status: models.ModerationFlagged, Provider: "test"}
+	}
+	if err := db.CreateInBatches(flags, 500).Error; err != nil {
+		t.Fatalf("seed flags: %v", err)
+	}
+}
+
+type streamed struct {
+	Flags     []models.ModerationFlag `json:"flags"`
+	Count     int                     `json:"count"`
+	Truncated bool                    `json:"truncated"`
+	Error     string                  `json:"error"`
+}
+
+func stream(t *testing.T, db *gorm.DB, limits streaming.Limits) (streamed, streaming.Result) {
+	t.Helper()
+	var body strings.Builder
+	res, err := streaming.WriteArray[models.ModerationFlag](context.Background(), &body, "flags", db.Model(&models.ModerationFlag{}).Order("id"), limits)
+	if err != nil {
+		t.Fatalf("WriteArray: %v", err)
+	}
+	var doc streamed
+	if err := json.Unmarshal([]byte(body.String()), &doc); err != nil {
+		t.Fatalf("streamed body isn't JSON: %v", err)
+	}
+	if res.Bytes != int64(body.Len()) {
+		t.Errorf("result reports %d bytes, wrote %d", res.Bytes, body.Len())
+	}
+	return doc, res
+}
+
+func TestWriteArrayLimits(t *testing.T) {
+	db := dbtest.Open(t)
+	seedFlags(t, db, 250)
+
+	tests := []struct {
+		name      string
+		limits    streaming.Limits
+		rows      int
+		truncated bool
+	}{
+		{"unlimited", streaming.Limits{FlushEvery: 100}, 250, false},
+		{"row cap", streaming.Limits{MaxRows: 40}, 40, true},
+		{"row cap equal to the result", streaming.Limits{MaxRows: 250}, 250, false},
+		{"byte cap", streaming.Limits{MaxBytes: 8 << 10}, -1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, res := stream(t, db, tt.limits)
+			if tt.rows >= 0 && res.Rows != tt.rows {
+				t.Errorf("rows = %d, want %d", res.Rows, tt.rows)
+			}
+			if doc.Count != res.Rows || len(doc.Flags) != res.Rows || doc.Truncated != tt.truncated || res.Truncated != tt.truncated {
+				t.Errorf("body count=%d len=%d truncated=%v, result %+v", doc.Count, len(doc.Flags), doc.Truncated, res)
+			}
+			if tt.limits.MaxBytes > 0 && (res.Bytes > tt.limits.MaxBytes+64 || res.Rows == 0) {
+				t.Errorf("byte cap %d: wrote %d bytes in %d rows", tt.limits.MaxBytes, res.Bytes, res.Rows)
+			}
+			for i, f := range doc.Flags {
+				if f.TargetID != uint(i+1) {
+					t.Fatalf("element %d has target %d; rows out of order", i, f.TargetID)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteArrayCancelled(t *testing.T) {
+	db := dbtest.Open(t)
+	seedFlags(t, db, 50)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := streaming.WriteArray[models.ModerationFlag](ctx, io.Discard, "flags", db.Model(&models.ModerationFlag{}), streaming.Limits{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestWriteArrayQueryError(t *testing.T) {
+	db := dbtest.Open(t)
+	var body strings.Builder
+	_, err := streaming.WriteArray[models.ModerationFlag](context.Background(), &body, "flags", db.Table("no_such_table"), streaming.Limits{})
+	if err == nil {
+		t.Fatal("query error not reported")
+	}
+	var doc streamed
+	if err := json.Unmarshal([]byte(body.String()), &doc); err != nil || doc.Error == "" || !doc.Truncated {
+		t.Errorf("aborted body %q should close with an error marker (%v)", body.String(), err)
+	}
+}
+
+func TestEachRunsAfterFind(t *testing.T) {
+	db := dbtest.Open(t)
+	db.Create(&models.Video{UploadID: "up", UserID: "u", Title: "t", TagsList: []string{"cats", "dogs"}})
+	var tags []string
+	err := streaming.Each(context.Background(), db.Model(&models.Video{}), func(v *models.Video) error {
+		tags = append(tags, v.TagsList...)
+		return nil
+	})
+	if err != nil || strings.Join(tags, ",") != "cats,dogs" {
+		t.Fatalf("Each = %v, tags %v; want the AfterFind hook to fill TagsList", err, tags)
+	}
+}
+
+// heapWriter discards output, sampling the live heap every 256 writes
+type heapWriter struct {
+	writes int
+	bytes  int64
+	peak   uint64
+}
+
+func (w *heapWriter) Write(p []byte) (int, error) {
+	w.writes++
+	w.bytes += int64(len(p))
+	if w.writes%256 == 1 {
+		runtime.GC()
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		w.peak = max(w.peak, m.HeapAlloc)
+	}
+	return len(p), nil
+}
+
+// Flush makes heapWriter an http.Flusher, so each FlushEvery batch reaches Write
+func (w *heapWriter) Flush() {}
+
+func TestWriteArrayMemoryStaysFlat(t *testing.T) {
+	if testing.Short() {
+		t.Skip("streams tens of thousands of rows")
+	}
+	db := dbtest.Open(t)
+	seedFlags(t, db, 40000)
+	query := func(n int) *gorm.DB { return db.Model(&models.ModerationFlag{}).Order("id").Limit(n) }
+
+	// The live heap while streaming 40k rows should match the heap for 4k; holding
+	// the rows or the body would grow it by the size of the output
+	peak := func(n int) (uint64, int64) {
+		w := &heapWriter{}
+		res, err := streaming.WriteArray[models.ModerationFlag](context.Background(), w, "flags", query(n), streaming.Limits{FlushEvery: 50})
+		if err != nil || res.Rows != n {
+			t.Fatalf("stream %d rows: %d rows, %v", n, res.Rows, err)
+		}
+		return w.peak, w.bytes
+	}
+	runtime.GC()
+	small, _ := peak(4000)
+	large, written := peak(40000)
+	if written < 8<<20 {
+		t.Fatalf("only %d bytes streamed; the test needs a body well above the heap budget", written)
+	}
+	if growth := int64(large) - int64(small); growth > 2<<20 {
+		t.Errorf("live heap grew by %d bytes between 4k and 40k rows (%d bytes streamed)", growth, written)
+	}
+}
@@ -0,0 +1,83 @@
+// Package tracing sets up OpenTelemetry tracing. Spans are exported over OTLP/HTTP
+// when an OTLP endpoint is configured with the standard OTEL_* variables; otherwise
+// the global provider stays a no-op and spans cost next to nothing. Trace context
+// is always propagated, so a caller's trace passes through the catalog even when
+// the catalog itself exports nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultServiceName is the service.name reported when OTEL_SERVICE_NAME is unset
+const DefaultServiceName = "video-catalog-api"
+
+// instrumentation names the catalog's own spans
+const instrumentation = "github.com/streamhive/video-catalog-api"
+
+// Setup installs the global tracer provider and propagator. It returns a function
+// that flushes buffered spans on shutdown, and whether spans are being exported.
+// Exporting is on when OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set, unless OTEL_SDK_DISABLED=true or
+// OTEL_TRACES_EXPORTER=none. The exporter reads the rest of its settings (headers,
+// timeout, compression) from the standard variables itself.
+func Setup(ctx context.Context) (func(context.Context) error, bool, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !exportEnabled() {
+		return func(context.Context) error { return nil }, false, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", ServiceName())))
+	if err != nil {
+		return nil, false, fmt.Errorf("build trace resource: %w", err)
+	}
+	// The sampler honours OTEL_TRACES_SAMPLER when set
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, true, nil
+}
+
+func exportEnabled() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") || os.Getenv("OTEL_TRACES_EXPORTER") == "none" {
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// ServiceName is OTEL_SERVICE_NAME, or DefaultServiceName
+func ServiceName() string {
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		return name
+	}
+	return DefaultServiceName
+}
+
+// Start starts a span as a child of whatever span ctx carries
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, opts...)
+}
+
+// End records err on span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
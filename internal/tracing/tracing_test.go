@@ -0,0 +1,65 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/streamhive/video-catalog-api/internal/tracing"
+)
+
+func TestSetupExportsOnlyWithAnEndpoint(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{"no endpoint", nil, false},
+		{"endpoint", map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://127.0.0.1:4318"}, true},
+		{"traces endpoint", map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://127.0.0.1:4318/v1/traces"}, true},
+		{"sdk disabled", map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://127.0.0.1:4318", "OTEL_SDK_DISABLED": "true"}, false},
+		{"exporter none", map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://127.0.0.1:4318", "OTEL_TRACES_EXPORTER": "none"}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_SDK_DISABLED", "OTEL_TRACES_EXPORTER"} {
+				t.Setenv(name, tt.env[name])
+			}
+			otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+			shutdown, exporting, err := tracing.Setup(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exporting != tt.want {
+				t.Errorf("exporting = %v, want %v", exporting, tt.want)
+			}
+			// Nothing was recorded, so shutting down has nothing to send
+			if err := shutdown(ctx); err != nil {
+				t.Errorf("shutdown: %v", err)
+			}
+
+			// Incoming trace context is continued whether or not spans are exported
+			const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+			ctx := otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(http.Header{"Traceparent": {parent}}))
+			header := http.Header{}
+			otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+			if header.Get("traceparent") != parent {
+				t.Errorf("propagated traceparent = %q, want %q", header.Get("traceparent"), parent)
+			}
+		})
+	}
+}
+
+func TestServiceName(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	if got := tracing.ServiceName(); got != tracing.DefaultServiceName {
+		t.Errorf("ServiceName() = %q, want the default", got)
+	}
+	t.Setenv("OTEL_SERVICE_NAME", "catalog-canary")
+	if got := tracing.ServiceName(); got != "catalog-canary" {
+		t.Errorf("ServiceName() = %q, want OTEL_SERVICE_NAME", got)
+	}
+}
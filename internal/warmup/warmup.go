@@ -0,0 +1,143 @@
+package warmup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/metrics"
+)
+
+// Step outcomes
+const (
+	OutcomeOK         = "ok"
+	OutcomeFailed     = "failed"
+	OutcomeIncomplete = "incomplete"
+)
+
+// Step is a single warmup action run before the pod reports ready
+type Step struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// StepResult records how a step went
+type StepResult struct {
+	Name       string `json:"name"`
+	Outcome    string `json:"outcome"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Report summarizes a warmup run
+type Report struct {
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at,omitempty"`
+	DurationMs int64        `json:"duration_ms"`
+	Complete   bool         `json:"complete"`
+	Steps      []StepResult `json:"steps"`
+}
+
+// Runner executes warmup steps once and tracks readiness
+type Runner struct {
+	logger *zap.SugaredLogger
+	steps  []Step
+
+	mu     sync.RWMutex
+	report *Report
+	ready  bool
+}
+
+// NewRunner creates a warmup runner
+func NewRunner(logger *zap.SugaredLogger, steps ...Step) *Runner {
+	return &Runner{logger: logger, steps: steps}
+}
+
+// Run executes the steps in order, bounded by deadline. When the deadline passes the
+// remaining steps are marked incomplete and the runner reports ready anyway, so a slow
+// dependency delays readiness but can never block it forever.
+func (r *Runner) Run(ctx context.Context, deadline time.Duration) *Report {
+	ctx, cancel := context.WithTimeout(ctx, deadline)
+	defer cancel()
+
+	report := &Report{StartedAt: time.Now()}
+	r.setReport(report, false)
+
+	var incomplete []string
+	for _, step := range r.steps {
+		result := StepResult{Name: step.Name}
+		if ctx.Err() != nil {
+			result.Outcome = OutcomeIncomplete
+			incomplete = append(incomplete, step.Name)
+		} else {
+			start := time.Now()
+			err := step.Run(ctx)
+			result.DurationMs = time.Since(start).Milliseconds()
+			metrics.WarmupStepDuration.WithLabelValues(step.Name).Set(time.Since(start).Seconds())
+			switch {
+			case err == nil:
+				result.Outcome = OutcomeOK
+			case ctx.Err() != nil:
+				result.Outcome = OutcomeIncomplete
+				result.Error = err.Error()
+				incomplete = append(incomplete, step.Name)
+			default:
+				result.Outcome = OutcomeFailed
+				result.Error = err.Error()
+				incomplete = append(incomplete, step.Name)
+				r.logger.Warnw("Warmup step failed", "step", step.Name, "error", err)
+			}
+		}
+		metrics.WarmupStepsTotal.WithLabelValues(step.Name, result.Outcome).Inc()
+		report.Steps = append(report.Steps, result)
+		r.setReport(report, false)
+	}
+
+	report.FinishedAt = time.Now()
+	report.DurationMs = report.FinishedAt.Sub(report.StartedAt).Milliseconds()
+	report.Complete = len(incomplete) == 0
+	metrics.WarmupDuration.Set(report.FinishedAt.Sub(report.StartedAt).Seconds())
+
+	if report.Complete {
+		r.logger.Infow("Warmup completed", "duration", report.FinishedAt.Sub(report.StartedAt))
+	} else {
+		r.logger.Warnw("Warmup finished with incomplete steps; going ready anyway",
+			"incomplete", incomplete,
+			"duration", report.FinishedAt.Sub(report.StartedAt))
+	}
+	r.setReport(report, true)
+	return report
+}
+
+// setReport publishes a copy of report, so Run can keep filling in its own while
+// Report readers see the steps finished so far
+func (r *Runner) setReport(report *Report, ready bool) {
+	cp := *report
+	cp.Steps = append([]StepResult(nil), report.Steps...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report = &cp
+	r.ready = ready
+}
+
+// Ready reports whether warmup has finished (successfully or not)
+func (r *Runner) Ready() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.ready
+}
+
+// Report returns a copy of the latest warmup report, or nil before Run starts
+func (r *Runner) Report() *Report {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.report == nil {
+		return nil
+	}
+	// The published report is never written again, but callers may modify theirs
+	cp := *r.report
+	cp.Steps = append([]StepResult(nil), r.report.Steps...)
+	return &cp
+}
@@ -0,0 +1,163 @@
+package warmup_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamhive/video-catalog-api/internal/warmup"
+)
+
+func outcomes(report *warmup.Report) []string {
+	out := make([]string, len(report.Steps))
+	for i, s := range report.Steps {
+		out[i] = s.Name + "=" + s.Outcome
+	}
+	return out
+}
+
+func ok(context.Context) error { return nil }
+
+// waitForDeadline blocks like a dependency that never answers
+func waitForDeadline(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRunnerSteps(t *testing.T) {
+	tests := []struct {
+		name     string
+		steps    []warmup.Step
+		want     []string
+		complete bool
+	}{
+		{
+			name:     "all ok",
+			steps:    []warmup.Step{{"db", ok}, {"cache", ok}},
+			want:     []string{"db=ok", "cache=ok"},
+			complete: true,
+		},
+		{
+			name:  "failure doesn't stop later steps",
+			steps: []warmup.Step{{"db", func(context.Context) error { return errors.New("refused") }}, {"cache", ok}},
+			want:  []string{"db=failed", "cache=ok"},
+		},
+		{
+			name:  "deadline marks the rest incomplete",
+			steps: []warmup.Step{{"db", ok}, {"search", waitForDeadline}, {"cache", ok}},
+			want:  []string{"db=ok", "search=incomplete", "cache=incomplete"},
+		},
+		{
+			name:     "no steps",
+			complete: true,
+			want:     []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := warmup.NewRunner(zap.NewNop().Sugar(), tt.steps...)
+			if runner.Ready() || runner.Report() != nil {
+				t.Fatal("runner ready before Run")
+			}
+			start := time.Now()
+			report := runner.Run(context.Background(), 50*time.Millisecond)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Run took %v past a 50ms deadline", elapsed)
+			}
+			if got := outcomes(report); !equal(got, tt.want) {
+				t.Errorf("steps = %v, want %v", got, tt.want)
+			}
+			if report.Complete != tt.complete {
+				t.Errorf("complete = %v, want %v", report.Complete, tt.complete)
+			}
+			if !runner.Ready() {
+				t.Error("runner not ready after Run, even with incomplete steps")
+			}
+			if got := outcomes(runner.Report()); !equal(got, tt.want) {
+				t.Errorf("published steps = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunnerErrorOnDeadline(t *testing.T) {
+	runner := warmup.NewRunner(zap.NewNop().Sugar(), warmup.Step{Name: "search", Run: waitForDeadline})
+	report := runner.Run(context.Background(), 10*time.Millisecond)
+	if report.Steps[0].Error == "" {
+		t.Error("step interrupted by the deadline has no error")
+	}
+}
+
+// TestRunnerReportDuringRun reads the report while steps run; go test -race flags
+// any write Run makes to a published report
+func TestRunnerReportDuringRun(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	runner := warmup.NewRunner(zap.NewNop().Sugar(),
+		warmup.Step{Name: "db", Run: ok},
+		warmup.Step{Name: "slow", Run: func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		}},
+		warmup.Step{Name: "cache", Run: ok},
+	)
+
+	done := make(chan *warmup.Report)
+	go func() { done <- runner.Run(context.Background(), time.Minute) }()
+	<-started
+
+	if got := outcomes(runner.Report()); !equal(got, []string{"db=ok"}) {
+		t.Errorf("report mid-run = %v, want only the finished step", got)
+	}
+	if runner.Ready() {
+		t.Error("ready mid-run")
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if r := runner.Report(); r != nil {
+					_ = r.Complete
+					_ = len(r.Steps)
+				}
+			}
+		}
+	}()
+	close(release)
+	report := <-done
+	close(stop)
+	wg.Wait()
+
+	if !report.Complete || report.FinishedAt.IsZero() {
+		t.Errorf("final report = %+v", report)
+	}
+	// Changing the returned report doesn't reach the runner's copy
+	report.Steps[0].Outcome = "tampered"
+	if runner.Report().Steps[0].Outcome != warmup.OutcomeOK {
+		t.Error("Run returned the published report")
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}